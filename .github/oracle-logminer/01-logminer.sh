@@ -0,0 +1,17 @@
+#!/bin/bash
+# Enables the archive log mode and supplemental logging that are
+# required to mine the redo logs. This runs once, when the database is
+# first created.
+set -e
+sqlplus -s / as sysdba <<SQL
+WHENEVER SQLERROR EXIT FAILURE
+SHUTDOWN IMMEDIATE
+STARTUP MOUNT
+ALTER DATABASE ARCHIVELOG;
+ALTER DATABASE OPEN;
+ALTER PLUGGABLE DATABASE ALL OPEN;
+ALTER DATABASE ADD SUPPLEMENTAL LOG DATA;
+ALTER SESSION SET CONTAINER = FREEPDB1;
+GRANT LOGMINING TO SYSTEM;
+GRANT EXECUTE ON DBMS_LOGMNR TO SYSTEM;
+SQL
@@ -0,0 +1,39 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package mssqlcdc contains a command to perform logical replication
+// from the change data capture tables of a SQL Server database.
+package mssqlcdc
+
+import (
+	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/replicator/internal/source/mssqlcdc"
+	"github.com/cockroachdb/replicator/internal/util/stdlogical"
+	"github.com/spf13/cobra"
+)
+
+// Command returns the mssqlcdc subcommand.
+func Command() *cobra.Command {
+	cfg := &mssqlcdc.Config{}
+	return stdlogical.New(&stdlogical.Template{
+		Config: cfg,
+		Short:  "start a SQL Server CDC replication feed",
+		Start: func(ctx *stopper.Context, cmd *cobra.Command) (any, error) {
+			return mssqlcdc.Start(ctx, cfg)
+		},
+		Use: "mssqlcdc",
+	})
+}
@@ -120,6 +120,17 @@ func testTargetConnection(ctx *stopper.Context, connString string) error {
 		if result != 1 {
 			return errors.Errorf("SELECT 1 from dual; returned %d instead", result)
 		}
+	case types.ProductSQLServer:
+		log.Info("SQL Server detected")
+		log.Info("Testing basic query")
+		var result int
+		row := pool.DB.QueryRowContext(ctx, "SELECT 1")
+		if err := row.Scan(&result); err != nil {
+			return err
+		}
+		if result != 1 {
+			return errors.Errorf("SELECT 1 returned %d instead", result)
+		}
 	default:
 		return errors.Errorf("Database type %s not supported.", pool.Product)
 	}
@@ -0,0 +1,55 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package replay contains a command to re-apply mutations which have
+// been retained in the staging tables.
+package replay
+
+import (
+	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/replicator/internal/replay"
+	"github.com/spf13/cobra"
+)
+
+// Command returns the replay subcommand.
+func Command() *cobra.Command {
+	cfg := &replay.Config{}
+	cmd := &cobra.Command{
+		Args:  cobra.NoArgs,
+		Short: "re-apply retained staged mutations to a target",
+		Long: `Replay re-applies mutations which have been retained in the staging tables
+(see --retireOffset) within a range of timestamps. The mutations may be
+written into the original target schema or, with --intoSchema, into an
+alternate schema with the same table definitions. Use --dryRun to report
+the number of mutations per table without applying them.`,
+		Use: "replay",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			// main.go provides a stopper.
+			ctx := stopper.From(cmd.Context())
+			r, err := replay.New(ctx, cfg)
+			if err != nil {
+				return err
+			}
+			report, err := r.Run(ctx)
+			if err != nil {
+				return err
+			}
+			return report.Write(cmd.OutOrStdout())
+		},
+	}
+	cfg.Bind(cmd.Flags())
+	return cmd
+}
@@ -167,6 +167,11 @@ func (c *clientConfig) createTables(ctx *stopper.Context, targetPool *types.Targ
 		}
 		bigType = "NUMBER(38)"
 
+	case types.ProductSQLServer:
+		if err := targetPool.QueryRow("SELECT DB_NAME()").Scan(&current); err != nil {
+			return errors.WithStack(err)
+		}
+
 	default:
 		return errors.Errorf(
 			"the demo subcommand does not support creating a target schema within %s; "+
@@ -253,11 +258,12 @@ func (c *clientConfig) newServer(
 	if err != nil {
 		return nil, err
 	}
-	stdlogical.AddHandlers(svr.GetAuthenticator(), svr.GetServeMux(), svr.GetDiagnostics())
+	stdlogical.AddHandlers(svr.GetAuthenticator(), svr.GetServeMux(), svr.GetDiagnostics(),
+		svr.GetAdminHandler(svr.GetAuthenticator()))
 	log.Infof("server listening on %s", svr.GetListener().Addr())
 
 	if c.metricsAddr != "" {
-		cancel, err := stdlogical.MetricsServer(trust.New(), c.metricsAddr, svr.GetDiagnostics())
+		cancel, err := stdlogical.MetricsServer(trust.New(), c.metricsAddr, svr.GetDiagnostics(), nil)
 		if err != nil {
 			return nil, err
 		}
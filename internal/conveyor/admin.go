@@ -0,0 +1,249 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package conveyor
+
+import (
+	"cmp"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/applycfg"
+	"github.com/cockroachdb/replicator/internal/util/hlc"
+	"github.com/cockroachdb/replicator/internal/util/httpauth"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// registry tracks the Conveyor instances created by a Conveyors
+// factory and all of its clones, so that they may be administered.
+type registry struct {
+	mu struct {
+		sync.RWMutex
+		conveyors []*Conveyor
+	}
+}
+
+func (r *registry) add(c *Conveyor) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.mu.conveyors = append(r.mu.conveyors, c)
+}
+
+// list returns the known conveyors, ordered by kind and schema.
+func (r *registry) list() []*Conveyor {
+	r.mu.RLock()
+	ret := slices.Clone(r.mu.conveyors)
+	r.mu.RUnlock()
+	slices.SortFunc(ret, func(a, b *Conveyor) int {
+		if c := cmp.Compare(a.factory.kind, b.factory.kind); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.target.Raw(), b.target.Raw())
+	})
+	return ret
+}
+
+// Status is the administrative view of a Conveyor.
+//
+// The lag is the age of the committed time. For a time-delayed
+// replica, the lag includes the intentional delay, while the backlog
+// excludes it.
+type Status struct {
+	Kind           string    `json:"kind"`
+	Schema         string    `json:"schema"`
+	Mode           string    `json:"mode"`
+	Override       string    `json:"override,omitempty"`
+	Paused         bool      `json:"paused"`
+	Until          *hlc.Time `json:"until,omitempty"`
+	Committed      hlc.Time  `json:"committed"`
+	Proposed       hlc.Time  `json:"proposed"`
+	LagSeconds     float64   `json:"lag_seconds"`
+	DelaySeconds   float64   `json:"delay_seconds"`
+	BacklogSeconds float64   `json:"backlog_seconds"`
+	AsOf           time.Time `json:"as_of"`
+}
+
+// Status returns the administrative view of the conveyor.
+func (c *Conveyor) Status() *Status {
+	rng, _ := c.resolvingRange.Get()
+	now := time.Now()
+	lag := now.Sub(time.Unix(0, rng.Min().Nanos()))
+	ret := &Status{
+		Kind:           c.factory.kind,
+		Schema:         c.target.Raw(),
+		Mode:           c.Mode().String(),
+		Paused:         c.Paused(),
+		Committed:      rng.Min(),
+		Proposed:       rng.MaxInclusive(),
+		LagSeconds:     lag.Seconds(),
+		DelaySeconds:   c.factory.cfg.Delay.Seconds(),
+		BacklogSeconds: c.backlog(lag).Seconds(),
+		AsOf:           now.UTC(),
+	}
+	if override := c.Override(); override != applycfg.ModeUnknown {
+		ret.Override = override.String()
+	}
+	if until := c.Until(); until != hlc.Zero() {
+		ret.Until = &until
+	}
+	return ret
+}
+
+// AdminHandler returns an [http.Handler] which allows the conveyors
+// to be inspected and controlled. The paths are relative to the mount
+// point of the handler:
+//
+//	GET  /conveyors                        List conveyors
+//	POST /pause?schema=S[&kind=K]          Stop applying mutations
+//	POST /pause?schema=S&until=T[&kind=K]  Apply mutations through T, then stop
+//	POST /resume?schema=S[&kind=K]         Resume applying mutations
+//	POST /mode?schema=S&mode=M[&kind=K]    Force a mode, or "auto"
+//	POST /rewind?schema=S&to=T[&kind=K]    Re-apply staged mutations after T
+//
+// Each request is authenticated against the target schema of the
+// conveyors that it reads or modifies. The conveyors to modify may be
+// restricted to a specific kind of source if the same schema is used
+// by multiple frontends. The response to each request is the updated
+// list of conveyor statuses.
+func (c *Conveyors) AdminHandler(auth types.Authenticator) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /conveyors", func(w http.ResponseWriter, req *http.Request) {
+		ret := make([]*Status, 0)
+		for _, conv := range c.registry.list() {
+			ok, err := auth.Check(req.Context(), conv.target, httpauth.Token(req))
+			if err != nil {
+				log.WithError(err).Warn("could not authenticate request")
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if ok {
+				ret = append(ret, conv.Status())
+			}
+		}
+		writeStatus(w, ret)
+	})
+	mux.Handle("POST /pause", c.adminAction(auth, func(req *http.Request, conv *Conveyor) error {
+		if q := req.URL.Query().Get("until"); q != "" {
+			until, err := hlc.Parse(q)
+			if err != nil {
+				return &adminError{http.StatusBadRequest, err}
+			}
+			if err := conv.SetUntil(until); err != nil {
+				return &adminError{http.StatusConflict, err}
+			}
+			return nil
+		}
+		conv.SetPaused(true)
+		return nil
+	}))
+	mux.Handle("POST /resume", c.adminAction(auth, func(_ *http.Request, conv *Conveyor) error {
+		conv.SetPaused(false)
+		return nil
+	}))
+	mux.Handle("POST /mode", c.adminAction(auth, func(req *http.Request, conv *Conveyor) error {
+		mode, err := applycfg.ParseMode(req.URL.Query().Get("mode"))
+		if err != nil {
+			return &adminError{http.StatusBadRequest, err}
+		}
+		return conv.SetMode(mode)
+	}))
+	mux.Handle("POST /rewind", c.adminAction(auth, func(req *http.Request, conv *Conveyor) error {
+		to, err := hlc.Parse(req.URL.Query().Get("to"))
+		if err != nil {
+			return &adminError{http.StatusBadRequest, err}
+		}
+		_, err = conv.Rewind(req.Context(), to)
+		return err
+	}))
+	return mux
+}
+
+// adminError allows an admin action to choose a response code.
+type adminError struct {
+	code int
+	err  error
+}
+
+func (e *adminError) Error() string { return e.err.Error() }
+
+// adminAction returns a handler which invokes the callback on all
+// conveyors selected by the request.
+func (c *Conveyors) adminAction(
+	auth types.Authenticator, fn func(req *http.Request, conv *Conveyor) error,
+) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		q := req.URL.Query()
+		if q.Get("schema") == "" {
+			http.Error(w, "a schema parameter is required", http.StatusBadRequest)
+			return
+		}
+		schema, err := ident.ParseSchema(q.Get("schema"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		ok, err := auth.Check(req.Context(), schema, httpauth.Token(req))
+		if err != nil {
+			log.WithError(err).Warn("could not authenticate request")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if !ok {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		kind := q.Get("kind")
+		var ret []*Status
+		for _, conv := range c.registry.list() {
+			if !ident.Equal(conv.target, schema) || (kind != "" && conv.factory.kind != kind) {
+				continue
+			}
+			if err := fn(req, conv); err != nil {
+				code := http.StatusInternalServerError
+				if admin := (*adminError)(nil); errors.As(err, &admin) {
+					code = admin.code
+				}
+				log.WithError(err).Warnf("admin request %s failed for %s", req.URL, schema)
+				http.Error(w, err.Error(), code)
+				return
+			}
+			log.Infof("admin request %s applied to %s %s", req.URL, conv.factory.kind, schema)
+			ret = append(ret, conv.Status())
+		}
+		if len(ret) == 0 {
+			http.Error(w, "no conveyor for schema "+schema.Raw(), http.StatusNotFound)
+			return
+		}
+		writeStatus(w, ret)
+	})
+}
+
+func writeStatus(w http.ResponseWriter, status []*Status) {
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(status); err != nil {
+		log.WithError(err).Warn("could not write conveyor status")
+	}
+}
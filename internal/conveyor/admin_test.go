@@ -0,0 +1,63 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package conveyor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cockroachdb/replicator/internal/util/auth/reject"
+	"github.com/cockroachdb/replicator/internal/util/auth/trust"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAdminHandler checks request validation, since a running conveyor
+// requires a staging database.
+func TestAdminHandler(t *testing.T) {
+	c := &Conveyors{registry: &registry{}}
+
+	tcs := []struct {
+		method, path string
+		rejected     bool
+		code         int
+		body         string
+	}{
+		{method: http.MethodGet, path: "/conveyors", code: http.StatusOK, body: "[]\n"},
+		{method: http.MethodPost, path: "/conveyors", code: http.StatusMethodNotAllowed},
+		{method: http.MethodPost, path: "/pause", code: http.StatusBadRequest},
+		{method: http.MethodPost, path: "/pause?schema=db.public", code: http.StatusNotFound},
+		{method: http.MethodPost, path: "/pause?schema=db.public", rejected: true, code: http.StatusForbidden},
+		{method: http.MethodGet, path: "/pause?schema=db.public", code: http.StatusMethodNotAllowed},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.method+" "+tc.path, func(t *testing.T) {
+			r := require.New(t)
+			h := c.AdminHandler(trust.New())
+			if tc.rejected {
+				h = c.AdminHandler(reject.New())
+			}
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+			r.Equal(tc.code, w.Code)
+			if tc.body != "" {
+				r.Equal(tc.body, w.Body.String())
+			}
+		})
+	}
+}
@@ -19,6 +19,7 @@ package conveyor
 import (
 	"time"
 
+	"github.com/pkg/errors"
 	"github.com/spf13/pflag"
 )
 
@@ -39,6 +40,11 @@ type Config struct {
 	// Force the use of BestEffort mode.
 	BestEffortOnly bool
 
+	// If non-zero, mutations are held in the staging tables until the
+	// checkpoint that contains them is at least this old. This creates
+	// a replica which deliberately lags behind the source.
+	Delay time.Duration
+
 	// Don't use a core changefeed for cross-Replicator notifications
 	// and only use a polling strategy for detecting changes to the
 	// timestamp bounds.
@@ -63,6 +69,9 @@ func (c *Config) Bind(f *pflag.FlagSet) {
 			"is behind; 0 to disable")
 	f.BoolVar(&c.BestEffortOnly, "bestEffortOnly", false,
 		"eventually-consistent mode; useful for high throughput, skew-tolerant schemas with FKs")
+	f.DurationVar(&c.Delay, "applyDelay", 0,
+		"hold mutations in staging until they are at least this old to maintain "+
+			"a time-delayed replica; 0 to disable")
 	f.BoolVar(&c.DisableCheckpointStream, "disableCheckpointStream", false,
 		"disable cross-Replicator checkpoint notifications and rely only on polling")
 	f.BoolVar(&c.Immediate, "immediate", false,
@@ -75,5 +84,12 @@ func (c *Config) Bind(f *pflag.FlagSet) {
 
 // Preflight ensures the Config is in a known-good state.
 func (c *Config) Preflight() error {
+	if c.Delay < 0 {
+		return errors.New("applyDelay must not be negative")
+	}
+	if c.Delay > 0 && c.Immediate {
+		return errors.New("applyDelay cannot be used with immediate mode, " +
+			"since mutations are not staged")
+	}
 	return nil
 }
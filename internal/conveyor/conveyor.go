@@ -26,17 +26,23 @@ import (
 	"github.com/cockroachdb/field-eng-powertools/stopper"
 	"github.com/cockroachdb/field-eng-powertools/stopvar"
 	"github.com/cockroachdb/replicator/internal/sequencer"
+	"github.com/cockroachdb/replicator/internal/sequencer/pii"
 	"github.com/cockroachdb/replicator/internal/sequencer/retire"
 	"github.com/cockroachdb/replicator/internal/sequencer/script"
 	"github.com/cockroachdb/replicator/internal/sequencer/switcher"
 	"github.com/cockroachdb/replicator/internal/staging/checkpoint"
-	"github.com/cockroachdb/replicator/internal/target/apply"
 	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/applycfg"
 	"github.com/cockroachdb/replicator/internal/util/hlc"
 	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 )
 
+// restartGracePeriod bounds the amount of time that a sequencer will be
+// given to drain when it is restarted.
+const restartGracePeriod = 10 * time.Second
+
 // Conveyors manages the plumbing necessary to deliver mutations to a
 // target schema across multiple partitions. It is also responsible for
 // mode-switching.
@@ -44,11 +50,15 @@ type Conveyors struct {
 	cfg           *Config                 // Controls the mode of operations.
 	checkpoints   *checkpoint.Checkpoints // Checkpoints factory.
 	kind          string                  // Used by metrics.
+	pii           *pii.PII                // Protects column values before staging.
+	registry      *registry               // Shared across all kinds for administration.
 	retire        *retire.Retire          // Removes old mutations.
 	script        *script.Sequencer       // Userscript wrappers.
+	stagers       types.Stagers           // Staging access for rewinding.
+	stagingPool   *types.StagingPool      // Staging database.
 	stopper       *stopper.Context        // Manages the lifetime of the goroutines.
 	switcher      *switcher.Switcher      // Switches between mode of operations.
-	tableAcceptor *apply.Acceptor         // Writes batches of mutations into target tables.
+	tableAcceptor types.TableAcceptor     // Writes batches of mutations into target tables.
 	watchers      types.Watchers          // Target schema access.
 
 	mu struct {
@@ -96,12 +106,22 @@ func (c *Conveyors) Get(schema ident.Schema) (*Conveyor, error) {
 	}
 
 	var opts []checkpoint.Option
+	if c.cfg.Delay > 0 {
+		opts = append(opts, checkpoint.Delay(c.cfg.Delay))
+	}
 	if c.cfg.DisableCheckpointStream {
 		opts = append(opts, checkpoint.DisableStream())
 	}
 	if l := c.cfg.LimitLookahead; l > 0 {
 		opts = append(opts, checkpoint.LimitLookahead(l))
 	}
+	// Acceptors such as the Kafka target emit resolved markers once
+	// the group has been committed.
+	if res, ok := c.tableAcceptor.(types.ResolvedAcceptor); ok {
+		opts = append(opts, checkpoint.OnCommit(func(ctx context.Context, rng hlc.Range) error {
+			return res.AcceptResolved(ctx, tableGroup, rng.MaxInclusive())
+		}))
+	}
 	ret.checkpoint, err = c.checkpoints.Start(c.stopper, tableGroup, &ret.resolvingRange, opts...)
 	if err != nil {
 		return nil, err
@@ -111,29 +131,25 @@ func (c *Conveyors) Get(schema ident.Schema) (*Conveyor, error) {
 	ret.modeSelector(c.stopper)
 
 	seq := sequencer.Sequencer(c.switcher.WithMode(&ret.mode))
-	seq, err = c.script.Wrap(c.stopper, seq)
+	seq, err = c.pii.Wrap(c.stopper, seq) // No-op if no policies.
 	if err != nil {
 		return nil, err
 	}
-
-	ret.acceptor, ret.stat, err = seq.Start(
-		c.stopper,
-		&sequencer.StartOptions{
-			Bounds:   &ret.resolvingRange,
-			Delegate: types.OrderedAcceptorFrom(c.tableAcceptor, c.watchers),
-			Group:    tableGroup,
-		})
+	ret.seq, err = c.script.Wrap(c.stopper, seq)
 	if err != nil {
 		return nil, err
 	}
 
-	// Add top-of-funnel reporting.
-	labels := []string{c.kind, schema.Raw()}
-	ret.acceptor = types.CountingAcceptor(ret.acceptor,
-		mutationsErrorCount.WithLabelValues(labels...),
-		mutationsReceivedCount.WithLabelValues(labels...),
-		mutationsSuccessCount.WithLabelValues(labels...),
-	)
+	// Copy the resolving range into the sequencer's bounds, unless
+	// the conveyor has been paused.
+	ret.pumpBounds(c.stopper)
+
+	ret.mu.Lock()
+	err = ret.startLocked()
+	ret.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
 
 	// Advance the stored resolved timestamps.
 	ret.updateResolved(c.stopper)
@@ -145,6 +161,7 @@ func (c *Conveyors) Get(schema ident.Schema) (*Conveyor, error) {
 	ret.metrics(c.stopper)
 
 	c.mu.targets.Put(schema, ret)
+	c.registry.add(ret)
 	return ret, nil
 }
 
@@ -176,8 +193,12 @@ func (c *Conveyors) clone() *Conveyors {
 		cfg:           c.cfg,
 		checkpoints:   c.checkpoints,
 		kind:          c.kind,
+		pii:           c.pii,
+		registry:      c.registry,
 		retire:        c.retire,
 		script:        c.script,
+		stagers:       c.stagers,
+		stagingPool:   c.stagingPool,
 		stopper:       c.stopper,
 		switcher:      c.switcher,
 		tableAcceptor: c.tableAcceptor,
@@ -189,21 +210,49 @@ func (c *Conveyors) clone() *Conveyors {
 // It provides an abstraction over various delivery strategies and it
 // manages checkpoints across multiple partitions for a table group.
 type Conveyor struct {
-	acceptor       types.MultiAcceptor         // Possibly-async writes to the target.
-	checkpoint     *checkpoint.Group           // Persistence of checkpoint (fka. resolved) timestamps
-	factory        *Conveyors                  // Factory that created this conveyor.
-	mode           notify.Var[switcher.Mode]   // Switchable strategies.
-	resolvingRange notify.Var[hlc.Range]       // Range of resolved timestamps to be processed.
-	stat           *notify.Var[sequencer.Stat] // Processing status.
-	target         ident.Schema                // Identify for logging.
-	watcher        types.Watcher               // Schema info.
+	bounds         notify.Var[hlc.Range]      // Range presented to the sequencer.
+	checkpoint     *checkpoint.Group          // Persistence of checkpoint (fka. resolved) timestamps
+	factory        *Conveyors                 // Factory that created this conveyor.
+	mode           notify.Var[applycfg.Mode]  // Switchable strategies.
+	override       notify.Var[applycfg.Mode]  // Operator-selected mode, if not ModeUnknown.
+	paused         notify.Var[bool]           // Holds the sequencer's bounds in place.
+	resolvingRange notify.Var[hlc.Range]      // Range of resolved timestamps to be processed.
+	seq            sequencer.Sequencer        // Restarted when rewinding.
+	stat           notify.Var[sequencer.Stat] // Processing status.
+	target         ident.Schema               // Identify for logging.
+	until          notify.Var[hlc.Time]       // If non-zero, hold the bounds after this time.
+	watcher        types.Watcher              // Schema info.
+
+	mu struct {
+		sync.RWMutex
+		acceptor types.MultiAcceptor // Possibly-async writes to the target.
+		stopper  *stopper.Context    // Governs the current sequencer.
+	}
 }
 
-// AcceptMultiBatch transmits the batch. The options may be nil.
+// AcceptMultiBatch transmits the batch. The options may be nil. If the
+// conveyor has been paused while in immediate mode, this method will
+// block until the conveyor is resumed.
 func (c *Conveyor) AcceptMultiBatch(
 	ctx context.Context, batch *types.MultiBatch, options *types.AcceptOptions,
 ) error {
-	return c.acceptor.AcceptMultiBatch(ctx, batch, options)
+	for {
+		paused, changed := c.paused.Get()
+		if !paused {
+			break
+		}
+		if mode, _ := c.mode.Get(); mode != applycfg.ModeImmediate {
+			break
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.mu.acceptor.AcceptMultiBatch(ctx, batch, options)
 }
 
 // Advance the checkpoint for all the named partitions.
@@ -216,6 +265,31 @@ func (c *Conveyor) Ensure(ctx context.Context, partitions []ident.Ident) error {
 	return c.checkpoint.Ensure(ctx, partitions)
 }
 
+// Mode returns the current mode of operation.
+func (c *Conveyor) Mode() applycfg.Mode {
+	mode, _ := c.mode.Get()
+	return mode
+}
+
+// Override returns the operator-selected mode, or
+// [applycfg.ModeUnknown] if the mode is automatically selected.
+func (c *Conveyor) Override() applycfg.Mode {
+	mode, _ := c.override.Get()
+	return mode
+}
+
+// Paused returns true if the conveyor has been paused.
+func (c *Conveyor) Paused() bool {
+	paused, _ := c.paused.Get()
+	return paused
+}
+
+// Until returns the time set by [Conveyor.SetUntil] or a zero time.
+func (c *Conveyor) Until() hlc.Time {
+	until, _ := c.until.Get()
+	return until
+}
+
 // Range returns the range of resolved timestamps to be processed.
 func (c *Conveyor) Range() *notify.Var[hlc.Range] {
 	return &c.resolvingRange
@@ -226,9 +300,89 @@ func (c *Conveyor) Refresh() {
 	c.checkpoint.Refresh()
 }
 
+// Rewind causes all staged mutations after the given time to be
+// re-applied. The checkpoints after the time are marked as unapplied,
+// the staged mutations are marked as unapplied, and the sequencer is
+// restarted. Mutations which have already been retired from staging
+// cannot be re-applied. This method returns the new range of resolved
+// timestamps to be processed.
+func (c *Conveyor) Rewind(ctx context.Context, to hlc.Time) (hlc.Range, error) {
+	if c.Mode() == applycfg.ModeImmediate {
+		return hlc.RangeEmpty(), errors.Errorf(
+			"cannot rewind %s while in immediate mode, since mutations are not staged", c.target)
+	}
+
+	// Block incoming mutations while the sequencer is restarted.
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.mu.stopper.Stop(restartGracePeriod)
+	if err := c.mu.stopper.Wait(); err != nil {
+		log.WithError(err).Warnf("error while stopping sequencer for %s", c.target)
+	}
+
+	// The new bounds must be established before restarting, since
+	// the staging readers will not move backwards.
+	rng, err := c.rewindLocked(ctx, to)
+	if err != nil {
+		// Try to get back to where we were.
+		if startErr := c.startLocked(); startErr != nil {
+			log.WithError(startErr).Errorf("could not restart sequencer for %s", c.target)
+		}
+		return hlc.RangeEmpty(), err
+	}
+	if err := c.startLocked(); err != nil {
+		return hlc.RangeEmpty(), err
+	}
+	log.Infof("rewound %s to %s", c.target, rng)
+	return rng, nil
+}
+
+// SetMode forces the conveyor to use a specific mode of operation.
+// Passing [applycfg.ModeUnknown] restores automatic mode selection.
+func (c *Conveyor) SetMode(mode applycfg.Mode) error {
+	if mode < applycfg.ModeUnknown || mode > applycfg.MaxMode {
+		return errors.Errorf("invalid mode %d", mode)
+	}
+	c.override.Set(mode)
+	rng, _ := c.resolvingRange.Get()
+	c.selectMode(rng)
+	return nil
+}
+
+// SetPaused pauses or resumes the conveyor. A paused conveyor will
+// continue to stage incoming mutations, but will not apply them to
+// the target. In immediate mode, incoming mutations will be blocked.
+// Resuming the conveyor also clears any time set by [Conveyor.SetUntil].
+func (c *Conveyor) SetPaused(paused bool) {
+	if !paused {
+		c.until.Set(hlc.Zero())
+	}
+	c.paused.Set(paused)
+}
+
+// SetUntil allows the conveyor to apply mutations up to and including
+// the given time, and then hold in place. This allows an operator to
+// stop a time-delayed replica just before a problematic transaction
+// is applied. The conveyor will be resumed if it is paused.
+func (c *Conveyor) SetUntil(until hlc.Time) error {
+	if c.Mode() == applycfg.ModeImmediate {
+		return errors.Errorf(
+			"cannot hold %s at a time while in immediate mode, since mutations are not staged",
+			c.target)
+	}
+	if bounds, _ := c.bounds.Get(); hlc.Compare(until, bounds.MaxInclusive()) < 0 {
+		return errors.Errorf(
+			"%s may have already applied mutations through %s; use rewind instead",
+			c.target, bounds.MaxInclusive())
+	}
+	c.until.Set(until)
+	c.paused.Set(false)
+	return nil
+}
+
 // Stat returns the progress of all tables being managed.
 func (c *Conveyor) Stat() *notify.Var[sequencer.Stat] {
-	return c.stat
+	return &c.stat
 }
 
 // TableGroup returns the TableGroup associated to this conveyor.
@@ -254,12 +408,17 @@ func (c *Conveyor) metrics(ctx *stopper.Context) {
 		min := resolvedMinTimestamp.WithLabelValues(labels...)
 		max := resolvedMaxTimestamp.WithLabelValues(labels...)
 		sourceLag := sourceLagDuration.WithLabelValues(labels...)
+		targetBacklog := targetBacklogDuration.WithLabelValues(labels...)
+		targetDelay := targetDelayDuration.WithLabelValues(labels...)
 		targetLag := targetLagDuration.WithLabelValues(labels...)
+		targetDelay.Set(c.factory.cfg.Delay.Seconds())
 		_, err := stopvar.DoWhenChangedOrInterval(ctx,
 			hlc.RangeEmpty(), &c.resolvingRange, tick,
 			func(ctx *stopper.Context, _, new hlc.Range) error {
+				lag := time.Duration(time.Now().UnixNano() - new.Min().Nanos())
 				min.Set(float64(new.Min().Nanos()) / 1e9)
-				targetLag.Set(float64(time.Now().UnixNano()-new.Min().Nanos()) / 1e9)
+				targetBacklog.Set(c.backlog(lag).Seconds())
+				targetLag.Set(lag.Seconds())
 				max.Set(float64(new.MaxInclusive().Nanos()) / 1e9)
 				sourceLag.Set(float64(time.Now().UnixNano()-new.MaxInclusive().Nanos()) / 1e9)
 				return nil
@@ -269,11 +428,8 @@ func (c *Conveyor) metrics(ctx *stopper.Context) {
 }
 
 func (c *Conveyor) modeSelector(ctx *stopper.Context) {
-	if c.factory.cfg.Immediate {
-		c.mode.Set(switcher.ModeImmediate)
-		return
-	} else if c.factory.cfg.BestEffortOnly {
-		c.mode.Set(switcher.ModeBestEffort)
+	if c.factory.cfg.Immediate || c.factory.cfg.BestEffortOnly {
+		c.selectMode(hlc.RangeEmpty())
 		return
 	}
 	// The initial update will be async, so wait for it.
@@ -284,37 +440,7 @@ func (c *Conveyor) modeSelector(ctx *stopper.Context) {
 			&c.resolvingRange,
 			10*time.Second, // Re-evaluate to allow un-jamming big serial transactions.
 			func(ctx *stopper.Context, _, bounds hlc.Range) error {
-				minTime := time.Unix(0, bounds.Min().Nanos())
-				lag := time.Since(minTime)
-
-				// Sometimes you don't know what you want.
-				want := switcher.ModeUnknown
-				if c.factory.cfg.BestEffortWindow <= 0 {
-					// Force a consistent mode.
-					want = switcher.ModeConsistent
-				} else if lag >= c.factory.cfg.BestEffortWindow {
-					// Fallen behind, switch to best-effort.
-					want = switcher.ModeBestEffort
-				} else if lag <= c.factory.cfg.BestEffortWindow/4 {
-					// Caught up close-enough to the current time.
-					want = switcher.ModeConsistent
-				}
-
-				_, _, _ = c.mode.Update(func(current switcher.Mode) (switcher.Mode, error) {
-					// Pick a reasonable default for uninitialized case.
-					// Choosing BestEffort here allows us to optimize
-					// for the case where a user creates a changefeed
-					// that's going to perform a large backfill.
-					if current == switcher.ModeUnknown && want == switcher.ModeUnknown {
-						want = switcher.ModeBestEffort
-					} else if want == switcher.ModeUnknown || current == want {
-						// No decision above or no change.
-						return current, notify.ErrNoUpdate
-					}
-
-					log.Tracef("setting group %s mode to %s", c.target, want)
-					return want, nil
-				})
+				c.selectMode(bounds)
 				return nil
 			})
 		return err
@@ -322,13 +448,170 @@ func (c *Conveyor) modeSelector(ctx *stopper.Context) {
 	<-initialSet
 }
 
+// backlog subtracts the configured delay from the lag of the conveyor.
+func (c *Conveyor) backlog(lag time.Duration) time.Duration {
+	return max(lag-c.factory.cfg.Delay, 0)
+}
+
+// nextBounds returns the bounds to present to the sequencer, given the
+// resolving range. This method returns false if the bounds should be
+// held in place because the conveyor has been paused or has reached
+// the time set by [Conveyor.SetUntil].
+func (c *Conveyor) nextBounds(rng hlc.Range) (hlc.Range, bool) {
+	if c.Paused() {
+		return hlc.RangeEmpty(), false
+	}
+	until := c.Until()
+	if until == hlc.Zero() || hlc.Compare(rng.MaxInclusive(), until) <= 0 {
+		return rng, true
+	}
+	if hlc.Compare(rng.Min(), until) >= 0 {
+		return hlc.RangeEmpty(), false
+	}
+	return hlc.RangeIncluding(rng.Min(), until), true
+}
+
+// pumpBounds copies the resolving range into the bounds that are
+// presented to the sequencer. The bounds are held in place while the
+// conveyor is paused or once the time set by [Conveyor.SetUntil] has
+// been reached.
+func (c *Conveyor) pumpBounds(ctx *stopper.Context) {
+	rng, _ := c.resolvingRange.Get()
+	c.bounds.Set(rng)
+	ctx.Go(func(ctx *stopper.Context) error {
+		for {
+			rng, rngChanged := c.resolvingRange.Get()
+			_, pausedChanged := c.paused.Get()
+			_, untilChanged := c.until.Get()
+			if next, ok := c.nextBounds(rng); ok {
+				c.bounds.Set(next)
+			}
+			select {
+			case <-rngChanged:
+			case <-pausedChanged:
+			case <-untilChanged:
+			case <-ctx.Stopping():
+				return nil
+			}
+		}
+	})
+}
+
+// rewindLocked resets the checkpoints and staged data. The caller must
+// hold the write lock and the sequencer must have been stopped.
+func (c *Conveyor) rewindLocked(ctx context.Context, to hlc.Time) (hlc.Range, error) {
+	rng, err := c.checkpoint.Rewind(ctx, to)
+	if err != nil {
+		return hlc.RangeEmpty(), err
+	}
+	for _, table := range c.TableGroup().Tables {
+		stager, err := c.factory.stagers.Get(ctx, table)
+		if err != nil {
+			return hlc.RangeEmpty(), err
+		}
+		if err := stager.Rewind(ctx, c.factory.stagingPool, rng.Min()); err != nil {
+			return hlc.RangeEmpty(), err
+		}
+	}
+	if next, ok := c.nextBounds(rng); ok {
+		c.bounds.Set(next)
+	} else {
+		c.bounds.Set(hlc.RangeEmptyAt(rng.Min()))
+	}
+	return rng, nil
+}
+
+// selectMode updates the mode of operation, based on the configuration,
+// any operator override, and the lag of the resolving range.
+func (c *Conveyor) selectMode(bounds hlc.Range) {
+	// Sometimes you don't know what you want.
+	want := applycfg.ModeUnknown
+	if override := c.Override(); override != applycfg.ModeUnknown {
+		want = override
+	} else if c.factory.cfg.Immediate {
+		want = applycfg.ModeImmediate
+	} else if c.factory.cfg.BestEffortOnly {
+		want = applycfg.ModeBestEffort
+	} else if c.factory.cfg.BestEffortWindow <= 0 {
+		// Force a consistent mode.
+		want = applycfg.ModeConsistent
+	} else {
+		// A time-delayed replica is expected to lag.
+		minTime := time.Unix(0, bounds.Min().Nanos())
+		lag := c.backlog(time.Since(minTime))
+		if lag >= c.factory.cfg.BestEffortWindow {
+			// Fallen behind, switch to best-effort.
+			want = applycfg.ModeBestEffort
+		} else if lag <= c.factory.cfg.BestEffortWindow/4 {
+			// Caught up close-enough to the current time.
+			want = applycfg.ModeConsistent
+		}
+	}
+
+	_, _, _ = c.mode.Update(func(current applycfg.Mode) (applycfg.Mode, error) {
+		// Pick a reasonable default for uninitialized case.
+		// Choosing BestEffort here allows us to optimize
+		// for the case where a user creates a changefeed
+		// that's going to perform a large backfill.
+		if current == applycfg.ModeUnknown && want == applycfg.ModeUnknown {
+			want = applycfg.ModeBestEffort
+		} else if want == applycfg.ModeUnknown || current == want {
+			// No decision above or no change.
+			return current, notify.ErrNoUpdate
+		}
+
+		log.Tracef("setting group %s mode to %s", c.target, want)
+		return want, nil
+	})
+}
+
+// startLocked starts the sequencer under a new child context. The
+// caller must hold the write lock.
+func (c *Conveyor) startLocked() error {
+	ctx := stopper.WithContext(c.factory.stopper)
+	acc, stat, err := c.seq.Start(
+		ctx,
+		&sequencer.StartOptions{
+			Bounds:   &c.bounds,
+			Delegate: types.OrderedAcceptorFrom(c.factory.tableAcceptor, c.factory.watchers),
+			Group:    c.TableGroup(),
+		})
+	if err != nil {
+		ctx.Stop(0)
+		return err
+	}
+
+	// Add top-of-funnel reporting.
+	labels := []string{c.factory.kind, c.target.Raw()}
+	c.mu.acceptor = types.CountingAcceptor(acc,
+		mutationsErrorCount.WithLabelValues(labels...),
+		mutationsReceivedCount.WithLabelValues(labels...),
+		mutationsSuccessCount.WithLabelValues(labels...),
+	)
+	c.mu.stopper = ctx
+
+	// Relay the status so that callers have a stable variable to
+	// observe across restarts.
+	initial, _ := stat.Get()
+	c.stat.Set(initial)
+	ctx.Go(func(ctx *stopper.Context) error {
+		_, err := stopvar.DoWhenChanged(ctx, initial, stat,
+			func(ctx *stopper.Context, _, next sequencer.Stat) error {
+				c.stat.Set(next)
+				return nil
+			})
+		return err
+	})
+	return nil
+}
+
 // updateResolved will monitor the timestamp to which tables in the
 // group have advanced and update the resolved timestamp table.
 func (c *Conveyor) updateResolved(ctx *stopper.Context) {
 	ctx.Go(func(ctx *stopper.Context) error {
 		_, err := stopvar.DoWhenChanged(ctx,
 			nil,
-			c.stat,
+			&c.stat,
 			func(ctx *stopper.Context, old, new sequencer.Stat) error {
 				oldMin := sequencer.CommonProgress(old)
 				newMin := sequencer.CommonProgress(new)
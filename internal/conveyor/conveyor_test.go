@@ -0,0 +1,74 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package conveyor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/replicator/internal/util/hlc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigPreflight(t *testing.T) {
+	r := require.New(t)
+	r.NoError((&Config{Delay: time.Hour}).Preflight())
+	r.ErrorContains((&Config{Delay: -time.Hour}).Preflight(), "negative")
+	r.ErrorContains((&Config{Delay: time.Hour, Immediate: true}).Preflight(), "immediate")
+}
+
+// TestNextBounds verifies the bounds that are presented to the
+// sequencer when the conveyor is paused or held at a time.
+func TestNextBounds(t *testing.T) {
+	r := require.New(t)
+	c := &Conveyor{factory: &Conveyors{cfg: &Config{Delay: time.Hour}}}
+	rng := hlc.RangeIncluding(hlc.New(5, 0), hlc.New(10, 0))
+
+	next, ok := c.nextBounds(rng)
+	r.True(ok)
+	r.Equal(rng, next)
+
+	c.paused.Set(true)
+	_, ok = c.nextBounds(rng)
+	r.False(ok)
+
+	// Setting a time resumes the conveyor, up to that time.
+	r.NoError(c.SetUntil(hlc.New(7, 0)))
+	r.False(c.Paused())
+	next, ok = c.nextBounds(rng)
+	r.True(ok)
+	r.Equal(hlc.RangeIncluding(hlc.New(5, 0), hlc.New(7, 0)), next)
+
+	// Hold once the time has been reached.
+	_, ok = c.nextBounds(hlc.RangeIncluding(hlc.New(7, 0), hlc.New(10, 0)))
+	r.False(ok)
+
+	// Cannot hold at a time that may have already been applied.
+	c.bounds.Set(next)
+	r.ErrorContains(c.SetUntil(hlc.New(6, 0)), "use rewind")
+
+	// Resuming clears the time.
+	c.SetPaused(false)
+	r.Equal(hlc.Zero(), c.Until())
+	next, ok = c.nextBounds(rng)
+	r.True(ok)
+	r.Equal(rng, next)
+
+	// The intentional delay is not reported as backlog.
+	r.Equal(time.Duration(0), c.backlog(time.Minute))
+	r.Equal(time.Minute, c.backlog(time.Hour+time.Minute))
+}
@@ -38,6 +38,14 @@ var (
 		Name: "source_lag_seconds",
 		Help: "the age of the most recently received checkpoint",
 	}, []string{"kind", "target"})
+	targetBacklogDuration = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "target_backlog_seconds",
+		Help: "the age of the data applied to the table, less any intentional delay",
+	}, []string{"kind", "target"})
+	targetDelayDuration = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "target_delay_seconds",
+		Help: "the intentional delay before mutations are applied to the table",
+	}, []string{"kind", "target"})
 	targetLagDuration = promauto.NewGaugeVec(prometheus.GaugeOpts{
 		Name: "target_lag_seconds",
 		Help: "the age of the data applied to the table",
@@ -18,11 +18,11 @@ package conveyor
 
 import (
 	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/replicator/internal/sequencer/pii"
 	"github.com/cockroachdb/replicator/internal/sequencer/retire"
 	"github.com/cockroachdb/replicator/internal/sequencer/script"
 	"github.com/cockroachdb/replicator/internal/sequencer/switcher"
 	"github.com/cockroachdb/replicator/internal/staging/checkpoint"
-	"github.com/cockroachdb/replicator/internal/target/apply"
 	"github.com/cockroachdb/replicator/internal/types"
 	"github.com/google/wire"
 )
@@ -33,11 +33,14 @@ var Set = wire.NewSet(ProvideConveyors)
 // ProvideConveyors is called by Wire.
 func ProvideConveyors(
 	ctx *stopper.Context,
-	acc *apply.Acceptor,
+	acc types.TableAcceptor,
 	cfg *Config,
 	checkpoints *checkpoint.Checkpoints,
+	pii *pii.PII,
 	script *script.Sequencer,
 	retire *retire.Retire,
+	stagers types.Stagers,
+	stagingPool *types.StagingPool,
 	sw *switcher.Switcher,
 	watchers types.Watchers,
 ) (*Conveyors, error) {
@@ -47,8 +50,12 @@ func ProvideConveyors(
 	return &Conveyors{
 		cfg:           cfg,
 		checkpoints:   checkpoints,
+		pii:           pii,
+		registry:      &registry{},
 		retire:        retire,
 		script:        script,
+		stagers:       stagers,
+		stagingPool:   stagingPool,
 		stopper:       ctx,
 		switcher:      sw,
 		tableAcceptor: acc,
@@ -0,0 +1,126 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package replay
+
+import (
+	"github.com/cockroachdb/replicator/internal/script"
+	"github.com/cockroachdb/replicator/internal/sequencer"
+	"github.com/cockroachdb/replicator/internal/sinkprod"
+	"github.com/cockroachdb/replicator/internal/target/dlq"
+	"github.com/cockroachdb/replicator/internal/util/hlc"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+)
+
+// Config contains the configuration necessary to replay staged
+// mutations.
+type Config struct {
+	DLQ       dlq.Config
+	Script    script.Config
+	Sequencer sequencer.Config
+	Staging   sinkprod.StagingConfig
+	Target    sinkprod.TargetConfig
+
+	// If true, only report the number of staged mutations that would
+	// be replayed.
+	DryRun bool
+	// The inclusive starting time of the mutations to replay.
+	From string
+	// An alternate schema to write the mutations into. If empty, the
+	// mutations will be written into TargetSchema.
+	IntoSchema ident.Schema
+	// The target schema whose staged mutations will be replayed.
+	TargetSchema ident.Schema
+	// The inclusive ending time of the mutations to replay.
+	To string
+
+	// Set by Preflight.
+	rng hlc.Range
+}
+
+// Bind adds flags to the set.
+func (c *Config) Bind(f *pflag.FlagSet) {
+	c.DLQ.Bind(f)
+	c.Script.Bind(f)
+	c.Sequencer.Bind(f)
+	c.Staging.Bind(f)
+	c.Target.Bind(f)
+
+	f.BoolVar(&c.DryRun, "dryRun", false,
+		"report the number of staged mutations per table without applying them")
+	f.StringVar(&c.From, "from", "",
+		"the inclusive HLC timestamp (e.g. 1700000000000000000.0000000000) to replay from; "+
+			"defaults to the oldest retained mutation")
+	f.Var(ident.NewSchemaFlag(&c.IntoSchema), "intoSchema",
+		"an alternate schema to write the replayed mutations into; defaults to targetSchema")
+	f.Var(ident.NewSchemaFlag(&c.TargetSchema), "targetSchema",
+		"the SQL database schema in the target cluster whose staged mutations will be replayed")
+	f.StringVar(&c.To, "to", "",
+		"the inclusive HLC timestamp to replay up to")
+}
+
+// Preflight updates the configuration with sane defaults or returns an
+// error if there are missing options for which a default cannot be
+// provided.
+func (c *Config) Preflight() error {
+	if err := c.DLQ.Preflight(); err != nil {
+		return err
+	}
+	if err := c.Script.Preflight(); err != nil {
+		return err
+	}
+	if err := c.Sequencer.Preflight(); err != nil {
+		return err
+	}
+	if err := c.Staging.Preflight(); err != nil {
+		return err
+	}
+	if err := c.Target.Preflight(); err != nil {
+		return err
+	}
+	if c.TargetSchema.Empty() {
+		return errors.New("no target schema specified")
+	}
+	if c.IntoSchema.Empty() {
+		c.IntoSchema = c.TargetSchema
+	}
+	return c.preflight()
+}
+
+// preflight validates the replay-specific options.
+func (c *Config) preflight() error {
+	from := hlc.Zero()
+	if c.From != "" {
+		var err error
+		if from, err = hlc.Parse(c.From); err != nil {
+			return errors.Wrap(err, "from")
+		}
+	}
+	if c.To == "" {
+		return errors.New("an ending timestamp must be specified")
+	}
+	to, err := hlc.Parse(c.To)
+	if err != nil {
+		return errors.Wrap(err, "to")
+	}
+	if hlc.Compare(from, to) > 0 {
+		return errors.Errorf("from (%s) must not be after to (%s)", from, to)
+	}
+	c.rng = hlc.RangeIncluding(from, to)
+	return nil
+}
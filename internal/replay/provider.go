@@ -0,0 +1,48 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package replay
+
+import (
+	"github.com/cockroachdb/replicator/internal/sequencer/script"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/google/wire"
+)
+
+// Set is used by Wire.
+var Set = wire.NewSet(ProvideReplay)
+
+// ProvideReplay is called by Wire.
+func ProvideReplay(
+	cfg *Config,
+	script *script.Sequencer,
+	stagers types.Stagers,
+	tableAcceptor types.TableAcceptor,
+	targetPool *types.TargetPool,
+	watchers types.Watchers,
+) (*Replay, error) {
+	if err := cfg.Preflight(); err != nil {
+		return nil, err
+	}
+	return &Replay{
+		cfg:           cfg,
+		script:        script,
+		stagers:       stagers,
+		tableAcceptor: tableAcceptor,
+		targetPool:    targetPool,
+		watchers:      watchers,
+	}, nil
+}
@@ -0,0 +1,298 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package replay re-applies mutations which have been retained in the
+// staging tables.
+package replay
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/cockroachdb/field-eng-powertools/notify"
+	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/replicator/internal/sequencer"
+	"github.com/cockroachdb/replicator/internal/sequencer/script"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/hlc"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// Replay re-emits staged mutations within a range of timestamps
+// through the target-side sequencer stack (i.e. userscript table
+// configuration and dependency-ordered application). The mutations
+// may be written into the original target schema, to repair it, or
+// into an alternate schema, to seed a new target.
+//
+// Only mutations which have not yet been removed by the retire
+// process are available. Staged mutations are not marked as applied
+// by a replay and no checkpoints are modified, so a replay can run
+// alongside ongoing replication.
+type Replay struct {
+	cfg           *Config
+	script        *script.Sequencer
+	stagers       types.Stagers
+	tableAcceptor types.TableAcceptor
+	targetPool    *types.TargetPool
+	watchers      types.Watchers
+}
+
+// Report summarizes the mutations that were replayed.
+type Report struct {
+	Counts *ident.TableMap[int] // Mutations per table.
+	DryRun bool                 // True if nothing was applied.
+	Into   ident.Schema         // The schema that was written to.
+	Range  hlc.Range            // The range of timestamps.
+}
+
+// Write prints the report in a tabular format.
+func (r *Report) Write(out io.Writer) error {
+	var tables []ident.Table
+	for table := range r.Counts.Keys() {
+		tables = append(tables, table)
+	}
+	sort.Slice(tables, func(i, j int) bool {
+		return tables[i].Raw() < tables[j].Raw()
+	})
+
+	verb := "replayed"
+	if r.DryRun {
+		verb = "would replay"
+	}
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	if _, err := fmt.Fprintf(w, "TABLE\tMUTATIONS\n"); err != nil {
+		return errors.WithStack(err)
+	}
+	total := 0
+	for _, table := range tables {
+		count := r.Counts.GetZero(table)
+		total += count
+		if _, err := fmt.Fprintf(w, "%s\t%d\n", table.Raw(), count); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return errors.WithStack(err)
+	}
+	_, err := fmt.Fprintf(out, "%s %d mutations in %s into %s\n", verb, total, r.Range, r.Into)
+	return errors.WithStack(err)
+}
+
+// Run performs the replay and blocks until it has completed.
+func (r *Replay) Run(ctx *stopper.Context) (*Report, error) {
+	w, err := r.watchers.Get(r.cfg.TargetSchema)
+	if err != nil {
+		return nil, err
+	}
+	var tables []ident.Table
+	for table := range w.Get().Columns.Keys() {
+		tables = append(tables, table)
+	}
+	if len(tables) == 0 {
+		return nil, errors.Errorf("no tables found in %s", r.cfg.TargetSchema)
+	}
+	group := &types.TableGroup{
+		Enclosing: r.cfg.TargetSchema,
+		Name:      ident.New(r.cfg.TargetSchema.Raw()),
+		Tables:    tables,
+	}
+
+	// The bounds never change.
+	bounds := notify.VarOf(r.cfg.rng)
+	reader, err := r.stagers.Query(ctx, &types.StagingQuery{
+		Bounds:         bounds,
+		FragmentSize:   r.cfg.Sequencer.ScanSize,
+		Group:          group,
+		IncludeApplied: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Run the replay in a nested context, so that the reader will be
+	// stopped once we're done.
+	sub := stopper.WithContext(ctx)
+	defer sub.Stop(r.cfg.Sequencer.TaskGracePeriod)
+
+	replayer := &replayer{
+		counts:     &ident.TableMap[int]{},
+		dryRun:     r.cfg.DryRun,
+		done:       make(chan error, 1),
+		targetPool: r.targetPool,
+	}
+	seq := sequencer.Sequencer(replayer)
+	if !r.cfg.DryRun {
+		seq, err = r.script.Wrap(sub, seq)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var delegate types.TableAcceptor = r.tableAcceptor
+	if !ident.Equal(r.cfg.IntoSchema, r.cfg.TargetSchema) {
+		delegate = &renamer{delegate, r.cfg.IntoSchema}
+	}
+	if _, _, err := seq.Start(sub, &sequencer.StartOptions{
+		BatchReader: reader,
+		Bounds:      bounds,
+		Delegate:    types.OrderedAcceptorFrom(delegate, r.watchers),
+		Group:       group,
+	}); err != nil {
+		return nil, err
+	}
+
+	select {
+	case err := <-replayer.done:
+		if err != nil {
+			return nil, err
+		}
+	case <-ctx.Stopping():
+		return nil, stopper.ErrStopped
+	}
+	return &Report{
+		Counts: replayer.counts,
+		DryRun: r.cfg.DryRun,
+		Into:   r.cfg.IntoSchema,
+		Range:  r.cfg.rng,
+	}, nil
+}
+
+// renamer writes mutations into an alternate schema.
+type renamer struct {
+	delegate types.TableAcceptor
+	into     ident.Schema
+}
+
+var _ types.TableAcceptor = (*renamer)(nil)
+
+// AcceptTableBatch implements [types.TableAcceptor].
+func (r *renamer) AcceptTableBatch(
+	ctx context.Context, batch *types.TableBatch, opts *types.AcceptOptions,
+) error {
+	return r.delegate.AcceptTableBatch(ctx, &types.TableBatch{
+		Data:  batch.Data,
+		Table: ident.NewTable(r.into, batch.Table.Table()),
+		Time:  batch.Time,
+	}, opts)
+}
+
+// replayer is a minimal [sequencer.Sequencer] which serially applies
+// the data from its BatchReader in time order. Unlike the core
+// sequencer, it does not acquire leases on the tables.
+type replayer struct {
+	counts     *ident.TableMap[int] // Only accessed by the run loop.
+	dryRun     bool                 // Count, but don't apply.
+	done       chan error           // Receives the outcome.
+	targetPool *types.TargetPool
+}
+
+var _ sequencer.Sequencer = (*replayer)(nil)
+
+// Start implements [sequencer.Sequencer].
+func (r *replayer) Start(
+	ctx *stopper.Context, opts *sequencer.StartOptions,
+) (types.MultiAcceptor, *notify.Var[sequencer.Stat], error) {
+	if opts.BatchReader == nil {
+		return nil, nil, errors.New("no BatchReader provided")
+	}
+	cursors, err := opts.BatchReader.Read(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	bounds, _ := opts.Bounds.Get()
+	stat := notify.VarOf(sequencer.NewStat(opts.Group, &ident.TableMap[hlc.Range]{}))
+
+	ctx.Go(func(ctx *stopper.Context) error {
+		r.done <- r.run(ctx, cursors, bounds, opts)
+		return nil
+	})
+	return opts.Delegate, stat, nil
+}
+
+// run consumes the cursors until the end of the bounds.
+func (r *replayer) run(
+	ctx *stopper.Context,
+	cursors <-chan *types.BatchCursor,
+	bounds hlc.Range,
+	opts *sequencer.StartOptions,
+) error {
+	// Fragmented batches are accumulated until complete.
+	pending := &types.MultiBatch{}
+	for {
+		var cursor *types.BatchCursor
+		var open bool
+		select {
+		case cursor, open = <-cursors:
+			if !open {
+				return errors.New("staging reader closed unexpectedly")
+			}
+		case <-ctx.Stopping():
+			return stopper.ErrStopped
+		}
+		if cursor.Error != nil {
+			return cursor.Error
+		}
+		if cursor.Batch != nil {
+			for table, mut := range cursor.Batch.Mutations() {
+				if err := pending.Accumulate(table, mut); err != nil {
+					return err
+				}
+			}
+			if cursor.Fragment {
+				continue
+			}
+			if err := r.apply(ctx, pending, opts.Delegate); err != nil {
+				return err
+			}
+			pending = &types.MultiBatch{}
+		}
+		if pending.Count() == 0 && hlc.Compare(cursor.Progress.Max(), bounds.Max()) >= 0 {
+			return nil
+		}
+	}
+}
+
+// apply counts the mutations and writes them to the target within a
+// single transaction.
+func (r *replayer) apply(
+	ctx context.Context, batch *types.MultiBatch, acc types.MultiAcceptor,
+) error {
+	for table := range batch.Mutations() {
+		r.counts.Put(table, r.counts.GetZero(table)+1)
+	}
+	if r.dryRun {
+		return nil
+	}
+	tx, err := r.targetPool.BeginConnTx(ctx, nil)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer func() { _ = tx.Rollback() }()
+	if err := acc.AcceptMultiBatch(ctx, batch, &types.AcceptOptions{
+		TargetQuerier: tx,
+	}); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return errors.WithStack(err)
+	}
+	log.Tracef("replayed %d mutations", batch.Count())
+	return nil
+}
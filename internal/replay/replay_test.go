@@ -0,0 +1,196 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package replay
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/field-eng-powertools/notify"
+	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/replicator/internal/sequencer"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/hlc"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigPreflight(t *testing.T) {
+	tcs := []struct {
+		from, to string
+		expected hlc.Range
+		err      string
+	}{
+		{
+			to:       "10.0",
+			expected: hlc.RangeIncluding(hlc.Zero(), hlc.New(10, 0)),
+		},
+		{
+			from:     "5.0000000001",
+			to:       "10.0",
+			expected: hlc.RangeIncluding(hlc.New(5, 1), hlc.New(10, 0)),
+		},
+		{
+			from: "5.0000000001",
+			err:  "an ending timestamp must be specified",
+		},
+		{
+			from: "10.0",
+			to:   "5.0",
+			err:  "must not be after",
+		},
+		{
+			to:  "garbage",
+			err: "to",
+		},
+	}
+
+	for idx, tc := range tcs {
+		t.Run(fmt.Sprintf("%d", idx), func(t *testing.T) {
+			r := require.New(t)
+			cfg := &Config{From: tc.from, To: tc.to}
+			err := cfg.preflight()
+			if tc.err != "" {
+				r.ErrorContains(err, tc.err)
+				return
+			}
+			r.NoError(err)
+			r.Equal(tc.expected, cfg.rng)
+		})
+	}
+}
+
+func TestReplayerDryRun(t *testing.T) {
+	r := require.New(t)
+	ctx := stopper.WithContext(context.Background())
+	defer ctx.Stop(0)
+
+	schema := ident.MustSchema(ident.New("db"), ident.New("public"))
+	tblA := ident.NewTable(schema, ident.New("a"))
+	tblB := ident.NewTable(schema, ident.New("b"))
+
+	batchAt := func(nanos int64, tables ...ident.Table) *types.TemporalBatch {
+		ts := hlc.New(nanos, 0)
+		ret := &types.TemporalBatch{Time: ts}
+		for idx, tbl := range tables {
+			r.NoError(ret.Accumulate(tbl, types.Mutation{
+				Data: json.RawMessage(`{}`),
+				Key:  json.RawMessage(fmt.Sprintf(`[%d]`, idx)),
+				Time: ts,
+			}))
+		}
+		return ret
+	}
+
+	bounds := hlc.RangeIncluding(hlc.Zero(), hlc.New(10, 0))
+	cursors := make(chan *types.BatchCursor, 4)
+	// The first batch is fragmented and must be accumulated.
+	cursors <- &types.BatchCursor{
+		Batch:    batchAt(1, tblA),
+		Fragment: true,
+		Progress: hlc.RangeIncluding(hlc.Zero(), hlc.New(1, 0)),
+	}
+	cursors <- &types.BatchCursor{
+		Batch:    batchAt(1, tblB),
+		Progress: hlc.RangeIncluding(hlc.Zero(), hlc.New(1, 0)),
+	}
+	cursors <- &types.BatchCursor{
+		Batch:    batchAt(5, tblA, tblB),
+		Progress: hlc.RangeIncluding(hlc.Zero(), hlc.New(5, 0)),
+	}
+	// Progress-only update to the end of the bounds.
+	cursors <- &types.BatchCursor{
+		Progress: bounds,
+	}
+
+	rep := &replayer{
+		counts: &ident.TableMap[int]{},
+		dryRun: true,
+		done:   make(chan error, 1),
+	}
+	_, _, err := rep.Start(ctx, &sequencer.StartOptions{
+		BatchReader: &fakeReader{cursors},
+		Bounds:      notify.VarOf(bounds),
+		Group: &types.TableGroup{
+			Enclosing: schema,
+			Name:      ident.New("test"),
+			Tables:    []ident.Table{tblA, tblB},
+		},
+	})
+	r.NoError(err)
+
+	select {
+	case err := <-rep.done:
+		r.NoError(err)
+	case <-time.After(time.Minute):
+		r.FailNow("timed out")
+	}
+	r.Equal(2, rep.counts.GetZero(tblA))
+	r.Equal(2, rep.counts.GetZero(tblB))
+
+	var buf bytes.Buffer
+	r.NoError((&Report{
+		Counts: rep.counts,
+		DryRun: true,
+		Into:   schema,
+		Range:  bounds,
+	}).Write(&buf))
+	r.Contains(buf.String(), "would replay 4 mutations")
+}
+
+func TestRenamer(t *testing.T) {
+	r := require.New(t)
+
+	from := ident.MustSchema(ident.New("db"), ident.New("public"))
+	into := ident.MustSchema(ident.New("other"), ident.New("public"))
+	tbl := ident.NewTable(from, ident.New("tbl"))
+
+	var seen []ident.Table
+	acc := &renamer{
+		delegate: &recorder{func(batch *types.TableBatch) {
+			seen = append(seen, batch.Table)
+		}},
+		into: into,
+	}
+	r.NoError(acc.AcceptTableBatch(context.Background(),
+		&types.TableBatch{Table: tbl}, &types.AcceptOptions{}))
+	r.Len(seen, 1)
+	r.True(ident.Equal(ident.NewTable(into, ident.New("tbl")), seen[0]))
+}
+
+type fakeReader struct {
+	ch <-chan *types.BatchCursor
+}
+
+func (r *fakeReader) Read(*stopper.Context) (<-chan *types.BatchCursor, error) {
+	return r.ch, nil
+}
+
+type recorder struct {
+	fn func(*types.TableBatch)
+}
+
+func (r *recorder) AcceptTableBatch(
+	_ context.Context, batch *types.TableBatch, _ *types.AcceptOptions,
+) error {
+	r.fn(batch)
+	return nil
+}
@@ -20,7 +20,12 @@ import (
 	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
 
+	"github.com/cockroachdb/replicator/internal/util/applycfg"
+	"github.com/cockroachdb/replicator/internal/util/filter"
+	"github.com/cockroachdb/replicator/internal/util/transform"
+	"github.com/pkg/errors"
 	"github.com/spf13/pflag"
 )
 
@@ -30,6 +35,30 @@ type Config struct {
 	MainPath string  // A path, relative to FS that holds the entrypoint.
 	Options  Options // The target for calls to api.setOptions().
 
+	// Row-filtering predicates to apply in addition to those
+	// configured by the userscript, keyed by table name. This is
+	// populated by Preflight.
+	Filters map[string]*filter.Filter
+	// Discard deletions that cannot be evaluated by Filters.
+	FilterDropUnknownDeletes bool
+	// Filters in table=predicate form. This will be cleared after
+	// Preflight has been called.
+	FilterSpecs []string
+
+	// Sequencing modes to use in addition to those configured by the
+	// userscript, keyed by table name. This is populated by Preflight.
+	Modes map[string]applycfg.Mode
+	// Modes in table=mode form. This will be cleared after Preflight
+	// has been called.
+	ModeSpecs []string
+
+	// Column transforms to apply in addition to those configured by
+	// the userscript. This is populated by Preflight.
+	Transforms transform.File
+	// The path to a YAML file of column transforms. This will be
+	// cleared after Preflight has been called.
+	TransformsPath string
+
 	// An external filesystem path. This will be cleared after Preflight
 	// has been called. This symbol is exported for testing.
 	UserScriptPath string
@@ -42,9 +71,20 @@ func (c *Config) Bind(f *pflag.FlagSet) {
 	}
 	f.StringVar(&c.UserScriptPath, "userscript", "",
 		"the path to a configuration script, see userscript subcommand")
+	f.StringArrayVar(&c.FilterSpecs, "filter", nil,
+		"a row filter of the form table=predicate, e.g. \"orders=region = 'us'\"; may be repeated")
+	f.BoolVar(&c.FilterDropUnknownDeletes, "filterDropUnknownDeletes", false,
+		"discard filtered deletes which do not include the prior state of the row")
+	f.StringArrayVar(&c.ModeSpecs, "tableMode", nil,
+		"a sequencing mode of the form table=mode, where mode is one of auto, besteffort, "+
+			"consistent, or immediate; applies to all tables related by foreign keys; may be repeated")
+	f.StringVar(&c.TransformsPath, "transforms", "",
+		"the path to a YAML file of per-column transforms")
 }
 
-// Preflight will set FS and MainPath, if UserScriptPath is set.
+// Preflight will set FS and MainPath, if UserScriptPath is set. It
+// will also load Transforms, if TransformsPath is set, and parse
+// FilterSpecs and ModeSpecs into Filters and Modes.
 func (c *Config) Preflight() error {
 	if c.UserScriptPath != "" {
 		path, err := filepath.Abs(c.UserScriptPath)
@@ -58,5 +98,49 @@ func (c *Config) Preflight() error {
 		c.UserScriptPath = ""
 	}
 
+	for _, spec := range c.FilterSpecs {
+		table, expr, ok := strings.Cut(spec, "=")
+		table, expr = strings.TrimSpace(table), strings.TrimSpace(expr)
+		if !ok || table == "" || expr == "" {
+			return errors.Errorf("filter %q must be of the form table=predicate", spec)
+		}
+		pred, err := filter.Parse(expr)
+		if err != nil {
+			return errors.Wrap(err, table)
+		}
+		pred.DropUnknownDeletes = c.FilterDropUnknownDeletes
+		if c.Filters == nil {
+			c.Filters = make(map[string]*filter.Filter)
+		}
+		c.Filters[table] = pred
+	}
+	c.FilterSpecs = nil
+
+	for _, spec := range c.ModeSpecs {
+		table, name, ok := strings.Cut(spec, "=")
+		table = strings.TrimSpace(table)
+		if !ok || table == "" {
+			return errors.Errorf("table mode %q must be of the form table=mode", spec)
+		}
+		mode, err := applycfg.ParseMode(name)
+		if err != nil {
+			return errors.Wrap(err, table)
+		}
+		if c.Modes == nil {
+			c.Modes = make(map[string]applycfg.Mode)
+		}
+		c.Modes[table] = mode
+	}
+	c.ModeSpecs = nil
+
+	if c.TransformsPath != "" {
+		xf, err := transform.ReadFile(c.TransformsPath)
+		if err != nil {
+			return errors.Wrap(err, c.TransformsPath)
+		}
+		c.Transforms = xf
+		c.TransformsPath = ""
+	}
+
 	return nil
 }
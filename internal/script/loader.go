@@ -32,7 +32,9 @@ import (
 	"github.com/cockroachdb/replicator/internal/util/applycfg"
 	"github.com/cockroachdb/replicator/internal/util/crep"
 	"github.com/cockroachdb/replicator/internal/util/diag"
+	"github.com/cockroachdb/replicator/internal/util/filter"
 	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/cockroachdb/replicator/internal/util/transform"
 	"github.com/dop251/goja"
 	esbuild "github.com/evanw/esbuild/pkg/api"
 	"github.com/pkg/errors"
@@ -102,8 +104,11 @@ var (
 type sourceJS struct {
 	DeletesTo goja.Value `goja:"deletesTo"` // A deletesToJS or a string.
 	Dispatch  dispatchJS `goja:"dispatch"`
-	Recurse   bool       `goja:"recurse"`
-	Target    string     `goja:"target"`
+	// A row-filtering predicate.
+	Filter                   string `goja:"filter"`
+	FilterDropUnknownDeletes bool   `goja:"filterDropUnknownDeletes"`
+	Recurse                  bool   `goja:"recurse"`
+	Target                   string `goja:"target"`
 }
 
 // targetJS is used in the API binding. The apply.Config.SourceNames
@@ -112,6 +117,8 @@ type sourceJS struct {
 type targetJS struct {
 	// Override built-in apply behavior.
 	Apply applyJS `goja:"apply"`
+	// The minimum number of rows in a batch to use a bulk-load path.
+	BulkThreshold int `goja:"bulkThreshold"`
 	// Column names.
 	CASColumns []string `goja:"cas"`
 	// Column to duration.
@@ -122,6 +129,12 @@ type targetJS struct {
 	Exprs map[string]string `goja:"exprs"`
 	// Column name.
 	Extras string `goja:"extras"`
+	// A row-filtering predicate.
+	Filter                   string `goja:"filter"`
+	FilterDropUnknownDeletes bool   `goja:"filterDropUnknownDeletes"`
+	// Column names, enables append-only history.
+	HistoryOp   string `goja:"historyOp"`
+	HistoryTime string `goja:"historyTime"`
 	// Column names.
 	Ignore map[string]bool `goja:"ignore"`
 	// Mutation to mutation.
@@ -129,9 +142,36 @@ type targetJS struct {
 	// Two- or three-way merge operator. The bindMerge method will
 	// validate the type of value.
 	Merge goja.Value `goja:"merge"`
+	// Sequencing mode name.
+	Mode string `goja:"mode"`
 	// For targets without a bulk-transfer mechanism, the maximum number
 	// of rows to send in a single statement.
 	RowLimit int `goja:"rowLimit"`
+	// Column names, enables SCD Type 2 versioning.
+	SCDCurrent   string `goja:"scdCurrent"`
+	SCDValidFrom string `goja:"scdValidFrom"`
+	SCDValidTo   string `goja:"scdValidTo"`
+	// Column names, enables soft deletes.
+	SoftDelete     string `goja:"softDelete"`
+	SoftDeleteTime string `goja:"softDeleteTime"`
+	// Column to declarative transforms.
+	Transforms map[string][]*transformJS `goja:"transforms"`
+}
+
+// transformJS is used in the API binding and is converted into a
+// [transform.Op].
+type transformJS struct {
+	Algorithm   string `goja:"algorithm"`
+	Char        string `goja:"char"`
+	Keep        int    `goja:"keep"`
+	Length      int    `goja:"length"`
+	Op          string `goja:"op"`
+	Pattern     string `goja:"pattern"`
+	Replacement string `goja:"replacement"`
+	Secret      string `goja:"secret"`
+	To          string `goja:"to"`
+	Type        string `goja:"type"`
+	Value       any    `goja:"value"`
 }
 
 // Loader is responsible for the first-pass execution of the user
@@ -142,18 +182,22 @@ type targetJS struct {
 // target schema, call [Loader.Bind] to return a [UserScript] that
 // operates within the given target schema.
 type Loader struct {
-	apiModule    *goja.Object          // The imported replicator module.
-	applyConfigs *applycfg.Configs     // Injected.
-	diags        *diag.Diagnostics     // Injected.
-	fs           fs.FS                 // Used by require.
-	options      Options               // Target of api.setOptions().
-	requireStack []*url.URL            // Allows relative import paths.
-	requireCache map[string]goja.Value // Keys are URLs.
-	rt           *goja.Runtime         // JS Runtime.
-	rtMu         *sync.RWMutex         // Serialize access to the VM.
-	sources      map[string]*sourceJS  // User configuration.
-	targets      map[string]*targetJS  // User configuration.
-	tasks        *workgroup.Group      // Limit concurrency of JS background tasks.
+	apiModule    *goja.Object              // The imported replicator module.
+	applyConfigs *applycfg.Configs         // Injected.
+	diags        *diag.Diagnostics         // Injected.
+	filters      map[string]*filter.Filter // Row filters from flags.
+	modes        map[string]applycfg.Mode  // Sequencing modes from flags.
+	fs           fs.FS                     // Used by require.
+	options      Options                   // Target of api.setOptions().
+	requireStack []*url.URL                // Allows relative import paths.
+	requireCache map[string]goja.Value     // Keys are URLs.
+	rt           *goja.Runtime             // JS Runtime.
+	rtMu         *sync.RWMutex             // Serialize access to the VM.
+	schemaChange schemaChangeJS            // Target of api.onSchemaChange().
+	sources      map[string]*sourceJS      // User configuration.
+	targets      map[string]*targetJS      // User configuration.
+	tasks        *workgroup.Group          // Limit concurrency of JS background tasks.
+	transforms   transform.File            // Column transforms from a file.
 }
 
 // Bind resolves the various table names used in the script file to the
@@ -168,15 +212,20 @@ func (l *Loader) Bind(
 	targetAcceptor types.TableAcceptor,
 	watchers types.Watchers,
 ) (*UserScript, error) {
+	sch := target.Schema()
+
 	// In the unconfigured case, return an unconfigured script.
 	if l.fs == nil {
-		return &UserScript{
+		ret := &UserScript{
 			Sources: &ident.Map[*Source]{},
 			Targets: &ident.TableMap[*Target]{},
-		}, nil
+		}
+		if err := l.bindStatic(sch, ret.Targets); err != nil {
+			return nil, err
+		}
+		return ret, nil
 	}
 
-	sch := target.Schema()
 	watcher, err := watchers.Get(sch)
 	if err != nil {
 		return nil, err
@@ -211,6 +260,10 @@ func (l *Loader) Bind(
 		l.diags.Unregister(diagName)
 	})
 
+	if err := l.bindStatic(sch, ret.Targets); err != nil {
+		return nil, err
+	}
+
 	for tbl, tblCfg := range ret.Targets.All() {
 		if err := l.applyConfigs.Set(tbl, &tblCfg.Config); err != nil {
 			return nil, errors.Wrap(err, tbl.Raw())
@@ -220,6 +273,61 @@ func (l *Loader) Bind(
 	return ret, err
 }
 
+// bindStatic resolves the table names used by flag- or file-based
+// configuration against the target schema. For tables that are
+// configured by the userscript, the static configuration is patched
+// into the script's configuration, replacing the values for the same
+// columns or options. Other tables receive a configuration that
+// contains only the static values.
+func (l *Loader) bindStatic(sch ident.Schema, targets *ident.TableMap[*Target]) error {
+	static := &ident.TableMap[*applycfg.Config]{}
+	resolve := func(tableName string) (*applycfg.Config, error) {
+		table, _, err := ident.ParseTableRelative(tableName, sch)
+		if err != nil {
+			return nil, errors.Wrapf(err, "configuration for %q", tableName)
+		}
+		cfg, ok := static.Get(table)
+		if !ok {
+			cfg = applycfg.NewConfig()
+			static.Put(table, cfg)
+		}
+		return cfg, nil
+	}
+
+	for tableName, pred := range l.filters {
+		cfg, err := resolve(tableName)
+		if err != nil {
+			return err
+		}
+		cfg.Filter = pred
+	}
+	for tableName, mode := range l.modes {
+		cfg, err := resolve(tableName)
+		if err != nil {
+			return err
+		}
+		cfg.Mode = mode
+	}
+	for tableName := range l.transforms {
+		cfg, err := resolve(tableName)
+		if err != nil {
+			return err
+		}
+		l.transforms.Table(tableName).CopyInto(cfg.Transforms)
+	}
+
+	for table, cfg := range static.All() {
+		if tgt, ok := targets.Get(table); ok {
+			tgt.Config.Patch(cfg)
+			continue
+		}
+		if err := l.applyConfigs.Set(table, cfg); err != nil {
+			return errors.Wrap(err, table.Raw())
+		}
+	}
+	return nil
+}
+
 // configureSource is exported to the JS runtime.
 func (l *Loader) configureSource(sourceName string, bag *sourceJS) error {
 	if (bag.Dispatch != nil) == (bag.Target != "") {
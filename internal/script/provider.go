@@ -49,7 +49,12 @@ func ProvideLoader(
 
 	// Return an empty version if unconfigured.
 	if cfg.FS == nil {
-		return &Loader{}, nil
+		return &Loader{
+			applyConfigs: applyConfigs,
+			filters:      cfg.Filters,
+			modes:        cfg.Modes,
+			transforms:   cfg.Transforms,
+		}, nil
 	}
 
 	options := cfg.Options
@@ -60,7 +65,9 @@ func ProvideLoader(
 	l := &Loader{
 		applyConfigs: applyConfigs,
 		diags:        diags,
+		filters:      cfg.Filters,
 		fs:           cfg.FS,
+		modes:        cfg.Modes,
 		options:      options,
 		requireCache: make(map[string]goja.Value),
 		rt:           goja.New(),
@@ -68,6 +75,7 @@ func ProvideLoader(
 		sources:      make(map[string]*sourceJS),
 		targets:      make(map[string]*targetJS),
 		tasks:        workgroup.WithSize(ctx, 2*runtime.GOMAXPROCS(0), 100_000),
+		transforms:   cfg.Transforms,
 	}
 
 	// Use a "goja" tag on struct fields to control name bindings.
@@ -100,6 +108,9 @@ func ProvideLoader(
 	if err := apiModule.Set("getTX", notInTransaction); err != nil {
 		return nil, err
 	}
+	if err := apiModule.Set("onSchemaChange", l.onSchemaChange); err != nil {
+		return nil, err
+	}
 	if err := apiModule.Set("randomUUID", randomUUID); err != nil {
 		return nil, err
 	}
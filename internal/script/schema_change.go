@@ -0,0 +1,65 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package script
+
+import (
+	"context"
+
+	"github.com/dop251/goja"
+)
+
+// A SchemaChange describes a DDL statement that was observed in a
+// source database. It is passed to a user-provided OnSchemaChange
+// callback.
+type SchemaChange struct {
+	Kind      string         `goja:"kind" json:"kind"`           // E.g. "ALTER TABLE".
+	Meta      map[string]any `goja:"meta" json:"meta,omitempty"` // Source-specific metadata.
+	Schema    string         `goja:"schema" json:"schema"`       // The target schema.
+	Statement string         `goja:"statement" json:"statement"` // The source DDL.
+	Tables    []string       `goja:"tables" json:"tables"`       // Affected target tables.
+}
+
+// OnSchemaChange is a user-provided callback that is invoked when a
+// source reports a schema change. It returns zero or more SQL
+// statements to execute in the target database. OnSchemaChange
+// functions are internally synchronized to ensure single-threaded
+// access to the underlying JS VM.
+type OnSchemaChange func(ctx context.Context, change *SchemaChange) ([]string, error)
+
+// A JS function that receives a schema change and returns an optional
+// array of SQL statements.
+//
+//	{ change } => [ "ALTER TABLE ...", ... ]
+type schemaChangeJS func(change *SchemaChange) ([]string, error)
+
+// onSchemaChange is exported to the JS runtime.
+func (l *Loader) onSchemaChange(fn schemaChangeJS) {
+	l.schemaChange = fn
+}
+
+// bindSchemaChange exports a user-provided function as an
+// OnSchemaChange.
+func (s *UserScript) bindSchemaChange(fn schemaChangeJS) OnSchemaChange {
+	return func(_ context.Context, change *SchemaChange) ([]string, error) {
+		var ret []string
+		err := s.execJS(func(*goja.Runtime) (err error) {
+			ret, err = fn(change)
+			return err
+		})
+		return ret, err
+	}
+}
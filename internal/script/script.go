@@ -30,8 +30,10 @@ import (
 	"github.com/cockroachdb/replicator/internal/util/applycfg"
 	"github.com/cockroachdb/replicator/internal/util/crep"
 	"github.com/cockroachdb/replicator/internal/util/diag"
+	"github.com/cockroachdb/replicator/internal/util/filter"
 	"github.com/cockroachdb/replicator/internal/util/ident"
 	"github.com/cockroachdb/replicator/internal/util/merge"
+	"github.com/cockroachdb/replicator/internal/util/transform"
 	"github.com/dop251/goja"
 	"github.com/pkg/errors"
 )
@@ -101,6 +103,8 @@ type Source struct {
 	// A user-provided function that routes mutations to zero or more
 	// tables.
 	Dispatch Dispatch `json:"-"`
+	// Discard source mutations which do not match a predicate.
+	Filter *filter.Filter
 	// Enable recursion in sources which support nested sources.
 	Recurse bool
 }
@@ -137,8 +141,11 @@ var errUseExec = errors.New("UserScript.execJS() must be used when calling JS co
 // instance of the userscript would then have distinct global variables.
 type UserScript struct {
 	Delegate types.TableAcceptor
-	Sources  *ident.Map[*Source]
-	Targets  *ident.TableMap[*Target]
+	// OnSchemaChange will be nil if the script has not called
+	// api.onSchemaChange().
+	OnSchemaChange OnSchemaChange
+	Sources        *ident.Map[*Source]
+	Targets        *ident.TableMap[*Target]
 
 	apiModule *goja.Object         // The Replicator JS module.
 	rt        *goja.Runtime        // The JavaScript VM. See execJS.
@@ -251,6 +258,19 @@ func (s *UserScript) bind(loader *Loader) error {
 		default:
 			return errors.Errorf("configureSource(%q): dispatch or target required", sourceName)
 		}
+
+		if bag.Filter != "" {
+			pred, err := filter.Parse(bag.Filter)
+			if err != nil {
+				return errors.Wrapf(err, "configureSource(%q)", sourceName)
+			}
+			pred.DropUnknownDeletes = bag.FilterDropUnknownDeletes
+			src.Filter = pred
+		}
+	}
+
+	if loader.schemaChange != nil {
+		s.OnSchemaChange = s.bindSchemaChange(loader.schemaChange)
 	}
 
 	// Evaluate calls to api.configureTarget(). As above, we implement a
@@ -287,11 +307,30 @@ func (s *UserScript) bind(loader *Loader) error {
 		if bag.Extras != "" {
 			tgt.Extras = ident.New(bag.Extras)
 		}
+		if bag.Filter != "" {
+			tgt.Filter, err = filter.Parse(bag.Filter)
+			if err != nil {
+				return errors.Wrapf(err, "configureTable(%q)", tableName)
+			}
+			tgt.Filter.DropUnknownDeletes = bag.FilterDropUnknownDeletes
+		}
+		if bag.HistoryOp != "" {
+			tgt.HistoryOp = ident.New(bag.HistoryOp)
+		}
+		if bag.HistoryTime != "" {
+			tgt.HistoryTime = ident.New(bag.HistoryTime)
+		}
 		if bag.Map == nil {
 			tgt.Map = identity
 		} else {
 			tgt.Map = s.bindMap(table, bag.Map)
 		}
+		if bag.Mode != "" {
+			tgt.Mode, err = applycfg.ParseMode(bag.Mode)
+			if err != nil {
+				return errors.Wrapf(err, "configureTable(%q)", tableName)
+			}
+		}
 		if bag.Merge != nil {
 			tgt.Merger, err = s.bindMerge(table, bag.Merge)
 			if err != nil {
@@ -303,7 +342,45 @@ func (s *UserScript) bind(loader *Loader) error {
 				tgt.Ignore.Put(ident.New(k), true)
 			}
 		}
+		tgt.BulkThreshold = bag.BulkThreshold
 		tgt.RowLimit = bag.RowLimit
+		if bag.SCDCurrent != "" {
+			tgt.SCDCurrent = ident.New(bag.SCDCurrent)
+		}
+		if bag.SCDValidFrom != "" {
+			tgt.SCDValidFrom = ident.New(bag.SCDValidFrom)
+		}
+		if bag.SCDValidTo != "" {
+			tgt.SCDValidTo = ident.New(bag.SCDValidTo)
+		}
+		if bag.SoftDelete != "" {
+			tgt.SoftDelete = ident.New(bag.SoftDelete)
+		}
+		if bag.SoftDeleteTime != "" {
+			tgt.SoftDeleteTime = ident.New(bag.SoftDeleteTime)
+		}
+		for col, ops := range bag.Transforms {
+			pipeline := make(transform.Pipeline, len(ops))
+			for idx, op := range ops {
+				pipeline[idx] = &transform.Op{
+					Kind:        transform.Kind(op.Op),
+					Algorithm:   op.Algorithm,
+					Char:        op.Char,
+					Keep:        op.Keep,
+					Length:      op.Length,
+					Pattern:     op.Pattern,
+					Replacement: op.Replacement,
+					Secret:      op.Secret,
+					To:          op.To,
+					Type:        op.Type,
+					Value:       op.Value,
+				}
+			}
+			if err := pipeline.Compile(); err != nil {
+				return errors.Wrapf(err, "configureTable(%q): %s", tableName, col)
+			}
+			tgt.Transforms.Put(ident.New(col), pipeline)
+		}
 	}
 
 	return nil
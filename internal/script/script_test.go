@@ -20,18 +20,24 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/cockroachdb/field-eng-powertools/stopper"
 	"github.com/cockroachdb/replicator/internal/sinktest"
 	"github.com/cockroachdb/replicator/internal/sinktest/all"
 	"github.com/cockroachdb/replicator/internal/sinktest/base"
 	"github.com/cockroachdb/replicator/internal/target/apply"
 	"github.com/cockroachdb/replicator/internal/types"
 	"github.com/cockroachdb/replicator/internal/util/applycfg"
+	"github.com/cockroachdb/replicator/internal/util/diag"
+	"github.com/cockroachdb/replicator/internal/util/filter"
 	"github.com/cockroachdb/replicator/internal/util/hlc"
 	"github.com/cockroachdb/replicator/internal/util/ident"
 	"github.com/cockroachdb/replicator/internal/util/merge"
+	"github.com/cockroachdb/replicator/internal/util/transform"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -119,6 +125,26 @@ CREATE TABLE %s.skewed_merge_times(
 	tbl1 := ident.NewTable(schema, ident.New("table1"))
 	tbl2 := ident.NewTable(schema, ident.New("table2"))
 
+	if a.NotNil(s.OnSchemaChange) {
+		stmts, err := s.OnSchemaChange(ctx, &SchemaChange{
+			Kind:      "ALTER TABLE",
+			Schema:    schema.Raw(),
+			Statement: "ALTER TABLE table1 ADD COLUMN extra INT",
+			Tables:    []string{tbl1.String()},
+		})
+		a.NoError(err)
+		a.Equal([]string{fmt.Sprintf("ALTER TABLE %s ADD COLUMN IF NOT EXISTS extra INT", tbl1)}, stmts)
+
+		stmts, err = s.OnSchemaChange(ctx, &SchemaChange{
+			Kind:      "DROP TABLE",
+			Schema:    schema.Raw(),
+			Statement: "DROP TABLE table1",
+			Tables:    []string{tbl1.String()},
+		})
+		a.NoError(err)
+		a.Empty(stmts)
+	}
+
 	if cfg := s.Sources.GetZero(ident.New("expander")); a.NotNil(cfg) {
 		mut := types.Mutation{
 			Before: []byte(`{"before":true}`),
@@ -204,12 +230,20 @@ CREATE TABLE %s.skewed_merge_times(
 
 	if cfg := s.Sources.GetZero(ident.New("recursive")); a.NotNil(cfg) {
 		a.True(cfg.Recurse)
+		if a.NotNil(cfg.Filter) {
+			a.Equal("tenant IN (1, 2)", cfg.Filter.String())
+		}
 	}
 
 	table := ident.NewTable(schema, ident.New("all_features"))
 	if cfg := s.Targets.GetZero(table); a.NotNil(cfg) {
+		expectedFilter, err := filter.Parse("region = 'us' AND NOT archived")
+		r.NoError(err)
+		expectedFilter.DropUnknownDeletes = true
+
 		expectedApply := applycfg.Config{
-			CASColumns: []ident.Ident{ident.New("cas0"), ident.New("cas1")},
+			BulkThreshold: 1000,
+			CASColumns:    []ident.Ident{ident.New("cas0"), ident.New("cas1")},
 			Deadlines: ident.MapOf[time.Duration](
 				ident.New("dl0"), time.Hour,
 				ident.New("dl1"), time.Minute,
@@ -219,14 +253,30 @@ CREATE TABLE %s.skewed_merge_times(
 				ident.New("expr1"), "Hello Library!",
 			),
 			Extras: ident.New("overflow_column"),
+			Filter: expectedFilter,
 			Ignore: ident.MapOf[bool](
 				ident.New("ign0"), true,
 				ident.New("ign1"), true,
 				// The false value is dropped.
 			),
 			// SourceName not used; that can be handled by the function.
-			SourceNames: &ident.Map[applycfg.SourceColumn]{},
-			RowLimit:    99,
+			SourceNames:    &ident.Map[applycfg.SourceColumn]{},
+			Mode:           applycfg.ModeConsistent,
+			RowLimit:       99,
+			SCDCurrent:     ident.New("is_current"),
+			SCDValidFrom:   ident.New("valid_from"),
+			SCDValidTo:     ident.New("valid_to"),
+			SoftDelete:     ident.New("is_deleted"),
+			SoftDeleteTime: ident.New("deleted_at"),
+			Transforms: ident.MapOf[transform.Pipeline](
+				ident.New("email"), transform.Pipeline{
+					{Kind: transform.KindHash, Algorithm: transform.AlgorithmHMACSHA256, Secret: "shh"},
+				},
+				ident.New("phone"), transform.Pipeline{
+					{Kind: transform.KindMask, Keep: 4},
+					{Kind: transform.KindRename, To: "phone_masked"},
+				},
+			),
 		}
 		a.True(expectedApply.Equal(&cfg.Config))
 
@@ -574,3 +624,63 @@ func TestLegacyImport(t *testing.T) {
 	r.NoError(err)
 	r.Equal(map[string]string{"old": "world"}, opts.data)
 }
+
+// Verify that filters and transforms from flags or files are bound to
+// tables, even when no userscript has been configured.
+func TestStaticConfig(t *testing.T) {
+	r := require.New(t)
+	ctx := stopper.WithContext(context.Background())
+	defer ctx.Stop(time.Second)
+
+	path := filepath.Join(t.TempDir(), "transforms.yaml")
+	r.NoError(os.WriteFile(path, []byte(`
+my_table:
+  email:
+    - op: hash
+`), 0644))
+
+	diags := diag.New(ctx)
+	configs, err := applycfg.ProvideConfigs(diags)
+	r.NoError(err)
+
+	cfg := &Config{
+		FilterDropUnknownDeletes: true,
+		FilterSpecs:              []string{"my_table = region = 'us'", "other_table=tenant > 1"},
+		ModeSpecs:                []string{"other_table = Immediate"},
+		TransformsPath:           path,
+	}
+	loader, err := ProvideLoader(ctx, configs, cfg, diags)
+	r.NoError(err)
+	r.Empty(cfg.FilterSpecs)
+	r.Empty(cfg.ModeSpecs)
+	r.Empty(cfg.TransformsPath)
+
+	schema := ident.MustSchema(ident.New("db"), ident.Public)
+	_, err = loader.Bind(ctx, schema, nil, nil)
+	r.NoError(err)
+
+	applyCfg, _ := configs.Get(ident.NewTable(schema, ident.New("my_table"))).Get()
+	r.True(applyCfg.Transforms.Equal(
+		ident.MapOf[transform.Pipeline](
+			ident.New("email"), transform.Pipeline{{Kind: transform.KindHash}},
+		),
+		transform.Pipeline.Equal))
+	r.NotNil(applyCfg.Filter)
+	r.Equal("region = 'us'", applyCfg.Filter.String())
+	r.True(applyCfg.Filter.DropUnknownDeletes)
+
+	applyCfg, _ = configs.Get(ident.NewTable(schema, ident.New("other_table"))).Get()
+	r.NotNil(applyCfg.Filter)
+	r.Equal("tenant > 1", applyCfg.Filter.String())
+	r.Equal(applycfg.ModeImmediate, applyCfg.Mode)
+
+	// Verify flag validation.
+	r.ErrorContains((&Config{FilterSpecs: []string{"my_table"}}).Preflight(),
+		"must be of the form table=predicate")
+	r.ErrorContains((&Config{FilterSpecs: []string{"my_table=a ="}}).Preflight(),
+		"unexpected end of expression")
+	r.ErrorContains((&Config{ModeSpecs: []string{"my_table"}}).Preflight(),
+		"must be of the form table=mode")
+	r.ErrorContains((&Config{ModeSpecs: []string{"my_table=fast"}}).Preflight(),
+		"unknown mode")
+}
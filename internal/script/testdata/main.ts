@@ -91,6 +91,8 @@ api.configureSource("partitioned", {
 api.configureSource("recursive", {
     target: "top_level",
     recurse: true,
+    // Only replicate a subset of the source documents.
+    filter: "tenant IN (1, 2)",
 })
 
 api.configureTable("all_features", {
@@ -100,6 +102,8 @@ api.configureTable("all_features", {
         // It can delegate to the standard apply pipeline.
         return api.getTX().apply(ops)
     },
+    // Stage large batches with a bulk-load mechanism.
+    bulkThreshold: 1000,
     // Compare-and-set operations.
     cas: ["cas0", "cas1"],
     // Drop old data.
@@ -118,6 +122,10 @@ api.configureTable("all_features", {
     },
     // Place unmapped data into JSONB column.
     extras: "overflow_column",
+    // Only apply rows which match a predicate. Deletes which do not
+    // carry the prior state of the row will be discarded.
+    filter: "region = 'us' AND NOT archived",
+    filterDropUnknownDeletes: true,
     // Allow column in target database to be ignored.
     ignore: {
         "ign0": true,
@@ -153,6 +161,21 @@ api.configureTable("all_features", {
     // is mainly needed for ultra-wide tables and databases with a
     // relatively small number of available bind variables.
     rowLimit: 99,
+    // Apply this table, and any tables related by foreign keys, in a
+    // transactionally-consistent fashion.
+    mode: "consistent",
+    // Maintain the table as a type-2 slowly-changing dimension.
+    scdCurrent: "is_current",
+    scdValidFrom: "valid_from",
+    scdValidTo: "valid_to",
+    // Convert deletions into updates of a tombstone column.
+    softDelete: "is_deleted",
+    softDeleteTime: "deleted_at",
+    // Declarative column transforms.
+    transforms: {
+        "email": [{op: "hash", algorithm: "hmac-sha256", secret: "shh"}],
+        "phone": [{op: "mask", keep: 4}, {op: "rename", to: "phone_masked"}],
+    },
 });
 
 // Elide all deletes for the table, e.g.: for archival use cases.
@@ -351,4 +374,13 @@ async function countCurrentTable() {
     }
 }
 
+// Schema changes reported by a source may be translated into
+// statements to execute in the target database.
+api.onSchemaChange((change: api.SchemaChange): string[] | null => {
+    if (change.kind !== "ALTER TABLE") {
+        return null;
+    }
+    return change.tables.map(tbl => `ALTER TABLE ${tbl} ADD COLUMN IF NOT EXISTS extra INT`);
+});
+
 api.setOptions({"hello": "world"});
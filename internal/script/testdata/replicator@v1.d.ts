@@ -152,6 +152,18 @@ declare module "replicator@v1" {
          * to be passed to the source's destination.
          */
         recurse: boolean;
+        /**
+         * A SQL-like predicate, e.g. <code>region = 'us'</code>, which
+         * is evaluated against incoming documents. Documents which do
+         * not match are discarded before they are dispatched.
+         *
+         * @see ConfigureTableOptions.filter
+         */
+        filter: string;
+        /**
+         * @see ConfigureTableOptions.filterDropUnknownDeletes
+         */
+        filterDropUnknownDeletes: boolean;
     }
 
     /**
@@ -201,6 +213,14 @@ declare module "replicator@v1" {
          * @param ops - The operations to apply to the target database.
          */
         apply(ops: ApplyOp[]): Promise<any>;
+        /**
+         * The minimum number of rows in a batch for the upserts to be
+         * staged using a bulk-load mechanism (e.g. COPY or LOAD DATA)
+         * and then merged into the target table. This is incompatible
+         * with exprs and merge functions. If unset, bulk loading is
+         * disabled.
+         */
+        bulkThreshold: number;
         /**
          * A list of columns to enable compare-and-set behavior.
          */
@@ -230,6 +250,39 @@ declare module "replicator@v1" {
          * stored in.
          */
         extras: Column;
+        /**
+         * A predicate which rows must match in order to be applied to
+         * the table. The syntax is a subset of SQL, supporting
+         * comparisons, <code>IN</code>, <code>IS [NOT] NULL</code>,
+         * <code>AND</code>, <code>OR</code>, and <code>NOT</code>:
+         * <code>region = 'us' AND tenant_id IN (1, 2)</code>.
+         *
+         * Deletions are evaluated against the prior state of the row,
+         * if the source provides it. An update whose prior state
+         * matched, but whose new state does not, is applied as a
+         * deletion.
+         */
+        filter: string;
+        /**
+         * A deletion, or a non-matching update, which does not carry
+         * the prior state of the row is passed through as a deletion
+         * by default, since the row may have been applied previously.
+         * Set this option to discard such mutations instead.
+         */
+        filterDropUnknownDeletes: boolean;
+        /**
+         * Enables append-only history mode. Every mutation is inserted
+         * as a new row, with this column receiving the operation
+         * (<code>upsert</code> or <code>delete</code>). Requires
+         * historyTime to be set.
+         */
+        historyOp: Column;
+        /**
+         * In history mode, this column receives the HLC timestamp of
+         * each mutation. It must be part of the target table's primary
+         * key.
+         */
+        historyTime: Column;
         /**
          * Columns that may be ignored in the input data. This allows,
          * for example, columns to be dropped from the destination
@@ -248,6 +301,22 @@ declare module "replicator@v1" {
          * Enables a user-defined, two- or three-way merge function.
          */
         merge: MergeFunction | StandardMerge;
+        /**
+         * Overrides the sequencing mode used to apply mutations to the
+         * table. This allows, for example, large append-only tables to
+         * be written in <code>immediate</code> mode, while a set of
+         * tables with foreign-key relationships is applied in
+         * <code>consistent</code> mode.
+         *
+         * The override applies to all tables that are related to this
+         * table by foreign keys, since those tables must be applied
+         * together. If related tables request different modes, the
+         * most conservative mode is used, with <code>consistent</code>
+         * preferred over <code>besteffort</code>, which is preferred
+         * over <code>immediate</code>. The value <code>auto</code>
+         * has the same effect as leaving the mode unset.
+         */
+        mode: "auto" | "besteffort" | "consistent" | "immediate";
         /**
          * This is a tuning parameter which allows the maximum number
          * of rows in a single UPSERT or DELETE statement to be
@@ -256,6 +325,41 @@ declare module "replicator@v1" {
          * variables. If unset, a reasonable value will be chosen.
          */
         rowLimit: number;
+        /**
+         * Enables SCD Type 2 versioning. This boolean column marks the
+         * current version of a row. Requires scdValidFrom and
+         * scdValidTo to be set.
+         */
+        scdCurrent: Column;
+        /**
+         * In SCD Type 2 mode, this column receives the HLC timestamp at
+         * which a version became current. It must be part of the
+         * target table's primary key.
+         */
+        scdValidFrom: Column;
+        /**
+         * In SCD Type 2 mode, this column receives the HLC timestamp at
+         * which a version was superseded or deleted.
+         */
+        scdValidTo: Column;
+        /**
+         * Enables soft deletes. A deletion will instead set this
+         * boolean column to true. Upserts will reset it to false.
+         */
+        softDelete: Column;
+        /**
+         * An optional timestamp column which records the time at which
+         * a soft delete was applied.
+         */
+        softDeleteTime: Column;
+        /**
+         * Declarative transforms that are applied, in order, to the
+         * values of a column before they are written to the target.
+         * These run natively and do not require a map() function.
+         * A transforms file passed to the --transforms flag will
+         * replace the pipelines configured here for the same column.
+         */
+        transforms: { [k: Column]: Transform[] };
     };
 
     /**
@@ -347,6 +451,48 @@ declare module "replicator@v1" {
         type: string;
     };
 
+    /**
+     * A Transform is a single step in a column's transform pipeline.
+     *
+     * @see ConfigureTableOptions.transforms
+     */
+    type Transform = {
+        /**
+         * One of:
+         * <ul>
+         * <li>cast: convert the value to a bool, float, int, or string.</li>
+         * <li>constant: replace the value with a fixed value.</li>
+         * <li>hash: replace the value with a hex-encoded sha256 or
+         *   hmac-sha256 digest.</li>
+         * <li>mask: replace all but the last few characters.</li>
+         * <li>rename: write the value to another column.</li>
+         * <li>replace: perform a regular-expression replacement.</li>
+         * <li>truncate: limit the value to a number of characters.</li>
+         * </ul>
+         */
+        op: "cast" | "constant" | "hash" | "mask" | "rename" | "replace" | "truncate";
+        /** For hash, either "sha256" (the default) or "hmac-sha256". */
+        algorithm?: "sha256" | "hmac-sha256";
+        /** For mask, the replacement character. Defaults to "*". */
+        char?: string;
+        /** For mask, the number of trailing characters to retain. */
+        keep?: number;
+        /** For truncate, the maximum number of characters. */
+        length?: number;
+        /** For replace, a Go regular expression. */
+        pattern?: string;
+        /** For replace, the replacement, which may refer to groups. */
+        replacement?: string;
+        /** For hash, the key to use with hmac-sha256. */
+        secret?: string;
+        /** For rename, the name of the destination column. */
+        to?: Column;
+        /** For cast, one of "bool", "float", "int", or "string". */
+        type?: "bool" | "float" | "int" | "string";
+        /** For constant, the replacement value. */
+        value?: DocumentValue;
+    };
+
     /**
      * TargetTX allows the userscript to execute arbitrary SQL
      * statements against the target database.
@@ -399,6 +545,47 @@ declare module "replicator@v1" {
         table(): string;
     };
 
+    /**
+     * Register a callback to receive schema changes (DDL statements)
+     * that are observed by replication sources which support them.
+     * The source must be configured to route schema changes to the
+     * userscript.
+     *
+     * @param fn - A callback that receives the schema change and
+     * returns zero or more SQL statements to execute in the target
+     * database. Returning null will discard the schema change.
+     */
+    function onSchemaChange(fn: (change: SchemaChange) => string[] | null): void;
+
+    /**
+     * @see onSchemaChange
+     */
+    type SchemaChange = {
+        /**
+         * The kind of DDL statement, e.g. <code>ALTER TABLE</code>.
+         */
+        kind: string;
+        /**
+         * Source-specific metadata about the schema change.
+         */
+        meta: Document;
+        /**
+         * The target schema.
+         */
+        schema: string;
+        /**
+         * The DDL statement, as executed in the source database.
+         */
+        statement: string;
+        /**
+         * The target tables that are affected by the statement. Each
+         * element is the schema-qualified name of a table, e.g.
+         * <code>database.schema.table</code>. Unlike a {@link Table},
+         * the elements of the name are never quoted.
+         */
+        tables: string[];
+    };
+
     /**
      * @returns a string containing a random UUID.
      */
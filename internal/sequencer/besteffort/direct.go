@@ -23,7 +23,6 @@ import (
 	"github.com/cockroachdb/replicator/internal/types"
 	"github.com/cockroachdb/replicator/internal/util/lockset"
 	"github.com/cockroachdb/replicator/internal/util/metrics"
-	"github.com/jackc/pgx/v5"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 )
@@ -73,9 +72,9 @@ func (a *directAcceptor) AcceptTableBatch(
 	// Perform work via the scheduler to ensure we can't step on
 	// anyone's toes.
 	outcome := a.scheduler.TableBatch(batch, func() error {
-		stageTx, err := a.stagingPool.BeginTx(ctx, pgx.TxOptions{})
+		stageTx, err := a.stagingPool.BeginStagingTx(ctx)
 		if err != nil {
-			return errors.WithStack(err)
+			return err
 		}
 		defer func() { _ = stageTx.Rollback(ctx) }()
 
@@ -44,6 +44,8 @@ type Config struct {
 	FlushPeriod      time.Duration // Don't queue mutations for longer than this.
 	FlushSize        int           // Ideal target database transaction size
 	IdempotentSource bool          // The upstream source is idempotent, disable extra marking.
+	PIIKeys          string        // A file of named keys for PII policies.
+	PIIPolicies      string        // A file of per-column PII policies.
 	Parallelism      int           // The number of concurrent connections to use.
 	QuiescentPeriod  time.Duration // How often to sweep for queued mutations.
 	RetireOffset     time.Duration // Delay removal of applied mutations.
@@ -60,6 +62,10 @@ func (c *Config) Bind(flags *pflag.FlagSet) {
 		"ideal batch size to determine when to flush mutations")
 	flags.BoolVar(&c.IdempotentSource, AssumeIdempotent, false,
 		"disable the extra staging table queries that debounce non-idempotent redelivery in changefeeds")
+	flags.StringVar(&c.PIIKeys, "piiKeys", "",
+		"a YAML file of base64-encoded keys used by PII encrypt and tokenize policies")
+	flags.StringVar(&c.PIIPolicies, "piiPolicies", "",
+		"a YAML file of per-column policies to mask, tokenize, or encrypt PII before staging")
 	flags.IntVar(&c.Parallelism, "parallelism", DefaultParallelism,
 		"the number of concurrent database transactions to use")
 	flags.DurationVar(&c.QuiescentPeriod, "quiescentPeriod", DefaultQuiescentPeriod,
@@ -149,7 +149,9 @@ func (r *round) tryCommit(ctx *stopper.Context) error {
 	log.Tracef("round.tryCommit: beginning for %s to %s", r.group, r.advanceTo)
 	r.lastAttempt.SetToCurrentTime()
 
-	targetTx, err := r.targetPool.BeginTx(ctx, nil)
+	// Use a dedicated connection so that appliers may make use of
+	// driver-specific bulk-loading APIs.
+	targetTx, err := r.targetPool.BeginConnTx(ctx, nil)
 	if err != nil {
 		return errors.WithStack(err)
 	}
@@ -22,7 +22,6 @@ import (
 	"github.com/cockroachdb/replicator/internal/types"
 	"github.com/cockroachdb/replicator/internal/util/ident"
 	"github.com/cockroachdb/replicator/internal/util/retry"
-	"github.com/jackc/pgx/v5"
 	"github.com/pkg/errors"
 )
 
@@ -107,9 +106,9 @@ func (s *marker) AcceptTemporalBatch(
 
 func (s *marker) mark(ctx context.Context, flattened *ident.TableMap[[]types.Mutation]) error {
 	return retry.Retry(ctx, s.stagingPool, func(ctx context.Context) error {
-		tx, err := s.stagingPool.BeginTx(ctx, pgx.TxOptions{})
+		tx, err := s.stagingPool.BeginStagingTx(ctx)
 		if err != nil {
-			return errors.WithStack(err)
+			return err
 		}
 		defer func() { _ = tx.Rollback(context.Background()) }()
 
@@ -119,7 +118,7 @@ func (s *marker) mark(ctx context.Context, flattened *ident.TableMap[[]types.Mut
 				return err
 			}
 
-			if err := stager.MarkApplied(ctx, s.stagingPool, muts); err != nil {
+			if err := stager.MarkApplied(ctx, tx, muts); err != nil {
 				return err
 			}
 		}
@@ -0,0 +1,215 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package pii
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/pkg/errors"
+)
+
+// acceptor protects the values in copies of the batches that it
+// receives. The original batches are not modified, so that a retried
+// batch will not be protected twice.
+type acceptor struct {
+	delegate types.MultiAcceptor
+	tables   *ident.TableMap[*ident.Map[*protector]]
+	watcher  types.Watcher
+}
+
+var _ types.MultiAcceptor = (*acceptor)(nil)
+
+// AcceptMultiBatch implements [types.MultiAcceptor].
+func (a *acceptor) AcceptMultiBatch(
+	ctx context.Context, batch *types.MultiBatch, opts *types.AcceptOptions,
+) error {
+	batch = batch.Copy()
+	for _, temp := range batch.Data {
+		for sub := range temp.Data.Values() {
+			if err := a.protectBatch(sub); err != nil {
+				return err
+			}
+		}
+	}
+	return a.delegate.AcceptMultiBatch(ctx, batch, opts)
+}
+
+// AcceptTableBatch implements [types.MultiAcceptor].
+func (a *acceptor) AcceptTableBatch(
+	ctx context.Context, batch *types.TableBatch, opts *types.AcceptOptions,
+) error {
+	if _, ok := a.tables.Get(batch.Table); ok {
+		batch = batch.Copy()
+		if err := a.protectBatch(batch); err != nil {
+			return err
+		}
+	}
+	return a.delegate.AcceptTableBatch(ctx, batch, opts)
+}
+
+// AcceptTemporalBatch implements [types.MultiAcceptor].
+func (a *acceptor) AcceptTemporalBatch(
+	ctx context.Context, batch *types.TemporalBatch, opts *types.AcceptOptions,
+) error {
+	batch = batch.Copy()
+	for sub := range batch.Data.Values() {
+		if err := a.protectBatch(sub); err != nil {
+			return err
+		}
+	}
+	return a.delegate.AcceptTemporalBatch(ctx, batch, opts)
+}
+
+// Unwrap is an informal protocol to return the delegate.
+func (a *acceptor) Unwrap() types.MultiAcceptor {
+	return a.delegate
+}
+
+// primaryKeys returns the names of the table's primary-key columns,
+// in key order. An error will be returned if a primary-key column
+// would be protected by a non-deterministic policy.
+func (a *acceptor) primaryKeys(table ident.Table) ([]ident.Ident, error) {
+	cols, ok := a.watcher.Get().Columns.Get(table)
+	if !ok {
+		return nil, errors.Errorf("pii policy references unknown table %s", table)
+	}
+	protectors := a.tables.GetZero(table)
+	var ret []ident.Ident
+	for _, col := range cols {
+		if !col.Primary {
+			continue
+		}
+		if p, ok := protectors.Get(col.Name); ok && !p.deterministic() {
+			return nil, errors.Errorf(
+				"pii policy %s for %s.%s would not preserve primary key; use %s instead",
+				p.policy.Action, table, col.Name, ActionTokenize)
+		}
+		ret = append(ret, col.Name)
+	}
+	return ret, nil
+}
+
+// protectBatch rewrites the mutations in the batch, in place.
+func (a *acceptor) protectBatch(batch *types.TableBatch) error {
+	protectors, ok := a.tables.Get(batch.Table)
+	if !ok {
+		return nil
+	}
+	pks, err := a.primaryKeys(batch.Table)
+	if err != nil {
+		return err
+	}
+	for idx := range batch.Data {
+		if err := protectMutation(protectors, pks, &batch.Data[idx]); err != nil {
+			return errors.Wrap(err, batch.Table.Raw())
+		}
+	}
+	return nil
+}
+
+// protectMutation replaces the Before, Data, and Key fields of the
+// mutation.
+func protectMutation(
+	protectors *ident.Map[*protector], pks []ident.Ident, mut *types.Mutation,
+) error {
+	var err error
+	if mut.Before, err = protectDocument(protectors, mut.Before); err != nil {
+		return err
+	}
+	if mut.Data, err = protectDocument(protectors, mut.Data); err != nil {
+		return err
+	}
+	if mut.Key, err = protectKey(protectors, pks, mut.Key); err != nil {
+		return err
+	}
+	return nil
+}
+
+var nullBytes = []byte("null")
+
+// protectDocument rewrites the properties of a JSON object. The
+// original data is returned if no properties were protected.
+func protectDocument(protectors *ident.Map[*protector], data json.RawMessage) (json.RawMessage, error) {
+	if len(data) == 0 || bytes.Equal(data, nullBytes) {
+		return data, nil
+	}
+	var doc map[string]any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	changed := false
+	for prop, value := range doc {
+		p, ok := protectors.Get(ident.New(prop))
+		if !ok {
+			continue
+		}
+		protected, err := p.protect(value)
+		if err != nil {
+			return nil, errors.Wrap(err, prop)
+		}
+		doc[prop] = protected
+		changed = true
+	}
+	if !changed {
+		return data, nil
+	}
+	ret, err := json.Marshal(doc)
+	return ret, errors.WithStack(err)
+}
+
+// protectKey rewrites the elements of a JSON key array that correspond
+// to protected primary-key columns.
+func protectKey(
+	protectors *ident.Map[*protector], pks []ident.Ident, data json.RawMessage,
+) (json.RawMessage, error) {
+	if len(data) == 0 {
+		return data, nil
+	}
+	var key []any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&key); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	changed := false
+	for idx, col := range pks {
+		if idx >= len(key) {
+			break
+		}
+		p, ok := protectors.Get(col)
+		if !ok {
+			continue
+		}
+		protected, err := p.protect(key[idx])
+		if err != nil {
+			return nil, errors.Wrap(err, col.Raw())
+		}
+		key[idx] = protected
+		changed = true
+	}
+	if !changed {
+		return data, nil
+	}
+	ret, err := json.Marshal(key)
+	return ret, errors.WithStack(err)
+}
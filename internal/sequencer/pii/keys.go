@@ -0,0 +1,71 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package pii
+
+import (
+	"context"
+	"encoding/base64"
+	"os"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// Keys provides named key material to encryption and tokenization
+// policies. Implementations might read keys from a local file or
+// retrieve them from an external key-management service.
+type Keys interface {
+	// Key returns the named key. An error should be returned if the
+	// key does not exist.
+	Key(ctx context.Context, name string) ([]byte, error)
+}
+
+// FileKeys is a [Keys] implementation that is loaded from a YAML file
+// which maps key names to base64-encoded key material.
+//
+//	tokens: 7mK0n9Ww0bQ7R3qD6h7pQk6wN9mZ6qv4vX1bH0f3p2E=
+type FileKeys map[string][]byte
+
+var _ Keys = FileKeys(nil)
+
+// ReadKeys loads a keys file.
+func ReadKeys(path string) (FileKeys, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var encoded map[string]string
+	if err := yaml.Unmarshal(data, &encoded); err != nil {
+		return nil, errors.Wrap(err, path)
+	}
+	ret := make(FileKeys, len(encoded))
+	for name, value := range encoded {
+		ret[name], err = base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, errors.Wrapf(err, "%s: key %q", path, name)
+		}
+	}
+	return ret, nil
+}
+
+// Key implements [Keys].
+func (k FileKeys) Key(_ context.Context, name string) ([]byte, error) {
+	if ret, ok := k[name]; ok {
+		return ret, nil
+	}
+	return nil, errors.Errorf("unknown key %q", name)
+}
@@ -0,0 +1,115 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package pii contains a sequencer shim that masks, tokenizes, or
+// encrypts column values before they are staged or applied.
+package pii
+
+import (
+	"context"
+
+	"github.com/cockroachdb/field-eng-powertools/notify"
+	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/replicator/internal/sequencer"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/diag"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/pkg/errors"
+)
+
+// PII is a [sequencer.Shim] which protects column values according to
+// the policies named by [sequencer.Config.PIIPolicies]. Primary-key
+// columns may only be tokenized, so that upserts and deletes of the
+// same row continue to match in the target.
+type PII struct {
+	policies   Policies                          // Reported via diagnostics.
+	protectors map[string]*ident.Map[*protector] // Keyed by unresolved table name.
+	watchers   types.Watchers
+}
+
+var (
+	_ diag.Diagnostic = (*PII)(nil)
+	_ sequencer.Shim  = (*PII)(nil)
+)
+
+// Diagnostic implements [diag.Diagnostic]. Only key names, not key
+// material, are reported.
+func (p *PII) Diagnostic(_ context.Context) any {
+	return p.policies
+}
+
+// Wrap implements [sequencer.Shim].
+func (p *PII) Wrap(
+	_ *stopper.Context, delegate sequencer.Sequencer,
+) (sequencer.Sequencer, error) {
+	return &wrapper{p, delegate}, nil
+}
+
+type wrapper struct {
+	*PII
+	delegate sequencer.Sequencer
+}
+
+var _ sequencer.Sequencer = (*wrapper)(nil)
+
+// Start injects a protecting acceptor at the periphery of the delegate
+// so that protected values are never staged.
+func (w *wrapper) Start(
+	ctx *stopper.Context, opts *sequencer.StartOptions,
+) (types.MultiAcceptor, *notify.Var[sequencer.Stat], error) {
+	if len(w.protectors) == 0 {
+		return w.delegate.Start(ctx, opts)
+	}
+
+	schema, err := opts.Group.Schema()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tables := &ident.TableMap[*ident.Map[*protector]]{}
+	for tableName, cols := range w.protectors {
+		table, _, err := ident.ParseTableRelative(tableName, schema)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "pii policy for %q", tableName)
+		}
+		tables.Put(table, cols)
+	}
+
+	watcher, err := w.watchers.Get(schema)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	acc := &acceptor{tables: tables, watcher: watcher}
+	// Fail fast if a primary key would be modified unsafely.
+	for table := range tables.Keys() {
+		if _, err := acc.primaryKeys(table); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	delegate, stat, err := w.delegate.Start(ctx, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	acc.delegate = delegate
+	return acc, stat, nil
+}
+
+// Unwrap is an informal protocol to return the delegate.
+func (w *wrapper) Unwrap() sequencer.Sequencer {
+	return w.delegate
+}
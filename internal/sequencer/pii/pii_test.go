@@ -0,0 +1,190 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package pii
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/stretchr/testify/require"
+)
+
+var testKey = []byte("0123456789abcdef0123456789abcdef")
+
+func TestMask(t *testing.T) {
+	tcs := []struct {
+		in   string
+		keep int
+		out  string
+	}{
+		{"", 0, ""},
+		{"555-867-5309", 4, "000-000-5309"},
+		{"Bob Smith", 0, "Xxx Xxxxx"},
+		{"abc", 10, "abc"},
+		{"über@example.com", 4, "xxxx@xxxxxxx.com"},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.in, func(t *testing.T) {
+			require.Equal(t, tc.out, mask(tc.in, tc.keep))
+		})
+	}
+}
+
+func TestParsePolicies(t *testing.T) {
+	r := require.New(t)
+
+	policies, err := ParsePolicies([]byte(`
+public.customers:
+  email:
+    action: tokenize
+    key: tokens
+  phone:
+    action: mask
+    keep: 4
+`))
+	r.NoError(err)
+	r.Equal(Policies{
+		"public.customers": {
+			"email": {Action: ActionTokenize, Key: "tokens"},
+			"phone": {Action: ActionMask, Keep: 4},
+		},
+	}, policies)
+
+	_, err = ParsePolicies([]byte("t:\n  c:\n    action: encrypt\n"))
+	r.ErrorContains(err, "t.c: encrypt: a key name is required")
+
+	_, err = ParsePolicies([]byte("t:\n  c:\n    action: shred\n"))
+	r.ErrorContains(err, `unknown action "shred"`)
+}
+
+func TestProtect(t *testing.T) {
+	r := require.New(t)
+
+	enc, err := newProtector(&Policy{Action: ActionEncrypt, Key: "k"}, testKey)
+	r.NoError(err)
+	msk, err := newProtector(&Policy{Action: ActionMask, Keep: 2}, nil)
+	r.NoError(err)
+	tok, err := newProtector(&Policy{Action: ActionTokenize, Key: "k"}, testKey)
+	r.NoError(err)
+
+	_, err = newProtector(&Policy{Action: ActionEncrypt, Key: "k"}, []byte("short"))
+	r.ErrorContains(err, "invalid key size")
+
+	// Nulls are preserved.
+	for _, p := range []*protector{enc, msk, tok} {
+		out, err := p.protect(nil)
+		r.NoError(err)
+		r.Nil(out)
+	}
+
+	// Numbers are protected in their string form.
+	out, err := msk.protect(json.Number("12345"))
+	r.NoError(err)
+	r.Equal("00045", out)
+
+	// Tokens are deterministic.
+	tok1, err := tok.protect("hello")
+	r.NoError(err)
+	tok2, err := tok.protect("hello")
+	r.NoError(err)
+	r.Equal(tok1, tok2)
+	tok3, err := tok.protect("world")
+	r.NoError(err)
+	r.NotEqual(tok1, tok3)
+
+	// Ciphertext is randomized, but may be decrypted.
+	enc1, err := enc.protect("secret")
+	r.NoError(err)
+	enc2, err := enc.protect("secret")
+	r.NoError(err)
+	r.NotEqual(enc1, enc2)
+
+	block, err := aes.NewCipher(testKey)
+	r.NoError(err)
+	aead, err := cipher.NewGCM(block)
+	r.NoError(err)
+	data, err := base64.StdEncoding.DecodeString(enc1.(string))
+	r.NoError(err)
+	plain, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+	r.NoError(err)
+	r.Equal("secret", string(plain))
+}
+
+func TestProtectMutation(t *testing.T) {
+	r := require.New(t)
+
+	msk, err := newProtector(&Policy{Action: ActionMask}, nil)
+	r.NoError(err)
+	tok, err := newProtector(&Policy{Action: ActionTokenize, Key: "k"}, testKey)
+	r.NoError(err)
+	token, err := tok.protect("bob@example.com")
+	r.NoError(err)
+
+	protectors := &ident.Map[*protector]{}
+	protectors.Put(ident.New("email"), tok)
+	protectors.Put(ident.New("name"), msk)
+	pks := []ident.Ident{ident.New("id"), ident.New("email")}
+
+	mut := types.Mutation{
+		Before: json.RawMessage(`{"id":1,"email":"bob@example.com","name":"Bob"}`),
+		Data:   json.RawMessage(`{"id":1,"email":"bob@example.com","name":"Robert","big":12345678901234567890}`),
+		Key:    json.RawMessage(`[1,"bob@example.com"]`),
+	}
+	r.NoError(protectMutation(protectors, pks, &mut))
+
+	r.JSONEq(`{"id":1,"email":"`+token.(string)+`","name":"Xxx"}`, string(mut.Before))
+	r.JSONEq(`{"id":1,"email":"`+token.(string)+`","name":"Xxxxxx","big":12345678901234567890}`, string(mut.Data))
+	r.JSONEq(`[1,"`+token.(string)+`"]`, string(mut.Key))
+
+	// Deletions without data must still match.
+	del := types.Mutation{Key: json.RawMessage(`[1,"bob@example.com"]`)}
+	r.NoError(protectMutation(protectors, pks, &del))
+	r.Equal(mut.Key, del.Key)
+	r.Empty(del.Data)
+
+	// Unprotected documents are not rewritten.
+	orig := json.RawMessage(`{ "id" : 1 }`)
+	out, err := protectDocument(protectors, orig)
+	r.NoError(err)
+	r.Equal(orig, out)
+}
+
+func TestFileKeys(t *testing.T) {
+	r := require.New(t)
+
+	path := filepath.Join(t.TempDir(), "keys.yaml")
+	r.NoError(os.WriteFile(path,
+		[]byte("tokens: "+base64.StdEncoding.EncodeToString(testKey)+"\n"), 0600))
+
+	keys, err := ReadKeys(path)
+	r.NoError(err)
+
+	key, err := keys.Key(context.Background(), "tokens")
+	r.NoError(err)
+	r.Equal(testKey, key)
+
+	_, err = keys.Key(context.Background(), "missing")
+	r.ErrorContains(err, `unknown key "missing"`)
+}
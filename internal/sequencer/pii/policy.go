@@ -0,0 +1,211 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package pii
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"unicode"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// An Action determines how a column's values are protected.
+type Action string
+
+// The supported actions.
+const (
+	// ActionEncrypt replaces the value with the base64-encoded
+	// concatenation of a random nonce and the AES-GCM ciphertext.
+	ActionEncrypt Action = "encrypt"
+	// ActionMask replaces letters and digits with placeholders, while
+	// retaining punctuation, length, and the last Keep characters.
+	ActionMask Action = "mask"
+	// ActionTokenize replaces the value with a hex-encoded
+	// HMAC-SHA256. Equal inputs yield equal tokens, so tokenized
+	// columns may be used as, or joined against, primary keys.
+	ActionTokenize Action = "tokenize"
+)
+
+// A Policy describes how to protect the values of a single column.
+type Policy struct {
+	Action Action `json:"action"         yaml:"action"`
+	Keep   int    `json:"keep,omitempty" yaml:"keep,omitempty"` // mask
+	Key    string `json:"key,omitempty"  yaml:"key,omitempty"`  // encrypt, tokenize
+}
+
+// Validate returns an error if the Policy is malformed.
+func (p *Policy) Validate() error {
+	switch p.Action {
+	case ActionEncrypt, ActionTokenize:
+		if p.Key == "" {
+			return errors.Errorf("%s: a key name is required", p.Action)
+		}
+	case ActionMask:
+		if p.Keep < 0 {
+			return errors.New("mask: keep must not be negative")
+		}
+	default:
+		return errors.Errorf("unknown action %q", p.Action)
+	}
+	return nil
+}
+
+// Policies are keyed by table name and then by column name. Table
+// names may be qualified or relative to the target schema.
+//
+//	public.customers:
+//	  email:
+//	    action: tokenize
+//	    key: tokens
+//	  phone:
+//	    action: mask
+//	    keep: 4
+//	  notes:
+//	    action: encrypt
+//	    key: notes
+type Policies map[string]map[string]*Policy
+
+// ReadPolicies loads and validates the policies in a YAML file.
+func ReadPolicies(path string) (Policies, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return ParsePolicies(data)
+}
+
+// ParsePolicies loads and validates policies from YAML data.
+func ParsePolicies(data []byte) (Policies, error) {
+	var ret Policies
+	if err := yaml.Unmarshal(data, &ret); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	for table, cols := range ret {
+		for col, policy := range cols {
+			if policy == nil {
+				return nil, errors.Errorf("%s.%s: empty policy", table, col)
+			}
+			if err := policy.Validate(); err != nil {
+				return nil, errors.Wrapf(err, "%s.%s", table, col)
+			}
+		}
+	}
+	return ret, nil
+}
+
+// A protector applies a Policy, using resolved key material.
+type protector struct {
+	aead   cipher.AEAD // Set for ActionEncrypt.
+	policy *Policy
+	secret []byte // Set for ActionTokenize.
+}
+
+// newProtector resolves the key material required by the Policy.
+func newProtector(policy *Policy, key []byte) (*protector, error) {
+	ret := &protector{policy: policy}
+	switch policy.Action {
+	case ActionEncrypt:
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, errors.Wrapf(err, "key %q", policy.Key)
+		}
+		ret.aead, err = cipher.NewGCM(block)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+	case ActionTokenize:
+		if len(key) == 0 {
+			return nil, errors.Errorf("key %q is empty", policy.Key)
+		}
+		ret.secret = key
+	}
+	return ret, nil
+}
+
+// deterministic returns true if the protected value is a function of
+// the input value alone and distinct inputs are very likely to yield
+// distinct outputs. Only deterministic policies may be applied to
+// primary-key columns.
+func (p *protector) deterministic() bool {
+	return p.policy.Action == ActionTokenize
+}
+
+// protect returns a replacement for the value. Null values are
+// preserved. Non-string values are protected in their JSON
+// representation, so the replacement is always a string.
+func (p *protector) protect(value any) (any, error) {
+	var s string
+	switch t := value.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		s = t
+	case json.Number:
+		s = t.String()
+	default:
+		buf, err := json.Marshal(t)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		s = string(buf)
+	}
+
+	switch p.policy.Action {
+	case ActionEncrypt:
+		nonce := make([]byte, p.aead.NonceSize())
+		if _, err := rand.Read(nonce); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return base64.StdEncoding.EncodeToString(p.aead.Seal(nonce, nonce, []byte(s), nil)), nil
+	case ActionMask:
+		return mask(s, p.policy.Keep), nil
+	case ActionTokenize:
+		h := hmac.New(sha256.New, p.secret)
+		_, _ = h.Write([]byte(s))
+		return hex.EncodeToString(h.Sum(nil)), nil
+	default:
+		return nil, errors.Errorf("unknown action %q", p.policy.Action)
+	}
+}
+
+// mask replaces lowercase letters with x, uppercase letters with X, and
+// digits with 0. All other characters and the last keep characters
+// are retained.
+func mask(s string, keep int) string {
+	runes := []rune(s)
+	for idx := 0; idx < len(runes)-keep; idx++ {
+		r := runes[idx]
+		switch {
+		case unicode.IsDigit(r):
+			runes[idx] = '0'
+		case unicode.IsUpper(r):
+			runes[idx] = 'X'
+		case unicode.IsLetter(r):
+			runes[idx] = 'x'
+		}
+	}
+	return string(runes)
+}
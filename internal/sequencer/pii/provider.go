@@ -0,0 +1,90 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package pii
+
+import (
+	"context"
+
+	"github.com/cockroachdb/replicator/internal/sequencer"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/diag"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/google/wire"
+	"github.com/pkg/errors"
+)
+
+// Set is used by Wire.
+var Set = wire.NewSet(
+	ProvideKeys,
+	ProvideShim,
+)
+
+// ProvideKeys is called by Wire to load the file named by
+// [sequencer.Config.PIIKeys]. Deployments which use an external
+// key-management service may provide a different [Keys]
+// implementation.
+func ProvideKeys(cfg *sequencer.Config) (Keys, error) {
+	if cfg.PIIKeys == "" {
+		return FileKeys{}, nil
+	}
+	return ReadKeys(cfg.PIIKeys)
+}
+
+// ProvideShim is called by Wire. It will load the policy file and
+// resolve all required keys.
+func ProvideShim(
+	ctx context.Context,
+	cfg *sequencer.Config,
+	diags *diag.Diagnostics,
+	keys Keys,
+	watchers types.Watchers,
+) (*PII, error) {
+	ret := &PII{
+		protectors: make(map[string]*ident.Map[*protector]),
+		watchers:   watchers,
+	}
+	if cfg.PIIPolicies != "" {
+		var err error
+		ret.policies, err = ReadPolicies(cfg.PIIPolicies)
+		if err != nil {
+			return nil, errors.Wrap(err, cfg.PIIPolicies)
+		}
+	}
+	for table, cols := range ret.policies {
+		protectors := &ident.Map[*protector]{}
+		for col, policy := range cols {
+			var key []byte
+			if policy.Key != "" {
+				var err error
+				key, err = keys.Key(ctx, policy.Key)
+				if err != nil {
+					return nil, errors.Wrapf(err, "%s.%s", table, col)
+				}
+			}
+			p, err := newProtector(policy, key)
+			if err != nil {
+				return nil, errors.Wrapf(err, "%s.%s", table, col)
+			}
+			protectors.Put(ident.New(col), p)
+		}
+		ret.protectors[table] = protectors
+	}
+	if err := diags.Register("pii", ret); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
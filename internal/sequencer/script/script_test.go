@@ -29,11 +29,11 @@ import (
 	"github.com/cockroachdb/replicator/internal/script"
 	"github.com/cockroachdb/replicator/internal/sequencer"
 	"github.com/cockroachdb/replicator/internal/sequencer/seqtest"
-	"github.com/cockroachdb/replicator/internal/sequencer/switcher"
 	"github.com/cockroachdb/replicator/internal/sinktest"
 	"github.com/cockroachdb/replicator/internal/sinktest/all"
 	"github.com/cockroachdb/replicator/internal/sinktest/base"
 	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/applycfg"
 	"github.com/cockroachdb/replicator/internal/util/hlc"
 	"github.com/cockroachdb/replicator/internal/util/ident"
 	log "github.com/sirupsen/logrus"
@@ -41,14 +41,14 @@ import (
 )
 
 func TestUserScriptSequencer(t *testing.T) {
-	for mode := switcher.MinMode; mode <= switcher.MaxMode; mode++ {
+	for mode := applycfg.MinMode; mode <= applycfg.MaxMode; mode++ {
 		t.Run(mode.String(), func(t *testing.T) {
 			testUserScriptSequencer(t, mode)
 		})
 	}
 }
 
-func testUserScriptSequencer(t *testing.T, baseMode switcher.Mode) {
+func testUserScriptSequencer(t *testing.T, baseMode applycfg.Mode) {
 	r := require.New(t)
 
 	// Create a basic test fixture.
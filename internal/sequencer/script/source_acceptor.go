@@ -80,6 +80,19 @@ func acceptBatch[B types.Batch[B]](
 func (a *sourceAcceptor) acceptOne(
 	ctx context.Context, acc *types.MultiBatch, table ident.Table, mutToDispatch types.Mutation,
 ) error {
+	if pred := a.sourceBindings.Filter; pred != nil {
+		var keep bool
+		var err error
+		mutToDispatch, keep, err = pred.Apply(mutToDispatch)
+		if err != nil {
+			return errors.Wrap(err, a.group.Name.Raw())
+		}
+		if !keep {
+			sourceFiltered.WithLabelValues(a.group.Name.Raw()).Inc()
+			return nil
+		}
+	}
+
 	script.AddMeta(a.group.Name.Raw(), table, &mutToDispatch)
 
 	isDelete := mutToDispatch.IsDelete()
@@ -44,7 +44,7 @@ var _ types.TableAcceptor = (*targetAcceptor)(nil)
 func (a *targetAcceptor) AcceptTableBatch(
 	ctx context.Context, batch *types.TableBatch, opts *types.AcceptOptions,
 ) error {
-	if _, isTX := opts.TargetQuerier.(*sql.Tx); a.ensureTX && !isTX {
+	if _, isTX := opts.TargetQuerier.(types.TargetTx); a.ensureTX && !isTX {
 		return a.acceptWithTransaction(ctx, batch, opts)
 	}
 	return a.doMap(ctx, batch, opts)
@@ -57,9 +57,9 @@ func (a *targetAcceptor) acceptWithTransaction(
 	ctx context.Context, batch *types.TableBatch, opts *types.AcceptOptions,
 ) error {
 	log.Trace("creating target transaction for user-defined apply function")
-	tx, err := a.targetPool.BeginTx(ctx, &sql.TxOptions{})
+	tx, err := a.targetPool.BeginConnTx(ctx, &sql.TxOptions{})
 	if err != nil {
-		return errors.WithStack(err)
+		return err
 	}
 	defer func() { _ = tx.Rollback() }()
 
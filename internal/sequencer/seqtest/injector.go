@@ -33,7 +33,8 @@ import (
 
 func NewSequencerFixture(*all.Fixture, *sequencer.Config, *userScript.Config) (*Fixture, error) {
 	panic(wire.Build(
-		wire.FieldsOf(new(*base.Fixture), "Context", "StagingDB", "StagingPool", "TargetPool"),
+		wire.FieldsOf(new(*base.Fixture),
+			"Context", "StagingDB", "StagingLocal", "StagingPool", "TargetPool"),
 		wire.Bind(new(context.Context), new(*stopper.Context)),
 
 		wire.FieldsOf(new(*all.Fixture),
@@ -34,7 +34,9 @@ import (
 	"github.com/cockroachdb/replicator/internal/sinktest/all"
 	"github.com/cockroachdb/replicator/internal/staging/leases"
 	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/applycfg"
 	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/cockroachdb/replicator/internal/util/kvstore"
 	"github.com/pkg/errors"
 )
 
@@ -55,18 +57,18 @@ type Fixture struct {
 // SequencerFor returns a Sequencer instance that corresponds to the
 // given mode enum.
 func (f *Fixture) SequencerFor(
-	ctx *stopper.Context, mode switcher.Mode,
+	ctx *stopper.Context, mode applycfg.Mode,
 ) (sequencer.Sequencer, error) {
 	switch mode {
-	case switcher.ModeBestEffort:
+	case applycfg.ModeBestEffort:
 		stg, err := f.Staging.Wrap(ctx, f.Core)
 		if err != nil {
 			return nil, err
 		}
 		return f.BestEffort.Wrap(ctx, stg)
-	case switcher.ModeConsistent:
+	case applycfg.ModeConsistent:
 		return f.Staging.Wrap(ctx, f.Core)
-	case switcher.ModeImmediate:
+	case applycfg.ModeImmediate:
 		return f.Immediate, nil
 	default:
 		return nil, errors.Errorf("unimplemented, %s", mode)
@@ -74,10 +76,14 @@ func (f *Fixture) SequencerFor(
 }
 
 func provideLeases(
-	ctx context.Context, pool *types.StagingPool, stagingDB ident.StagingSchema,
+	ctx context.Context,
+	pool *types.StagingPool,
+	local *kvstore.Store,
+	stagingDB ident.StagingSchema,
 ) (types.Leases, error) {
 	target := pool.HintNoFTS(ident.NewTable(stagingDB.Schema(), ident.New("leases")))
 	return leases.New(ctx, leases.Config{
+		Local:  local,
 		Pool:   pool,
 		Target: target,
 	})
@@ -38,8 +38,9 @@ func NewSequencerFixture(fixture *all.Fixture, config *sequencer.Config, scriptC
 	chaosChaos := &chaos.Chaos{
 		Config: config,
 	}
+	store := baseFixture.StagingLocal
 	stagingSchema := baseFixture.StagingDB
-	leases, err := provideLeases(context, stagingPool, stagingSchema)
+	leases, err := provideLeases(context, stagingPool, store, stagingSchema)
 	if err != nil {
 		return nil, err
 	}
@@ -58,7 +59,7 @@ func NewSequencerFixture(fixture *all.Fixture, config *sequencer.Config, scriptC
 	}
 	scriptSequencer := script2.ProvideSequencer(loader, targetPool, watchers)
 	stagingStaging := staging.ProvideStaging(config, marker, stagers, stagingPool)
-	switcherSwitcher := switcher.ProvideSequencer(bestEffort, coreCore, diagnostics, immediateImmediate, stagingStaging, stagingPool, targetPool)
+	switcherSwitcher := switcher.ProvideSequencer(configs, bestEffort, coreCore, diagnostics, immediateImmediate, stagingStaging, stagingPool, targetPool, watchers)
 	seqtestFixture := &Fixture{
 		Fixture:    fixture,
 		BestEffort: bestEffort,
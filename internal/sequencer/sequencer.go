@@ -23,9 +23,11 @@ import (
 
 	"github.com/cockroachdb/field-eng-powertools/notify"
 	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/field-eng-powertools/stopvar"
 	"github.com/cockroachdb/replicator/internal/types"
 	"github.com/cockroachdb/replicator/internal/util/hlc"
 	"github.com/cockroachdb/replicator/internal/util/ident"
+	log "github.com/sirupsen/logrus"
 )
 
 // A Sequencer implements a lifecycle strategy for mutations. The
@@ -110,6 +112,36 @@ func CommonProgress(s Stat) hlc.Range {
 	return hlc.RangeExcluding(hlc.Zero(), commonMax)
 }
 
+// NotifyResolved emits resolved timestamps to the acceptor, if it is a
+// [types.ResolvedAcceptor], as transactions are committed. It starts a
+// goroutine which calls the acceptor whenever the [CommonProgress] of
+// the Stat advances. This allows frontends which do not use a
+// checkpoint group (i.e. immediate mode) to emit resolved markers.
+func NotifyResolved(
+	ctx *stopper.Context, group *types.TableGroup, stat *notify.Var[Stat], acc types.TableAcceptor,
+) {
+	res, ok := acc.(types.ResolvedAcceptor)
+	if !ok {
+		return
+	}
+	ctx.Go(func(ctx *stopper.Context) error {
+		_, err := stopvar.DoWhenChanged(ctx, nil, stat,
+			func(ctx *stopper.Context, old, new Stat) error {
+				oldMin := CommonProgress(old)
+				newMin := CommonProgress(new)
+				if oldMin == newMin || newMin.Empty() {
+					return nil
+				}
+				if err := res.AcceptResolved(ctx, group, newMin.MaxInclusive()); err != nil {
+					log.WithError(err).Warnf(
+						"could not accept resolved timestamp for %s; will continue", group)
+				}
+				return nil
+			})
+		return err
+	})
+}
+
 type stat struct {
 	group    *types.TableGroup
 	progress *ident.TableMap[hlc.Range]
@@ -26,6 +26,7 @@ import (
 	"github.com/cockroachdb/field-eng-powertools/stopvar"
 	"github.com/cockroachdb/replicator/internal/sequencer"
 	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/applycfg"
 	"github.com/cockroachdb/replicator/internal/util/diag"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
@@ -35,7 +36,7 @@ import (
 type groupSequencer struct {
 	*Switcher
 	group  *types.TableGroup
-	mode   *notify.Var[Mode]
+	mode   *notify.Var[applycfg.Mode]
 	status notify.Var[sequencer.Stat]
 
 	// The members of this struct are replaced whenever we switch modes.
@@ -57,7 +58,7 @@ var (
 func (g *groupSequencer) Diagnostic(context.Context) any {
 	ret := struct {
 		Group *types.TableGroup
-		Mode  Mode
+		Mode  applycfg.Mode
 		Stat  sequencer.Stat
 	}{
 		Group: g.group,
@@ -86,7 +87,7 @@ func (g *groupSequencer) Start(
 
 	ctx.Go(func(ctx *stopper.Context) error {
 		_, err := stopvar.DoWhenChanged(ctx, initialMode, g.mode,
-			func(ctx *stopper.Context, old, new Mode) error {
+			func(ctx *stopper.Context, old, new applycfg.Mode) error {
 				g.mu.Lock()
 				defer g.mu.Unlock()
 				if err := g.switchModeLocked(ctx, opts, new); err == nil {
@@ -107,7 +108,7 @@ func (g *groupSequencer) Start(
 }
 
 func (g *groupSequencer) switchModeLocked(
-	ctx *stopper.Context, opts *sequencer.StartOptions, next Mode,
+	ctx *stopper.Context, opts *sequencer.StartOptions, next applycfg.Mode,
 ) error {
 	if g.mu.stopper != nil {
 		log.Tracef("%s: waiting for previous epoch to complete", g.group)
@@ -126,18 +127,18 @@ func (g *groupSequencer) switchModeLocked(
 	var err error
 	var nextSeq sequencer.Sequencer
 	switch next {
-	case ModeBestEffort:
+	case applycfg.ModeBestEffort:
 		nextSeq, err = g.staging.Wrap(ctx, g.core)
 		if err != nil {
 			return err
 		}
 		nextSeq, err = g.bestEffort.Wrap(ctx, nextSeq)
-	case ModeImmediate:
+	case applycfg.ModeImmediate:
 		nextSeq = g.immediate
 		// Immediate doesn't progess staged data.
 		opts = opts.Copy()
 		opts.BatchReader = nil
-	case ModeConsistent:
+	case applycfg.ModeConsistent:
 		nextSeq, err = g.staging.Wrap(ctx, g.core)
 	default:
 		return errors.Errorf("unimplemented: %v", next)
@@ -0,0 +1,120 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package switcher
+
+import (
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/applycfg"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	log "github.com/sirupsen/logrus"
+)
+
+// overrides returns the fixed mode to use for tables in the group,
+// based on the per-table configuration. Tables which should follow
+// the switcher's mode will not be present in the returned map.
+func (s *Switcher) overrides(group *types.TableGroup) (*ident.TableMap[applycfg.Mode], error) {
+	requested := &ident.TableMap[applycfg.Mode]{}
+	for _, table := range group.Tables {
+		cfg, _ := s.applyConfigs.Get(table).Get()
+		if cfg.Mode != applycfg.ModeUnknown {
+			requested.Put(table, cfg.Mode)
+		}
+	}
+	if requested.Len() == 0 {
+		return requested, nil
+	}
+
+	w, err := s.watchers.Get(group.Enclosing)
+	if err != nil {
+		return nil, err
+	}
+	return resolveOverrides(group.Tables, w.Get().Dependencies, requested), nil
+}
+
+// resolveOverrides extends each requested mode to all tables which are
+// connected to the requesting table by FK relationships, in either
+// direction. Connected tables must be applied by the same sequencer, so
+// that parent and child rows are written in a coordinated fashion. If
+// connected tables request different modes, the safest mode is chosen.
+func resolveOverrides(
+	tables []ident.Table,
+	parentsToChildren *ident.TableMap[[]ident.Table],
+	requested *ident.TableMap[applycfg.Mode],
+) *ident.TableMap[applycfg.Mode] {
+	// A union-find structure to identify connected tables.
+	parents := &ident.TableMap[ident.Table]{}
+	var find func(table ident.Table) ident.Table
+	find = func(table ident.Table) ident.Table {
+		parent, ok := parents.Get(table)
+		if !ok || ident.Equal(parent, table) {
+			return table
+		}
+		root := find(parent)
+		parents.Put(table, root)
+		return root
+	}
+	if parentsToChildren != nil {
+		for parent, children := range parentsToChildren.All() {
+			for _, child := range children {
+				if a, b := find(parent), find(child); !ident.Equal(a, b) {
+					parents.Put(a, b)
+				}
+			}
+		}
+	}
+
+	byRoot := &ident.TableMap[applycfg.Mode]{}
+	for table, mode := range requested.All() {
+		root := find(table)
+		if prev, ok := byRoot.Get(root); ok && prev != mode {
+			safest := safer(prev, mode)
+			log.Warnf("tables related to %s by foreign keys request both %s and %s; using %s",
+				table, prev, mode, safest)
+			mode = safest
+		}
+		byRoot.Put(root, mode)
+	}
+
+	ret := &ident.TableMap[applycfg.Mode]{}
+	for _, table := range tables {
+		if mode, ok := byRoot.Get(find(table)); ok {
+			ret.Put(table, mode)
+		}
+	}
+	return ret
+}
+
+// safer returns the mode which provides the stronger consistency
+// guarantees.
+func safer(a, b applycfg.Mode) applycfg.Mode {
+	rank := func(m applycfg.Mode) int {
+		switch m {
+		case applycfg.ModeConsistent:
+			return 3
+		case applycfg.ModeBestEffort:
+			return 2
+		case applycfg.ModeImmediate:
+			return 1
+		default:
+			return 0
+		}
+	}
+	if rank(b) > rank(a) {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,168 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package switcher
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/applycfg"
+	"github.com/cockroachdb/replicator/internal/util/hlc"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveOverrides(t *testing.T) {
+	r := require.New(t)
+	schema := ident.MustSchema(ident.New("db"), ident.Public)
+	tbl := func(name string) ident.Table {
+		return ident.NewTable(schema, ident.New(name))
+	}
+
+	// The parent and child are in different components, since the
+	// child is assigned to the component of its other parent.
+	parentA, parentB, child := tbl("parent_a"), tbl("parent_b"), tbl("child")
+	events, logs := tbl("events"), tbl("logs")
+	tables := []ident.Table{parentA, parentB, child, events, logs}
+	deps := &ident.TableMap[[]ident.Table]{}
+	deps.Put(parentA, []ident.Table{child})
+	deps.Put(parentB, []ident.Table{child})
+	deps.Put(events, nil)
+	deps.Put(logs, nil)
+
+	tcs := []struct {
+		name      string
+		requested map[ident.Table]applycfg.Mode
+		expected  map[ident.Table]applycfg.Mode
+	}{
+		{
+			name:      "unrelated",
+			requested: map[ident.Table]applycfg.Mode{events: applycfg.ModeImmediate},
+			expected:  map[ident.Table]applycfg.Mode{events: applycfg.ModeImmediate},
+		},
+		{
+			name:      "extends to related tables",
+			requested: map[ident.Table]applycfg.Mode{parentB: applycfg.ModeBestEffort},
+			expected: map[ident.Table]applycfg.Mode{
+				parentA: applycfg.ModeBestEffort,
+				parentB: applycfg.ModeBestEffort,
+				child:   applycfg.ModeBestEffort,
+			},
+		},
+		{
+			name: "conflicts use the safest mode",
+			requested: map[ident.Table]applycfg.Mode{
+				parentA: applycfg.ModeImmediate,
+				child:   applycfg.ModeConsistent,
+				logs:    applycfg.ModeImmediate,
+			},
+			expected: map[ident.Table]applycfg.Mode{
+				parentA: applycfg.ModeConsistent,
+				parentB: applycfg.ModeConsistent,
+				child:   applycfg.ModeConsistent,
+				logs:    applycfg.ModeImmediate,
+			},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			r := require.New(t)
+			requested := &ident.TableMap[applycfg.Mode]{}
+			for table, mode := range tc.requested {
+				requested.Put(table, mode)
+			}
+			found := resolveOverrides(tables, deps, requested)
+			r.Equal(len(tc.expected), found.Len())
+			for table, mode := range tc.expected {
+				r.Equal(mode, found.GetZero(table), table)
+			}
+		})
+	}
+
+	r.Equal(applycfg.ModeConsistent, safer(applycfg.ModeImmediate, applycfg.ModeConsistent))
+	r.Equal(applycfg.ModeBestEffort, safer(applycfg.ModeBestEffort, applycfg.ModeImmediate))
+}
+
+func TestRouter(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+	schema := ident.MustSchema(ident.New("db"), ident.Public)
+	tblA := ident.NewTable(schema, ident.New("a"))
+	tblB := ident.NewTable(schema, ident.New("b"))
+
+	accA, accB := &recorder{}, &recorder{}
+	rt := &router{routes: &ident.TableMap[types.MultiAcceptor]{}}
+	rt.routes.Put(tblA, accA)
+	rt.routes.Put(tblB, accB)
+
+	mut := func(nanos int64) types.Mutation {
+		return types.Mutation{
+			Data: json.RawMessage(`{"pk":1}`),
+			Key:  json.RawMessage(`[1]`),
+			Time: hlc.New(nanos, 0),
+		}
+	}
+	batch := &types.MultiBatch{}
+	r.NoError(batch.Accumulate(tblA, mut(1)))
+	r.NoError(batch.Accumulate(tblB, mut(1)))
+	r.NoError(batch.Accumulate(tblA, mut(2)))
+	r.NoError(rt.AcceptMultiBatch(ctx, batch, &types.AcceptOptions{}))
+	r.Equal(2, accA.count)
+	r.Equal(1, accB.count)
+
+	r.NoError(rt.AcceptTableBatch(ctx, &types.TableBatch{
+		Data:  []types.Mutation{mut(3)},
+		Table: tblB,
+		Time:  hlc.New(3, 0),
+	}, &types.AcceptOptions{}))
+	r.Equal(2, accB.count)
+
+	unknown := &types.MultiBatch{}
+	r.NoError(unknown.Accumulate(ident.NewTable(schema, ident.New("c")), mut(4)))
+	r.ErrorContains(rt.AcceptMultiBatch(ctx, unknown, &types.AcceptOptions{}), "unknown table")
+}
+
+// recorder counts the mutations that it receives.
+type recorder struct {
+	count int
+}
+
+var _ types.MultiAcceptor = (*recorder)(nil)
+
+func (r *recorder) AcceptMultiBatch(
+	_ context.Context, batch *types.MultiBatch, _ *types.AcceptOptions,
+) error {
+	r.count += batch.Count()
+	return nil
+}
+
+func (r *recorder) AcceptTableBatch(
+	_ context.Context, batch *types.TableBatch, _ *types.AcceptOptions,
+) error {
+	r.count += batch.Count()
+	return nil
+}
+
+func (r *recorder) AcceptTemporalBatch(
+	_ context.Context, batch *types.TemporalBatch, _ *types.AcceptOptions,
+) error {
+	r.count += batch.Count()
+	return nil
+}
@@ -22,10 +22,12 @@ import (
 	"github.com/cockroachdb/replicator/internal/sequencer/core"
 	"github.com/cockroachdb/replicator/internal/sequencer/decorators"
 	"github.com/cockroachdb/replicator/internal/sequencer/immediate"
+	"github.com/cockroachdb/replicator/internal/sequencer/pii"
 	"github.com/cockroachdb/replicator/internal/sequencer/scheduler"
 	"github.com/cockroachdb/replicator/internal/sequencer/script"
 	"github.com/cockroachdb/replicator/internal/sequencer/staging"
 	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/applycfg"
 	"github.com/cockroachdb/replicator/internal/util/diag"
 	"github.com/google/wire"
 )
@@ -37,6 +39,7 @@ var Set = wire.NewSet(
 	core.Set,
 	immediate.Set,
 	decorators.Set,
+	pii.Set,
 	script.Set,
 	scheduler.Set,
 	staging.Set,
@@ -46,6 +49,7 @@ var Set = wire.NewSet(
 
 // ProvideSequencer is called by Wire.
 func ProvideSequencer(
+	applyConfigs *applycfg.Configs,
 	best *besteffort.BestEffort,
 	core *core.Core,
 	diags *diag.Diagnostics,
@@ -53,14 +57,17 @@ func ProvideSequencer(
 	stg *staging.Staging,
 	stagingPool *types.StagingPool,
 	targetPool *types.TargetPool,
+	watchers types.Watchers,
 ) *Switcher {
 	return &Switcher{
-		bestEffort:  best,
-		core:        core,
-		diags:       diags,
-		immediate:   imm,
-		staging:     stg,
-		stagingPool: stagingPool,
-		targetPool:  targetPool,
+		applyConfigs: applyConfigs,
+		bestEffort:   best,
+		core:         core,
+		diags:        diags,
+		immediate:    imm,
+		staging:      stg,
+		stagingPool:  stagingPool,
+		targetPool:   targetPool,
+		watchers:     watchers,
 	}
 }
@@ -0,0 +1,157 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package switcher
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/cockroachdb/field-eng-powertools/notify"
+	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/field-eng-powertools/stopvar"
+	"github.com/cockroachdb/replicator/internal/sequencer"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/applycfg"
+	"github.com/cockroachdb/replicator/internal/util/hlc"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// startRoutes partitions the tables in the group by their overridden
+// mode and starts a groupSequencer for each partition. Tables without
+// an override follow the Switcher's mode variable. The returned
+// acceptor routes mutations to the partition that contains the table.
+func (s *Switcher) startRoutes(
+	ctx *stopper.Context, opts *sequencer.StartOptions, overrides *ident.TableMap[applycfg.Mode],
+) (types.MultiAcceptor, *notify.Var[sequencer.Stat], error) {
+	byMode := make(map[applycfg.Mode][]ident.Table)
+	for _, table := range opts.Group.Tables {
+		mode := overrides.GetZero(table)
+		byMode[mode] = append(byMode[mode], table)
+	}
+
+	// Ensure the initial map has all tables in it. This ensures that
+	// all tables must make some progress before the stat will advance.
+	statMap := &ident.TableMap[hlc.Range]{}
+	for _, table := range opts.Group.Tables {
+		statMap.Put(table, hlc.RangeEmpty())
+	}
+	stats := notify.VarOf(sequencer.NewStat(opts.Group, statMap))
+
+	ret := &router{routes: &ident.TableMap[types.MultiAcceptor]{}}
+	for mode := applycfg.ModeUnknown; mode <= applycfg.MaxMode; mode++ {
+		tables := byMode[mode]
+		if len(tables) == 0 {
+			continue
+		}
+		subOpts := opts.Copy()
+		subOpts.Group.Tables = tables
+
+		modeVar := s.mode
+		diagName := fmt.Sprintf("switcher-%s", opts.Group.Name.Raw())
+		if mode == applycfg.ModeUnknown {
+			log.Infof("%s: tables %s use the default mode", opts.Group, tables)
+		} else {
+			modeVar = notify.VarOf(mode)
+			diagName = fmt.Sprintf("%s-%s", diagName, mode)
+			log.Infof("%s: tables %s are configured to use %s", opts.Group, tables, mode)
+		}
+
+		subAcc, subStats, err := s.startGroup(ctx, subOpts, modeVar, diagName)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, table := range tables {
+			ret.routes.Put(table, subAcc)
+		}
+
+		// Aggregate the progress values together.
+		ctx.Go(func(ctx *stopper.Context) error {
+			_, err := stopvar.DoWhenChanged(ctx, nil, subStats,
+				func(ctx *stopper.Context, _, subStat sequencer.Stat) error {
+					_, _, err := stats.Update(func(old sequencer.Stat) (sequencer.Stat, error) {
+						next := old.Copy()
+						subStat.Progress().CopyInto(next.Progress())
+						return next, nil
+					})
+					return err
+				})
+			return err
+		})
+	}
+	return ret, stats, nil
+}
+
+// A router sends mutations to the sequencer which is responsible for
+// the target table.
+type router struct {
+	routes *ident.TableMap[types.MultiAcceptor]
+}
+
+var _ types.MultiAcceptor = (*router)(nil)
+
+// AcceptMultiBatch splits the batch based on destination tables.
+func (r *router) AcceptMultiBatch(
+	ctx context.Context, batch *types.MultiBatch, opts *types.AcceptOptions,
+) error {
+	var order []types.MultiAcceptor
+	split := make(map[types.MultiAcceptor]*types.MultiBatch)
+	for table, mut := range batch.Mutations() {
+		dest, ok := r.routes.Get(table)
+		if !ok {
+			return errors.Errorf("unknown table %s", table)
+		}
+		sub, ok := split[dest]
+		if !ok {
+			sub = &types.MultiBatch{}
+			split[dest] = sub
+			order = append(order, dest)
+		}
+		if err := sub.Accumulate(table, mut); err != nil {
+			return err
+		}
+	}
+	for _, dest := range order {
+		if err := dest.AcceptMultiBatch(ctx, split[dest], opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AcceptTableBatch passes through to the table's acceptor.
+func (r *router) AcceptTableBatch(
+	ctx context.Context, batch *types.TableBatch, opts *types.AcceptOptions,
+) error {
+	dest, ok := r.routes.Get(batch.Table)
+	if !ok {
+		return errors.Errorf("unknown table %s", batch.Table)
+	}
+	return dest.AcceptTableBatch(ctx, batch, opts)
+}
+
+// AcceptTemporalBatch delegates to AcceptMultiBatch.
+func (r *router) AcceptTemporalBatch(
+	ctx context.Context, batch *types.TemporalBatch, opts *types.AcceptOptions,
+) error {
+	multi := &types.MultiBatch{
+		ByTime: map[hlc.Time]*types.TemporalBatch{batch.Time: batch},
+		Data:   []*types.TemporalBatch{batch},
+	}
+	return r.AcceptMultiBatch(ctx, multi, opts)
+}
@@ -29,38 +29,30 @@ import (
 	"github.com/cockroachdb/replicator/internal/sequencer/immediate"
 	"github.com/cockroachdb/replicator/internal/sequencer/staging"
 	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/applycfg"
 	"github.com/cockroachdb/replicator/internal/util/diag"
 	"github.com/pkg/errors"
 )
 
-// Mode describes a strategy using another sequencer.
-//
-//go:generate go run golang.org/x/tools/cmd/stringer -type=Mode
-type Mode int
-
-// The modes of operation that can be dynamically selected from.
-const (
-	ModeUnknown Mode = iota
-	ModeBestEffort
-	ModeConsistent
-	ModeImmediate
-
-	MaxMode = iota - 1 // Used for testing all modes.
-	MinMode = Mode(1)  // Used for testing all modes.
-)
-
 // Switcher switches between delegate sequencers. It also adds script
 // bindings into the sequencer stack.
+//
+// Tables may be configured to use a fixed mode (see
+// [applycfg.Config.Mode]), in which case the tables in the group will
+// be partitioned by mode. Tables without a configured mode follow the
+// mode set by [Switcher.WithMode].
 type Switcher struct {
-	bestEffort  *besteffort.BestEffort
-	core        *core.Core
-	diags       *diag.Diagnostics
-	immediate   *immediate.Immediate
-	staging     *staging.Staging
-	stagingPool *types.StagingPool
-	targetPool  *types.TargetPool
+	applyConfigs *applycfg.Configs
+	bestEffort   *besteffort.BestEffort
+	core         *core.Core
+	diags        *diag.Diagnostics
+	immediate    *immediate.Immediate
+	staging      *staging.Staging
+	stagingPool  *types.StagingPool
+	targetPool   *types.TargetPool
+	watchers     types.Watchers
 
-	mode *notify.Var[Mode] // Set by WithMode.
+	mode *notify.Var[applycfg.Mode] // Set by WithMode.
 }
 
 var _ sequencer.Sequencer = (*Switcher)(nil)
@@ -77,17 +69,30 @@ func (s *Switcher) Start(
 	// Ensure the group is ready to go before returning. Otherwise,
 	// the accept methods wouldn't have anywhere to send mutations to.
 	initialMode, _ := mode.Get()
-	if initialMode == ModeUnknown {
+	if initialMode == applycfg.ModeUnknown {
 		return nil, nil, errors.New("the mode variable must be set before calling Start")
 	}
 
+	overrides, err := s.overrides(opts.Group)
+	if err != nil {
+		return nil, nil, err
+	}
+	if overrides.Len() > 0 {
+		return s.startRoutes(ctx, opts, overrides)
+	}
+	return s.startGroup(ctx, opts, mode, fmt.Sprintf("switcher-%s", opts.Group.Name.Raw()))
+}
+
+// startGroup starts a groupSequencer for all tables in the group.
+func (s *Switcher) startGroup(
+	ctx *stopper.Context, opts *sequencer.StartOptions, mode *notify.Var[applycfg.Mode], diagName string,
+) (types.MultiAcceptor, *notify.Var[sequencer.Stat], error) {
 	g := &groupSequencer{
 		Switcher: s,
 		group:    opts.Group,
 		mode:     mode,
 	}
 
-	diagName := fmt.Sprintf("switcher-%s", g.group.Name.Raw())
 	if err := s.diags.Register(diagName, g); err != nil {
 		return nil, nil, err
 	}
@@ -101,7 +106,7 @@ func (s *Switcher) Start(
 // WithMode returns a copy of the Switcher that uses the given variable
 // for mode control. This method exists so that Switcher can satisfy the
 // [sequencer.Sequencer] interface.
-func (s *Switcher) WithMode(mode *notify.Var[Mode]) *Switcher {
+func (s *Switcher) WithMode(mode *notify.Var[applycfg.Mode]) *Switcher {
 	ret := *s
 	ret.mode = mode
 	return &ret
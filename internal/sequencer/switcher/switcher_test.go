@@ -26,8 +26,8 @@ import (
 	"github.com/cockroachdb/field-eng-powertools/stopper"
 	"github.com/cockroachdb/replicator/internal/sequencer"
 	"github.com/cockroachdb/replicator/internal/sequencer/seqtest"
-	"github.com/cockroachdb/replicator/internal/sequencer/switcher"
 	"github.com/cockroachdb/replicator/internal/sinktest/all"
+	"github.com/cockroachdb/replicator/internal/util/applycfg"
 	"github.com/cockroachdb/replicator/internal/util/hlc"
 )
 
@@ -36,11 +36,11 @@ func TestSwitcher(t *testing.T) {
 		func(t *testing.T, fixture *all.Fixture, seqFixture *seqtest.Fixture) sequencer.Sequencer {
 			ctx := fixture.Context
 			// Ensure we cove both startup cases in CI.
-			var initial switcher.Mode
+			var initial applycfg.Mode
 			if rand.Float32() < 0.5 {
-				initial = switcher.ModeBestEffort
+				initial = applycfg.ModeBestEffort
 			} else {
-				initial = switcher.ModeConsistent
+				initial = applycfg.ModeConsistent
 			}
 			mode := notify.VarOf(initial)
 			seqFixture.BestEffort.SetTimeSource(hlc.Zero) // The test rig uses fake timestamps.
@@ -48,12 +48,12 @@ func TestSwitcher(t *testing.T) {
 				for {
 					select {
 					case <-time.After(time.Second):
-						_, _, _ = mode.Update(func(mode switcher.Mode) (switcher.Mode, error) {
+						_, _, _ = mode.Update(func(mode applycfg.Mode) (applycfg.Mode, error) {
 							switch mode {
-							case switcher.ModeBestEffort:
-								return switcher.ModeConsistent, nil
-							case switcher.ModeConsistent:
-								return switcher.ModeBestEffort, nil
+							case applycfg.ModeBestEffort:
+								return applycfg.ModeConsistent, nil
+							case applycfg.ModeConsistent:
+								return applycfg.ModeBestEffort, nil
 							default:
 								panic(fmt.Sprintf("unexpected state %s", mode))
 							}
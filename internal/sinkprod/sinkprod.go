@@ -27,9 +27,11 @@ import (
 // Set is used by Wire.
 var Set = wire.NewSet(
 	ProvideStagingDB,
+	ProvideStagingLocal,
 	ProvideStagingPool,
 	ProvideTargetPool,
 	ProvideStatementCache,
+	ProvideTableAcceptor,
 )
 
 const (
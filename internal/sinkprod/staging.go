@@ -25,8 +25,10 @@ import (
 	"github.com/cockroachdb/replicator/internal/types"
 	"github.com/cockroachdb/replicator/internal/util/diag"
 	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/cockroachdb/replicator/internal/util/kvstore"
 	"github.com/cockroachdb/replicator/internal/util/stdpool"
 	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
 	"github.com/spf13/pflag"
 )
 
@@ -41,6 +43,12 @@ type StagingConfig struct {
 	CommonConfig
 	// Create the destination schema.
 	CreateSchema bool
+	// If set, staged mutations, checkpoints, leases, and memos will be
+	// kept in an embedded store in this directory and no connection to
+	// a staging database will be made. Features which require SQL
+	// access to the staging database, such as JWT authentication, are
+	// unavailable.
+	LocalDir string
 	// The name of a SQL schema in the staging cluster to store
 	// metadata in.
 	Schema ident.Schema
@@ -52,6 +60,11 @@ func (c *StagingConfig) Bind(f *pflag.FlagSet) {
 	c.Schema = StagingSchemaDefault
 	f.BoolVar(&c.CreateSchema, "stagingCreateSchema", false,
 		"automatically create the staging schema if it does not exist")
+	f.StringVar(&c.LocalDir, "stagingLocalDir", "",
+		"a directory in which to keep staged mutations, checkpoints, leases, "+
+			"and memos in an embedded store instead of the staging database; "+
+			"the directory must not be shared with other processes; "+
+			"requires disableAuthentication when accepting HTTP requests")
 	f.Var(ident.NewSchemaFlag(&c.Schema), "stagingSchema",
 		"a SQL database schema to store metadata in")
 }
@@ -80,6 +93,12 @@ func ProvideStagingDB(
 		return ident.StagingSchema(config.Schema), nil
 	}
 
+	// There is no staging database in which to look for the legacy
+	// schema.
+	if config.LocalDir != "" {
+		return ident.StagingSchema(StagingSchemaDefault), nil
+	}
+
 	// Check for existence of legacy schema.
 	var exists bool
 	if err := pool.QueryRow(ctx,
@@ -94,12 +113,36 @@ func ProvideStagingDB(
 	return ident.StagingSchema(StagingSchemaDefault), nil
 }
 
+// ProvideStagingLocal is called by Wire to open the embedded store
+// that holds staging data if [StagingConfig.LocalDir] is set. Otherwise,
+// it returns nil. The store will be closed when the context is stopped.
+func ProvideStagingLocal(ctx *stopper.Context, config *StagingConfig) (*kvstore.Store, error) {
+	if config.LocalDir == "" {
+		return nil, nil
+	}
+	store, err := kvstore.Open(config.LocalDir)
+	if err != nil {
+		return nil, err
+	}
+	ctx.Defer(func() {
+		if err := store.Close(); err != nil {
+			log.WithError(err).Warn("could not close local staging store")
+		}
+	})
+	return store, nil
+}
+
 // ProvideStagingPool is called by Wire to create a connection pool that
 // accesses the staging cluster. The pool will be closed when the
-// context is stopped.
+// context is stopped. If [StagingConfig.LocalDir] is set, the returned
+// pool will not have a database connection.
 func ProvideStagingPool(
 	ctx *stopper.Context, config *StagingConfig, diags *diag.Diagnostics, tgtConfig *TargetConfig,
 ) (*types.StagingPool, error) {
+	if config.LocalDir != "" {
+		return stdpool.NewLocalStaging(), nil
+	}
+
 	// Use target endpoint if needed.
 	conn := config.Conn
 	if conn == "" {
@@ -17,9 +17,13 @@
 package sinkprod
 
 import (
+	"context"
 	"testing"
+	"time"
 
+	"github.com/cockroachdb/field-eng-powertools/stopper"
 	"github.com/cockroachdb/replicator/internal/sinktest/base"
+	"github.com/cockroachdb/replicator/internal/types"
 	"github.com/cockroachdb/replicator/internal/util/ident"
 	"github.com/stretchr/testify/require"
 )
@@ -55,3 +59,35 @@ func TestLegacyStagingName(t *testing.T) {
 	expect(StagingSchemaLegacy, StagingSchemaLegacy)
 	expect(foo, foo)
 }
+
+// TestLocalStaging verifies that no staging database is required when
+// an embedded store is configured.
+func TestLocalStaging(t *testing.T) {
+	r := require.New(t)
+	ctx := stopper.WithContext(context.Background())
+	defer ctx.Stop(time.Second)
+
+	cfg := &StagingConfig{LocalDir: t.TempDir()}
+	r.NoError(cfg.Preflight())
+
+	local, err := ProvideStagingLocal(ctx, cfg)
+	r.NoError(err)
+	r.NotNil(local)
+
+	// The target connection string is unset, so any attempt to
+	// connect would fail.
+	pool, err := ProvideStagingPool(ctx, cfg, nil, &TargetConfig{})
+	r.NoError(err)
+	r.Nil(pool.Pool)
+	r.NoError(pool.Ping(ctx))
+
+	stagingDB, err := ProvideStagingDB(ctx, cfg, pool)
+	r.NoError(err)
+	r.True(ident.Equal(StagingSchemaDefault, stagingDB.Schema()))
+
+	tx, err := pool.BeginStagingTx(ctx)
+	r.NoError(err)
+	_, err = tx.Exec(ctx, "SELECT 1")
+	r.ErrorIs(err, types.ErrNoStagingDB)
+	r.NoError(tx.Commit(ctx))
+}
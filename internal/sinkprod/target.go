@@ -21,6 +21,9 @@ import (
 
 	"github.com/cockroachdb/field-eng-powertools/stopper"
 	"github.com/cockroachdb/replicator/internal/staging/version"
+	"github.com/cockroachdb/replicator/internal/target/apply"
+	"github.com/cockroachdb/replicator/internal/target/kafka"
+	"github.com/cockroachdb/replicator/internal/target/objstore"
 	"github.com/cockroachdb/replicator/internal/types"
 	"github.com/cockroachdb/replicator/internal/util/diag"
 	"github.com/cockroachdb/replicator/internal/util/stdpool"
@@ -42,6 +45,13 @@ type TargetConfig struct {
 	// there may be more or fewer available resources to retain
 	// statements.
 	StatementCacheSize int
+
+	// If configured, mutations will be produced to Kafka instead of
+	// being applied to the target database.
+	Kafka kafka.Config
+	// If configured, mutations will be written to an object store
+	// instead of being applied to the target database.
+	ObjectStore objstore.Config
 }
 
 // Bind adds flags to the set.
@@ -52,6 +62,9 @@ func (c *TargetConfig) Bind(f *pflag.FlagSet) {
 		"the maximum amount of time to wait for an update to be applied")
 	f.IntVar(&c.StatementCacheSize, "targetStatementCacheSize", defaultCacheSize,
 		"the maximum number of prepared statements to retain")
+
+	c.Kafka.Bind(f)
+	c.ObjectStore.Bind(f)
 }
 
 // Preflight ensures that unset configuration options have sane defaults
@@ -64,7 +77,21 @@ func (c *TargetConfig) Preflight() error {
 	if c.StatementCacheSize == 0 {
 		c.StatementCacheSize = defaultCacheSize
 	}
-	return nil
+	if c.Kafka.Enabled() && c.ObjectStore.Enabled() {
+		return errors.New("only one of targetKafkaBroker or targetStorageURL may be set")
+	}
+	if err := c.Kafka.Preflight(); err != nil {
+		return err
+	}
+	return c.ObjectStore.Preflight()
+}
+
+// Redirected returns true if mutations are sent to Kafka or an object
+// store instead of being applied to the target database. Operations
+// that act directly upon the target tables, such as truncations or
+// schema changes, cannot be replicated in this case.
+func (c *TargetConfig) Redirected() bool {
+	return c.Kafka.Enabled() || c.ObjectStore.Enabled()
 }
 
 // ProvideTargetPool is called by Wire to create a connection pool that
@@ -115,3 +142,22 @@ func ProvideStatementCache(
 	}
 	return &types.TargetStatements{Cache: ret}, nil
 }
+
+// ProvideTableAcceptor is called by Wire to select the acceptor which
+// ultimately receives mutations. Unless a Kafka or object-store target
+// has been configured, this will be the acceptor which applies
+// mutations to the target database.
+func ProvideTableAcceptor(
+	ctx *stopper.Context, acc *apply.Acceptor, config *TargetConfig,
+) (types.TableAcceptor, error) {
+	switch {
+	case config.Kafka.Enabled():
+		log.Infof("producing mutations to Kafka brokers %v", config.Kafka.Brokers)
+		return kafka.NewAcceptor(ctx, &config.Kafka)
+	case config.ObjectStore.Enabled():
+		log.Info("writing mutations to an object store")
+		return objstore.NewAcceptor(ctx, &config.ObjectStore)
+	default:
+		return acc, nil
+	}
+}
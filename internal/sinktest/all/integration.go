@@ -44,10 +44,20 @@ const (
 	// variable in workflows/tests.yaml.
 	ObjectStoreName = "objstore"
 
+	// OracleName must be kept in alignment with the
+	// .github/docker-compose.yml file and the integration matrix
+	// variable in workflows/tests.yaml.
+	OracleName = "oracle"
+
 	// PostgreSQLName must be kept in alignment with the
 	// .github/docker-compose.yml file and the integration matrix
 	// variable in workflows/tests.yaml.
 	PostgreSQLName = "postgresql"
+
+	// SQLServerName must be kept in alignment with the
+	// .github/docker-compose.yml file and the integration matrix
+	// variable in workflows/tests.yaml.
+	SQLServerName = "sqlserver"
 )
 
 // IntegrationMain runs the tests if the value of IntegrationEnvName
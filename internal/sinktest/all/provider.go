@@ -47,7 +47,7 @@ var TestSet = wire.NewSet(
 var TestSetBase = wire.NewSet(
 	wire.FieldsOf(new(*base.Fixture),
 		"Context", "SourcePool", "SourceSchema",
-		"StagingPool", "StagingDB",
+		"StagingLocal", "StagingPool", "StagingDB",
 		"TargetCache", "TargetPool", "TargetSchema"),
 	diag.New,
 	staging.Set,
@@ -36,6 +36,10 @@ func NewFixture(t testing.TB) (*Fixture, error) {
 	if err != nil {
 		return nil, err
 	}
+	store, err := base.ProvideStagingLocal(context)
+	if err != nil {
+		return nil, err
+	}
 	stagingPool, err := base.ProvideStagingPool(context)
 	if err != nil {
 		return nil, err
@@ -57,6 +61,7 @@ func NewFixture(t testing.TB) (*Fixture, error) {
 		Context:      context,
 		SourcePool:   sourcePool,
 		SourceSchema: sourceSchema,
+		StagingLocal: store,
 		StagingPool:  stagingPool,
 		StagingDB:    stagingSchema,
 		TargetCache:  targetStatements,
@@ -71,11 +76,11 @@ func NewFixture(t testing.TB) (*Fixture, error) {
 	if err != nil {
 		return nil, err
 	}
-	memoMemo, err := memo.ProvideMemo(context, stagingPool, stagingSchema)
+	typesMemo, err := memo.ProvideMemo(context, stagingPool, store, stagingSchema)
 	if err != nil {
 		return nil, err
 	}
-	backup := schemawatch.ProvideBackup(memoMemo, stagingPool)
+	backup := schemawatch.ProvideBackup(typesMemo, stagingPool)
 	watchers, err := schemawatch.ProvideFactory(context, targetPool, diagnostics, backup)
 	if err != nil {
 		return nil, err
@@ -89,12 +94,12 @@ func NewFixture(t testing.TB) (*Fixture, error) {
 	if err != nil {
 		return nil, err
 	}
-	checkpoints, err := checkpoint.ProvideCheckpoints(context, stagingPool, stagingSchema)
+	checkpoints, err := checkpoint.ProvideCheckpoints(context, stagingPool, store, stagingSchema)
 	if err != nil {
 		return nil, err
 	}
-	stagers := stage.ProvideFactory(stagingPool, stagingSchema, context)
-	checker := version.ProvideChecker(stagingPool, memoMemo)
+	stagers := stage.ProvideFactory(stagingPool, store, stagingSchema, context)
+	checker := version.ProvideChecker(stagingPool, typesMemo)
 	watcher, err := ProvideWatcher(targetSchema, watchers)
 	if err != nil {
 		return nil, err
@@ -108,7 +113,7 @@ func NewFixture(t testing.TB) (*Fixture, error) {
 		DLQConfig:      config,
 		DLQs:           dlQs,
 		Loader:         loader,
-		Memo:           memoMemo,
+		Memo:           typesMemo,
 		Stagers:        stagers,
 		VersionChecker: checker,
 		Watchers:       watchers,
@@ -132,12 +137,13 @@ func NewFixtureFromBase(fixture *base.Fixture) (*Fixture, error) {
 	}
 	targetPool := fixture.TargetPool
 	stagingPool := fixture.StagingPool
+	store := fixture.StagingLocal
 	stagingSchema := fixture.StagingDB
-	memoMemo, err := memo.ProvideMemo(context, stagingPool, stagingSchema)
+	typesMemo, err := memo.ProvideMemo(context, stagingPool, store, stagingSchema)
 	if err != nil {
 		return nil, err
 	}
-	backup := schemawatch.ProvideBackup(memoMemo, stagingPool)
+	backup := schemawatch.ProvideBackup(typesMemo, stagingPool)
 	watchers, err := schemawatch.ProvideFactory(context, targetPool, diagnostics, backup)
 	if err != nil {
 		return nil, err
@@ -151,12 +157,12 @@ func NewFixtureFromBase(fixture *base.Fixture) (*Fixture, error) {
 	if err != nil {
 		return nil, err
 	}
-	checkpoints, err := checkpoint.ProvideCheckpoints(context, stagingPool, stagingSchema)
+	checkpoints, err := checkpoint.ProvideCheckpoints(context, stagingPool, store, stagingSchema)
 	if err != nil {
 		return nil, err
 	}
-	stagers := stage.ProvideFactory(stagingPool, stagingSchema, context)
-	checker := version.ProvideChecker(stagingPool, memoMemo)
+	stagers := stage.ProvideFactory(stagingPool, store, stagingSchema, context)
+	checker := version.ProvideChecker(stagingPool, typesMemo)
 	targetSchema := fixture.TargetSchema
 	watcher, err := ProvideWatcher(targetSchema, watchers)
 	if err != nil {
@@ -171,7 +177,7 @@ func NewFixtureFromBase(fixture *base.Fixture) (*Fixture, error) {
 		DLQConfig:      config,
 		DLQs:           dlQs,
 		Loader:         loader,
-		Memo:           memoMemo,
+		Memo:           typesMemo,
 		Stagers:        stagers,
 		VersionChecker: checker,
 		Watchers:       watchers,
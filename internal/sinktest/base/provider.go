@@ -35,6 +35,7 @@ import (
 	"github.com/cockroachdb/replicator/internal/types"
 	"github.com/cockroachdb/replicator/internal/util/diag"
 	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/cockroachdb/replicator/internal/util/kvstore"
 	"github.com/cockroachdb/replicator/internal/util/retry"
 	"github.com/cockroachdb/replicator/internal/util/stdpool"
 	"github.com/cockroachdb/replicator/internal/util/stmtcache"
@@ -50,6 +51,7 @@ const (
 	DummyPassword = "SoupOrSecret"
 
 	envSourceString  = "TEST_SOURCE_CONNECT"
+	envStagingLocal  = "TEST_STAGING_LOCAL"
 	envStagingString = "TEST_STAGING_CONNECT"
 	envTargetString  = "TEST_TARGET_CONNECT"
 
@@ -61,6 +63,7 @@ const (
 var (
 	sourceConn   *string
 	stagingConn  *string
+	stagingLocal *bool
 	targetString *string
 )
 
@@ -82,6 +85,9 @@ func init() {
 	stagingConn = flag.String("testStagingConnect", stagingConnect,
 		"the connection string to use for the staging db")
 
+	stagingLocal = flag.Bool("testStagingLocal", len(os.Getenv(envStagingLocal)) > 0,
+		"use an embedded store for staged mutations, checkpoints, leases, and memos")
+
 	targetConnect := defaultConnString
 	if found := os.Getenv(envTargetString); len(found) > 0 {
 		targetConnect = found
@@ -93,6 +99,7 @@ func init() {
 // TestSet is used by wire.
 var TestSet = wire.NewSet(
 	ProvideContext,
+	ProvideStagingLocal,
 	ProvideStagingSchema,
 	ProvideStagingPool,
 	ProvideSourcePool,
@@ -113,6 +120,7 @@ type Fixture struct {
 	Context      *stopper.Context        // The context for the test.
 	SourcePool   *types.SourcePool       // Access to user-data tables and changefeed creation.
 	SourceSchema sinktest.SourceSchema   // A container for tables within SourcePool.
+	StagingLocal *kvstore.Store          // Embedded staging store, if enabled.
 	StagingPool  *types.StagingPool      // Access to _replicator database.
 	StagingDB    ident.StagingSchema     // The _replicator SQL DATABASE.
 	TargetCache  *types.TargetStatements // Prepared statements.
@@ -274,6 +282,28 @@ func ProvideStagingPool(ctx *stopper.Context) (*types.StagingPool, error) {
 	return pool, nil
 }
 
+// ProvideStagingLocal returns an embedded store for staging data if the
+// -testStagingLocal flag is set. Otherwise, it returns nil.
+func ProvideStagingLocal(ctx *stopper.Context) (*kvstore.Store, error) {
+	if !*stagingLocal {
+		return nil, nil
+	}
+	dir, err := os.MkdirTemp("", "replicator-staging-")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	store, err := kvstore.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	ctx.Defer(func() {
+		_ = store.Close()
+		_ = os.RemoveAll(dir)
+	})
+	log.Infof("using local staging store in %s", dir)
+	return store, nil
+}
+
 // ProvideTargetPool connects to the target database (which is most
 // often the same as the source database).
 func ProvideTargetPool(
@@ -383,7 +413,8 @@ func provideSchema[P types.AnyPool](
 	ctx *stopper.Context, pool P, prefix string,
 ) (ident.Schema, error) {
 	switch pool.Info().Product {
-	case types.ProductCockroachDB, types.ProductMariaDB, types.ProductMySQL, types.ProductPostgreSQL:
+	case types.ProductCockroachDB, types.ProductMariaDB, types.ProductMySQL,
+		types.ProductPostgreSQL, types.ProductSQLServer:
 		return CreateSchema(ctx, pool, prefix)
 
 	case types.ProductOracle:
@@ -456,8 +487,16 @@ func CreateSchema[P types.AnyPool](
 		// Called from stopper.Context.Defer, so we must use Background.
 		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 		defer cancel()
-		err := retry.Execute(ctx, pool,
-			fmt.Sprintf("DROP DATABASE IF EXISTS %s %s", name, option))
+		stmt := fmt.Sprintf("DROP DATABASE IF EXISTS %s %s", name, option)
+		if pool.Info().Product == types.ProductSQLServer {
+			// SQL Server won't drop a database with open connections,
+			// nor does it support CASCADE.
+			stmt = fmt.Sprintf(`IF DB_ID(N'%[2]s') IS NOT NULL BEGIN
+ALTER DATABASE %[1]s SET SINGLE_USER WITH ROLLBACK IMMEDIATE;
+DROP DATABASE %[1]s;
+END`, name, name.Raw())
+		}
+		err := retry.Execute(ctx, pool, stmt)
 		log.WithError(err).WithField("target", name).Debug("dropped database")
 	}
 	ctx.Defer(cancel)
@@ -478,6 +517,8 @@ func CreateSchema[P types.AnyPool](
 	switch pool.Info().Product {
 	case types.ProductMariaDB, types.ProductMySQL:
 		sch, err = ident.NewSchema(name)
+	case types.ProductSQLServer:
+		sch, err = ident.NewSchema(name, ident.New("dbo"))
 	default:
 		sch, err = ident.NewSchema(name, ident.Public)
 	}
@@ -25,6 +25,10 @@ func NewFixture(t testing.TB) (*Fixture, error) {
 	if err != nil {
 		return nil, err
 	}
+	store, err := ProvideStagingLocal(context)
+	if err != nil {
+		return nil, err
+	}
 	stagingPool, err := ProvideStagingPool(context)
 	if err != nil {
 		return nil, err
@@ -46,6 +50,7 @@ func NewFixture(t testing.TB) (*Fixture, error) {
 		Context:      context,
 		SourcePool:   sourcePool,
 		SourceSchema: sourceSchema,
+		StagingLocal: store,
 		StagingPool:  stagingPool,
 		StagingDB:    stagingSchema,
 		TargetCache:  targetStatements,
@@ -257,7 +257,7 @@ func (r *request) schemaSegmentCount() int {
 		return 0
 	case types.ProductOracle, types.ProductMariaDB, types.ProductMySQL:
 		return 1 // e.g. MY_SCHEMA
-	case types.ProductCockroachDB, types.ProductPostgreSQL:
+	case types.ProductCockroachDB, types.ProductPostgreSQL, types.ProductSQLServer:
 		return 2 // e.g. MY_DB.MY_SCHEMA
 	default:
 		panic(fmt.Sprintf("unimplemented: %s", r.handler.TargetPool.Product))
@@ -20,6 +20,7 @@ import (
 	stagingProd "github.com/cockroachdb/replicator/internal/sinkprod"
 	"github.com/cockroachdb/replicator/internal/source/cdc"
 	"github.com/cockroachdb/replicator/internal/util/stdserver"
+	"github.com/pkg/errors"
 	"github.com/spf13/pflag"
 )
 
@@ -58,5 +59,10 @@ func (c *Config) Preflight() error {
 	if err := c.Target.Preflight(); err != nil {
 		return err
 	}
+	// The JWT authenticator keeps its keys in the staging database.
+	if c.Staging.LocalDir != "" && !c.HTTP.DisableAuth {
+		return errors.New("stagingLocalDir requires disableAuthentication, " +
+			"since authentication keys are kept in the staging database")
+	}
 	return nil
 }
@@ -203,7 +203,7 @@ func testIntegration(t *testing.T, cfg testConfig) {
 	r.NoError(err)
 	defer cancel()
 	// This is normally taken care of by stdlogical.Command.
-	stdlogical.AddHandlers(targetFixture.Authenticator, targetFixture.Server.GetServeMux(), targetFixture.Diagnostics)
+	stdlogical.AddHandlers(targetFixture.Authenticator, targetFixture.Server.GetServeMux(), targetFixture.Diagnostics, nil)
 
 	// Set up source and target tables.
 	source, err := sourceFixture.CreateSourceTable(ctx, "CREATE TABLE %s (pk INT PRIMARY KEY, val STRING)")
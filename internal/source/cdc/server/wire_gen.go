@@ -14,6 +14,7 @@ import (
 	"github.com/cockroachdb/replicator/internal/sequencer/core"
 	"github.com/cockroachdb/replicator/internal/sequencer/decorators"
 	"github.com/cockroachdb/replicator/internal/sequencer/immediate"
+	"github.com/cockroachdb/replicator/internal/sequencer/pii"
 	"github.com/cockroachdb/replicator/internal/sequencer/retire"
 	"github.com/cockroachdb/replicator/internal/sequencer/scheduler"
 	script2 "github.com/cockroachdb/replicator/internal/sequencer/script"
@@ -77,11 +78,15 @@ func NewServer(ctx *stopper.Context, config *Config) (*Server, error) {
 	if err != nil {
 		return nil, err
 	}
-	memoMemo, err := memo.ProvideMemo(ctx, stagingPool, stagingSchema)
+	store, err := sinkprod.ProvideStagingLocal(ctx, stagingConfig)
 	if err != nil {
 		return nil, err
 	}
-	checker := version.ProvideChecker(stagingPool, memoMemo)
+	typesMemo, err := memo.ProvideMemo(ctx, stagingPool, store, stagingSchema)
+	if err != nil {
+		return nil, err
+	}
+	checker := version.ProvideChecker(stagingPool, typesMemo)
 	targetPool, err := sinkprod.ProvideTargetPool(ctx, checker, targetConfig, diagnostics)
 	if err != nil {
 		return nil, err
@@ -91,7 +96,7 @@ func NewServer(ctx *stopper.Context, config *Config) (*Server, error) {
 		return nil, err
 	}
 	dlqConfig := cdc.ProvideDLQConfig(cdcConfig)
-	backup := schemawatch.ProvideBackup(memoMemo, stagingPool)
+	backup := schemawatch.ProvideBackup(typesMemo, stagingPool)
 	watchers, err := schemawatch.ProvideFactory(ctx, targetPool, diagnostics, backup)
 	if err != nil {
 		return nil, err
@@ -105,21 +110,33 @@ func NewServer(ctx *stopper.Context, config *Config) (*Server, error) {
 	if err != nil {
 		return nil, err
 	}
+	tableAcceptor, err := sinkprod.ProvideTableAcceptor(ctx, acceptor, targetConfig)
+	if err != nil {
+		return nil, err
+	}
 	conveyorConfig := cdc.ProvideConveyorConfig(cdcConfig)
-	checkpoints, err := checkpoint.ProvideCheckpoints(ctx, stagingPool, stagingSchema)
+	checkpoints, err := checkpoint.ProvideCheckpoints(ctx, stagingPool, store, stagingSchema)
 	if err != nil {
 		return nil, err
 	}
-	sequencer := script2.ProvideSequencer(loader, targetPool, watchers)
 	sequencerConfig := cdc.ProvideSequencerConfig(cdcConfig)
-	stagers := stage.ProvideFactory(stagingPool, stagingSchema, ctx)
+	keys, err := pii.ProvideKeys(sequencerConfig)
+	if err != nil {
+		return nil, err
+	}
+	piiPII, err := pii.ProvideShim(ctx, sequencerConfig, diagnostics, keys, watchers)
+	if err != nil {
+		return nil, err
+	}
+	sequencer := script2.ProvideSequencer(loader, targetPool, watchers)
+	stagers := stage.ProvideFactory(stagingPool, store, stagingSchema, ctx)
 	retireRetire := retire.ProvideRetire(sequencerConfig, stagingPool, stagers)
 	schedulerScheduler, err := scheduler.ProvideScheduler(ctx, sequencerConfig)
 	if err != nil {
 		return nil, err
 	}
 	bestEffort := besteffort.ProvideBestEffort(sequencerConfig, schedulerScheduler, stagers, stagingPool, watchers)
-	typesLeases, err := leases.ProvideLeases(ctx, stagingPool, stagingSchema)
+	typesLeases, err := leases.ProvideLeases(ctx, stagingPool, store, stagingSchema)
 	if err != nil {
 		return nil, err
 	}
@@ -129,8 +146,8 @@ func NewServer(ctx *stopper.Context, config *Config) (*Server, error) {
 	retryTarget := decorators.ProvideRetryTarget(targetPool)
 	immediateImmediate := immediate.ProvideImmediate(sequencerConfig, targetPool, marker, once, retryTarget, stagers)
 	stagingStaging := staging.ProvideStaging(sequencerConfig, marker, stagers, stagingPool)
-	switcherSwitcher := switcher.ProvideSequencer(bestEffort, coreCore, diagnostics, immediateImmediate, stagingStaging, stagingPool, targetPool)
-	conveyors, err := conveyor.ProvideConveyors(ctx, acceptor, conveyorConfig, checkpoints, sequencer, retireRetire, switcherSwitcher, watchers)
+	switcherSwitcher := switcher.ProvideSequencer(configs, bestEffort, coreCore, diagnostics, immediateImmediate, stagingStaging, stagingPool, targetPool, watchers)
+	conveyors, err := conveyor.ProvideConveyors(ctx, tableAcceptor, conveyorConfig, checkpoints, piiPII, sequencer, retireRetire, stagers, stagingPool, switcherSwitcher, watchers)
 	if err != nil {
 		return nil, err
 	}
@@ -147,6 +164,7 @@ func NewServer(ctx *stopper.Context, config *Config) (*Server, error) {
 	serverServer := &Server{
 		Server:        server,
 		Checkpoints:   checkpoints,
+		Conveyors:     conveyors,
 		StagingSchema: stagingSchema,
 		StagingPool:   stagingPool,
 		TargetPool:    targetPool,
@@ -178,12 +196,16 @@ func newTestFixture(context *stopper.Context, config *Config) (*testFixture, fun
 	if err != nil {
 		return nil, nil, err
 	}
-	memoMemo, err := memo.ProvideMemo(context, stagingPool, stagingSchema)
+	store, err := sinkprod.ProvideStagingLocal(context, stagingConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	typesMemo, err := memo.ProvideMemo(context, stagingPool, store, stagingSchema)
 	if err != nil {
 		return nil, nil, err
 	}
 	cdcConfig := &config.CDC
-	checker := version.ProvideChecker(stagingPool, memoMemo)
+	checker := version.ProvideChecker(stagingPool, typesMemo)
 	targetPool, err := sinkprod.ProvideTargetPool(context, checker, targetConfig, diagnostics)
 	if err != nil {
 		return nil, nil, err
@@ -197,7 +219,7 @@ func newTestFixture(context *stopper.Context, config *Config) (*testFixture, fun
 		return nil, nil, err
 	}
 	dlqConfig := cdc.ProvideDLQConfig(cdcConfig)
-	backup := schemawatch.ProvideBackup(memoMemo, stagingPool)
+	backup := schemawatch.ProvideBackup(typesMemo, stagingPool)
 	watchers, err := schemawatch.ProvideFactory(context, targetPool, diagnostics, backup)
 	if err != nil {
 		return nil, nil, err
@@ -211,8 +233,21 @@ func newTestFixture(context *stopper.Context, config *Config) (*testFixture, fun
 	if err != nil {
 		return nil, nil, err
 	}
+	tableAcceptor, err := sinkprod.ProvideTableAcceptor(context, acceptor, targetConfig)
+	if err != nil {
+		return nil, nil, err
+	}
 	conveyorConfig := cdc.ProvideConveyorConfig(cdcConfig)
-	checkpoints, err := checkpoint.ProvideCheckpoints(context, stagingPool, stagingSchema)
+	checkpoints, err := checkpoint.ProvideCheckpoints(context, stagingPool, store, stagingSchema)
+	if err != nil {
+		return nil, nil, err
+	}
+	sequencerConfig := cdc.ProvideSequencerConfig(cdcConfig)
+	keys, err := pii.ProvideKeys(sequencerConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+	piiPII, err := pii.ProvideShim(context, sequencerConfig, diagnostics, keys, watchers)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -222,15 +257,14 @@ func newTestFixture(context *stopper.Context, config *Config) (*testFixture, fun
 		return nil, nil, err
 	}
 	sequencer := script2.ProvideSequencer(scriptLoader, targetPool, watchers)
-	sequencerConfig := cdc.ProvideSequencerConfig(cdcConfig)
-	stagers := stage.ProvideFactory(stagingPool, stagingSchema, context)
+	stagers := stage.ProvideFactory(stagingPool, store, stagingSchema, context)
 	retireRetire := retire.ProvideRetire(sequencerConfig, stagingPool, stagers)
 	schedulerScheduler, err := scheduler.ProvideScheduler(context, sequencerConfig)
 	if err != nil {
 		return nil, nil, err
 	}
 	bestEffort := besteffort.ProvideBestEffort(sequencerConfig, schedulerScheduler, stagers, stagingPool, watchers)
-	typesLeases, err := leases.ProvideLeases(context, stagingPool, stagingSchema)
+	typesLeases, err := leases.ProvideLeases(context, stagingPool, store, stagingSchema)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -240,8 +274,8 @@ func newTestFixture(context *stopper.Context, config *Config) (*testFixture, fun
 	retryTarget := decorators.ProvideRetryTarget(targetPool)
 	immediateImmediate := immediate.ProvideImmediate(sequencerConfig, targetPool, marker, once, retryTarget, stagers)
 	stagingStaging := staging.ProvideStaging(sequencerConfig, marker, stagers, stagingPool)
-	switcherSwitcher := switcher.ProvideSequencer(bestEffort, coreCore, diagnostics, immediateImmediate, stagingStaging, stagingPool, targetPool)
-	conveyors, err := conveyor.ProvideConveyors(context, acceptor, conveyorConfig, checkpoints, sequencer, retireRetire, switcherSwitcher, watchers)
+	switcherSwitcher := switcher.ProvideSequencer(configs, bestEffort, coreCore, diagnostics, immediateImmediate, stagingStaging, stagingPool, targetPool, watchers)
+	conveyors, err := conveyor.ProvideConveyors(context, tableAcceptor, conveyorConfig, checkpoints, piiPII, sequencer, retireRetire, stagers, stagingPool, switcherSwitcher, watchers)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -260,7 +294,7 @@ func newTestFixture(context *stopper.Context, config *Config) (*testFixture, fun
 		Config:        config,
 		Diagnostics:   diagnostics,
 		Listener:      listener,
-		Memo:          memoMemo,
+		Memo:          typesMemo,
 		StagingPool:   stagingPool,
 		Server:        server,
 		StagingDB:     stagingSchema,
@@ -33,6 +33,8 @@ import (
 	"github.com/cockroachdb/replicator/internal/staging/checkpoint"
 	"github.com/cockroachdb/replicator/internal/staging/leases"
 	"github.com/cockroachdb/replicator/internal/target"
+	"github.com/cockroachdb/replicator/internal/target/apply"
+	"github.com/cockroachdb/replicator/internal/types"
 	"github.com/cockroachdb/replicator/internal/util/auth/trust"
 	"github.com/cockroachdb/replicator/internal/util/diag"
 	"github.com/google/wire"
@@ -49,7 +51,7 @@ func newTestFixture(*all.Fixture, *Config) (*testFixture, error) {
 	panic(wire.Build(
 		Set,
 		wire.FieldsOf(new(*base.Fixture),
-			"Context", "StagingDB", "StagingPool", "TargetCache", "TargetPool"),
+			"Context", "StagingDB", "StagingLocal", "StagingPool", "TargetCache", "TargetPool"),
 		wire.FieldsOf(new(*all.Fixture),
 			"Configs", "Fixture", "Stagers", "Memo"),
 		diag.New,
@@ -62,5 +64,6 @@ func newTestFixture(*all.Fixture, *Config) (*testFixture, error) {
 		trust.New, // Is valid to use as a provider.
 		wire.Struct(new(testFixture), "*"),
 		wire.Bind(new(context.Context), new(*stopper.Context)),
+		wire.Bind(new(types.TableAcceptor), new(*apply.Acceptor)),
 	))
 }
@@ -13,6 +13,7 @@ import (
 	"github.com/cockroachdb/replicator/internal/sequencer/core"
 	"github.com/cockroachdb/replicator/internal/sequencer/decorators"
 	"github.com/cockroachdb/replicator/internal/sequencer/immediate"
+	"github.com/cockroachdb/replicator/internal/sequencer/pii"
 	"github.com/cockroachdb/replicator/internal/sequencer/retire"
 	"github.com/cockroachdb/replicator/internal/sequencer/scheduler"
 	script2 "github.com/cockroachdb/replicator/internal/sequencer/script"
@@ -63,8 +64,17 @@ func newTestFixture(fixture *all.Fixture, config *Config) (*testFixture, error)
 		return nil, err
 	}
 	conveyorConfig := ProvideConveyorConfig(config)
+	store := baseFixture.StagingLocal
 	stagingSchema := baseFixture.StagingDB
-	checkpoints, err := checkpoint.ProvideCheckpoints(context, stagingPool, stagingSchema)
+	checkpoints, err := checkpoint.ProvideCheckpoints(context, stagingPool, store, stagingSchema)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := pii.ProvideKeys(sequencerConfig)
+	if err != nil {
+		return nil, err
+	}
+	piiPII, err := pii.ProvideShim(context, sequencerConfig, diagnostics, keys, watchers)
 	if err != nil {
 		return nil, err
 	}
@@ -75,7 +85,7 @@ func newTestFixture(fixture *all.Fixture, config *Config) (*testFixture, error)
 	}
 	sequencer := script2.ProvideSequencer(scriptLoader, targetPool, watchers)
 	retireRetire := retire.ProvideRetire(sequencerConfig, stagingPool, stagers)
-	typesLeases, err := leases.ProvideLeases(context, stagingPool, stagingSchema)
+	typesLeases, err := leases.ProvideLeases(context, stagingPool, store, stagingSchema)
 	if err != nil {
 		return nil, err
 	}
@@ -85,8 +95,8 @@ func newTestFixture(fixture *all.Fixture, config *Config) (*testFixture, error)
 	retryTarget := decorators.ProvideRetryTarget(targetPool)
 	immediateImmediate := immediate.ProvideImmediate(sequencerConfig, targetPool, marker, once, retryTarget, stagers)
 	stagingStaging := staging.ProvideStaging(sequencerConfig, marker, stagers, stagingPool)
-	switcherSwitcher := switcher.ProvideSequencer(bestEffort, coreCore, diagnostics, immediateImmediate, stagingStaging, stagingPool, targetPool)
-	conveyors, err := conveyor.ProvideConveyors(context, acceptor, conveyorConfig, checkpoints, sequencer, retireRetire, switcherSwitcher, watchers)
+	switcherSwitcher := switcher.ProvideSequencer(configs, bestEffort, coreCore, diagnostics, immediateImmediate, stagingStaging, stagingPool, targetPool, watchers)
+	conveyors, err := conveyor.ProvideConveyors(context, acceptor, conveyorConfig, checkpoints, piiPII, sequencer, retireRetire, stagers, stagingPool, switcherSwitcher, watchers)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,530 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package kafka
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/linkedin/goavro/v2"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sync/singleflight"
+)
+
+// Messages in the Confluent wire format begin with a zero byte and a
+// four-byte, big-endian schema id.
+const (
+	avroHeaderLength = 5
+	avroMagicByte    = 0
+)
+
+// An avroSchema contains a codec to decode binary data and the schema
+// definition, which is used to convert the generic values returned by
+// the codec into JSON-compatible values.
+type avroSchema struct {
+	codec *goavro.Codec
+	names map[string]any // Named types within the schema.
+	root  any            // The schema, as generic JSON.
+}
+
+// avroDecoder decodes messages that are in the Confluent wire format,
+// using writer schemas that are retrieved from a schema registry.
+type avroDecoder struct {
+	client   *http.Client
+	fetches  singleflight.Group // Coalesces concurrent fetches of a schema.
+	registry *url.URL
+
+	mu struct {
+		sync.RWMutex
+		schemas map[uint32]*avroSchema
+	}
+}
+
+// newAvroDecoder constructs a decoder that will retrieve schemas from
+// the Confluent schema registry at the given URL. HTTP basic
+// authentication may be configured by including credentials in the
+// URL.
+func newAvroDecoder(registry string) (*avroDecoder, error) {
+	u, err := url.Parse(registry)
+	if err != nil {
+		return nil, errors.Wrap(err, "malformed schema registry url")
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, errors.Errorf("unsupported schema registry url scheme %q", u.Scheme)
+	}
+	ret := &avroDecoder{
+		client:   &http.Client{Timeout: 30 * time.Second},
+		registry: u,
+	}
+	ret.mu.schemas = make(map[uint32]*avroSchema)
+	return ret, nil
+}
+
+// decode implements payloadDecoder.
+func (d *avroDecoder) decode(ctx context.Context, msg *sarama.ConsumerMessage) (*payload, error) {
+	ret := &payload{}
+	// Empty input is a no-op.
+	if len(msg.Value) == 0 {
+		return ret, nil
+	}
+	value, err := d.decodeValue(ctx, msg.Value)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not decode payload")
+	}
+	envelope, ok := value.(map[string]any)
+	if !ok {
+		return nil, errors.Errorf("expecting an envelope record, got %T", value)
+	}
+	if ret.Resolved, err = envelopeString(envelope, "resolved"); err != nil {
+		return nil, err
+	}
+	if ret.Resolved != "" {
+		return ret, nil
+	}
+	if ret.Updated, err = envelopeString(envelope, "updated"); err != nil {
+		return nil, err
+	}
+	if after := envelope["after"]; after != nil {
+		if ret.After, err = json.Marshal(after); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	if before := envelope["before"]; before != nil {
+		if ret.Before, err = json.Marshal(before); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	if len(msg.Key) > 0 {
+		if ret.Key, err = d.decodeKey(ctx, msg.Key); err != nil {
+			return nil, errors.Wrap(err, "could not decode key")
+		}
+	}
+	return ret, nil
+}
+
+// decodeKey converts a key record into a JSON array that contains the
+// record's fields in their declared order.
+func (d *avroDecoder) decodeKey(ctx context.Context, data []byte) (json.RawMessage, error) {
+	sch, native, err := d.decodeNative(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+	root := sch.root
+	if ref, ok := root.(string); ok {
+		if named, ok := sch.names[ref]; ok {
+			root = named
+		}
+	}
+	var key []any
+	if fields, ok := avroFields(root); ok {
+		record, ok := native.(map[string]any)
+		if !ok {
+			return nil, errors.Errorf("expecting a key record, got %T", native)
+		}
+		for _, field := range fields {
+			value, err := sch.toJSON(field.Type, record[field.Name])
+			if err != nil {
+				return nil, errors.Wrap(err, field.Name)
+			}
+			key = append(key, value)
+		}
+	} else {
+		value, err := sch.toJSON(sch.root, native)
+		if err != nil {
+			return nil, err
+		}
+		key = []any{value}
+	}
+	ret, err := json.Marshal(key)
+	return ret, errors.WithStack(err)
+}
+
+// decodeValue returns a JSON-compatible representation of the data.
+func (d *avroDecoder) decodeValue(ctx context.Context, data []byte) (any, error) {
+	sch, native, err := d.decodeNative(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+	return sch.toJSON(sch.root, native)
+}
+
+// decodeNative returns the schema and the value as decoded by goavro.
+func (d *avroDecoder) decodeNative(ctx context.Context, data []byte) (*avroSchema, any, error) {
+	if len(data) < avroHeaderLength || data[0] != avroMagicByte {
+		return nil, nil, errors.New("data is not in the Confluent wire format")
+	}
+	id := binary.BigEndian.Uint32(data[1:avroHeaderLength])
+	sch, err := d.schema(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	native, remaining, err := sch.codec.NativeFromBinary(data[avroHeaderLength:])
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "schema id %d", id)
+	}
+	if len(remaining) > 0 {
+		return nil, nil, errors.Errorf("%d unexpected trailing bytes", len(remaining))
+	}
+	return sch, native, nil
+}
+
+// schema returns the cached schema or retrieves it from the registry.
+// The lock is not held while the schema is being fetched, so decoding
+// messages with known schemas is not delayed by the registry.
+// Concurrent requests for the same schema share a single fetch, which
+// uses the context of the caller that started it.
+func (d *avroDecoder) schema(ctx context.Context, id uint32) (*avroSchema, error) {
+	d.mu.RLock()
+	found, ok := d.mu.schemas[id]
+	d.mu.RUnlock()
+	if ok {
+		return found, nil
+	}
+
+	ch := d.fetches.DoChan(fmt.Sprint(id), func() (any, error) {
+		// Another fetch may have completed since the cache was checked.
+		d.mu.RLock()
+		found, ok := d.mu.schemas[id]
+		d.mu.RUnlock()
+		if ok {
+			return found, nil
+		}
+		found, err := d.fetch(ctx, id)
+		if err != nil {
+			schemaFetchErrors.Inc()
+			return nil, err
+		}
+		d.mu.Lock()
+		d.mu.schemas[id] = found
+		d.mu.Unlock()
+		schemaFetchCount.Inc()
+		return found, nil
+	})
+	select {
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res.Val.(*avroSchema), nil
+	case <-ctx.Done():
+		return nil, errors.WithStack(ctx.Err())
+	}
+}
+
+// fetch retrieves a schema from the registry.
+func (d *avroDecoder) fetch(ctx context.Context, id uint32) (*avroSchema, error) {
+	u := *d.registry
+	u.User = nil
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		u.JoinPath("schemas", "ids", fmt.Sprint(id)).String(), nil)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	req.Header.Set("Accept", "application/vnd.schemaregistry.v1+json")
+	if user := d.registry.User; user != nil {
+		pass, _ := user.Password()
+		req.SetBasicAuth(user.Username(), pass)
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not fetch schema id %d", id)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("could not fetch schema id %d: %s", id, resp.Status)
+	}
+	var body struct {
+		Schema     string `json:"schema"`
+		SchemaType string `json:"schemaType"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, errors.Wrapf(err, "could not decode schema id %d", id)
+	}
+	if body.SchemaType != "" && body.SchemaType != "AVRO" {
+		return nil, errors.Errorf("schema id %d has unsupported type %s", id, body.SchemaType)
+	}
+	log.Debugf("fetched schema id %d: %s", id, body.Schema)
+	return newAvroSchema(body.Schema)
+}
+
+// newAvroSchema parses the schema definition.
+func newAvroSchema(schema string) (*avroSchema, error) {
+	codec, err := goavro.NewCodec(schema)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	ret := &avroSchema{
+		codec: codec,
+		names: make(map[string]any),
+	}
+	if err := json.Unmarshal([]byte(schema), &ret.root); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	ret.collectNames(ret.root, "")
+	return ret, nil
+}
+
+// An avroField is an element of a record schema.
+type avroField struct {
+	Name string
+	Type any
+}
+
+// avroFields returns the fields of a record schema.
+func avroFields(schema any) ([]avroField, bool) {
+	obj, ok := schema.(map[string]any)
+	if !ok || (obj["type"] != "record" && obj["type"] != "error") {
+		return nil, false
+	}
+	fields, _ := obj["fields"].([]any)
+	ret := make([]avroField, 0, len(fields))
+	for _, field := range fields {
+		if obj, ok := field.(map[string]any); ok {
+			name, _ := obj["name"].(string)
+			ret = append(ret, avroField{Name: name, Type: obj["type"]})
+		}
+	}
+	return ret, true
+}
+
+// avroFullName qualifies a type name with a namespace.
+func avroFullName(obj map[string]any, namespace string) (full string, enclosing string) {
+	name, _ := obj["name"].(string)
+	if ns, ok := obj["namespace"].(string); ok && ns != "" {
+		namespace = ns
+	}
+	if strings.Contains(name, ".") {
+		return name, name[:strings.LastIndex(name, ".")]
+	}
+	if namespace == "" {
+		return name, namespace
+	}
+	return namespace + "." + name, namespace
+}
+
+// collectNames records all named types, so that they may be referenced
+// regardless of traversal order. Types are registered under both their
+// full and short names.
+func (s *avroSchema) collectNames(schema any, namespace string) {
+	switch t := schema.(type) {
+	case []any:
+		for _, member := range t {
+			s.collectNames(member, namespace)
+		}
+	case map[string]any:
+		switch t["type"] {
+		case "record", "error", "enum", "fixed":
+			full, enclosing := avroFullName(t, namespace)
+			// Record the inherited namespace, so that the full name
+			// can be computed without knowing the enclosing type.
+			if _, ok := t["namespace"]; !ok && enclosing != "" {
+				t["namespace"] = enclosing
+			}
+			s.names[full] = t
+			if short, _ := t["name"].(string); short != full {
+				s.names[short] = t
+			}
+			if fields, ok := avroFields(t); ok {
+				for _, field := range fields {
+					s.collectNames(field.Type, enclosing)
+				}
+			}
+		case "array":
+			s.collectNames(t["items"], namespace)
+		case "map":
+			s.collectNames(t["values"], namespace)
+		default:
+			// A nested type definition, e.g. {"type": {"type": "map" ...}}
+			s.collectNames(t["type"], namespace)
+		}
+	}
+}
+
+// unionMemberMatches returns true if the name is used by goavro to
+// identify the member of a union. Logical types that goavro does not
+// support are identified by their underlying type.
+func (s *avroSchema) unionMemberMatches(member any, name string) bool {
+	switch t := member.(type) {
+	case string:
+		if named, ok := s.names[t].(map[string]any); ok {
+			full, _ := avroFullName(named, "")
+			return full == name
+		}
+		return t == name
+	case map[string]any:
+		switch typ := t["type"].(type) {
+		case string:
+			switch typ {
+			case "record", "error", "enum", "fixed":
+				full, _ := avroFullName(t, "")
+				return full == name
+			}
+			if logical, ok := t["logicalType"].(string); ok && typ+"."+logical == name {
+				return true
+			}
+			return typ == name
+		default:
+			return s.unionMemberMatches(typ, name)
+		}
+	}
+	return false
+}
+
+// toJSON converts a value returned by goavro into a form that is
+// compatible with the JSON payloads emitted by CockroachDB changefeeds.
+// Unions are unwrapped, decimals are converted to numbers, temporal
+// types are converted to strings, and bytes are hex-encoded.
+func (s *avroSchema) toJSON(schema any, value any) (any, error) {
+	if value == nil {
+		return nil, nil
+	}
+	switch t := schema.(type) {
+	case []any:
+		// A non-nil union is a map containing a single entry, keyed by
+		// the name of the member type.
+		wrapped, ok := value.(map[string]any)
+		if !ok || len(wrapped) != 1 {
+			return nil, errors.Errorf("expecting a union value, got %T", value)
+		}
+		for name, inner := range wrapped {
+			for _, member := range t {
+				if s.unionMemberMatches(member, name) {
+					return s.toJSON(member, inner)
+				}
+			}
+			return nil, errors.Errorf("unknown union member %q", name)
+		}
+
+	case string:
+		if named, ok := s.names[t]; ok {
+			return s.toJSON(named, value)
+		}
+		return avroPrimitive(value), nil
+
+	case map[string]any:
+		switch t["type"] {
+		case "record", "error":
+			record, ok := value.(map[string]any)
+			if !ok {
+				return nil, errors.Errorf("expecting a record, got %T", value)
+			}
+			fields, _ := avroFields(t)
+			ret := make(map[string]any, len(fields))
+			for _, field := range fields {
+				converted, err := s.toJSON(field.Type, record[field.Name])
+				if err != nil {
+					return nil, errors.Wrap(err, field.Name)
+				}
+				ret[field.Name] = converted
+			}
+			return ret, nil
+
+		case "array":
+			elts, ok := value.([]any)
+			if !ok {
+				return nil, errors.Errorf("expecting an array, got %T", value)
+			}
+			ret := make([]any, len(elts))
+			for idx, elt := range elts {
+				converted, err := s.toJSON(t["items"], elt)
+				if err != nil {
+					return nil, err
+				}
+				ret[idx] = converted
+			}
+			return ret, nil
+
+		case "map":
+			m, ok := value.(map[string]any)
+			if !ok {
+				return nil, errors.Errorf("expecting a map, got %T", value)
+			}
+			ret := make(map[string]any, len(m))
+			for k, v := range m {
+				converted, err := s.toJSON(t["values"], v)
+				if err != nil {
+					return nil, err
+				}
+				ret[k] = converted
+			}
+			return ret, nil
+		}
+
+		switch t["logicalType"] {
+		case "decimal":
+			if rat, ok := value.(*big.Rat); ok {
+				scale, _ := t["scale"].(float64)
+				return json.Number(rat.FloatString(int(scale))), nil
+			}
+		case "date":
+			if tm, ok := value.(time.Time); ok {
+				return tm.UTC().Format(time.DateOnly), nil
+			}
+		case "time-millis", "time-micros":
+			if d, ok := value.(time.Duration); ok {
+				return formatTimeOfDay(d), nil
+			}
+		}
+		if inner, ok := t["type"]; ok {
+			if _, isString := inner.(string); !isString {
+				return s.toJSON(inner, value)
+			}
+		}
+		return avroPrimitive(value), nil
+	}
+	return nil, errors.Errorf("unexpected schema %v", schema)
+}
+
+// avroPrimitive converts goavro's representation of primitive types.
+func avroPrimitive(value any) any {
+	switch t := value.(type) {
+	case []byte:
+		return `\x` + hex.EncodeToString(t)
+	case time.Time:
+		return t.UTC().Format(time.RFC3339Nano)
+	default:
+		return value
+	}
+}
+
+// formatTimeOfDay formats a duration since midnight.
+func formatTimeOfDay(d time.Duration) string {
+	return time.Time{}.Add(d).Format("15:04:05.999999")
+}
+
+// envelopeString extracts an optional string field.
+func envelopeString(envelope map[string]any, key string) (string, error) {
+	switch t := envelope[key].(type) {
+	case nil:
+		return "", nil
+	case string:
+		return t, nil
+	default:
+		return "", errors.Errorf("expecting a string for %q, got %T", key, t)
+	}
+}
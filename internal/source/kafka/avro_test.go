@@ -0,0 +1,334 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package kafka
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/cockroachdb/replicator/internal/util/hlc"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/linkedin/goavro/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testKeySchema = `{
+  "type": "record", "name": "foo_key", "fields": [
+    {"name": "k", "type": "long"},
+    {"name": "s", "type": ["null", "string"]}
+  ]}`
+	testValueSchema = `{
+  "type": "record", "name": "foo_envelope", "fields": [
+    {"name": "after", "type": ["null", {
+      "type": "record", "name": "foo", "namespace": "ns", "fields": [
+        {"name": "k", "type": "long"},
+        {"name": "v", "type": ["null", "string"]},
+        {"name": "d", "type": ["null", {"type": "bytes", "logicalType": "decimal", "precision": 10, "scale": 2}]},
+        {"name": "ts", "type": ["null", {"type": "long", "logicalType": "timestamp-micros"}]},
+        {"name": "dt", "type": ["null", {"type": "int", "logicalType": "date"}]},
+        {"name": "u", "type": ["null", {"type": "string", "logicalType": "uuid"}]},
+        {"name": "b", "type": ["null", "bytes"]},
+        {"name": "a", "type": ["null", {"type": "array", "items": ["null", "long"]}]}
+      ]}]},
+    {"name": "before", "type": ["null", "ns.foo"]},
+    {"name": "updated", "type": ["null", "string"]}
+  ]}`
+	testResolvedSchema = `{
+  "type": "record", "name": "resolved", "fields": [
+    {"name": "resolved", "type": "string"}
+  ]}`
+)
+
+// stubRegistry serves schemas by id, using the subset of the Confluent
+// schema registry API that is used by the decoder.
+func stubRegistry(t *testing.T, schemas map[int]string) (*httptest.Server, *atomic.Int32) {
+	var count atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		count.Add(1)
+		id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/schemas/ids/"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		schema, ok := schemas[id]
+		if !ok {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/vnd.schemaregistry.v1+json")
+		_ = json.NewEncoder(w).Encode(map[string]string{"schema": schema})
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &count
+}
+
+// encodeAvro returns the value in the Confluent wire format.
+func encodeAvro(t *testing.T, id uint32, schema string, value any) []byte {
+	codec, err := goavro.NewCodec(schema)
+	require.NoError(t, err)
+	buf := []byte{avroMagicByte, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(buf[1:], id)
+	buf, err = codec.BinaryFromNative(buf, value)
+	require.NoError(t, err)
+	return buf
+}
+
+// TestAvroDecoder verifies that Avro-encoded messages are converted
+// into the same payloads that would be produced by the JSON format.
+func TestAvroDecoder(t *testing.T) {
+	r := require.New(t)
+	srv, fetches := stubRegistry(t, map[int]string{
+		1: testKeySchema,
+		2: testValueSchema,
+		3: testResolvedSchema,
+	})
+	dec, err := newAvroDecoder(srv.URL)
+	r.NoError(err)
+
+	key := encodeAvro(t, 1, testKeySchema, map[string]any{
+		"k": int64(42),
+		"s": goavro.Union("string", "hello"),
+	})
+	after := goavro.Union("ns.foo", map[string]any{
+		"k":  int64(42),
+		"v":  goavro.Union("string", "world"),
+		"d":  goavro.Union("bytes.decimal", big.NewRat(12345, 100)),
+		"ts": goavro.Union("long.timestamp-micros", time.Date(2024, 1, 2, 3, 4, 5, 6000, time.UTC)),
+		"dt": goavro.Union("int.date", time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)),
+		"u":  goavro.Union("string", "7f8b8e4a-7d7f-4b3c-9b8a-3c1f0d2f5e6a"),
+		"b":  goavro.Union("bytes", []byte{0xca, 0xfe}),
+		"a":  goavro.Union("array", []any{goavro.Union("long", int64(1)), nil}),
+	})
+
+	tcs := []struct {
+		name    string
+		msg     *sarama.ConsumerMessage
+		want    *payload
+		wantErr string
+	}{
+		{
+			name: "insert",
+			msg: &sarama.ConsumerMessage{
+				Key: key,
+				Value: encodeAvro(t, 2, testValueSchema, map[string]any{
+					"after":   after,
+					"before":  nil,
+					"updated": goavro.Union("string", "1.0"),
+				}),
+			},
+			want: &payload{
+				After: json.RawMessage(`{"a":[1,null],"b":"\\xcafe","d":123.45,"dt":"2024-01-02",` +
+					`"k":42,"ts":"2024-01-02T03:04:05.000006Z","u":"7f8b8e4a-7d7f-4b3c-9b8a-3c1f0d2f5e6a","v":"world"}`),
+				Key:     json.RawMessage(`[42,"hello"]`),
+				Updated: "1.0",
+			},
+		},
+		{
+			name: "delete",
+			msg: &sarama.ConsumerMessage{
+				Key: key,
+				Value: encodeAvro(t, 2, testValueSchema, map[string]any{
+					"after":   nil,
+					"before":  after,
+					"updated": goavro.Union("string", "2.0"),
+				}),
+			},
+			want: &payload{
+				Before: json.RawMessage(`{"a":[1,null],"b":"\\xcafe","d":123.45,"dt":"2024-01-02",` +
+					`"k":42,"ts":"2024-01-02T03:04:05.000006Z","u":"7f8b8e4a-7d7f-4b3c-9b8a-3c1f0d2f5e6a","v":"world"}`),
+				Key:     json.RawMessage(`[42,"hello"]`),
+				Updated: "2.0",
+			},
+		},
+		{
+			name: "resolved",
+			msg: &sarama.ConsumerMessage{
+				Value: encodeAvro(t, 3, testResolvedSchema, map[string]any{
+					"resolved": "3.0",
+				}),
+			},
+			want: &payload{
+				Resolved: "3.0",
+			},
+		},
+		{
+			name: "empty",
+			msg:  &sarama.ConsumerMessage{},
+			want: &payload{},
+		},
+		{
+			name:    "not wire format",
+			msg:     &sarama.ConsumerMessage{Value: []byte(`{"after": {}}`)},
+			wantErr: "not in the Confluent wire format",
+		},
+		{
+			name: "unknown schema",
+			msg: &sarama.ConsumerMessage{
+				Value: encodeAvro(t, 99, testResolvedSchema, map[string]any{
+					"resolved": "3.0",
+				}),
+			},
+			wantErr: "could not fetch schema id 99",
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			r := require.New(t)
+			got, err := dec.decode(context.Background(), tc.msg)
+			if tc.wantErr != "" {
+				r.ErrorContains(err, tc.wantErr)
+				return
+			}
+			r.NoError(err)
+			r.Equal(tc.want.Resolved, got.Resolved)
+			r.Equal(tc.want.Updated, got.Updated)
+			requireJSONEq(t, tc.want.After, got.After)
+			requireJSONEq(t, tc.want.Before, got.Before)
+			requireJSONEq(t, tc.want.Key, got.Key)
+		})
+	}
+
+	// Schemas are cached; only the failed lookup is retried.
+	r.Equal(int32(4), fetches.Load())
+	_, err = dec.decode(context.Background(), tcs[0].msg)
+	r.NoError(err)
+	r.Equal(int32(4), fetches.Load())
+}
+
+// TestAvroSchemaFetch verifies that a slow schema registry does not
+// delay the decoding of messages whose schemas are cached, and that
+// concurrent requests for a schema share a single fetch.
+func TestAvroSchemaFetch(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+
+	var fetches atomic.Int32
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/schemas/ids/2" {
+			fetches.Add(1)
+			select {
+			case <-release:
+			case <-req.Context().Done():
+				return
+			}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]string{"schema": testKeySchema})
+	}))
+	t.Cleanup(srv.Close)
+	dec, err := newAvroDecoder(srv.URL)
+	r.NoError(err)
+
+	cached, err := dec.schema(ctx, 1)
+	r.NoError(err)
+
+	// Start several lookups of a schema that will be blocked.
+	const waiters = 5
+	results := make(chan *avroSchema, waiters)
+	for range waiters {
+		go func() {
+			sch, err := dec.schema(ctx, 2)
+			assert.NoError(t, err)
+			results <- sch
+		}()
+	}
+	r.Eventually(func() bool { return fetches.Load() == 1 },
+		10*time.Second, time.Millisecond)
+
+	// Cached schemas are still available.
+	found, err := dec.schema(ctx, 1)
+	r.NoError(err)
+	r.Same(cached, found)
+
+	// A caller may stop waiting for the fetch.
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = dec.schema(canceled, 2)
+	r.ErrorIs(err, context.Canceled)
+
+	close(release)
+	first := <-results
+	r.NotNil(first)
+	for range waiters - 1 {
+		r.Same(first, <-results)
+	}
+	r.Equal(int32(1), fetches.Load())
+}
+
+// TestAvroConsumer verifies that decoded keys are used by the
+// consumer.
+func TestAvroConsumer(t *testing.T) {
+	r := require.New(t)
+	srv, _ := stubRegistry(t, map[int]string{
+		1: testKeySchema,
+		2: testValueSchema,
+	})
+	dec, err := newAvroDecoder(srv.URL)
+	r.NoError(err)
+
+	consumer := &Consumer{
+		decoder:   dec.decode,
+		schema:    ident.MustSchema(ident.New("db"), ident.New("public")),
+		timeRange: hlc.RangeIncluding(hlc.Zero(), hlc.New(10, 0)),
+	}
+	batch := newPartitionBatch()
+	_, err = consumer.accumulate(context.Background(), batch, hlc.Zero(), &sarama.ConsumerMessage{
+		Topic: "foo",
+		Key: encodeAvro(t, 1, testKeySchema, map[string]any{
+			"k": int64(1),
+			"s": nil,
+		}),
+		Value: encodeAvro(t, 2, testValueSchema, map[string]any{
+			"after": goavro.Union("ns.foo", map[string]any{
+				"k":  int64(1),
+				"v":  nil,
+				"d":  nil,
+				"ts": nil,
+				"dt": nil,
+				"u":  nil,
+				"b":  nil,
+				"a":  nil,
+			}),
+			"before":  nil,
+			"updated": goavro.Union("string", "1.0"),
+		}),
+	})
+	r.NoError(err)
+	r.Equal(1, batch.Count())
+	r.Contains(batch.keys, `[1,null]`)
+}
+
+func requireJSONEq(t *testing.T, expected, actual json.RawMessage) {
+	t.Helper()
+	if expected == nil {
+		require.Nil(t, actual)
+		return
+	}
+	require.JSONEq(t, string(expected), string(actual))
+}
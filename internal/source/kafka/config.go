@@ -56,16 +56,21 @@ type Config struct {
 	TargetSchema     ident.Schema
 	BatchSize        int           // How many messages to accumulate before committing to the target
 	Brokers          []string      // The address of the Kafka brokers
+	Format           string        // The format of the messages.
 	Group            string        // the Kafka consumer group id.
 	MaxTimestamp     string        // Only accept messages at or older than this timestamp
 	MinTimestamp     string        // Only accept messages at or newer than this timestamp
 	ResolvedInterval time.Duration // Minimal duration between resolved timestamps.
 	SASL             SASLConfig    // SASL parameters
+	SchemaRegistry   string        // The URL of a Confluent schema registry.
 	Strategy         string        // Kafka consumer group re-balance strategy
 	Topics           []string      // The list of topics that the consumer should use.
 
 	// The following are computed.
 
+	// Extracts mutations from messages, based on the message format.
+	decoder payloadDecoder
+
 	// The kafka connector configuration.
 	saramaConfig *sarama.Config
 	// Timestamp range, computed based on minTimestamp and maxTimestamp.
@@ -98,6 +103,8 @@ func (c *Config) Bind(f *pflag.FlagSet) {
 
 	f.IntVar(&c.BatchSize, "batchSize", 100, "messages to accumulate before committing to the target")
 	f.StringArrayVar(&c.Brokers, "broker", nil, "address of Kafka broker(s)")
+	f.StringVar(&c.Format, "format", formatCRDB,
+		"the format of the messages; one of crdb or debezium")
 	f.StringVar(&c.Group, "group", "", "the Kafka consumer group id")
 	f.StringVar(&c.MaxTimestamp, "maxTimestamp", "",
 		"only accept messages older than this timestamp; this is an exclusive upper limit")
@@ -115,6 +122,9 @@ the configured min_checkpoint_frequency specified in CREATE CHANGEFEED
 command (but may be emitted less frequently).
 Please see the CREATE CHANGEFEED documentation for details.
 `)
+	f.StringVar(&c.SchemaRegistry, "schemaRegistry", "",
+		"the URL of a Confluent schema registry; if set, messages are decoded as Avro "+
+			"(e.g. a changefeed created with format=avro)")
 	f.StringVar(&c.Strategy, "strategy", "sticky", "Kafka consumer group re-balance strategy")
 	f.StringArrayVar(&c.Topics, "topic", nil, "the topic(s) that the consumer should use")
 
@@ -194,6 +204,32 @@ func (c *Config) preflight(ctx context.Context) error {
 	}
 	c.timeRange = hlc.RangeExcluding(minTimestamp, maxTimestamp)
 	log.Infof("Kafka time range %s", c.timeRange)
+	var avro *avroDecoder
+	if c.SchemaRegistry != "" {
+		if avro, err = newAvroDecoder(c.SchemaRegistry); err != nil {
+			return err
+		}
+	}
+	switch c.Format {
+	case "", formatCRDB:
+		if avro == nil {
+			c.decoder = asPayload
+		} else {
+			c.decoder = avro.decode
+		}
+	case formatDebezium:
+		// Seeking relies on resolved timestamp messages.
+		if c.MinTimestamp != "" {
+			return errors.New("minTimestamp is not supported with the debezium format")
+		}
+		if avro == nil {
+			c.decoder = asDebeziumPayload
+		} else {
+			c.decoder = avro.decodeDebezium
+		}
+	default:
+		return errors.Errorf("unknown format %q", c.Format)
+	}
 	sc := sarama.NewConfig()
 	switch c.Strategy {
 	case "sticky":
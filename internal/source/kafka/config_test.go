@@ -476,6 +476,69 @@ func TestPreflight(t *testing.T) {
 			},
 			wantErr: "OAUTH2 requires a client id",
 		},
+		{
+			name: "schema registry",
+			in: &Config{
+				Group:            "mygroup",
+				Brokers:          []string{"mybroker"},
+				ResolvedInterval: time.Second,
+				SchemaRegistry:   "http://registry:8081",
+				Topics:           []string{"mytopic"},
+				Strategy:         "sticky",
+			},
+			strategy:  []sarama.BalanceStrategy{sarama.NewBalanceStrategySticky()},
+			timeRange: maxRange,
+		},
+		{
+			name: "bad schema registry",
+			in: &Config{
+				Group:            "mygroup",
+				Brokers:          []string{"mybroker"},
+				ResolvedInterval: time.Second,
+				SchemaRegistry:   "registry:8081",
+				Topics:           []string{"mytopic"},
+				Strategy:         "sticky",
+			},
+			wantErr: "unsupported schema registry url scheme",
+		},
+		{
+			name: "debezium",
+			in: &Config{
+				Format:           formatDebezium,
+				Group:            "mygroup",
+				Brokers:          []string{"mybroker"},
+				ResolvedInterval: time.Second,
+				Topics:           []string{"mytopic"},
+				Strategy:         "sticky",
+			},
+			strategy:  []sarama.BalanceStrategy{sarama.NewBalanceStrategySticky()},
+			timeRange: maxRange,
+		},
+		{
+			name: "debezium min timestamp",
+			in: &Config{
+				Format:           formatDebezium,
+				Group:            "mygroup",
+				Brokers:          []string{"mybroker"},
+				MinTimestamp:     "1.0",
+				ResolvedInterval: time.Second,
+				Topics:           []string{"mytopic"},
+				Strategy:         "sticky",
+			},
+			wantErr: "minTimestamp is not supported with the debezium format",
+		},
+		{
+			name: "unknown format",
+			in: &Config{
+				Format:           "protobuf",
+				Group:            "mygroup",
+				Brokers:          []string{"mybroker"},
+				ResolvedInterval: time.Second,
+				Topics:           []string{"mytopic"},
+				Strategy:         "sticky",
+			},
+			wantErr: `unknown format "protobuf"`,
+		},
 	}
 	t.Parallel()
 	for _, test := range tests {
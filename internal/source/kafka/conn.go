@@ -17,6 +17,7 @@
 package kafka
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -31,8 +32,6 @@ import (
 // Conn encapsulates all wire-connection behavior. It is
 // responsible for receiving replication messages and replying with
 // status updates.
-// TODO (silvano): support Avro format, schema registry.
-// https://github.com/cockroachdb/cdc-sink/issues/776
 type Conn struct {
 	// The connector configuration.
 	config *Config
@@ -56,7 +55,7 @@ type offsetRange struct {
 func (c *Conn) Start(ctx *stopper.Context) (err error) {
 	var start []*partitionState
 	if c.config.MinTimestamp != "" {
-		start, err = c.getOffsets(c.config.timeRange.Min())
+		start, err = c.getOffsets(ctx, c.config.timeRange.Min())
 		if err != nil {
 			return errors.Wrap(err, "cannot get offsets")
 		}
@@ -69,9 +68,12 @@ func (c *Conn) Start(ctx *stopper.Context) (err error) {
 	c.consumer = &Consumer{
 		conveyor:  c.conveyor,
 		batchSize: c.config.BatchSize,
+		decoder:   c.config.decoder,
+		format:    c.config.Format,
 		schema:    c.config.TargetSchema,
 		timeRange: c.config.timeRange,
 		fromState: start,
+		committed: c.committedMetadata,
 	}
 
 	// Start a process to copy data to the target.
@@ -136,14 +138,41 @@ func (c *Conn) ensureCheckpoints(ctx *stopper.Context) error {
 	return nil
 }
 
+// committedMetadata returns the metadata of the consumer group's
+// committed offsets for the given partitions, keyed by
+// topicPartitionID. Partitions without metadata are omitted.
+func (c *Conn) committedMetadata(claims map[string][]int32) (map[string]string, error) {
+	admin, err := sarama.NewClusterAdmin(c.config.Brokers, c.config.saramaConfig)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to instantiate admin client")
+	}
+	defer admin.Close()
+	resp, err := admin.ListConsumerGroupOffsets(c.config.Group, claims)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to fetch offsets for group %s", c.config.Group)
+	}
+	ret := make(map[string]string)
+	for topic, blocks := range resp.Blocks {
+		for partition, block := range blocks {
+			if !errors.Is(block.Err, sarama.ErrNoError) {
+				return nil, errors.Wrapf(block.Err, "unable to fetch offset for %s@%d", topic, partition)
+			}
+			if block.Metadata != "" {
+				ret[topicPartitionID(topic, partition)] = block.Metadata
+			}
+		}
+	}
+	return ret, nil
+}
+
 // getOffsets finds the offsets based on resolved timestamp messages
-func (c *Conn) getOffsets(min hlc.Time) ([]*partitionState, error) {
+func (c *Conn) getOffsets(ctx context.Context, min hlc.Time) ([]*partitionState, error) {
 	seeker, err := NewOffsetSeeker(c.config)
 	if err != nil {
 		return nil, err
 	}
 	defer seeker.Close()
-	return seeker.GetOffsets(c.config.Topics, min)
+	return seeker.GetOffsets(ctx, c.config.Topics, min)
 }
 
 func topicPartitionID(topic string, partition int32) string {
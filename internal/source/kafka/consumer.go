@@ -57,12 +57,19 @@ func (b *partitionBatch) Count() int {
 type Consumer struct {
 	batchSize int               // Batch size for writes.
 	conveyor  Conveyor          // The destination for writes.
+	decoder   payloadDecoder    // Extracts mutations from messages.
+	format    string            // The format of the messages.
 	fromState []*partitionState // The initial offsets for each partitions.
 	schema    ident.Schema      // The target schema.
 	timeRange hlc.Range         // The time range for incoming mutations.
+	// Returns the metadata of the committed offsets of the claimed
+	// partitions, keyed by topicPartitionID. Used to seed the
+	// partition clocks.
+	committed func(claims map[string][]int32) (map[string]string, error)
 	mu        struct {
 		sync.Mutex
-		done map[string]bool
+		clocks map[string]*partitionClock
+		done   map[string]bool
 	}
 }
 
@@ -81,8 +88,21 @@ func (c *Consumer) Setup(session sarama.ConsumerGroupSession) error {
 		log.Debugf("setup: marking offset %s@%d to %d", marker.topic, marker.partition, marker.offset)
 		session.MarkOffset(marker.topic, marker.partition, marker.offset, "start")
 	}
+	clocks := make(map[string]*partitionClock)
+	if c.format == formatDebezium && c.committed != nil {
+		metadata, err := c.committed(session.Claims())
+		if err != nil {
+			return err
+		}
+		for partition, value := range metadata {
+			clock := &partitionClock{}
+			clock.seed(value)
+			clocks[partition] = clock
+		}
+	}
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	c.mu.clocks = clocks
 	c.mu.done = make(map[string]bool)
 	return nil
 }
@@ -122,7 +142,7 @@ func (c *Consumer) ConsumeClaim(
 				log.Debugf("message channel for topic=%s partition=%d was closed", claim.Topic(), claim.Partition())
 				return nil
 			}
-			payload, err := c.accumulate(batch, lastResolved, message)
+			payload, err := c.accumulate(ctx, batch, lastResolved, message)
 			if err != nil {
 				log.WithError(err).Error("failed to add messages to a batch")
 				return err
@@ -161,6 +181,9 @@ func (c *Consumer) ConsumeClaim(
 					log.WithError(err).Error("failed to accept a batch")
 					return err
 				}
+				if err := c.advance(ctx, partition); err != nil {
+					return err
+				}
 				c.mark(session, consumed)
 			}
 		// Should return when `session.Context()` is done.
@@ -174,11 +197,57 @@ func (c *Consumer) ConsumeClaim(
 				log.WithError(err).Error("failed to accept a batch")
 				return err
 			}
+			if err := c.advance(ctx, partition); err != nil {
+				return err
+			}
 			c.mark(session, consumed)
 		}
 	}
 }
 
+// advance moves the checkpoint of a partition forward, based upon the
+// timestamps that have been assigned to the messages that were
+// accepted. This is used in place of resolved timestamps for formats
+// which do not provide them.
+func (c *Consumer) advance(ctx context.Context, partition string) error {
+	if c.format != formatDebezium {
+		return nil
+	}
+	clock := c.clock(partition)
+	watermark, ok := clock.watermark()
+	if !ok {
+		return nil
+	}
+	if err := c.conveyor.Advance(ctx, ident.New(partition), watermark); err != nil {
+		// The clock starts over if the partition's committed offset
+		// did not record its time, so the checkpoint may be ahead of
+		// the watermark for a while.
+		if clock.advanced == hlc.Zero() {
+			log.WithError(err).Warnf("could not advance watermark for %s; will retry", partition)
+			return nil
+		}
+		return err
+	}
+	clock.advanced = watermark
+	log.Tracef("Watermark partition=%s timestamp=%s", partition, watermark)
+	return nil
+}
+
+// clock returns the clock associated with the partition.
+func (c *Consumer) clock(partition string) *partitionClock {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.mu.clocks == nil {
+		c.mu.clocks = make(map[string]*partitionClock)
+	}
+	ret, ok := c.mu.clocks[partition]
+	if !ok {
+		ret = &partitionClock{}
+		c.mu.clocks[partition] = ret
+	}
+	return ret
+}
+
 // allDone returns true if we processed all the messages before the
 // maxTimestamp on all the partitions.
 func (c *Consumer) allDone() bool {
@@ -200,12 +269,18 @@ func (c *Consumer) done(partition string, done bool) {
 }
 
 // mark advances the offset on each topic/partition and removes it from the map that
-// track the latest message received on the topic/partition.
+// track the latest message received on the topic/partition. With the
+// debezium format, the partition's clock is recorded in the metadata
+// of the offset.
 func (c *Consumer) mark(
 	session sarama.ConsumerGroupSession, consumed map[string]*sarama.ConsumerMessage,
 ) {
 	for key, message := range consumed {
-		session.MarkMessage(message, "")
+		var metadata string
+		if c.format == formatDebezium {
+			metadata = c.clock(key).metadata()
+		}
+		session.MarkMessage(message, metadata)
 		delete(consumed, key)
 	}
 }
@@ -225,12 +300,23 @@ func (c *Consumer) accept(ctx context.Context, batch *partitionBatch) (*partitio
 
 // accumulate adds the message to the batch and returns the decoded payload.
 func (c *Consumer) accumulate(
-	batch *partitionBatch, lastResolved hlc.Time, msg *sarama.ConsumerMessage,
+	ctx context.Context, batch *partitionBatch, lastResolved hlc.Time, msg *sarama.ConsumerMessage,
 ) (*payload, error) {
-	payload, err := asPayload(msg)
+	decode := c.decoder
+	if decode == nil {
+		decode = asPayload
+	}
+	var clock *partitionClock
+	if c.format == formatDebezium {
+		clock = c.clock(topicPartitionID(msg.Topic, msg.Partition))
+	}
+	payload, err := decode(ctx, msg)
 	if err != nil {
 		return nil, err
 	}
+	if payload == nil {
+		return nil, nil
+	}
 	if payload.Resolved != "" {
 		log.Debugf("Resolved [%s@%d %d] %s ",
 			msg.Topic, msg.Partition, msg.Offset, payload.Resolved)
@@ -238,8 +324,10 @@ func (c *Consumer) accumulate(
 	}
 	log.Debugf("Mutation [%s@%d offset=%d time=%s] [key=%s mvcc=%s]",
 		msg.Topic, msg.Partition, msg.Offset, msg.Timestamp, string(msg.Key), payload.Updated)
-	timestamp, err := hlc.Parse(payload.Updated)
-	if err != nil {
+	var timestamp hlc.Time
+	if clock != nil {
+		timestamp = clock.assign(payload.Time)
+	} else if timestamp, err = hlc.Parse(payload.Updated); err != nil {
 		return nil, err
 	}
 	// Derive table name from topic.
@@ -256,8 +344,12 @@ func (c *Consumer) accumulate(
 		log.Warnf("timestamp after before last resolved for key %s (%s < %s)", msg.Key, timestamp, lastResolved)
 		return nil, nil
 	}
+	mutKey := payload.Key
+	if mutKey == nil {
+		mutKey = msg.Key
+	}
 	// Keep the most recent mutation for a specific key within a batch.
-	key := string(msg.Key)
+	key := string(mutKey)
 	if seen, ok := batch.keys[key]; ok && hlc.Compare(seen, timestamp) >= 0 {
 		log.Debugf("skipping duplicate %s@%s", string(msg.Key), timestamp)
 		return nil, nil
@@ -270,7 +362,8 @@ func (c *Consumer) accumulate(
 	mut := types.Mutation{
 		Before: payload.Before,
 		Data:   payload.After,
-		Key:    msg.Key,
+		Key:    mutKey,
+		Meta:   payload.Meta,
 		Time:   timestamp,
 	}
 	script.AddMeta("kafka", table, &mut)
@@ -17,6 +17,7 @@
 package kafka
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -152,7 +153,7 @@ func TestAccumulate(t *testing.T) {
 		schema:    ident.MustSchema(ident.New("db"), ident.New("public")),
 	}
 	for _, test := range tests {
-		_, err := consumer.accumulate(batch, hlc.Zero(), test.msg)
+		_, err := consumer.accumulate(context.Background(), batch, hlc.Zero(), test.msg)
 		if test.wantErr != "" {
 			a.Error(err)
 			a.ErrorContains(err, test.wantErr)
@@ -0,0 +1,339 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/cockroachdb/replicator/internal/util/hlc"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// The message formats that are supported.
+const (
+	formatCRDB     = "crdb"     // CockroachDB changefeed envelopes.
+	formatDebezium = "debezium" // Debezium change events.
+)
+
+// asDebeziumPayload extracts the mutation payload from a Debezium
+// change event that was encoded using the JSON converter, with or
+// without embedded schemas.
+func asDebeziumPayload(_ context.Context, msg *sarama.ConsumerMessage) (*payload, error) {
+	key, err := debeziumJSONKey(msg.Key)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not decode key")
+	}
+	var value any
+	if len(bytes.TrimSpace(msg.Value)) > 0 {
+		dec := json.NewDecoder(bytes.NewReader(msg.Value))
+		dec.UseNumber()
+		if err := dec.Decode(&value); err != nil {
+			return nil, errors.Wrap(err, "could not decode payload")
+		}
+	}
+	return debeziumPayload(msg, key, value)
+}
+
+// decodeDebezium extracts the mutation payload from a Debezium change
+// event that was encoded using an Avro converter.
+func (d *avroDecoder) decodeDebezium(
+	ctx context.Context, msg *sarama.ConsumerMessage,
+) (*payload, error) {
+	var key json.RawMessage
+	if len(msg.Key) > 0 {
+		var err error
+		if key, err = d.decodeKey(ctx, msg.Key); err != nil {
+			return nil, errors.Wrap(err, "could not decode key")
+		}
+	}
+	var value any
+	if len(msg.Value) > 0 {
+		var err error
+		if value, err = d.decodeValue(ctx, msg.Value); err != nil {
+			return nil, errors.Wrap(err, "could not decode payload")
+		}
+	}
+	return debeziumPayload(msg, key, value)
+}
+
+// debeziumPayload converts a Debezium change event into a payload. The
+// payload's Time field contains a candidate timestamp that is derived
+// from the source database's commit time. A nil payload will be
+// returned for events which should be skipped.
+func debeziumPayload(msg *sarama.ConsumerMessage, key json.RawMessage, value any) (*payload, error) {
+	// A tombstone follows a delete event, to allow log compaction.
+	if value == nil {
+		if key == nil {
+			return nil, nil
+		}
+		ret := &payload{Key: key}
+		if !msg.Timestamp.IsZero() {
+			ret.Time = hlc.New(msg.Timestamp.UnixNano(), 0)
+		}
+		return ret, nil
+	}
+
+	envelope, ok := value.(map[string]any)
+	if !ok {
+		return nil, errors.Errorf("expecting a change event, got %T", value)
+	}
+	// Unwrap messages from the JSON converter with schemas enabled.
+	if inner, ok := envelope["payload"].(map[string]any); ok {
+		if _, hasSchema := envelope["schema"]; hasSchema {
+			envelope = inner
+		}
+	}
+
+	op, _ := envelope["op"].(string)
+	source, _ := envelope["source"].(map[string]any)
+	ret := &payload{
+		Key: key,
+		Meta: map[string]any{
+			"op":     op,
+			"source": source,
+		},
+	}
+
+	switch op {
+	case "c", "r", "u":
+		after, ok := envelope["after"]
+		if !ok || after == nil {
+			return nil, errors.Errorf("change event with op %q has no after value", op)
+		}
+		var err error
+		if ret.After, err = json.Marshal(after); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	case "d":
+		// Leave After empty to indicate a deletion.
+	default:
+		// E.g. truncate or message events.
+		log.Debugf("skipping debezium event with op %q at %s@%d offset=%d",
+			op, msg.Topic, msg.Partition, msg.Offset)
+		return nil, nil
+	}
+	if before := envelope["before"]; before != nil {
+		var err error
+		if ret.Before, err = json.Marshal(before); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+
+	nanos, err := debeziumNanos(source, envelope)
+	if err != nil {
+		return nil, err
+	}
+	ret.Time = hlc.New(nanos, 0)
+	return ret, nil
+}
+
+// debeziumNanos returns the time at which the change was made in the
+// source database, using the most precise field that is available. The
+// time at which Debezium processed the event is used as a fallback.
+func debeziumNanos(source, envelope map[string]any) (int64, error) {
+	for _, field := range []struct {
+		name  string
+		scale int64
+	}{
+		{"ts_ns", 1},
+		{"ts_us", int64(time.Microsecond)},
+		{"ts_ms", int64(time.Millisecond)},
+	} {
+		if v, ok := debeziumInt(source[field.name]); ok {
+			return v * field.scale, nil
+		}
+	}
+	if v, ok := debeziumInt(envelope["ts_ms"]); ok {
+		return v * int64(time.Millisecond), nil
+	}
+	return 0, errors.New("change event does not contain a timestamp")
+}
+
+// debeziumInt converts the JSON and Avro representations of numbers.
+func debeziumInt(v any) (int64, bool) {
+	switch t := v.(type) {
+	case json.Number:
+		i, err := t.Int64()
+		return i, err == nil
+	case int64:
+		return t, true
+	case int32:
+		return int64(t), true
+	case int:
+		return int64(t), true
+	case float64:
+		return int64(t), true
+	case string:
+		i, err := strconv.ParseInt(t, 10, 64)
+		return i, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// debeziumJSONKey converts a JSON key into an array of values, in the
+// order in which the key fields appear in the message.
+func debeziumJSONKey(data []byte) (json.RawMessage, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, nil
+	}
+	switch data[0] {
+	case '[':
+		// Already in the expected format.
+		return data, nil
+	case '{':
+	default:
+		// A scalar value.
+		return json.RawMessage("[" + string(data) + "]"), nil
+	}
+
+	fields, err := orderedFields(data)
+	if err != nil {
+		return nil, err
+	}
+	// Unwrap messages from the JSON converter with schemas enabled.
+	if len(fields) == 2 {
+		var payload json.RawMessage
+		hasSchema := false
+		for _, field := range fields {
+			switch field.name {
+			case "payload":
+				payload = field.value
+			case "schema":
+				hasSchema = true
+			}
+		}
+		if hasSchema && payload != nil {
+			return debeziumJSONKey(payload)
+		}
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('[')
+	for idx, field := range fields {
+		if idx > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(field.value)
+	}
+	buf.WriteByte(']')
+	return buf.Bytes(), nil
+}
+
+// An orderedField is a member of a JSON object.
+type orderedField struct {
+	name  string
+	value json.RawMessage
+}
+
+// orderedFields returns the members of a JSON object in the order in
+// which they appear.
+func orderedFields(data []byte) ([]orderedField, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil {
+		return nil, errors.WithStack(err)
+	} else if tok != json.Delim('{') {
+		return nil, errors.Errorf("expecting a JSON object, got %v", tok)
+	}
+	var ret []orderedField
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		name, _ := tok.(string)
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		ret = append(ret, orderedField{name, value})
+	}
+	return ret, nil
+}
+
+// A partitionClock assigns strictly-increasing timestamps to the
+// messages within a partition when the message format does not provide
+// a resolved timestamp. Every timestamp that will be assigned in the
+// future is after the most recently assigned timestamp, so it is used
+// as the partition's watermark. The checkpoint of the consumer group
+// resolves to the minimum of these per-partition maximums; a partition
+// that does not receive messages will hold back the group.
+//
+// The most recently assigned timestamp is recorded in the metadata of
+// the partition's committed offset. When the partition is assigned to
+// a consumer, the clock is seeded from that metadata, so the messages
+// which follow the committed offset are assigned timestamps after the
+// watermark that was previously reported.
+type partitionClock struct {
+	advanced hlc.Time // The most recent watermark that was reported.
+	last     hlc.Time // The most recently assigned timestamp.
+}
+
+// assign returns a timestamp for a message which is strictly greater
+// than the previously-assigned timestamp. The wall time of the
+// candidate will be preserved unless the source time has moved
+// backwards or it is within the same nanosecond as the previous
+// message.
+func (p *partitionClock) assign(candidate hlc.Time) hlc.Time {
+	if candidate.Nanos() > p.last.Nanos() {
+		p.last = hlc.New(candidate.Nanos(), 0)
+	} else {
+		p.last = p.last.Next()
+	}
+	return p.last
+}
+
+// metadata returns the value to record alongside the partition's
+// committed offset.
+func (p *partitionClock) metadata() string {
+	if p.last == hlc.Zero() {
+		return ""
+	}
+	return p.last.String()
+}
+
+// seed restores the clock from the metadata of a committed offset.
+// Metadata that was not written by a partitionClock is ignored.
+func (p *partitionClock) seed(metadata string) {
+	ts, err := hlc.Parse(metadata)
+	if err != nil {
+		return
+	}
+	if hlc.Compare(ts, p.last) > 0 {
+		p.last = ts
+		p.advanced = ts
+	}
+}
+
+// watermark returns a new watermark if it is greater than the
+// previously-reported value.
+func (p *partitionClock) watermark() (hlc.Time, bool) {
+	if p.last == hlc.Zero() {
+		return hlc.Zero(), false
+	}
+	if hlc.Compare(p.last, p.advanced) <= 0 {
+		return hlc.Zero(), false
+	}
+	return p.last, true
+}
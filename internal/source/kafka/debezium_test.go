@@ -0,0 +1,346 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/cockroachdb/replicator/internal/util/hlc"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/linkedin/goavro/v2"
+	"github.com/stretchr/testify/require"
+)
+
+const testDebeziumSchema = `{
+  "type": "record", "name": "Envelope", "namespace": "dbserver.inventory.customers", "fields": [
+    {"name": "before", "type": ["null", {
+      "type": "record", "name": "Value", "fields": [
+        {"name": "id", "type": "int"},
+        {"name": "email", "type": ["null", "string"]}
+      ]}]},
+    {"name": "after", "type": ["null", "Value"]},
+    {"name": "source", "type": {
+      "type": "record", "name": "Source", "namespace": "io.debezium.connector.postgresql", "fields": [
+        {"name": "ts_ms", "type": "long"},
+        {"name": "lsn", "type": ["null", "long"]}
+      ]}},
+    {"name": "op", "type": "string"},
+    {"name": "ts_ms", "type": ["null", "long"]}
+  ]}`
+
+// TestDebeziumPayload verifies that Debezium change events are
+// converted into payloads.
+func TestDebeziumPayload(t *testing.T) {
+	const ms = int64(time.Millisecond)
+	tcs := []struct {
+		name    string
+		msg     *sarama.ConsumerMessage
+		want    *payload // A nil value indicates a skipped message.
+		wantErr string
+	}{
+		{
+			name: "create",
+			msg: &sarama.ConsumerMessage{
+				Key: []byte(`{"id":1}`),
+				Value: []byte(`{"before":null,"after":{"id":1,"email":"a@example.com"},` +
+					`"source":{"ts_ms":1700000000000,"lsn":42},"op":"c","ts_ms":1700000000500}`),
+			},
+			want: &payload{
+				After: json.RawMessage(`{"id":1,"email":"a@example.com"}`),
+				Key:   json.RawMessage(`[1]`),
+				Time:  hlc.New(1700000000000*ms, 0),
+			},
+		},
+		{
+			name: "update with schema",
+			msg: &sarama.ConsumerMessage{
+				Key: []byte(`{"schema":{"type":"struct"},"payload":{"tenant":"x","id":1}}`),
+				Value: []byte(`{"schema":{"type":"struct"},"payload":{` +
+					`"before":{"id":1,"email":"a@example.com"},"after":{"id":1,"email":"b@example.com"},` +
+					`"source":{"ts_ms":1700000000000,"ts_us":1700000000000123},"op":"u"}}`),
+			},
+			want: &payload{
+				After:  json.RawMessage(`{"id":1,"email":"b@example.com"}`),
+				Before: json.RawMessage(`{"id":1,"email":"a@example.com"}`),
+				Key:    json.RawMessage(`["x",1]`),
+				Time:   hlc.New(1700000000000123*int64(time.Microsecond), 0),
+			},
+		},
+		{
+			name: "snapshot",
+			msg: &sarama.ConsumerMessage{
+				Key:   []byte(`1`),
+				Value: []byte(`{"after":{"id":1},"source":{},"op":"r","ts_ms":1700000000500}`),
+			},
+			want: &payload{
+				After: json.RawMessage(`{"id":1}`),
+				Key:   json.RawMessage(`[1]`),
+				Time:  hlc.New(1700000000500*ms, 0),
+			},
+		},
+		{
+			name: "delete",
+			msg: &sarama.ConsumerMessage{
+				Key: []byte(`{"id":1}`),
+				Value: []byte(`{"before":{"id":1,"email":"b@example.com"},"after":null,` +
+					`"source":{"ts_ms":1700000001000},"op":"d"}`),
+			},
+			want: &payload{
+				Before: json.RawMessage(`{"id":1,"email":"b@example.com"}`),
+				Key:    json.RawMessage(`[1]`),
+				Time:   hlc.New(1700000001000*ms, 0),
+			},
+		},
+		{
+			name: "tombstone",
+			msg: &sarama.ConsumerMessage{
+				Key:       []byte(`{"id":1}`),
+				Timestamp: time.UnixMilli(1700000001001),
+			},
+			want: &payload{
+				Key:  json.RawMessage(`[1]`),
+				Time: hlc.New(1700000001001*ms, 0),
+			},
+		},
+		{
+			name: "truncate",
+			msg: &sarama.ConsumerMessage{
+				Value: []byte(`{"source":{"ts_ms":1700000000000},"op":"t"}`),
+			},
+		},
+		{
+			name: "empty",
+			msg:  &sarama.ConsumerMessage{},
+		},
+		{
+			name: "no timestamp",
+			msg: &sarama.ConsumerMessage{
+				Key:   []byte(`{"id":1}`),
+				Value: []byte(`{"after":{"id":1},"op":"c"}`),
+			},
+			wantErr: "does not contain a timestamp",
+		},
+		{
+			name: "no after",
+			msg: &sarama.ConsumerMessage{
+				Key:   []byte(`{"id":1}`),
+				Value: []byte(`{"after":null,"source":{"ts_ms":1},"op":"u"}`),
+			},
+			wantErr: "has no after value",
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			r := require.New(t)
+			got, err := asDebeziumPayload(context.Background(), tc.msg)
+			if tc.wantErr != "" {
+				r.ErrorContains(err, tc.wantErr)
+				return
+			}
+			r.NoError(err)
+			if tc.want == nil {
+				r.Nil(got)
+				return
+			}
+			r.NotNil(got)
+			requireJSONEq(t, tc.want.After, got.After)
+			requireJSONEq(t, tc.want.Before, got.Before)
+			r.Equal(string(tc.want.Key), string(got.Key))
+			r.Equal(tc.want.Time, got.Time)
+		})
+	}
+}
+
+// TestDebeziumAvro verifies that Avro-encoded change events are
+// decoded.
+func TestDebeziumAvro(t *testing.T) {
+	r := require.New(t)
+	srv, _ := stubRegistry(t, map[int]string{
+		1: testKeySchema,
+		2: testDebeziumSchema,
+	})
+	dec, err := newAvroDecoder(srv.URL)
+	r.NoError(err)
+
+	key := encodeAvro(t, 1, testKeySchema, map[string]any{
+		"k": int64(1),
+		"s": nil,
+	})
+	got, err := dec.decodeDebezium(context.Background(), &sarama.ConsumerMessage{
+		Key: key,
+		Value: encodeAvro(t, 2, testDebeziumSchema, map[string]any{
+			"before": nil,
+			"after": goavro.Union("dbserver.inventory.customers.Value", map[string]any{
+				"id":    int32(1),
+				"email": goavro.Union("string", "a@example.com"),
+			}),
+			"source": map[string]any{
+				"ts_ms": int64(1700000000000),
+				"lsn":   goavro.Union("long", int64(42)),
+			},
+			"op":    "c",
+			"ts_ms": nil,
+		}),
+	})
+	r.NoError(err)
+	requireJSONEq(t, json.RawMessage(`{"id":1,"email":"a@example.com"}`), got.After)
+	r.Nil(got.Before)
+	r.Equal(`[1,null]`, string(got.Key))
+	r.Equal(hlc.New(1700000000000*int64(time.Millisecond), 0), got.Time)
+	r.Equal("c", got.Meta["op"])
+
+	// Tombstone.
+	got, err = dec.decodeDebezium(context.Background(), &sarama.ConsumerMessage{Key: key})
+	r.NoError(err)
+	r.Nil(got.After)
+	r.Equal(`[1,null]`, string(got.Key))
+}
+
+// TestPartitionClock verifies that timestamps are strictly increasing
+// and that the watermark is the most recently assigned timestamp.
+func TestPartitionClock(t *testing.T) {
+	r := require.New(t)
+	clock := &partitionClock{}
+
+	_, ok := clock.watermark()
+	r.False(ok)
+	r.Empty(clock.metadata())
+
+	r.Equal(hlc.New(100, 0), clock.assign(hlc.New(100, 0)))
+	// Same source time, e.g. within a transaction.
+	r.Equal(hlc.New(100, 1), clock.assign(hlc.New(100, 0)))
+	// The source time went backwards.
+	r.Equal(hlc.New(100, 2), clock.assign(hlc.New(50, 0)))
+
+	wm, ok := clock.watermark()
+	r.True(ok)
+	r.Equal(hlc.New(100, 2), wm)
+	clock.advanced = wm
+	_, ok = clock.watermark()
+	r.False(ok)
+
+	r.Equal(hlc.New(200, 0), clock.assign(hlc.New(200, 0)))
+	wm, ok = clock.watermark()
+	r.True(ok)
+	r.Equal(hlc.New(200, 0), wm)
+	clock.advanced = wm
+
+	// A new clock, seeded from the committed metadata, continues
+	// after the reported watermark.
+	seeded := &partitionClock{}
+	seeded.seed("start")
+	r.Equal(hlc.Zero(), seeded.last)
+	seeded.seed(clock.metadata())
+	_, ok = seeded.watermark()
+	r.False(ok)
+	r.Equal(hlc.New(200, 1), seeded.assign(hlc.New(150, 0)))
+	wm, ok = seeded.watermark()
+	r.True(ok)
+	r.Equal(hlc.New(200, 1), wm)
+}
+
+// TestDebeziumConsumer verifies that the consumer assigns timestamps
+// to Debezium messages and advances the partition's watermark.
+func TestDebeziumConsumer(t *testing.T) {
+	ctx := context.Background()
+	r := require.New(t)
+	conveyor := &mockConveyor{}
+	consumer := &Consumer{
+		conveyor:  conveyor,
+		decoder:   asDebeziumPayload,
+		format:    formatDebezium,
+		schema:    ident.MustSchema(ident.New("db"), ident.New("public")),
+		timeRange: maxRange,
+	}
+	batch := newPartitionBatch()
+	for idx, value := range []string{
+		`{"after":{"id":1,"v":"a"},"source":{"ts_ms":1},"op":"c"}`,
+		`{"after":{"id":2,"v":"a"},"source":{"ts_ms":1},"op":"c"}`,
+		`{"before":{"id":1,"v":"a"},"source":{"ts_ms":2},"op":"d"}`,
+		``,
+		`{"source":{"ts_ms":3},"op":"t"}`,
+	} {
+		_, err := consumer.accumulate(ctx, batch, hlc.Zero(), &sarama.ConsumerMessage{
+			Topic:  "table",
+			Key:    []byte(`{"id":` + []string{"1", "2", "1", "1", "1"}[idx] + `}`),
+			Offset: int64(idx),
+			Value:  []byte(value),
+		})
+		r.NoError(err)
+	}
+	// The truncate event is skipped.
+	r.Equal(4, batch.Count())
+	table := ident.NewTable(consumer.schema, ident.New("table"))
+	temporal := batch.data.Data
+	r.Len(temporal, 4)
+	r.Equal(hlc.New(int64(time.Millisecond), 0), temporal[0].Time)
+	r.Equal(hlc.New(int64(time.Millisecond), 1), temporal[1].Time)
+	r.Equal(hlc.New(2*int64(time.Millisecond), 0), temporal[2].Time)
+	// The tombstone has no timestamp, so it follows the delete.
+	r.Equal(hlc.New(2*int64(time.Millisecond), 1), temporal[3].Time)
+
+	del := temporal[2].Data.GetZero(table)
+	r.NotNil(del)
+	r.True(del.Data[0].IsDelete())
+	r.Equal("d", del.Data[0].Meta["op"])
+	tombstone := temporal[3].Data.GetZero(table)
+	r.NotNil(tombstone)
+	r.True(tombstone.Data[0].IsDelete())
+
+	partition := topicPartitionID("table", 0)
+	r.NoError(consumer.advance(ctx, partition))
+	wm := conveyor.getTimestamp(ident.New(partition))
+	r.Equal(hlc.New(2*int64(time.Millisecond), 1), wm)
+
+	// The clock is recorded when the offset is committed and restored
+	// when the partition is next assigned.
+	session := &fakeSession{ctx: ctx}
+	consumer.mark(session, map[string]*sarama.ConsumerMessage{
+		partition: {Topic: "table", Offset: 4},
+	})
+	r.Equal(wm.String(), session.metadata)
+	consumer.committed = func(map[string][]int32) (map[string]string, error) {
+		return map[string]string{partition: session.metadata}, nil
+	}
+	r.NoError(consumer.Setup(session))
+	r.Equal(wm, consumer.clock(partition).last)
+	_, ok := consumer.clock(partition).watermark()
+	r.False(ok)
+}
+
+// fakeSession records the metadata of marked messages.
+type fakeSession struct {
+	sarama.ConsumerGroupSession
+	ctx      context.Context
+	metadata string
+}
+
+func (s *fakeSession) Claims() map[string][]int32 {
+	return map[string][]int32{"table": {0}}
+}
+
+func (s *fakeSession) Context() context.Context {
+	return s.ctx
+}
+
+func (s *fakeSession) MarkMessage(_ *sarama.ConsumerMessage, metadata string) {
+	s.metadata = metadata
+}
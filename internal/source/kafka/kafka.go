@@ -19,20 +19,38 @@
 package kafka
 
 import (
+	"net/http"
+
+	"github.com/cockroachdb/replicator/internal/conveyor"
+	"github.com/cockroachdb/replicator/internal/types"
 	"github.com/cockroachdb/replicator/internal/util/diag"
 	"github.com/cockroachdb/replicator/internal/util/stdlogical"
 )
 
 // Kafka is a kafka logical replication loop.
 type Kafka struct {
-	Conn        *Conn
-	Diagnostics *diag.Diagnostics
+	Authenticator types.Authenticator // Nil if there is no staging database.
+	Conn          *Conn
+	Conveyors     *conveyor.Conveyors
+	Diagnostics   *diag.Diagnostics
 }
 
 var (
-	_ stdlogical.HasDiagnostics = (*Kafka)(nil)
+	_ stdlogical.HasAdmin              = (*Kafka)(nil)
+	_ stdlogical.HasAdminAuthenticator = (*Kafka)(nil)
+	_ stdlogical.HasDiagnostics        = (*Kafka)(nil)
 )
 
+// GetAdminHandler implements [stdlogical.HasAdmin].
+func (k *Kafka) GetAdminHandler(auth types.Authenticator) http.Handler {
+	return k.Conveyors.AdminHandler(auth)
+}
+
+// GetAdminAuthenticator implements [stdlogical.HasAdminAuthenticator].
+func (k *Kafka) GetAdminAuthenticator() types.Authenticator {
+	return k.Authenticator
+}
+
 // GetDiagnostics implements [stdlogical.HasDiagnostics].
 func (k *Kafka) GetDiagnostics() *diag.Diagnostics {
 	return k.Diagnostics
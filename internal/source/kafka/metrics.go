@@ -24,6 +24,14 @@ import (
 // TODO (silvano) Provide a grafana dashboard for kafka connector.
 // https://github.com/cockroachdb/replicator/issues/829
 var (
+	schemaFetchCount = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "kafka_schema_fetch_count",
+		Help: "the number of Avro schemas retrieved from the schema registry",
+	})
+	schemaFetchErrors = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "kafka_schema_fetch_errors",
+		Help: "the number of errors encountered while retrieving Avro schemas",
+	})
 	seekMessagesCount = promauto.NewCounterVec(prometheus.CounterOpts{
 		Name: "kafka_seeks_count",
 		Help: "the total of messages read seeking a minimum resolved timestamp",
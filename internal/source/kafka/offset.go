@@ -17,6 +17,7 @@
 package kafka
 
 import (
+	"context"
 	"strconv"
 	"time"
 
@@ -30,7 +31,7 @@ import (
 type OffsetSeeker interface {
 	// GetOffsets finds the most recent offsets for resolved timestamp messages
 	// that are before the given time, and in the given topics.
-	GetOffsets([]string, hlc.Time) ([]*partitionState, error)
+	GetOffsets(context.Context, []string, hlc.Time) ([]*partitionState, error)
 	// Close shuts down the connection with the Kafka broker.
 	Close() error
 }
@@ -39,6 +40,7 @@ type OffsetSeeker interface {
 type offsetSeeker struct {
 	client                 sarama.Client
 	consumer               sarama.Consumer
+	decoder                payloadDecoder
 	resolvedIntervalMillis int64
 }
 
@@ -55,6 +57,7 @@ func NewOffsetSeeker(config *Config) (OffsetSeeker, error) {
 	return &offsetSeeker{
 		client:                 cl,
 		consumer:               consumer,
+		decoder:                config.decoder,
 		resolvedIntervalMillis: config.ResolvedInterval.Milliseconds(),
 	}, nil
 }
@@ -62,7 +65,9 @@ func NewOffsetSeeker(config *Config) (OffsetSeeker, error) {
 var _ OffsetSeeker = &offsetSeeker{}
 
 // GetOffsets implements OffsetSeeker.
-func (o *offsetSeeker) GetOffsets(topics []string, min hlc.Time) ([]*partitionState, error) {
+func (o *offsetSeeker) GetOffsets(
+	ctx context.Context, topics []string, min hlc.Time,
+) ([]*partitionState, error) {
 	res := make([]*partitionState, 0)
 	// TODO (silvano): make this parallel https://github.com/cockroachdb/replicator/issues/830
 	for _, topic := range topics {
@@ -71,7 +76,7 @@ func (o *offsetSeeker) GetOffsets(topics []string, min hlc.Time) ([]*partitionSt
 			return nil, err
 		}
 		for _, partition := range partitions {
-			offset, err := o.getPartitionOffset(min, topic, partition)
+			offset, err := o.getPartitionOffset(ctx, min, topic, partition)
 			if err != nil {
 				return nil, err
 			}
@@ -96,7 +101,7 @@ func (o *offsetSeeker) Close() error {
 // getPartitionOffset get the most recent offsets at the given time
 // for a specific topic and partition.
 func (o *offsetSeeker) getPartitionOffset(
-	min hlc.Time, topic string, partition int32,
+	ctx context.Context, min hlc.Time, topic string, partition int32,
 ) (int64, error) {
 	minMillis := min.Nanos() / int64(time.Millisecond)
 	// Get the offset at log head.
@@ -136,7 +141,7 @@ func (o *offsetSeeker) getPartitionOffset(
 		max := last
 		last = offset
 		// Verify that we see the min timestamp right after the offset.
-		offset, err = o.seekResolved(min, topic, partition,
+		offset, err = o.seekResolved(ctx, min, topic, partition,
 			offsetRange{offset, max})
 		if err != nil {
 			return 0, errors.WithStack(err)
@@ -159,7 +164,7 @@ func (o *offsetSeeker) getPartitionOffset(
 // specified offset range that is before the given time.
 // It returns sarama.OffsetOldest if we don't find it.
 func (o *offsetSeeker) seekResolved(
-	min hlc.Time, topic string, partition int32, offsets offsetRange,
+	ctx context.Context, min hlc.Time, topic string, partition int32, offsets offsetRange,
 ) (int64, error) {
 	log.Tracef("seekResolved: finding a message earlier than %s within [%d - %d]", min, offsets.min, offsets.max)
 	partConsumer, err := o.consumer.ConsumePartition(topic, partition, offsets.min)
@@ -179,7 +184,11 @@ func (o *offsetSeeker) seekResolved(
 				// we reached the end of the offset range without finding the resolved timestamp.
 				return sarama.OffsetOldest, nil
 			}
-			payload, err := asPayload(msg)
+			decode := o.decoder
+			if decode == nil {
+				decode = asPayload
+			}
+			payload, err := decode(ctx, msg)
 			if err != nil {
 				return 0, err
 			}
@@ -17,6 +17,7 @@
 package kafka
 
 import (
+	"context"
 	"fmt"
 	"math/rand/v2"
 	"testing"
@@ -335,7 +336,7 @@ func TestGetOffsets(t *testing.T) {
 					consumer:               consumer,
 					resolvedIntervalMillis: interval,
 				}
-				got, err := seeker.GetOffsets(tt.topics, tt.min)
+				got, err := seeker.GetOffsets(context.Background(), tt.topics, tt.min)
 				a.NoError(err)
 				for _, g := range got {
 					a.Equal(tt.want[g.topic][int(g.partition)], g.offset)
@@ -18,10 +18,12 @@ package kafka
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io"
 
 	"github.com/IBM/sarama"
+	"github.com/cockroachdb/replicator/internal/util/hlc"
 	"github.com/pkg/errors"
 )
 
@@ -31,10 +33,25 @@ type payload struct {
 	Before   json.RawMessage `json:"before"`
 	Resolved string          `json:"resolved"`
 	Updated  string          `json:"updated"`
+
+	// Key is set by decoders that must transform the message key into
+	// a JSON array. If nil, the message key is used as-is.
+	Key json.RawMessage `json:"-"`
+	// Meta contains additional, format-specific, information to
+	// expose to user scripts.
+	Meta map[string]any `json:"-"`
+	// Time is set by decoders for formats that do not provide an
+	// updated timestamp. It is used as a candidate timestamp by the
+	// partition's clock.
+	Time hlc.Time `json:"-"`
 }
 
+// A payloadDecoder extracts the mutation payload from a Kafka consumer
+// message. A nil payload indicates that the message should be skipped.
+type payloadDecoder func(ctx context.Context, msg *sarama.ConsumerMessage) (*payload, error)
+
 // asPayload extracts the mutation payload from a Kafka consumer message.
-func asPayload(msg *sarama.ConsumerMessage) (*payload, error) {
+func asPayload(_ context.Context, msg *sarama.ConsumerMessage) (*payload, error) {
 	payload := &payload{}
 	dec := json.NewDecoder(bytes.NewReader(msg.Value))
 	dec.UseNumber()
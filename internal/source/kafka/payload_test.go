@@ -17,6 +17,7 @@
 package kafka
 
 import (
+	"context"
 	"testing"
 	"time"
 
@@ -128,7 +129,7 @@ func TestAsPayload(t *testing.T) {
 	for _, tt := range tests {
 		a := assert.New(t)
 		r := require.New(t)
-		got, err := asPayload(tt.msg)
+		got, err := asPayload(context.Background(), tt.msg)
 		if tt.wantErr != "" {
 			a.Error(err)
 			a.ErrorContains(err, tt.wantErr)
@@ -20,11 +20,16 @@ import (
 	"github.com/cockroachdb/field-eng-powertools/stopper"
 	"github.com/cockroachdb/replicator/internal/conveyor"
 	"github.com/cockroachdb/replicator/internal/script"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/diag"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/cockroachdb/replicator/internal/util/stdserver"
 	"github.com/google/wire"
 )
 
 // Set is used by Wire.
 var Set = wire.NewSet(
+	ProvideAuthenticator,
 	ProvideConn,
 	ProvideConveyorConfig,
 	ProvideEagerConfig,
@@ -37,6 +42,18 @@ func ProvideEagerConfig(cfg *Config, _ *script.Loader) *EagerConfig {
 	return (*EagerConfig)(cfg)
 }
 
+// ProvideAuthenticator is called by Wire to construct a JWT-based
+// authenticator for the administrative endpoints. It returns nil if
+// staging data is kept in an embedded store.
+func ProvideAuthenticator(
+	ctx *stopper.Context,
+	diags *diag.Diagnostics,
+	pool *types.StagingPool,
+	stagingDB ident.StagingSchema,
+) (types.Authenticator, error) {
+	return stdserver.StagingAuthenticator(ctx, diags, pool, stagingDB)
+}
+
 // ProvideConveyorConfig is called by Wire.
 func ProvideConveyorConfig(cfg *Config) *conveyor.Config {
 	return &cfg.Conveyor
@@ -14,6 +14,7 @@ import (
 	"github.com/cockroachdb/replicator/internal/sequencer/core"
 	"github.com/cockroachdb/replicator/internal/sequencer/decorators"
 	"github.com/cockroachdb/replicator/internal/sequencer/immediate"
+	"github.com/cockroachdb/replicator/internal/sequencer/pii"
 	"github.com/cockroachdb/replicator/internal/sequencer/retire"
 	"github.com/cockroachdb/replicator/internal/sequencer/scheduler"
 	script2 "github.com/cockroachdb/replicator/internal/sequencer/script"
@@ -49,8 +50,8 @@ func Start(ctx *stopper.Context, config *Config) (*Kafka, error) {
 		return nil, err
 	}
 	eagerConfig := ProvideEagerConfig(config, loader)
-	targetConfig := &eagerConfig.Target
 	stagingConfig := &eagerConfig.Staging
+	targetConfig := &eagerConfig.Target
 	stagingPool, err := sinkprod.ProvideStagingPool(ctx, stagingConfig, diagnostics, targetConfig)
 	if err != nil {
 		return nil, err
@@ -59,11 +60,19 @@ func Start(ctx *stopper.Context, config *Config) (*Kafka, error) {
 	if err != nil {
 		return nil, err
 	}
-	memoMemo, err := memo.ProvideMemo(ctx, stagingPool, stagingSchema)
+	authenticator, err := ProvideAuthenticator(ctx, diagnostics, stagingPool, stagingSchema)
+	if err != nil {
+		return nil, err
+	}
+	store, err := sinkprod.ProvideStagingLocal(ctx, stagingConfig)
 	if err != nil {
 		return nil, err
 	}
-	checker := version.ProvideChecker(stagingPool, memoMemo)
+	typesMemo, err := memo.ProvideMemo(ctx, stagingPool, store, stagingSchema)
+	if err != nil {
+		return nil, err
+	}
+	checker := version.ProvideChecker(stagingPool, typesMemo)
 	targetPool, err := sinkprod.ProvideTargetPool(ctx, checker, targetConfig, diagnostics)
 	if err != nil {
 		return nil, err
@@ -73,7 +82,7 @@ func Start(ctx *stopper.Context, config *Config) (*Kafka, error) {
 		return nil, err
 	}
 	dlqConfig := &eagerConfig.DLQ
-	backup := schemawatch.ProvideBackup(memoMemo, stagingPool)
+	backup := schemawatch.ProvideBackup(typesMemo, stagingPool)
 	watchers, err := schemawatch.ProvideFactory(ctx, targetPool, diagnostics, backup)
 	if err != nil {
 		return nil, err
@@ -87,21 +96,33 @@ func Start(ctx *stopper.Context, config *Config) (*Kafka, error) {
 	if err != nil {
 		return nil, err
 	}
+	tableAcceptor, err := sinkprod.ProvideTableAcceptor(ctx, acceptor, targetConfig)
+	if err != nil {
+		return nil, err
+	}
 	conveyorConfig := ProvideConveyorConfig(config)
-	checkpoints, err := checkpoint.ProvideCheckpoints(ctx, stagingPool, stagingSchema)
+	checkpoints, err := checkpoint.ProvideCheckpoints(ctx, stagingPool, store, stagingSchema)
 	if err != nil {
 		return nil, err
 	}
-	sequencer := script2.ProvideSequencer(loader, targetPool, watchers)
 	sequencerConfig := &eagerConfig.Sequencer
-	stagers := stage.ProvideFactory(stagingPool, stagingSchema, ctx)
+	keys, err := pii.ProvideKeys(sequencerConfig)
+	if err != nil {
+		return nil, err
+	}
+	piiPII, err := pii.ProvideShim(ctx, sequencerConfig, diagnostics, keys, watchers)
+	if err != nil {
+		return nil, err
+	}
+	sequencer := script2.ProvideSequencer(loader, targetPool, watchers)
+	stagers := stage.ProvideFactory(stagingPool, store, stagingSchema, ctx)
 	retireRetire := retire.ProvideRetire(sequencerConfig, stagingPool, stagers)
 	schedulerScheduler, err := scheduler.ProvideScheduler(ctx, sequencerConfig)
 	if err != nil {
 		return nil, err
 	}
 	bestEffort := besteffort.ProvideBestEffort(sequencerConfig, schedulerScheduler, stagers, stagingPool, watchers)
-	typesLeases, err := leases.ProvideLeases(ctx, stagingPool, stagingSchema)
+	typesLeases, err := leases.ProvideLeases(ctx, stagingPool, store, stagingSchema)
 	if err != nil {
 		return nil, err
 	}
@@ -111,8 +132,8 @@ func Start(ctx *stopper.Context, config *Config) (*Kafka, error) {
 	retryTarget := decorators.ProvideRetryTarget(targetPool)
 	immediateImmediate := immediate.ProvideImmediate(sequencerConfig, targetPool, marker, once, retryTarget, stagers)
 	stagingStaging := staging.ProvideStaging(sequencerConfig, marker, stagers, stagingPool)
-	switcherSwitcher := switcher.ProvideSequencer(bestEffort, coreCore, diagnostics, immediateImmediate, stagingStaging, stagingPool, targetPool)
-	conveyors, err := conveyor.ProvideConveyors(ctx, acceptor, conveyorConfig, checkpoints, sequencer, retireRetire, switcherSwitcher, watchers)
+	switcherSwitcher := switcher.ProvideSequencer(configs, bestEffort, coreCore, diagnostics, immediateImmediate, stagingStaging, stagingPool, targetPool, watchers)
+	conveyors, err := conveyor.ProvideConveyors(ctx, tableAcceptor, conveyorConfig, checkpoints, piiPII, sequencer, retireRetire, stagers, stagingPool, switcherSwitcher, watchers)
 	if err != nil {
 		return nil, err
 	}
@@ -121,8 +142,10 @@ func Start(ctx *stopper.Context, config *Config) (*Kafka, error) {
 		return nil, err
 	}
 	kafka := &Kafka{
-		Conn:        conn,
-		Diagnostics: diagnostics,
+		Authenticator: authenticator,
+		Conn:          conn,
+		Conveyors:     conveyors,
+		Diagnostics:   diagnostics,
 	}
 	return kafka, nil
 }
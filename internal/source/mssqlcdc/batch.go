@@ -0,0 +1,176 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package mssqlcdc
+
+import (
+	"encoding/json"
+	"slices"
+	"time"
+
+	"github.com/cockroachdb/replicator/internal/script"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/hlc"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// The values of the __$operation column that we handle. The before
+// image of an update (3) is not requested.
+const (
+	opDelete      = 1
+	opInsert      = 2
+	opUpdateAfter = 4
+)
+
+// A column associates a source column name with a value.
+type column struct {
+	name  string
+	value any
+}
+
+// A change is a row from a change table.
+type change struct {
+	columns    []column
+	commitTime time.Time // The commit time of the transaction.
+	lsn        lsn       // The commit LSN of the transaction.
+	operation  int
+	seq        lsn // Orders the changes within a transaction.
+	table      ident.Table
+}
+
+// sortChanges orders changes read from multiple change tables by
+// transaction and then by the order of the changes within the
+// transaction.
+func sortChanges(changes []*change) {
+	slices.SortStableFunc(changes, func(a, b *change) int {
+		if c := a.lsn.Compare(b.lsn); c != 0 {
+			return c
+		}
+		return a.seq.Compare(b.seq)
+	})
+}
+
+// A batcher converts changes into mutations and accumulates them into
+// batches of transactions. Each transaction is assigned a distinct
+// time, derived from its commit time.
+type batcher struct {
+	// Returns the columns of a target table.
+	columns func(ident.Table) ([]types.ColData, bool)
+	// The time assigned to the most recent transaction.
+	last hlc.Time
+	// The LSN of the most recent transaction.
+	lastLSN lsn
+}
+
+// timeFor returns the time to assign to the transaction with the given
+// commit time. Since transactions may share a commit time, the returned
+// time is forced to advance.
+func (b *batcher) timeFor(commitTime time.Time) hlc.Time {
+	ret := hlc.From(commitTime)
+	if hlc.Compare(ret, b.last) <= 0 {
+		ret = b.last.Next()
+	}
+	b.last = ret
+	return ret
+}
+
+// add appends the sorted changes to the batch.
+func (b *batcher) add(batch *types.MultiBatch, changes []*change) error {
+	for _, chg := range changes {
+		if chg.lsn != b.lastLSN {
+			b.timeFor(chg.commitTime)
+			b.lastLSN = chg.lsn
+		}
+		cols, ok := b.columns(chg.table)
+		if !ok {
+			log.Tracef("skipping change to %s because it is not in the target schema", chg.table)
+			continue
+		}
+		mut, err := toMutation(chg, cols)
+		if err != nil {
+			return err
+		}
+		mut.Time = b.last
+		script.AddMeta("mssqlcdc", chg.table, &mut)
+		if err := batch.Accumulate(chg.table, mut); err != nil {
+			return err
+		}
+		mutationCount.WithLabelValues(operationName(chg.operation)).Inc()
+	}
+	return nil
+}
+
+// toMutation converts a change into a mutation, using the columns of
+// the target table to determine the primary key.
+func toMutation(chg *change, cols []types.ColData) (types.Mutation, error) {
+	var mut types.Mutation
+	switch chg.operation {
+	case opDelete:
+		mut.Deletion = true
+	case opInsert, opUpdateAfter:
+	default:
+		return mut, errors.Errorf("unexpected operation %d at LSN %s", chg.operation, chg.lsn)
+	}
+
+	data := make(map[string]any, len(chg.columns))
+	for _, col := range chg.columns {
+		data[col.name] = col.value
+	}
+	var key []any
+	for _, col := range cols {
+		if !col.Primary {
+			continue
+		}
+		idx := slices.IndexFunc(chg.columns, func(c column) bool {
+			return ident.Equal(ident.New(c.name), col.Name)
+		})
+		if idx < 0 {
+			return mut, errors.Errorf("primary key column %s is not captured for %s",
+				col.Name, chg.table)
+		}
+		key = append(key, chg.columns[idx].value)
+	}
+	if len(key) == 0 {
+		return mut, errors.Errorf("table %s has no primary key", chg.table)
+	}
+
+	var err error
+	mut.Key, err = json.Marshal(key)
+	if err != nil {
+		return mut, errors.WithStack(err)
+	}
+	mut.Data, err = json.Marshal(data)
+	if err != nil {
+		return mut, errors.WithStack(err)
+	}
+	return mut, nil
+}
+
+// operationName returns a metrics label for an operation.
+func operationName(op int) string {
+	switch op {
+	case opDelete:
+		return "delete"
+	case opInsert:
+		return "insert"
+	case opUpdateAfter:
+		return "update"
+	default:
+		return "unknown"
+	}
+}
@@ -0,0 +1,200 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package mssqlcdc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/hlc"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mustLSN(t *testing.T, s string) lsn {
+	t.Helper()
+	ret, err := parseLSN(s)
+	require.NoError(t, err)
+	return ret
+}
+
+func TestSortChanges(t *testing.T) {
+	a := assert.New(t)
+	lsn1 := mustLSN(t, "0x00000001000000010001")
+	lsn2 := mustLSN(t, "0x00000001000000020001")
+	seq1 := mustLSN(t, "0x00000001000000010002")
+	seq2 := mustLSN(t, "0x00000001000000010003")
+
+	changes := []*change{
+		{lsn: lsn2, seq: seq1, operation: 1},
+		{lsn: lsn1, seq: seq2, operation: 2},
+		{lsn: lsn1, seq: seq1, operation: 3},
+	}
+	sortChanges(changes)
+	ops := make([]int, len(changes))
+	for idx, chg := range changes {
+		ops[idx] = chg.operation
+	}
+	a.Equal([]int{3, 2, 1}, ops)
+}
+
+func TestTimeFor(t *testing.T) {
+	a := assert.New(t)
+	now := time.Now()
+	b := &batcher{}
+
+	first := b.timeFor(now)
+	a.Equal(hlc.From(now), first)
+
+	// Transactions that share a commit time must be distinct.
+	second := b.timeFor(now)
+	a.Equal(first.Next(), second)
+
+	// The clock in the source may go backwards.
+	third := b.timeFor(now.Add(-time.Second))
+	a.Equal(second.Next(), third)
+
+	fourth := b.timeFor(now.Add(time.Second))
+	a.Equal(hlc.From(now.Add(time.Second)), fourth)
+}
+
+func TestBatcherAdd(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+
+	schema := ident.MustSchema(ident.New("target"), ident.Public)
+	known := ident.NewTable(schema, ident.New("known"))
+	unknown := ident.NewTable(schema, ident.New("unknown"))
+	b := &batcher{
+		columns: func(tbl ident.Table) ([]types.ColData, bool) {
+			if !ident.Equal(tbl, known) {
+				return nil, false
+			}
+			return []types.ColData{
+				{Name: ident.New("pk"), Primary: true},
+				{Name: ident.New("val")},
+			}, true
+		},
+	}
+
+	now := time.Now()
+	lsn1 := mustLSN(t, "0x00000001000000010001")
+	lsn2 := mustLSN(t, "0x00000001000000020001")
+	changes := []*change{
+		{
+			columns:    []column{{"pk", 1}, {"val", "one"}},
+			commitTime: now,
+			lsn:        lsn1,
+			operation:  opInsert,
+			seq:        lsn1,
+			table:      known,
+		},
+		{
+			columns:    []column{{"pk", 1}},
+			commitTime: now,
+			lsn:        lsn1,
+			operation:  opInsert,
+			seq:        lsn1.next(),
+			table:      unknown,
+		},
+		{
+			columns:    []column{{"pk", 1}, {"val", "one"}},
+			commitTime: now,
+			lsn:        lsn2,
+			operation:  opDelete,
+			seq:        lsn2,
+			table:      known,
+		},
+	}
+
+	var batch types.MultiBatch
+	r.NoError(b.add(&batch, changes))
+	a.Equal(2, batch.Count())
+	r.Len(batch.Data, 2)
+	a.Equal(hlc.From(now), batch.Data[0].Time)
+	a.Equal(hlc.From(now).Next(), batch.Data[1].Time)
+	a.Equal(lsn2, b.lastLSN)
+	a.Equal(batch.Data[1].Time, b.last)
+
+	var muts []types.Mutation
+	for _, mut := range batch.Mutations() {
+		muts = append(muts, mut)
+	}
+	r.Len(muts, 2)
+	a.False(muts[0].IsDelete())
+	a.JSONEq(`[1]`, string(muts[0].Key))
+	a.JSONEq(`{"pk":1,"val":"one"}`, string(muts[0].Data))
+	a.True(muts[1].IsDelete())
+	a.JSONEq(`[1]`, string(muts[1].Key))
+}
+
+func TestToMutationErrors(t *testing.T) {
+	a := assert.New(t)
+	table := ident.NewTable(ident.MustSchema(ident.Public), ident.New("tbl"))
+	cols := []types.ColData{
+		{Name: ident.New("pk"), Primary: true},
+		{Name: ident.New("val")},
+	}
+
+	_, err := toMutation(&change{
+		columns:   []column{{"val", 1}},
+		operation: opInsert,
+		table:     table,
+	}, cols)
+	a.ErrorContains(err, "is not captured")
+
+	_, err = toMutation(&change{
+		columns:   []column{{"val", 1}},
+		operation: opInsert,
+		table:     table,
+	}, cols[1:])
+	a.ErrorContains(err, "has no primary key")
+
+	_, err = toMutation(&change{
+		columns:   []column{{"pk", 1}},
+		operation: 3,
+		table:     table,
+	}, cols)
+	a.ErrorContains(err, "unexpected operation")
+}
+
+func TestDecodeValue(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+
+	v, err := decodeValue("VARBINARY", []byte{0xca, 0xfe})
+	r.NoError(err)
+	a.Equal(`\xcafe`, v)
+
+	v, err = decodeValue("DECIMAL", []byte("12.50"))
+	r.NoError(err)
+	a.Equal("12.50", v)
+
+	v, err = decodeValue("INT", int64(42))
+	r.NoError(err)
+	a.Equal(int64(42), v)
+
+	// SQL Server stores the first groups of a GUID in little-endian.
+	v, err = decodeValue("UNIQUEIDENTIFIER", []byte{
+		0x33, 0x22, 0x11, 0x00, 0x55, 0x44, 0x77, 0x66,
+		0x88, 0x99, 0xaa, 0xbb, 0xcc, 0xdd, 0xee, 0xff,
+	})
+	r.NoError(err)
+	a.Equal("00112233-4455-6677-8899-AABBCCDDEEFF", v)
+}
@@ -0,0 +1,168 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package mssqlcdc
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/cockroachdb/replicator/internal/conveyor"
+	"github.com/cockroachdb/replicator/internal/script"
+	"github.com/cockroachdb/replicator/internal/sequencer"
+	"github.com/cockroachdb/replicator/internal/sinkprod"
+	"github.com/cockroachdb/replicator/internal/target/dlq"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+)
+
+const (
+	defaultBatchSize    = 1000
+	defaultLSNWindow    = 10_000
+	defaultPollInterval = time.Second
+	defaultSourceSchema = "dbo"
+)
+
+// EagerConfig is a hack to get Wire to move userscript evaluation to
+// the beginning of the injector. This allows CLI flags to be set by the
+// script.
+type EagerConfig Config
+
+// Config contains the configuration necessary for reading the change
+// tables of a SQL Server database. SourceConn and TargetSchema are
+// mandatory.
+type Config struct {
+	Conveyor  conveyor.Config
+	DLQ       dlq.Config
+	Script    script.Config
+	Sequencer sequencer.Config
+	Staging   sinkprod.StagingConfig
+	Target    sinkprod.TargetConfig
+
+	// The number of mutations to accumulate before delivering them to
+	// the target. Transactions are never split.
+	BatchSize int
+	// The LSN to start from, if no state has been persisted. If empty,
+	// the current maximum LSN of the source database is used.
+	DefaultLSN string
+	// The maximum number of source transactions to read from the
+	// change tables in each poll.
+	LSNWindow int
+	// How often to check the source database for new changes.
+	PollInterval time.Duration
+	// Connection string for the source db.
+	SourceConn string
+	// Only capture instances for tables within this schema are read.
+	SourceSchema string
+	// The SQL schema in the target cluster to write into.
+	TargetSchema ident.Schema
+
+	// The fields below are extracted by Preflight.
+
+	database   string // The name of the source database.
+	defaultLSN lsn
+	identifier string // Used for leasing and state.
+}
+
+// Bind adds flags to the set. It delegates to the embedded Config.Bind.
+func (c *Config) Bind(f *pflag.FlagSet) {
+	c.Conveyor.Bind(f)
+	c.DLQ.Bind(f)
+	c.Script.Bind(f)
+	c.Sequencer.Bind(f)
+	c.Staging.Bind(f)
+	c.Target.Bind(f)
+
+	f.IntVar(&c.BatchSize, "batchSize", defaultBatchSize,
+		"the number of mutations to accumulate before delivering them to the target")
+	f.StringVar(&c.DefaultLSN, "defaultLSN", "",
+		"the LSN to start from if no state is persisted, e.g. 0x0000002A000001F80003; "+
+			"defaults to the current maximum LSN")
+	f.IntVar(&c.LSNWindow, "lsnWindow", defaultLSNWindow,
+		"the maximum number of source transactions to read from the change tables at once")
+	f.DurationVar(&c.PollInterval, "pollInterval", defaultPollInterval,
+		"how often to check the source database for new changes")
+	f.StringVar(&c.SourceConn, "sourceConn", "",
+		"the source database's connection string")
+	f.StringVar(&c.SourceSchema, "sourceSchema", defaultSourceSchema,
+		"only read the capture instances of tables within this schema")
+	f.Var(ident.NewSchemaFlag(&c.TargetSchema), "targetSchema",
+		"the SQL database schema in the target cluster to update")
+}
+
+// Preflight updates the configuration with sane defaults or returns an
+// error if there are missing options for which a default cannot be
+// provided.
+func (c *Config) Preflight() error {
+	if err := c.Conveyor.Preflight(); err != nil {
+		return err
+	}
+	if err := c.DLQ.Preflight(); err != nil {
+		return err
+	}
+	if err := c.Script.Preflight(); err != nil {
+		return err
+	}
+	if err := c.Sequencer.Preflight(); err != nil {
+		return err
+	}
+	if err := c.Staging.Preflight(); err != nil {
+		return err
+	}
+	if err := c.Target.Preflight(); err != nil {
+		return err
+	}
+
+	if c.BatchSize <= 0 {
+		c.BatchSize = defaultBatchSize
+	}
+	if c.LSNWindow <= 0 {
+		c.LSNWindow = defaultLSNWindow
+	}
+	if c.PollInterval <= 0 {
+		c.PollInterval = defaultPollInterval
+	}
+	if c.SourceSchema == "" {
+		c.SourceSchema = defaultSourceSchema
+	}
+	if c.TargetSchema.Empty() {
+		return errors.New("no target schema specified")
+	}
+	if c.SourceConn == "" {
+		return errors.New("no SourceConn was configured")
+	}
+	u, err := url.Parse(c.SourceConn)
+	if err != nil {
+		return errors.Wrap(err, "invalid SourceConn")
+	}
+	if u.Scheme != "sqlserver" {
+		return errors.Errorf("SourceConn must use the sqlserver:// scheme, found %q", u.Scheme)
+	}
+	c.database = u.Query().Get("database")
+	if c.database == "" {
+		return errors.New("SourceConn must specify the database parameter")
+	}
+	if c.DefaultLSN != "" {
+		if c.defaultLSN, err = parseLSN(c.DefaultLSN); err != nil {
+			return err
+		}
+	}
+	c.identifier = fmt.Sprintf("mssqlcdc:%s/%s/%s:%s",
+		u.Host, c.database, c.SourceSchema, c.TargetSchema.Raw())
+	return nil
+}
@@ -0,0 +1,415 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package mssqlcdc contains support for reading changes from the
+// change data capture tables of a SQL Server database.
+// See https://learn.microsoft.com/en-us/sql/relational-databases/track-changes/about-change-data-capture-sql-server
+package mssqlcdc
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	mssql "github.com/microsoft/go-mssqldb"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// The name of the column that we add to the change table queries to
+// retrieve the commit time of the transaction.
+const commitTimeColumn = "__replicator_commit_time"
+
+// The capture instances of the tables in a schema. A table may have
+// two capture instances while its schema is being changed.
+const captureInstancesQuery = `
+SELECT capture_instance, OBJECT_NAME(source_object_id), sys.fn_cdc_get_min_lsn(capture_instance)
+  FROM cdc.change_tables
+ WHERE OBJECT_SCHEMA_NAME(source_object_id) = @p1
+ ORDER BY create_date`
+
+// Conn polls the change tables of the source database and delivers the
+// changes to a Conveyor.
+type Conn struct {
+	// Converts changes to mutations.
+	batcher *batcher
+	// The connector configuration.
+	config *Config
+	// Delivers mutation to the target database.
+	conveyor Conveyor
+	// Ensures that only one replicator instance reads the change tables.
+	leases types.Leases
+	// Access to the source database.
+	sourceDB *sql.DB
+	// Access to the staging database.
+	stagingPool *types.StagingPool
+	// Persistent state.
+	state state
+}
+
+// A captureInstance is a change table.
+type captureInstance struct {
+	// The first LSN which is available in the change table.
+	minLSN lsn
+	// The name of the capture instance.
+	name string
+	// True if the capture instance took over from an older capture
+	// instance of the same table.
+	replaces bool
+	// If not zero, the LSN at which a newer capture instance for the
+	// table took over.
+	supersededAt lsn
+	// The target table.
+	table ident.Table
+}
+
+// Start the replication loop.
+func (c *Conn) Start(ctx *stopper.Context) error {
+	// Ensure that only one replicator process is reading the change
+	// tables.
+	ctx.Go(func(ctx *stopper.Context) error {
+		log.Infof("Acquiring lease %s", c.config.identifier)
+		c.leases.Singleton(ctx,
+			[]string{c.config.identifier},
+			func(ctx context.Context) error {
+				if err := c.run(stopper.WithContext(ctx)); err != nil && err != stopper.ErrStopped {
+					log.WithError(err).Error("SQL Server CDC replication failed")
+					return err
+				}
+				log.Info("SQL Server CDC replication shutting down")
+				return types.ErrCancelSingleton
+			})
+		return nil
+	})
+	return nil
+}
+
+// run polls the change tables until the context is stopped.
+func (c *Conn) run(ctx *stopper.Context) error {
+	partition := ident.New(c.config.database)
+	if err := c.conveyor.Ensure(ctx, []ident.Ident{partition}); err != nil {
+		return err
+	}
+	last, err := c.state.getLast(ctx, c.stagingPool)
+	if err != nil {
+		return err
+	}
+	var from lsn
+	switch {
+	case !last.LSN.IsZero():
+		from = last.LSN.next()
+		log.Infof("resuming after LSN %s stored in the memo table", last.LSN)
+	case !c.config.defaultLSN.IsZero():
+		from = c.config.defaultLSN
+		log.Infof("starting from LSN %s from the command line", from)
+	default:
+		max, err := c.maxLSN(ctx)
+		if err != nil {
+			return err
+		}
+		from = max.next()
+		log.Infof("starting after the current maximum LSN %s", max)
+	}
+	c.batcher.last = last.Time
+
+	for !ctx.IsStopping() {
+		max, err := c.maxLSN(ctx)
+		if err != nil {
+			return err
+		}
+		if max.Compare(from) < 0 {
+			select {
+			case <-ctx.Stopping():
+			case <-time.After(c.config.PollInterval):
+			}
+			continue
+		}
+		to, err := c.windowEnd(ctx, from, max)
+		if err != nil {
+			return err
+		}
+		if err := c.poll(ctx, partition, from, to); err != nil {
+			return err
+		}
+		from = to.next()
+	}
+	return nil
+}
+
+// poll delivers the changes within the inclusive range of LSNs.
+func (c *Conn) poll(ctx *stopper.Context, partition ident.Ident, from, to lsn) error {
+	start := time.Now()
+	instances, err := c.captureInstances(ctx)
+	if err != nil {
+		return err
+	}
+	var changes []*change
+	for _, instance := range instances {
+		found, err := c.readChanges(ctx, instance, from, to)
+		if err != nil {
+			return err
+		}
+		changes = append(changes, found...)
+	}
+	sortChanges(changes)
+
+	before := c.batcher.last
+	batch := &types.MultiBatch{}
+	flush := func() error {
+		if batch.Count() == 0 {
+			return nil
+		}
+		if err := c.conveyor.AcceptMultiBatch(ctx, batch, &types.AcceptOptions{}); err != nil {
+			return err
+		}
+		batch = &types.MultiBatch{}
+		return nil
+	}
+	for len(changes) > 0 {
+		// Find the end of the transaction.
+		end := 1
+		for end < len(changes) && changes[end].lsn == changes[0].lsn {
+			end++
+		}
+		if err := c.batcher.add(batch, changes[:end]); err != nil {
+			return err
+		}
+		changes = changes[end:]
+		if batch.Count() >= c.config.BatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+
+	// Persist the watermark before advancing the checkpoint. If the
+	// process stops in between, the checkpoint will be advanced past
+	// these changes once the next transaction is read.
+	if err := c.state.setLast(ctx, c.stagingPool, &watermark{
+		LSN:  to,
+		Time: c.batcher.last,
+	}); err != nil {
+		return err
+	}
+	if c.batcher.last != before {
+		if err := c.conveyor.Advance(ctx, partition, c.batcher.last); err != nil {
+			return err
+		}
+	}
+	pollDurations.Observe(time.Since(start).Seconds())
+	return nil
+}
+
+// captureInstances returns the capture instances of the tables in the
+// source schema.
+func (c *Conn) captureInstances(ctx context.Context) ([]*captureInstance, error) {
+	rows, err := c.sourceDB.QueryContext(ctx, captureInstancesQuery, c.config.SourceSchema)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not query capture instances")
+	}
+	defer rows.Close()
+	var ret []*captureInstance
+	latest := &ident.TableMap[*captureInstance]{}
+	for rows.Next() {
+		var instance captureInstance
+		var tableName string
+		if err := rows.Scan(&instance.name, &tableName, &instance.minLSN); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		instance.table = ident.NewTable(c.config.TargetSchema, ident.New(tableName))
+		// Instances are ordered by creation; a newer instance for the
+		// same table takes over once it has started capturing.
+		if prev, ok := latest.Get(instance.table); ok {
+			prev.supersededAt = instance.minLSN
+			instance.replaces = true
+		}
+		latest.Put(instance.table, &instance)
+		ret = append(ret, &instance)
+	}
+	return ret, errors.WithStack(rows.Err())
+}
+
+// readChanges returns the changes in the capture instance within the
+// inclusive range of LSNs.
+func (c *Conn) readChanges(
+	ctx context.Context, instance *captureInstance, from, to lsn,
+) ([]*change, error) {
+	from, err := instance.start(from)
+	if err != nil {
+		return nil, err
+	}
+	if from.Compare(to) > 0 {
+		return nil, nil
+	}
+	q := fmt.Sprintf(
+		"SELECT sys.fn_cdc_map_lsn_to_time(__$start_lsn) AS %s, * "+
+			"FROM [cdc].[fn_cdc_get_all_changes_%s](@p1, @p2, N'all')",
+		commitTimeColumn, strings.ReplaceAll(instance.name, "]", "]]"))
+	rows, err := c.sourceDB.QueryContext(ctx, q, from, to)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not read changes from %s", instance.name)
+	}
+	defer rows.Close()
+	colTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var ret []*change
+	for rows.Next() {
+		values := make([]any, len(colTypes))
+		ptrs := make([]any, len(colTypes))
+		for idx := range values {
+			ptrs[idx] = &values[idx]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		chg := &change{table: instance.table}
+		for idx, colType := range colTypes {
+			name := colType.Name()
+			switch name {
+			case commitTimeColumn:
+				chg.commitTime, _ = values[idx].(time.Time)
+			case "__$start_lsn":
+				err = chg.lsn.Scan(values[idx])
+			case "__$seqval":
+				err = chg.seq.Scan(values[idx])
+			case "__$operation":
+				op, ok := values[idx].(int64)
+				if !ok {
+					err = errors.Errorf("unexpected operation type %T", values[idx])
+				}
+				chg.operation = int(op)
+			default:
+				if strings.HasPrefix(name, "__$") {
+					continue
+				}
+				var value any
+				value, err = decodeValue(colType.DatabaseTypeName(), values[idx])
+				chg.columns = append(chg.columns, column{name: name, value: value})
+			}
+			if err != nil {
+				return nil, errors.Wrapf(err, "could not decode %s from %s", name, instance.name)
+			}
+		}
+		if !instance.supersededAt.IsZero() && chg.lsn.Compare(instance.supersededAt) >= 0 {
+			continue
+		}
+		ret = append(ret, chg)
+	}
+	return ret, errors.WithStack(rows.Err())
+}
+
+// start returns the LSN from which the capture instance should be read
+// in order to resume from the given LSN. An error is returned if
+// changes after the resume point are no longer available, which occurs
+// if the CDC cleanup job has removed them. A capture instance which
+// replaces an older instance of the same table is read from its
+// beginning, since the older instance contains the earlier changes.
+func (i *captureInstance) start(from lsn) (lsn, error) {
+	if i.minLSN.Compare(from) <= 0 {
+		return from, nil
+	}
+	if !i.replaces {
+		return lsn{}, errors.Errorf(
+			"capture instance %s begins at %s, but replication resumes from %s; "+
+				"the changes in between are no longer available, "+
+				"possibly due to the CDC retention period",
+			i.name, i.minLSN, from)
+	}
+	log.Debugf("capture instance %s takes over at %s", i.name, i.minLSN)
+	return i.minLSN, nil
+}
+
+// This query returns the commit LSN of the last transaction within a
+// window of transactions which begins at a given LSN.
+//
+// $1 = the maximum number of transactions
+// $2 = the first LSN
+// $3 = the last LSN
+const windowQuery = `
+SELECT MAX(start_lsn) FROM (
+  SELECT TOP (@p1) start_lsn
+    FROM cdc.lsn_time_mapping
+   WHERE start_lsn >= @p2 AND start_lsn <= @p3
+   ORDER BY start_lsn) AS w`
+
+// windowEnd returns the LSN at which a poll that begins at from should
+// end, so that each poll reads at most LSNWindow transactions. The max
+// value is returned if fewer transactions are available.
+func (c *Conn) windowEnd(ctx context.Context, from, max lsn) (lsn, error) {
+	var raw []byte
+	if err := c.sourceDB.QueryRowContext(ctx, windowQuery,
+		c.config.LSNWindow, from, max,
+	).Scan(&raw); err != nil {
+		return lsn{}, errors.Wrap(err, "could not query LSN window")
+	}
+	if raw == nil {
+		return max, nil
+	}
+	var ret lsn
+	if err := ret.Scan(raw); err != nil {
+		return lsn{}, err
+	}
+	return ret, nil
+}
+
+// maxLSN returns the most recent LSN that is available in the change
+// tables.
+func (c *Conn) maxLSN(ctx context.Context) (lsn, error) {
+	var ret lsn
+	var raw []byte
+	if err := c.sourceDB.QueryRowContext(ctx,
+		"SELECT sys.fn_cdc_get_max_lsn()",
+	).Scan(&raw); err != nil {
+		return ret, errors.Wrap(err, "could not query maximum LSN")
+	}
+	if raw == nil {
+		return ret, errors.New("change data capture is not enabled for the source database")
+	}
+	return ret, ret.Scan(raw)
+}
+
+// decodeValue converts a value returned by the database driver into a
+// value that can be encoded as JSON.
+func decodeValue(typeName string, value any) (any, error) {
+	data, ok := value.([]byte)
+	if !ok {
+		return value, nil
+	}
+	switch typeName {
+	case "UNIQUEIDENTIFIER":
+		var u mssql.UniqueIdentifier
+		if err := u.Scan(data); err != nil {
+			return nil, err
+		}
+		return u.String(), nil
+	case "BINARY", "IMAGE", "TIMESTAMP", "VARBINARY":
+		return `\x` + hex.EncodeToString(data), nil
+	default:
+		// Includes DECIMAL, MONEY, and character types.
+		return string(data), nil
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package mssqlcdc
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/hex"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// An lsn is a SQL Server log sequence number, which identifies a
+// position in the transaction log. The value is formatted as
+// hexadecimal, as in the output of sys.fn_cdc_get_max_lsn().
+type lsn [10]byte
+
+// next returns the LSN that immediately follows this one.
+func (l lsn) next() lsn {
+	for idx := len(l) - 1; idx >= 0; idx-- {
+		l[idx]++
+		if l[idx] != 0 {
+			break
+		}
+	}
+	return l
+}
+
+// parseLSN parses an LSN from its hexadecimal representation, with an
+// optional 0x prefix.
+func parseLSN(s string) (lsn, error) {
+	var ret lsn
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")
+	data, err := hex.DecodeString(s)
+	if err != nil {
+		return ret, errors.Wrapf(err, "invalid LSN %q", s)
+	}
+	if len(data) != len(ret) {
+		return ret, errors.Errorf("invalid LSN %q: expecting %d bytes", s, len(ret))
+	}
+	copy(ret[:], data)
+	return ret, nil
+}
+
+// Compare returns -1, 0, or 1 if the LSN is less than, equal to, or
+// greater than the other.
+func (l lsn) Compare(o lsn) int {
+	return bytes.Compare(l[:], o[:])
+}
+
+// IsZero returns true if the LSN has not been set.
+func (l lsn) IsZero() bool {
+	return l == lsn{}
+}
+
+// MarshalText implements [encoding.TextMarshaler].
+func (l lsn) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+// Scan implements [sql.Scanner]. The database driver returns LSNs as
+// BINARY(10) values.
+func (l *lsn) Scan(src any) error {
+	data, ok := src.([]byte)
+	if !ok {
+		return errors.Errorf("unexpected LSN type %T", src)
+	}
+	if len(data) != len(l) {
+		return errors.Errorf("unexpected LSN length %d", len(data))
+	}
+	copy(l[:], data)
+	return nil
+}
+
+// String returns the hexadecimal representation of the LSN.
+func (l lsn) String() string {
+	return "0x" + strings.ToUpper(hex.EncodeToString(l[:]))
+}
+
+// Value implements [driver.Valuer].
+func (l lsn) Value() (driver.Value, error) {
+	return l[:], nil
+}
+
+// UnmarshalText implements [encoding.TextUnmarshaler].
+func (l *lsn) UnmarshalText(data []byte) error {
+	parsed, err := parseLSN(string(data))
+	if err != nil {
+		return err
+	}
+	*l = parsed
+	return nil
+}
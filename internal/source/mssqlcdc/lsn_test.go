@@ -0,0 +1,112 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package mssqlcdc
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLSN(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+
+	l, err := parseLSN("0x0000002A000001A80003")
+	r.NoError(err)
+	a.Equal("0x0000002A000001A80003", l.String())
+	a.False(l.IsZero())
+
+	// The prefix is optional and the case does not matter.
+	same, err := parseLSN("0000002a000001a80003")
+	r.NoError(err)
+	a.Equal(l, same)
+	a.Equal(0, l.Compare(same))
+
+	next := l.next()
+	a.Equal("0x0000002A000001A80004", next.String())
+	a.Equal(-1, l.Compare(next))
+	a.Equal(1, next.Compare(l))
+
+	// Verify carry.
+	carry, err := parseLSN("0x000000000000000000FF")
+	r.NoError(err)
+	a.Equal("0x00000000000000000100", carry.next().String())
+
+	var zero lsn
+	a.True(zero.IsZero())
+
+	_, err = parseLSN("0x0102")
+	a.ErrorContains(err, "expecting 10 bytes")
+	_, err = parseLSN("not hex")
+	a.ErrorContains(err, "invalid LSN")
+}
+
+func TestLSNEncoding(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+
+	l, err := parseLSN("0x0000002A000001A80003")
+	r.NoError(err)
+
+	data, err := json.Marshal(l)
+	r.NoError(err)
+	a.Equal(`"0x0000002A000001A80003"`, string(data))
+	var decoded lsn
+	r.NoError(json.Unmarshal(data, &decoded))
+	a.Equal(l, decoded)
+
+	value, err := l.Value()
+	r.NoError(err)
+	var scanned lsn
+	r.NoError(scanned.Scan(value))
+	a.Equal(l, scanned)
+
+	a.ErrorContains(scanned.Scan("0x00"), "unexpected LSN type")
+	a.ErrorContains(scanned.Scan([]byte{1, 2}), "unexpected LSN length")
+}
+
+func TestCaptureInstanceStart(t *testing.T) {
+	r := require.New(t)
+
+	min := lsn{9: 10}
+	before := lsn{9: 5}
+	after := lsn{9: 15}
+
+	instance := &captureInstance{minLSN: min, name: "dbo_t"}
+
+	// Resuming within the capture instance is unaffected.
+	start, err := instance.start(after)
+	r.NoError(err)
+	r.Equal(after, start)
+	start, err = instance.start(min)
+	r.NoError(err)
+	r.Equal(min, start)
+
+	// Changes between the resume point and the beginning of the
+	// capture instance have been lost.
+	_, err = instance.start(before)
+	r.ErrorContains(err, "no longer available")
+
+	// A replacement instance picks up where the older one ends.
+	instance.replaces = true
+	start, err = instance.start(before)
+	r.NoError(err)
+	r.Equal(min, start)
+}
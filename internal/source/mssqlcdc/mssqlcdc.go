@@ -0,0 +1,56 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package mssqlcdc
+
+import (
+	"net/http"
+
+	"github.com/cockroachdb/replicator/internal/conveyor"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/diag"
+	"github.com/cockroachdb/replicator/internal/util/stdlogical"
+)
+
+// MSSQLCDC is a logical replication loop that reads the change tables
+// of a SQL Server database.
+type MSSQLCDC struct {
+	Authenticator types.Authenticator // Nil if there is no staging database.
+	Conn          *Conn
+	Conveyors     *conveyor.Conveyors
+	Diagnostics   *diag.Diagnostics
+}
+
+var (
+	_ stdlogical.HasAdmin              = (*MSSQLCDC)(nil)
+	_ stdlogical.HasAdminAuthenticator = (*MSSQLCDC)(nil)
+	_ stdlogical.HasDiagnostics        = (*MSSQLCDC)(nil)
+)
+
+// GetAdminHandler implements [stdlogical.HasAdmin].
+func (m *MSSQLCDC) GetAdminHandler(auth types.Authenticator) http.Handler {
+	return m.Conveyors.AdminHandler(auth)
+}
+
+// GetAdminAuthenticator implements [stdlogical.HasAdminAuthenticator].
+func (m *MSSQLCDC) GetAdminAuthenticator() types.Authenticator {
+	return m.Authenticator
+}
+
+// GetDiagnostics implements [stdlogical.HasDiagnostics].
+func (m *MSSQLCDC) GetDiagnostics() *diag.Diagnostics {
+	return m.Diagnostics
+}
@@ -0,0 +1,109 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package mssqlcdc
+
+import (
+	"database/sql"
+
+	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/replicator/internal/conveyor"
+	"github.com/cockroachdb/replicator/internal/script"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/diag"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/cockroachdb/replicator/internal/util/stdserver"
+	"github.com/google/wire"
+	"github.com/pkg/errors"
+
+	_ "github.com/microsoft/go-mssqldb" // Registers the sqlserver driver.
+)
+
+// Set is used by Wire.
+var Set = wire.NewSet(
+	ProvideAuthenticator,
+	ProvideConn,
+	ProvideEagerConfig,
+)
+
+// ProvideEagerConfig is a hack to move up the evaluation of the user
+// script so that the options callbacks can set any non-script-related
+// CLI flags.
+func ProvideEagerConfig(cfg *Config, _ *script.Loader) *EagerConfig {
+	return (*EagerConfig)(cfg)
+}
+
+// ProvideAuthenticator is called by Wire to construct a JWT-based
+// authenticator for the administrative endpoints. It returns nil if
+// staging data is kept in an embedded store.
+func ProvideAuthenticator(
+	ctx *stopper.Context,
+	diags *diag.Diagnostics,
+	pool *types.StagingPool,
+	stagingDB ident.StagingSchema,
+) (types.Authenticator, error) {
+	return stdserver.StagingAuthenticator(ctx, diags, pool, stagingDB)
+}
+
+// ProvideConn is called by Wire to construct the connector. There's a
+// fake dependency on the script loader so that flags can be evaluated
+// first.
+func ProvideConn(
+	ctx *stopper.Context,
+	config *Config,
+	conv *conveyor.Conveyors,
+	leases types.Leases,
+	memo types.Memo,
+	stagingPool *types.StagingPool,
+) (*Conn, error) {
+	if err := config.Preflight(); err != nil {
+		return nil, err
+	}
+	conveyors := conv.WithKind("mssqlcdc")
+	if err := conveyors.Bootstrap(); err != nil {
+		return nil, err
+	}
+	conveyor, err := conveyors.Get(config.TargetSchema)
+	if err != nil {
+		return nil, err
+	}
+
+	sourceDB, err := sql.Open("sqlserver", config.SourceConn)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	ctx.Defer(func() { _ = sourceDB.Close() })
+	if err := sourceDB.PingContext(ctx); err != nil {
+		return nil, errors.Wrap(err, "could not connect to the source database")
+	}
+
+	columns := func(table ident.Table) ([]types.ColData, bool) {
+		return conveyor.Watcher().Get().Columns.Get(table)
+	}
+	conn := &Conn{
+		batcher:     &batcher{columns: columns},
+		config:      config,
+		conveyor:    conveyor,
+		leases:      leases,
+		sourceDB:    sourceDB,
+		stagingPool: stagingPool,
+		state: state{
+			key:  config.identifier,
+			memo: memo,
+		},
+	}
+	return conn, conn.Start(ctx)
+}
@@ -0,0 +1,67 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package mssqlcdc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/hlc"
+	"github.com/pkg/errors"
+)
+
+// state is used to persist the LSN watermark.
+type state struct {
+	key  string
+	memo types.Memo
+}
+
+// watermark records the last LSN that was read from the change tables
+// and the time that was assigned to the last transaction.
+type watermark struct {
+	LSN  lsn      `json:"lsn"`
+	Time hlc.Time `json:"time"`
+}
+
+// getLast retrieves the watermark. A zero value is returned if no
+// watermark has been stored.
+func (s *state) getLast(ctx context.Context, tx types.StagingQuerier) (*watermark, error) {
+	v, err := s.memo.Get(ctx, tx, s.key)
+	if err != nil {
+		return nil, err
+	}
+	ret := &watermark{}
+	if v != nil {
+		dec := json.NewDecoder(bytes.NewReader(v))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(ret); err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	return ret, nil
+}
+
+// setLast stores the watermark.
+func (s *state) setLast(ctx context.Context, tx types.StagingQuerier, last *watermark) error {
+	value, err := json.Marshal(last)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return s.memo.Put(ctx, tx, s.key, value)
+}
@@ -22,12 +22,15 @@ import (
 	"net/url"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/cockroachdb/replicator/internal/script"
 	"github.com/cockroachdb/replicator/internal/sequencer"
 	"github.com/cockroachdb/replicator/internal/sinkprod"
 	"github.com/cockroachdb/replicator/internal/target/dlq"
+	"github.com/cockroachdb/replicator/internal/target/evolve"
 	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/cockroachdb/replicator/internal/util/snapshot"
 	"github.com/pkg/errors"
 	"github.com/spf13/pflag"
 )
@@ -41,15 +44,20 @@ type EagerConfig Config
 // replication connection. ServerID and SourceConn are mandatory.
 type Config struct {
 	DLQ       dlq.Config
+	Evolve    evolve.Config
 	Script    script.Config
 	Sequencer sequencer.Config
+	Snapshot  snapshot.Config
 	Staging   sinkprod.StagingConfig
 	Target    sinkprod.TargetConfig
 
+	DDLPolicy     DDLPolicy
 	InitialGTID   string
 	FetchMetadata bool
 	SourceConn    string // Connection string for the source db.
 	ProcessID     uint32 // A unique ID to identify this process to the master.
+	// The source database whose tables are copied by the snapshot.
+	SnapshotDatabase string
 	// The SQL schema in the target cluster to write into. This value is
 	// optional if a userscript dispatch function is present.
 	TargetSchema ident.Schema
@@ -66,16 +74,22 @@ type Config struct {
 // Bind adds flags to the set. It delegates to the embedded Config.Bind.
 func (c *Config) Bind(f *pflag.FlagSet) {
 	c.DLQ.Bind(f)
+	c.Evolve.Bind(f)
 	c.Script.Bind(f)
 	c.Sequencer.Bind(f)
+	c.Snapshot.Bind(f)
 	c.Staging.Bind(f)
 	c.Target.Bind(f)
 
+	f.Var(&c.DDLPolicy, "ddlPolicy",
+		"how to handle DDL statements; one of ignore, pause, apply, or script")
 	f.StringVar(&c.InitialGTID, "defaultGTIDSet", "",
 		"default GTIDSet. Used if no state is persisted")
 
 	f.Uint32Var(&c.ProcessID, "replicationProcessID", 10,
 		"the replication process id to report to the source database")
+	f.StringVar(&c.SnapshotDatabase, "snapshotDatabase", "",
+		"the source database whose tables are copied by the initial snapshot")
 	f.StringVar(&c.SourceConn, "sourceConn", "",
 		"the source database's connection string")
 	f.BoolVar(&c.FetchMetadata, "fetchMetadata", false,
@@ -138,12 +152,18 @@ func (c *Config) Preflight() error {
 	if err := c.DLQ.Preflight(); err != nil {
 		return err
 	}
+	if err := c.Evolve.Preflight(); err != nil {
+		return err
+	}
 	if err := c.Script.Preflight(); err != nil {
 		return err
 	}
 	if err := c.Sequencer.Preflight(); err != nil {
 		return err
 	}
+	if err := c.Snapshot.Preflight(); err != nil {
+		return err
+	}
 	if err := c.Staging.Preflight(); err != nil {
 		return err
 	}
@@ -163,6 +183,24 @@ func (c *Config) Preflight() error {
 		return errors.New("no SourceConn was configured")
 	}
 
+	// Schema changes are executed directly against the target tables.
+	switch c.DDLPolicy {
+	case DDLPolicyApply, DDLPolicyScript:
+		if c.Target.Redirected() {
+			return errors.Errorf("--ddlPolicy %s cannot be used with a Kafka or object-store target",
+				strings.ToLower(c.DDLPolicy.String()))
+		}
+	}
+
+	if c.Snapshot.Enabled {
+		if c.SnapshotDatabase == "" {
+			return errors.New("snapshotDatabase must be specified to take a snapshot")
+		}
+		if c.InitialGTID != "" {
+			return errors.New("defaultGTIDSet cannot be used with snapshot")
+		}
+	}
+
 	u, err := url.Parse(c.SourceConn)
 	if err != nil {
 		return err
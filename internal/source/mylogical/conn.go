@@ -29,6 +29,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/cockroachdb/field-eng-powertools/notify"
@@ -36,6 +37,7 @@ import (
 	"github.com/cockroachdb/field-eng-powertools/stopvar"
 	"github.com/cockroachdb/replicator/internal/script"
 	"github.com/cockroachdb/replicator/internal/sequencer"
+	"github.com/cockroachdb/replicator/internal/target/evolve"
 	"github.com/cockroachdb/replicator/internal/types"
 	"github.com/cockroachdb/replicator/internal/util/diag"
 	"github.com/cockroachdb/replicator/internal/util/hlc"
@@ -63,14 +65,23 @@ type conn struct {
 	columns *ident.TableMap[[]types.ColData]
 	// The connector configuration.
 	config *Config
+	// Creates or alters target tables, if enabled.
+	evolver *evolve.Evolver
 	// Flavor is one of the mysql.MySQLFlavor or mysql.MariaDBFlavor constants
 	flavor string
 	// Persistent storage for WAL data.
 	memo types.Memo
 	// Ensure the timestamps we generate always march forward.
 	monotonic hlc.Clock
+	// Set if the DDLPolicyScript is in use.
+	onSchemaChange script.OnSchemaChange
 	// Map source ids to target tables.
 	relations map[uint64]ident.Table
+	// Recently-observed DDL statements, reported via Diagnostic.
+	schemaChanges struct {
+		sync.Mutex
+		recent []*schemaChange
+	}
 	// Progress reports from the underlying sequencer.
 	stat *notify.Var[sequencer.Stat]
 	// The configuration for opening replication connections.
@@ -81,8 +92,15 @@ type conn struct {
 	target ident.Schema
 	// Access to the target database.
 	targetDB *types.TargetPool
+	// Access to the target schema.
+	watchers types.Watchers
 	// Managed by persistWALOffset.
 	walOffset notify.Var[*consistentPoint]
+	// The WAL offset most recently written to the memo.
+	walStored struct {
+		sync.Mutex
+		last *consistentPoint
+	}
 }
 
 // mutationType is the type of mutation
@@ -101,10 +119,15 @@ var _ diag.Diagnostic = (*conn)(nil)
 
 // Diagnostic implements [diag.Diagnostic].
 func (c *conn) Diagnostic(_ context.Context) any {
+	c.schemaChanges.Lock()
+	schemaChanges := append([]*schemaChange(nil), c.schemaChanges.recent...)
+	c.schemaChanges.Unlock()
+
 	return map[string]any{
-		"columns":   c.columns,
-		"flavor":    c.flavor,
-		"relations": c.relations,
+		"columns":       c.columns,
+		"flavor":        c.flavor,
+		"relations":     c.relations,
+		"schemaChanges": schemaChanges,
 	}
 }
 
@@ -115,10 +138,20 @@ func (c *conn) Start(ctx *stopper.Context) error {
 		return err
 	}
 
-	// Start a process to copy data to the target.
+	// Start a process to copy data to the target. Streaming begins
+	// once the initial snapshot, if any, has completed.
 	ctx.Go(func(ctx *stopper.Context) error {
+		snapshotDone := false
 		for !ctx.IsStopping() {
-			if err := c.copyMessages(ctx); err != nil {
+			var err error
+			if !snapshotDone {
+				err = c.snapshot(ctx)
+				snapshotDone = err == nil
+			}
+			if snapshotDone {
+				err = c.copyMessages(ctx)
+			}
+			if err != nil {
 				log.WithError(err).Warn("error while copying messages; will retry")
 				select {
 				case <-ctx.Stopping():
@@ -197,19 +230,7 @@ func (c *conn) accumulateBatch(
 			if err := tx.Commit(); err != nil {
 				return nil, errors.WithStack(err)
 			}
-
-			// TODO(bob): This is a temporary hack until this frontend
-			// is switched to using the core sequencer. Very shortly,
-			// the sequencer stat will reflect the progress of
-			// transactions that have been committed to the target. In
-			// the meantime, we're in immediate operation, so we'll fake
-			// one up.
-			fakeProgress := &ident.TableMap[hlc.Range]{}
-			fakeTable := ident.NewTable(c.target, ident.New("fake"))
-			fakeProgress.Put(fakeTable, hlc.RangeIncluding(hlc.Zero(), batch.Time))
-			c.stat.Set(sequencer.NewStat(&types.TableGroup{
-				Tables: []ident.Table{fakeTable},
-			}, fakeProgress))
+			c.markProgress(batch.Time)
 		}
 
 		return nil, nil
@@ -234,12 +255,9 @@ func (c *conn) accumulateBatch(
 		}, nil
 
 	case *replication.MariadbGTIDEvent:
-		// We ignore events that won't have a terminating COMMIT
-		// events, e.g. schema changes.
+		// Events that won't have a terminating COMMIT, e.g. schema
+		// changes, are followed by a single QueryEvent.
 		// See flags section: https://mariadb.com/kb/en/gtid_event/
-		if e.IsStandalone() {
-			return nil, nil
-		}
 		ts := time.Unix(int64(ev.Header.Timestamp), 0)
 		lastCP := c.monotonic.Last().External().(*consistentPoint)
 		nextCP, err := lastCP.withMariaGTIDSet(ts, &e.GTID)
@@ -251,15 +269,28 @@ func (c *conn) accumulateBatch(
 		}, nil
 
 	case *replication.QueryEvent:
-		// Only supporting BEGIN
-		// DDL statement would also sent here.
 		log.Tracef("Query:  %s %+v\n", e.Query, e.GSet)
 		if bytes.Equal(e.Query, []byte("BEGIN")) {
 			return batch, nil
 		}
+		isDDL, err := c.onDDL(ctx, e)
+		if err != nil {
+			return nil, err
+		}
+		// A DDL statement is its own transaction, so we'll mark the
+		// GTID as having been processed. The GTID is stored before
+		// reading any further events, since the statement may have
+		// been executed in the target and cannot be repeated.
+		if isDDL && batch != nil && batch.Count() == 0 {
+			c.markProgress(batch.Time)
+			if err := c.storeWALOffset(ctx, batch.Time.External().(*consistentPoint)); err != nil {
+				return nil, err
+			}
+			return nil, nil
+		}
 
 	case *replication.TableMapEvent:
-		if err := c.onRelation(e); err != nil {
+		if err := c.onRelation(ctx, e); err != nil {
 			return batch, err
 		}
 
@@ -286,6 +317,22 @@ func (c *conn) accumulateBatch(
 	return batch, nil
 }
 
+// markProgress updates the sequencer stat to indicate that all
+// transactions up to and including the time have been committed.
+func (c *conn) markProgress(time hlc.Time) {
+	// TODO(bob): This is a temporary hack until this frontend is
+	// switched to using the core sequencer. Very shortly, the sequencer
+	// stat will reflect the progress of transactions that have been
+	// committed to the target. In the meantime, we're in immediate
+	// operation, so we'll fake one up.
+	fakeProgress := &ident.TableMap[hlc.Range]{}
+	fakeTable := ident.NewTable(c.target, ident.New("fake"))
+	fakeProgress.Put(fakeTable, hlc.RangeIncluding(hlc.Zero(), time))
+	c.stat.Set(sequencer.NewStat(&types.TableGroup{
+		Tables: []ident.Table{fakeTable},
+	}, fakeProgress))
+}
+
 // copyMessages is the main replication loop. It will open a connection
 // to the source, accumulate messages, and commit data to the target.
 func (c *conn) copyMessages(ctx *stopper.Context) error {
@@ -469,7 +516,7 @@ func (c *conn) getColNames(table ident.Table) ([][]byte, []uint64, error) {
 // onRelation updates the source database namespace mappings.
 // Columns names are only available if
 // set global binlog_row_metadata = full;
-func (c *conn) onRelation(msg *replication.TableMapEvent) error {
+func (c *conn) onRelation(ctx context.Context, msg *replication.TableMapEvent) error {
 	targetTbl := ident.NewTable(c.target, ident.New(string(msg.Table)))
 	log.Tracef("Learned %+v", targetTbl)
 	columnNames, primaryKeys := msg.ColumnName, msg.PrimaryKey
@@ -491,6 +538,7 @@ func (c *conn) onRelation(msg *replication.TableMapEvent) error {
 	}
 	c.relations[msg.TableID] = targetTbl
 	colData := make([]types.ColData, msg.ColumnCount)
+	sourceCols := make([]types.ColData, msg.ColumnCount)
 	primary := make(map[uint64]bool)
 	for _, p := range primaryKeys {
 		primary[p] = true
@@ -507,9 +555,57 @@ func (c *conn) onRelation(msg *replication.TableMapEvent) error {
 			Primary: found,
 			Type:    fmt.Sprintf("%d", ctype),
 		}
+		sourceCols[idx] = colData[idx]
+		sourceCols[idx].Type = sourceType(msg, idx)
 	}
 	c.columns.Put(targetTbl, colData)
-	return nil
+	// Create or alter the target table before any data arrives.
+	return c.evolver.Ensure(ctx, targetTbl, sourceCols)
+}
+
+// sourceType returns the PostgreSQL-style name of a column type, or an
+// empty string if the type cannot be mapped. The names are understood
+// by the schema evolution logic.
+func sourceType(msg *replication.TableMapEvent, idx int) string {
+	switch msg.ColumnType[idx] {
+	case mysql.MYSQL_TYPE_TINY, mysql.MYSQL_TYPE_SHORT, mysql.MYSQL_TYPE_YEAR:
+		return "int2"
+	case mysql.MYSQL_TYPE_INT24, mysql.MYSQL_TYPE_LONG:
+		return "int4"
+	case mysql.MYSQL_TYPE_LONGLONG, mysql.MYSQL_TYPE_BIT:
+		return "int8"
+	case mysql.MYSQL_TYPE_FLOAT:
+		return "float4"
+	case mysql.MYSQL_TYPE_DOUBLE:
+		return "float8"
+	case mysql.MYSQL_TYPE_DECIMAL, mysql.MYSQL_TYPE_NEWDECIMAL:
+		return "numeric"
+	case mysql.MYSQL_TYPE_DATE, mysql.MYSQL_TYPE_NEWDATE:
+		return "date"
+	case mysql.MYSQL_TYPE_TIME, mysql.MYSQL_TYPE_TIME2:
+		return "time"
+	case mysql.MYSQL_TYPE_DATETIME, mysql.MYSQL_TYPE_DATETIME2:
+		return "timestamp"
+	case mysql.MYSQL_TYPE_TIMESTAMP, mysql.MYSQL_TYPE_TIMESTAMP2:
+		return "timestamptz"
+	case mysql.MYSQL_TYPE_JSON:
+		return "jsonb"
+	case mysql.MYSQL_TYPE_GEOMETRY:
+		return "geometry"
+	case mysql.MYSQL_TYPE_VARCHAR, mysql.MYSQL_TYPE_VAR_STRING, mysql.MYSQL_TYPE_STRING,
+		mysql.MYSQL_TYPE_ENUM, mysql.MYSQL_TYPE_SET:
+		return "text"
+	case mysql.MYSQL_TYPE_TINY_BLOB, mysql.MYSQL_TYPE_MEDIUM_BLOB,
+		mysql.MYSQL_TYPE_LONG_BLOB, mysql.MYSQL_TYPE_BLOB:
+		// TEXT columns are reported as BLOBs with a character set,
+		// which requires binlog_row_metadata=FULL.
+		if msg.IsCharacterColumn(idx) {
+			return "text"
+		}
+		return "bytea"
+	default:
+		return ""
+	}
 }
 
 var (
@@ -600,8 +696,7 @@ func getFlavor(config *Config) (string, string, error) {
 // in the stopper to occasionally write an updated value back to the
 // memo.
 func (c *conn) persistWALOffset(ctx *stopper.Context) error {
-	key := fmt.Sprintf("mysql-wal-offset-%s", c.target.Raw())
-	found, err := c.memo.Get(ctx, c.stagingDB, key)
+	found, err := c.memo.Get(ctx, c.stagingDB, c.walOffsetKey())
 	if err != nil {
 		return err
 	}
@@ -629,9 +724,7 @@ func (c *conn) persistWALOffset(ctx *stopper.Context) error {
 	ctx.Go(func(ctx *stopper.Context) error {
 		_, err := stopvar.DoWhenChanged(ctx, cp, &c.walOffset,
 			func(ctx *stopper.Context, _, cp *consistentPoint) error {
-				if err := c.memo.Put(ctx, c.stagingDB, key, []byte(cp.String())); err == nil {
-					log.Tracef("stored WAL offset %s: %s", key, cp)
-				} else {
+				if err := c.storeWALOffset(ctx, cp); err != nil {
 					log.WithError(err).Error("could not persist WAL offset")
 				}
 				return nil
@@ -641,6 +734,28 @@ func (c *conn) persistWALOffset(ctx *stopper.Context) error {
 	return nil
 }
 
+// storeWALOffset writes the WAL offset to the memo, unless a more
+// recent offset has already been written.
+func (c *conn) storeWALOffset(ctx context.Context, cp *consistentPoint) error {
+	c.walStored.Lock()
+	defer c.walStored.Unlock()
+	if last := c.walStored.last; last != nil && cp.Less(last) {
+		return nil
+	}
+	key := c.walOffsetKey()
+	if err := c.memo.Put(ctx, c.stagingDB, key, []byte(cp.String())); err != nil {
+		return err
+	}
+	c.walStored.last = cp
+	log.Tracef("stored WAL offset %s: %s", key, cp)
+	return nil
+}
+
+// walOffsetKey returns the memo key for the WAL offset.
+func (c *conn) walOffsetKey() string {
+	return fmt.Sprintf("mysql-wal-offset-%s", c.target.Raw())
+}
+
 // checkSystemSetting verifies that the given system variable is set to one of
 // the expected values.
 func checkSystemSetting(c *client.Conn, variable string, expected []string) error {
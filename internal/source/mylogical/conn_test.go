@@ -24,7 +24,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/cockroachdb/field-eng-powertools/notify"
 	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/replicator/internal/sequencer"
 	"github.com/cockroachdb/replicator/internal/types"
 	"github.com/cockroachdb/replicator/internal/util/ident"
 	"github.com/go-mysql-org/go-mysql/mysql"
@@ -329,7 +331,7 @@ func TestOnRelation(t *testing.T) {
 				relations: make(map[uint64]ident.Table),
 				target:    tt.targetSchema,
 			}
-			err := c.onRelation(tt.tableEvent)
+			err := c.onRelation(context.Background(), tt.tableEvent)
 			a.NoError(err)
 			a.Equal(tt.wantTable.Raw(), c.relations[tt.tableEvent.TableID].Raw())
 			cols, ok := c.columns.Get(tt.wantTable)
@@ -452,3 +454,48 @@ func TestInitialConsistentPoint(t *testing.T) {
 		})
 	}
 }
+
+// TestDDLStoresWALOffset verifies that the GTID of a DDL statement is
+// written to the memo before the next event is processed.
+func TestDDLStoresWALOffset(t *testing.T) {
+	r := require.New(t)
+	stop := stopper.WithContext(context.Background())
+	defer stop.Stop(0)
+
+	m := &mockMemo{}
+	c := &conn{
+		columns:   &ident.TableMap[[]types.ColData]{},
+		config:    &Config{DDLPolicy: DDLPolicyIgnore},
+		flavor:    mysql.MySQLFlavor,
+		memo:      m,
+		relations: make(map[uint64]ident.Table),
+		stat:      &notify.Var[sequencer.Stat]{},
+		target:    ident.MustSchema(ident.New("db"), ident.Public),
+	}
+	older, err := newConsistentPoint(mysql.MySQLFlavor).
+		parseFrom("6fa7e6ef-c49a-11ec-950a-0242ac120002:1-10")
+	r.NoError(err)
+	cp, err := newConsistentPoint(mysql.MySQLFlavor).
+		parseFrom("6fa7e6ef-c49a-11ec-950a-0242ac120002:1-11")
+	r.NoError(err)
+
+	batch, err := c.accumulateBatch(stop, &replication.BinlogEvent{
+		Header: &replication.EventHeader{},
+		Event: &replication.QueryEvent{
+			Schema: []byte("src"),
+			Query:  []byte("CREATE TABLE t (id INT PRIMARY KEY)"),
+		},
+	}, &types.TemporalBatch{Time: c.monotonic.External(cp)})
+	r.NoError(err)
+	r.Nil(batch)
+
+	stored, err := m.Get(stop, nil, c.walOffsetKey())
+	r.NoError(err)
+	r.Equal(cp.String(), string(stored))
+
+	// An older offset, e.g. from the asynchronous writer, is ignored.
+	r.NoError(c.storeWALOffset(stop, older))
+	stored, err = m.Get(stop, nil, c.walOffsetKey())
+	r.NoError(err)
+	r.Equal(cp.String(), string(stored))
+}
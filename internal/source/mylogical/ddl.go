@@ -0,0 +1,613 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package mylogical
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/replicator/internal/script"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/go-mysql-org/go-mysql/replication"
+	"github.com/pingcap/tidb/pkg/parser"
+	"github.com/pingcap/tidb/pkg/parser/ast"
+	"github.com/pingcap/tidb/pkg/parser/charset"
+	"github.com/pingcap/tidb/pkg/parser/format"
+	"github.com/pingcap/tidb/pkg/parser/model"
+	"github.com/pingcap/tidb/pkg/parser/mysql"
+	_ "github.com/pingcap/tidb/pkg/parser/test_driver" // Literal values.
+	parserTypes "github.com/pingcap/tidb/pkg/parser/types"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+// DDLPolicy determines how DDL statements in the binlog are handled.
+type DDLPolicy int
+
+// These constants are ordered such that the zero value is the default.
+const (
+	// DDLPolicyIgnore logs the schema change and continues.
+	DDLPolicyIgnore DDLPolicy = iota
+	// DDLPolicyPause halts replication until the operator intervenes.
+	DDLPolicyPause
+	// DDLPolicyApply translates the DDL and executes it in the target.
+	DDLPolicyApply
+	// DDLPolicyScript passes the DDL to a userscript callback.
+	DDLPolicyScript
+)
+
+//go:generate go run golang.org/x/tools/cmd/stringer -type=DDLPolicy -trimprefix DDLPolicy
+
+// DDLPolicies returns all valid DDLPolicy values.
+func DDLPolicies() []DDLPolicy {
+	return []DDLPolicy{DDLPolicyIgnore, DDLPolicyPause, DDLPolicyApply, DDLPolicyScript}
+}
+
+// Set implements [pflag.Value].
+func (p *DDLPolicy) Set(v string) error {
+	if v == "" {
+		*p = DDLPolicyIgnore
+		return nil
+	}
+	for _, policy := range DDLPolicies() {
+		if strings.EqualFold(policy.String(), v) {
+			*p = policy
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown DDL policy %q", v)
+}
+
+// Type implements [pflag.Value].
+func (p *DDLPolicy) Type() string {
+	return "string"
+}
+
+// The number of schema changes to retain for diagnostic purposes.
+const schemaChangeHistory = 16
+
+// systemSchemas will not have their DDL statements reported.
+var systemSchemas = map[string]bool{
+	"information_schema": true,
+	"mysql":              true,
+	"performance_schema": true,
+	"sys":                true,
+}
+
+// ddlPattern is a cheap filter to avoid invoking the SQL parser on
+// QueryEvents that cannot possibly contain a table-related DDL
+// statement (e.g. COMMIT, SAVEPOINT, GRANT).
+var ddlPattern = regexp.MustCompile(
+	`(?is)^\s*(?:/\*.*?\*/\s*)*(?:(?:ALTER|CREATE|DROP|RENAME)\s.*\b(?:INDEX|TABLES?)\b|TRUNCATE\s)`)
+
+// A ddlStatement is a DDL statement that affects one or more tables.
+type ddlStatement struct {
+	kind   string           // E.g. "ALTER TABLE".
+	node   ast.DDLNode      // The parsed statement.
+	sql    string           // The original statement.
+	tables []*ast.TableName // Source tables, always schema-qualified.
+}
+
+// A schemaChange is retained in the connection's diagnostic output.
+type schemaChange struct {
+	Kind      string        `json:"kind"`
+	Outcome   string        `json:"outcome"`
+	Received  time.Time     `json:"received"`
+	Statement string        `json:"statement"`
+	Tables    []ident.Table `json:"tables"`
+	Target    []string      `json:"target,omitempty"` // Statements executed in the target.
+}
+
+// onDDL processes a QueryEvent which may contain DDL statements. Column
+// metadata for affected tables is discarded and then the configured
+// DDLPolicy is applied. This method returns true if the query contained
+// a DDL statement.
+func (c *conn) onDDL(ctx context.Context, e *replication.QueryEvent) (bool, error) {
+	query := string(e.Query)
+	if !ddlPattern.MatchString(query) {
+		return false, nil
+	}
+	stmts, err := parseDDL(string(e.Schema), query)
+	if err != nil {
+		if c.config.DDLPolicy == DDLPolicyIgnore {
+			log.WithError(err).Warn("ignoring unparseable DDL statement")
+			return true, nil
+		}
+		return false, err
+	}
+
+	for _, stmt := range stmts {
+		change := &schemaChange{
+			Kind:      stmt.kind,
+			Received:  time.Now().UTC(),
+			Statement: stmt.sql,
+			Tables:    c.targetTables(stmt),
+		}
+		c.forgetTables(change.Tables)
+		ddlCount.With(prometheus.Labels{"kind": stmt.kind}).Inc()
+
+		err := c.applyDDLPolicy(ctx, stmt, change)
+		if err != nil {
+			change.Outcome = err.Error()
+		}
+		c.recordSchemaChange(change)
+		if err != nil {
+			return false, err
+		}
+	}
+	return len(stmts) > 0, nil
+}
+
+// applyDDLPolicy executes the configured DDLPolicy. It will update the
+// schemaChange with the outcome.
+func (c *conn) applyDDLPolicy(ctx context.Context, stmt *ddlStatement, change *schemaChange) error {
+	switch c.config.DDLPolicy {
+	case DDLPolicyIgnore:
+		change.Outcome = "ignored"
+		log.WithFields(log.Fields{
+			"statement": stmt.sql,
+			"tables":    change.Tables,
+		}).Warn("ignoring DDL statement; the target schema may need to be updated")
+		return nil
+
+	case DDLPolicyPause:
+		return errors.Errorf("replication paused by DDL statement %q; "+
+			"update the target schema and restart with --ddlPolicy ignore", stmt.sql)
+
+	case DDLPolicyApply:
+		translated, err := translateDDL(c.targetDB.Product, c.target, stmt)
+		if err != nil {
+			return err
+		}
+		change.Target = translated
+
+	case DDLPolicyScript:
+		tables := make([]string, len(change.Tables))
+		for idx, tbl := range change.Tables {
+			tables[idx] = tbl.Raw()
+		}
+		translated, err := c.onSchemaChange(ctx, &script.SchemaChange{
+			Kind: stmt.kind,
+			Meta: map[string]any{
+				"flavor": c.flavor,
+			},
+			Schema:    c.target.Raw(),
+			Statement: stmt.sql,
+			Tables:    tables,
+		})
+		if err != nil {
+			return err
+		}
+		change.Target = translated
+
+	default:
+		return errors.Errorf("unimplemented: %s", c.config.DDLPolicy)
+	}
+
+	if err := c.execTarget(ctx, change.Target); err != nil {
+		return err
+	}
+	change.Outcome = "applied"
+	log.WithFields(log.Fields{
+		"source": stmt.sql,
+		"target": change.Target,
+	}).Info("applied DDL statement to target")
+	return nil
+}
+
+// execTarget executes the statements within a target transaction and
+// then refreshes the target's schema data.
+func (c *conn) execTarget(ctx context.Context, stmts []string) error {
+	if len(stmts) == 0 {
+		return nil
+	}
+	tx, err := c.targetDB.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer tx.Rollback()
+	for _, stmt := range stmts {
+		if _, err := tx.ExecContext(ctx, stmt); err != nil {
+			return errors.Wrap(err, stmt)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return errors.WithStack(err)
+	}
+	watcher, err := c.watchers.Get(c.target)
+	if err != nil {
+		return err
+	}
+	return watcher.Refresh(ctx, c.targetDB)
+}
+
+// forgetTables discards any cached metadata for the tables, so that it
+// will be reloaded from the next TableMapEvent.
+func (c *conn) forgetTables(tables []ident.Table) {
+	for _, tbl := range tables {
+		c.columns.Delete(tbl)
+		for id, known := range c.relations {
+			if ident.Equal(known, tbl) {
+				delete(c.relations, id)
+			}
+		}
+	}
+}
+
+// recordSchemaChange retains the change for diagnostic purposes.
+func (c *conn) recordSchemaChange(change *schemaChange) {
+	c.schemaChanges.Lock()
+	defer c.schemaChanges.Unlock()
+	c.schemaChanges.recent = append(c.schemaChanges.recent, change)
+	if over := len(c.schemaChanges.recent) - schemaChangeHistory; over > 0 {
+		c.schemaChanges.recent = c.schemaChanges.recent[over:]
+	}
+}
+
+// targetTables maps the source tables in the statement to tables in the
+// target schema.
+func (c *conn) targetTables(stmt *ddlStatement) []ident.Table {
+	ret := make([]ident.Table, len(stmt.tables))
+	for idx, tbl := range stmt.tables {
+		ret[idx] = ident.NewTable(c.target, ident.New(tbl.Name.O))
+	}
+	return ret
+}
+
+// parseDDL extracts the table-related DDL statements from the query.
+// Tables that are not schema-qualified are assumed to reside in the
+// default database. Statements which only affect system schemas or
+// temporary tables are ignored.
+func parseDDL(defaultDB, query string) ([]*ddlStatement, error) {
+	nodes, _, err := parser.New().Parse(query, "", "")
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not parse DDL statement %q", query)
+	}
+	var ret []*ddlStatement
+	for _, node := range nodes {
+		ddl, ok := node.(ast.DDLNode)
+		if !ok {
+			continue
+		}
+		stmt := &ddlStatement{
+			node: ddl,
+			sql:  strings.TrimSpace(node.Text()),
+		}
+		switch t := ddl.(type) {
+		case *ast.AlterTableStmt:
+			stmt.kind = "ALTER TABLE"
+			stmt.tables = []*ast.TableName{t.Table}
+			for _, spec := range t.Specs {
+				if spec.Tp == ast.AlterTableRenameTable {
+					stmt.tables = append(stmt.tables, spec.NewTable)
+				}
+			}
+		case *ast.CreateIndexStmt:
+			stmt.kind = "CREATE INDEX"
+			stmt.tables = []*ast.TableName{t.Table}
+		case *ast.CreateTableStmt:
+			if t.TemporaryKeyword != ast.TemporaryNone {
+				continue
+			}
+			stmt.kind = "CREATE TABLE"
+			stmt.tables = []*ast.TableName{t.Table}
+		case *ast.DropIndexStmt:
+			stmt.kind = "DROP INDEX"
+			stmt.tables = []*ast.TableName{t.Table}
+		case *ast.DropTableStmt:
+			if t.IsView || t.TemporaryKeyword != ast.TemporaryNone {
+				continue
+			}
+			stmt.kind = "DROP TABLE"
+			stmt.tables = t.Tables
+		case *ast.RenameTableStmt:
+			stmt.kind = "RENAME TABLE"
+			for _, pair := range t.TableToTables {
+				stmt.tables = append(stmt.tables, pair.OldTable, pair.NewTable)
+			}
+		case *ast.TruncateTableStmt:
+			stmt.kind = "TRUNCATE TABLE"
+			stmt.tables = []*ast.TableName{t.Table}
+		default:
+			// E.g. CREATE DATABASE.
+			continue
+		}
+
+		system := true
+		for _, tbl := range stmt.tables {
+			if tbl.Schema.O == "" {
+				tbl.Schema = model.NewCIStr(defaultDB)
+			}
+			system = system && systemSchemas[tbl.Schema.L]
+		}
+		if system {
+			continue
+		}
+		ret = append(ret, stmt)
+	}
+	return ret, nil
+}
+
+// translateDDL returns statements to execute in the target database
+// that are equivalent to the source DDL statement. MySQL-compatible
+// targets receive the original statement, with table names rewritten
+// into the target schema. A subset of DDL is supported for targets
+// that use PostgreSQL dialects.
+func translateDDL(product types.Product, target ident.Schema, stmt *ddlStatement) ([]string, error) {
+	switch product {
+	case types.ProductMariaDB, types.ProductMySQL:
+		return restoreDDL(target, stmt)
+	case types.ProductCockroachDB, types.ProductPostgreSQL:
+		return translatePostgresDDL(target, stmt)
+	default:
+		return nil, errors.Errorf("DDL translation is not supported for %s targets", product)
+	}
+}
+
+// schemaRewriter sets the schema of all table names in an AST.
+type schemaRewriter struct {
+	schema model.CIStr
+}
+
+// Enter implements [ast.Visitor].
+func (r *schemaRewriter) Enter(n ast.Node) (ast.Node, bool) {
+	if tbl, ok := n.(*ast.TableName); ok {
+		tbl.Schema = r.schema
+	}
+	return n, false
+}
+
+// Leave implements [ast.Visitor].
+func (r *schemaRewriter) Leave(n ast.Node) (ast.Node, bool) {
+	return n, true
+}
+
+// restoreDDL re-renders the statement for a MySQL-compatible target.
+func restoreDDL(target ident.Schema, stmt *ddlStatement) ([]string, error) {
+	db, _ := target.Split()
+	stmt.node.Accept(&schemaRewriter{schema: model.NewCIStr(db.Raw())})
+
+	var sb strings.Builder
+	flags := format.RestoreStringSingleQuotes |
+		format.RestoreKeyWordUppercase |
+		format.RestoreNameBackQuotes
+	if err := stmt.node.Restore(format.NewRestoreCtx(flags, &sb)); err != nil {
+		return nil, errors.Wrapf(err, "could not restore DDL statement %q", stmt.sql)
+	}
+	return []string{sb.String()}, nil
+}
+
+// translatePostgresDDL supports the creation, removal, and renaming of
+// tables and columns.
+func translatePostgresDDL(target ident.Schema, stmt *ddlStatement) ([]string, error) {
+	table := func(tbl *ast.TableName) ident.Table {
+		return ident.NewTable(target, ident.New(tbl.Name.O))
+	}
+	unsupported := func() error {
+		return errors.Errorf("DDL statement %q cannot be translated; "+
+			"consider using --ddlPolicy script", stmt.sql)
+	}
+
+	switch t := stmt.node.(type) {
+	case *ast.AlterTableStmt:
+		tbl := table(t.Table)
+		var ret []string
+		for _, spec := range t.Specs {
+			switch spec.Tp {
+			case ast.AlterTableAddColumns:
+				if len(spec.NewConstraints) > 0 {
+					return nil, unsupported()
+				}
+				for _, col := range spec.NewColumns {
+					def, err := postgresColumn(col)
+					if err != nil {
+						return nil, err
+					}
+					ifNotExists := ""
+					if spec.IfNotExists {
+						ifNotExists = "IF NOT EXISTS "
+					}
+					ret = append(ret, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s%s",
+						tbl, ifNotExists, def))
+				}
+			case ast.AlterTableDropColumn:
+				ifExists := ""
+				if spec.IfExists {
+					ifExists = "IF EXISTS "
+				}
+				ret = append(ret, fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s%s",
+					tbl, ifExists, ident.New(spec.OldColumnName.Name.O)))
+			case ast.AlterTableRenameColumn:
+				ret = append(ret, fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s",
+					tbl, ident.New(spec.OldColumnName.Name.O), ident.New(spec.NewColumnName.Name.O)))
+			case ast.AlterTableRenameTable:
+				ret = append(ret, fmt.Sprintf("ALTER TABLE %s RENAME TO %s",
+					tbl, ident.New(spec.NewTable.Name.O)))
+				tbl = table(spec.NewTable)
+			case ast.AlterTableAlgorithm, ast.AlterTableLock:
+				// Ignore MySQL execution hints.
+			default:
+				return nil, unsupported()
+			}
+		}
+		return ret, nil
+
+	case *ast.CreateTableStmt:
+		if t.ReferTable != nil || t.Select != nil {
+			return nil, unsupported()
+		}
+		var defs []string
+		var pks []string
+		for _, col := range t.Cols {
+			def, err := postgresColumn(col)
+			if err != nil {
+				return nil, err
+			}
+			defs = append(defs, def)
+		}
+		for _, cons := range t.Constraints {
+			switch cons.Tp {
+			case ast.ConstraintPrimaryKey:
+				for _, key := range cons.Keys {
+					if key.Column == nil {
+						return nil, unsupported()
+					}
+					pks = append(pks, ident.New(key.Column.Name.O).String())
+				}
+			case ast.ConstraintIndex, ast.ConstraintKey:
+				// Secondary indexes are left to the operator.
+			default:
+				return nil, unsupported()
+			}
+		}
+		if len(pks) > 0 {
+			defs = append(defs, fmt.Sprintf("PRIMARY KEY (%s)", strings.Join(pks, ", ")))
+		}
+		ifNotExists := ""
+		if t.IfNotExists {
+			ifNotExists = "IF NOT EXISTS "
+		}
+		return []string{fmt.Sprintf("CREATE TABLE %s%s (%s)",
+			ifNotExists, table(t.Table), strings.Join(defs, ", "))}, nil
+
+	case *ast.DropTableStmt:
+		names := make([]string, len(t.Tables))
+		for idx, tbl := range t.Tables {
+			names[idx] = table(tbl).String()
+		}
+		ifExists := ""
+		if t.IfExists {
+			ifExists = "IF EXISTS "
+		}
+		return []string{fmt.Sprintf("DROP TABLE %s%s", ifExists, strings.Join(names, ", "))}, nil
+
+	case *ast.RenameTableStmt:
+		ret := make([]string, len(t.TableToTables))
+		for idx, pair := range t.TableToTables {
+			ret[idx] = fmt.Sprintf("ALTER TABLE %s RENAME TO %s",
+				table(pair.OldTable), ident.New(pair.NewTable.Name.O))
+		}
+		return ret, nil
+
+	case *ast.TruncateTableStmt:
+		return []string{fmt.Sprintf("TRUNCATE TABLE %s", table(t.Table))}, nil
+
+	default:
+		return nil, unsupported()
+	}
+}
+
+// postgresColumn returns a column definition, including the column's
+// name, type, and nullability.
+func postgresColumn(col *ast.ColumnDef) (string, error) {
+	typ, err := postgresType(col.Tp)
+	if err != nil {
+		return "", errors.Wrap(err, col.Name.Name.O)
+	}
+	var sb strings.Builder
+	sb.WriteString(ident.New(col.Name.Name.O).String())
+	sb.WriteString(" ")
+	sb.WriteString(typ)
+	for _, opt := range col.Options {
+		switch opt.Tp {
+		case ast.ColumnOptionNotNull:
+			sb.WriteString(" NOT NULL")
+		case ast.ColumnOptionPrimaryKey:
+			sb.WriteString(" PRIMARY KEY")
+		}
+	}
+	return sb.String(), nil
+}
+
+// postgresType maps a MySQL column type to a PostgreSQL-compatible one.
+func postgresType(ft *parserTypes.FieldType) (string, error) {
+	binary := ft.GetCharset() == charset.CharsetBin
+	unsigned := mysql.HasUnsignedFlag(ft.GetFlag())
+	flen, decimal := ft.GetFlen(), ft.GetDecimal()
+
+	switch ft.GetType() {
+	case mysql.TypeTiny, mysql.TypeShort, mysql.TypeYear:
+		return "INT2", nil
+	case mysql.TypeInt24:
+		return "INT4", nil
+	case mysql.TypeLong:
+		if unsigned {
+			return "INT8", nil
+		}
+		return "INT4", nil
+	case mysql.TypeLonglong:
+		if unsigned {
+			return "DECIMAL(20)", nil
+		}
+		return "INT8", nil
+	case mysql.TypeFloat:
+		return "FLOAT4", nil
+	case mysql.TypeDouble:
+		return "FLOAT8", nil
+	case mysql.TypeNewDecimal:
+		if flen > 0 && decimal >= 0 {
+			return fmt.Sprintf("DECIMAL(%d,%d)", flen, decimal), nil
+		}
+		return "DECIMAL", nil
+	case mysql.TypeString:
+		if binary {
+			return "BYTEA", nil
+		}
+		if flen > 0 {
+			return fmt.Sprintf("CHAR(%d)", flen), nil
+		}
+		return "CHAR", nil
+	case mysql.TypeVarchar, mysql.TypeVarString:
+		if binary {
+			return "BYTEA", nil
+		}
+		if flen > 0 {
+			return fmt.Sprintf("VARCHAR(%d)", flen), nil
+		}
+		return "VARCHAR", nil
+	case mysql.TypeTinyBlob, mysql.TypeBlob, mysql.TypeMediumBlob, mysql.TypeLongBlob:
+		if binary {
+			return "BYTEA", nil
+		}
+		return "TEXT", nil
+	case mysql.TypeEnum, mysql.TypeSet:
+		return "TEXT", nil
+	case mysql.TypeDate:
+		return "DATE", nil
+	case mysql.TypeDatetime:
+		return "TIMESTAMP", nil
+	case mysql.TypeTimestamp:
+		return "TIMESTAMPTZ", nil
+	case mysql.TypeDuration:
+		return "TIME", nil
+	case mysql.TypeJSON:
+		return "JSONB", nil
+	case mysql.TypeBit:
+		if flen > 0 {
+			return fmt.Sprintf("VARBIT(%d)", flen), nil
+		}
+		return "VARBIT", nil
+	default:
+		return "", errors.Errorf("unsupported column type %s", ft.String())
+	}
+}
@@ -0,0 +1,225 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package mylogical
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDDLPattern(t *testing.T) {
+	tcs := []struct {
+		query string
+		match bool
+	}{
+		{"BEGIN", false},
+		{"COMMIT", false},
+		{"SAVEPOINT `sp`", false},
+		{"CREATE DATABASE foo", false},
+		{"GRANT SELECT ON foo.* TO 'bar'", false},
+		{"ALTER TABLE t ADD COLUMN c INT", true},
+		{"alter table t add column c int", true},
+		{"/* comment */ DROP TABLE `t` /* generated by server */", true},
+		{"CREATE UNIQUE INDEX idx ON t (c)", true},
+		{"RENAME TABLE a TO b", true},
+		{"TRUNCATE t", true},
+		{"TRUNCATE TABLE t", true},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.query, func(t *testing.T) {
+			require.Equal(t, tc.match, ddlPattern.MatchString(tc.query))
+		})
+	}
+}
+
+func TestParseDDL(t *testing.T) {
+	tcs := []struct {
+		query  string
+		kind   string
+		tables []string // db.table
+	}{
+		{
+			query:  "ALTER TABLE t ADD COLUMN c INT",
+			kind:   "ALTER TABLE",
+			tables: []string{"src.t"},
+		},
+		{
+			query:  "ALTER TABLE other.t RENAME TO other.u",
+			kind:   "ALTER TABLE",
+			tables: []string{"other.t", "other.u"},
+		},
+		{
+			query:  "CREATE TABLE t (pk INT PRIMARY KEY)",
+			kind:   "CREATE TABLE",
+			tables: []string{"src.t"},
+		},
+		{
+			query:  "DROP TABLE IF EXISTS `a`, `b` /* generated by server */",
+			kind:   "DROP TABLE",
+			tables: []string{"src.a", "src.b"},
+		},
+		{
+			query:  "RENAME TABLE a TO b",
+			kind:   "RENAME TABLE",
+			tables: []string{"src.a", "src.b"},
+		},
+		{
+			query:  "CREATE INDEX idx ON t (c)",
+			kind:   "CREATE INDEX",
+			tables: []string{"src.t"},
+		},
+		{query: "CREATE TEMPORARY TABLE t (pk INT)"},
+		{query: "DROP TEMPORARY TABLE IF EXISTS t"},
+		{query: "DROP VIEW v"},
+		{query: "ALTER TABLE mysql.user ADD COLUMN c INT"},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.query, func(t *testing.T) {
+			r := require.New(t)
+			stmts, err := parseDDL("src", tc.query)
+			r.NoError(err)
+			if tc.kind == "" {
+				r.Empty(stmts)
+				return
+			}
+			r.Len(stmts, 1)
+			r.Equal(tc.kind, stmts[0].kind)
+			r.Equal(tc.query, stmts[0].sql)
+			var tables []string
+			for _, tbl := range stmts[0].tables {
+				tables = append(tables, tbl.Schema.O+"."+tbl.Name.O)
+			}
+			r.Equal(tc.tables, tables)
+		})
+	}
+
+	_, err := parseDDL("src", "ALTER TABLE t FROBNICATE")
+	require.ErrorContains(t, err, "could not parse")
+}
+
+func TestTranslateDDL(t *testing.T) {
+	crdb := ident.MustSchema(ident.New("tgt"), ident.Public)
+	my := ident.MustSchema(ident.New("tgt"))
+
+	tcs := []struct {
+		product  types.Product
+		query    string
+		expected []string
+		err      string
+	}{
+		{
+			product: types.ProductCockroachDB,
+			query: "CREATE TABLE t (pk BIGINT NOT NULL, v VARCHAR(32), " +
+				"b VARBINARY(16), j JSON, PRIMARY KEY (pk), KEY idx (v))",
+			expected: []string{`CREATE TABLE "tgt"."public"."t" (` +
+				`"pk" INT8 NOT NULL, "v" VARCHAR(32), "b" BYTEA, "j" JSONB, PRIMARY KEY ("pk"))`},
+		},
+		{
+			product:  types.ProductPostgreSQL,
+			query:    "CREATE TABLE IF NOT EXISTS t (pk INT UNSIGNED PRIMARY KEY, d DECIMAL(10,2))",
+			expected: []string{`CREATE TABLE IF NOT EXISTS "tgt"."public"."t" ("pk" INT8 PRIMARY KEY, "d" DECIMAL(10,2))`},
+		},
+		{
+			product: types.ProductCockroachDB,
+			query:   "ALTER TABLE t ADD COLUMN c INT NOT NULL, DROP COLUMN d, RENAME COLUMN e TO f, ALGORITHM=INPLACE",
+			expected: []string{
+				`ALTER TABLE "tgt"."public"."t" ADD COLUMN "c" INT4 NOT NULL`,
+				`ALTER TABLE "tgt"."public"."t" DROP COLUMN "d"`,
+				`ALTER TABLE "tgt"."public"."t" RENAME COLUMN "e" TO "f"`,
+			},
+		},
+		{
+			product:  types.ProductCockroachDB,
+			query:    "RENAME TABLE a TO b",
+			expected: []string{`ALTER TABLE "tgt"."public"."a" RENAME TO "b"`},
+		},
+		{
+			product:  types.ProductCockroachDB,
+			query:    "DROP TABLE IF EXISTS a, b",
+			expected: []string{`DROP TABLE IF EXISTS "tgt"."public"."a", "tgt"."public"."b"`},
+		},
+		{
+			product: types.ProductCockroachDB,
+			query:   "ALTER TABLE t ADD INDEX idx (c)",
+			err:     "cannot be translated",
+		},
+		{
+			product: types.ProductCockroachDB,
+			query:   "ALTER TABLE t MODIFY COLUMN c BIGINT",
+			err:     "cannot be translated",
+		},
+		{
+			product:  types.ProductMySQL,
+			query:    "ALTER TABLE src.t ADD COLUMN c INT DEFAULT 42",
+			expected: []string{"ALTER TABLE `tgt`.`t` ADD COLUMN `c` INT DEFAULT 42"},
+		},
+		{
+			product:  types.ProductMariaDB,
+			query:    "CREATE INDEX idx ON t (c)",
+			expected: []string{"CREATE INDEX `idx` ON `tgt`.`t` (`c`)"},
+		},
+		{
+			product: types.ProductOracle,
+			query:   "TRUNCATE TABLE t",
+			err:     "not supported",
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.query, func(t *testing.T) {
+			r := require.New(t)
+			stmts, err := parseDDL("src", tc.query)
+			r.NoError(err)
+			r.Len(stmts, 1)
+
+			target := crdb
+			if tc.product == types.ProductMySQL || tc.product == types.ProductMariaDB {
+				target = my
+			}
+			translated, err := translateDDL(tc.product, target, stmts[0])
+			if tc.err != "" {
+				r.ErrorContains(err, tc.err)
+				return
+			}
+			r.NoError(err)
+			r.Equal(tc.expected, translated)
+		})
+	}
+}
+
+func TestDDLPolicyRedirected(t *testing.T) {
+	r := require.New(t)
+
+	cfg := &Config{}
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	cfg.Bind(flags)
+	r.NoError(flags.Parse([]string{
+		"--sourceConn", "mysql://root@localhost:3306/?sslmode=disable",
+		"--stagingConn", "postgresql://staging",
+		"--targetConn", "postgresql://target",
+		"--targetSchema", "db.public",
+		"--targetStorageURL", "file:///tmp/out",
+		"--ddlPolicy", "apply",
+	}))
+	r.ErrorContains(cfg.Preflight(), "--ddlPolicy apply cannot be used")
+
+	r.NoError(flags.Set("ddlPolicy", "ignore"))
+	r.NoError(cfg.Preflight())
+}
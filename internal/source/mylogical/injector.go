@@ -27,10 +27,12 @@ import (
 	"github.com/cockroachdb/replicator/internal/sequencer/chaos"
 	"github.com/cockroachdb/replicator/internal/sequencer/decorators"
 	"github.com/cockroachdb/replicator/internal/sequencer/immediate"
+	"github.com/cockroachdb/replicator/internal/sequencer/pii"
 	scriptSequencer "github.com/cockroachdb/replicator/internal/sequencer/script"
 	"github.com/cockroachdb/replicator/internal/sinkprod"
 	"github.com/cockroachdb/replicator/internal/staging"
 	"github.com/cockroachdb/replicator/internal/target"
+	"github.com/cockroachdb/replicator/internal/target/evolve"
 	"github.com/cockroachdb/replicator/internal/util/diag"
 	"github.com/google/wire"
 )
@@ -42,12 +44,14 @@ func Start(ctx *stopper.Context, config *Config) (*MYLogical, error) {
 		wire.Bind(new(context.Context), new(*stopper.Context)),
 		wire.Struct(new(MYLogical), "*"),
 		wire.FieldsOf(new(*Config), "Script"),
-		wire.FieldsOf(new(*EagerConfig), "DLQ", "Sequencer", "Staging", "Target"),
+		wire.FieldsOf(new(*EagerConfig), "DLQ", "Evolve", "Sequencer", "Staging", "Target"),
 		Set,
 		chaos.Set,
 		decorators.Set,
 		diag.New,
+		evolve.Set,
 		immediate.Set,
+		pii.Set,
 		scriptRuntime.Set,
 		scriptSequencer.Set,
 		sinkprod.Set,
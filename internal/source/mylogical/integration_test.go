@@ -32,6 +32,7 @@ import (
 	"github.com/cockroachdb/replicator/internal/sinktest/base"
 	"github.com/cockroachdb/replicator/internal/sinktest/scripttest"
 	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/cockroachdb/replicator/internal/util/snapshot"
 	"github.com/cockroachdb/replicator/internal/util/stamp"
 	"github.com/go-mysql-org/go-mysql/client"
 	"github.com/go-mysql-org/go-mysql/mysql"
@@ -627,3 +628,74 @@ func loadInitialGTIDSet(ctx context.Context, flavor string, myPool *client.Pool)
 	log.Infof("gtidSet: %s", gtidSet)
 	return gtidSet, nil
 }
+
+// TestSnapshot verifies that existing rows are copied by the initial
+// snapshot and that changes are streamed once the snapshot has
+// completed.
+func TestSnapshot(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+	fixture, err := all.NewFixture(t)
+	r.NoError(err)
+	ctx := fixture.Context
+	crdbPool := fixture.TargetPool
+
+	config, err := getConfig(fixture, &fixtureConfig{}, ident.Table{})
+	r.NoError(err)
+	sourceDB := fixture.SourceSchema.Idents(nil)[0]
+	config.Snapshot = snapshot.Config{
+		BatchSize:   100,
+		Enabled:     true,
+		Parallelism: 2,
+	}
+	config.SnapshotDatabase = sourceDB.Raw()
+	r.NoError(config.Preflight())
+
+	myPool, cancel, err := setupMYPool(config, sourceDB)
+	r.NoError(err)
+	defer cancel()
+
+	// Use a composite key whose order differs from the column order.
+	tgt := ident.NewTable(fixture.TargetSchema.Schema(), ident.New("snap"))
+	_, err = crdbPool.ExecContext(ctx, fmt.Sprintf(
+		"CREATE TABLE %s (v STRING, k1 INT, k2 STRING, PRIMARY KEY (k2, k1))", tgt))
+	r.NoError(err)
+	_, err = myExec(ctx, myPool,
+		"CREATE TABLE snap (v VARCHAR(20), k1 INT, k2 VARCHAR(10), PRIMARY KEY (k2, k1))")
+	r.NoError(err)
+
+	const rowCount = 1024
+	values := make([]string, rowCount)
+	for i := range values {
+		values[i] = fmt.Sprintf("('v%[1]d', %[1]d, 'k%[2]d')", i+1, (i+1)%7)
+	}
+	_, err = myExec(ctx, myPool, "INSERT INTO snap VALUES "+strings.Join(values, ", "))
+	r.NoError(err)
+
+	repl, err := Start(ctx, config)
+	r.NoError(err)
+
+	waitFor := func(predicate string, expected int) {
+		for {
+			count, err := base.GetRowCountWithPredicate(ctx, crdbPool, tgt, predicate)
+			r.NoError(err)
+			if count == expected {
+				return
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+	waitFor("true", rowCount)
+
+	// Changes are streamed after the snapshot.
+	_, err = myExec(ctx, myPool, "UPDATE snap SET v = 'updated' WHERE k1 <= 100")
+	r.NoError(err)
+	_, err = myExec(ctx, myPool, "INSERT INTO snap VALUES ('new', 0, 'k0')")
+	r.NoError(err)
+	waitFor("v = 'updated'", 100)
+	waitFor("true", rowCount+1)
+
+	sinktest.CheckDiagnostics(ctx, t, repl.Diagnostics)
+	ctx.Stop(time.Second)
+	a.NoError(ctx.Wait())
+}
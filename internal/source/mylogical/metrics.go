@@ -22,6 +22,13 @@ import (
 )
 
 var (
+	ddlCount = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "mylogical_ddl_total",
+			Help: "the number of DDL statements received, by kind",
+		},
+		[]string{"kind"},
+	)
 	dialFailureCount = promauto.NewCounter(prometheus.CounterOpts{
 		Name: "mylogical_dial_failure_total",
 		Help: "the number of times we failed to create a replication connection",
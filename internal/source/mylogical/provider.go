@@ -23,13 +23,15 @@ import (
 	"github.com/cockroachdb/replicator/internal/sequencer"
 	"github.com/cockroachdb/replicator/internal/sequencer/chaos"
 	"github.com/cockroachdb/replicator/internal/sequencer/immediate"
+	"github.com/cockroachdb/replicator/internal/sequencer/pii"
 	scriptSeq "github.com/cockroachdb/replicator/internal/sequencer/script"
-	"github.com/cockroachdb/replicator/internal/target/apply"
+	"github.com/cockroachdb/replicator/internal/target/evolve"
 	"github.com/cockroachdb/replicator/internal/types"
 	"github.com/cockroachdb/replicator/internal/util/hlc"
 	"github.com/cockroachdb/replicator/internal/util/ident"
 	"github.com/go-mysql-org/go-mysql/replication"
 	"github.com/google/wire"
+	"github.com/pkg/errors"
 )
 
 // Set is used by Wire.
@@ -43,11 +45,14 @@ var Set = wire.NewSet(
 // the script loader so that flags can be evaluated first.
 func ProvideConn(
 	ctx *stopper.Context,
-	acc *apply.Acceptor,
+	acc types.TableAcceptor,
 	chaos *chaos.Chaos,
 	config *Config,
+	evolver *evolve.Evolver,
 	imm *immediate.Immediate,
+	loader *script.Loader,
 	memo types.Memo,
+	pii *pii.PII,
 	scriptSeq *scriptSeq.Sequencer,
 	stagingPool *types.StagingPool,
 	targetPool *types.TargetPool,
@@ -72,7 +77,11 @@ func ProvideConn(
 		TLSConfig: config.tlsConfig,
 	}
 
-	seq, err := scriptSeq.Wrap(ctx, imm)
+	seq, err := pii.Wrap(ctx, imm) // No-op if no policies.
+	if err != nil {
+		return nil, err
+	}
+	seq, err = scriptSeq.Wrap(ctx, seq)
 	if err != nil {
 		return nil, err
 	}
@@ -80,31 +89,58 @@ func ProvideConn(
 	if err != nil {
 		return nil, err
 	}
+	group := &types.TableGroup{
+		Name:      ident.New(config.TargetSchema.Raw()),
+		Enclosing: config.TargetSchema,
+	}
 	connAcceptor, stat, err := seq.Start(ctx, &sequencer.StartOptions{
 		Delegate: types.OrderedAcceptorFrom(acc, watchers),
 		Bounds:   &notify.Var[hlc.Range]{}, // Not currently used.
-		Group: &types.TableGroup{
-			Name:      ident.New(config.TargetSchema.Raw()),
-			Enclosing: config.TargetSchema,
-		},
+		Group:    group,
 	})
 	if err != nil {
 		return nil, err
 	}
+	sequencer.NotifyResolved(ctx, group, stat, acc)
+
+	var onSchemaChange script.OnSchemaChange
+	switch config.DDLPolicy {
+	case DDLPolicyApply:
+		switch targetPool.Product {
+		case types.ProductCockroachDB, types.ProductMariaDB,
+			types.ProductMySQL, types.ProductPostgreSQL:
+		default:
+			return nil, errors.Errorf("--ddlPolicy %s is not supported for %s targets",
+				config.DDLPolicy, targetPool.Product)
+		}
+	case DDLPolicyScript:
+		scr, err := loader.Bind(ctx, config.TargetSchema, acc, watchers)
+		if err != nil {
+			return nil, err
+		}
+		if scr.OnSchemaChange == nil {
+			return nil, errors.Errorf("--ddlPolicy %s requires the userscript to call api.onSchemaChange()",
+				config.DDLPolicy)
+		}
+		onSchemaChange = scr.OnSchemaChange
+	}
 
 	ret := &conn{
-		acceptor:     connAcceptor,
-		columns:      &ident.TableMap[[]types.ColData]{},
-		config:       config,
-		memo:         memo,
-		flavor:       flavor,
-		relations:    make(map[uint64]ident.Table),
-		sourceConfig: cfg,
-		stagingDB:    stagingPool,
-		stat:         stat,
-		target:       config.TargetSchema,
-		targetDB:     targetPool,
-		walOffset:    notify.Var[*consistentPoint]{},
+		acceptor:       connAcceptor,
+		columns:        &ident.TableMap[[]types.ColData]{},
+		config:         config,
+		evolver:        evolver,
+		memo:           memo,
+		flavor:         flavor,
+		onSchemaChange: onSchemaChange,
+		relations:      make(map[uint64]ident.Table),
+		sourceConfig:   cfg,
+		stagingDB:      stagingPool,
+		stat:           stat,
+		target:         config.TargetSchema,
+		targetDB:       targetPool,
+		walOffset:      notify.Var[*consistentPoint]{},
+		watchers:       watchers,
 	}
 
 	return (*Conn)(ret), ret.Start(ctx)
@@ -0,0 +1,313 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package mylogical
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/replicator/internal/util/hlc"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/cockroachdb/replicator/internal/util/snapshot"
+	"github.com/go-mysql-org/go-mysql/client"
+	"github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// snapshot copies the tables in the configured database into the
+// target, if an initial snapshot was requested and replication has not
+// yet started. Streaming will resume from the GTID set that
+// corresponds to the snapshot.
+func (c *conn) snapshot(ctx context.Context) error {
+	if !c.config.Snapshot.Enabled {
+		return nil
+	}
+	key := fmt.Sprintf("mysql-snapshot-%s", c.target.Raw())
+	state, err := snapshot.Load(ctx, c.memo, c.stagingDB, key)
+	if err != nil {
+		return err
+	}
+	if state != nil && state.Done {
+		return c.startFromSnapshot(state)
+	}
+
+	var myReaders []*myReader
+	if state == nil {
+		if cp, _ := c.walOffset.Get(); !cp.IsZero() {
+			log.Info("replication has already started; skipping initial snapshot")
+			return nil
+		}
+		state = &snapshot.State{Time: hlc.From(time.Now())}
+		myReaders, err = c.lockedSnapshot(state)
+	} else {
+		// The original view is no longer available, so the remaining
+		// tables will be read from a newer view.
+		log.Info("resuming initial snapshot")
+		myReaders, err = c.snapshotReaders()
+	}
+	if err != nil {
+		return err
+	}
+	readers := make([]snapshot.Reader, len(myReaders))
+	for idx, r := range myReaders {
+		readers[idx] = r
+	}
+
+	tables, err := c.snapshotTables(myReaders[0].conn)
+	if err == nil {
+		err = snapshot.Store(ctx, c.memo, c.stagingDB, key, state)
+	}
+	if err != nil {
+		for _, r := range readers {
+			_ = r.Close()
+		}
+		return err
+	}
+	copier := &snapshot.Copier{
+		Acceptor:    c.acceptor,
+		Config:      &c.config.Snapshot,
+		Key:         key,
+		Memo:        c.memo,
+		Source:      "mylogical",
+		StagingPool: c.stagingDB,
+		TargetPool:  c.targetDB,
+	}
+	if err := copier.Run(ctx, state, tables, readers); err != nil {
+		return err
+	}
+	return c.startFromSnapshot(state)
+}
+
+// startFromSnapshot sets the GTID set to stream from, unless streaming
+// has already made progress.
+func (c *conn) startFromSnapshot(state *snapshot.State) error {
+	if cp, _ := c.walOffset.Get(); !cp.IsZero() {
+		return nil
+	}
+	cp, err := newConsistentPoint(c.flavor).parseFrom(state.Position)
+	if err != nil {
+		return err
+	}
+	log.Infof("streaming from snapshot GTID set %s", cp)
+	c.monotonic.External(cp.clone())
+	c.walOffset.Set(cp)
+	return nil
+}
+
+// lockedSnapshot briefly holds a global read lock while it opens the
+// readers and records the GTID set that corresponds to their view of
+// the source. This requires the RELOAD privilege.
+func (c *conn) lockedSnapshot(state *snapshot.State) ([]*myReader, error) {
+	lock, err := getConnection(c.config)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer lock.Close()
+	if _, err := lock.Execute("FLUSH TABLES WITH READ LOCK"); err != nil {
+		return nil, errors.Wrap(err, "could not acquire global read lock")
+	}
+	readers, err := c.snapshotReaders()
+	if err != nil {
+		return nil, err
+	}
+
+	q := "SELECT @@GLOBAL.gtid_executed"
+	if c.flavor == mysql.MariaDBFlavor {
+		q = "SELECT @@GLOBAL.gtid_binlog_pos"
+	}
+	res, err := lock.Execute(q)
+	if err == nil && len(res.Values) == 0 {
+		err = errors.New("unable to retrieve GTID set")
+	}
+	if err == nil {
+		_, err = lock.Execute("UNLOCK TABLES")
+	}
+	if err != nil {
+		for _, r := range readers {
+			_ = r.Close()
+		}
+		return nil, errors.WithStack(err)
+	}
+	// MySQL separates the sets of each source with a newline.
+	state.Position = strings.ReplaceAll(string(res.Values[0][0].AsString()), "\n", "")
+	log.Infof("taking initial snapshot at GTID set %s", state.Position)
+	return readers, nil
+}
+
+// snapshotReaders opens transactions against the source, which will
+// each have a consistent view of the source if a global read lock is
+// held.
+func (c *conn) snapshotReaders() ([]*myReader, error) {
+	ret := make([]*myReader, 0, c.config.Snapshot.Parallelism)
+	for i := 0; i < c.config.Snapshot.Parallelism; i++ {
+		r, err := c.openReader()
+		if err != nil {
+			for _, r := range ret {
+				_ = r.Close()
+			}
+			return nil, err
+		}
+		ret = append(ret, r)
+	}
+	return ret, nil
+}
+
+func (c *conn) openReader() (*myReader, error) {
+	cl, err := getConnection(c.config)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	for _, stmt := range []string{
+		"SET SESSION TRANSACTION ISOLATION LEVEL REPEATABLE READ",
+		"START TRANSACTION WITH CONSISTENT SNAPSHOT",
+	} {
+		if _, err := cl.Execute(stmt); err != nil {
+			_ = cl.Close()
+			return nil, errors.Wrapf(err, "could not open snapshot transaction")
+		}
+	}
+	return &myReader{conn: cl}, nil
+}
+
+// snapshotTables returns the base tables in the configured database.
+func (c *conn) snapshotTables(cl *client.Conn) ([]*snapshot.Table, error) {
+	db := c.config.SnapshotDatabase
+	res, err := cl.Execute(`
+		SELECT TABLE_NAME
+		FROM INFORMATION_SCHEMA.TABLES
+		WHERE TABLE_SCHEMA = ? AND TABLE_TYPE = 'BASE TABLE'
+		ORDER BY TABLE_NAME`, db)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	schema := ident.MustSchema(ident.New(db), ident.Public)
+	ret := make([]*snapshot.Table, 0, len(res.Values))
+	for _, row := range res.Values {
+		name := string(row[0].AsString())
+		table := &snapshot.Table{
+			Source: ident.NewTable(schema, ident.New(name)),
+			// Consistent with onRelation.
+			Target: ident.NewTable(c.target, ident.New(name)),
+		}
+		table.Columns, err = loadSnapshotColumns(cl, `
+			SELECT COLUMN_NAME
+			FROM INFORMATION_SCHEMA.COLUMNS
+			WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?
+			ORDER BY ORDINAL_POSITION`, db, name)
+		if err != nil {
+			return nil, err
+		}
+		table.Key, err = loadSnapshotColumns(cl, `
+			SELECT COLUMN_NAME
+			FROM INFORMATION_SCHEMA.KEY_COLUMN_USAGE
+			WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? AND CONSTRAINT_NAME = 'PRIMARY'
+			ORDER BY ORDINAL_POSITION`, db, name)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, table)
+	}
+	return ret, nil
+}
+
+// loadSnapshotColumns returns the column names from the query.
+func loadSnapshotColumns(cl *client.Conn, q string, db, table string) ([]ident.Ident, error) {
+	res, err := cl.Execute(q, db, table)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not load columns of %s.%s", db, table)
+	}
+	ret := make([]ident.Ident, len(res.Values))
+	for idx, row := range res.Values {
+		ret[idx] = ident.New(string(row[0].AsString()))
+	}
+	return ret, nil
+}
+
+// myReader reads from a transaction with a consistent snapshot.
+// Values are converted in the same manner as values received from the
+// replication stream.
+type myReader struct {
+	conn *client.Conn
+}
+
+var _ snapshot.Reader = (*myReader)(nil)
+
+// Close implements [snapshot.Reader].
+func (r *myReader) Close() error {
+	return errors.WithStack(r.conn.Close())
+}
+
+// Read implements [snapshot.Reader].
+func (r *myReader) Read(
+	_ context.Context, table *snapshot.Table, after []any, limit int,
+) ([][]any, error) {
+	keys := make([]string, len(table.Key))
+	for idx, col := range table.Key {
+		keys[idx] = quoteMySQL(col)
+	}
+	cols := make([]string, len(table.Columns))
+	for idx, col := range table.Columns {
+		cols[idx] = quoteMySQL(col)
+	}
+	db, _ := table.Source.Schema().Split()
+
+	var q strings.Builder
+	fmt.Fprintf(&q, "SELECT %s FROM %s.%s", strings.Join(cols, ", "),
+		quoteMySQL(db), quoteMySQL(table.Source.Table()))
+	if after != nil {
+		fmt.Fprintf(&q, " WHERE (%s) > (%s)", strings.Join(keys, ", "),
+			strings.TrimSuffix(strings.Repeat("?, ", len(keys)), ", "))
+	}
+	fmt.Fprintf(&q, " ORDER BY %s LIMIT %d", strings.Join(keys, ", "), limit)
+
+	res, err := r.conn.Execute(q.String(), after...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	ret := make([][]any, len(res.Values))
+	for rowIdx, values := range res.Values {
+		row := make([]any, len(values))
+		for idx, value := range values {
+			switch v := value.Value().(type) {
+			case []byte:
+				if res.Fields[idx].Type == mysql.MYSQL_TYPE_BIT {
+					// Consistent with onDataTuple.
+					var bits uint64
+					for _, b := range v {
+						bits = bits<<8 | uint64(b)
+					}
+					row[idx] = strconv.FormatUint(bits, 2)
+				} else {
+					row[idx] = string(v)
+				}
+			default:
+				row[idx] = v
+			}
+		}
+		ret[rowIdx] = row
+	}
+	return ret, nil
+}
+
+// quoteMySQL quotes an identifier for use in a MySQL query.
+func quoteMySQL(id ident.Ident) string {
+	return "`" + strings.ReplaceAll(id.Raw(), "`", "``") + "`"
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/cockroachdb/replicator/internal/sequencer/chaos"
 	"github.com/cockroachdb/replicator/internal/sequencer/decorators"
 	"github.com/cockroachdb/replicator/internal/sequencer/immediate"
+	"github.com/cockroachdb/replicator/internal/sequencer/pii"
 	script2 "github.com/cockroachdb/replicator/internal/sequencer/script"
 	"github.com/cockroachdb/replicator/internal/sinkprod"
 	"github.com/cockroachdb/replicator/internal/staging/memo"
@@ -19,12 +20,17 @@ import (
 	"github.com/cockroachdb/replicator/internal/staging/version"
 	"github.com/cockroachdb/replicator/internal/target/apply"
 	"github.com/cockroachdb/replicator/internal/target/dlq"
+	"github.com/cockroachdb/replicator/internal/target/evolve"
 	"github.com/cockroachdb/replicator/internal/target/load"
 	"github.com/cockroachdb/replicator/internal/target/schemawatch"
 	"github.com/cockroachdb/replicator/internal/util/applycfg"
 	"github.com/cockroachdb/replicator/internal/util/diag"
 )
 
+import (
+	_ "github.com/pingcap/tidb/pkg/parser/test_driver"
+)
+
 // Injectors from injector.go:
 
 // Start creates a MySQL/MariaDB logical replication loop using the
@@ -50,15 +56,19 @@ func Start(ctx *stopper.Context, config *Config) (*MYLogical, error) {
 	if err != nil {
 		return nil, err
 	}
+	store, err := sinkprod.ProvideStagingLocal(ctx, stagingConfig)
+	if err != nil {
+		return nil, err
+	}
 	stagingSchema, err := sinkprod.ProvideStagingDB(ctx, stagingConfig, stagingPool)
 	if err != nil {
 		return nil, err
 	}
-	memoMemo, err := memo.ProvideMemo(ctx, stagingPool, stagingSchema)
+	typesMemo, err := memo.ProvideMemo(ctx, stagingPool, store, stagingSchema)
 	if err != nil {
 		return nil, err
 	}
-	checker := version.ProvideChecker(stagingPool, memoMemo)
+	checker := version.ProvideChecker(stagingPool, typesMemo)
 	targetPool, err := sinkprod.ProvideTargetPool(ctx, checker, targetConfig, diagnostics)
 	if err != nil {
 		return nil, err
@@ -68,7 +78,7 @@ func Start(ctx *stopper.Context, config *Config) (*MYLogical, error) {
 		return nil, err
 	}
 	dlqConfig := &eagerConfig.DLQ
-	backup := schemawatch.ProvideBackup(memoMemo, stagingPool)
+	backup := schemawatch.ProvideBackup(typesMemo, stagingPool)
 	watchers, err := schemawatch.ProvideFactory(ctx, targetPool, diagnostics, backup)
 	if err != nil {
 		return nil, err
@@ -82,17 +92,34 @@ func Start(ctx *stopper.Context, config *Config) (*MYLogical, error) {
 	if err != nil {
 		return nil, err
 	}
+	tableAcceptor, err := sinkprod.ProvideTableAcceptor(ctx, acceptor, targetConfig)
+	if err != nil {
+		return nil, err
+	}
 	sequencerConfig := &eagerConfig.Sequencer
 	chaosChaos := &chaos.Chaos{
 		Config: sequencerConfig,
 	}
-	stagers := stage.ProvideFactory(stagingPool, stagingSchema, ctx)
+	evolveConfig := &eagerConfig.Evolve
+	evolver, err := evolve.ProvideEvolver(evolveConfig, typesMemo, stagingPool, targetPool, watchers)
+	if err != nil {
+		return nil, err
+	}
+	stagers := stage.ProvideFactory(stagingPool, store, stagingSchema, ctx)
 	marker := decorators.ProvideMarker(stagingPool, stagers)
 	once := decorators.ProvideOnce(stagingPool, stagers)
 	retryTarget := decorators.ProvideRetryTarget(targetPool)
 	immediateImmediate := immediate.ProvideImmediate(sequencerConfig, targetPool, marker, once, retryTarget, stagers)
+	keys, err := pii.ProvideKeys(sequencerConfig)
+	if err != nil {
+		return nil, err
+	}
+	piiPII, err := pii.ProvideShim(ctx, sequencerConfig, diagnostics, keys, watchers)
+	if err != nil {
+		return nil, err
+	}
 	sequencer := script2.ProvideSequencer(loader, targetPool, watchers)
-	mylogicalConn, err := ProvideConn(ctx, acceptor, chaosChaos, config, immediateImmediate, memoMemo, sequencer, stagingPool, targetPool, watchers)
+	mylogicalConn, err := ProvideConn(ctx, tableAcceptor, chaosChaos, config, evolver, immediateImmediate, loader, typesMemo, piiPII, sequencer, stagingPool, targetPool, watchers)
 	if err != nil {
 		return nil, err
 	}
@@ -19,6 +19,7 @@
 package bucket
 
 import (
+	"context"
 	"io"
 
 	"github.com/cockroachdb/field-eng-powertools/stopper"
@@ -56,3 +57,18 @@ type Bucket interface {
 	// order.
 	Walk(ctx *stopper.Context, prefix string, options *WalkOptions, f func(*stopper.Context, string) error) error
 }
+
+// Writer provides write access to an object storage bucket.
+type Writer interface {
+	// Put stores the content at the named path, replacing any existing
+	// object. The object must not be visible to readers until all of
+	// its content has been written.
+	Put(ctx context.Context, path string, content []byte) error
+}
+
+// ReadWriter provides read and write access to an object storage
+// bucket.
+type ReadWriter interface {
+	Bucket
+	Writer
+}
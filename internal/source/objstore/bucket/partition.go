@@ -0,0 +1,95 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package bucket
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+)
+
+// PartitionFormat identifies how files are organized within
+// a bucket: daily, hourly or flat formats.
+type PartitionFormat int
+
+//go:generate go run golang.org/x/tools/cmd/stringer -type=PartitionFormat
+
+const (
+	// Daily is the default option:
+	// /[date]/[timestamp]-[uniquer]-[topic]-[schema-id]
+	Daily PartitionFormat = iota
+	// Flat result in no file partitioning:
+	// /[timestamp]-[uniquer]-[topic]-[schema-id]
+	Flat
+	// Hourly will partition into an hourly directory:
+	// /[date]/[hour]/[timestamp]-[uniquer]-[topic]-[schema-id]
+	Hourly
+)
+
+// PartitionFormats returns the available partition formats.
+func PartitionFormats() []string {
+	var res []string
+	for i := Daily; i <= Hourly; i++ {
+		res = append(res, i.String())
+	}
+	return res
+}
+
+var _ pflag.Value = new(PartitionFormat)
+
+// Dir returns the folder, relative to the root of the changefeed
+// output, that contains files with the given timestamp. The result is
+// empty for the Flat format.
+func (p PartitionFormat) Dir(timestamp time.Time) (string, error) {
+	switch p {
+	case Daily:
+		return timestamp.Format("2006-01-02"), nil
+	case Hourly:
+		return path.Join(timestamp.Format("2006-01-02"), timestamp.Format("15")), nil
+	case Flat:
+		return "", nil
+	default:
+		return "", errors.Errorf("invalid partition format %s", p)
+	}
+}
+
+// Set implements pflag.Value
+func (p *PartitionFormat) Set(value string) error {
+	switch strings.ToLower(value) {
+	case "":
+		// default partition format, when nothing is specified.
+		*p = PartitionFormat(Daily)
+	case "daily":
+		*p = PartitionFormat(Daily)
+	case "flat":
+		*p = PartitionFormat(Flat)
+	case "hourly":
+		*p = PartitionFormat(Hourly)
+	default:
+		return errors.Errorf("invalid partition format %q", value)
+	}
+	return nil
+}
+
+// Type implements pflag.Value
+func (p PartitionFormat) Type() string {
+	return fmt.Sprintf("%T", p)
+}
@@ -1,6 +1,6 @@
 // Code generated by "stringer -type=PartitionFormat"; DO NOT EDIT.
 
-package objstore
+package bucket
 
 import "strconv"
 
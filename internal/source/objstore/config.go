@@ -19,6 +19,7 @@ package objstore
 import (
 	"bufio"
 	"context"
+	"encoding/base64"
 	"fmt"
 	"io/fs"
 	"math"
@@ -32,6 +33,10 @@ import (
 	"github.com/cockroachdb/replicator/internal/script"
 	"github.com/cockroachdb/replicator/internal/sequencer"
 	"github.com/cockroachdb/replicator/internal/sinkprod"
+	"github.com/cockroachdb/replicator/internal/source/objstore/bucket"
+	"github.com/cockroachdb/replicator/internal/source/objstore/notification"
+	"github.com/cockroachdb/replicator/internal/source/objstore/providers/azure"
+	"github.com/cockroachdb/replicator/internal/source/objstore/providers/gcs"
 	"github.com/cockroachdb/replicator/internal/source/objstore/providers/s3"
 	"github.com/cockroachdb/replicator/internal/target/dlq"
 	"github.com/cockroachdb/replicator/internal/util/hlc"
@@ -47,6 +52,7 @@ var (
 	defaultBufferSize           = bufio.MaxScanTokenSize // 64K
 	defaultFetchDelay           = 100 * time.Millisecond
 	defaultNumberOfWorkers      = runtime.GOMAXPROCS(0)
+	defaultReconcileInterval    = time.Minute
 	defaultRetryInitialInterval = 10 * time.Millisecond
 	defaultRetryMaxTime         = 10 * time.Second
 )
@@ -58,54 +64,19 @@ type EagerConfig Config
 
 // PartitionFormat identifies how files are organized within
 // a bucket: daily, hourly or flat formats.
-type PartitionFormat int
-
-//go:generate go run golang.org/x/tools/cmd/stringer -type=PartitionFormat
+type PartitionFormat = bucket.PartitionFormat
 
+// The partition formats are defined in the bucket package, so that
+// they may be shared with the object-store target.
 const (
-	// Daily is the default option:
-	// /[date]/[timestamp]-[uniquer]-[topic]-[schema-id]
-	Daily PartitionFormat = iota
-	// Flat result in no file partitioning:
-	// /[timestamp]-[uniquer]-[topic]-[schema-id]
-	Flat
-	// Hourly will partition into an hourly directory:
-	// /[date]/[hour]/[timestamp]-[uniquer]-[topic]-[schema-id]
-	Hourly
+	Daily  = bucket.Daily
+	Flat   = bucket.Flat
+	Hourly = bucket.Hourly
 )
 
 // PartitionFormats returns the available partition formats.
 func PartitionFormats() []string {
-	var res []string
-	for i := Daily; i <= Hourly; i++ {
-		res = append(res, i.String())
-	}
-	return res
-}
-
-var _ pflag.Value = new(PartitionFormat)
-
-// Set implements pflag.Value
-func (p *PartitionFormat) Set(value string) error {
-	switch strings.ToLower(value) {
-	case "":
-		// default partition format, when nothing is specified.
-		*p = PartitionFormat(Daily)
-	case "daily":
-		*p = PartitionFormat(Daily)
-	case "flat":
-		*p = PartitionFormat(Flat)
-	case "hourly":
-		*p = PartitionFormat(Hourly)
-	default:
-		return errors.Errorf("invalid partition format %q", value)
-	}
-	return nil
-}
-
-// Type implements pflag.Value
-func (p PartitionFormat) Type() string {
-	return fmt.Sprintf("%T", p)
+	return bucket.PartitionFormats()
 }
 
 // Provider identifies the type of providers.
@@ -120,12 +91,20 @@ const (
 	LocalStorage
 	// S3Storage identifies a object stored backed by AWS S3.
 	S3Storage
+	// GCSStorage identifies a object stored backed by Google Cloud Storage.
+	GCSStorage
+	// AzureStorage identifies a object stored backed by Azure Blob Storage.
+	AzureStorage
 )
 
 // Providers maps a URL scheme to a Provider.
 var Providers = map[string]Provider{
-	"file": LocalStorage,
-	"s3":   S3Storage,
+	"azure":         AzureStorage,
+	"azure-blob":    AzureStorage,
+	"azure-storage": AzureStorage,
+	"file":          LocalStorage,
+	"gs":            GCSStorage,
+	"s3":            S3Storage,
 }
 
 // Config contains the configuration necessary for creating a
@@ -144,7 +123,9 @@ type Config struct {
 	FetchDelay           time.Duration
 	MaxTimestamp         hlc.Time
 	MinTimestamp         hlc.Time
+	NotificationURL      string
 	PartitionFormat      PartitionFormat
+	ReconcileInterval    time.Duration
 	RetryInitialInterval time.Duration
 	RetryMaxTime         time.Duration
 	StorageURL           string
@@ -152,12 +133,15 @@ type Config struct {
 	Workers              int
 
 	// The following are computed
-	bucketName string
-	identifier string // used for leasing and state.
-	local      fs.FS
-	prefix     string
-	s3         *s3.Config
-	timeRange  hlc.Range // Timestamp range, computed based on minTimestamp and maxTimestamp.
+	azure        *azure.Config
+	bucketName   string
+	gcs          *gcs.Config
+	identifier   string // used for leasing and state.
+	local        fs.FS
+	notification *notification.Config
+	prefix       string
+	s3           *s3.Config
+	timeRange    hlc.Range // Timestamp range, computed based on minTimestamp and maxTimestamp.
 }
 
 // Bind adds flags to the set. It delegates to the embedded Config.Bind.
@@ -182,14 +166,23 @@ cluster_logical_timestamp().`)
 		`"only accept unprocessed messages at or newer than this timestamp; this is an inclusive lower limit.
 The timestamp must be provided in the HLC timestamp format, as returned by
 cluster_logical_timestamp().`)
+	f.StringVar(&c.NotificationURL, "notificationURL", "",
+		`receive object-created notifications to discover new files, rather than
+periodically listing the bucket. The notifications must be in the S3 event
+format and may be delivered by an SQS queue or a Kafka topic:
+  sqs://sqs.us-east-1.amazonaws.com/123456789012/queue
+  kafka://broker1:9092,broker2:9092/topic?group=replicator`)
 	f.Var(&c.PartitionFormat, "partitionFormat",
 		fmt.Sprintf("how changefeed file paths are partitioned: %s",
 			strings.Join(PartitionFormats(), ", ")))
+	f.DurationVar(&c.ReconcileInterval, "reconcileInterval", defaultReconcileInterval,
+		"time to wait between listing the bucket, if notifications are enabled")
 	f.DurationVar(&c.RetryInitialInterval, "retryInitial", defaultRetryInitialInterval,
 		"initial time to wait before retrying an operation that failed because of a transient error")
 	f.DurationVar(&c.RetryMaxTime, "retryMax", defaultRetryMaxTime,
 		"maximum time allowed for retrying an operation that failed because of a transient error")
-	f.StringVar(&c.StorageURL, "storageURL", "", "the URL to access the storage")
+	f.StringVar(&c.StorageURL, "storageURL", "",
+		"the URL to access the storage; one of file://, s3://, gs:// or azure://")
 	f.Var(ident.NewSchemaFlag(&c.TargetSchema), "targetSchema",
 		"the SQL database schema in the target cluster to update")
 	f.IntVar(&c.Workers, "workers", defaultNumberOfWorkers,
@@ -243,6 +236,15 @@ func (c *Config) preflight() error {
 		return errors.New("minTimestamp must be before maxTimestamp")
 	}
 	c.timeRange = hlc.RangeExcluding(c.MinTimestamp, maxTimestamp)
+	c.notification = nil
+	if c.NotificationURL != "" {
+		if c.notification, err = notification.ParseURL(c.NotificationURL); err != nil {
+			return err
+		}
+		if c.ReconcileInterval <= 0 {
+			return errors.New("reconcileInterval must be positive")
+		}
+	}
 	switch Providers[u.Scheme] {
 	case LocalStorage:
 		c.local = os.DirFS(u.Path)
@@ -279,6 +281,59 @@ func (c *Config) preflight() error {
 			SecretKey:    paramValue(params, "AWS_SECRET_ACCESS_KEY"),
 			SessionToken: paramValue(params, "AWS_SESSION_TOKEN"),
 		}
+	case GCSStorage:
+		if u.Host == "" {
+			return errors.New("missing bucket name in URL. Must be gs://bucket/folder")
+		}
+		c.bucketName = u.Host
+		c.prefix = strings.TrimPrefix(u.Path, "/")
+		c.identifier = fmt.Sprintf("objstore:%s//%s/%s", u.Scheme, u.Host, u.Path)
+		params := u.Query()
+		var credentials []byte
+		// Use the application default credentials, unless a key is provided.
+		switch auth := paramValue(params, "AUTH"); auth {
+		case "", "specified":
+			if encoded := paramValue(params, "CREDENTIALS"); encoded != "" {
+				if credentials, err = base64.StdEncoding.DecodeString(encoded); err != nil {
+					return errors.Wrap(err, "CREDENTIALS must be base64-encoded")
+				}
+			} else if auth == "specified" {
+				return errors.New("AUTH=specified requires CREDENTIALS")
+			}
+		case "implicit":
+		default:
+			return errors.Errorf("unsupported AUTH=%s; must be specified or implicit", auth)
+		}
+		if c.Conveyor.Immediate {
+			c.Workers = 1
+		}
+		c.gcs = &gcs.Config{
+			Bucket:      c.bucketName,
+			Credentials: credentials,
+			Endpoint:    paramValue(params, "GCS_ENDPOINT"),
+		}
+	case AzureStorage:
+		if u.Host == "" {
+			return errors.New("missing container name in URL. Must be azure://container/folder")
+		}
+		c.bucketName = u.Host
+		c.prefix = strings.TrimPrefix(u.Path, "/")
+		c.identifier = fmt.Sprintf("objstore:%s//%s/%s", u.Scheme, u.Host, u.Path)
+		params := u.Query()
+		account := paramValue(params, "AZURE_ACCOUNT_NAME")
+		endpoint := paramValue(params, "AZURE_ENDPOINT")
+		if account == "" && endpoint == "" {
+			return errors.New("AZURE_ACCOUNT_NAME must be specified")
+		}
+		if c.Conveyor.Immediate {
+			c.Workers = 1
+		}
+		c.azure = &azure.Config{
+			AccountKey:  paramValue(params, "AZURE_ACCOUNT_KEY"),
+			AccountName: account,
+			Container:   c.bucketName,
+			Endpoint:    endpoint,
+		}
 	default:
 		return errors.Errorf("unknown scheme %s", u.Scheme)
 	}
@@ -0,0 +1,96 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package objstore
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/replicator/internal/source/objstore/providers/azure"
+	"github.com/cockroachdb/replicator/internal/source/objstore/providers/gcs"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPreflightProviders verifies that the storage URL is parsed into
+// the provider-specific configuration.
+func TestPreflightProviders(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		gcs     *gcs.Config
+		azure   *azure.Config
+		prefix  string
+		wantErr string
+	}{
+		{
+			name: "gcs",
+			url:  "gs://bucket/folder?AUTH=specified&CREDENTIALS=e30=",
+			gcs: &gcs.Config{
+				Bucket:      "bucket",
+				Credentials: []byte("{}"),
+			},
+			prefix: "folder",
+		},
+		{
+			name: "gcs emulator",
+			url:  "gs://bucket?GCS_ENDPOINT=http://localhost:4443/storage/v1/",
+			gcs: &gcs.Config{
+				Bucket:   "bucket",
+				Endpoint: "http://localhost:4443/storage/v1/",
+			},
+		},
+		{
+			name:    "gcs missing credentials",
+			url:     "gs://bucket/folder?AUTH=specified",
+			wantErr: "AUTH=specified requires CREDENTIALS",
+		},
+		{
+			name:    "gcs missing bucket",
+			url:     "gs:///folder",
+			wantErr: "missing bucket name",
+		},
+		{
+			name: "azure",
+			url:  "azure-blob://container/a/b?AZURE_ACCOUNT_NAME=acct&AZURE_ACCOUNT_KEY=key",
+			azure: &azure.Config{
+				AccountKey:  "key",
+				AccountName: "acct",
+				Container:   "container",
+			},
+			prefix: "a/b",
+		},
+		{
+			name:    "azure missing account",
+			url:     "azure://container/a/b",
+			wantErr: "AZURE_ACCOUNT_NAME must be specified",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+			config := &Config{StorageURL: tt.url}
+			err := config.preflight()
+			if tt.wantErr != "" {
+				r.ErrorContains(err, tt.wantErr)
+				return
+			}
+			r.NoError(err)
+			r.Equal(tt.gcs, config.gcs)
+			r.Equal(tt.azure, config.azure)
+			r.Equal(tt.prefix, config.prefix)
+		})
+	}
+}
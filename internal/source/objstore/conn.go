@@ -28,6 +28,7 @@ import (
 	"github.com/cockroachdb/field-eng-powertools/stopper"
 	"github.com/cockroachdb/replicator/internal/source/objstore/bucket"
 	"github.com/cockroachdb/replicator/internal/source/objstore/eventproc"
+	"github.com/cockroachdb/replicator/internal/source/objstore/notification"
 	"github.com/cockroachdb/replicator/internal/types"
 	"github.com/cockroachdb/replicator/internal/util/cdcjson"
 	"github.com/cockroachdb/replicator/internal/util/hlc"
@@ -49,8 +50,12 @@ type Conn struct {
 	config *Config
 	// Delivers mutation to the target database.
 	conveyor Conveyor
+	// Tracks objects reported by notifications; may be nil.
+	discovery *discovery
 	// Ensures that only one replicator instances acts on file.
 	leases types.Leases
+	// Object-created notifications; may be nil.
+	notifications notification.Source
 	// Parser for ndjson files.
 	parser *cdcjson.NDJsonParser
 	// event processor
@@ -119,6 +124,16 @@ func (c *Conn) apply(ctx *stopper.Context, dir string) error {
 	}
 	resolvedRanges := make(chan (*resolvedRange))
 
+	// Start a go routine to receive notifications about new files.
+	if c.notifications != nil {
+		ctx.Go(func(ctx *stopper.Context) error {
+			return c.notifications.Run(ctx, func(_ context.Context, events []notification.Event) error {
+				c.discovery.add(events)
+				return nil
+			})
+		})
+	}
+
 	// Start a go routine to find resolved timestamps
 	ctx.Go(func(ctx *stopper.Context) error {
 		defer close(resolvedRanges)
@@ -266,23 +281,12 @@ func (c *Conn) getResolvedTimestamp(ctx *stopper.Context, file string) (hlc.Time
 // filePrefix returns a path within the connection's bucket based on the configured
 // partition file format.
 func (c *Conn) filePrefix(dir string, timestamp time.Time) (string, error) {
-	switch c.config.PartitionFormat {
-	case Daily:
-		return path.Join(c.config.bucketName, dir,
-			timestamp.Format("2006-01-02"),
-			timestamp.Format("20060102150405")), nil
-	case Hourly:
-		return path.Join(c.config.bucketName, dir,
-			timestamp.Format("2006-01-02"),
-			timestamp.Format("15"),
-			timestamp.Format("20060102150405")), nil
-	case Flat:
-		return path.Join(c.config.bucketName, dir,
-			timestamp.Format("20060102150405")), nil
-	default:
-		return "",
-			errors.Errorf("invalid partition format %s", c.config.PartitionFormat)
+	partition, err := c.config.PartitionFormat.Dir(timestamp)
+	if err != nil {
+		return "", err
 	}
+	return path.Join(c.config.bucketName, dir, partition,
+		timestamp.Format("20060102150405")), nil
 }
 
 // findResolved discovers ranges of files between two consecutive resolved timestamps.
@@ -292,65 +296,20 @@ func (c *Conn) findResolved(
 ) (*resolvedRange, error) {
 	log.WithField("bucket", c.config.bucketName).
 		Tracef("findResolved %s", lowerBound)
+	if c.discovery != nil {
+		return c.awaitResolved(ctx, dir, lowerBound)
+	}
 	ticker := time.NewTicker(c.config.FetchDelay)
-	var upperBound string
-	// Number of entries between two resolved timestamps.
-	count := 0
+	defer ticker.Stop()
 	for {
-		start := time.Now()
-		options := &bucket.WalkOptions{
-			StartAfter: lowerBound,
-			Limit:      bucket.NoLimit,
-			Recursive:  true,
-		}
-		operation := func() error {
-			return c.bucket.Walk(ctx, dir, options,
-				func(ctx *stopper.Context, file string) error {
-					bucketScanCount.WithLabelValues(c.config.bucketName).Inc()
-					log.WithField("bucket", c.config.bucketName).
-						Tracef("processing %s", file)
-					file = path.Join(c.config.bucketName, file)
-					if strings.HasSuffix(file, resolvedSuffix) {
-						batchSize.WithLabelValues(c.config.bucketName).Observe(float64(count))
-						if count > 0 {
-							// We found a range with mutations, we will stop
-							// the walk
-							upperBound = file
-							return bucket.ErrSkipAll
-						}
-						log.WithField("bucket", c.config.bucketName).
-							Debugf("no transactions between %s and %s", lowerBound, file)
-						// If we get here, there is nothing interesting in
-						// between resolved timestamps.
-						// Resetting the lower bound of the range we need to process.
-						lowerBound = file
-
-						// TODO (silvano): we might be able to persist the
-						// last resolved timestamp to memo, however we have
-						// to make sure that all the concurrent processors
-						// are done. For busy systems this shouldn't matter,
-						// as it is unlikely that we don't have transactions
-						// between two resolved timestamps.
-
-						return nil
-					}
-					count++
-					if ctx.IsStopping() {
-						return bucket.ErrSkipAll
-					}
-					return nil
-				})
-		}
-		err := c.retry(operation, "find resolved")
+		var found *resolvedRange
+		var err error
+		found, lowerBound, err = c.scanResolved(ctx, dir, lowerBound)
 		if err != nil {
-			log.WithField("bucket", c.config.bucketName).WithError(err).
-				Errorf("find resolved failed")
 			return nil, err
 		}
-		fetchResolvedDuration.WithLabelValues(c.config.bucketName).
-			Observe(float64(time.Since(start).Seconds()))
-		if upperBound != "" {
-			break
+		if found != nil {
+			return found, nil
 		}
 		select {
 		case <-ctx.Stopping():
@@ -358,11 +317,110 @@ func (c *Conn) findResolved(
 		case <-ticker.C:
 		}
 	}
+}
+
+// awaitResolved waits for a notification that a resolved timestamp
+// file has been created. The bucket is periodically listed, in case
+// notifications have been lost.
+func (c *Conn) awaitResolved(
+	ctx *stopper.Context, dir string, lowerBound string,
+) (*resolvedRange, error) {
+	ticker := time.NewTicker(c.config.ReconcileInterval)
+	defer ticker.Stop()
+	for {
+		changed := c.discovery.changed()
+		if found, ok := c.discovery.next(lowerBound); ok {
+			batchSize.WithLabelValues(c.config.bucketName).Observe(float64(found.count))
+			return found, nil
+		}
+		select {
+		case <-ctx.Stopping():
+			return nil, stopper.ErrStopped
+		case <-changed:
+		case <-ticker.C:
+			var found *resolvedRange
+			var err error
+			found, lowerBound, err = c.scanResolved(ctx, dir, lowerBound)
+			if err != nil {
+				return nil, err
+			}
+			if found != nil {
+				return found, nil
+			}
+		}
+	}
+}
+
+// scanResolved walks the bucket once, looking for a range of files
+// between two consecutive resolved timestamps. It returns a nil range
+// if none was found, and a lower bound that may have been advanced past
+// resolved timestamps with no files in between.
+func (c *Conn) scanResolved(
+	ctx *stopper.Context, dir string, lowerBound string,
+) (*resolvedRange, string, error) {
+	start := time.Now()
+	var upperBound string
+	// Number of entries between two resolved timestamps.
+	count := 0
+	options := &bucket.WalkOptions{
+		StartAfter: lowerBound,
+		Limit:      bucket.NoLimit,
+		Recursive:  true,
+	}
+	operation := func() error {
+		return c.bucket.Walk(ctx, dir, options,
+			func(ctx *stopper.Context, file string) error {
+				bucketScanCount.WithLabelValues(c.config.bucketName).Inc()
+				log.WithField("bucket", c.config.bucketName).
+					Tracef("processing %s", file)
+				file = path.Join(c.config.bucketName, file)
+				if strings.HasSuffix(file, resolvedSuffix) {
+					batchSize.WithLabelValues(c.config.bucketName).Observe(float64(count))
+					if count > 0 {
+						// We found a range with mutations, we will stop
+						// the walk
+						upperBound = file
+						return bucket.ErrSkipAll
+					}
+					log.WithField("bucket", c.config.bucketName).
+						Debugf("no transactions between %s and %s", lowerBound, file)
+					// If we get here, there is nothing interesting in
+					// between resolved timestamps.
+					// Resetting the lower bound of the range we need to process.
+					lowerBound = file
+
+					// TODO (silvano): we might be able to persist the
+					// last resolved timestamp to memo, however we have
+					// to make sure that all the concurrent processors
+					// are done. For busy systems this shouldn't matter,
+					// as it is unlikely that we don't have transactions
+					// between two resolved timestamps.
+
+					return nil
+				}
+				count++
+				if ctx.IsStopping() {
+					return bucket.ErrSkipAll
+				}
+				return nil
+			})
+	}
+	err := c.retry(operation, "find resolved")
+	if err != nil {
+		log.WithField("bucket", c.config.bucketName).WithError(err).
+			Errorf("find resolved failed")
+		return nil, lowerBound, err
+	}
+	fetchResolvedDuration.WithLabelValues(c.config.bucketName).
+		Observe(float64(time.Since(start).Seconds()))
+	if upperBound == "" {
+		return nil, lowerBound, nil
+	}
 	return &resolvedRange{
 		from:  lowerBound,
 		to:    upperBound,
 		count: count,
-	}, nil
+	}, lowerBound, nil
 }
 
 // processBatch receives a list of files and dispatches a processor for each file on
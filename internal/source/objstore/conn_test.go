@@ -33,6 +33,8 @@ import (
 	"github.com/cockroachdb/field-eng-powertools/stopper"
 	"github.com/cockroachdb/replicator/internal/sinktest/mocks"
 	"github.com/cockroachdb/replicator/internal/source/objstore/eventproc"
+	"github.com/cockroachdb/replicator/internal/source/objstore/format"
+	"github.com/cockroachdb/replicator/internal/source/objstore/notification"
 	"github.com/cockroachdb/replicator/internal/source/objstore/providers/local"
 	"github.com/cockroachdb/replicator/internal/staging/memo"
 	"github.com/cockroachdb/replicator/internal/types"
@@ -425,7 +427,7 @@ func buildTimestampVerifier(fs fs.FS) (*Conn, *timestampTracker, error) {
 	if err != nil {
 		return nil, nil, err
 	}
-	processor := eventproc.NewLocal(conveyor, bucket, parser, ident.MustSchema(ident.Public))
+	processor := eventproc.NewLocal(conveyor, bucket, format.New(parser, nil), ident.MustSchema(ident.Public))
 	return &Conn{
 		bucket: bucket,
 		config: &Config{
@@ -522,3 +524,46 @@ func timeRange(from int, to int) hlc.Range {
 func timestamp(seconds int) time.Time {
 	return time.Date(2024, 1, 1, 1, 1, seconds, 0, time.UTC)
 }
+
+// TestFindResolvedNotifications verifies that ranges are found using
+// notifications, and that the bucket is listed if notifications are
+// missing.
+func TestFindResolvedNotifications(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	stop := stopper.WithContext(ctx)
+	r := require.New(t)
+	a := assert.New(t)
+	rootFS := make(fstest.MapFS)
+	ranges, _, err := generate(rootFS, baseDir, defaultUpperLimit)
+	r.NoError(err)
+	r.Greater(len(ranges), 2)
+	conn, _, err := buildConn(rootFS, defaultProbTransientError)
+	r.NoError(err)
+	conn.config.ReconcileInterval = time.Hour
+	conn.discovery = newDiscovery("", baseDir)
+
+	// Report all the files, except the ones in the last range.
+	last := ranges[len(ranges)-1]
+	var events []notification.Event
+	for name := range rootFS {
+		if name <= last.from {
+			events = append(events, notification.Event{Key: name})
+		}
+	}
+	conn.discovery.add(events)
+
+	from := ""
+	for _, expected := range ranges[:len(ranges)-1] {
+		got, err := conn.findResolved(stop, baseDir, from)
+		r.NoError(err)
+		a.Equal(expected, got)
+		from = got.to
+	}
+
+	// The last range is only found by listing the bucket.
+	conn.config.ReconcileInterval = time.Millisecond
+	got, err := conn.findResolved(stop, baseDir, from)
+	r.NoError(err)
+	a.Equal(last, got)
+}
@@ -0,0 +1,137 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package objstore
+
+import (
+	"path"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/cockroachdb/field-eng-powertools/notify"
+	"github.com/cockroachdb/replicator/internal/source/objstore/notification"
+)
+
+// discovery tracks the objects that have been reported by
+// a notification.Source, so that resolved timestamp files can be found
+// without listing the bucket. Since notifications may be delayed or
+// lost, the files between two resolved timestamps are still collected
+// by listing the bucket.
+type discovery struct {
+	bucketName string // Only objects within the bucket are tracked.
+	prefix     string // Only objects with the prefix are tracked.
+
+	updated notify.Var[int] // Incremented when a resolved file is added.
+	mu      struct {
+		sync.Mutex
+		data     []string // Sorted names of mutation files.
+		resolved []string // Sorted names of resolved timestamp files.
+	}
+}
+
+// newDiscovery returns a discovery for the objects within the given
+// folder in the bucket.
+func newDiscovery(bucketName, dir string) *discovery {
+	prefix := path.Join(bucketName, dir)
+	if !strings.HasSuffix(prefix, "/") {
+		prefix += "/"
+	}
+	return &discovery{
+		bucketName: bucketName,
+		prefix:     prefix,
+	}
+}
+
+// add records the objects that have been created. Object names are
+// qualified by the bucket name, to match the names that are used when
+// walking the bucket.
+func (d *discovery) add(events []notification.Event) {
+	foundResolved := false
+	d.mu.Lock()
+	for _, event := range events {
+		if event.Bucket != d.bucketName {
+			continue
+		}
+		file := path.Join(event.Bucket, event.Key)
+		if !strings.HasPrefix(file, d.prefix) {
+			continue
+		}
+		notificationCount.WithLabelValues(d.bucketName).Inc()
+		if strings.HasSuffix(file, resolvedSuffix) {
+			d.mu.resolved = insertSorted(d.mu.resolved, file)
+			foundResolved = true
+		} else {
+			d.mu.data = insertSorted(d.mu.data, file)
+		}
+	}
+	d.mu.Unlock()
+	if foundResolved {
+		_, _, _ = d.updated.Update(func(old int) (int, error) { return old + 1, nil })
+	}
+}
+
+// changed returns a channel that is closed when a resolved file is
+// added.
+func (d *discovery) changed() <-chan struct{} {
+	_, ch := d.updated.Get()
+	return ch
+}
+
+// next returns the range between the lower bound and the first
+// resolved timestamp file that follows it. The count of mutation files
+// in the range is only an estimate, since notifications for the files
+// may be delayed or lost, or may arrive after the notification for the
+// resolved timestamp file. The range is returned even if no mutation
+// files are known, so that the caller will list the bucket to find
+// them. Objects before the lower bound are discarded, since they have
+// already been processed.
+func (d *discovery) next(lowerBound string) (*resolvedRange, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.mu.data = trimSorted(d.mu.data, lowerBound)
+	d.mu.resolved = trimSorted(d.mu.resolved, lowerBound)
+	if len(d.mu.resolved) == 0 {
+		return nil, false
+	}
+	to := d.mu.resolved[0]
+	// Mutation files that precede the resolved timestamp file.
+	count, _ := slices.BinarySearch(d.mu.data, to)
+	return &resolvedRange{
+		count: count,
+		from:  lowerBound,
+		to:    to,
+	}, true
+}
+
+// insertSorted adds the value to the sorted slice, if not present.
+func insertSorted(s []string, value string) []string {
+	idx, found := slices.BinarySearch(s, value)
+	if found {
+		return s
+	}
+	return slices.Insert(s, idx, value)
+}
+
+// trimSorted removes the values that are less than or equal to the
+// bound from the sorted slice.
+func trimSorted(s []string, bound string) []string {
+	idx, found := slices.BinarySearch(s, bound)
+	if found {
+		idx++
+	}
+	return slices.Delete(s, 0, idx)
+}
@@ -0,0 +1,136 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package objstore
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/replicator/internal/source/objstore/notification"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestDiscovery verifies that resolved ranges are computed from the
+// notifications that have been received.
+func TestDiscovery(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+	d := newDiscovery("bucket", "prefix")
+
+	changed := d.changed()
+	d.add([]notification.Event{
+		{Bucket: "bucket", Key: "prefix/2.ndjson"},
+		{Bucket: "bucket", Key: "prefix/1.ndjson"},
+		{Bucket: "other", Key: "prefix/3.RESOLVED"},
+		{Bucket: "bucket", Key: "other/3.RESOLVED"},
+	})
+	// Only mutation files were added.
+	select {
+	case <-changed:
+		r.Fail("unexpected notification")
+	default:
+	}
+	_, ok := d.next("")
+	a.False(ok)
+
+	d.add([]notification.Event{
+		{Bucket: "bucket", Key: "prefix/3.RESOLVED"},
+		{Bucket: "bucket", Key: "prefix/4.RESOLVED"},
+		{Bucket: "bucket", Key: "prefix/6.RESOLVED"},
+		{Bucket: "bucket", Key: "prefix/5.ndjson"},
+		// Duplicate deliveries are ignored.
+		{Bucket: "bucket", Key: "prefix/5.ndjson"},
+	})
+	<-changed
+
+	got, ok := d.next("")
+	r.True(ok)
+	a.Equal(&resolvedRange{
+		count: 2,
+		from:  "",
+		to:    "bucket/prefix/3.RESOLVED",
+	}, got)
+
+	// The resolved timestamp with no known mutations is not skipped,
+	// since the bucket must be listed to verify that it is empty.
+	got, ok = d.next(got.to)
+	r.True(ok)
+	a.Equal(&resolvedRange{
+		count: 0,
+		from:  "bucket/prefix/3.RESOLVED",
+		to:    "bucket/prefix/4.RESOLVED",
+	}, got)
+
+	got, ok = d.next(got.to)
+	r.True(ok)
+	a.Equal(&resolvedRange{
+		count: 1,
+		from:  "bucket/prefix/4.RESOLVED",
+		to:    "bucket/prefix/6.RESOLVED",
+	}, got)
+
+	_, ok = d.next(got.to)
+	a.False(ok)
+	a.Empty(d.mu.data)
+	a.Empty(d.mu.resolved)
+}
+
+// TestDiscoveryResolvedFirst verifies that a resolved timestamp file
+// whose notification arrives before the notifications for its mutation
+// files is not skipped.
+func TestDiscoveryResolvedFirst(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+	d := newDiscovery("bucket", "prefix")
+
+	d.add([]notification.Event{
+		{Bucket: "bucket", Key: "prefix/2.RESOLVED"},
+		{Bucket: "bucket", Key: "prefix/4.RESOLVED"},
+	})
+
+	// The first range must end at the first resolved timestamp, so
+	// that the files before it will be found by listing the bucket.
+	got, ok := d.next("")
+	r.True(ok)
+	a.Equal(&resolvedRange{
+		count: 0,
+		from:  "",
+		to:    "bucket/prefix/2.RESOLVED",
+	}, got)
+
+	// Late notifications for the mutation files don't change the
+	// range.
+	d.add([]notification.Event{
+		{Bucket: "bucket", Key: "prefix/1.ndjson"},
+		{Bucket: "bucket", Key: "prefix/3.ndjson"},
+	})
+	got, ok = d.next("")
+	r.True(ok)
+	a.Equal(&resolvedRange{
+		count: 1,
+		from:  "",
+		to:    "bucket/prefix/2.RESOLVED",
+	}, got)
+
+	got, ok = d.next(got.to)
+	r.True(ok)
+	a.Equal(&resolvedRange{
+		count: 1,
+		from:  "bucket/prefix/2.RESOLVED",
+		to:    "bucket/prefix/4.RESOLVED",
+	}, got)
+}
@@ -18,12 +18,11 @@ package eventproc
 
 import (
 	"context"
-	"io"
 
 	"github.com/cockroachdb/field-eng-powertools/stopper"
 	"github.com/cockroachdb/replicator/internal/source/objstore/bucket"
+	"github.com/cockroachdb/replicator/internal/source/objstore/format"
 	"github.com/cockroachdb/replicator/internal/types"
-	"github.com/cockroachdb/replicator/internal/util/cdcjson"
 	"github.com/cockroachdb/replicator/internal/util/ident"
 	"github.com/pkg/errors"
 )
@@ -37,7 +36,7 @@ type Acceptor interface {
 type localProcessor struct {
 	acceptor Acceptor
 	bucket   bucket.Bucket
-	parser   *cdcjson.NDJsonParser
+	decoders format.Decoders
 	schema   ident.Schema
 }
 
@@ -45,12 +44,12 @@ var _ Processor = &localProcessor{}
 
 // NewLocal creates a local processor.
 func NewLocal(
-	acceptor Acceptor, bucket bucket.Bucket, parser *cdcjson.NDJsonParser, schema ident.Schema,
+	acceptor Acceptor, bucket bucket.Bucket, decoders format.Decoders, schema ident.Schema,
 ) Processor {
 	return &localProcessor{
 		acceptor: acceptor,
 		bucket:   bucket,
-		parser:   parser,
+		decoders: decoders,
 		schema:   schema,
 	}
 }
@@ -60,7 +59,7 @@ func (c *localProcessor) Process(
 	ctx *stopper.Context, path string, filters ...types.MutationFilter,
 ) error {
 	// Extract the table name from the path.
-	tableName, err := getTableName(path)
+	tableName, ts, err := parsePath(path)
 	if err != nil {
 		return err
 	}
@@ -72,8 +71,22 @@ func (c *localProcessor) Process(
 	}
 	defer buff.Close()
 
+	// Find the decoder for the file format, based on the file extension.
+	decoder, content, err := c.decoders.Open(path, buff)
+	if errors.Is(err, format.ErrUnknownFormat) {
+		return errors.Wrapf(ErrInvalidPath, "unsupported format for %s", path)
+	}
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+
 	// Parse the mutations inside the file into a Batch.
-	batch, err := c.parser.Parse(table, filteredReader(filters...), buff)
+	batch, err := decoder.Decode(&format.File{
+		Path:  path,
+		Table: table,
+		Time:  ts,
+	}, content, filters...)
 	if err != nil {
 		return errors.Wrapf(err, "failed to parse %s", path)
 	}
@@ -81,27 +94,3 @@ func (c *localProcessor) Process(
 	// Send the batch downstream to the target.
 	return c.acceptor.AcceptMultiBatch(ctx, batch, &types.AcceptOptions{})
 }
-
-// filteredReader returns a function reads mutations from
-// from a regular changefeed, removing mutations that
-// don't match all the given filters.
-func filteredReader(
-	filters ...types.MutationFilter,
-) func(reader io.Reader) (types.Mutation, error) {
-	return func(reader io.Reader) (types.Mutation, error) {
-		// read a mutation
-		mut, err := cdcjson.BulkMutationReader()(reader)
-		if err != nil {
-			return types.Mutation{}, err
-		}
-		// check that it satisfies all the filters, if any.
-		for _, filter := range filters {
-			if !filter(mut) {
-				// returning an empty mutation,
-				// which will be discarded by the parser.
-				return types.Mutation{}, nil
-			}
-		}
-		return mut, nil
-	}
-}
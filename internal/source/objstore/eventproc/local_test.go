@@ -28,6 +28,7 @@ import (
 
 	"github.com/cockroachdb/field-eng-powertools/stopper"
 	"github.com/cockroachdb/replicator/internal/sinktest/recorder"
+	"github.com/cockroachdb/replicator/internal/source/objstore/format"
 	"github.com/cockroachdb/replicator/internal/source/objstore/providers/local"
 	"github.com/cockroachdb/replicator/internal/types"
 	"github.com/cockroachdb/replicator/internal/util/cdcjson"
@@ -188,7 +189,7 @@ func TestLocalProcess(t *testing.T) {
 			a := assert.New(t)
 			parser, _ := cdcjson.New(bufio.MaxScanTokenSize)
 			schema := ident.MustSchema(ident.Public)
-			processor := NewLocal(tt.acceptor, bucket, parser, schema)
+			processor := NewLocal(tt.acceptor, bucket, format.New(parser, nil), schema)
 			err := processor.Process(stop, filepath.Join(bucketName, tt.path), tt.filters...)
 			if tt.wantErr != nil {
 				a.ErrorContains(err, tt.wantErr.Error())
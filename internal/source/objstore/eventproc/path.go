@@ -19,22 +19,44 @@ package eventproc
 import (
 	"path/filepath"
 	"regexp"
+	"strconv"
+	"time"
 
+	"github.com/cockroachdb/replicator/internal/util/hlc"
 	"github.com/cockroachdb/replicator/internal/util/ident"
 	"github.com/pkg/errors"
 )
 
 var (
-	ndjsonRegex = regexp.MustCompile(`^(?P<prelude>([^-]+-){5})(?P<topic>.+)-(?P<schema_id>[^-]+).ndjson$`)
-	ndjsonTopic = ndjsonRegex.SubexpIndex("topic")
+	fileRegex = regexp.MustCompile(`^(?P<prelude>([^-]+-){5})(?P<topic>.+)-(?P<schema_id>[^-.]+)\.(?P<ext>[^-]+)$`)
+	fileTopic = fileRegex.SubexpIndex("topic")
+	// The timestamp is formatted as YYYYMMDDHHMMSSNNNNNNNNNLLLLLLLLLL,
+	// where N are the nanoseconds and L the logical clock.
+	timestampRegex = regexp.MustCompile(`^(?P<wall>\d{14})(?P<nanos>\d{9})(?P<logical>\d{10})-`)
 )
 
-// getTableName extracts the table name from a changefeed cloud storage ndjson file.
-func getTableName(path string) (ident.Ident, error) {
-	res := ndjsonRegex.FindStringSubmatch(filepath.Base(path))
+// parsePath extracts the table name and the timestamp from the name of
+// a changefeed cloud storage file. The timestamp is a lower bound for
+// the timestamps of the mutations in the file; it's zero if it cannot
+// be extracted.
+func parsePath(path string) (ident.Ident, hlc.Time, error) {
+	name := filepath.Base(path)
+	res := fileRegex.FindStringSubmatch(name)
 	if res == nil {
-		return ident.Ident{},
+		return ident.Ident{}, hlc.Zero(),
 			errors.Wrapf(ErrInvalidPath, "unable to extract table name from %s", path)
 	}
-	return ident.New(res[ndjsonTopic]), nil
+	table := ident.New(res[fileTopic])
+	ts := timestampRegex.FindStringSubmatch(name)
+	if ts == nil {
+		return table, hlc.Zero(), nil
+	}
+	wall, err := time.Parse("20060102150405", ts[1])
+	if err != nil {
+		return table, hlc.Zero(), nil
+	}
+	// The regular expression ensures that these are digits.
+	nanos, _ := strconv.Atoi(ts[2])
+	logical, _ := strconv.Atoi(ts[3])
+	return table, hlc.New(wall.UnixNano()+int64(nanos), logical), nil
 }
@@ -18,28 +18,50 @@ package eventproc
 
 import (
 	"testing"
+	"time"
 
+	"github.com/cockroachdb/replicator/internal/util/hlc"
 	"github.com/cockroachdb/replicator/internal/util/ident"
 	"github.com/stretchr/testify/assert"
 )
 
-func TestGetTableName(t *testing.T) {
+func TestParsePath(t *testing.T) {
 	tests := []struct {
 		name    string
 		path    string
 		want    ident.Ident
+		wantTS  hlc.Time
 		wantErr error
 	}{
 		{
-			name: "good",
-			path: "202405031553360274740000000000000-08779498965a12e2-1-2-00000000-mytable-2.ndjson",
-			want: ident.New("mytable"),
+			name:   "good",
+			path:   "202405031553360274740000000000000-08779498965a12e2-1-2-00000000-mytable-2.ndjson",
+			want:   ident.New("mytable"),
+			wantTS: hlc.New(time.Date(2024, 5, 3, 15, 53, 36, 27474000, time.UTC).UnixNano(), 0),
 		},
 		{
-			name:    "invalid suffix",
-			path:    "202405031553360274740000000000000-08779498965a12e2-1-2-00000000-mytable-2.csv",
+			name:   "parquet",
+			path:   "202405031553360274740000000000003-08779498965a12e2-1-2-00000000-my-table-2.parquet",
+			want:   ident.New("my-table"),
+			wantTS: hlc.New(time.Date(2024, 5, 3, 15, 53, 36, 27474000, time.UTC).UnixNano(), 3),
+		},
+		{
+			name:   "compressed",
+			path:   "202405031553360274740000000000000-08779498965a12e2-1-2-00000000-mytable-2.ndjson.gz",
+			want:   ident.New("mytable"),
+			wantTS: hlc.New(time.Date(2024, 5, 3, 15, 53, 36, 27474000, time.UTC).UnixNano(), 0),
+		},
+		{
+			name:    "no suffix",
+			path:    "202405031553360274740000000000000-08779498965a12e2-1-2-00000000-mytable-2",
 			wantErr: ErrInvalidPath,
 		},
+		{
+			name:   "invalid timestamp",
+			path:   "202405991553360274740000000000000-08779498965a12e2-1-2-00000000-mytable-2.ndjson",
+			want:   ident.New("mytable"),
+			wantTS: hlc.Zero(),
+		},
 		{
 			name:    "invalid",
 			path:    "-202405031553360274740000000000000-08779498965a12e2-1-2-00000000-mytable-2.json",
@@ -54,13 +76,14 @@ func TestGetTableName(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			a := assert.New(t)
-			got, err := getTableName(tt.path)
+			got, ts, err := parsePath(tt.path)
 			if tt.wantErr != nil {
 				a.ErrorIs(err, tt.wantErr)
 				return
 			}
 			a.NoError(err)
 			a.Equal(tt.want, got)
+			a.Equal(tt.wantTS, ts)
 		})
 	}
 }
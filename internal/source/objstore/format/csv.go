@@ -0,0 +1,132 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package format
+
+import (
+	"cmp"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/hlc"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/pkg/errors"
+)
+
+// csvDecoder decodes CSV files, such as exports of a table. The first
+// record must be a header that names the columns. The metadata columns
+// are optional: a row is an upsert unless __crdb__event_type is "d",
+// and if neither __crdb__updated nor __crdb__mvcc_timestamp is present,
+// the timestamp encoded in the file name is used. Empty values are
+// treated as NULL. Since the primary key is not encoded separately, it
+// is extracted from the row using the schema of the target table.
+type csvDecoder struct {
+	schema Schema
+}
+
+var _ Decoder = &csvDecoder{}
+
+// Decode implements Decoder.
+func (d *csvDecoder) Decode(
+	file *File, r io.Reader, filters ...types.MutationFilter,
+) (*types.MultiBatch, error) {
+	keys, err := primaryKeys(d.schema, file.Table)
+	if err != nil {
+		return nil, err
+	}
+	reader := csv.NewReader(r)
+	reader.ReuseRecord = true
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return &types.MultiBatch{}, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read header")
+	}
+	header = append([]string(nil), header...)
+
+	// The position of each primary key column within a record.
+	keyIdx := make([]int, keys.Len())
+	for i := range keyIdx {
+		keyIdx[i] = -1
+	}
+	for i, name := range header {
+		if pos, ok := keys.Get(ident.New(name)); ok {
+			keyIdx[pos] = i
+		}
+	}
+	for _, idx := range keyIdx {
+		if idx < 0 {
+			return nil, errors.New("missing primary key columns")
+		}
+	}
+
+	batch := &types.MultiBatch{}
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return batch, nil
+		}
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		var eventType, mvcc, updated string
+		data := make(map[string]any, len(header))
+		for i, name := range header {
+			switch name {
+			case eventTypeColumn:
+				eventType = record[i]
+			case mvccColumn:
+				mvcc = record[i]
+			case updatedColumn:
+				updated = record[i]
+			default:
+				if record[i] == "" {
+					data[name] = nil
+				} else {
+					data[name] = record[i]
+				}
+			}
+		}
+		mut := types.Mutation{Time: file.Time}
+		if ts := cmp.Or(updated, mvcc); ts != "" {
+			if mut.Time, err = hlc.Parse(ts); err != nil {
+				return nil, err
+			}
+		} else if mut.Time == hlc.Zero() {
+			return nil, errors.Errorf("missing %s column", updatedColumn)
+		}
+		key := make([]any, len(keyIdx))
+		for pos, idx := range keyIdx {
+			if record[idx] != "" {
+				key[pos] = record[idx]
+			}
+		}
+		if mut.Key, err = json.Marshal(key); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if eventType != "d" {
+			if mut.Data, err = json.Marshal(data); err != nil {
+				return nil, errors.WithStack(err)
+			}
+		}
+		if err := accumulate(batch, file.Table, mut, filters); err != nil {
+			return nil, err
+		}
+	}
+}
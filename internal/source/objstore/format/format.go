@@ -0,0 +1,150 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package format decodes the files written by a changefeed into cloud
+// storage. The format of a file is determined by its extension, e.g.
+// ".ndjson" or ".parquet", optionally followed by a compression
+// extension, e.g. ".gz" or ".zst".
+package format
+
+import (
+	"compress/gzip"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/cdcjson"
+	"github.com/cockroachdb/replicator/internal/util/hlc"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pkg/errors"
+)
+
+// Metadata columns added by a changefeed.
+const (
+	eventTypeColumn = "__crdb__event_type"
+	mvccColumn      = "__crdb__mvcc_timestamp"
+	updatedColumn   = "__crdb__updated"
+)
+
+// ErrUnknownFormat is returned if no Decoder is available for a file.
+var ErrUnknownFormat = errors.New("unknown file format")
+
+// A File describes a changefeed file.
+type File struct {
+	// The path of the file within the bucket.
+	Path string
+	// The table that the mutations will be applied to.
+	Table ident.Table
+	// The timestamp encoded in the file name. It's a lower bound for
+	// the timestamps of the mutations in the file.
+	Time hlc.Time
+}
+
+// A Decoder extracts the mutations contained in a changefeed file.
+type Decoder interface {
+	// Decode reads the uncompressed content of the file and returns
+	// the mutations that satisfy all the filters.
+	Decode(file *File, r io.Reader, filters ...types.MutationFilter) (*types.MultiBatch, error)
+}
+
+// Decoders maps a file extension, without the leading dot, to the
+// Decoder for the format.
+type Decoders map[string]Decoder
+
+// Schema returns the columns of a table in the target database. It's
+// used by formats that don't encode the primary key of a row
+// separately.
+type Schema func(table ident.Table) ([]types.ColData, error)
+
+// New returns the Decoders for all the supported formats.
+func New(parser *cdcjson.NDJsonParser, schema Schema) Decoders {
+	return Decoders{
+		"csv":     &csvDecoder{schema: schema},
+		"ndjson":  &ndjsonDecoder{parser: parser},
+		"parquet": &parquetDecoder{schema: schema},
+	}
+}
+
+// Open returns the Decoder for the format of the named file and a
+// reader for its uncompressed content.
+func (d Decoders) Open(name string, r io.Reader) (Decoder, io.ReadCloser, error) {
+	compression := path.Ext(name)
+	if compression != ".gz" && compression != ".zst" {
+		compression = ""
+	}
+	ext := path.Ext(strings.TrimSuffix(name, compression))
+	decoder, ok := d[strings.TrimPrefix(ext, ".")]
+	if !ok {
+		return nil, nil, errors.Wrapf(ErrUnknownFormat, "%s", name)
+	}
+	switch compression {
+	case ".gz":
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "could not decompress %s", name)
+		}
+		return decoder, gz, nil
+	case ".zst":
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "could not decompress %s", name)
+		}
+		return decoder, zr.IOReadCloser(), nil
+	default:
+		return decoder, io.NopCloser(r), nil
+	}
+}
+
+// accumulate adds the mutation to the batch, if it satisfies all the
+// filters.
+func accumulate(
+	batch *types.MultiBatch, table ident.Table, mut types.Mutation, filters []types.MutationFilter,
+) error {
+	// Discard phantom deletes.
+	if mut.IsDelete() && mut.Key == nil {
+		return nil
+	}
+	for _, filter := range filters {
+		if !filter(mut) {
+			return nil
+		}
+	}
+	return batch.Accumulate(table, mut)
+}
+
+// primaryKeys returns the primary key columns of the table, and their
+// position within the key.
+func primaryKeys(schema Schema, table ident.Table) (*ident.Map[int], error) {
+	if schema == nil {
+		return nil, errors.New("the target schema is not available")
+	}
+	columns, err := schema(table)
+	if err != nil {
+		return nil, err
+	}
+	keys := &ident.Map[int]{}
+	for _, col := range columns {
+		if col.Primary {
+			keys.Put(col.Name, keys.Len())
+		}
+	}
+	if keys.Len() == 0 {
+		return nil, errors.Errorf("table %s has no primary key columns", table)
+	}
+	return keys, nil
+}
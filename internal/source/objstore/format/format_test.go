@@ -0,0 +1,212 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package format
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/cdcjson"
+	"github.com/cockroachdb/replicator/internal/util/hlc"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const ndjsonContent = `{"after": {"pk": 1, "v": "a"}, "key": [1], "updated": "1.0"}
+{"after": null, "key": [2], "updated": "2.0"}
+`
+
+func gzipped(t *testing.T, content string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func zstded(t *testing.T, content string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := zstd.NewWriter(&buf)
+	require.NoError(t, err)
+	_, err = w.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+// TestOpen verifies that the format and the compression are detected
+// using the file extensions.
+func TestOpen(t *testing.T) {
+	parser, err := cdcjson.New(bufio.MaxScanTokenSize)
+	require.NoError(t, err)
+	decoders := New(parser, testSchema("pk"))
+	table := ident.NewTable(ident.MustSchema(ident.Public), ident.New("mytable"))
+
+	tests := []struct {
+		name    string
+		path    string
+		content []byte
+		wantErr error
+	}{
+		{
+			name:    "ndjson",
+			path:    "dir/file.ndjson",
+			content: []byte(ndjsonContent),
+		},
+		{
+			name:    "gzip",
+			path:    "dir/file.ndjson.gz",
+			content: gzipped(t, ndjsonContent),
+		},
+		{
+			name:    "zstd",
+			path:    "dir/file.ndjson.zst",
+			content: zstded(t, ndjsonContent),
+		},
+		{
+			name:    "unknown",
+			path:    "dir/file.avro",
+			wantErr: ErrUnknownFormat,
+		},
+		{
+			name:    "unknown compressed",
+			path:    "dir/file.gz",
+			wantErr: ErrUnknownFormat,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := assert.New(t)
+			r := require.New(t)
+			decoder, content, err := decoders.Open(tt.path, bytes.NewReader(tt.content))
+			if tt.wantErr != nil {
+				a.ErrorIs(err, tt.wantErr)
+				return
+			}
+			r.NoError(err)
+			defer content.Close()
+			batch, err := decoder.Decode(&File{Path: tt.path, Table: table}, content)
+			r.NoError(err)
+			r.Equal(2, batch.Count())
+		})
+	}
+}
+
+func TestCSVDecode(t *testing.T) {
+	table := ident.NewTable(ident.MustSchema(ident.Public), ident.New("mytable"))
+	fileTime := hlc.New(100, 0)
+
+	tests := []struct {
+		name    string
+		content string
+		time    hlc.Time
+		want    []types.Mutation
+		wantErr string
+	}{
+		{
+			name:    "export",
+			content: "name,pk\na,1\n,2\n",
+			time:    fileTime,
+			want: []types.Mutation{
+				{
+					Data: json.RawMessage(`{"name":"a","pk":"1"}`),
+					Key:  json.RawMessage(`["1"]`),
+					Time: fileTime,
+				},
+				{
+					Data: json.RawMessage(`{"name":null,"pk":"2"}`),
+					Key:  json.RawMessage(`["2"]`),
+					Time: fileTime,
+				},
+			},
+		},
+		{
+			name:    "metadata",
+			content: "pk,name,__crdb__event_type,__crdb__updated\n1,a,c,1.0\n1,,d,2.0000000001\n",
+			want: []types.Mutation{
+				{
+					Data: json.RawMessage(`{"name":"a","pk":"1"}`),
+					Key:  json.RawMessage(`["1"]`),
+					Time: hlc.New(1, 0),
+				},
+				{
+					Key:  json.RawMessage(`["1"]`),
+					Time: hlc.New(2, 1),
+				},
+			},
+		},
+		{
+			name:    "empty",
+			content: "",
+		},
+		{
+			name:    "missing timestamp",
+			content: "pk,name\n1,a\n",
+			wantErr: "missing __crdb__updated column",
+		},
+		{
+			name:    "missing key",
+			content: "name\na\n",
+			time:    fileTime,
+			wantErr: "missing primary key columns",
+		},
+		{
+			name:    "malformed",
+			content: "pk,name\n1,a,b\n",
+			time:    fileTime,
+			wantErr: "wrong number of fields",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := assert.New(t)
+			r := require.New(t)
+			decoder := &csvDecoder{schema: testSchema("pk")}
+			batch, err := decoder.Decode(&File{Path: "file.csv", Table: table, Time: tt.time},
+				strings.NewReader(tt.content))
+			if tt.wantErr != "" {
+				a.ErrorContains(err, tt.wantErr)
+				return
+			}
+			r.NoError(err)
+			var got []types.Mutation
+			for _, mut := range batch.Mutations() {
+				got = append(got, mut)
+			}
+			r.Len(got, len(tt.want))
+			for i, want := range tt.want {
+				a.Equal(want.Time, got[i].Time)
+				a.JSONEq(string(want.Key), string(got[i].Key))
+				if want.Data == nil {
+					a.True(got[i].IsDelete())
+				} else {
+					a.JSONEq(string(want.Data), string(got[i].Data))
+				}
+			}
+		})
+	}
+}
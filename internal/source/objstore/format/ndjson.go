@@ -0,0 +1,61 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package format
+
+import (
+	"io"
+
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/cdcjson"
+)
+
+// ndjsonDecoder decodes newline-delimited JSON files, written by
+// changefeeds with format=json.
+type ndjsonDecoder struct {
+	parser *cdcjson.NDJsonParser
+}
+
+var _ Decoder = &ndjsonDecoder{}
+
+// Decode implements Decoder.
+func (d *ndjsonDecoder) Decode(
+	file *File, r io.Reader, filters ...types.MutationFilter,
+) (*types.MultiBatch, error) {
+	return d.parser.Parse(file.Table, filteredReader(filters...), r)
+}
+
+// filteredReader returns a function reads mutations from
+// from a regular changefeed, removing mutations that
+// don't match all the given filters.
+func filteredReader(filters ...types.MutationFilter) cdcjson.MutationReader {
+	return func(reader io.Reader) (types.Mutation, error) {
+		// read a mutation
+		mut, err := cdcjson.BulkMutationReader()(reader)
+		if err != nil {
+			return types.Mutation{}, err
+		}
+		// check that it satisfies all the filters, if any.
+		for _, filter := range filters {
+			if !filter(mut) {
+				// returning an empty mutation,
+				// which will be discarded by the parser.
+				return types.Mutation{}, nil
+			}
+		}
+		return mut, nil
+	}
+}
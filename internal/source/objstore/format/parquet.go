@@ -0,0 +1,370 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package format
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/hlc"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/parquet-go/parquet-go"
+	"github.com/parquet-go/parquet-go/deprecated"
+	pformat "github.com/parquet-go/parquet-go/format"
+	"github.com/pkg/errors"
+)
+
+// The number of rows to read at once.
+const parquetBatchSize = 128
+
+// parquetDecoder decodes Parquet files, written by changefeeds with
+// format=parquet. Each row contains the columns of the table, and the
+// __crdb__event_type and __crdb__updated (or __crdb__mvcc_timestamp)
+// metadata columns. Since the primary key is not encoded separately,
+// it is extracted from the row using the schema of the target table.
+type parquetDecoder struct {
+	schema Schema
+}
+
+var _ Decoder = &parquetDecoder{}
+
+// parquetColumn describes how to decode a top-level field of the
+// Parquet schema. Each field maps to exactly one leaf column.
+type parquetColumn struct {
+	name string
+	// The leaf node that contains the values.
+	leaf parquet.Node
+	// For lists, the definition levels of the list itself, of the
+	// repeated group and of the element. A definition level lower than
+	// listDef means that the list is null; lower than repeatedDef that
+	// the list is empty; lower than elementDef that the element is
+	// null.
+	list                             bool
+	listDef, repeatedDef, elementDef int
+}
+
+// Decode implements Decoder.
+func (d *parquetDecoder) Decode(
+	file *File, r io.Reader, filters ...types.MutationFilter,
+) (*types.MultiBatch, error) {
+	keys, err := primaryKeys(d.schema, file.Table)
+	if err != nil {
+		return nil, err
+	}
+	// Parquet files must be read from the end.
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	f, err := parquet.OpenFile(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	reader := parquet.NewReader(f)
+	defer reader.Close()
+	columns, err := parquetColumns(reader.Schema())
+	if err != nil {
+		return nil, err
+	}
+
+	batch := &types.MultiBatch{}
+	rows := make([]parquet.Row, parquetBatchSize)
+	for {
+		count, err := reader.ReadRows(rows)
+		for _, row := range rows[:count] {
+			mut, err := parquetMutation(columns, keys, row)
+			if err != nil {
+				return nil, err
+			}
+			if err := accumulate(batch, file.Table, mut, filters); err != nil {
+				return nil, err
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return batch, nil
+		}
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+}
+
+// parquetColumns returns the columns to decode, indexed by the leaf
+// column index.
+func parquetColumns(schema *parquet.Schema) ([]*parquetColumn, error) {
+	fields := schema.Fields()
+	ret := make([]*parquetColumn, 0, len(fields))
+	for _, field := range fields {
+		col := &parquetColumn{name: field.Name(), leaf: field}
+		if !field.Leaf() {
+			// Lists are encoded using the three-level structure:
+			//   <optional|required> group <name> (LIST) {
+			//     repeated group list {
+			//       <optional|required> <element-type> element;
+			//     }
+			//   }
+			// The LIST annotation is not always available, so the
+			// structure is checked instead.
+			var repeated, element parquet.Node
+			if len(field.Fields()) == 1 {
+				repeated = field.Fields()[0]
+				if repeated.Repeated() && !repeated.Leaf() && len(repeated.Fields()) == 1 {
+					element = repeated.Fields()[0]
+				}
+			}
+			if element == nil || !element.Leaf() {
+				return nil, errors.Errorf("unsupported type for column %s: %s", field.Name(), field)
+			}
+			col.leaf = element
+			col.list = true
+			if field.Optional() {
+				col.listDef = 1
+			}
+			col.repeatedDef = col.listDef + 1
+			col.elementDef = col.repeatedDef
+			if element.Optional() {
+				col.elementDef++
+			}
+		}
+		ret = append(ret, col)
+	}
+	return ret, nil
+}
+
+// parquetMutation converts a row into a mutation.
+func parquetMutation(
+	columns []*parquetColumn, keys *ident.Map[int], row parquet.Row,
+) (types.Mutation, error) {
+	var mut types.Mutation
+	var eventType, mvcc, updated string
+	data := make(map[string]any, len(columns))
+	key := make([]any, keys.Len())
+	foundKeys := 0
+	var err error
+	row.Range(func(idx int, values []parquet.Value) bool {
+		if idx >= len(columns) {
+			err = errors.Errorf("unexpected column %d", idx)
+			return false
+		}
+		col := columns[idx]
+		var value any
+		if value, err = col.decode(values); err != nil {
+			return false
+		}
+		switch col.name {
+		case eventTypeColumn:
+			eventType, _ = value.(string)
+		case mvccColumn:
+			mvcc, _ = value.(string)
+		case updatedColumn:
+			updated, _ = value.(string)
+		default:
+			data[col.name] = value
+			if pos, ok := keys.Get(ident.New(col.name)); ok {
+				key[pos] = value
+				foundKeys++
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return mut, err
+	}
+	if foundKeys != keys.Len() {
+		return mut, errors.New("missing primary key columns")
+	}
+	ts := cmp.Or(updated, mvcc)
+	if ts == "" {
+		return mut, errors.New("CREATE CHANGEFEED must specify the 'WITH updated' option")
+	}
+	if mut.Time, err = hlc.Parse(ts); err != nil {
+		return mut, err
+	}
+	if mut.Key, err = json.Marshal(key); err != nil {
+		return mut, errors.WithStack(err)
+	}
+	if eventType != "d" {
+		if mut.Data, err = json.Marshal(data); err != nil {
+			return mut, errors.WithStack(err)
+		}
+	}
+	return mut, nil
+}
+
+// decode returns the value of the column.
+func (c *parquetColumn) decode(values []parquet.Value) (any, error) {
+	if !c.list {
+		if len(values) != 1 {
+			return nil, errors.Errorf("column %s: expected one value, got %d", c.name, len(values))
+		}
+		if values[0].IsNull() {
+			return nil, nil
+		}
+		return parquetValue(c.leaf, values[0])
+	}
+	if len(values) == 1 {
+		if def := values[0].DefinitionLevel(); def < c.listDef {
+			return nil, nil
+		} else if def < c.repeatedDef {
+			return []any{}, nil
+		}
+	}
+	ret := make([]any, len(values))
+	for i, value := range values {
+		if value.DefinitionLevel() < c.elementDef {
+			continue
+		}
+		var err error
+		if ret[i], err = parquetValue(c.leaf, value); err != nil {
+			return nil, errors.Wrapf(err, "column %s", c.name)
+		}
+	}
+	return ret, nil
+}
+
+// parquetValue converts a value into a type that can be encoded as
+// JSON, using the same representation as a JSON changefeed.
+func parquetValue(node parquet.Node, value parquet.Value) (any, error) {
+	typ := node.Type()
+	lt := typ.LogicalType()
+	if lt == nil {
+		lt = convertedLogicalType(typ.ConvertedType())
+	}
+	switch value.Kind() {
+	case parquet.Boolean:
+		return value.Boolean(), nil
+	case parquet.Float:
+		return value.Float(), nil
+	case parquet.Double:
+		return value.Double(), nil
+	case parquet.Int32, parquet.Int64:
+		v := value.Int64()
+		if value.Kind() == parquet.Int32 {
+			v = int64(value.Int32())
+		}
+		switch {
+		case lt == nil:
+			return v, nil
+		case lt.Decimal != nil:
+			return decimal(big.NewInt(v), lt.Decimal.Scale), nil
+		case lt.Date != nil:
+			return time.Unix(v*24*60*60, 0).UTC().Format(time.DateOnly), nil
+		case lt.Time != nil:
+			return unixTime(lt.Time.Unit, v).Format("15:04:05.999999999"), nil
+		case lt.Timestamp != nil:
+			ts := unixTime(lt.Timestamp.Unit, v)
+			if lt.Timestamp.IsAdjustedToUTC {
+				return ts.Format(time.RFC3339Nano), nil
+			}
+			return ts.Format("2006-01-02T15:04:05.999999999"), nil
+		case lt.Integer != nil && !lt.Integer.IsSigned:
+			if value.Kind() == parquet.Int32 {
+				return uint64(uint32(v)), nil
+			}
+			return uint64(v), nil
+		default:
+			return v, nil
+		}
+	case parquet.ByteArray, parquet.FixedLenByteArray:
+		b := value.ByteArray()
+		switch {
+		case lt == nil:
+			return `\x` + hex.EncodeToString(b), nil
+		case lt.UTF8 != nil, lt.Enum != nil:
+			return string(b), nil
+		case lt.Json != nil:
+			if !json.Valid(b) {
+				return nil, errors.Errorf("invalid JSON value %q", b)
+			}
+			return json.RawMessage(bytes.Clone(b)), nil
+		case lt.UUID != nil:
+			if len(b) != 16 {
+				return nil, errors.Errorf("invalid UUID value %x", b)
+			}
+			s := hex.EncodeToString(b)
+			return strings.Join([]string{s[0:8], s[8:12], s[12:16], s[16:20], s[20:]}, "-"), nil
+		case lt.Decimal != nil:
+			// Big-endian, two's complement.
+			v := new(big.Int).SetBytes(b)
+			if len(b) > 0 && b[0]&0x80 != 0 {
+				v.Sub(v, new(big.Int).Lsh(big.NewInt(1), uint(len(b)*8)))
+			}
+			return decimal(v, lt.Decimal.Scale), nil
+		default:
+			return `\x` + hex.EncodeToString(b), nil
+		}
+	default:
+		return nil, errors.Errorf("unsupported parquet type %s", typ)
+	}
+}
+
+// convertedLogicalType maps the legacy annotations that don't require
+// additional parameters to a logical type.
+func convertedLogicalType(ct *deprecated.ConvertedType) *pformat.LogicalType {
+	if ct == nil {
+		return nil
+	}
+	switch *ct {
+	case deprecated.UTF8:
+		return &pformat.LogicalType{UTF8: &pformat.StringType{}}
+	case deprecated.Enum:
+		return &pformat.LogicalType{Enum: &pformat.EnumType{}}
+	case deprecated.Json:
+		return &pformat.LogicalType{Json: &pformat.JsonType{}}
+	case deprecated.Date:
+		return &pformat.LogicalType{Date: &pformat.DateType{}}
+	default:
+		return nil
+	}
+}
+
+// decimal formats an unscaled value as a number.
+func decimal(unscaled *big.Int, scale int32) json.Number {
+	if scale <= 0 {
+		return json.Number(unscaled.String())
+	}
+	digits := new(big.Int).Abs(unscaled).String()
+	if pad := int(scale) + 1 - len(digits); pad > 0 {
+		digits = strings.Repeat("0", pad) + digits
+	}
+	point := len(digits) - int(scale)
+	sign := ""
+	if unscaled.Sign() < 0 {
+		sign = "-"
+	}
+	return json.Number(sign + digits[:point] + "." + digits[point:])
+}
+
+// unixTime returns the time that is the given number of units since
+// the Unix epoch.
+func unixTime(unit pformat.TimeUnit, v int64) time.Time {
+	switch {
+	case unit.Millis != nil:
+		return time.UnixMilli(v).UTC()
+	case unit.Micros != nil:
+		return time.UnixMicro(v).UTC()
+	default:
+		return time.Unix(0, v).UTC()
+	}
+}
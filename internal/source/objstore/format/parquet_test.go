@@ -0,0 +1,294 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package format
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/hlc"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// parquetRow mimics the rows written by a changefeed.
+type parquetRow struct {
+	PK        int64    `parquet:"pk"`
+	Name      *string  `parquet:"name,optional"`
+	Price     int64    `parquet:"price,decimal(2:10)"`
+	Created   int64    `parquet:"created,timestamp(microsecond)"`
+	Day       int32    `parquet:"day,date"`
+	Data      []byte   `parquet:"data"`
+	Tags      []string `parquet:"tags,list"`
+	EventType string   `parquet:"__crdb__event_type"`
+	Updated   string   `parquet:"__crdb__updated"`
+}
+
+// parquetRowNoUpdated is missing the timestamp metadata.
+type parquetRowNoUpdated struct {
+	PK        int64  `parquet:"pk"`
+	EventType string `parquet:"__crdb__event_type"`
+}
+
+func writeParquet[T any](t *testing.T, rows []T) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := parquet.NewGenericWriter[T](&buf)
+	_, err := w.Write(rows)
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+func testSchema(primary ...string) Schema {
+	return func(table ident.Table) ([]types.ColData, error) {
+		var ret []types.ColData
+		for _, name := range primary {
+			ret = append(ret, types.ColData{Name: ident.New(name), Primary: true})
+		}
+		return append(ret, types.ColData{Name: ident.New("name")}), nil
+	}
+}
+
+func TestParquetDecode(t *testing.T) {
+	name := "a"
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	created := time.Date(2024, 1, 2, 3, 4, 5, 123456000, time.UTC)
+	content := writeParquet(t, []parquetRow{
+		{
+			PK:        1,
+			Name:      &name,
+			Price:     -12345,
+			Created:   created.UnixMicro(),
+			Day:       int32(day.Unix() / (24 * 60 * 60)),
+			Data:      []byte{1, 2},
+			Tags:      []string{"x", "y"},
+			EventType: "c",
+			Updated:   "10.0000000002",
+		},
+		{
+			PK:        2,
+			EventType: "d",
+			Updated:   "11.0000000000",
+		},
+		{
+			PK:        3,
+			Price:     5,
+			EventType: "u",
+			Updated:   "12.0000000000",
+		},
+	})
+	table := ident.NewTable(ident.MustSchema(ident.Public), ident.New("mytable"))
+	file := &File{Path: "file.parquet", Table: table}
+
+	tests := []struct {
+		name    string
+		content []byte
+		filters []types.MutationFilter
+		schema  Schema
+		want    []types.Mutation
+		wantErr string
+	}{
+		{
+			name:    "all",
+			content: content,
+			schema:  testSchema("pk"),
+			want: []types.Mutation{
+				{
+					Data: json.RawMessage(`{"created":"2024-01-02T03:04:05.123456Z","data":"\\x0102",
+						"day":"2024-01-01","name":"a","pk":1,"price":-123.45,"tags":["x","y"]}`),
+					Key:  json.RawMessage(`[1]`),
+					Time: hlc.New(10, 2),
+				},
+				{
+					Key:  json.RawMessage(`[2]`),
+					Time: hlc.New(11, 0),
+				},
+				{
+					Data: json.RawMessage(`{"created":"1970-01-01T00:00:00Z","data":"\\x",
+						"day":"1970-01-01","name":null,"pk":3,"price":0.05,"tags":[]}`),
+					Key:  json.RawMessage(`[3]`),
+					Time: hlc.New(12, 0),
+				},
+			},
+		},
+		{
+			name:    "filter",
+			content: content,
+			filters: []types.MutationFilter{
+				func(mut types.Mutation) bool {
+					return hlc.Compare(mut.Time, hlc.New(11, 0)) >= 0
+				},
+			},
+			schema: testSchema("pk"),
+			want: []types.Mutation{
+				{
+					Key:  json.RawMessage(`[2]`),
+					Time: hlc.New(11, 0),
+				},
+				{
+					Data: json.RawMessage(`{"created":"1970-01-01T00:00:00Z","data":"\\x",
+						"day":"1970-01-01","name":null,"pk":3,"price":0.05,"tags":[]}`),
+					Key:  json.RawMessage(`[3]`),
+					Time: hlc.New(12, 0),
+				},
+			},
+		},
+		{
+			name:    "missing key",
+			content: content,
+			schema:  testSchema("pk", "other"),
+			wantErr: "missing primary key columns",
+		},
+		{
+			name:    "missing schema",
+			content: content,
+			wantErr: "the target schema is not available",
+		},
+		{
+			name:    "missing updated",
+			content: writeParquet(t, []parquetRowNoUpdated{{PK: 1, EventType: "c"}}),
+			schema:  testSchema("pk"),
+			wantErr: "CREATE CHANGEFEED must specify the 'WITH updated' option",
+		},
+		{
+			name:    "not parquet",
+			content: []byte("hello"),
+			schema:  testSchema("pk"),
+			wantErr: "parquet",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := assert.New(t)
+			r := require.New(t)
+			decoder := &parquetDecoder{schema: tt.schema}
+			batch, err := decoder.Decode(file, bytes.NewReader(tt.content), tt.filters...)
+			if tt.wantErr != "" {
+				a.ErrorContains(err, tt.wantErr)
+				return
+			}
+			r.NoError(err)
+			var got []types.Mutation
+			for tbl, mut := range batch.Mutations() {
+				a.Equal(table, tbl)
+				got = append(got, mut)
+			}
+			r.Len(got, len(tt.want))
+			for i, want := range tt.want {
+				a.Equal(want.Time, got[i].Time)
+				a.JSONEq(string(want.Key), string(got[i].Key))
+				if want.Data == nil {
+					a.True(got[i].IsDelete())
+				} else {
+					a.JSONEq(string(want.Data), string(got[i].Data))
+				}
+			}
+		})
+	}
+}
+
+// TestParquetValue verifies the conversion of the logical types that
+// are not covered by TestParquetDecode.
+func TestParquetValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		node  parquet.Node
+		value parquet.Value
+		want  any
+	}{
+		{
+			name:  "uuid",
+			node:  parquet.UUID(),
+			value: parquet.FixedLenByteArrayValue([]byte{0x6b, 0xa7, 0xb8, 0x10, 0x9d, 0xad, 0x11, 0xd1, 0x80, 0xb4, 0x00, 0xc0, 0x4f, 0xd4, 0x30, 0xc8}),
+			want:  "6ba7b810-9dad-11d1-80b4-00c04fd430c8",
+		},
+		{
+			name:  "decimal bytes",
+			node:  parquet.Decimal(3, 20, parquet.FixedLenByteArrayType(4)),
+			value: parquet.FixedLenByteArrayValue([]byte{0xff, 0xff, 0xff, 0xfe}),
+			want:  json.Number("-0.002"),
+		},
+		{
+			name:  "decimal bytes positive",
+			node:  parquet.Decimal(2, 20, parquet.FixedLenByteArrayType(2)),
+			value: parquet.FixedLenByteArrayValue([]byte{0x01, 0x00}),
+			want:  json.Number("2.56"),
+		},
+		{
+			name:  "json",
+			node:  parquet.JSON(),
+			value: parquet.ByteArrayValue([]byte(`{"a":1}`)),
+			want:  json.RawMessage(`{"a":1}`),
+		},
+		{
+			name:  "string",
+			node:  parquet.String(),
+			value: parquet.ByteArrayValue([]byte("hello")),
+			want:  "hello",
+		},
+		{
+			name:  "enum",
+			node:  parquet.Enum(),
+			value: parquet.ByteArrayValue([]byte("red")),
+			want:  "red",
+		},
+		{
+			name:  "time",
+			node:  parquet.Time(parquet.Microsecond),
+			value: parquet.Int64Value((3*60*60 + 4*60 + 5) * 1_000_000),
+			want:  "03:04:05",
+		},
+		{
+			name:  "timestamp nanos",
+			node:  parquet.Timestamp(parquet.Nanosecond),
+			value: parquet.Int64Value(1_500_000_000),
+			want:  "1970-01-01T00:00:01.5Z",
+		},
+		{
+			name:  "unsigned",
+			node:  parquet.Uint(32),
+			value: parquet.Int32Value(-1),
+			want:  uint64(4294967295),
+		},
+		{
+			name:  "bool",
+			node:  parquet.Leaf(parquet.BooleanType),
+			value: parquet.BooleanValue(true),
+			want:  true,
+		},
+		{
+			name:  "double",
+			node:  parquet.Leaf(parquet.DoubleType),
+			value: parquet.DoubleValue(1.5),
+			want:  1.5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := require.New(t)
+			got, err := parquetValue(tt.node, tt.value)
+			r.NoError(err)
+			r.Equal(tt.want, got)
+		})
+	}
+}
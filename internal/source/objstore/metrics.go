@@ -45,9 +45,13 @@ var (
 		Help:    "the time spent in fetching resolved timestamps",
 		Buckets: metrics.LatencyBuckets,
 	}, bucketLabels)
+	notificationCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "objstore_notification_count",
+		Help: "the total number of object-created notifications received",
+	}, bucketLabels)
 	processDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
 		Name:    "objstore_process_duration_seconds",
-		Help:    "the time spent in processing one ndjson file",
+		Help:    "the time spent in processing one changefeed file",
 		Buckets: metrics.LatencyBuckets,
 	}, bucketLabels)
 	retryCount = promauto.NewCounterVec(prometheus.CounterOpts{
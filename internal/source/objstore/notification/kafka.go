@@ -0,0 +1,115 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package notification
+
+import (
+	"context"
+
+	"github.com/IBM/sarama"
+	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// KafkaConfig has the parameters used to receive events from a Kafka
+// topic.
+type KafkaConfig struct {
+	Brokers []string // The address of the Kafka brokers.
+	Group   string   // The Kafka consumer group id.
+	Topic   string   // The topic containing the events.
+}
+
+type kafkaSource struct {
+	config       *KafkaConfig
+	saramaConfig *sarama.Config
+}
+
+var _ Source = &kafkaSource{}
+
+// newKafka returns a Source backed by a Kafka topic.
+func newKafka(config *KafkaConfig) (*kafkaSource, error) {
+	sc := sarama.NewConfig()
+	// Objects created before we started will be found by listing the
+	// bucket.
+	sc.Consumer.Offsets.Initial = sarama.OffsetNewest
+	if err := sc.Validate(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &kafkaSource{
+		config:       config,
+		saramaConfig: sc,
+	}, nil
+}
+
+// Run implements Source.
+func (k *kafkaSource) Run(
+	ctx *stopper.Context, fn func(ctx context.Context, events []Event) error,
+) error {
+	group, err := sarama.NewConsumerGroup(k.config.Brokers, k.config.Group, k.saramaConfig)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer group.Close()
+	handler := &kafkaHandler{fn: fn}
+	for !ctx.IsStopping() {
+		if err := group.Consume(ctx, []string{k.config.Topic}, handler); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	return nil
+}
+
+// kafkaHandler delivers the events contained in the messages.
+type kafkaHandler struct {
+	fn func(ctx context.Context, events []Event) error
+}
+
+var _ sarama.ConsumerGroupHandler = &kafkaHandler{}
+
+// Setup implements sarama.ConsumerGroupHandler.
+func (h *kafkaHandler) Setup(sarama.ConsumerGroupSession) error { return nil }
+
+// Cleanup implements sarama.ConsumerGroupHandler.
+func (h *kafkaHandler) Cleanup(sarama.ConsumerGroupSession) error { return nil }
+
+// ConsumeClaim implements sarama.ConsumerGroupHandler.
+func (h *kafkaHandler) ConsumeClaim(
+	session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim,
+) error {
+	ctx := session.Context()
+	for {
+		select {
+		case msg, ok := <-claim.Messages():
+			if !ok {
+				return nil
+			}
+			events, err := ParseS3Event(msg.Value)
+			if err != nil {
+				// Retrying won't help, so skip the message.
+				log.WithError(err).Warnf("ignoring malformed notification at %s@%d offset=%d",
+					msg.Topic, msg.Partition, msg.Offset)
+			} else if len(events) > 0 {
+				if err := h.fn(ctx, events); err != nil {
+					return err
+				}
+			}
+			session.MarkMessage(msg, "")
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
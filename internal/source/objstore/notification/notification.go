@@ -0,0 +1,143 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package notification receives object-created events, so that new
+// objects in a bucket can be discovered without listing the bucket.
+// Events are expected to be in the format of S3 event notifications,
+// optionally wrapped in an SNS envelope, and may be delivered by an SQS
+// queue or a Kafka topic.
+package notification
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/pkg/errors"
+)
+
+// An Event identifies an object that was created in a bucket.
+type Event struct {
+	Bucket string // The name of the bucket.
+	Key    string // The name of the object within the bucket.
+}
+
+// A Source delivers object-created events.
+type Source interface {
+	// Run delivers events to the callback until the context is
+	// stopped. Events are acknowledged once the callback returns
+	// without an error. Delivery is at-least-once and events may be
+	// delivered out of order.
+	Run(ctx *stopper.Context, fn func(ctx context.Context, events []Event) error) error
+}
+
+// Config identifies the source of the events. Exactly one of the
+// fields will be set.
+type Config struct {
+	Kafka *KafkaConfig
+	SQS   *SQSConfig
+}
+
+// ParseURL returns the configuration for a source of events:
+//
+//	sqs://sqs.us-east-1.amazonaws.com/123456789012/queue
+//	kafka://broker1:9092,broker2:9092/topic?group=replicator
+//
+// An SQS queue may be accessed through an alternate endpoint, such as
+// an emulator, by adding the AWS_ENDPOINT parameter. Credentials are
+// taken from the AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, and
+// AWS_SESSION_TOKEN parameters, or from the default credential chain.
+func ParseURL(raw string) (*Config, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	params := u.Query()
+	switch u.Scheme {
+	case "sqs":
+		path := strings.Trim(u.Path, "/")
+		if u.Host == "" || path == "" {
+			return nil, errors.New("notification URL must be sqs://host/account/queue")
+		}
+		cfg := &SQSConfig{
+			AccessKey:    paramValue(params, "AWS_ACCESS_KEY_ID"),
+			Endpoint:     paramValue(params, "AWS_ENDPOINT"),
+			Region:       paramValue(params, "AWS_REGION"),
+			SecretKey:    paramValue(params, "AWS_SECRET_ACCESS_KEY"),
+			SessionToken: paramValue(params, "AWS_SESSION_TOKEN"),
+		}
+		queue := &url.URL{Scheme: "https", Host: u.Host, Path: "/" + path}
+		if cfg.Endpoint != "" {
+			endpoint, err := url.Parse(cfg.Endpoint)
+			if err != nil {
+				return nil, errors.Wrap(err, "invalid AWS_ENDPOINT")
+			}
+			queue.Scheme, queue.Host = endpoint.Scheme, endpoint.Host
+		}
+		cfg.QueueURL = queue.String()
+		if cfg.Region == "" {
+			// E.g. sqs.us-east-1.amazonaws.com
+			parts := strings.Split(u.Hostname(), ".")
+			if len(parts) < 3 || parts[0] != "sqs" {
+				return nil, errors.New("AWS_REGION must be specified")
+			}
+			cfg.Region = parts[1]
+		}
+		return &Config{SQS: cfg}, nil
+
+	case "kafka":
+		topic := strings.Trim(u.Path, "/")
+		if u.Host == "" || topic == "" {
+			return nil, errors.New("notification URL must be kafka://broker[,broker]/topic")
+		}
+		cfg := &KafkaConfig{
+			Brokers: strings.Split(u.Host, ","),
+			Group:   params.Get("group"),
+			Topic:   topic,
+		}
+		if cfg.Group == "" {
+			return nil, errors.New("the group parameter must be specified")
+		}
+		return &Config{Kafka: cfg}, nil
+
+	default:
+		return nil, errors.Errorf("unknown notification scheme %q", u.Scheme)
+	}
+}
+
+// New returns a source of events.
+func New(ctx context.Context, config *Config) (Source, error) {
+	switch {
+	case config.Kafka != nil:
+		return newKafka(config.Kafka)
+	case config.SQS != nil:
+		return newSQS(ctx, config.SQS)
+	default:
+		return nil, errors.New("invalid configuration. Missing notification source")
+	}
+}
+
+// paramValue gets the value for the specified parameter from the URL.
+// If not present in the URL, it retrieves a value from the environment.
+func paramValue(params url.Values, key string) string {
+	value := params.Get(key)
+	if value != "" {
+		return value
+	}
+	return os.Getenv(key)
+}
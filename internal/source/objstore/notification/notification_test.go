@@ -0,0 +1,260 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const s3Event = `{
+  "Records": [
+    {
+      "eventName": "ObjectCreated:Put",
+      "s3": {
+        "bucket": { "name": "bucket" },
+        "object": { "key": "dir/2024-01-01/202401010101.ndjson" }
+      }
+    },
+    {
+      "eventName": "ObjectRemoved:Delete",
+      "s3": {
+        "bucket": { "name": "bucket" },
+        "object": { "key": "dir/removed.ndjson" }
+      }
+    },
+    {
+      "eventName": "ObjectCreated:CompleteMultipartUpload",
+      "s3": {
+        "bucket": { "name": "bucket" },
+        "object": { "key": "dir/2024-01-01/202401010102.0.RESOLVED%3Fx+y" }
+      }
+    }
+  ]
+}`
+
+var s3Expected = []Event{
+	{Bucket: "bucket", Key: "dir/2024-01-01/202401010101.ndjson"},
+	{Bucket: "bucket", Key: "dir/2024-01-01/202401010102.0.RESOLVED?x y"},
+}
+
+func TestParseS3Event(t *testing.T) {
+	sns, err := json.Marshal(map[string]string{
+		"Type":    "Notification",
+		"Message": s3Event,
+	})
+	require.NoError(t, err)
+	tcs := []struct {
+		name    string
+		body    string
+		want    []Event
+		wantErr string
+	}{
+		{
+			name: "records",
+			body: s3Event,
+			want: s3Expected,
+		},
+		{
+			name: "sns",
+			body: string(sns),
+			want: s3Expected,
+		},
+		{
+			name: "test event",
+			body: `{"Service":"Amazon S3","Event":"s3:TestEvent","Bucket":"bucket"}`,
+		},
+		{
+			name:    "malformed",
+			body:    `{"Records": 1}`,
+			wantErr: "could not decode event",
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			a := assert.New(t)
+			got, err := ParseS3Event([]byte(tc.body))
+			if tc.wantErr != "" {
+				a.ErrorContains(err, tc.wantErr)
+				return
+			}
+			a.NoError(err)
+			a.Equal(tc.want, got)
+		})
+	}
+}
+
+func TestParseURL(t *testing.T) {
+	tcs := []struct {
+		name    string
+		url     string
+		want    *Config
+		wantErr string
+	}{
+		{
+			name: "sqs",
+			url:  "sqs://sqs.us-east-2.amazonaws.com/123456789012/queue",
+			want: &Config{SQS: &SQSConfig{
+				QueueURL: "https://sqs.us-east-2.amazonaws.com/123456789012/queue",
+				Region:   "us-east-2",
+			}},
+		},
+		{
+			name: "sqs endpoint",
+			url: "sqs://localhost/000000000000/queue?AWS_ENDPOINT=http://localhost:4566" +
+				"&AWS_REGION=us-east-1&AWS_ACCESS_KEY_ID=key&AWS_SECRET_ACCESS_KEY=secret",
+			want: &Config{SQS: &SQSConfig{
+				AccessKey: "key",
+				Endpoint:  "http://localhost:4566",
+				QueueURL:  "http://localhost:4566/000000000000/queue",
+				Region:    "us-east-1",
+				SecretKey: "secret",
+			}},
+		},
+		{
+			name:    "sqs no region",
+			url:     "sqs://localhost/000000000000/queue",
+			wantErr: "AWS_REGION must be specified",
+		},
+		{
+			name:    "sqs no queue",
+			url:     "sqs://sqs.us-east-2.amazonaws.com",
+			wantErr: "notification URL must be sqs://host/account/queue",
+		},
+		{
+			name: "kafka",
+			url:  "kafka://broker1:9092,broker2:9092/topic?group=replicator",
+			want: &Config{Kafka: &KafkaConfig{
+				Brokers: []string{"broker1:9092", "broker2:9092"},
+				Group:   "replicator",
+				Topic:   "topic",
+			}},
+		},
+		{
+			name:    "kafka no group",
+			url:     "kafka://broker1:9092/topic",
+			wantErr: "the group parameter must be specified",
+		},
+		{
+			name:    "unknown",
+			url:     "http://localhost/queue",
+			wantErr: `unknown notification scheme "http"`,
+		},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			a := assert.New(t)
+			got, err := ParseURL(tc.url)
+			if tc.wantErr != "" {
+				a.ErrorContains(err, tc.wantErr)
+				return
+			}
+			a.NoError(err)
+			a.Equal(tc.want, got)
+		})
+	}
+}
+
+// mockSQS delivers a fixed set of messages.
+type mockSQS struct {
+	mu struct {
+		sync.Mutex
+		deleted  []string
+		messages []types.Message
+	}
+}
+
+var _ sqsAccess = &mockSQS{}
+
+// DeleteMessageBatch implements sqsAccess.
+func (m *mockSQS) DeleteMessageBatch(
+	_ context.Context, params *sqs.DeleteMessageBatchInput, _ ...func(*sqs.Options),
+) (*sqs.DeleteMessageBatchOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, entry := range params.Entries {
+		m.mu.deleted = append(m.mu.deleted, aws.ToString(entry.ReceiptHandle))
+	}
+	return &sqs.DeleteMessageBatchOutput{}, nil
+}
+
+// ReceiveMessage implements sqsAccess.
+func (m *mockSQS) ReceiveMessage(
+	ctx context.Context, params *sqs.ReceiveMessageInput, _ ...func(*sqs.Options),
+) (*sqs.ReceiveMessageOutput, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.mu.messages) == 0 {
+		// Simulate long polling.
+		select {
+		case <-ctx.Done():
+		case <-time.After(10 * time.Millisecond):
+		}
+		return &sqs.ReceiveMessageOutput{}, nil
+	}
+	count := min(len(m.mu.messages), int(params.MaxNumberOfMessages))
+	ret := m.mu.messages[:count]
+	m.mu.messages = m.mu.messages[count:]
+	return &sqs.ReceiveMessageOutput{Messages: ret}, nil
+}
+
+func (m *mockSQS) getDeleted() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]string(nil), m.mu.deleted...)
+}
+
+// TestSQS verifies that events are delivered and messages are
+// acknowledged, including the malformed ones.
+func TestSQS(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	stop := stopper.WithContext(ctx)
+
+	mock := &mockSQS{}
+	mock.mu.messages = []types.Message{
+		{Body: aws.String(s3Event), MessageId: aws.String("1"), ReceiptHandle: aws.String("r1")},
+		{Body: aws.String("garbage"), MessageId: aws.String("2"), ReceiptHandle: aws.String("r2")},
+	}
+	source := &sqsSource{client: mock, queueURL: "queue"}
+
+	received := make(chan []Event, 1)
+	stop.Go(func(ctx *stopper.Context) error {
+		return source.Run(ctx, func(_ context.Context, events []Event) error {
+			received <- events
+			return nil
+		})
+	})
+	r.Equal(s3Expected, <-received)
+	r.Eventually(func() bool {
+		return len(mock.getDeleted()) == 2
+	}, 10*time.Second, 10*time.Millisecond)
+	r.Equal([]string{"r1", "r2"}, mock.getDeleted())
+	stop.Stop(time.Second)
+	r.NoError(stop.Wait())
+}
@@ -0,0 +1,78 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package notification
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// s3Message is the union of the messages that we may receive: an S3
+// event notification, a test event sent when notifications are
+// configured, or an SNS envelope containing either.
+type s3Message struct {
+	// S3 event notification.
+	Records []struct {
+		EventName string `json:"eventName"`
+		S3        struct {
+			Bucket struct {
+				Name string `json:"name"`
+			} `json:"bucket"`
+			Object struct {
+				Key string `json:"key"`
+			} `json:"object"`
+		} `json:"s3"`
+	} `json:"Records"`
+
+	// S3 test event.
+	Event string `json:"Event"`
+
+	// SNS envelope.
+	Type    string `json:"Type"`
+	Message string `json:"Message"`
+}
+
+// ParseS3Event extracts the object-created events from an S3 event
+// notification. Other kinds of events are ignored.
+func ParseS3Event(body []byte) ([]Event, error) {
+	var msg s3Message
+	if err := json.Unmarshal(body, &msg); err != nil {
+		return nil, errors.Wrap(err, "could not decode event")
+	}
+	if msg.Type == "Notification" && msg.Message != "" {
+		return ParseS3Event([]byte(msg.Message))
+	}
+	var ret []Event
+	for _, record := range msg.Records {
+		if !strings.HasPrefix(record.EventName, "ObjectCreated:") {
+			continue
+		}
+		// Object keys are URL-encoded.
+		key, err := url.QueryUnescape(record.S3.Object.Key)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid key %q", record.S3.Object.Key)
+		}
+		ret = append(ret, Event{
+			Bucket: record.S3.Bucket.Name,
+			Key:    key,
+		})
+	}
+	return ret, nil
+}
@@ -0,0 +1,151 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package notification
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// The maximum values allowed by SQS.
+const (
+	sqsMaxMessages = 10
+	sqsWaitSeconds = 20
+)
+
+// SQSConfig has the parameters used to receive events from an SQS queue.
+type SQSConfig struct {
+	AccessKey    string // AWS Access Key.
+	Endpoint     string // Alternative server to use, e.g. an emulator.
+	QueueURL     string // The URL of the queue.
+	Region       string // The region of the queue.
+	SecretKey    string // Secret associated to the Access Key.
+	SessionToken string // Session token.
+}
+
+// sqsAccess defines the functions we are using to interact with the AWS
+// SDK. Mainly used for testing to implement a mock component.
+type sqsAccess interface {
+	// DeleteMessageBatch acknowledges messages.
+	DeleteMessageBatch(
+		ctx context.Context, params *sqs.DeleteMessageBatchInput, optFns ...func(*sqs.Options),
+	) (*sqs.DeleteMessageBatchOutput, error)
+	// ReceiveMessage retrieves messages, using long polling.
+	ReceiveMessage(
+		ctx context.Context, params *sqs.ReceiveMessageInput, optFns ...func(*sqs.Options),
+	) (*sqs.ReceiveMessageOutput, error)
+}
+
+type sqsSource struct {
+	client   sqsAccess
+	queueURL string
+}
+
+var _ Source = &sqsSource{}
+
+// newSQS returns a Source backed by an SQS queue.
+func newSQS(ctx context.Context, config *SQSConfig) (*sqsSource, error) {
+	opts := []func(*awsconfig.LoadOptions) error{
+		awsconfig.WithRegion(config.Region),
+	}
+	if config.AccessKey != "" {
+		opts = append(opts, awsconfig.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(
+				config.AccessKey, config.SecretKey, config.SessionToken)))
+	}
+	awsConfig, err := awsconfig.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	client := sqs.NewFromConfig(awsConfig, func(o *sqs.Options) {
+		if config.Endpoint != "" {
+			o.BaseEndpoint = aws.String(config.Endpoint)
+		}
+	})
+	return &sqsSource{
+		client:   client,
+		queueURL: config.QueueURL,
+	}, nil
+}
+
+// Run implements Source.
+func (s *sqsSource) Run(
+	ctx *stopper.Context, fn func(ctx context.Context, events []Event) error,
+) error {
+	for !ctx.IsStopping() {
+		out, err := s.client.ReceiveMessage(ctx, &sqs.ReceiveMessageInput{
+			QueueUrl:            aws.String(s.queueURL),
+			MaxNumberOfMessages: sqsMaxMessages,
+			WaitTimeSeconds:     sqsWaitSeconds,
+		})
+		if err != nil {
+			if ctx.IsStopping() {
+				return nil
+			}
+			log.WithError(err).Warnf("could not receive notifications from %s; will retry", s.queueURL)
+			select {
+			case <-ctx.Stopping():
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+		var events []Event
+		entries := make([]types.DeleteMessageBatchRequestEntry, 0, len(out.Messages))
+		for idx, msg := range out.Messages {
+			parsed, err := ParseS3Event([]byte(aws.ToString(msg.Body)))
+			if err != nil {
+				// Retrying won't help, so drop the message.
+				log.WithError(err).Warnf("ignoring malformed notification %s", aws.ToString(msg.MessageId))
+			}
+			events = append(events, parsed...)
+			entries = append(entries, types.DeleteMessageBatchRequestEntry{
+				Id:            aws.String(strconv.Itoa(idx)),
+				ReceiptHandle: msg.ReceiptHandle,
+			})
+		}
+		if len(events) > 0 {
+			if err := fn(ctx, events); err != nil {
+				return err
+			}
+		}
+		if len(entries) == 0 {
+			continue
+		}
+		// Messages that are not deleted will be redelivered, which is
+		// harmless.
+		res, err := s.client.DeleteMessageBatch(ctx, &sqs.DeleteMessageBatchInput{
+			Entries:  entries,
+			QueueUrl: aws.String(s.queueURL),
+		})
+		if err != nil {
+			log.WithError(err).Warnf("could not acknowledge notifications from %s", s.queueURL)
+		} else if len(res.Failed) > 0 {
+			log.Warnf("could not acknowledge %d notifications from %s", len(res.Failed), s.queueURL)
+		}
+	}
+	return nil
+}
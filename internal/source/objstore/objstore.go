@@ -19,20 +19,38 @@
 package objstore
 
 import (
+	"net/http"
+
+	"github.com/cockroachdb/replicator/internal/conveyor"
+	"github.com/cockroachdb/replicator/internal/types"
 	"github.com/cockroachdb/replicator/internal/util/diag"
 	"github.com/cockroachdb/replicator/internal/util/stdlogical"
 )
 
 // Objstore is a logical replication loop that uses cloud storage.
 type Objstore struct {
-	Conn        *Conn
-	Diagnostics *diag.Diagnostics
+	Authenticator types.Authenticator // Nil if there is no staging database.
+	Conn          *Conn
+	Conveyors     *conveyor.Conveyors
+	Diagnostics   *diag.Diagnostics
 }
 
 var (
-	_ stdlogical.HasDiagnostics = (*Objstore)(nil)
+	_ stdlogical.HasAdmin              = (*Objstore)(nil)
+	_ stdlogical.HasAdminAuthenticator = (*Objstore)(nil)
+	_ stdlogical.HasDiagnostics        = (*Objstore)(nil)
 )
 
+// GetAdminHandler implements [stdlogical.HasAdmin].
+func (k *Objstore) GetAdminHandler(auth types.Authenticator) http.Handler {
+	return k.Conveyors.AdminHandler(auth)
+}
+
+// GetAdminAuthenticator implements [stdlogical.HasAdminAuthenticator].
+func (k *Objstore) GetAdminAuthenticator() types.Authenticator {
+	return k.Authenticator
+}
+
 // GetDiagnostics implements [stdlogical.HasDiagnostics].
 func (k *Objstore) GetDiagnostics() *diag.Diagnostics {
 	return k.Diagnostics
@@ -17,22 +17,31 @@
 package objstore
 
 import (
+	"context"
+
 	"github.com/cockroachdb/field-eng-powertools/stopper"
 	"github.com/cockroachdb/replicator/internal/conveyor"
 	"github.com/cockroachdb/replicator/internal/script"
 	"github.com/cockroachdb/replicator/internal/source/objstore/bucket"
 	"github.com/cockroachdb/replicator/internal/source/objstore/eventproc"
+	"github.com/cockroachdb/replicator/internal/source/objstore/format"
+	"github.com/cockroachdb/replicator/internal/source/objstore/notification"
+	"github.com/cockroachdb/replicator/internal/source/objstore/providers/azure"
+	"github.com/cockroachdb/replicator/internal/source/objstore/providers/gcs"
 	"github.com/cockroachdb/replicator/internal/source/objstore/providers/local"
 	"github.com/cockroachdb/replicator/internal/source/objstore/providers/s3"
 	"github.com/cockroachdb/replicator/internal/types"
 	"github.com/cockroachdb/replicator/internal/util/cdcjson"
+	"github.com/cockroachdb/replicator/internal/util/diag"
 	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/cockroachdb/replicator/internal/util/stdserver"
 	"github.com/google/wire"
 	"github.com/pkg/errors"
 )
 
 // Set is used by Wire.
 var Set = wire.NewSet(
+	ProvideAuthenticator,
 	ProvideConn,
 	ProvideEagerConfig,
 )
@@ -44,6 +53,18 @@ func ProvideEagerConfig(cfg *Config, _ *script.Loader) *EagerConfig {
 	return (*EagerConfig)(cfg)
 }
 
+// ProvideAuthenticator is called by Wire to construct a JWT-based
+// authenticator for the administrative endpoints. It returns nil if
+// staging data is kept in an embedded store.
+func ProvideAuthenticator(
+	ctx *stopper.Context,
+	diags *diag.Diagnostics,
+	pool *types.StagingPool,
+	stagingDB ident.StagingSchema,
+) (types.Authenticator, error) {
+	return stdserver.StagingAuthenticator(ctx, diags, pool, stagingDB)
+}
+
 // ProvideConn is called by Wire to construct this package's
 // logical.Dialect implementation. There's a fake dependency on
 // the script loader so that flags can be evaluated first.
@@ -68,7 +89,7 @@ func ProvideConn(
 		return nil, err
 	}
 
-	bucket, err := newBucket(config)
+	bucket, err := newBucket(ctx, config)
 	if err != nil {
 		return nil, err
 	}
@@ -76,16 +97,38 @@ func ProvideConn(
 	if err != nil {
 		return nil, err
 	}
-	processor := eventproc.NewLocal(conveyor, bucket, parser, config.TargetSchema)
+	// Formats that don't encode the primary key separately rely on the
+	// schema of the target table.
+	schema := func(table ident.Table) ([]types.ColData, error) {
+		columns, ok := conveyor.Watcher().Get().Columns.Get(table)
+		if !ok {
+			return nil, errors.Errorf("table %s not found", table)
+		}
+		return columns, nil
+	}
+	decoders := format.New(parser, schema)
+	processor := eventproc.NewLocal(conveyor, bucket, decoders, config.TargetSchema)
+
+	var notifications notification.Source
+	var discovery *discovery
+	if config.notification != nil {
+		if notifications, err = notification.New(ctx, config.notification); err != nil {
+			return nil, err
+		}
+		discovery = newDiscovery(config.bucketName, config.prefix)
+	}
 
 	conn := &Conn{
-		bucket:      bucket,
-		config:      config,
-		conveyor:    conveyor,
-		leases:      leases,
-		parser:      parser,
-		processor:   processor,
-		stagingPool: stagingPool,
+		bucket:    bucket,
+		config:    config,
+		conveyor:  conveyor,
+		discovery: discovery,
+		leases:    leases,
+
+		notifications: notifications,
+		parser:        parser,
+		processor:     processor,
+		stagingPool:   stagingPool,
 		state: state{
 			memo: memo,
 			key:  config.identifier,
@@ -94,12 +137,16 @@ func ProvideConn(
 	return (*Conn)(conn), conn.Start(ctx)
 }
 
-func newBucket(config *Config) (bucket.Bucket, error) {
+func newBucket(ctx context.Context, config *Config) (bucket.Bucket, error) {
 	switch {
 	case config.local != nil:
 		return local.New(config.local)
 	case config.s3 != nil:
 		return s3.New(config.s3)
+	case config.gcs != nil:
+		return gcs.New(ctx, config.gcs)
+	case config.azure != nil:
+		return azure.New(config.azure)
 	default:
 		return nil, errors.Errorf("invalid configuration. Missing bucket specification")
 	}
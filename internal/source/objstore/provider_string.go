@@ -11,11 +11,13 @@ func _() {
 	_ = x[UnknownStorage-0]
 	_ = x[LocalStorage-1]
 	_ = x[S3Storage-2]
+	_ = x[GCSStorage-3]
+	_ = x[AzureStorage-4]
 }
 
-const _Provider_name = "UnknownStorageLocalStorageS3Storage"
+const _Provider_name = "UnknownStorageLocalStorageS3StorageGCSStorageAzureStorage"
 
-var _Provider_index = [...]uint8{0, 14, 26, 35}
+var _Provider_index = [...]uint8{0, 14, 26, 35, 45, 57}
 
 func (i Provider) String() string {
 	if i < 0 || i >= Provider(len(_Provider_index)-1) {
@@ -0,0 +1,191 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package azure provides access to Azure Blob Storage containers. This
+// is not a generic abstract layer, but it rather focuses on accessing
+// CockroachDB changefeed events stored in a container.
+package azure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/replicator/internal/source/objstore/bucket"
+)
+
+const (
+	// Delimiter is the folder delimiter used. Blob storage has a flat
+	// namespace, but blob names may contain "/" to create a virtual
+	// hierarchy.
+	Delimiter = "/"
+)
+
+var (
+	// RetriableErrors identifies errors that are transient. The
+	// operation causing the error may be retried.
+	RetriableErrors = []int{
+		http.StatusBadGateway,
+		http.StatusGatewayTimeout,
+		http.StatusInternalServerError,
+		http.StatusServiceUnavailable,
+		http.StatusTooManyRequests,
+	}
+)
+
+// Config has the parameters used to connect to Azure Blob Storage.
+type Config struct {
+	AccountKey  string // Shared key; if empty, requests are anonymous.
+	AccountName string // The storage account.
+	Container   string // The name of the container.
+	// Alternative service URL, for testing against an emulator such
+	// as Azurite (e.g. http://127.0.0.1:10000/devstoreaccount1). The
+	// default is derived from the account name.
+	Endpoint string
+}
+
+// azureAccess defines the functions we are using to interact with the
+// Azure SDK. Mainly used for testing to implement a mock component.
+type azureAccess interface {
+	// Download returns the content of the named blob.
+	Download(ctx context.Context, containerName string, blobName string) (io.ReadCloser, error)
+	// List calls f for each blob with the given prefix, in lexical
+	// order.
+	List(ctx context.Context, containerName string, prefix string, f func(name string) error) error
+}
+
+// New returns a bucket reader backed by an Azure Blob Storage container.
+func New(config *Config) (bucket.Bucket, error) {
+	endpoint := config.Endpoint
+	if endpoint == "" {
+		if config.AccountName == "" {
+			return nil, errors.New("missing account name")
+		}
+		endpoint = fmt.Sprintf("https://%s.blob.core.windows.net/", config.AccountName)
+	}
+	var azureClient *azblob.Client
+	var err error
+	if config.AccountKey != "" {
+		cred, credErr := azblob.NewSharedKeyCredential(config.AccountName, config.AccountKey)
+		if credErr != nil {
+			return nil, credErr
+		}
+		azureClient, err = azblob.NewClientWithSharedKeyCredential(endpoint, cred, nil)
+	} else {
+		// The endpoint may contain a SAS token.
+		azureClient, err = azblob.NewClientWithNoCredential(endpoint, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &azureBucket{
+		client:    &client{ref: azureClient},
+		container: config.Container,
+	}, nil
+}
+
+type azureBucket struct {
+	client    azureAccess
+	container string
+}
+
+var _ bucket.Bucket = &azureBucket{}
+
+// Walk implements bucket.Bucket
+func (b *azureBucket) Walk(
+	ctx *stopper.Context,
+	dir string,
+	options *bucket.WalkOptions,
+	f func(*stopper.Context, string) error,
+) error {
+	// Ensure the object name actually ends with a dir suffix. Otherwise we'll just iterate the
+	// object itself as one prefix item.
+	if dir != "" {
+		dir = strings.TrimSuffix(dir, Delimiter) + Delimiter
+	}
+	// The service does not support starting a listing after a given
+	// name, so we filter the entries here.
+	after := strings.TrimPrefix(options.StartAfter, b.container+Delimiter)
+	count := 0
+	lastFolder := ""
+	err := b.client.List(ctx, b.container, dir, func(key string) error {
+		if key == dir {
+			return nil
+		}
+		if !options.Recursive {
+			// Collapse nested entries into their folder.
+			if idx := strings.Index(key[len(dir):], Delimiter); idx >= 0 {
+				key = key[:len(dir)+idx+1]
+				if key == lastFolder {
+					return nil
+				}
+				lastFolder = key
+			}
+		}
+		if after != "" && key <= after {
+			return nil
+		}
+		if err := f(ctx, key); err != nil {
+			return err
+		}
+		count++
+		if options.Limit > 0 && count >= options.Limit {
+			return bucket.ErrSkipAll
+		}
+		return nil
+	})
+	if errors.Is(err, bucket.ErrSkipAll) {
+		return nil
+	}
+	return mapError(err)
+}
+
+// Open implements bucket.Bucket
+func (b *azureBucket) Open(ctx *stopper.Context, file string) (io.ReadCloser, error) {
+	file = strings.TrimPrefix(file, b.container+Delimiter)
+	r, err := b.client.Download(ctx, b.container, file)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	return r, nil
+}
+
+// mapError converts the errors returned by the SDK into the errors
+// defined by the bucket package.
+func mapError(err error) error {
+	if err == nil {
+		return nil
+	}
+	switch {
+	case bloberror.HasCode(err, bloberror.BlobNotFound):
+		return errors.Join(bucket.ErrNoSuchKey, err)
+	case bloberror.HasCode(err, bloberror.ContainerNotFound):
+		return errors.Join(bucket.ErrNoSuchBucket, err)
+	}
+	var respErr *azcore.ResponseError
+	if errors.As(err, &respErr) && slices.Contains(RetriableErrors, respErr.StatusCode) {
+		return errors.Join(bucket.ErrTransient, err)
+	}
+	return err
+}
@@ -0,0 +1,168 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package azure
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/replicator/internal/source/objstore/bucket"
+	"github.com/cockroachdb/replicator/internal/source/objstore/providers/storetest"
+	"github.com/stretchr/testify/require"
+)
+
+// mockAzure is in memory blob container.
+type mockAzure struct {
+	containerName string
+	files         sync.Map
+}
+
+var _ azureAccess = &mockAzure{}
+var _ storetest.Writer = &mockAzure{}
+
+// Download implements azureAccess.
+func (m *mockAzure) Download(
+	ctx context.Context, containerName string, blobName string,
+) (io.ReadCloser, error) {
+	if containerName != m.containerName {
+		return nil, bucket.ErrNoSuchBucket
+	}
+	file, ok := m.files.Load(blobName)
+	if !ok {
+		return nil, bucket.ErrNoSuchKey
+	}
+	return io.NopCloser(bytes.NewReader(file.([]byte))), nil
+}
+
+// List implements azureAccess.
+func (m *mockAzure) List(
+	ctx context.Context, containerName string, prefix string, f func(name string) error,
+) error {
+	if containerName != m.containerName {
+		return bucket.ErrNoSuchBucket
+	}
+	files := make([]string, 0, 10)
+	m.files.Range(func(key any, value any) bool {
+		files = append(files, key.(string))
+		return true
+	})
+	sort.Strings(files)
+	for _, file := range files {
+		if !strings.HasPrefix(file, prefix) {
+			continue
+		}
+		if err := f(file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Store implements storetest.Writer.
+func (m *mockAzure) Store(ctx context.Context, name string, buf []byte) error {
+	m.files.Store(name, buf)
+	return nil
+}
+
+func TestOpen(t *testing.T) {
+	suite(t).Open(t)
+}
+
+func TestOverwrite(t *testing.T) {
+	suite(t).Overwrite(t)
+}
+
+func TestWalk(t *testing.T) {
+	suite(t).Walk(t)
+}
+
+func TestWalkWithSkipAll(t *testing.T) {
+	suite(t).WalkWithSkipAll(t)
+}
+
+// suite returns a test suite backed by a mock, or by an emulator such
+// as Azurite if the AZURE_STORAGE_CONNECTION_STRING environment
+// variable is set. The container must already exist in the emulator.
+func suite(t *testing.T) *storetest.Suite {
+	if conn := os.Getenv("AZURE_STORAGE_CONNECTION_STRING"); conn != "" {
+		azureClient, err := azblob.NewClientFromConnectionString(conn, nil)
+		require.NoError(t, err)
+		return &storetest.Suite{
+			Reader: &azureBucket{
+				client:    &client{ref: azureClient},
+				container: "test",
+			},
+			Writer: &emulatorWriter{client: azureClient, container: "test"},
+		}
+	}
+	mockAzure := &mockAzure{
+		containerName: "test",
+	}
+	return &storetest.Suite{
+		Reader: &azureBucket{
+			client:    mockAzure,
+			container: "test",
+		},
+		Writer: mockAzure,
+	}
+}
+
+// emulatorWriter stores blobs in an emulated container.
+type emulatorWriter struct {
+	client    *azblob.Client
+	container string
+}
+
+// Store implements storetest.Writer.
+func (w *emulatorWriter) Store(ctx context.Context, name string, buf []byte) error {
+	_, err := w.client.UploadBuffer(ctx, w.container, name, buf, nil)
+	return err
+}
+
+// TestWalkFolders verifies that nested blobs are reported as folders
+// if the walk is not recursive.
+func TestWalkFolders(t *testing.T) {
+	r := require.New(t)
+	s := suite(t)
+	ctx := stopper.WithContext(context.Background())
+	for _, name := range []string{"a.txt", "b/000.txt", "b/001.txt", "c/d/000.txt", "e.txt"} {
+		r.NoError(s.Writer.Store(ctx, name, []byte(name)))
+	}
+	var got []string
+	r.NoError(s.Reader.Walk(ctx, "", &bucket.WalkOptions{StartAfter: "a.txt"},
+		func(_ *stopper.Context, name string) error {
+			got = append(got, name)
+			return nil
+		}))
+	r.Equal([]string{"b/", "c/", "e.txt"}, got)
+
+	got = nil
+	r.NoError(s.Reader.Walk(ctx, "c", &bucket.WalkOptions{},
+		func(_ *stopper.Context, name string) error {
+			got = append(got, name)
+			return nil
+		}))
+	r.Equal([]string{"c/d/"}, got)
+}
@@ -0,0 +1,71 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package azure
+
+import (
+	"context"
+	"io"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+)
+
+// client wraps a *azblob.Client so we can hide the paging of blob
+// listings and the structure of the download response. This simplifies
+// testing.
+type client struct {
+	ref *azblob.Client
+}
+
+var _ azureAccess = &client{}
+
+// Download implements azureAccess.
+func (c *client) Download(
+	ctx context.Context, containerName string, blobName string,
+) (io.ReadCloser, error) {
+	resp, err := c.ref.DownloadStream(ctx, containerName, blobName, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// List implements azureAccess.
+func (c *client) List(
+	ctx context.Context, containerName string, prefix string, f func(name string) error,
+) error {
+	pager := c.ref.NewListBlobsFlatPager(containerName, &azblob.ListBlobsFlatOptions{
+		Prefix: &prefix,
+	})
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		if page.Segment == nil {
+			continue
+		}
+		for _, item := range page.Segment.BlobItems {
+			if item.Name == nil {
+				continue
+			}
+			if err := f(*item.Name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package gcs
+
+import (
+	"context"
+	"io"
+
+	"cloud.google.com/go/storage"
+)
+
+// client wraps a *storage.Client so we can access objects by bucket
+// and object name, rather than through handles. This simplifies
+// testing.
+type client struct {
+	ref *storage.Client
+}
+
+var _ gcsAccess = &client{}
+
+// NewReader implements gcsAccess.
+func (c *client) NewReader(
+	ctx context.Context, bucketName string, objectName string,
+) (io.ReadCloser, error) {
+	return c.ref.Bucket(bucketName).Object(objectName).NewReader(ctx)
+}
+
+// Objects implements gcsAccess.
+func (c *client) Objects(
+	ctx context.Context, bucketName string, query *storage.Query,
+) objectIterator {
+	return c.ref.Bucket(bucketName).Objects(ctx, query)
+}
@@ -0,0 +1,188 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package gcs provides access to Google Cloud Storage buckets. This is
+// not a generic abstract layer, but it rather focuses on accessing
+// CockroachDB changefeed events stored in a GCS bucket.
+package gcs
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/replicator/internal/source/objstore/bucket"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+)
+
+const (
+	// Delimiter is the folder delimiter used. GCS has a flat namespace,
+	// but it supports listing objects as if they were organized in
+	// a hierarchy of folders.
+	Delimiter = "/"
+)
+
+var (
+	// RetriableErrors identifies errors that are transient. The
+	// operation causing the error may be retried.
+	RetriableErrors = []int{
+		http.StatusBadGateway,
+		http.StatusGatewayTimeout,
+		http.StatusInternalServerError,
+		http.StatusServiceUnavailable,
+		http.StatusTooManyRequests,
+	}
+)
+
+// Config has the parameters used to connect to GCS.
+type Config struct {
+	Bucket      string // The name of the bucket.
+	Credentials []byte // A service account key, in JSON format.
+	// Alternative server to use, for testing against an emulator
+	// (e.g. http://localhost:4443/storage/v1/). If set, requests are
+	// not authenticated.
+	Endpoint string
+}
+
+// objectIterator is implemented by *storage.ObjectIterator.
+type objectIterator interface {
+	// Next returns the next result. Its second return value is
+	// iterator.Done if there are no more results.
+	Next() (*storage.ObjectAttrs, error)
+}
+
+// gcsAccess defines the functions we are using to interact with the GCS
+// SDK. Mainly used for testing to implement a mock component.
+type gcsAccess interface {
+	// NewReader returns the content of the named object.
+	NewReader(ctx context.Context, bucketName string, objectName string) (io.ReadCloser, error)
+	// Objects scans the entries in the bucket.
+	Objects(ctx context.Context, bucketName string, query *storage.Query) objectIterator
+}
+
+// New returns a bucket reader backed by Google Cloud Storage.
+func New(ctx context.Context, config *Config) (bucket.Bucket, error) {
+	var opts []option.ClientOption
+	if config.Endpoint != "" {
+		opts = append(opts, option.WithEndpoint(config.Endpoint), option.WithoutAuthentication())
+	} else if len(config.Credentials) > 0 {
+		opts = append(opts, option.WithCredentialsJSON(config.Credentials))
+	}
+	// Otherwise, use the application default credentials.
+	gcsClient, err := storage.NewClient(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &gcsBucket{
+		client: &client{ref: gcsClient},
+		bucket: config.Bucket,
+	}, nil
+}
+
+type gcsBucket struct {
+	client gcsAccess
+	bucket string
+}
+
+var _ bucket.Bucket = &gcsBucket{}
+
+// Walk implements bucket.Bucket
+func (b *gcsBucket) Walk(
+	ctx *stopper.Context,
+	dir string,
+	options *bucket.WalkOptions,
+	f func(*stopper.Context, string) error,
+) error {
+	// Ensure the object name actually ends with a dir suffix. Otherwise we'll just iterate the
+	// object itself as one prefix item.
+	if dir != "" {
+		dir = strings.TrimSuffix(dir, Delimiter) + Delimiter
+	}
+	after := strings.TrimPrefix(options.StartAfter, b.bucket+Delimiter)
+	query := &storage.Query{
+		Prefix: dir,
+		// StartOffset is inclusive, so we skip the entry below.
+		StartOffset: after,
+	}
+	if !options.Recursive {
+		query.Delimiter = Delimiter
+	}
+	if err := query.SetAttrSelection([]string{"Name"}); err != nil {
+		return err
+	}
+	objects := b.client.Objects(ctx, b.bucket, query)
+	count := 0
+	for {
+		object, err := objects.Next()
+		if errors.Is(err, iterator.Done) {
+			return nil
+		}
+		if err != nil {
+			return mapError(err)
+		}
+		// Folders are returned as prefixes, if a delimiter is set.
+		key := object.Name
+		if key == "" {
+			key = object.Prefix
+		}
+		if key == "" || key == dir || (after != "" && key <= after) {
+			continue
+		}
+		if err := f(ctx, key); err != nil {
+			if errors.Is(err, bucket.ErrSkipAll) {
+				return nil
+			}
+			return err
+		}
+		count++
+		if options.Limit > 0 && count >= options.Limit {
+			return nil
+		}
+	}
+}
+
+// Open implements bucket.Bucket
+func (b *gcsBucket) Open(ctx *stopper.Context, file string) (io.ReadCloser, error) {
+	file = strings.TrimPrefix(file, b.bucket+Delimiter)
+	r, err := b.client.NewReader(ctx, b.bucket, file)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	return r, nil
+}
+
+// mapError converts the errors returned by the SDK into the errors
+// defined by the bucket package.
+func mapError(err error) error {
+	switch {
+	case errors.Is(err, storage.ErrObjectNotExist):
+		return errors.Join(bucket.ErrNoSuchKey, err)
+	case errors.Is(err, storage.ErrBucketNotExist):
+		return errors.Join(bucket.ErrNoSuchBucket, err)
+	}
+	var apiErr *googleapi.Error
+	if errors.As(err, &apiErr) && slices.Contains(RetriableErrors, apiErr.Code) {
+		return errors.Join(bucket.ErrTransient, err)
+	}
+	return err
+}
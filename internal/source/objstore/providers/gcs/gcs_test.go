@@ -0,0 +1,175 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package gcs
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"cloud.google.com/go/storage"
+	"github.com/cockroachdb/replicator/internal/source/objstore/providers/storetest"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/api/iterator"
+)
+
+// mockGCS is in memory GCS bucket.
+type mockGCS struct {
+	bucketName string
+	files      sync.Map
+}
+
+var _ gcsAccess = &mockGCS{}
+var _ storetest.Writer = &mockGCS{}
+
+// NewReader implements gcsAccess.
+func (m *mockGCS) NewReader(
+	ctx context.Context, bucketName string, objectName string,
+) (io.ReadCloser, error) {
+	if bucketName != m.bucketName {
+		return nil, storage.ErrBucketNotExist
+	}
+	file, ok := m.files.Load(objectName)
+	if !ok {
+		return nil, storage.ErrObjectNotExist
+	}
+	return io.NopCloser(bytes.NewReader(file.([]byte))), nil
+}
+
+// Objects implements gcsAccess.
+func (m *mockGCS) Objects(
+	ctx context.Context, bucketName string, query *storage.Query,
+) objectIterator {
+	if bucketName != m.bucketName {
+		return &mockIterator{err: storage.ErrBucketNotExist}
+	}
+	files := make([]string, 0, 10)
+	m.files.Range(func(key any, value any) bool {
+		files = append(files, key.(string))
+		return true
+	})
+	sort.Strings(files)
+	ret := &mockIterator{}
+	for _, f := range files {
+		if !strings.HasPrefix(f, query.Prefix) || f < query.StartOffset {
+			continue
+		}
+		if query.Delimiter != "" {
+			rest := strings.TrimPrefix(f, query.Prefix)
+			if idx := strings.Index(rest, query.Delimiter); idx >= 0 {
+				prefix := query.Prefix + rest[:idx+1]
+				if len(ret.attrs) == 0 || ret.attrs[len(ret.attrs)-1].Prefix != prefix {
+					ret.attrs = append(ret.attrs, &storage.ObjectAttrs{Prefix: prefix})
+				}
+				continue
+			}
+		}
+		ret.attrs = append(ret.attrs, &storage.ObjectAttrs{Name: f})
+	}
+	return ret
+}
+
+// Store implements storetest.Writer.
+func (m *mockGCS) Store(ctx context.Context, name string, buf []byte) error {
+	m.files.Store(name, buf)
+	return nil
+}
+
+type mockIterator struct {
+	attrs []*storage.ObjectAttrs
+	err   error
+}
+
+// Next implements objectIterator.
+func (i *mockIterator) Next() (*storage.ObjectAttrs, error) {
+	if i.err != nil {
+		return nil, i.err
+	}
+	if len(i.attrs) == 0 {
+		return nil, iterator.Done
+	}
+	ret := i.attrs[0]
+	i.attrs = i.attrs[1:]
+	return ret, nil
+}
+
+func TestOpen(t *testing.T) {
+	suite(t).Open(t)
+}
+
+func TestOverwrite(t *testing.T) {
+	suite(t).Overwrite(t)
+}
+
+func TestWalk(t *testing.T) {
+	suite(t).Walk(t)
+}
+
+func TestWalkWithSkipAll(t *testing.T) {
+	suite(t).WalkWithSkipAll(t)
+}
+
+// suite returns a test suite backed by a mock, or by an emulator such
+// as fake-gcs-server if the STORAGE_EMULATOR_HOST environment variable
+// is set. The bucket must already exist in the emulator.
+func suite(t *testing.T) *storetest.Suite {
+	if host := os.Getenv("STORAGE_EMULATOR_HOST"); host != "" {
+		ctx := context.Background()
+		gcsClient, err := storage.NewClient(ctx)
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = gcsClient.Close() })
+		c := &client{ref: gcsClient}
+		return &storetest.Suite{
+			Reader: &gcsBucket{
+				client: c,
+				bucket: "test",
+			},
+			Writer: &emulatorWriter{client: gcsClient, bucket: "test"},
+		}
+	}
+	mockGCS := &mockGCS{
+		bucketName: "test",
+	}
+	return &storetest.Suite{
+		Reader: &gcsBucket{
+			client: mockGCS,
+			bucket: "test",
+		},
+		Writer: mockGCS,
+	}
+}
+
+// emulatorWriter stores objects in an emulated bucket.
+type emulatorWriter struct {
+	bucket string
+	client *storage.Client
+}
+
+// Store implements storetest.Writer.
+func (w *emulatorWriter) Store(ctx context.Context, name string, buf []byte) error {
+	wr := w.client.Bucket(w.bucket).Object(name).NewWriter(ctx)
+	if _, err := wr.Write(buf); err != nil {
+		_ = wr.Close()
+		return err
+	}
+	return wr.Close()
+}
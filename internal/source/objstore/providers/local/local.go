@@ -18,9 +18,11 @@
 package local
 
 import (
+	"context"
 	"errors"
 	"io"
 	"io/fs"
+	"os"
 	"path/filepath"
 	"strings"
 
@@ -35,12 +37,26 @@ func New(fs fs.FS) (bucket.Bucket, error) {
 	}, nil
 }
 
+// NewDir creates a bucket reader and writer for a directory in the
+// local filesystem.
+func NewDir(root string) (bucket.ReadWriter, error) {
+	return &localBucket{
+		filesystem: os.DirFS(root),
+		root:       root,
+	}, nil
+}
+
 // localBucket is a bucket backed by a filesystem.
 type localBucket struct {
 	filesystem fs.FS
+	root       string // Only set if the bucket is writable.
 }
 
-var _ bucket.Bucket = &localBucket{}
+var _ bucket.ReadWriter = &localBucket{}
+
+// tempPrefix is prepended to the names of files that are being
+// written. Files with this prefix are ignored by Walk.
+const tempPrefix = ".tmp-"
 
 // Iter implements bucket.Bucket
 func (b *localBucket) Walk(
@@ -59,6 +75,9 @@ func (b *localBucket) Walk(
 			if options.Limit > 0 && count >= options.Limit {
 				return fs.SkipAll
 			}
+			if strings.HasPrefix(filepath.Base(path), tempPrefix) {
+				return nil
+			}
 			if d != nil && d.IsDir() {
 				if options.Recursive {
 					return nil
@@ -83,6 +102,37 @@ func (b *localBucket) Open(ctx *stopper.Context, file string) (io.ReadCloser, er
 	return r, err
 }
 
+// Put implements bucket.Writer. The content is written to a temporary
+// file in the same directory, which is then renamed.
+func (b *localBucket) Put(_ context.Context, file string, content []byte) error {
+	if b.root == "" {
+		return errors.New("bucket is read-only")
+	}
+	name := filepath.Join(b.root, filepath.Clean(file))
+	dir := filepath.Dir(name)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, tempPrefix+"*")
+	if err != nil {
+		return err
+	}
+	// Cleanup is a no-op once the file has been renamed.
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if _, err := tmp.Write(content); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), name)
+}
+
 // fs returns the underlying filesystem.
 func (b *localBucket) fs() fs.FS {
 	return b.filesystem
@@ -18,11 +18,14 @@ package local
 
 import (
 	"context"
+	"os"
 	"path/filepath"
 	"testing"
 	"testing/fstest"
 	"time"
 
+	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/replicator/internal/source/objstore/bucket"
 	"github.com/cockroachdb/replicator/internal/source/objstore/providers/storetest"
 	"github.com/stretchr/testify/require"
 )
@@ -66,6 +69,40 @@ func TestWalkWithSkipAll(t *testing.T) {
 	suite(t).WalkWithSkipAll(t)
 }
 
+// TestPut verifies that objects written to a directory can be read
+// back, and that partially-written files are not visible.
+func TestPut(t *testing.T) {
+	r := require.New(t)
+	var b bucket.ReadWriter
+	var root string
+	putSuite := func() *storetest.Suite {
+		var err error
+		root = t.TempDir()
+		b, err = NewDir(root)
+		r.NoError(err)
+		return &storetest.Suite{
+			Reader: b,
+			Writer: &storetest.Putter{Writer: b},
+		}
+	}
+	putSuite().Open(t)
+	putSuite().Overwrite(t)
+	putSuite().Walk(t)
+
+	// Simulate a file which is in the process of being written.
+	r.NoError(os.WriteFile(filepath.Join(root, "000", tempPrefix+"123"), []byte("x"), 0644))
+	var found []string
+	r.NoError(b.Walk(nil, "000", &bucket.WalkOptions{Recursive: true},
+		func(_ *stopper.Context, path string) error {
+			found = append(found, path)
+			return nil
+		}))
+	r.Equal([]string{"000/000.txt", "000/001.txt", "000/002.txt", "000/003.txt"}, found)
+
+	// Read-only buckets cannot be written to.
+	r.ErrorContains(suite(t).Reader.(bucket.Writer).Put(context.Background(), "x", nil), "read-only")
+}
+
 // suite builds a validator for a in memory filesystem.
 func suite(t *testing.T) *storetest.Suite {
 	rootFS := make(fstest.MapFS)
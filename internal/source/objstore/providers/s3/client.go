@@ -17,6 +17,7 @@
 package s3
 
 import (
+	"bytes"
 	"context"
 	"io"
 
@@ -47,3 +48,12 @@ func (c *client) ListObjects(
 ) <-chan minio.ObjectInfo {
 	return c.ref.ListObjects(ctx, bucketName, opts)
 }
+
+// PutObject implements s3Access.
+func (c *client) PutObject(
+	ctx context.Context, bucketName string, objectName string, content []byte,
+) error {
+	_, err := c.ref.PutObject(ctx, bucketName, objectName,
+		bytes.NewReader(content), int64(len(content)), minio.PutObjectOptions{})
+	return err
+}
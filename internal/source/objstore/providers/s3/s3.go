@@ -72,10 +72,18 @@ type s3Access interface {
 	GetObject(ctx context.Context, bucketName string, objectName string, opts minio.GetObjectOptions) (io.ReadCloser, error)
 	// ListObjects scans the entries in the bucket.
 	ListObjects(ctx context.Context, bucketName string, opts minio.ListObjectsOptions) <-chan minio.ObjectInfo
+	// PutObject stores the content of the named object.
+	PutObject(ctx context.Context, bucketName string, objectName string, content []byte) error
 }
 
 // New returns a bucket reader backed by a S3 provider.
 func New(config *Config) (bucket.Bucket, error) {
+	return NewReadWriter(config)
+}
+
+// NewReadWriter returns a bucket reader and writer backed by a S3
+// provider.
+func NewReadWriter(config *Config) (bucket.ReadWriter, error) {
 	// Set up different authentication methods.
 	creds := credentials.NewChainCredentials([]credentials.Provider{
 		// Authentication based on the configuration.
@@ -116,6 +124,8 @@ type s3Bucket struct {
 	bucket string
 }
 
+var _ bucket.ReadWriter = &s3Bucket{}
+
 // Iter implements bucket.Bucket
 func (b *s3Bucket) Walk(
 	ctx *stopper.Context,
@@ -183,3 +193,17 @@ func (b *s3Bucket) Open(ctx *stopper.Context, file string) (io.ReadCloser, error
 	}
 	return r, err
 }
+
+// Put implements bucket.Writer. S3 objects only become visible once
+// they have been completely uploaded.
+func (b *s3Bucket) Put(ctx context.Context, file string, content []byte) error {
+	file = strings.TrimPrefix(file, b.bucket+Delimiter)
+	err := b.client.PutObject(ctx, b.bucket, file, content)
+	if err != nil {
+		resp := minio.ToErrorResponse(err)
+		if slices.Contains(RetriableErrors, resp.StatusCode) {
+			return errors.Join(bucket.ErrTransient, err)
+		}
+	}
+	return err
+}
@@ -90,6 +90,17 @@ func (m *mockS3) ListObjects(
 	return ch
 }
 
+// PutObject implements s3Access.
+func (m *mockS3) PutObject(
+	ctx context.Context, bucketName string, objectName string, content []byte,
+) error {
+	if bucketName != m.bucketName {
+		return bucket.ErrNoSuchBucket
+	}
+	m.files.Store(objectName, content)
+	return nil
+}
+
 // Store implements validate.Writer.
 func (m *mockS3) Store(ctx context.Context, name string, buf []byte) error {
 	m.files.Store(name, buf)
@@ -112,6 +123,19 @@ func TestWalkWithSkipAll(t *testing.T) {
 	suite().WalkWithSkipAll(t)
 }
 
+// TestPut verifies that objects written through the bucket can be
+// read back.
+func TestPut(t *testing.T) {
+	putSuite := func() *storetest.Suite {
+		s := suite()
+		s.Writer = &storetest.Putter{Writer: s.Reader.(bucket.Writer)}
+		return s
+	}
+	putSuite().Open(t)
+	putSuite().Overwrite(t)
+	putSuite().Walk(t)
+}
+
 func suite() *storetest.Suite {
 	mockS3 := &mockS3{
 		bucketName: "test",
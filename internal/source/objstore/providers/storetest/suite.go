@@ -36,6 +36,20 @@ type Writer interface {
 	Store(ctx context.Context, name string, buf []byte) error
 }
 
+// Putter adapts a bucket.Writer to the Writer interface, so that the
+// providers which support writes can be tested with their own Put
+// method.
+type Putter struct {
+	bucket.Writer
+}
+
+var _ Writer = &Putter{}
+
+// Store implements Writer.
+func (p *Putter) Store(ctx context.Context, name string, buf []byte) error {
+	return p.Put(ctx, name, buf)
+}
+
 // Suite verifies that the providers for bucket.Bucket can
 // read and list objects from a bucket.
 // TODO (silvano): expand the test cases, add integration tests.
@@ -0,0 +1,159 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oraclelogminer
+
+import (
+	"github.com/cockroachdb/replicator/internal/util/oracleparser/redo"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// The values of V$LOGMNR_CONTENTS.OPERATION_CODE that we handle.
+const (
+	opInsert      = 1
+	opDelete      = 2
+	opUpdate      = 3
+	opDDL         = 5
+	opStart       = 6
+	opCommit      = 7
+	opRollback    = 36
+	opUnsupported = 255
+)
+
+// A logRow is a row from V$LOGMNR_CONTENTS.
+type logRow struct {
+	SCN       uint64
+	Operation int
+	Owner     string
+	Table     string
+	XID       string
+	SQL       string
+	CSF       bool // SQL is continued in the following row.
+}
+
+// A transaction accumulates the statements that were executed by a
+// source transaction.
+type transaction struct {
+	commit     uint64 // The SCN of the commit record.
+	start      uint64 // The SCN of the first record.
+	statements []*redo.Statement
+	xid        string
+}
+
+// A txBuffer reassembles the rows mined from the redo logs into
+// committed transactions.
+type txBuffer struct {
+	// Transactions that committed at or before this SCN have already
+	// been applied.
+	applied uint64
+	open    map[string]*transaction
+	// A row whose SQL_REDO is continued in the following rows.
+	partial *logRow
+}
+
+// newTxBuffer constructs a txBuffer that will skip transactions that
+// were committed at or before the SCN.
+func newTxBuffer(applied uint64) *txBuffer {
+	return &txBuffer{
+		applied: applied,
+		open:    make(map[string]*transaction),
+	}
+}
+
+// add processes the next row, returning a transaction once its commit
+// record has been seen. Rolled-back transactions are discarded.
+func (b *txBuffer) add(row *logRow) (*transaction, error) {
+	if b.partial != nil {
+		if b.partial.XID != row.XID || b.partial.SCN != row.SCN {
+			return nil, errors.Errorf("expecting continuation of statement in transaction %s at SCN %d",
+				b.partial.XID, b.partial.SCN)
+		}
+		joined := *b.partial
+		joined.SQL += row.SQL
+		joined.CSF = row.CSF
+		b.partial = nil
+		row = &joined
+	}
+	if row.CSF {
+		b.partial = row
+		return nil, nil
+	}
+
+	switch row.Operation {
+	case opStart:
+		b.begin(row)
+
+	case opInsert, opUpdate, opDelete:
+		stmt, err := redo.Parse(row.SQL)
+		if err != nil {
+			return nil, errors.Wrapf(err, "transaction %s at SCN %d", row.XID, row.SCN)
+		}
+		txn := b.begin(row)
+		txn.statements = append(txn.statements, stmt)
+
+	case opCommit:
+		txn, ok := b.open[row.XID]
+		if !ok {
+			return nil, nil
+		}
+		delete(b.open, row.XID)
+		if row.SCN <= b.applied {
+			log.Tracef("skipping transaction %s committed at %d", row.XID, row.SCN)
+			return nil, nil
+		}
+		txn.commit = row.SCN
+		return txn, nil
+
+	case opRollback:
+		delete(b.open, row.XID)
+
+	case opDDL:
+		log.Infof("DDL at SCN %d will not be replicated: %s", row.SCN, row.SQL)
+
+	case opUnsupported:
+		unsupportedCount.WithLabelValues(row.Table).Inc()
+		log.Warnf("unsupported operation on %s.%s at SCN %d", row.Owner, row.Table, row.SCN)
+
+	default:
+		log.Tracef("ignoring operation %d at SCN %d", row.Operation, row.SCN)
+	}
+	return nil, nil
+}
+
+// begin returns the open transaction for the row, creating it if
+// necessary.
+func (b *txBuffer) begin(row *logRow) *transaction {
+	txn, ok := b.open[row.XID]
+	if !ok {
+		txn = &transaction{start: row.SCN, xid: row.XID}
+		b.open[row.XID] = txn
+	}
+	return txn
+}
+
+// restart returns the SCN from which mining must resume in order to
+// see all the changes made by the open transactions. The provided SCN
+// is returned if there are no open transactions that started earlier.
+func (b *txBuffer) restart(scn uint64) uint64 {
+	for _, txn := range b.open {
+		scn = min(scn, txn.start)
+	}
+	if b.partial != nil {
+		scn = min(scn, b.partial.SCN)
+	}
+	return scn
+}
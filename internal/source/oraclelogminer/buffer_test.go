@@ -0,0 +1,189 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oraclelogminer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/replicator/internal/util/oracleparser/redo"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTxBuffer(t *testing.T) {
+	r := require.New(t)
+
+	insert := func(scn uint64, xid string) *logRow {
+		return &logRow{
+			SCN: scn, Operation: opInsert, Owner: "HR", Table: "T", XID: xid,
+			SQL: `insert into "HR"."T"("PK","V") values ('1','a');`,
+		}
+	}
+
+	b := newTxBuffer(100)
+	rows := []*logRow{
+		// Interleaved transactions.
+		{SCN: 101, Operation: opStart, XID: "A"},
+		insert(102, "A"),
+		{SCN: 103, Operation: opStart, XID: "B"},
+		insert(104, "B"),
+		// A statement that is continued in the following row.
+		{SCN: 105, Operation: opUpdate, Owner: "HR", Table: "T", XID: "A", CSF: true,
+			SQL: `update "HR"."T" set "V" = 'b' where "PK" = `},
+		{SCN: 105, Operation: opUpdate, Owner: "HR", Table: "T", XID: "A",
+			SQL: `'1' and "V" = 'a';`},
+		{SCN: 106, Operation: opDDL, XID: "C", SQL: "create table x (a int)"},
+	}
+	for _, row := range rows {
+		txn, err := b.add(row)
+		r.NoError(err)
+		r.Nil(txn)
+	}
+	r.Len(b.open, 2)
+	r.Equal(uint64(101), b.restart(200))
+
+	// Commit A.
+	txn, err := b.add(&logRow{SCN: 107, Operation: opCommit, XID: "A"})
+	r.NoError(err)
+	r.NotNil(txn)
+	r.Equal(uint64(101), txn.start)
+	r.Equal(uint64(107), txn.commit)
+	r.Len(txn.statements, 2)
+	r.Equal(redo.Insert, txn.statements[0].Operation)
+	r.Equal(redo.Update, txn.statements[1].Operation)
+	r.Equal(uint64(103), b.restart(200))
+
+	// Roll back B.
+	txn, err = b.add(&logRow{SCN: 108, Operation: opRollback, XID: "B"})
+	r.NoError(err)
+	r.Nil(txn)
+	r.Empty(b.open)
+	r.Equal(uint64(200), b.restart(200))
+
+	// A commit without any changes to the tables.
+	txn, err = b.add(&logRow{SCN: 109, Operation: opCommit, XID: "D"})
+	r.NoError(err)
+	r.Nil(txn)
+
+	// A missing continuation.
+	_, err = b.add(&logRow{SCN: 110, Operation: opInsert, XID: "E", CSF: true, SQL: "insert"})
+	r.NoError(err)
+	r.Equal(uint64(110), b.restart(200))
+	_, err = b.add(insert(111, "E"))
+	r.ErrorContains(err, "expecting continuation")
+
+	// Malformed SQL.
+	b = newTxBuffer(0)
+	_, err = b.add(&logRow{SCN: 1, Operation: opInsert, XID: "A", SQL: "insert into"})
+	r.ErrorContains(err, "transaction A at SCN 1")
+}
+
+// TestTxBufferRestart verifies that transactions which were applied
+// before a restart are skipped when they are mined again.
+func TestTxBufferRestart(t *testing.T) {
+	r := require.New(t)
+
+	cp := &checkpoint{Commit: 105, Restart: 101}
+	parsed, err := parseCheckpoint(cp.Bytes())
+	r.NoError(err)
+	r.Equal(cp, parsed)
+
+	b := newTxBuffer(parsed.Commit)
+	rows := []*logRow{
+		{SCN: 101, Operation: opStart, XID: "A"},
+		{SCN: 102, Operation: opDelete, Owner: "HR", Table: "T", XID: "A",
+			SQL: `delete from "HR"."T" where "PK" = '1';`},
+		{SCN: 103, Operation: opStart, XID: "B"},
+		{SCN: 104, Operation: opDelete, Owner: "HR", Table: "T", XID: "B",
+			SQL: `delete from "HR"."T" where "PK" = '2';`},
+		{SCN: 105, Operation: opCommit, XID: "B"},
+	}
+	for _, row := range rows {
+		txn, err := b.add(row)
+		r.NoError(err)
+		r.Nil(txn)
+	}
+	txn, err := b.add(&logRow{SCN: 106, Operation: opCommit, XID: "A"})
+	r.NoError(err)
+	r.NotNil(txn)
+	r.Equal("A", txn.xid)
+
+	_, err = parseCheckpoint([]byte("garbage"))
+	r.Error(err)
+}
+
+// TestTxBufferContinuation verifies that a statement which LogMiner
+// splits across several rows is reassembled, even when the rows break
+// within quoted identifiers, literals, and function calls.
+func TestTxBufferContinuation(t *testing.T) {
+	r := require.New(t)
+
+	sql := `insert into "HR"."Wide ""T"""("ID","NOTE","AT","RAW") values ` +
+		`('1','it''s, (quoted)',TO_DATE('2024-01-01 00:00:00', 'YYYY-MM-DD HH24:MI:SS'),HEXTORAW('0aff'));`
+	// Break the statement within the table name, a doubled quote, a
+	// literal, and each function call.
+	breaks := []int{
+		strings.Index(sql, `Wide`) + 2,
+		strings.Index(sql, `""T`) + 1,
+		strings.Index(sql, `it''s`) + 3,
+		strings.Index(sql, `TO_DATE`) + 4,
+		strings.Index(sql, `'YYYY`) + 6,
+		strings.Index(sql, `HEXTORAW`) + 9,
+	}
+	var rows []*logRow
+	last := 0
+	for _, idx := range append(breaks, len(sql)) {
+		rows = append(rows, &logRow{
+			SCN: 101, Operation: opInsert, Owner: "HR", Table: `Wide "T"`, XID: "A",
+			SQL: sql[last:idx],
+			CSF: idx < len(sql),
+		})
+		last = idx
+	}
+
+	b := newTxBuffer(0)
+	for _, row := range rows {
+		txn, err := b.add(row)
+		r.NoError(err)
+		r.Nil(txn)
+	}
+	r.Nil(b.partial)
+	txn, err := b.add(&logRow{SCN: 102, Operation: opCommit, XID: "A"})
+	r.NoError(err)
+	r.NotNil(txn)
+	r.Len(txn.statements, 1)
+
+	stmt := txn.statements[0]
+	r.Equal(redo.Insert, stmt.Operation)
+	r.Equal("HR", stmt.Owner)
+	r.Equal(`Wide "T"`, stmt.Table)
+	r.Equal([]redo.Column{
+		{Name: "ID", Value: "1"},
+		{Name: "NOTE", Value: "it's, (quoted)"},
+		{Name: "AT", Value: redo.Expr(`TO_DATE('2024-01-01 00:00:00','YYYY-MM-DD HH24:MI:SS')`)},
+		{Name: "RAW", Value: redo.Expr(`HEXTORAW('0aff')`)},
+	}, stmt.Values)
+
+	// A continuation from a different transaction is an error.
+	b = newTxBuffer(0)
+	_, err = b.add(rows[0])
+	r.NoError(err)
+	next := *rows[1]
+	next.XID = "B"
+	_, err = b.add(&next)
+	r.ErrorContains(err, "expecting continuation")
+}
@@ -0,0 +1,57 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oraclelogminer
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// A checkpoint records the progress of the mining process.
+//
+// A transaction's changes may be interleaved with those of other
+// transactions and will precede its commit record in the redo logs.
+// Mining must therefore resume from the SCN of the first change of the
+// oldest transaction that was still open when the checkpoint was
+// taken. Transactions which are encountered again, but which committed
+// at or before the checkpoint, are skipped.
+type checkpoint struct {
+	Commit  uint64 `json:"commit"`  // The commit SCN of the last applied transaction.
+	Restart uint64 `json:"restart"` // The SCN to resume mining from.
+}
+
+// parseCheckpoint decodes a checkpoint that was stored in the memo.
+func parseCheckpoint(data []byte) (*checkpoint, error) {
+	ret := &checkpoint{}
+	if err := json.Unmarshal(data, ret); err != nil {
+		return nil, errors.Wrap(err, "could not decode SCN checkpoint")
+	}
+	return ret, nil
+}
+
+// Bytes returns the encoded form of the checkpoint.
+func (c *checkpoint) Bytes() []byte {
+	ret, _ := json.Marshal(c)
+	return ret
+}
+
+// String is for debugging use.
+func (c *checkpoint) String() string {
+	return fmt.Sprintf("restart=%d commit=%d", c.Restart, c.Commit)
+}
@@ -0,0 +1,133 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oraclelogminer
+
+import (
+	"time"
+
+	"github.com/cockroachdb/replicator/internal/script"
+	"github.com/cockroachdb/replicator/internal/sequencer"
+	"github.com/cockroachdb/replicator/internal/sinkprod"
+	"github.com/cockroachdb/replicator/internal/target/dlq"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+)
+
+const (
+	defaultPollInterval = time.Second
+	defaultSCNWindow    = 100_000
+)
+
+// EagerConfig is a hack to get Wire to move userscript evaluation to
+// the beginning of the injector. This allows CLI flags to be set by the
+// script.
+type EagerConfig Config
+
+// Config contains the configuration necessary for mining the redo
+// logs of an Oracle database. SourceConn, SourceOwner, and
+// TargetSchema are mandatory.
+type Config struct {
+	DLQ       dlq.Config
+	Script    script.Config
+	Sequencer sequencer.Config
+	Staging   sinkprod.StagingConfig
+	Target    sinkprod.TargetConfig
+
+	// The SCN to start mining from, if no state has been persisted. If
+	// zero, the current SCN of the source database is used.
+	DefaultSCN uint64
+	// How often to check the source database for new changes.
+	PollInterval time.Duration
+	// The maximum number of SCNs to include in a LogMiner session.
+	SCNWindow uint64
+	// Connection string for the source db.
+	SourceConn string
+	// The owner of the tables in the source database to replicate.
+	SourceOwner string
+	// The SQL schema in the target cluster to write into.
+	TargetSchema ident.Schema
+}
+
+// Bind adds flags to the set. It delegates to the embedded Config.Bind.
+func (c *Config) Bind(f *pflag.FlagSet) {
+	c.DLQ.Bind(f)
+	c.Script.Bind(f)
+	c.Sequencer.Bind(f)
+	c.Staging.Bind(f)
+	c.Target.Bind(f)
+
+	f.Uint64Var(&c.DefaultSCN, "defaultSCN", 0,
+		"the SCN to start mining from if no state is persisted; defaults to the current SCN")
+	f.DurationVar(&c.PollInterval, "pollInterval", defaultPollInterval,
+		"how often to check the source database for new changes")
+	f.Uint64Var(&c.SCNWindow, "scnWindow", defaultSCNWindow,
+		"the maximum number of SCNs to include in a single LogMiner session")
+	f.StringVar(&c.SourceConn, "sourceConn", "",
+		"the source database's connection string")
+	f.StringVar(&c.SourceOwner, "sourceOwner", "",
+		"the owner of the tables in the source database to replicate")
+	f.Var(ident.NewSchemaFlag(&c.TargetSchema), "targetSchema",
+		"the SQL database schema in the target cluster to update")
+
+	// Set to true below.
+	if err := f.MarkHidden(sequencer.AssumeIdempotent); err != nil {
+		panic(err)
+	}
+}
+
+// Preflight updates the configuration with sane defaults or returns an
+// error if there are missing options for which a default cannot be
+// provided.
+func (c *Config) Preflight() error {
+	if err := c.DLQ.Preflight(); err != nil {
+		return err
+	}
+	if err := c.Script.Preflight(); err != nil {
+		return err
+	}
+	if err := c.Sequencer.Preflight(); err != nil {
+		return err
+	}
+	if err := c.Staging.Preflight(); err != nil {
+		return err
+	}
+	if err := c.Target.Preflight(); err != nil {
+		return err
+	}
+
+	// We can disable idempotent tracking in the sequencer stack
+	// since the redo logs are replayed from a checkpoint.
+	c.Sequencer.IdempotentSource = true
+
+	if c.PollInterval <= 0 {
+		c.PollInterval = defaultPollInterval
+	}
+	if c.SCNWindow == 0 {
+		c.SCNWindow = defaultSCNWindow
+	}
+	if c.SourceConn == "" {
+		return errors.New("no SourceConn was configured")
+	}
+	if c.SourceOwner == "" {
+		return errors.New("no SourceOwner was configured")
+	}
+	if c.TargetSchema.Empty() {
+		return errors.New("no target schema specified")
+	}
+	return nil
+}
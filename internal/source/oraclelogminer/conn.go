@@ -0,0 +1,507 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package oraclelogminer contains support for reading changes from an
+// Oracle database by using LogMiner to tail its redo logs.
+// See https://docs.oracle.com/en/database/oracle/oracle-database/19/sutil/oracle-logminer-utility.html
+package oraclelogminer
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/cockroachdb/field-eng-powertools/notify"
+	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/field-eng-powertools/stopvar"
+	"github.com/cockroachdb/replicator/internal/script"
+	"github.com/cockroachdb/replicator/internal/sequencer"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/diag"
+	"github.com/cockroachdb/replicator/internal/util/hlc"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/cockroachdb/replicator/internal/util/oracleparser/redo"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// The session parameters that determine how LogMiner renders values
+// in SQL_REDO. The formats are chosen to be understood by the target.
+var sessionParameters = [][2]string{
+	{"NLS_DATE_FORMAT", "YYYY-MM-DD HH24:MI:SS"},
+	{"NLS_NUMERIC_CHARACTERS", ".,"},
+	{"NLS_TIMESTAMP_FORMAT", "YYYY-MM-DD HH24:MI:SS.FF"},
+	{"NLS_TIMESTAMP_TZ_FORMAT", "YYYY-MM-DD HH24:MI:SS.FF TZH:TZM"},
+}
+
+// The redo log files that contain changes in an SCN range. Archived
+// logs are preferred, since an online log may be overwritten once it
+// has been archived.
+const logFilesQuery = `
+SELECT NAME FROM V$ARCHIVED_LOG
+ WHERE NAME IS NOT NULL AND STANDBY_DEST = 'NO' AND DELETED = 'NO'
+   AND NEXT_CHANGE# > :1 AND FIRST_CHANGE# <= :2
+UNION
+SELECT MIN(f.MEMBER) FROM V$LOG l JOIN V$LOGFILE f ON l.GROUP# = f.GROUP#
+ WHERE l.NEXT_CHANGE# > :1 AND l.FIRST_CHANGE# <= :2
+   AND l.SEQUENCE# NOT IN (
+       SELECT SEQUENCE# FROM V$ARCHIVED_LOG
+        WHERE NAME IS NOT NULL AND STANDBY_DEST = 'NO' AND DELETED = 'NO')
+ GROUP BY l.GROUP#`
+
+// The mined changes. Rows with ROLLBACK = 1 undo a change that was
+// rolled back to a savepoint; their SQL_REDO is a compensating
+// statement, so they are applied like any other change.
+const contentsQuery = `
+SELECT SCN, OPERATION_CODE, NVL(SEG_OWNER, ' '), NVL(TABLE_NAME, ' '),
+       RAWTOHEX(XID), NVL(SQL_REDO, ' '), CSF
+  FROM V$LOGMNR_CONTENTS
+ WHERE OPERATION_CODE IN (6, 7, 36)
+    OR SEG_OWNER = :1 AND OPERATION_CODE IN (1, 2, 3, 5, 255)`
+
+// Conn exports the package-internal type.
+type Conn conn
+
+// conn tails the redo logs of the source database and writes committed
+// transactions to the target.
+type conn struct {
+	// The destination for writes.
+	acceptor types.TemporalAcceptor
+	// The connector configuration.
+	config *Config
+	// Persistent storage for the checkpoint.
+	memo types.Memo
+	// Ensure the timestamps we generate always march forward.
+	monotonic hlc.Clock
+	// Progress reports from the underlying sequencer.
+	stat *notify.Var[sequencer.Stat]
+	// Access to the source database.
+	sourceDB *sql.DB
+	// Access to the staging cluster.
+	stagingDB *types.StagingPool
+	// The destination for writes.
+	target ident.Schema
+	// Access to the target database.
+	targetDB *types.TargetPool
+	// Access to the target schema.
+	watchers types.Watchers
+	// Managed by persistCheckpoint.
+	checkpoint notify.Var[*checkpoint]
+}
+
+var _ diag.Diagnostic = (*conn)(nil)
+
+// Diagnostic implements [diag.Diagnostic].
+func (c *conn) Diagnostic(_ context.Context) any {
+	cp, _ := c.checkpoint.Get()
+	return map[string]any{
+		"checkpoint": cp,
+		"owner":      c.config.SourceOwner,
+	}
+}
+
+// Start loads the checkpoint and starts the mining loop.
+func (c *conn) Start(ctx *stopper.Context) error {
+	// Call this first to load the previous checkpoint. We want to reset
+	// our state before starting the main copier routine.
+	if err := c.persistCheckpoint(ctx); err != nil {
+		return err
+	}
+
+	// Start a process to copy data to the target.
+	ctx.Go(func(ctx *stopper.Context) error {
+		for !ctx.IsStopping() {
+			if err := c.copyMessages(ctx); err != nil {
+				log.WithError(err).Warn("error while mining redo logs; will retry")
+				select {
+				case <-ctx.Stopping():
+				case <-time.After(time.Second):
+				}
+			}
+		}
+		return nil
+	})
+	// Sync the sequencer's progress back to our checkpoint.
+	ctx.Go(func(ctx *stopper.Context) error {
+		// Inner callback returns nil.
+		_, _ = stopvar.DoWhenChanged(ctx, nil, c.stat,
+			func(ctx *stopper.Context, old, next sequencer.Stat) error {
+				oldProgress := sequencer.CommonProgress(old).Max()
+				progress := sequencer.CommonProgress(next).Max()
+				// Debounce intermediate progress updates (e.g. partial
+				// table progress).
+				if hlc.Compare(progress, oldProgress) > 0 {
+					cp := progress.External().(*checkpoint)
+					log.Debugf("progressed to checkpoint: %s", cp)
+					c.checkpoint.Set(cp)
+				}
+				return nil
+			})
+		return nil
+	})
+	return nil
+}
+
+// copyMessages is the main replication loop. It repeatedly starts a
+// LogMiner session for the range of SCNs that follows the previous
+// session and commits the transactions that it finds to the target.
+func (c *conn) copyMessages(ctx *stopper.Context) error {
+	db, err := c.sourceDB.Conn(ctx)
+	if err != nil {
+		dialFailureCount.Inc()
+		return errors.WithStack(err)
+	}
+	defer db.Close()
+	dialSuccessCount.Inc()
+
+	for _, param := range sessionParameters {
+		if _, err := db.ExecContext(ctx,
+			fmt.Sprintf("ALTER SESSION SET %s = '%s'", param[0], param[1]),
+		); err != nil {
+			return errors.Wrapf(err, "could not set %s", param[0])
+		}
+	}
+
+	cp, _ := c.checkpoint.Get()
+	if cp == nil {
+		return errors.New("missing checkpoint")
+	}
+	buffer := newTxBuffer(cp.Commit)
+	from := cp.Restart
+
+	for !ctx.IsStopping() {
+		var current uint64
+		if err := db.QueryRowContext(ctx,
+			"SELECT CURRENT_SCN FROM V$DATABASE",
+		).Scan(&current); err != nil {
+			return errors.WithStack(err)
+		}
+		if current < from {
+			select {
+			case <-ctx.Stopping():
+			case <-time.After(c.config.PollInterval):
+			}
+			continue
+		}
+		to := min(current, from+c.config.SCNWindow-1)
+		if err := c.mine(ctx, db, buffer, from, to); err != nil {
+			return err
+		}
+		from = to + 1
+		scnGauge.Set(float64(to))
+
+		// Advance the checkpoint past ranges that contain no open
+		// transactions, so that they won't be mined again.
+		last := c.monotonic.Last().External().(*checkpoint)
+		if restart := buffer.restart(from); restart > last.Restart {
+			c.markProgress(c.monotonic.External(&checkpoint{
+				Commit:  last.Commit,
+				Restart: restart,
+			}))
+		}
+
+		// The SCN advances even when the database is idle. If we have
+		// caught up, wait before starting another session instead of
+		// mining a stream of tiny windows.
+		if to == current {
+			select {
+			case <-ctx.Stopping():
+			case <-time.After(c.config.PollInterval):
+			}
+		}
+	}
+	return nil
+}
+
+// mine runs a LogMiner session over the inclusive range of SCNs.
+func (c *conn) mine(
+	ctx *stopper.Context, db *sql.Conn, buffer *txBuffer, from, to uint64,
+) error {
+	start := time.Now()
+	rows, err := db.QueryContext(ctx, logFilesQuery, from, to)
+	if err != nil {
+		return errors.Wrap(err, "could not find redo log files")
+	}
+	var files []string
+	for rows.Next() {
+		var file string
+		if err := rows.Scan(&file); err != nil {
+			_ = rows.Close()
+			return errors.WithStack(err)
+		}
+		files = append(files, file)
+	}
+	if err := rows.Err(); err != nil {
+		return errors.WithStack(err)
+	}
+	if len(files) == 0 {
+		return errors.Errorf("no redo log files contain SCN %d; "+
+			"the logs may have been deleted", from)
+	}
+	for idx, file := range files {
+		option := "DBMS_LOGMNR.ADDFILE"
+		if idx == 0 {
+			option = "DBMS_LOGMNR.NEW"
+		}
+		if _, err := db.ExecContext(ctx, fmt.Sprintf(
+			"BEGIN DBMS_LOGMNR.ADD_LOGFILE(LOGFILENAME => :1, OPTIONS => %s); END;", option,
+		), file); err != nil {
+			return errors.Wrapf(err, "could not add log file %s", file)
+		}
+	}
+	if _, err := db.ExecContext(ctx, `
+BEGIN DBMS_LOGMNR.START_LOGMNR(
+  STARTSCN => :1,
+  ENDSCN => :2,
+  OPTIONS => DBMS_LOGMNR.DICT_FROM_ONLINE_CATALOG + DBMS_LOGMNR.NO_ROWID_IN_STMT);
+END;`, from, to); err != nil {
+		return errors.Wrapf(err, "could not start LogMiner for SCNs %d to %d", from, to)
+	}
+	defer func() {
+		// Use a background context, since the session must be ended
+		// even if we are stopping.
+		if _, err := db.ExecContext(context.Background(),
+			"BEGIN DBMS_LOGMNR.END_LOGMNR; END;"); err != nil {
+			log.WithError(err).Warn("could not end LogMiner session")
+		}
+	}()
+
+	rows, err = db.QueryContext(ctx, contentsQuery, c.config.SourceOwner)
+	if err != nil {
+		return errors.Wrap(err, "could not query LogMiner contents")
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var row logRow
+		if err := rows.Scan(&row.SCN, &row.Operation, &row.Owner, &row.Table,
+			&row.XID, &row.SQL, &row.CSF); err != nil {
+			return errors.WithStack(err)
+		}
+		rowCount.WithLabelValues(operationName(row.Operation)).Inc()
+		txn, err := buffer.add(&row)
+		if err != nil {
+			return err
+		}
+		if txn == nil {
+			continue
+		}
+		if err := c.onTransaction(ctx, txn, buffer.restart(txn.commit)); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return errors.WithStack(err)
+	}
+	mineDurations.Observe(time.Since(start).Seconds())
+	return nil
+}
+
+// onTransaction commits the changes made by the source transaction to
+// the target.
+func (c *conn) onTransaction(ctx *stopper.Context, txn *transaction, restart uint64) error {
+	watcher, err := c.watchers.Get(c.target)
+	if err != nil {
+		return err
+	}
+	tables := watcher.Get().Columns
+	batch := &types.TemporalBatch{
+		Time: c.monotonic.External(&checkpoint{
+			Commit:  txn.commit,
+			Restart: restart,
+		}),
+	}
+	for _, stmt := range txn.statements {
+		tbl := ident.NewTable(c.target, ident.New(stmt.Table))
+		cols, ok := tables.Get(tbl)
+		if !ok {
+			log.Tracef("skipping change to %s because it is not in the target schema %s",
+				stmt.Table, c.target)
+			continue
+		}
+		mut, err := toMutation(stmt, cols)
+		if err != nil {
+			return errors.Wrapf(err, "transaction %s committed at SCN %d", txn.xid, txn.commit)
+		}
+		mut.Time = batch.Time
+		script.AddMeta("oraclelogminer", tbl, &mut)
+		if err := batch.Accumulate(tbl, mut); err != nil {
+			return err
+		}
+		mutationCount.WithLabelValues(stmt.Operation.String()).Inc()
+	}
+
+	if batch.Count() == 0 {
+		log.Tracef("skipping empty transaction %s", txn.xid)
+	} else {
+		tx, err := c.targetDB.BeginTx(ctx, &sql.TxOptions{})
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		defer tx.Rollback()
+
+		if err := c.acceptor.AcceptTemporalBatch(ctx, batch, &types.AcceptOptions{
+			TargetQuerier: tx,
+		}); err != nil {
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	c.markProgress(batch.Time)
+	return nil
+}
+
+// markProgress updates the sequencer stat to indicate that all
+// transactions up to and including the time have been committed.
+func (c *conn) markProgress(time hlc.Time) {
+	// We're in immediate operation, so we'll fake up a stat to
+	// indicate that the transaction has been committed.
+	fakeProgress := &ident.TableMap[hlc.Range]{}
+	fakeTable := ident.NewTable(c.target, ident.New("fake"))
+	fakeProgress.Put(fakeTable, hlc.RangeIncluding(hlc.Zero(), time))
+	c.stat.Set(sequencer.NewStat(&types.TableGroup{
+		Tables: []ident.Table{fakeTable},
+	}, fakeProgress))
+}
+
+// toMutation converts a redo statement into a mutation, using the
+// columns of the target table to determine the primary key. The update
+// and delete statements only contain the complete row if supplemental
+// logging of all columns is enabled for the table.
+func toMutation(stmt *redo.Statement, cols []types.ColData) (types.Mutation, error) {
+	var mut types.Mutation
+	image := stmt.Image()
+	data := make(map[string]any, len(image))
+	for _, col := range image {
+		value := col.Value
+		if expr, ok := value.(redo.Expr); ok {
+			value, ok = expr.Literal()
+			if !ok {
+				return mut, errors.Errorf("unsupported value %s for column %s.%s",
+					expr, stmt.Table, col.Name)
+			}
+		}
+		data[col.Name] = value
+	}
+
+	var key []any
+	for _, col := range cols {
+		if !col.Primary {
+			continue
+		}
+		found := false
+		for _, imageCol := range image {
+			if ident.Equal(ident.New(imageCol.Name), col.Name) {
+				key = append(key, data[imageCol.Name])
+				found = true
+				break
+			}
+		}
+		if !found {
+			return mut, errors.Errorf("primary key column %s is missing from a change to %s; "+
+				"ensure that supplemental logging is enabled", col.Name, stmt.Table)
+		}
+	}
+	if len(key) == 0 {
+		return mut, errors.Errorf("table %s has no primary key", stmt.Table)
+	}
+
+	var err error
+	mut.Key, err = json.Marshal(key)
+	if err != nil {
+		return mut, errors.WithStack(err)
+	}
+	mut.Data, err = json.Marshal(data)
+	if err != nil {
+		return mut, errors.WithStack(err)
+	}
+	mut.Deletion = stmt.Operation == redo.Delete
+	return mut, nil
+}
+
+// persistCheckpoint loads an existing value from memo into checkpoint
+// or initializes it from the configuration or the source database. It
+// will also start a goroutine in the stopper to occasionally write an
+// updated value back to the memo.
+func (c *conn) persistCheckpoint(ctx *stopper.Context) error {
+	key := fmt.Sprintf("oracle-scn-%s", c.target.Raw())
+	found, err := c.memo.Get(ctx, c.stagingDB, key)
+	if err != nil {
+		return err
+	}
+	var cp *checkpoint
+	switch {
+	case len(found) > 0:
+		cp, err = parseCheckpoint(found)
+		if err != nil {
+			return err
+		}
+		log.Infof("using SCN checkpoint stored in the memo table: %s", cp)
+	case c.config.DefaultSCN > 0:
+		cp = &checkpoint{Restart: c.config.DefaultSCN}
+		log.Infof("using SCN from the command line: %s", cp)
+	default:
+		var current uint64
+		if err := c.sourceDB.QueryRowContext(ctx,
+			"SELECT CURRENT_SCN FROM V$DATABASE",
+		).Scan(&current); err != nil {
+			return errors.Wrap(err, "could not query current SCN")
+		}
+		cp = &checkpoint{Restart: current}
+		log.Infof("using current SCN of the source database: %s", cp)
+	}
+	c.monotonic.External(cp)
+	c.checkpoint.Set(cp)
+
+	ctx.Go(func(ctx *stopper.Context) error {
+		_, err := stopvar.DoWhenChanged(ctx, cp, &c.checkpoint,
+			func(ctx *stopper.Context, _, cp *checkpoint) error {
+				if err := c.memo.Put(ctx, c.stagingDB, key, cp.Bytes()); err == nil {
+					log.Tracef("stored SCN checkpoint %s: %s", key, cp)
+				} else {
+					log.WithError(err).Error("could not persist SCN checkpoint")
+				}
+				return nil
+			})
+		return err
+	})
+	return nil
+}
+
+// operationName returns a metrics label for an OPERATION_CODE.
+func operationName(op int) string {
+	switch op {
+	case opInsert:
+		return "insert"
+	case opDelete:
+		return "delete"
+	case opUpdate:
+		return "update"
+	case opDDL:
+		return "ddl"
+	case opStart:
+		return "start"
+	case opCommit:
+		return "commit"
+	case opRollback:
+		return "rollback"
+	default:
+		return "unsupported"
+	}
+}
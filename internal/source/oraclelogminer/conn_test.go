@@ -0,0 +1,100 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oraclelogminer
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/cockroachdb/replicator/internal/util/oracleparser/redo"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToMutation(t *testing.T) {
+	cols := []types.ColData{
+		{Name: ident.New("pk"), Primary: true, Type: "INT8"},
+		{Name: ident.New("at"), Type: "TIMESTAMP"},
+		{Name: ident.New("v"), Type: "STRING"},
+	}
+
+	tcs := []struct {
+		name     string
+		sql      string
+		deletion bool
+		key      string
+		data     string
+		err      string
+	}{
+		{
+			name: "insert",
+			sql: `insert into "HR"."T"("PK","AT","V") values ` +
+				`('1',TO_DATE('2024-01-01 00:00:00', 'YYYY-MM-DD HH24:MI:SS'),NULL);`,
+			key:  `["1"]`,
+			data: `{"AT":"2024-01-01 00:00:00","PK":"1","V":null}`,
+		},
+		{
+			name: "update",
+			sql:  `update "HR"."T" set "V" = 'b' where "PK" = '1' and "AT" IS NULL and "V" = 'a';`,
+			key:  `["1"]`,
+			data: `{"AT":null,"PK":"1","V":"b"}`,
+		},
+		{
+			name:     "delete",
+			sql:      `delete from "HR"."T" where "PK" = '1' and "AT" IS NULL and "V" = 'a';`,
+			deletion: true,
+			key:      `["1"]`,
+			data:     `{"AT":null,"PK":"1","V":"a"}`,
+		},
+		{
+			name: "missing key",
+			sql:  `update "HR"."T" set "V" = 'b' where "V" = 'a';`,
+			err:  "supplemental logging",
+		},
+		{
+			name: "unsupported value",
+			sql:  `insert into "HR"."T"("PK","V") values ('1',UNISTR('\00e9'));`,
+			err:  "unsupported value",
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			a := assert.New(t)
+			r := require.New(t)
+
+			stmt, err := redo.Parse(tc.sql)
+			r.NoError(err)
+			mut, err := toMutation(stmt, cols)
+			if tc.err != "" {
+				a.ErrorContains(err, tc.err)
+				return
+			}
+			r.NoError(err)
+			a.Equal(tc.deletion, mut.Deletion)
+			a.JSONEq(tc.key, string(mut.Key))
+			a.JSONEq(tc.data, string(mut.Data))
+		})
+	}
+
+	// A table without a primary key.
+	stmt, err := redo.Parse(`insert into "HR"."T"("V") values ('a');`)
+	require.NoError(t, err)
+	_, err = toMutation(stmt, cols[1:])
+	assert.ErrorContains(t, err, "no primary key")
+}
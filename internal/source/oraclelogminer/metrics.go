@@ -0,0 +1,55 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oraclelogminer
+
+import (
+	"github.com/cockroachdb/replicator/internal/util/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	dialFailureCount = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "oraclelogminer_dial_failure_total",
+		Help: "the number of times we failed to connect to the source database",
+	})
+	dialSuccessCount = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "oraclelogminer_dial_success_total",
+		Help: "the number of times we successfully connected to the source database",
+	})
+	mineDurations = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "oraclelogminer_mine_duration_seconds",
+		Help:    "the length of time it took to process a range of SCNs",
+		Buckets: metrics.LatencyBuckets,
+	})
+	mutationCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "oraclelogminer_mutation_total",
+		Help: "the number of mutations applied to the target, by type",
+	}, []string{"type"})
+	rowCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "oraclelogminer_row_total",
+		Help: "the number of LogMiner rows received, by operation",
+	}, []string{"operation"})
+	scnGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "oraclelogminer_scn",
+		Help: "the most recent SCN that has been mined",
+	})
+	unsupportedCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "oraclelogminer_unsupported_total",
+		Help: "the number of changes that LogMiner could not reconstruct, by table",
+	}, []string{"table"})
+)
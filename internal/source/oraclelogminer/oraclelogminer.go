@@ -0,0 +1,37 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oraclelogminer
+
+import (
+	"github.com/cockroachdb/replicator/internal/util/diag"
+	"github.com/cockroachdb/replicator/internal/util/stdlogical"
+)
+
+// OracleLogMiner is an Oracle redo log replication loop.
+type OracleLogMiner struct {
+	Conn        *Conn
+	Diagnostics *diag.Diagnostics
+}
+
+var (
+	_ stdlogical.HasDiagnostics = (*OracleLogMiner)(nil)
+)
+
+// GetDiagnostics implements [stdlogical.HasDiagnostics].
+func (l *OracleLogMiner) GetDiagnostics() *diag.Diagnostics {
+	return l.Diagnostics
+}
@@ -0,0 +1,118 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package oraclelogminer
+
+import (
+	"github.com/cockroachdb/field-eng-powertools/notify"
+	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/replicator/internal/script"
+	"github.com/cockroachdb/replicator/internal/sequencer"
+	"github.com/cockroachdb/replicator/internal/sequencer/chaos"
+	"github.com/cockroachdb/replicator/internal/sequencer/immediate"
+	"github.com/cockroachdb/replicator/internal/sequencer/pii"
+	scriptSeq "github.com/cockroachdb/replicator/internal/sequencer/script"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/diag"
+	"github.com/cockroachdb/replicator/internal/util/hlc"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/cockroachdb/replicator/internal/util/stdpool"
+	"github.com/google/wire"
+)
+
+// Set is used by Wire.
+var Set = wire.NewSet(
+	ProvideConn,
+	ProvideEagerConfig,
+)
+
+// ProvideConn is called by Wire to construct the connection to the
+// source database. There's a fake dependency on the script loader so
+// that flags can be evaluated first.
+func ProvideConn(
+	ctx *stopper.Context,
+	acc types.TableAcceptor,
+	chaos *chaos.Chaos,
+	config *Config,
+	diags *diag.Diagnostics,
+	imm *immediate.Immediate,
+	_ *script.Loader,
+	memo types.Memo,
+	pii *pii.PII,
+	scriptSeq *scriptSeq.Sequencer,
+	stagingPool *types.StagingPool,
+	targetPool *types.TargetPool,
+	watchers types.Watchers,
+) (*Conn, error) {
+	if err := config.Preflight(); err != nil {
+		return nil, err
+	}
+
+	sourcePool, err := stdpool.OpenOracleAsTarget(ctx, config.SourceConn)
+	if err != nil {
+		return nil, err
+	}
+
+	seq, err := pii.Wrap(ctx, imm) // No-op if no policies.
+	if err != nil {
+		return nil, err
+	}
+	seq, err = scriptSeq.Wrap(ctx, seq)
+	if err != nil {
+		return nil, err
+	}
+	seq, err = chaos.Wrap(ctx, seq) // No-op if probability is 0.
+	if err != nil {
+		return nil, err
+	}
+	group := &types.TableGroup{
+		Name:      ident.New(config.TargetSchema.Raw()),
+		Enclosing: config.TargetSchema,
+	}
+	connAcceptor, stat, err := seq.Start(ctx, &sequencer.StartOptions{
+		Delegate: types.OrderedAcceptorFrom(acc, watchers),
+		Bounds:   &notify.Var[hlc.Range]{}, // Not currently used.
+		Group:    group,
+	})
+	if err != nil {
+		return nil, err
+	}
+	sequencer.NotifyResolved(ctx, group, stat, acc)
+
+	ret := &conn{
+		acceptor:  connAcceptor,
+		config:    config,
+		memo:      memo,
+		sourceDB:  sourcePool.DB,
+		stagingDB: stagingPool,
+		stat:      stat,
+		target:    config.TargetSchema,
+		targetDB:  targetPool,
+		watchers:  watchers,
+	}
+	if err := diags.Register("oraclelogminer", ret); err != nil {
+		return nil, err
+	}
+
+	return (*Conn)(ret), ret.Start(ctx)
+}
+
+// ProvideEagerConfig is a hack to move up the evaluation of the user
+// script so that the options callbacks can set any non-script-related
+// CLI flags. The configuration will be preflighted.
+func ProvideEagerConfig(cfg *Config, _ *script.Loader) (*EagerConfig, error) {
+	return (*EagerConfig)(cfg), cfg.Preflight()
+}
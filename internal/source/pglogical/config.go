@@ -17,13 +17,17 @@
 package pglogical
 
 import (
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/cockroachdb/replicator/internal/script"
 	"github.com/cockroachdb/replicator/internal/sequencer"
 	"github.com/cockroachdb/replicator/internal/sinkprod"
 	"github.com/cockroachdb/replicator/internal/target/dlq"
+	"github.com/cockroachdb/replicator/internal/target/evolve"
 	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/cockroachdb/replicator/internal/util/snapshot"
 	"github.com/pkg/errors"
 	"github.com/spf13/pflag"
 )
@@ -32,6 +36,56 @@ const (
 	defaultStandbyTimeout = 5 * time.Second
 )
 
+// TruncateMode determines how TRUNCATE messages received from the
+// source database are applied to the target.
+type TruncateMode int
+
+//go:generate go run golang.org/x/tools/cmd/stringer -type=TruncateMode -trimprefix TruncateMode
+
+const (
+	// TruncateModeReject is the default behavior, which halts
+	// replication if a TRUNCATE message is received.
+	TruncateModeReject TruncateMode = iota
+	// TruncateModeDelete will execute a DELETE statement against each
+	// truncated target table, within the target transaction.
+	TruncateModeDelete
+	// TruncateModeTruncate will execute a TRUNCATE TABLE statement
+	// against the truncated target tables. This mode is only supported
+	// for PostgreSQL and CockroachDB targets.
+	TruncateModeTruncate
+)
+
+// TruncateModes returns the available truncation modes.
+func TruncateModes() []string {
+	var res []string
+	for i := TruncateModeReject; i <= TruncateModeTruncate; i++ {
+		res = append(res, strings.ToLower(i.String()))
+	}
+	return res
+}
+
+var _ pflag.Value = new(TruncateMode)
+
+// Set implements pflag.Value.
+func (m *TruncateMode) Set(value string) error {
+	switch strings.ToLower(value) {
+	case "", "reject":
+		*m = TruncateModeReject
+	case "delete":
+		*m = TruncateModeDelete
+	case "truncate":
+		*m = TruncateModeTruncate
+	default:
+		return errors.Errorf("invalid truncate mode %q", value)
+	}
+	return nil
+}
+
+// Type implements pflag.Value.
+func (m TruncateMode) Type() string {
+	return fmt.Sprintf("%T", m)
+}
+
 // EagerConfig is a hack to get Wire to move userscript evaluation to
 // the beginning of the injector. This allows CLI flags to be set by the
 // script.
@@ -42,8 +96,10 @@ type EagerConfig Config
 // mandatory unless explicitly indicated.
 type Config struct {
 	DLQ       dlq.Config
+	Evolve    evolve.Config
 	Script    script.Config
 	Sequencer sequencer.Config
+	Snapshot  snapshot.Config
 	Staging   sinkprod.StagingConfig
 	Target    sinkprod.TargetConfig
 
@@ -58,13 +114,17 @@ type Config struct {
 	// The SQL schema in the target cluster to write into. This value is
 	// optional if a userscript dispatch function is present.
 	TargetSchema ident.Schema
+	// Determines how TRUNCATE operations in the source are handled.
+	TruncateMode TruncateMode
 }
 
 // Bind adds flags to the set.
 func (c *Config) Bind(f *pflag.FlagSet) {
 	c.DLQ.Bind(f)
+	c.Evolve.Bind(f)
 	c.Script.Bind(f)
 	c.Sequencer.Bind(f)
+	c.Snapshot.Bind(f)
 	c.Staging.Bind(f)
 	c.Target.Bind(f)
 
@@ -76,6 +136,9 @@ func (c *Config) Bind(f *pflag.FlagSet) {
 		"how often to report WAL progress to the source server")
 	f.Var(ident.NewSchemaFlag(&c.TargetSchema), "targetSchema",
 		"the SQL database schema in the target cluster to update")
+	f.Var(&c.TruncateMode, "truncateMode",
+		fmt.Sprintf("how TRUNCATE operations in the source are applied to the target: %s",
+			strings.Join(TruncateModes(), ", ")))
 
 	// The apply package supports sparse mutations now.
 	var deprecated bool
@@ -98,12 +161,18 @@ func (c *Config) Preflight() error {
 	if err := c.DLQ.Preflight(); err != nil {
 		return err
 	}
+	if err := c.Evolve.Preflight(); err != nil {
+		return err
+	}
 	if err := c.Script.Preflight(); err != nil {
 		return err
 	}
 	if err := c.Sequencer.Preflight(); err != nil {
 		return err
 	}
+	if err := c.Snapshot.Preflight(); err != nil {
+		return err
+	}
 	if err := c.Staging.Preflight(); err != nil {
 		return err
 	}
@@ -130,5 +199,9 @@ func (c *Config) Preflight() error {
 	if c.TargetSchema.Empty() {
 		return errors.New("no target schema specified")
 	}
+	if c.TruncateMode != TruncateModeReject && c.Target.Redirected() {
+		return errors.Errorf("--truncateMode %s cannot be used with a Kafka or object-store target",
+			strings.ToLower(c.TruncateMode.String()))
+	}
 	return nil
 }
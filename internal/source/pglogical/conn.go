@@ -23,6 +23,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/cockroachdb/field-eng-powertools/notify"
@@ -30,13 +31,17 @@ import (
 	"github.com/cockroachdb/field-eng-powertools/stopvar"
 	"github.com/cockroachdb/replicator/internal/script"
 	"github.com/cockroachdb/replicator/internal/sequencer"
+	"github.com/cockroachdb/replicator/internal/target/evolve"
 	"github.com/cockroachdb/replicator/internal/types"
 	"github.com/cockroachdb/replicator/internal/util/hlc"
 	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/cockroachdb/replicator/internal/util/snapshot"
 	"github.com/google/uuid"
 	"github.com/jackc/pglogrepl"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgproto3"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 )
@@ -49,6 +54,8 @@ type Conn struct {
 	acceptor types.TemporalAcceptor
 	// Columns, as ordered by the source database.
 	columns *ident.TableMap[[]types.ColData]
+	// Creates or alters target tables, if enabled.
+	evolver *evolve.Evolver
 	// Persistent storage for WAL data.
 	memo types.Memo
 	// Ensure the timestamps we generate always march forward.
@@ -59,8 +66,14 @@ type Conn struct {
 	relations map[uint32]ident.Table
 	// The name of the slot within the publication.
 	slotName string
+	// Controls the initial snapshot of the source tables.
+	snapshotConfig *snapshot.Config
+	// Access to staged mutations, to clear truncated tables.
+	stagers types.Stagers
 	// The configuration for opening replication connections.
 	sourceConfig *pgconn.Config
+	// The configuration for opening non-replication connections.
+	sqlConfig *pgx.ConnConfig
 	// How ofter to commit the consistent point
 	standbyTimeout time.Duration
 	// Access to the staging cluster.
@@ -71,8 +84,14 @@ type Conn struct {
 	target ident.Schema
 	// Access to the target database.
 	targetDB *types.TargetPool
+	// Determines how TRUNCATE messages are handled.
+	truncateMode TruncateMode
+	// Truncations received within the current transaction.
+	truncations []*truncation
 	// Holds the guaranteed-committed LSN.
 	walOffset notify.Var[pglogrepl.LSN]
+	// Provides table-dependency ordering for truncations.
+	watchers types.Watchers
 }
 
 // Start launches goroutines into the context.
@@ -83,10 +102,20 @@ func (c *Conn) Start(ctx *stopper.Context) error {
 		return err
 	}
 
-	// Start a process to copy data to the target.
+	// Start a process to copy data to the target. Streaming begins
+	// once the initial snapshot, if any, has completed.
 	ctx.Go(func(ctx *stopper.Context) error {
+		snapshotDone := false
 		for !ctx.IsStopping() {
-			if err := c.copyMessages(ctx); err != nil {
+			var err error
+			if !snapshotDone {
+				err = c.snapshot(ctx)
+				snapshotDone = err == nil
+			}
+			if snapshotDone {
+				err = c.copyMessages(ctx)
+			}
+			if err != nil {
 				log.WithError(err).Warn("error while copying messages; will retry")
 				select {
 				case <-ctx.Stopping():
@@ -130,11 +159,14 @@ func (c *Conn) accumulateBatch(
 		// The replication protocol says that we'll see these
 		// descriptors before any use of the relation id in the
 		// stream. We'll map the int value to our table identifiers.
-		c.onRelation(msg)
+		if err := c.onRelation(ctx, msg); err != nil {
+			return nil, err
+		}
 		return batch, nil
 
 	case *pglogrepl.BeginMessage:
 		log.Tracef("received transaction beginning at %s", msg.FinalLSN)
+		c.truncations = nil
 		// Create a new batch to accumulate into. It may be discarded
 		// later if the timestamp precedes the latest commit.
 		return &types.TemporalBatch{
@@ -145,7 +177,7 @@ func (c *Conn) accumulateBatch(
 		// In Postgres version < v15, the stream might contain empty transactions.
 		// See https://github.com/postgres/postgres/commit/d5a9d86d8f
 		// We will skip them to avoid unnecessary writes to the memo table.
-		if batch.Count() == 0 {
+		if batch.Count() == 0 && len(c.truncations) == 0 {
 			emptyTransactionCount.Inc()
 			log.Trace("skipping empty transaction")
 		} else {
@@ -154,16 +186,39 @@ func (c *Conn) accumulateBatch(
 				return nil, errors.WithStack(err)
 			}
 			defer tx.Rollback()
+			opts := &types.AcceptOptions{TargetQuerier: tx}
+
+			// Apply any mutations that preceded a truncation, and then
+			// the truncation itself.
+			for _, trunc := range c.truncations {
+				if trunc.before.Count() > 0 {
+					if err := c.acceptor.AcceptTemporalBatch(ctx, trunc.before, opts); err != nil {
+						return nil, err
+					}
+				}
+				if err := c.applyTruncation(ctx, tx, trunc); err != nil {
+					return nil, err
+				}
+			}
 
-			if err := c.acceptor.AcceptTemporalBatch(ctx, batch, &types.AcceptOptions{
-				TargetQuerier: tx,
-			}); err != nil {
-				return nil, err
+			if batch.Count() > 0 {
+				if err := c.acceptor.AcceptTemporalBatch(ctx, batch, opts); err != nil {
+					return nil, err
+				}
 			}
 
 			if err := tx.Commit(); err != nil {
 				return nil, errors.WithStack(err)
 			}
+
+			// Discard staged data for the truncated tables.
+			for _, trunc := range c.truncations {
+				if err := c.retireTruncated(ctx, trunc, batch.Time); err != nil {
+					return nil, err
+				}
+			}
+			c.truncations = nil
+
 			// TODO(bob): This is a temporary hack until this frontend
 			// is switched to using the core sequencer. Very shortly,
 			// the sequencer stat will reflect the progress of
@@ -189,7 +244,7 @@ func (c *Conn) accumulateBatch(
 		return batch, c.onDataTuple(batch, msg.RelationID, msg.NewTuple, false /* isDelete */)
 
 	case *pglogrepl.TruncateMessage:
-		return nil, errors.Errorf("the TRUNCATE operation cannot be supported on table %d", msg.RelationNum)
+		return c.onTruncate(batch, msg)
 
 	case *pglogrepl.TypeMessage:
 		// This type is intentionally discarded. We interpret the
@@ -418,7 +473,7 @@ func (c *Conn) onDataTuple(
 }
 
 // learn updates the source database namespace mappings.
-func (c *Conn) onRelation(msg *pglogrepl.RelationMessage) {
+func (c *Conn) onRelation(ctx context.Context, msg *pglogrepl.RelationMessage) error {
 	// The replication protocol says that we'll see these
 	// descriptors before any use of the relation id in the
 	// stream. We'll map the int value to our table identifiers.
@@ -426,6 +481,7 @@ func (c *Conn) onRelation(msg *pglogrepl.RelationMessage) {
 	c.relations[msg.RelationID] = tbl
 
 	colNames := make([]types.ColData, len(msg.Columns))
+	sourceCols := make([]types.ColData, len(msg.Columns))
 	for idx, col := range msg.Columns {
 		colNames[idx] = types.ColData{
 			Name:    ident.New(col.Name),
@@ -434,6 +490,8 @@ func (c *Conn) onRelation(msg *pglogrepl.RelationMessage) {
 			// ConnInfo metadata methods.
 			Type: fmt.Sprintf("%d", col.DataType),
 		}
+		sourceCols[idx] = colNames[idx]
+		sourceCols[idx].Type = typeName(col.DataType)
 	}
 	c.columns.Put(tbl, colNames)
 
@@ -442,6 +500,26 @@ func (c *Conn) onRelation(msg *pglogrepl.RelationMessage) {
 		"RelationID": msg.RelationID,
 		"Table":      tbl,
 	}).Trace("learned relation")
+
+	// Create or alter the target table before any data arrives.
+	return c.evolver.Ensure(ctx, tbl, sourceCols)
+}
+
+// pgTypes is used to look up the names of built-in types.
+var pgTypes = pgtype.NewMap()
+
+// typeName returns the name of a built-in type, or an empty string if
+// the type is not known (e.g. enums and other user-defined types).
+func typeName(oid uint32) string {
+	typ, ok := pgTypes.TypeForOID(oid)
+	if !ok {
+		return ""
+	}
+	// Array types are named with a leading underscore.
+	if name, isArray := strings.CutPrefix(typ.Name, "_"); isArray {
+		return name + "[]"
+	}
+	return typ.Name
 }
 
 // persistWALOffset loads an existing value from memo into walOffset. It
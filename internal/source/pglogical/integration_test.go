@@ -39,6 +39,7 @@ import (
 	"github.com/cockroachdb/replicator/internal/sinktest/scripttest"
 	"github.com/cockroachdb/replicator/internal/util/batches"
 	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/cockroachdb/replicator/internal/util/snapshot"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/pkg/errors"
@@ -608,6 +609,104 @@ func TestEmptyTransactions(t *testing.T) {
 
 }
 
+// TestTruncate verifies that TRUNCATE messages are applied in order,
+// relative to the other mutations in the source transaction.
+func TestTruncate(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+
+	fixture, err := base.NewFixture(t)
+	r.NoError(err)
+
+	ctx := fixture.Context
+	dbSchema := fixture.TargetSchema.Schema()
+	dbName := dbSchema.Idents(nil)[0] // Extract first name part.
+	crdbPool := fixture.TargetPool
+
+	pgPool, cancel, err := setupPGPool(dbName)
+	r.NoError(err)
+	defer cancel()
+
+	parent := ident.NewTable(dbSchema, ident.New("parent"))
+	child := ident.NewTable(dbSchema, ident.New("child"))
+	schemas := []string{
+		fmt.Sprintf("CREATE TABLE %s (k INT PRIMARY KEY, v INT)", parent),
+		fmt.Sprintf("CREATE TABLE %s (k INT PRIMARY KEY, p INT REFERENCES %s(k))", child, parent),
+	}
+	for _, schema := range schemas {
+		_, err := crdbPool.ExecContext(ctx, schema)
+		r.NoError(err)
+		_, err = pgPool.Exec(ctx, schema)
+		r.NoError(err)
+	}
+
+	cancel, err = setupPublication(ctx, pgPool, dbName, "ALL TABLES")
+	r.NoError(err)
+	defer cancel()
+
+	pubNameRaw := publicationName(dbName).Raw()
+	cfg := &Config{
+		Staging: sinkprod.StagingConfig{
+			Schema: fixture.StagingDB.Schema(),
+		},
+		Target: sinkprod.TargetConfig{
+			CommonConfig: sinkprod.CommonConfig{
+				Conn: crdbPool.ConnectionString,
+			},
+			ApplyTimeout: 2 * time.Minute, // Increase to make using the debugger easier.
+		},
+		Publication:    pubNameRaw,
+		Slot:           pubNameRaw,
+		SourceConn:     *pgConnString + dbName.Raw(),
+		StandbyTimeout: 100 * time.Millisecond,
+		TargetSchema:   dbSchema,
+		TruncateMode:   TruncateModeDelete,
+	}
+	r.NoError(cfg.Preflight())
+	repl, err := Start(ctx, cfg)
+	r.NoError(err)
+
+	// Populate both tables.
+	for i := 0; i < 10; i++ {
+		_, err := pgPool.Exec(ctx, fmt.Sprintf("INSERT INTO %s VALUES ($1, $1)", parent), i)
+		r.NoError(err)
+		_, err = pgPool.Exec(ctx, fmt.Sprintf("INSERT INTO %s VALUES ($1, $1)", child), i)
+		r.NoError(err)
+	}
+
+	// Insert, truncate, and insert again in the same transaction. Only
+	// the row inserted after the truncation should survive.
+	tx, err := pgPool.Begin(ctx)
+	r.NoError(err)
+	_, err = tx.Exec(ctx, fmt.Sprintf("INSERT INTO %s VALUES (100, 100)", parent))
+	r.NoError(err)
+	_, err = tx.Exec(ctx, fmt.Sprintf("TRUNCATE %s CASCADE", parent))
+	r.NoError(err)
+	_, err = tx.Exec(ctx, fmt.Sprintf("INSERT INTO %s VALUES (200, 200)", parent))
+	r.NoError(err)
+	r.NoError(tx.Commit(ctx))
+
+	for {
+		parentCount, err := base.GetRowCount(ctx, crdbPool, parent)
+		r.NoError(err)
+		childCount, err := base.GetRowCount(ctx, crdbPool, child)
+		r.NoError(err)
+		log.Tracef("truncate counts: %d %d", parentCount, childCount)
+		if parentCount == 1 && childCount == 0 {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	var v int
+	r.NoError(crdbPool.QueryRowContext(ctx,
+		fmt.Sprintf("SELECT v FROM %s", parent)).Scan(&v))
+	a.Equal(200, v)
+
+	sinktest.CheckDiagnostics(ctx, t, repl.Diagnostics)
+	ctx.Stop(time.Second)
+	a.NoError(ctx.Wait())
+}
+
 const letterBytes = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
 
 func randString(n int) string {
@@ -817,3 +916,98 @@ func setupPublication(
 		log.Trace("finished pg pool cleanup")
 	}, nil
 }
+
+// TestSnapshot verifies that existing rows are copied by the initial
+// snapshot, which creates the replication slot, and that changes are
+// streamed once the snapshot has completed.
+func TestSnapshot(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+
+	fixture, err := base.NewFixture(t)
+	r.NoError(err)
+
+	ctx := fixture.Context
+	dbSchema := fixture.TargetSchema.Schema()
+	dbName := dbSchema.Idents(nil)[0] // Extract first name part.
+
+	pgPool, cancel, err := setupPGPool(dbName)
+	r.NoError(err)
+	defer cancel()
+
+	crdbPool := fixture.TargetPool
+	tbl := ident.NewTable(dbSchema, ident.New("snapshot"))
+
+	// Use a composite key whose order differs from the column order.
+	schema := fmt.Sprintf("CREATE TABLE %s (v TEXT, k1 INT, k2 TEXT, PRIMARY KEY (k2, k1))", tbl)
+	_, err = crdbPool.ExecContext(ctx, schema)
+	r.NoError(err)
+	_, err = pgPool.Exec(ctx, schema)
+	r.NoError(err)
+
+	const rowCount = 1024
+	_, err = pgPool.Exec(ctx, fmt.Sprintf(
+		"INSERT INTO %s SELECT 'v' || i, i, 'k' || (i %% 7) FROM generate_series(1, $1) i", tbl),
+		rowCount)
+	r.NoError(err)
+
+	// Create the publication, but not the replication slot.
+	pubName := publicationName(dbName)
+	_, err = pgPool.Exec(ctx, fmt.Sprintf("CREATE PUBLICATION %s FOR ALL TABLES", pubName))
+	r.NoError(err)
+	defer func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		_, _ = pgPool.Exec(ctx, "SELECT pg_drop_replication_slot($1)", pubName.Raw())
+		_, _ = pgPool.Exec(ctx, fmt.Sprintf("DROP PUBLICATION %s", pubName))
+	}()
+
+	cfg := &Config{
+		Snapshot: snapshot.Config{
+			BatchSize:   100,
+			Enabled:     true,
+			Parallelism: 2,
+		},
+		Staging: sinkprod.StagingConfig{
+			Schema: fixture.StagingDB.Schema(),
+		},
+		Target: sinkprod.TargetConfig{
+			CommonConfig: sinkprod.CommonConfig{
+				Conn: crdbPool.ConnectionString,
+			},
+			ApplyTimeout: 2 * time.Minute, // Increase to make using the debugger easier.
+		},
+		Publication:    pubName.Raw(),
+		Slot:           pubName.Raw(),
+		SourceConn:     *pgConnString + dbName.Raw(),
+		StandbyTimeout: 100 * time.Millisecond,
+		TargetSchema:   dbSchema,
+	}
+	r.NoError(cfg.Preflight())
+	repl, err := Start(ctx, cfg)
+	r.NoError(err)
+
+	waitFor := func(predicate string, expected int) {
+		for {
+			count, err := base.GetRowCountWithPredicate(ctx, crdbPool, tbl, predicate)
+			r.NoError(err)
+			if count == expected {
+				return
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+	waitFor("true", rowCount)
+
+	// Changes are streamed after the snapshot.
+	_, err = pgPool.Exec(ctx, fmt.Sprintf("UPDATE %s SET v = 'updated' WHERE k1 <= 100", tbl))
+	r.NoError(err)
+	_, err = pgPool.Exec(ctx, fmt.Sprintf("INSERT INTO %s VALUES ('new', 0, 'k0')", tbl))
+	r.NoError(err)
+	waitFor("v = 'updated'", 100)
+	waitFor("true", rowCount+1)
+
+	sinktest.CheckDiagnostics(ctx, t, repl.Diagnostics)
+	ctx.Stop(time.Second)
+	a.NoError(ctx.Wait())
+}
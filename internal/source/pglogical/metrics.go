@@ -34,6 +34,10 @@ var (
 		Name: "pglogical_empty_transactions",
 		Help: "the number of empty transactions we have seen",
 	})
+	truncateCount = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "pglogical_truncated_tables_total",
+		Help: "the number of tables named in TRUNCATE messages",
+	})
 	unchangedToastedColumns = promauto.NewCounter(prometheus.CounterOpts{
 		Name: "pglogical_unchanged_toasted_columns",
 		Help: "the number of times we see unchanged toasted columns",
@@ -23,8 +23,9 @@ import (
 	"github.com/cockroachdb/replicator/internal/sequencer"
 	"github.com/cockroachdb/replicator/internal/sequencer/chaos"
 	"github.com/cockroachdb/replicator/internal/sequencer/immediate"
+	"github.com/cockroachdb/replicator/internal/sequencer/pii"
 	"github.com/cockroachdb/replicator/internal/sequencer/script"
-	"github.com/cockroachdb/replicator/internal/target/apply"
+	"github.com/cockroachdb/replicator/internal/target/evolve"
 	"github.com/cockroachdb/replicator/internal/types"
 	"github.com/cockroachdb/replicator/internal/util/hlc"
 	"github.com/cockroachdb/replicator/internal/util/ident"
@@ -47,12 +48,15 @@ var Set = wire.NewSet(
 // evaluated first.
 func ProvideConn(
 	ctx *stopper.Context,
-	acc *apply.Acceptor,
+	acc types.TableAcceptor,
 	chaos *chaos.Chaos,
 	config *Config,
+	evolver *evolve.Evolver,
 	imm *immediate.Immediate,
 	memo types.Memo,
+	pii *pii.PII,
 	scriptSeq *script.Sequencer,
+	stagers types.Stagers,
 	stagingPool *types.StagingPool,
 	targetPool *types.TargetPool,
 	watchers types.Watchers,
@@ -60,6 +64,15 @@ func ProvideConn(
 	if err := config.Preflight(); err != nil {
 		return nil, err
 	}
+	if config.TruncateMode == TruncateModeTruncate {
+		switch targetPool.Product {
+		case types.ProductCockroachDB, types.ProductPostgreSQL:
+		default:
+			return nil, errors.Errorf(
+				"truncate mode %s is not supported for %s targets; use %s instead",
+				TruncateModeTruncate, targetPool.Product, TruncateModeDelete)
+		}
+	}
 	// Verify that the publication and replication slots were configured
 	// by the user. We could create the replication slot ourselves, but
 	// we want to coordinate the timing of the backup, restore, and
@@ -91,19 +104,26 @@ func ProvideConn(
 	).Scan(&count); err != nil {
 		return nil, errors.WithStack(err)
 	}
-	if count != 1 {
+	if count == 1 {
+		log.Tracef("validated that replication slot %q exists", config.Slot)
+	} else if config.Snapshot.Enabled {
+		log.Tracef("replication slot %q will be created by the initial snapshot", config.Slot)
+	} else {
 		return nil, errors.Errorf(
 			"run SELECT pg_create_logical_replication_slot('%s', 'pgoutput'); in source database, "+
-				"then perform bulk data copy",
+				"then perform bulk data copy, or use --snapshot",
 			config.Slot)
 	}
-	log.Tracef("validated that replication slot %q exists", config.Slot)
 
 	// Copy the configuration and tweak it for replication behavior.
 	sourceConfig := source.Config().Config.Copy()
 	sourceConfig.RuntimeParams["replication"] = "database"
 
-	seq, err := scriptSeq.Wrap(ctx, imm)
+	seq, err := pii.Wrap(ctx, imm) // No-op if no policies.
+	if err != nil {
+		return nil, err
+	}
+	seq, err = scriptSeq.Wrap(ctx, seq)
 	if err != nil {
 		return nil, err
 	}
@@ -111,31 +131,39 @@ func ProvideConn(
 	if err != nil {
 		return nil, err
 	}
+	group := &types.TableGroup{
+		Name:      ident.New(config.TargetSchema.Raw()),
+		Enclosing: config.TargetSchema,
+	}
 	connAcceptor, statVar, err := seq.Start(ctx, &sequencer.StartOptions{
 		Delegate: types.OrderedAcceptorFrom(acc, watchers),
 		Bounds:   &notify.Var[hlc.Range]{}, // Not currently used.
-		Group: &types.TableGroup{
-			Name:      ident.New(config.TargetSchema.Raw()),
-			Enclosing: config.TargetSchema,
-		},
+		Group:    group,
 	})
 	if err != nil {
 		return nil, err
 	}
+	sequencer.NotifyResolved(ctx, group, statVar, acc)
 
 	conn := &Conn{
 		acceptor:        connAcceptor,
 		columns:         &ident.TableMap[[]types.ColData]{},
+		evolver:         evolver,
 		memo:            memo,
 		publicationName: config.Publication,
 		relations:       make(map[uint32]ident.Table),
 		slotName:        config.Slot,
+		snapshotConfig:  &config.Snapshot,
 		sourceConfig:    sourceConfig,
+		sqlConfig:       source.Config().Copy(),
+		stagers:         stagers,
 		standbyTimeout:  config.StandbyTimeout,
 		stagingDB:       stagingPool,
 		stat:            statVar,
 		target:          config.TargetSchema,
 		targetDB:        targetPool,
+		truncateMode:    config.TruncateMode,
+		watchers:        watchers,
 	}
 	return conn, conn.Start(ctx)
 }
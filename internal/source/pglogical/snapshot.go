@@ -0,0 +1,340 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package pglogical
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/replicator/internal/util/hlc"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/cockroachdb/replicator/internal/util/snapshot"
+	"github.com/jackc/pglogrepl"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// snapshot copies the tables in the publication into the target, if an
+// initial snapshot was requested and replication has not yet started.
+// If the replication slot does not exist, it will be created and the
+// tables will be read from the snapshot that is exported by the slot.
+func (c *Conn) snapshot(ctx context.Context) error {
+	if !c.snapshotConfig.Enabled {
+		return nil
+	}
+	key := fmt.Sprintf("pglogical-snapshot-%s", c.target.Raw())
+	state, err := snapshot.Load(ctx, c.memo, c.stagingDB, key)
+	if err != nil {
+		return err
+	}
+	if state != nil && state.Done {
+		return nil
+	}
+
+	var pgReaders []*pgReader
+	if state == nil {
+		if lsn, _ := c.walOffset.Get(); lsn != 0 {
+			log.Info("replication has already started; skipping initial snapshot")
+			return nil
+		}
+		state = &snapshot.State{Time: hlc.From(time.Now())}
+		pgReaders, err = c.exportSnapshot(ctx, state)
+	} else {
+		// The exported snapshot is no longer available, so the
+		// remaining tables will be read from a newer view.
+		log.Info("resuming initial snapshot")
+		pgReaders, err = c.snapshotReaders(ctx, "")
+	}
+	if err != nil {
+		return err
+	}
+	readers := make([]snapshot.Reader, len(pgReaders))
+	for idx, r := range pgReaders {
+		readers[idx] = r
+	}
+
+	tables, err := c.snapshotTables(ctx, pgReaders[0].tx)
+	if err == nil {
+		err = snapshot.Store(ctx, c.memo, c.stagingDB, key, state)
+	}
+	if err != nil {
+		for _, r := range readers {
+			_ = r.Close()
+		}
+		return err
+	}
+	copier := &snapshot.Copier{
+		Acceptor:    c.acceptor,
+		Config:      c.snapshotConfig,
+		Key:         key,
+		Memo:        c.memo,
+		Source:      "pglogical",
+		StagingPool: c.stagingDB,
+		TargetPool:  c.targetDB,
+	}
+	return copier.Run(ctx, state, tables, readers)
+}
+
+// exportSnapshot creates the replication slot, if it does not exist,
+// and returns readers which use the snapshot exported by the slot. If
+// the slot already exists, streaming will begin from the slot's
+// position, so the readers may use any newer view of the source.
+func (c *Conn) exportSnapshot(
+	ctx context.Context, state *snapshot.State,
+) ([]*pgReader, error) {
+	replConn, err := pgconn.ConnectConfig(ctx, c.sourceConfig)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	// The exported snapshot remains valid until the replication
+	// connection is used again or closed, so the readers must be
+	// opened first.
+	defer replConn.Close(context.Background())
+
+	conn, err := pgx.ConnectConfig(ctx, c.sqlConfig)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var count int
+	err = conn.QueryRow(ctx,
+		"SELECT count(*) FROM pg_replication_slots WHERE slot_name = $1",
+		c.slotName,
+	).Scan(&count)
+	_ = conn.Close(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if count > 0 {
+		log.Infof("replication slot %q already exists; taking snapshot from current view", c.slotName)
+		return c.snapshotReaders(ctx, "")
+	}
+
+	res, err := pglogrepl.CreateReplicationSlot(ctx, replConn, c.slotName, "pgoutput",
+		pglogrepl.CreateReplicationSlotOptions{SnapshotAction: "EXPORT_SNAPSHOT"})
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not create replication slot %q", c.slotName)
+	}
+	log.Infof("created replication slot %q at %s", c.slotName, res.ConsistentPoint)
+	state.Position = res.ConsistentPoint
+	return c.snapshotReaders(ctx, res.SnapshotName)
+}
+
+// snapshotReaders opens read-only transactions against the source. If
+// a snapshot name is provided, the transactions will use it.
+func (c *Conn) snapshotReaders(
+	ctx context.Context, snapshotName string,
+) ([]*pgReader, error) {
+	ret := make([]*pgReader, 0, c.snapshotConfig.Parallelism)
+	for i := 0; i < c.snapshotConfig.Parallelism; i++ {
+		r, err := c.openReader(ctx, snapshotName)
+		if err != nil {
+			for _, r := range ret {
+				_ = r.Close()
+			}
+			return nil, err
+		}
+		ret = append(ret, r)
+	}
+	return ret, nil
+}
+
+func (c *Conn) openReader(ctx context.Context, snapshotName string) (*pgReader, error) {
+	conn, err := pgx.ConnectConfig(ctx, c.sqlConfig)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	tx, err := conn.BeginTx(ctx, pgx.TxOptions{
+		AccessMode: pgx.ReadOnly,
+		IsoLevel:   pgx.RepeatableRead,
+	})
+	if err != nil {
+		_ = conn.Close(ctx)
+		return nil, errors.WithStack(err)
+	}
+	if snapshotName != "" {
+		// The snapshot name cannot be passed as a parameter.
+		if _, err := tx.Exec(ctx, fmt.Sprintf("SET TRANSACTION SNAPSHOT '%s'",
+			strings.ReplaceAll(snapshotName, "'", "''"))); err != nil {
+			_ = conn.Close(ctx)
+			return nil, errors.Wrap(err, "could not import snapshot")
+		}
+	}
+	return &pgReader{conn: conn, tx: tx}, nil
+}
+
+// snapshotTables returns the tables in the publication.
+func (c *Conn) snapshotTables(ctx context.Context, tx pgx.Tx) ([]*snapshot.Table, error) {
+	rows, err := tx.Query(ctx,
+		"SELECT schemaname, tablename FROM pg_publication_tables WHERE pubname = $1 "+
+			"ORDER BY schemaname, tablename",
+		c.publicationName)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var ret []*snapshot.Table
+	for rows.Next() {
+		var schemaName, tableName string
+		if err := rows.Scan(&schemaName, &tableName); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		ret = append(ret, &snapshot.Table{
+			Source: ident.NewTable(ident.MustSchema(ident.New(schemaName)), ident.New(tableName)),
+			// Consistent with onRelation.
+			Target: ident.NewTable(c.target, ident.New(tableName)),
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	for _, table := range ret {
+		if err := loadSnapshotColumns(ctx, tx, table); err != nil {
+			return nil, err
+		}
+	}
+	return ret, nil
+}
+
+// loadSnapshotColumns populates the columns and primary key of the
+// table. The key columns are ordered as in the primary index, so that
+// the index can be used to read the table in key order.
+func loadSnapshotColumns(ctx context.Context, tx pgx.Tx, table *snapshot.Table) error {
+	load := func(q string) ([]ident.Ident, error) {
+		rows, err := tx.Query(ctx, q, table.Source.String())
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not load columns of %s", table.Source)
+		}
+		var ret []ident.Ident
+		for rows.Next() {
+			var name string
+			if err := rows.Scan(&name); err != nil {
+				return nil, errors.WithStack(err)
+			}
+			ret = append(ret, ident.New(name))
+		}
+		return ret, errors.WithStack(rows.Err())
+	}
+	var err error
+	table.Columns, err = load(`
+SELECT attname FROM pg_attribute
+ WHERE attrelid = $1::regclass AND attnum > 0 AND NOT attisdropped
+ ORDER BY attnum`)
+	if err != nil {
+		return err
+	}
+	table.Key, err = load(`
+SELECT a.attname
+  FROM pg_index i
+  JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = ANY(i.indkey)
+ WHERE i.indrelid = $1::regclass AND i.indisprimary
+ ORDER BY array_position(i.indkey::int2[], a.attnum)`)
+	return err
+}
+
+// pgReader reads from a repeatable-read transaction. Values are
+// returned in their textual representation, to match the values
+// received from the replication stream.
+type pgReader struct {
+	conn *pgx.Conn
+	tx   pgx.Tx
+}
+
+var _ snapshot.Reader = (*pgReader)(nil)
+
+// Close implements [snapshot.Reader].
+func (r *pgReader) Close() error {
+	_ = r.tx.Rollback(context.Background())
+	return errors.WithStack(r.conn.Close(context.Background()))
+}
+
+// Read implements [snapshot.Reader].
+func (r *pgReader) Read(
+	ctx context.Context, table *snapshot.Table, after []any, limit int,
+) ([][]any, error) {
+	var q strings.Builder
+	q.WriteString("SELECT ")
+	for idx, col := range table.Columns {
+		if idx > 0 {
+			q.WriteString(", ")
+		}
+		fmt.Fprintf(&q, "%s::text", col)
+	}
+	fmt.Fprintf(&q, " FROM %s", table.Source)
+	var args []any
+	if after != nil {
+		// Compare the text values after casting them to the type of
+		// the key columns.
+		q.WriteString(" WHERE (")
+		for idx, col := range table.Key {
+			if idx > 0 {
+				q.WriteString(", ")
+			}
+			q.WriteString(col.String())
+		}
+		q.WriteString(") > (")
+		for idx := range table.Key {
+			if idx > 0 {
+				q.WriteString(", ")
+			}
+			fmt.Fprintf(&q, "$%d", idx+1)
+		}
+		q.WriteString(")")
+		for _, value := range after {
+			args = append(args, fmt.Sprint(value))
+		}
+	}
+	q.WriteString(" ORDER BY ")
+	for idx, col := range table.Key {
+		if idx > 0 {
+			q.WriteString(", ")
+		}
+		q.WriteString(col.String())
+	}
+	fmt.Fprintf(&q, " LIMIT %d", limit)
+
+	// The simple protocol sends the arguments as untyped literals,
+	// which are coerced to the type of the key columns.
+	rows, err := r.tx.Query(ctx, q.String(),
+		append([]any{pgx.QueryExecModeSimpleProtocol}, args...)...)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer rows.Close()
+	var ret [][]any
+	for rows.Next() {
+		values := make([]*string, len(table.Columns))
+		dest := make([]any, len(values))
+		for idx := range values {
+			dest[idx] = &values[idx]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		row := make([]any, len(values))
+		for idx, value := range values {
+			if value != nil {
+				row[idx] = *value
+			}
+		}
+		ret = append(ret, row)
+	}
+	return ret, errors.WithStack(rows.Err())
+}
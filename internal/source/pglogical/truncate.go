@@ -0,0 +1,181 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package pglogical
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/hlc"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/jackc/pglogrepl"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// A truncation records a TRUNCATE message that was received in the
+// middle of a source transaction. The mutations that preceded the
+// TRUNCATE must be applied before the truncation, so they are held
+// alongside it.
+type truncation struct {
+	before          *types.TemporalBatch // Mutations received before the TRUNCATE.
+	cascade         bool                 // TRUNCATE ... CASCADE
+	restartIdentity bool                 // TRUNCATE ... RESTART IDENTITY
+	tables          []ident.Table        // The tables named in the message.
+}
+
+// onTruncate records the truncation, to be executed when the enclosing
+// transaction commits. It returns a new batch into which subsequent
+// mutations will be accumulated.
+func (c *Conn) onTruncate(
+	batch *types.TemporalBatch, msg *pglogrepl.TruncateMessage,
+) (*types.TemporalBatch, error) {
+	if c.truncateMode == TruncateModeReject {
+		return nil, errors.Errorf("the TRUNCATE operation cannot be supported on table %d; "+
+			"set --truncateMode to enable", msg.RelationNum)
+	}
+	if batch == nil {
+		log.Trace("ignoring replayed message")
+		return nil, nil
+	}
+	tables := make([]ident.Table, len(msg.RelationIDs))
+	for idx, relation := range msg.RelationIDs {
+		tbl, ok := c.relations[relation]
+		if !ok {
+			return nil, errors.Errorf("unknown relation id %d", relation)
+		}
+		tables[idx] = tbl
+	}
+	c.truncations = append(c.truncations, &truncation{
+		before:          batch,
+		cascade:         msg.Option&pglogrepl.TruncateOptionCascade != 0,
+		restartIdentity: msg.Option&pglogrepl.TruncateOptionRestartIdentity != 0,
+		tables:          tables,
+	})
+	truncateCount.Add(float64(len(tables)))
+	log.WithFields(log.Fields{
+		"options": msg.Option,
+		"tables":  tables,
+	}).Debug("received truncate")
+	return &types.TemporalBatch{Time: batch.Time}, nil
+}
+
+// applyTruncation executes the truncation within the target
+// transaction.
+func (c *Conn) applyTruncation(
+	ctx context.Context, tx types.TargetQuerier, trunc *truncation,
+) error {
+	watcher, err := c.watchers.Get(c.target)
+	if err != nil {
+		return err
+	}
+	tables, err := truncationOrder(watcher.Get(), trunc)
+	if err != nil {
+		return err
+	}
+
+	switch c.truncateMode {
+	case TruncateModeDelete:
+		// Child tables will appear before their parents.
+		for _, tbl := range tables {
+			if _, err := tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s", tbl)); err != nil {
+				return errors.Wrap(err, tbl.String())
+			}
+		}
+		if trunc.restartIdentity {
+			log.Debug("RESTART IDENTITY is ignored when deleting from target tables")
+		}
+
+	case TruncateModeTruncate:
+		var sb strings.Builder
+		sb.WriteString("TRUNCATE TABLE ")
+		for idx, tbl := range tables {
+			if idx > 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString(tbl.String())
+		}
+		if trunc.restartIdentity {
+			if c.targetDB.Product == types.ProductPostgreSQL {
+				sb.WriteString(" RESTART IDENTITY")
+			} else {
+				log.Debugf("RESTART IDENTITY is not supported by %s", c.targetDB.Product)
+			}
+		}
+		if trunc.cascade {
+			sb.WriteString(" CASCADE")
+		}
+		if _, err := tx.ExecContext(ctx, sb.String()); err != nil {
+			return errors.Wrap(err, sb.String())
+		}
+
+	default:
+		return errors.Errorf("unimplemented: %s", c.truncateMode)
+	}
+	return nil
+}
+
+// retireTruncated removes any staged mutations for the truncated
+// tables, up to and including the time of the truncation.
+func (c *Conn) retireTruncated(ctx context.Context, trunc *truncation, end hlc.Time) error {
+	for _, tbl := range trunc.tables {
+		stager, err := c.stagers.Get(ctx, tbl)
+		if err != nil {
+			return err
+		}
+		if err := stager.Retire(ctx, c.stagingDB, end); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// truncationOrder returns the target tables to operate on, such that
+// child tables will appear before their parents. If the truncation
+// cascades, all tables that (transitively) reference the truncated
+// tables will also be included.
+func truncationOrder(schema *types.SchemaData, trunc *truncation) ([]ident.Table, error) {
+	selected := &ident.TableMap[bool]{}
+	var visit func(tbl ident.Table)
+	visit = func(tbl ident.Table) {
+		if selected.GetZero(tbl) {
+			return
+		}
+		selected.Put(tbl, true)
+		if trunc.cascade {
+			for _, child := range schema.Dependencies.GetZero(tbl) {
+				visit(child)
+			}
+		}
+	}
+	for _, tbl := range trunc.tables {
+		if _, ok := schema.TableComponents.Get(tbl); !ok {
+			return nil, errors.Errorf("truncated table %s not found in target", tbl)
+		}
+		visit(tbl)
+	}
+
+	ret := make([]ident.Table, 0, selected.Len())
+	for _, tbl := range schema.Entire.ReverseOrder {
+		if selected.GetZero(tbl) {
+			ret = append(ret, tbl)
+		}
+	}
+	return ret, nil
+}
@@ -0,0 +1,110 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package pglogical
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTruncationOrder(t *testing.T) {
+	r := require.New(t)
+	sch := ident.MustSchema(ident.New("db"), ident.Public)
+	parent := ident.NewTable(sch, ident.New("parent"))
+	child := ident.NewTable(sch, ident.New("child"))
+	grandchild := ident.NewTable(sch, ident.New("grandchild"))
+	other := ident.NewTable(sch, ident.New("other"))
+
+	deps := &ident.TableMap[[]ident.Table]{}
+	deps.Put(parent, []ident.Table{child})
+	deps.Put(child, []ident.Table{grandchild})
+	deps.Put(other, nil)
+	schema := &types.SchemaData{}
+	r.NoError(schema.SetDependencies(deps))
+
+	tcs := []struct {
+		name     string
+		trunc    *truncation
+		expected []ident.Table
+		err      string
+	}{
+		{
+			name:     "single",
+			trunc:    &truncation{tables: []ident.Table{child}},
+			expected: []ident.Table{child},
+		},
+		{
+			name:     "explicit",
+			trunc:    &truncation{tables: []ident.Table{parent, grandchild}},
+			expected: []ident.Table{grandchild, parent},
+		},
+		{
+			name:     "cascade",
+			trunc:    &truncation{cascade: true, tables: []ident.Table{parent}},
+			expected: []ident.Table{grandchild, child, parent},
+		},
+		{
+			name:     "cascade_leaf",
+			trunc:    &truncation{cascade: true, tables: []ident.Table{other}},
+			expected: []ident.Table{other},
+		},
+		{
+			name: "unknown",
+			trunc: &truncation{tables: []ident.Table{
+				ident.NewTable(sch, ident.New("unknown"))}},
+			err: "not found in target",
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			r := require.New(t)
+			found, err := truncationOrder(schema, tc.trunc)
+			if tc.err != "" {
+				r.ErrorContains(err, tc.err)
+				return
+			}
+			r.NoError(err)
+			r.Equal(tc.expected, found)
+		})
+	}
+}
+
+func TestTruncateModeRedirected(t *testing.T) {
+	r := require.New(t)
+
+	cfg := &Config{}
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	cfg.Bind(flags)
+	r.NoError(flags.Parse([]string{
+		"--publicationName", "pub",
+		"--sourceConn", "postgresql://source",
+		"--stagingConn", "postgresql://staging",
+		"--targetConn", "postgresql://target",
+		"--targetSchema", "db.public",
+		"--targetStorageURL", "file:///tmp/out",
+		"--truncateMode", "delete",
+	}))
+	r.ErrorContains(cfg.Preflight(), "--truncateMode delete cannot be used")
+
+	r.NoError(flags.Set("truncateMode", "reject"))
+	r.NoError(cfg.Preflight())
+}
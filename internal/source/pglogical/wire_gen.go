@@ -12,6 +12,7 @@ import (
 	"github.com/cockroachdb/replicator/internal/sequencer/chaos"
 	"github.com/cockroachdb/replicator/internal/sequencer/decorators"
 	"github.com/cockroachdb/replicator/internal/sequencer/immediate"
+	"github.com/cockroachdb/replicator/internal/sequencer/pii"
 	script2 "github.com/cockroachdb/replicator/internal/sequencer/script"
 	"github.com/cockroachdb/replicator/internal/sinkprod"
 	"github.com/cockroachdb/replicator/internal/staging/memo"
@@ -19,6 +20,7 @@ import (
 	"github.com/cockroachdb/replicator/internal/staging/version"
 	"github.com/cockroachdb/replicator/internal/target/apply"
 	"github.com/cockroachdb/replicator/internal/target/dlq"
+	"github.com/cockroachdb/replicator/internal/target/evolve"
 	"github.com/cockroachdb/replicator/internal/target/load"
 	"github.com/cockroachdb/replicator/internal/target/schemawatch"
 	"github.com/cockroachdb/replicator/internal/util/applycfg"
@@ -50,15 +52,19 @@ func Start(context *stopper.Context, config *Config) (*PGLogical, error) {
 	if err != nil {
 		return nil, err
 	}
+	store, err := sinkprod.ProvideStagingLocal(context, stagingConfig)
+	if err != nil {
+		return nil, err
+	}
 	stagingSchema, err := sinkprod.ProvideStagingDB(context, stagingConfig, stagingPool)
 	if err != nil {
 		return nil, err
 	}
-	memoMemo, err := memo.ProvideMemo(context, stagingPool, stagingSchema)
+	typesMemo, err := memo.ProvideMemo(context, stagingPool, store, stagingSchema)
 	if err != nil {
 		return nil, err
 	}
-	checker := version.ProvideChecker(stagingPool, memoMemo)
+	checker := version.ProvideChecker(stagingPool, typesMemo)
 	targetPool, err := sinkprod.ProvideTargetPool(context, checker, targetConfig, diagnostics)
 	if err != nil {
 		return nil, err
@@ -68,7 +74,7 @@ func Start(context *stopper.Context, config *Config) (*PGLogical, error) {
 		return nil, err
 	}
 	dlqConfig := &eagerConfig.DLQ
-	backup := schemawatch.ProvideBackup(memoMemo, stagingPool)
+	backup := schemawatch.ProvideBackup(typesMemo, stagingPool)
 	watchers, err := schemawatch.ProvideFactory(context, targetPool, diagnostics, backup)
 	if err != nil {
 		return nil, err
@@ -82,24 +88,41 @@ func Start(context *stopper.Context, config *Config) (*PGLogical, error) {
 	if err != nil {
 		return nil, err
 	}
+	tableAcceptor, err := sinkprod.ProvideTableAcceptor(context, acceptor, targetConfig)
+	if err != nil {
+		return nil, err
+	}
 	sequencerConfig := &eagerConfig.Sequencer
 	chaosChaos := &chaos.Chaos{
 		Config: sequencerConfig,
 	}
-	stagers := stage.ProvideFactory(stagingPool, stagingSchema, context)
+	evolveConfig := &eagerConfig.Evolve
+	evolver, err := evolve.ProvideEvolver(evolveConfig, typesMemo, stagingPool, targetPool, watchers)
+	if err != nil {
+		return nil, err
+	}
+	stagers := stage.ProvideFactory(stagingPool, store, stagingSchema, context)
 	marker := decorators.ProvideMarker(stagingPool, stagers)
 	once := decorators.ProvideOnce(stagingPool, stagers)
 	retryTarget := decorators.ProvideRetryTarget(targetPool)
 	immediateImmediate := immediate.ProvideImmediate(sequencerConfig, targetPool, marker, once, retryTarget, stagers)
+	keys, err := pii.ProvideKeys(sequencerConfig)
+	if err != nil {
+		return nil, err
+	}
+	piiPII, err := pii.ProvideShim(context, sequencerConfig, diagnostics, keys, watchers)
+	if err != nil {
+		return nil, err
+	}
 	sequencer := script2.ProvideSequencer(loader, targetPool, watchers)
-	conn, err := ProvideConn(context, acceptor, chaosChaos, config, immediateImmediate, memoMemo, sequencer, stagingPool, targetPool, watchers)
+	conn, err := ProvideConn(context, tableAcceptor, chaosChaos, config, evolver, immediateImmediate, typesMemo, piiPII, sequencer, stagers, stagingPool, targetPool, watchers)
 	if err != nil {
 		return nil, err
 	}
 	pgLogical := &PGLogical{
 		Conn:        conn,
 		Diagnostics: diagnostics,
-		Memo:        memoMemo,
+		Memo:        typesMemo,
 	}
 	return pgLogical, nil
 }
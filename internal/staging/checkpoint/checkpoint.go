@@ -20,7 +20,7 @@ package checkpoint
 
 import (
 	"context"
-	"fmt"
+	"time"
 
 	"github.com/cockroachdb/field-eng-powertools/notify"
 	"github.com/cockroachdb/field-eng-powertools/stopper"
@@ -34,8 +34,41 @@ import (
 // Checkpoints is a factory for [Group] instances, which manage
 // checkpoint timestamps associated with a group of tables.
 type Checkpoints struct {
-	pool      *types.StagingPool
-	metaTable ident.Table
+	backend backend
+	pool    *types.StagingPool
+}
+
+// A backend provides durable storage for checkpoints. It is
+// implemented by sqlBackend and localBackend.
+type backend interface {
+	// newStore returns the storage for a single group.
+	newStore(group *types.TableGroup, lookahead int, delay time.Duration) store
+	// scanForTargetSchemas implements [Checkpoints.ScanForTargetSchemas].
+	scanForTargetSchemas(ctx context.Context) ([]ident.Schema, error)
+}
+
+// A store reads and writes the checkpoints of a single group. Errors
+// will be retried by the [Group].
+type store interface {
+	// advance records a new checkpoint for the partition, returning
+	// false if the checkpoint would go backwards.
+	advance(ctx context.Context, partition ident.Ident, ts hlc.Time) (bool, error)
+	// commit marks the checkpoints within the open range as applied.
+	commit(ctx context.Context, rng hlc.Range) error
+	// ensure creates a minimum, applied checkpoint for any partition
+	// that does not have a checkpoint.
+	ensure(ctx context.Context, partitions []ident.Ident) error
+	// refresh computes the range of checkpoints which should be
+	// processed. See refreshTemplate for details.
+	refresh(ctx context.Context, knownCommitted hlc.Time) (hlc.Range, error)
+	// rewind marks all checkpoints after the time as unapplied.
+	rewind(ctx context.Context, to hlc.Time) error
+}
+
+// A streamer is a store that can receive notifications when the
+// checkpoints are updated by another instance.
+type streamer interface {
+	streamJob(ctx *stopper.Context, group *Group)
 }
 
 // Start a background goroutine to update the provided bounds variable.
@@ -45,10 +78,17 @@ type Checkpoints struct {
 func (r *Checkpoints) Start(
 	ctx *stopper.Context, group *types.TableGroup, bounds *notify.Var[hlc.Range], options ...Option,
 ) (*Group, error) {
+	var delayBy time.Duration
 	var lookahead int
+	var onCommits []onCommit
 	useStream := true
 	for _, opt := range options {
 		switch t := opt.(type) {
+		case delay:
+			delayBy = time.Duration(t)
+			if delayBy < 0 {
+				return nil, errors.New("delay must not be negative")
+			}
 		case disableStream:
 			useStream = false
 		case limitLookahead:
@@ -56,28 +96,33 @@ func (r *Checkpoints) Start(
 			if lookahead <= 0 {
 				return nil, errors.New("lookahead must be greater than zero")
 			}
+		case onCommit:
+			onCommits = append(onCommits, t)
 		}
 	}
-	ret := r.newGroup(group, bounds, lookahead)
+	ret := r.newGroup(group, bounds, lookahead, delayBy)
+	ret.onCommit = onCommits
 	// Populate data immediately.
 	if err := ret.refreshBounds(ctx); err != nil {
 		return nil, err
 	}
+	ret.resumeCallbacks()
 	ret.refreshJob(ctx)
 	ret.reportMetrics(ctx)
-	if useStream {
-		ret.streamJob(ctx)
+	if s, ok := ret.store.(streamer); ok && useStream {
+		s.streamJob(ctx, ret)
 	}
 
 	return ret, nil
 }
 
 func (r *Checkpoints) newGroup(
-	group *types.TableGroup, bounds *notify.Var[hlc.Range], lookahead int,
+	group *types.TableGroup, bounds *notify.Var[hlc.Range], lookahead int, delay time.Duration,
 ) *Group {
 	ret := &Group{
 		bounds: bounds,
 		pool:   r.pool,
+		store:  r.backend.newStore(group, lookahead, delay),
 		target: group,
 	}
 
@@ -90,51 +135,11 @@ func (r *Checkpoints) newGroup(
 	ret.metrics.proposedAge = proposedAge.With(labels)
 	ret.metrics.proposedTime = proposedTime.With(labels)
 	ret.metrics.refreshDuration = refreshDuration.With(labels)
-
-	var limit string
-	if lookahead > 0 {
-		// +1 to have a window that includes both the last applied
-		// checkpoint and the next unapplied checkpoint.
-		limit = fmt.Sprintf("WHERE r <= %d", lookahead+1)
-	}
-	// This query may indeed require a full table scan.
-	ret.sql.refresh = fmt.Sprintf(refreshTemplate, r.metaTable, limit)
-	ret.sql.stream = fmt.Sprintf(streamTemplate, r.metaTable)
-
-	hinted := r.pool.HintNoFTS(r.metaTable)
-	ret.sql.advance = fmt.Sprintf(advanceTemplate, hinted)
-	ret.sql.ensure = fmt.Sprintf(ensureTemplate, hinted)
-	ret.sql.commit = fmt.Sprintf(commitTemplate, hinted)
 	return ret
 }
 
-const scanForTargetTemplate = `
-SELECT DISTINCT group_name
-FROM %[1]s
-WHERE target_applied_at IS NULL
-`
-
 // ScanForTargetSchemas reports any group names that have unresolved
 // timestamps.
 func (r *Checkpoints) ScanForTargetSchemas(ctx context.Context) ([]ident.Schema, error) {
-	rows, err := r.pool.Query(ctx, fmt.Sprintf(scanForTargetTemplate, r.metaTable))
-	if err != nil {
-		return nil, errors.WithStack(err)
-	}
-	defer rows.Close()
-	var ret []ident.Schema
-	for rows.Next() {
-		var schemaRaw string
-		if err := rows.Scan(&schemaRaw); err != nil {
-			return nil, errors.WithStack(err)
-		}
-
-		sch, err := ident.ParseSchema(schemaRaw)
-		if err != nil {
-			return nil, err
-		}
-		ret = append(ret, sch)
-	}
-
-	return ret, nil
+	return r.backend.scanForTargetSchemas(ctx)
 }
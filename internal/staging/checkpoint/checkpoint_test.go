@@ -17,7 +17,9 @@
 package checkpoint
 
 import (
+	"context"
 	"fmt"
+	"sync"
 	"testing"
 	"time"
 
@@ -28,6 +30,7 @@ import (
 	"github.com/cockroachdb/replicator/internal/util/hlc"
 	"github.com/cockroachdb/replicator/internal/util/ident"
 	"github.com/jackc/pgx/v5"
+	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/require"
 )
@@ -40,7 +43,7 @@ func TestResolved(t *testing.T) {
 
 	ctx := fixture.Context
 
-	chk, err := ProvideCheckpoints(ctx, fixture.StagingPool, fixture.StagingDB)
+	chk, err := ProvideCheckpoints(ctx, fixture.StagingPool, nil, fixture.StagingDB)
 	r.NoError(err)
 
 	// We're going to test that two independent groups operating on the
@@ -153,7 +156,7 @@ func TestLimitLookahead(t *testing.T) {
 
 	ctx := fixture.Context
 
-	chk, err := ProvideCheckpoints(ctx, fixture.StagingPool, fixture.StagingDB)
+	chk, err := ProvideCheckpoints(ctx, fixture.StagingPool, nil, fixture.StagingDB)
 	r.NoError(err)
 
 	g1, err := chk.Start(ctx,
@@ -214,6 +217,73 @@ func TestLimitLookahead(t *testing.T) {
 	r.Equal(hlc.RangeIncluding(hlc.New(maxNanos, 0), hlc.New(maxNanos, 0)), rng)
 }
 
+// This test verifies that OnCommit callbacks are invoked once a range
+// has been committed and that failed callbacks are retried.
+func TestOnCommit(t *testing.T) {
+	r := require.New(t)
+
+	fixture, err := base.NewFixture(t)
+	r.NoError(err)
+
+	ctx := fixture.Context
+
+	chk, err := ProvideCheckpoints(ctx, fixture.StagingPool, nil, fixture.StagingDB)
+	r.NoError(err)
+
+	var mu sync.Mutex
+	var committed []hlc.Range
+	var failCommit bool
+	g, err := chk.Start(ctx,
+		&types.TableGroup{Name: ident.New("fake")},
+		&notify.Var[hlc.Range]{},
+		OnCommit(func(_ context.Context, rng hlc.Range) error {
+			mu.Lock()
+			defer mu.Unlock()
+			if failCommit {
+				return errors.New("callback failed")
+			}
+			committed = append(committed, rng)
+			return nil
+		}),
+	)
+	r.NoError(err)
+	getCommitted := func() []hlc.Range {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]hlc.Range(nil), committed...)
+	}
+
+	part := ident.New("partition")
+	r.NoError(g.Advance(ctx, part, hlc.New(1, 0)))
+	r.NoError(g.Advance(ctx, part, hlc.New(2, 0)))
+
+	rng := hlc.RangeIncluding(hlc.Zero(), hlc.New(1, 0))
+	r.NoError(g.Commit(ctx, rng))
+	r.Equal([]hlc.Range{rng}, getCommitted())
+
+	// Errors from the callback don't fail the commit, since the
+	// checkpoint has already been recorded.
+	mu.Lock()
+	failCommit = true
+	mu.Unlock()
+	next := hlc.RangeIncluding(hlc.Zero(), hlc.New(2, 0))
+	r.NoError(g.Commit(ctx, next))
+	found, err := g.refreshQuery(ctx, hlc.Zero())
+	r.NoError(err)
+	r.Equal(hlc.New(2, 0), found.Min())
+	r.Equal([]hlc.Range{rng}, getCommitted())
+
+	// The failed callback is retried in the background.
+	mu.Lock()
+	failCommit = false
+	mu.Unlock()
+	g.Refresh()
+	r.Eventually(func() bool {
+		return len(getCommitted()) == 2
+	}, 10*time.Second, 10*time.Millisecond)
+	r.Equal([]hlc.Range{rng, next}, getCommitted())
+}
+
 // This test validates that an update to one group wakes another.
 func TestStreamNotification(t *testing.T) {
 	r := require.New(t)
@@ -223,23 +293,25 @@ func TestStreamNotification(t *testing.T) {
 
 	ctx := fixture.Context
 
-	chk, err := ProvideCheckpoints(ctx, fixture.StagingPool, fixture.StagingDB)
+	chk, err := ProvideCheckpoints(ctx, fixture.StagingPool, nil, fixture.StagingDB)
 	r.NoError(err)
 
 	// Construct a partial group that only runs the stream.
 	receiver := chk.newGroup(
 		&types.TableGroup{Name: ident.New("fake")},
 		&notify.Var[hlc.Range]{},
-		0,
+		0, // lookahead
+		0, // delay
 	)
 	receiver.streamConn = notify.VarOf[*pgx.Conn](nil)
 	_, woken := receiver.fastWakeup.Get()
-	receiver.streamJob(ctx)
+	receiver.store.(streamer).streamJob(ctx, receiver)
 
 	sender := chk.newGroup(
 		&types.TableGroup{Name: ident.New("fake")},
 		&notify.Var[hlc.Range]{},
-		0,
+		0, // lookahead
+		0, // delay
 	)
 
 	select {
@@ -290,7 +362,7 @@ func testTransitions(t *testing.T, partitionCount int) {
 	r.NoError(err)
 	ctx := fixture.Context
 
-	chk, err := ProvideCheckpoints(ctx, fixture.StagingPool, fixture.StagingDB)
+	chk, err := ProvideCheckpoints(ctx, fixture.StagingPool, nil, fixture.StagingDB)
 	r.NoError(err)
 
 	partIdent := func(part int) ident.Ident {
@@ -303,7 +375,8 @@ func testTransitions(t *testing.T, partitionCount int) {
 			Enclosing: fixture.TargetSchema.Schema(),
 		},
 		notify.VarOf(hlc.RangeEmpty()),
-		1024,
+		1024, // lookahead
+		0,    // delay
 	)
 
 	expect := func(low, high int) {
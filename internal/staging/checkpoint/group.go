@@ -18,7 +18,7 @@ package checkpoint
 
 import (
 	"context"
-	"database/sql"
+	"sync"
 	"time"
 
 	"github.com/cockroachdb/field-eng-powertools/notify"
@@ -70,10 +70,22 @@ CREATE TABLE IF NOT EXISTS %[1]s (
 type Group struct {
 	bounds     *notify.Var[hlc.Range]
 	fastWakeup notify.Var[struct{}]
+	onCommit   []onCommit
 	pool       *types.StagingPool
+	store      store
 	streamConn *notify.Var[*pgx.Conn] // Used for testing.
 	target     *types.TableGroup
 
+	// Tracks the OnCommit callbacks which must be retried.
+	callbacks struct {
+		sync.Mutex
+		pending bool      // The callbacks have not succeeded for rng.
+		rng     hlc.Range // The most recently committed range.
+	}
+	// Serializes invocations of the OnCommit callbacks, so that they
+	// observe committed ranges in order.
+	callbacksRunning sync.Mutex
+
 	metrics struct {
 		advanceDuration prometheus.Observer
 		backwards       prometheus.Counter
@@ -84,14 +96,6 @@ type Group struct {
 		proposedTime    prometheus.Gauge
 		refreshDuration prometheus.Observer
 	}
-
-	sql struct {
-		advance string
-		commit  string
-		ensure  string
-		refresh string
-		stream  string
-	}
 }
 
 // This query conditionally inserts a new mark for a partition if
@@ -123,16 +127,11 @@ SELECT * FROM to_insert`
 // asynchronously refresh the Group.
 func (r *Group) Advance(ctx context.Context, partition ident.Ident, ts hlc.Time) error {
 	start := time.Now()
-	tag, err := r.pool.Exec(ctx,
-		r.sql.advance,
-		r.target.Name.Canonical().Raw(),
-		partition.Canonical().Raw(),
-		ts,
-	)
+	advanced, err := r.store.advance(ctx, partition, ts)
 	if err != nil {
-		return errors.WithStack(err)
+		return err
 	}
-	if tag.RowsAffected() == 0 {
+	if !advanced {
 		r.metrics.backwards.Inc()
 		return errors.Errorf(
 			"proposed checkpoint timestamp for group=%s, partition=%s is going backwards: %s; "+
@@ -166,26 +165,89 @@ AND target_applied_at IS NULL
 
 // Commit updates the applied-at timestamp associated with the
 // checkpoints in the open range [min,max). This will asynchronously
-// refresh the Group.
+// refresh the Group and then invoke any [OnCommit] callbacks. The
+// result of Commit does not depend upon the callbacks, since the
+// checkpoints will already have been recorded.
 func (r *Group) Commit(ctx context.Context, rng hlc.Range) error {
 	err := retry.Retry(ctx, r.pool, func(ctx context.Context) error {
 		start := time.Now()
-		_, err := r.pool.Exec(ctx,
-			r.sql.commit,
-			r.target.Name.Canonical().Raw(),
-			rng.Min(),
-			rng.Max(),
-		)
+		err := r.store.commit(ctx, rng)
 		if err == nil {
 			r.metrics.commitDuration.Observe(time.Since(start).Seconds())
 		}
-		return errors.WithStack(err)
+		return err
 	})
-	if err == nil {
-		log.Tracef("recorded checkpoint timestamps for %s: %s", r.target, rng)
-		r.Refresh()
+	if err != nil {
+		return err
 	}
-	return err
+	log.Tracef("recorded checkpoint timestamps for %s: %s", r.target, rng)
+	r.Refresh()
+	if len(r.onCommit) > 0 {
+		r.callbacks.Lock()
+		r.callbacks.pending = true
+		r.callbacks.rng = rng
+		r.callbacks.Unlock()
+		r.runCallbacks(ctx)
+	}
+	return nil
+}
+
+// runCallbacks invokes the [OnCommit] callbacks for the most recently
+// committed range. If a callback fails, it will be retried by the
+// refresh loop until it succeeds or the range is superseded by a later
+// call to Commit. The callbacks are not invoked while holding the
+// callbacks lock, so a slow callback will not delay the recording of a
+// newer range.
+func (r *Group) runCallbacks(ctx context.Context) {
+	r.callbacksRunning.Lock()
+	defer r.callbacksRunning.Unlock()
+
+	r.callbacks.Lock()
+	pending, rng := r.callbacks.pending, r.callbacks.rng
+	r.callbacks.Unlock()
+	if !pending {
+		return
+	}
+
+	for _, fn := range r.onCommit {
+		if err := fn(ctx, rng); err != nil {
+			log.WithError(err).Warnf("commit callback failed for %s at %s; will retry",
+				r.target, rng)
+			return
+		}
+	}
+
+	// Don't clear the flag if a newer range was committed while the
+	// callbacks were running.
+	r.callbacks.Lock()
+	if r.callbacks.rng == rng {
+		r.callbacks.pending = false
+	}
+	r.callbacks.Unlock()
+}
+
+// resumeCallbacks arranges for the refresh loop to invoke the
+// [OnCommit] callbacks for the most recently committed checkpoint when
+// the Group is started.
+// A callback that was still being retried when the process exited
+// would otherwise not be invoked until the next range is committed.
+func (r *Group) resumeCallbacks() {
+	if len(r.onCommit) == 0 {
+		return
+	}
+	bounds, _ := r.bounds.Get()
+	committed := bounds.Min()
+	// Ignore the placeholder checkpoints created by Ensure.
+	if hlc.Compare(committed, hlc.New(1, 1)) <= 0 {
+		return
+	}
+	r.callbacks.Lock()
+	defer r.callbacks.Unlock()
+	if r.callbacks.pending {
+		return
+	}
+	r.callbacks.pending = true
+	r.callbacks.rng = hlc.RangeIncluding(committed, committed)
 }
 
 const ensureTemplate = `
@@ -200,18 +262,7 @@ SELECT $1, $2, 1.0000000001, now()
 // number of partitions associated with a group at any point in time.
 func (r *Group) Ensure(ctx context.Context, partitions []ident.Ident) error {
 	err := retry.Retry(ctx, r.pool, func(ctx context.Context) error {
-		tx, err := r.pool.Begin(ctx)
-		if err != nil {
-			return errors.WithStack(err)
-		}
-		defer func() { _ = tx.Rollback(ctx) }()
-
-		for _, part := range partitions {
-			if _, err := tx.Exec(ctx, r.sql.ensure, r.target.Name.Raw(), part.Raw()); err != nil {
-				return errors.Wrap(err, r.sql.ensure)
-			}
-		}
-		return errors.WithStack(tx.Commit(ctx))
+		return r.store.ensure(ctx, partitions)
 	})
 	if err == nil {
 		r.Refresh()
@@ -224,6 +275,42 @@ func (r *Group) Refresh() {
 	r.fastWakeup.Notify()
 }
 
+// Clears target_applied_at.
+//
+// $1 = group_name
+// $2 = source_hlc
+const rewindTemplate = `
+UPDATE %s
+SET target_applied_at = NULL
+WHERE group_name = $1
+AND source_hlc > $2
+AND target_applied_at IS NOT NULL
+`
+
+// Rewind marks all checkpoints after the given time as being
+// unapplied and synchronously resets the bounds of the Group. The
+// minimum of the new bounds will be the latest applied checkpoint at
+// or before the requested time. The new bounds are returned. The
+// caller is responsible for ensuring that any component reading from
+// the bounds is restarted, since readers are not expected to move
+// backwards in time.
+func (r *Group) Rewind(ctx context.Context, to hlc.Time) (hlc.Range, error) {
+	err := retry.Retry(ctx, r.pool, func(ctx context.Context) error {
+		return r.store.rewind(ctx, to)
+	})
+	if err != nil {
+		return hlc.RangeEmpty(), err
+	}
+	// Scan from the beginning to find the preceding checkpoint.
+	next, err := r.refreshQuery(ctx, hlc.Zero())
+	if err != nil {
+		return hlc.RangeEmpty(), err
+	}
+	r.bounds.Set(next)
+	log.Infof("group %s: rewound checkpoint range to %s", r.target, next)
+	return next, nil
+}
+
 // TableGroup returns the [types.TableGroup] whose checkpoints are being
 // persisted.
 func (r *Group) TableGroup() *types.TableGroup {
@@ -240,6 +327,7 @@ func (r *Group) TableGroup() *types.TableGroup {
 // Params:
 //   - $1: group name
 //   - $2: last successful checkpoint to reduce table scan range
+//   - $3: if the group is delayed, the newest checkpoint to consider
 //
 // CTE components:
 //   - edges: Source data to detect edge transitions between
@@ -282,6 +370,7 @@ SELECT partition,
   FROM %[1]s
  WHERE group_name = $1
    AND source_hlc >= $2
+   %[3]s
 WINDOW w AS (PARTITION BY partition ORDER BY source_hlc)
 ),
 lower_bound AS (
@@ -357,29 +446,22 @@ func (r *Group) refreshBounds(ctx context.Context) error {
 func (r *Group) refreshQuery(ctx context.Context, knownCommitted hlc.Time) (hlc.Range, error) {
 	var ret hlc.Range
 	err := retry.Retry(ctx, r.pool, func(ctx context.Context) error {
-		var nextMin, nextMax sql.Null[hlc.Time]
-		if err := r.pool.QueryRow(ctx, r.sql.refresh,
-			r.target.Name.Canonical().Raw(),
-			knownCommitted,
-		).Scan(&nextMin, &nextMax); err != nil {
-			return errors.WithStack(err)
-		}
-
-		if !nextMin.Valid {
-			nextMin.V = knownCommitted
-		}
-
-		if nextMax.Valid {
-			ret = hlc.RangeIncluding(nextMin.V, nextMax.V)
-		} else {
-			ret = hlc.RangeIncluding(nextMin.V, nextMin.V)
-		}
-
-		return nil
+		var err error
+		ret, err = r.store.refresh(ctx, knownCommitted)
+		return err
 	})
 	return ret, err
 }
 
+// horizon returns the newest checkpoint time that may be included in
+// the resolving range if the group is delayed.
+func horizon(delay time.Duration) (hlc.Time, bool) {
+	if delay <= 0 {
+		return hlc.Zero(), false
+	}
+	return hlc.New(time.Now().Add(-delay).UnixNano(), 0), true
+}
+
 // refreshJob starts a goroutine to periodically synchronize the
 // in-memory bounds with the database.
 func (r *Group) refreshJob(ctx *stopper.Context) {
@@ -400,6 +482,7 @@ func (r *Group) refreshJob(ctx *stopper.Context) {
 				log.WithError(err).Warnf("could not refresh checkpoint timestamp "+
 					"bounds for %s; will continue", r.target)
 			}
+			r.runCallbacks(ctx)
 		}
 	})
 }
@@ -0,0 +1,383 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package checkpoint
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/hlc"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/cockroachdb/replicator/internal/util/kvstore"
+	"github.com/pkg/errors"
+)
+
+// localPrefix is prepended to all checkpoint keys in an embedded store.
+// Keys are of the form prefix + group + 0 + hlc + 0 + partition, so
+// that the checkpoints for a group are stored in time order.
+const localPrefix = "checkpoint/"
+
+// localBackend stores checkpoints in an embedded store.
+type localBackend struct {
+	db *kvstore.Store
+}
+
+var _ backend = (*localBackend)(nil)
+
+func (b *localBackend) newStore(
+	group *types.TableGroup, lookahead int, delay time.Duration,
+) store {
+	return &localStore{
+		db:        b.db,
+		delay:     delay,
+		group:     group.Name,
+		lookahead: lookahead,
+	}
+}
+
+// localStore is the embedded-store equivalent of sqlStore. There are
+// no other instances to receive notifications from, so it does not
+// implement streamer.
+type localStore struct {
+	db        *kvstore.Store
+	delay     time.Duration
+	group     ident.Ident
+	lookahead int
+}
+
+var _ store = (*localStore)(nil)
+
+// localRecord is the persistent form of a checkpoint in an embedded
+// store.
+type localRecord struct {
+	FirstSeen time.Time  `json:"first_seen"`
+	AppliedAt *time.Time `json:"target_applied_at,omitempty"`
+}
+
+// localEntry is a decoded checkpoint.
+type localEntry struct {
+	key       []byte
+	partition string
+	rec       localRecord
+	ts        hlc.Time
+}
+
+func localGroupPrefix(group ident.Ident) []byte {
+	return []byte(localPrefix + group.Canonical().Raw() + "\x00")
+}
+
+func localKey(group ident.Ident, ts hlc.Time, partition string) []byte {
+	return append(localGroupPrefix(group),
+		fmt.Sprintf("%020d%010d\x00%s", ts.Nanos(), ts.Logical(), partition)...)
+}
+
+// localScan decodes all checkpoints associated with the group whose
+// timestamps are at least the given minimum.
+func localScan(tx *kvstore.Tx, group ident.Ident, from hlc.Time) ([]*localEntry, error) {
+	prefix := localGroupPrefix(group)
+	start := localKey(group, from, "")
+	var ret []*localEntry
+	for k, v := range tx.Scan(start, kvstore.PrefixEnd(prefix)) {
+		e, err := decodeLocal(k[len(prefix):], v)
+		if err != nil {
+			return nil, err
+		}
+		e.key = k
+		ret = append(ret, e)
+	}
+	return ret, nil
+}
+
+// decodeLocal parses the suffix of a key, after the group name.
+func decodeLocal(suffix, value []byte) (*localEntry, error) {
+	idx := bytes.IndexByte(suffix, 0)
+	if idx != 30 {
+		return nil, errors.Errorf("malformed checkpoint key %q", suffix)
+	}
+	var nanos int64
+	var logical int
+	if _, err := fmt.Sscanf(string(suffix[:idx]), "%020d%010d", &nanos, &logical); err != nil {
+		return nil, errors.Wrapf(err, "malformed checkpoint key %q", suffix)
+	}
+	ret := &localEntry{
+		partition: string(suffix[idx+1:]),
+		ts:        hlc.New(nanos, logical),
+	}
+	if err := json.Unmarshal(value, &ret.rec); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return ret, nil
+}
+
+func putLocal(tx *kvstore.Tx, key []byte, rec *localRecord) error {
+	buf, err := json.Marshal(rec)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return tx.Put(key, buf)
+}
+
+// advance is the embedded-store equivalent of advanceTemplate.
+func (s *localStore) advance(_ context.Context, partition ident.Ident, ts hlc.Time) (bool, error) {
+	group := s.group
+	part := partition.Canonical().Raw()
+	ok := true
+	err := s.db.Update(func(tx *kvstore.Tx) error {
+		entries, err := localScan(tx, group, hlc.Zero())
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if e.partition == part && hlc.Compare(ts, e.ts) < 0 {
+				ok = false
+				return nil
+			}
+		}
+		key := localKey(group, ts, part)
+		// Re-advancing to an existing timestamp is a no-op.
+		if tx.Get(key) != nil {
+			return nil
+		}
+		return putLocal(tx, key, &localRecord{FirstSeen: time.Now().UTC()})
+	})
+	return ok, err
+}
+
+// commit is the embedded-store equivalent of commitTemplate.
+func (s *localStore) commit(_ context.Context, rng hlc.Range) error {
+	return s.db.Update(func(tx *kvstore.Tx) error {
+		entries, err := localScan(tx, s.group, rng.Min())
+		if err != nil {
+			return err
+		}
+		now := time.Now().UTC()
+		for _, e := range entries {
+			if hlc.Compare(e.ts, rng.Max()) >= 0 {
+				break
+			}
+			if e.rec.AppliedAt != nil {
+				continue
+			}
+			e.rec.AppliedAt = &now
+			if err := putLocal(tx, e.key, &e.rec); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ensure is the embedded-store equivalent of ensureTemplate.
+func (s *localStore) ensure(_ context.Context, partitions []ident.Ident) error {
+	return s.db.Update(func(tx *kvstore.Tx) error {
+		entries, err := localScan(tx, s.group, hlc.Zero())
+		if err != nil {
+			return err
+		}
+		known := make(map[string]bool, len(entries))
+		for _, e := range entries {
+			known[e.partition] = true
+		}
+		now := time.Now().UTC()
+		for _, part := range partitions {
+			if known[part.Raw()] {
+				continue
+			}
+			known[part.Raw()] = true
+			if err := putLocal(tx,
+				localKey(s.group, hlc.New(1, 1), part.Raw()),
+				&localRecord{FirstSeen: now, AppliedAt: &now},
+			); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// rewind is the embedded-store equivalent of rewindTemplate.
+func (s *localStore) rewind(_ context.Context, to hlc.Time) error {
+	return s.db.Update(func(tx *kvstore.Tx) error {
+		entries, err := localScan(tx, s.group, to.Next())
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if e.rec.AppliedAt == nil {
+				continue
+			}
+			e.rec.AppliedAt = nil
+			if err := putLocal(tx, e.key, &e.rec); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// refresh is the embedded-store equivalent of refreshTemplate.
+func (s *localStore) refresh(_ context.Context, knownCommitted hlc.Time) (hlc.Range, error) {
+	var entries []*localEntry
+	if err := s.db.View(func(tx *kvstore.Tx) error {
+		var err error
+		entries, err = localScan(tx, s.group, knownCommitted)
+		return err
+	}); err != nil {
+		return hlc.RangeEmpty(), err
+	}
+	if horizon, ok := horizon(s.delay); ok {
+		// The entries are in time order.
+		for idx, e := range entries {
+			if hlc.Compare(e.ts, horizon) > 0 {
+				entries = entries[:idx]
+				break
+			}
+		}
+	}
+	return computeRange(entries, knownCommitted, s.lookahead), nil
+}
+
+// computeRange implements the windowing logic described on
+// refreshTemplate. The entries must be in time order.
+func computeRange(entries []*localEntry, knownCommitted hlc.Time, lookahead int) hlc.Range {
+	type partitionRange struct {
+		dirty    bool
+		min, max hlc.Time
+	}
+
+	// Group the entries by partition, preserving time order.
+	var partitions []string
+	byPartition := make(map[string][]*localEntry)
+	for _, e := range entries {
+		if _, found := byPartition[e.partition]; !found {
+			partitions = append(partitions, e.partition)
+		}
+		byPartition[e.partition] = append(byPartition[e.partition], e)
+	}
+
+	var ranges []*partitionRange
+	for _, part := range partitions {
+		rows := byPartition[part]
+		applied := func(idx int) bool { return rows[idx].rec.AppliedAt != nil }
+
+		// Find the transition from applied to unapplied and from
+		// unapplied back to applied, if there's a gap.
+		var lower, upper *hlc.Time
+		for idx := 0; idx < len(rows)-1; idx++ {
+			if lower == nil && applied(idx) && !applied(idx+1) {
+				lower = &rows[idx].ts
+			}
+			if upper == nil && !applied(idx) && applied(idx+1) {
+				upper = &rows[idx].ts
+			}
+		}
+
+		var rng *partitionRange
+		count := 0
+		for idx, row := range rows {
+			if lower != nil && hlc.Compare(row.ts, *lower) < 0 {
+				continue
+			}
+			if upper != nil && hlc.Compare(row.ts, *upper) > 0 {
+				continue
+			}
+			count++
+			if lookahead > 0 && count > lookahead+1 {
+				break
+			}
+			if rng == nil {
+				rng = &partitionRange{min: hlc.Zero()}
+				if lower != nil {
+					rng.min = *lower
+				}
+			}
+			rng.max = row.ts
+			rng.dirty = rng.dirty || !applied(idx)
+		}
+		if rng != nil {
+			ranges = append(ranges, rng)
+		}
+	}
+
+	if len(ranges) == 0 {
+		return hlc.RangeIncluding(knownCommitted, knownCommitted)
+	}
+
+	// No partition may outrun its peers.
+	minCommon := ranges[0].max
+	for _, rng := range ranges[1:] {
+		if hlc.Compare(rng.max, minCommon) < 0 {
+			minCommon = rng.max
+		}
+	}
+
+	var dirtyMin, dirtyMax *hlc.Time
+	for _, rng := range ranges {
+		if !rng.dirty || hlc.Compare(rng.max, minCommon) > 0 {
+			continue
+		}
+		if dirtyMin == nil || hlc.Compare(rng.min, *dirtyMin) < 0 {
+			dirtyMin = &rng.min
+		}
+		if dirtyMax == nil || hlc.Compare(rng.max, *dirtyMax) < 0 {
+			dirtyMax = &rng.max
+		}
+	}
+	if dirtyMin == nil {
+		return hlc.RangeIncluding(minCommon, minCommon)
+	}
+	return hlc.RangeIncluding(*dirtyMin, *dirtyMax)
+}
+
+// scanForTargetSchemas is the embedded-store equivalent of
+// scanForTargetTemplate.
+func (b *localBackend) scanForTargetSchemas(context.Context) ([]ident.Schema, error) {
+	var ret []ident.Schema
+	err := b.db.View(func(tx *kvstore.Tx) error {
+		seen := make(map[string]bool)
+		for k, v := range tx.ScanPrefix([]byte(localPrefix)) {
+			rest := k[len(localPrefix):]
+			idx := bytes.IndexByte(rest, 0)
+			if idx < 0 {
+				return errors.Errorf("malformed checkpoint key %q", k)
+			}
+			group := string(rest[:idx])
+			if seen[group] {
+				continue
+			}
+			e, err := decodeLocal(rest[idx+1:], v)
+			if err != nil {
+				return err
+			}
+			if e.rec.AppliedAt != nil {
+				continue
+			}
+			seen[group] = true
+			sch, err := ident.ParseSchema(group)
+			if err != nil {
+				return err
+			}
+			ret = append(ret, sch)
+		}
+		return nil
+	})
+	return ret, err
+}
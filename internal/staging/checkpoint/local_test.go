@@ -0,0 +1,279 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package checkpoint
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/field-eng-powertools/notify"
+	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/field-eng-powertools/stopvar"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/hlc"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/cockroachdb/replicator/internal/util/kvstore"
+	"github.com/cockroachdb/replicator/internal/util/stdpool"
+	"github.com/stretchr/testify/require"
+)
+
+// TestLocal exercises checkpoints stored in an embedded store.
+func TestLocal(t *testing.T) {
+	r := require.New(t)
+	base, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	ctx := stopper.WithContext(base)
+	defer ctx.Stop(time.Second)
+
+	store, err := kvstore.Open(t.TempDir())
+	r.NoError(err)
+	defer func() { r.NoError(store.Close()) }()
+
+	chk, err := ProvideCheckpoints(ctx, stdpool.NewLocalStaging(), store, ident.StagingSchema(
+		ident.MustSchema(ident.New("_replicator"), ident.Public)))
+	r.NoError(err)
+
+	bounds := &notify.Var[hlc.Range]{}
+	g, err := chk.Start(ctx, &types.TableGroup{Name: ident.New("fake")}, bounds)
+	r.NoError(err)
+
+	part := ident.New("partition")
+	for i := int64(1); i <= 10; i++ {
+		r.NoError(g.Advance(ctx, part, hlc.New(i, 0)))
+		r.NoError(g.Advance(ctx, part, hlc.New(i, 0)))
+	}
+	r.ErrorContains(g.Advance(ctx, part, hlc.New(5, 0)), "going backwards")
+	r.NoError(stopvar.WaitForValue(ctx,
+		hlc.RangeIncluding(hlc.Zero(), hlc.New(10, 0)), bounds))
+
+	found, err := chk.ScanForTargetSchemas(ctx)
+	r.NoError(err)
+	r.Equal([]ident.Schema{ident.MustSchema(ident.New("fake"))}, found)
+
+	r.NoError(g.Commit(ctx, hlc.RangeIncluding(hlc.Zero(), hlc.New(5, 0))))
+	r.NoError(stopvar.WaitForValue(ctx,
+		hlc.RangeIncluding(hlc.New(5, 0), hlc.New(10, 0)), bounds))
+
+	// Adding a new partition should hold back the range. The new
+	// partition's initial checkpoint is older than the committed time,
+	// so the window will be re-opened from zero.
+	other := ident.New("other")
+	r.NoError(g.Ensure(ctx, []ident.Ident{other}))
+	r.NoError(g.Advance(ctx, other, hlc.New(7, 0)))
+	r.NoError(stopvar.WaitForValue(ctx,
+		hlc.RangeIncluding(hlc.Zero(), hlc.New(7, 0)), bounds))
+
+	// Reopening should observe the same state.
+	r.NoError(g.Commit(ctx, hlc.RangeIncluding(hlc.Zero(), hlc.New(7, 0))))
+	next := &notify.Var[hlc.Range]{}
+	g2, err := chk.Start(ctx, &types.TableGroup{Name: ident.New("fake")}, next,
+		LimitLookahead(1))
+	r.NoError(err)
+	r.NoError(stopvar.WaitForValue(ctx,
+		hlc.RangeIncluding(hlc.New(7, 0), hlc.New(7, 0)), next))
+	r.NoError(g2.Advance(ctx, other, hlc.New(10, 0)))
+	r.NoError(stopvar.WaitForValue(ctx,
+		hlc.RangeIncluding(hlc.New(7, 0), hlc.New(8, 0)), next))
+
+	r.NoError(g.Commit(ctx, hlc.RangeIncluding(hlc.Zero(), hlc.New(10, 0))))
+	found, err = chk.ScanForTargetSchemas(ctx)
+	r.NoError(err)
+	r.Empty(found)
+}
+
+// TestLocalRewind verifies that applied checkpoints can be reopened.
+func TestLocalRewind(t *testing.T) {
+	r := require.New(t)
+	base, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	ctx := stopper.WithContext(base)
+	defer ctx.Stop(time.Second)
+
+	store, err := kvstore.Open(t.TempDir())
+	r.NoError(err)
+	defer func() { r.NoError(store.Close()) }()
+
+	chk, err := ProvideCheckpoints(ctx, stdpool.NewLocalStaging(), store, ident.StagingSchema(
+		ident.MustSchema(ident.New("_replicator"), ident.Public)))
+	r.NoError(err)
+
+	bounds := &notify.Var[hlc.Range]{}
+	g, err := chk.Start(ctx, &types.TableGroup{Name: ident.New("fake")}, bounds)
+	r.NoError(err)
+
+	part := ident.New("partition")
+	for i := int64(1); i <= 10; i++ {
+		r.NoError(g.Advance(ctx, part, hlc.New(i, 0)))
+	}
+	r.NoError(g.Commit(ctx, hlc.RangeIncluding(hlc.Zero(), hlc.New(10, 0))))
+	r.NoError(stopvar.WaitForValue(ctx,
+		hlc.RangeIncluding(hlc.New(10, 0), hlc.New(10, 0)), bounds))
+
+	rng, err := g.Rewind(ctx, hlc.New(4, 0))
+	r.NoError(err)
+	r.Equal(hlc.RangeIncluding(hlc.New(4, 0), hlc.New(10, 0)), rng)
+	found, _ := bounds.Get()
+	r.Equal(rng, found)
+
+	// Rewinding to a time that is not a checkpoint uses the preceding
+	// checkpoint as the lower bound.
+	rng, err = g.Rewind(ctx, hlc.New(2, 5))
+	r.NoError(err)
+	r.Equal(hlc.RangeIncluding(hlc.New(2, 0), hlc.New(10, 0)), rng)
+
+	// The rewound checkpoints can be committed again.
+	r.NoError(g.Commit(ctx, hlc.RangeIncluding(hlc.Zero(), hlc.New(10, 0))))
+	r.NoError(stopvar.WaitForValue(ctx,
+		hlc.RangeIncluding(hlc.New(10, 0), hlc.New(10, 0)), bounds))
+}
+
+// TestComputeRange checks gap handling, which can't be created via the
+// public API.
+func TestComputeRange(t *testing.T) {
+	r := require.New(t)
+	applied := time.Now()
+	entry := func(partition string, nanos int64, isApplied bool) *localEntry {
+		ret := &localEntry{partition: partition, ts: hlc.New(nanos, 0)}
+		if isApplied {
+			ret.rec.AppliedAt = &applied
+		}
+		return ret
+	}
+
+	r.Equal(hlc.RangeIncluding(hlc.New(3, 0), hlc.New(3, 0)),
+		computeRange(nil, hlc.New(3, 0), 0))
+
+	// A gap in the applied checkpoints limits the window.
+	r.Equal(hlc.RangeIncluding(hlc.New(1, 0), hlc.New(3, 0)),
+		computeRange([]*localEntry{
+			entry("p", 1, true),
+			entry("p", 2, false),
+			entry("p", 3, false),
+			entry("p", 4, true),
+			entry("p", 5, false),
+		}, hlc.Zero(), 0))
+
+	// Fully-applied partitions skip ahead to their common minimum.
+	r.Equal(hlc.RangeIncluding(hlc.New(4, 0), hlc.New(4, 0)),
+		computeRange([]*localEntry{
+			entry("a", 1, true),
+			entry("b", 2, true),
+			entry("a", 4, true),
+			entry("b", 6, true),
+		}, hlc.Zero(), 0))
+}
+
+// TestLocalDelay verifies that a delayed group does not include recent
+// checkpoints in its resolving range.
+func TestLocalDelay(t *testing.T) {
+	r := require.New(t)
+	base, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	ctx := stopper.WithContext(base)
+	defer ctx.Stop(time.Second)
+
+	store, err := kvstore.Open(t.TempDir())
+	r.NoError(err)
+	defer func() { r.NoError(store.Close()) }()
+
+	chk, err := ProvideCheckpoints(ctx, stdpool.NewLocalStaging(), store, ident.StagingSchema(
+		ident.MustSchema(ident.New("_replicator"), ident.Public)))
+	r.NoError(err)
+
+	_, err = chk.Start(ctx, &types.TableGroup{Name: ident.New("fake")},
+		&notify.Var[hlc.Range]{}, Delay(-time.Second))
+	r.ErrorContains(err, "negative")
+
+	bounds := &notify.Var[hlc.Range]{}
+	g, err := chk.Start(ctx, &types.TableGroup{Name: ident.New("fake")}, bounds,
+		Delay(time.Hour))
+	r.NoError(err)
+
+	now := time.Now()
+	old := hlc.New(now.Add(-2*time.Hour).UnixNano(), 0)
+	older := hlc.New(now.Add(-90*time.Minute).UnixNano(), 0)
+	recent := hlc.New(now.Add(-time.Minute).UnixNano(), 0)
+	part := ident.New("partition")
+	for _, ts := range []hlc.Time{old, older, recent} {
+		r.NoError(g.Advance(ctx, part, ts))
+	}
+	r.NoError(stopvar.WaitForValue(ctx, hlc.RangeIncluding(hlc.Zero(), older), bounds))
+
+	// Committing the eligible checkpoints does not expose the recent
+	// checkpoint.
+	r.NoError(g.Commit(ctx, hlc.RangeIncluding(hlc.Zero(), older)))
+	r.NoError(stopvar.WaitForValue(ctx, hlc.RangeIncluding(older, older), bounds))
+}
+
+// TestLocalResumeCallbacks verifies that the OnCommit callbacks are
+// invoked for the last committed checkpoint when a Group is restarted.
+func TestLocalResumeCallbacks(t *testing.T) {
+	r := require.New(t)
+	base, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	ctx := stopper.WithContext(base)
+	defer ctx.Stop(time.Second)
+
+	store, err := kvstore.Open(t.TempDir())
+	r.NoError(err)
+	defer func() { r.NoError(store.Close()) }()
+
+	chk, err := ProvideCheckpoints(ctx, stdpool.NewLocalStaging(), store, ident.StagingSchema(
+		ident.MustSchema(ident.New("_replicator"), ident.Public)))
+	r.NoError(err)
+
+	group := &types.TableGroup{Name: ident.New("fake")}
+	part := ident.New("partition")
+
+	calls := make(chan hlc.Range, 16)
+	onCommit := OnCommit(func(_ context.Context, rng hlc.Range) error {
+		calls <- rng
+		return nil
+	})
+	g, err := chk.Start(ctx, group, &notify.Var[hlc.Range]{}, onCommit)
+	r.NoError(err)
+	r.NoError(g.Ensure(ctx, []ident.Ident{part}))
+	for i := int64(2); i <= 10; i++ {
+		r.NoError(g.Advance(ctx, part, hlc.New(i, 0)))
+	}
+
+	// The callback fails, as though the process exited before it could
+	// be retried.
+	failing, err := chk.Start(ctx, group, &notify.Var[hlc.Range]{},
+		OnCommit(func(context.Context, hlc.Range) error {
+			return context.Canceled
+		}))
+	r.NoError(err)
+	r.NoError(failing.Commit(ctx, hlc.RangeIncluding(hlc.Zero(), hlc.New(5, 0))))
+	select {
+	case rng := <-calls:
+		r.Failf("unexpected callback", "%s", rng)
+	default:
+	}
+
+	// Restarting the group invokes the callback for the committed
+	// checkpoint.
+	_, err = chk.Start(ctx, group, &notify.Var[hlc.Range]{}, onCommit)
+	r.NoError(err)
+	select {
+	case rng := <-calls:
+		r.Equal(hlc.New(5, 0), rng.MaxInclusive())
+	case <-ctx.Done():
+		r.Fail("callback was not invoked")
+	}
+}
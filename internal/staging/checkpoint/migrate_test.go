@@ -66,7 +66,7 @@ INSERT INTO %s (target_schema, source_nanos, source_logical, target_applied_at)
 `, oldTable))
 	r.NoError(err)
 
-	chk, err := ProvideCheckpoints(ctx, fixture.StagingPool, fixture.StagingDB)
+	chk, err := ProvideCheckpoints(ctx, fixture.StagingPool, nil, fixture.StagingDB)
 	r.NoError(err)
 
 	schemas, err := chk.ScanForTargetSchemas(ctx)
@@ -84,7 +84,8 @@ INSERT INTO %s (target_schema, source_nanos, source_logical, target_applied_at)
 				Name: ident.New(id),
 			},
 			notify.VarOf(hlc.RangeEmpty()),
-			1024,
+			1024, // lookahead
+			0,    // delay
 		).refreshQuery(ctx, hlc.Zero())
 		r.NoError(err)
 		r.Equal(expect, rng)
@@ -100,6 +101,6 @@ INSERT INTO %s (target_schema, source_nanos, source_logical, target_applied_at)
 	r.Equal("23514", code) // check_violation
 
 	// Ensure that the next instance to be created will succeed.
-	_, err = ProvideCheckpoints(ctx, fixture.StagingPool, fixture.StagingDB)
+	_, err = ProvideCheckpoints(ctx, fixture.StagingPool, nil, fixture.StagingDB)
 	r.NoError(err)
 }
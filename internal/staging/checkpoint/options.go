@@ -16,11 +16,29 @@
 
 package checkpoint
 
+import (
+	"context"
+	"time"
+
+	"github.com/cockroachdb/replicator/internal/util/hlc"
+)
+
 // An Option to [Checkpoints.Start].
 type Option interface {
 	isOption()
 }
 
+type delay time.Duration
+
+// Delay prevents the resolving range from including checkpoints which
+// are newer than the given duration. This is used to create a replica
+// which deliberately lags behind the source.
+func Delay(d time.Duration) Option {
+	return delay(d)
+}
+
+func (delay) isOption() {}
+
 type disableStream struct{}
 
 func (disableStream) isOption() {}
@@ -31,6 +49,23 @@ func DisableStream() Option {
 	return disableStream{}
 }
 
+type onCommit func(ctx context.Context, rng hlc.Range) error
+
+// OnCommit registers a callback which will be invoked after
+// [Group.Commit] has durably recorded the range as having been
+// applied. Since the checkpoint has already been committed, an error
+// from the callback does not cause Commit to fail. Instead, the
+// callback will be retried in the background until it succeeds or a
+// later range is committed. When the Group is started, the callback
+// will be invoked again for the most recently committed checkpoint,
+// since a failed callback may not have been retried before the process
+// exited. Callbacks must therefore be idempotent.
+func OnCommit(fn func(ctx context.Context, rng hlc.Range) error) Option {
+	return onCommit(fn)
+}
+
+func (onCommit) isOption() {}
+
 type limitLookahead int
 
 // LimitLookahead limits the number of resolved timestamps that are used
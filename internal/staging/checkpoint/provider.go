@@ -22,6 +22,7 @@ import (
 
 	"github.com/cockroachdb/replicator/internal/types"
 	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/cockroachdb/replicator/internal/util/kvstore"
 	"github.com/google/wire"
 	"github.com/pkg/errors"
 )
@@ -31,8 +32,11 @@ var Set = wire.NewSet(ProvideCheckpoints)
 
 // ProvideCheckpoints is called by Wire.
 func ProvideCheckpoints(
-	ctx context.Context, pool *types.StagingPool, meta ident.StagingSchema,
+	ctx context.Context, pool *types.StagingPool, local *kvstore.Store, meta ident.StagingSchema,
 ) (*Checkpoints, error) {
+	if local != nil {
+		return &Checkpoints{backend: &localBackend{db: local}, pool: pool}, nil
+	}
 	metaTable := ident.NewTable(meta.Schema(), ident.New("checkpoints"))
 	if _, err := pool.Exec(ctx, fmt.Sprintf(schema, metaTable)); err != nil {
 		return nil, errors.WithStack(err)
@@ -46,7 +50,7 @@ func ProvideCheckpoints(
 	}
 
 	return &Checkpoints{
-		pool:      pool,
-		metaTable: metaTable,
+		backend: &sqlBackend{metaTable: metaTable, pool: pool},
+		pool:    pool,
 	}, nil
 }
@@ -0,0 +1,185 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package checkpoint
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/hlc"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/pkg/errors"
+)
+
+// sqlBackend stores checkpoints in the staging database.
+type sqlBackend struct {
+	metaTable ident.Table
+	pool      *types.StagingPool
+}
+
+var _ backend = (*sqlBackend)(nil)
+
+func (b *sqlBackend) newStore(
+	group *types.TableGroup, lookahead int, delay time.Duration,
+) store {
+	ret := &sqlStore{
+		delay: delay,
+		group: group,
+		pool:  b.pool,
+	}
+
+	var limit string
+	if lookahead > 0 {
+		// +1 to have a window that includes both the last applied
+		// checkpoint and the next unapplied checkpoint.
+		limit = fmt.Sprintf("WHERE r <= %d", lookahead+1)
+	}
+	var horizon string
+	if delay > 0 {
+		horizon = "AND source_hlc <= $3"
+	}
+	// This query may indeed require a full table scan.
+	ret.sql.refresh = fmt.Sprintf(refreshTemplate, b.metaTable, limit, horizon)
+	ret.sql.stream = fmt.Sprintf(streamTemplate, b.metaTable)
+
+	hinted := b.pool.HintNoFTS(b.metaTable)
+	ret.sql.advance = fmt.Sprintf(advanceTemplate, hinted)
+	ret.sql.ensure = fmt.Sprintf(ensureTemplate, hinted)
+	ret.sql.commit = fmt.Sprintf(commitTemplate, hinted)
+	ret.sql.rewind = fmt.Sprintf(rewindTemplate, hinted)
+	return ret
+}
+
+const scanForTargetTemplate = `
+SELECT DISTINCT group_name
+FROM %[1]s
+WHERE target_applied_at IS NULL
+`
+
+func (b *sqlBackend) scanForTargetSchemas(ctx context.Context) ([]ident.Schema, error) {
+	rows, err := b.pool.Query(ctx, fmt.Sprintf(scanForTargetTemplate, b.metaTable))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer rows.Close()
+	var ret []ident.Schema
+	for rows.Next() {
+		var schemaRaw string
+		if err := rows.Scan(&schemaRaw); err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		sch, err := ident.ParseSchema(schemaRaw)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, sch)
+	}
+
+	return ret, nil
+}
+
+// sqlStore executes the SQL templates in group.go.
+type sqlStore struct {
+	delay time.Duration // Ignore checkpoints newer than this.
+	group *types.TableGroup
+	pool  *types.StagingPool
+
+	sql struct {
+		advance string
+		commit  string
+		ensure  string
+		refresh string
+		rewind  string
+		stream  string
+	}
+}
+
+var (
+	_ store    = (*sqlStore)(nil)
+	_ streamer = (*sqlStore)(nil)
+)
+
+func (s *sqlStore) advance(ctx context.Context, partition ident.Ident, ts hlc.Time) (bool, error) {
+	tag, err := s.pool.Exec(ctx,
+		s.sql.advance,
+		s.group.Name.Canonical().Raw(),
+		partition.Canonical().Raw(),
+		ts,
+	)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (s *sqlStore) commit(ctx context.Context, rng hlc.Range) error {
+	_, err := s.pool.Exec(ctx,
+		s.sql.commit,
+		s.group.Name.Canonical().Raw(),
+		rng.Min(),
+		rng.Max(),
+	)
+	return errors.WithStack(err)
+}
+
+func (s *sqlStore) ensure(ctx context.Context, partitions []ident.Ident) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	for _, part := range partitions {
+		if _, err := tx.Exec(ctx, s.sql.ensure, s.group.Name.Raw(), part.Raw()); err != nil {
+			return errors.Wrap(err, s.sql.ensure)
+		}
+	}
+	return errors.WithStack(tx.Commit(ctx))
+}
+
+func (s *sqlStore) refresh(ctx context.Context, knownCommitted hlc.Time) (hlc.Range, error) {
+	var nextMin, nextMax sql.Null[hlc.Time]
+	args := []any{s.group.Name.Canonical().Raw(), knownCommitted}
+	if horizon, ok := horizon(s.delay); ok {
+		args = append(args, horizon)
+	}
+	if err := s.pool.QueryRow(ctx, s.sql.refresh, args...).Scan(&nextMin, &nextMax); err != nil {
+		return hlc.RangeEmpty(), errors.WithStack(err)
+	}
+
+	if !nextMin.Valid {
+		nextMin.V = knownCommitted
+	}
+
+	if nextMax.Valid {
+		return hlc.RangeIncluding(nextMin.V, nextMax.V), nil
+	}
+	return hlc.RangeIncluding(nextMin.V, nextMin.V), nil
+}
+
+func (s *sqlStore) rewind(ctx context.Context, to hlc.Time) error {
+	_, err := s.pool.Exec(ctx,
+		s.sql.rewind,
+		s.group.Name.Canonical().Raw(),
+		to,
+	)
+	return errors.Wrap(err, s.sql.rewind)
+}
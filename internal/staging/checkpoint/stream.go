@@ -31,10 +31,10 @@ const streamTemplate = "EXPERIMENTAL CHANGEFEED FOR %s WITH no_initial_scan, res
 
 // streamJob opens a core changefeed over the checkpoints table to
 // provide a cross-instance notification channel.
-func (r *Group) streamJob(ctx *stopper.Context) {
+func (s *sqlStore) streamJob(ctx *stopper.Context, r *Group) {
 	// This is a defensive check; this should almost always be the case.
 	var enabled bool
-	if err := r.pool.QueryRow(ctx, "SHOW CLUSTER SETTING kv.rangefeed.enabled").Scan(&enabled); err != nil {
+	if err := s.pool.QueryRow(ctx, "SHOW CLUSTER SETTING kv.rangefeed.enabled").Scan(&enabled); err != nil {
 		log.WithError(err).Warn(
 			"could not determine if rangefeeds are enabled; polling checkpoints table")
 		return
@@ -45,7 +45,7 @@ func (r *Group) streamJob(ctx *stopper.Context) {
 	}
 	ctx.Go(func(ctx *stopper.Context) error {
 		for !ctx.IsStopping() {
-			if err := r.doStream(ctx); err != nil {
+			if err := s.doStream(ctx, r); err != nil {
 				if !errors.Is(err, context.Canceled) {
 					log.WithError(err).Warnf("notification stream error for %s", r.target.Name)
 				}
@@ -62,10 +62,10 @@ func (r *Group) streamJob(ctx *stopper.Context) {
 	})
 }
 
-func (r *Group) doStream(ctx *stopper.Context) error {
+func (s *sqlStore) doStream(ctx *stopper.Context, r *Group) error {
 	// Consume a connection from the pool due to drain semantics.
 	// https://www.cockroachlabs.com/docs/stable/changefeed-for#considerations
-	pooled, err := r.pool.Pool.Acquire(ctx)
+	pooled, err := s.pool.Pool.Acquire(ctx)
 	if err != nil {
 		return errors.WithStack(err)
 	}
@@ -98,9 +98,9 @@ func (r *Group) doStream(ctx *stopper.Context) error {
 		return nil
 	})
 
-	rows, err := r.pool.Query(dbCtx, r.sql.stream)
+	rows, err := s.pool.Query(dbCtx, s.sql.stream)
 	if err != nil {
-		return errors.Wrap(err, r.sql.stream)
+		return errors.Wrap(err, s.sql.stream)
 	}
 	defer rows.Close()
 
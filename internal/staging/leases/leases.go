@@ -27,6 +27,7 @@ import (
 
 	"github.com/cockroachdb/replicator/internal/types"
 	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/cockroachdb/replicator/internal/util/kvstore"
 	"github.com/cockroachdb/replicator/internal/util/retry"
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
@@ -41,6 +42,7 @@ const (
 
 // Config is passed to New.
 type Config struct {
+	Local  *kvstore.Store             // If set, leases are kept in an embedded store.
 	Pool   *types.StagingPool         // Database access.
 	Target *ident.Hinted[ident.Table] // The lease table.
 
@@ -86,13 +88,23 @@ type lease struct {
 
 // leases coordinates global, singleton activities.
 type leases struct {
-	cfg      Config
-	hostname string // For operator convenience, not correctness.
-	sql      struct {
-		acquire string
-		release string
-		renew   string
-	}
+	cfg   Config
+	store store
+}
+
+// A store persists leases. It is implemented by sqlStore and
+// localStore.
+type store interface {
+	// acquire claims the named leases, with the given expiration time,
+	// if none of them are held at the given time. If the leases are
+	// blocked, the returned lease will contain the latest expiration
+	// time of the blocking leases.
+	acquire(ctx context.Context, names []string, expires, now time.Time) (lease, bool, error)
+	// release deletes the lease if it is still owned by the caller.
+	release(ctx context.Context, rel lease) (bool, error)
+	// renew updates the expiration time of all leases in tgt or none
+	// of them. It returns false if the lease has been stolen.
+	renew(ctx context.Context, tgt lease, expires time.Time) (bool, error)
 }
 
 // leaseFacade implements the public types.Lease interface.
@@ -130,7 +142,22 @@ func New(ctx context.Context, cfg Config) (types.Leases, error) {
 	if err := cfg.sanitize(); err != nil {
 		return nil, err
 	}
-	_, err := cfg.Pool.Exec(ctx, fmt.Sprintf(schema, cfg.Target.Base))
+	l := &leases{cfg: cfg}
+	// For operator convenience, not correctness.
+	hostname, err := os.Hostname()
+	if err == nil {
+		log.Tracef("lease hostname: %s", hostname)
+	} else {
+		hostname = uuid.NewString()
+		log.Warnf("could not determine OS hostname, will use as %s instead", hostname)
+	}
+
+	if cfg.Local != nil {
+		l.store = &localStore{db: cfg.Local, hostname: hostname}
+		return l, nil
+	}
+
+	_, err = cfg.Pool.Exec(ctx, fmt.Sprintf(schema, cfg.Target.Base))
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
@@ -143,17 +170,11 @@ func New(ctx context.Context, cfg Config) (types.Leases, error) {
 		return nil, errors.WithStack(err)
 	}
 
-	l := &leases{cfg: cfg}
-	l.sql.acquire = fmt.Sprintf(acquireTemplate, cfg.Target)
-	l.sql.release = fmt.Sprintf(releaseTemplate, cfg.Target)
-	l.sql.renew = fmt.Sprintf(renewTemplate, cfg.Target)
-
-	if l.hostname, err = os.Hostname(); err == nil {
-		log.Tracef("lease hostname: %s", l.hostname)
-	} else {
-		l.hostname = uuid.NewString()
-		log.Warnf("could not determine OS hostname, will use as %s instead", l.hostname)
-	}
+	sqlStore := &sqlStore{hostname: hostname, pool: cfg.Pool}
+	sqlStore.sql.acquire = fmt.Sprintf(acquireTemplate, cfg.Target)
+	sqlStore.sql.release = fmt.Sprintf(releaseTemplate, cfg.Target)
+	sqlStore.sql.renew = fmt.Sprintf(renewTemplate, cfg.Target)
+	l.store = sqlStore
 
 	return l, nil
 }
@@ -414,22 +435,7 @@ func (l *leases) tryAcquire(
 	// We only have millisecond-level resolution in the db.
 	now = now.UTC().Truncate(time.Millisecond)
 	expires := now.Add(l.cfg.Lifetime)
-	var blockedUntil *time.Time
-	var nonce uuid.UUID
-
-	if err := l.cfg.Pool.QueryRow(ctx,
-		l.sql.acquire,
-		names,
-		expires,
-		l.hostname,
-		now,
-	).Scan(&blockedUntil, &nonce); err != nil {
-		return lease{}, false, errors.WithStack(err)
-	}
-	if blockedUntil != nil {
-		return lease{*blockedUntil, names, uuid.UUID{}}, false, nil
-	}
-	return lease{expires, names, nonce}, true, nil
+	return l.store.acquire(ctx, names, expires, now)
 }
 
 // release destroys the given lease.
@@ -455,14 +461,7 @@ DELETE FROM %s
 
 // tryRelease deletes the lease from the database.
 func (l *leases) tryRelease(ctx context.Context, rel lease) (ok bool, err error) {
-	tag, err := l.cfg.Pool.Exec(ctx, l.sql.release, rel.names, rel.nonce)
-	if err != nil {
-		return false, errors.Wrap(err, l.sql.release)
-	}
-	if tag.RowsAffected() == 0 {
-		return false, nil
-	}
-	return true, nil
+	return l.store.release(ctx, rel)
 }
 
 func (l *leases) renew(ctx context.Context, tgt lease) (renewed lease, ok bool, err error) {
@@ -506,13 +505,9 @@ func (l *leases) tryRenew(ctx context.Context, tgt lease, now time.Time) (lease,
 	now = now.UTC()
 	expires := now.Add(l.cfg.Lifetime)
 
-	tag, err := l.cfg.Pool.Exec(ctx, l.sql.renew, expires, tgt.names, tgt.nonce)
-	if err != nil {
-		return tgt, false, errors.Wrap(err, l.sql.renew)
-	}
-
-	if tag.RowsAffected() == 0 {
-		return tgt, false, nil
+	ok, err := l.store.renew(ctx, tgt, expires)
+	if err != nil || !ok {
+		return tgt, false, err
 	}
 
 	tgt.expires = expires
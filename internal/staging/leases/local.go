@@ -0,0 +1,153 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package leases
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/cockroachdb/replicator/internal/util/kvstore"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// localPrefix is prepended to the names of leases stored in an
+// embedded store.
+const localPrefix = "leases/"
+
+// localStore keeps leases in an embedded store.
+type localStore struct {
+	db       *kvstore.Store
+	hostname string
+}
+
+var _ store = (*localStore)(nil)
+
+// localRecord is the persistent form of a lease in an embedded store.
+type localRecord struct {
+	Expires  time.Time `json:"expires"`
+	Hostname string    `json:"hostname"`
+	Nonce    uuid.UUID `json:"nonce"`
+}
+
+// getLocal returns the record for the named lease, if one exists.
+func getLocal(tx *kvstore.Tx, name string) (*localRecord, error) {
+	buf := tx.Get([]byte(localPrefix + name))
+	if buf == nil {
+		return nil, nil
+	}
+	var ret localRecord
+	if err := json.Unmarshal(buf, &ret); err != nil {
+		return nil, errors.Wrapf(err, "lease %s", name)
+	}
+	return &ret, nil
+}
+
+// putLocal stores the record for the named lease.
+func putLocal(tx *kvstore.Tx, name string, rec *localRecord) error {
+	buf, err := json.Marshal(rec)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return tx.Put([]byte(localPrefix+name), buf)
+}
+
+// acquire is the embedded-store equivalent of acquireTemplate.
+func (s *localStore) acquire(
+	_ context.Context, names []string, expires, now time.Time,
+) (lease, bool, error) {
+	var blockedUntil time.Time
+	nonce := uuid.New()
+
+	err := s.db.Update(func(tx *kvstore.Tx) error {
+		for _, name := range names {
+			rec, err := getLocal(tx, name)
+			if err != nil {
+				return err
+			}
+			if rec != nil && rec.Expires.After(now) && rec.Expires.After(blockedUntil) {
+				blockedUntil = rec.Expires
+			}
+		}
+		if !blockedUntil.IsZero() {
+			return nil
+		}
+		rec := &localRecord{Expires: expires, Hostname: s.hostname, Nonce: nonce}
+		for _, name := range names {
+			if err := putLocal(tx, name, rec); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return lease{}, false, err
+	}
+	if !blockedUntil.IsZero() {
+		return lease{blockedUntil, names, uuid.UUID{}}, false, nil
+	}
+	return lease{expires, names, nonce}, true, nil
+}
+
+// release is the embedded-store equivalent of releaseTemplate.
+func (s *localStore) release(_ context.Context, rel lease) (ok bool, err error) {
+	err = s.db.Update(func(tx *kvstore.Tx) error {
+		for _, name := range rel.names {
+			rec, err := getLocal(tx, name)
+			if err != nil {
+				return err
+			}
+			if rec == nil || rec.Nonce != rel.nonce {
+				continue
+			}
+			if err := tx.Delete([]byte(localPrefix + name)); err != nil {
+				return err
+			}
+			ok = true
+		}
+		return nil
+	})
+	return ok, err
+}
+
+// renew is the embedded-store equivalent of renewTemplate.
+func (s *localStore) renew(_ context.Context, tgt lease, expires time.Time) (bool, error) {
+	ok := true
+	err := s.db.Update(func(tx *kvstore.Tx) error {
+		recs := make([]*localRecord, len(tgt.names))
+		for idx, name := range tgt.names {
+			rec, err := getLocal(tx, name)
+			if err != nil {
+				return err
+			}
+			if rec == nil || rec.Nonce != tgt.nonce {
+				ok = false
+				return nil
+			}
+			recs[idx] = rec
+		}
+		for idx, name := range tgt.names {
+			recs[idx].Expires = expires
+			if err := putLocal(tx, name, recs[idx]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return ok && err == nil, err
+}
@@ -0,0 +1,101 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package leases
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/cockroachdb/replicator/internal/util/kvstore"
+	"github.com/cockroachdb/replicator/internal/util/stdpool"
+	"github.com/stretchr/testify/require"
+)
+
+// TestLocal exercises the embedded-store implementation of leases.
+func TestLocal(t *testing.T) {
+	r := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	store, err := kvstore.Open(t.TempDir())
+	r.NoError(err)
+	defer func() { r.NoError(store.Close()) }()
+
+	intf, err := New(ctx, Config{
+		Local:  store,
+		Pool:   stdpool.NewLocalStaging(),
+		Target: ident.WithHint(ident.NewTable(ident.MustSchema(ident.New("local")), ident.New("leases")), ""),
+	})
+	r.NoError(err)
+	l := intf.(*leases)
+
+	now := time.Now().UTC()
+	names := []string{"A", "B"}
+
+	initial, ok, err := l.tryAcquire(ctx, names, now)
+	r.NoError(err)
+	r.True(ok)
+
+	// Overlapping names are blocked until expiration.
+	blocked, ok, err := l.tryAcquire(ctx, []string{"B", "C"}, now.Add(l.cfg.Lifetime/2))
+	r.NoError(err)
+	r.False(ok)
+	r.Equal(initial.expires, blocked.expires)
+
+	// Renewal requires the nonce to match.
+	renewed, ok, err := l.tryRenew(ctx, initial, now.Add(time.Second))
+	r.NoError(err)
+	r.True(ok)
+	r.True(renewed.expires.After(initial.expires))
+
+	stolen := initial
+	stolen.nonce[0]++
+	_, ok, err = l.tryRenew(ctx, stolen, now)
+	r.NoError(err)
+	r.False(ok)
+	ok, err = l.tryRelease(ctx, stolen)
+	r.NoError(err)
+	r.False(ok)
+
+	// Once released, another caller may acquire the names.
+	ok, err = l.tryRelease(ctx, renewed)
+	r.NoError(err)
+	r.True(ok)
+	_, ok, err = l.tryAcquire(ctx, []string{"B", "C"}, now)
+	r.NoError(err)
+	r.True(ok)
+
+	// Check the public API.
+	_, err = l.Acquire(ctx, "C")
+	_, busy := types.IsLeaseBusy(err)
+	r.True(busy)
+
+	lease, err := l.Acquire(ctx, "D")
+	r.NoError(err)
+	lease.Release()
+	select {
+	case <-lease.Context().Done():
+	case <-time.After(time.Second):
+		r.Fail("lease context not canceled")
+	}
+	lease, err = l.Acquire(ctx, "D")
+	r.NoError(err)
+	lease.Release()
+}
@@ -22,6 +22,7 @@ import (
 
 	"github.com/cockroachdb/replicator/internal/types"
 	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/cockroachdb/replicator/internal/util/kvstore"
 	"github.com/google/wire"
 )
 
@@ -32,12 +33,16 @@ var Set = wire.NewSet(
 
 // ProvideLeases is called by Wire to configure the work-leasing strategy.
 func ProvideLeases(
-	ctx context.Context, pool *types.StagingPool, stagingDB ident.StagingSchema,
+	ctx context.Context,
+	pool *types.StagingPool,
+	local *kvstore.Store,
+	stagingDB ident.StagingSchema,
 ) (types.Leases, error) {
 	target := pool.HintNoFTS(ident.NewTable(stagingDB.Schema(), ident.New("leases")))
 	return New(ctx, Config{
 		Guard:      time.Second,
 		Lifetime:   5 * time.Second,
+		Local:      local,
 		RetryDelay: time.Second,
 		Poll:       time.Second,
 		Pool:       pool,
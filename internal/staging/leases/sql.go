@@ -0,0 +1,76 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package leases
+
+import (
+	"context"
+	"time"
+
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// sqlStore keeps leases in the staging database.
+type sqlStore struct {
+	hostname string
+	pool     *types.StagingPool
+	sql      struct {
+		acquire string
+		release string
+		renew   string
+	}
+}
+
+var _ store = (*sqlStore)(nil)
+
+func (s *sqlStore) acquire(
+	ctx context.Context, names []string, expires, now time.Time,
+) (lease, bool, error) {
+	var blockedUntil *time.Time
+	var nonce uuid.UUID
+
+	if err := s.pool.QueryRow(ctx,
+		s.sql.acquire,
+		names,
+		expires,
+		s.hostname,
+		now,
+	).Scan(&blockedUntil, &nonce); err != nil {
+		return lease{}, false, errors.WithStack(err)
+	}
+	if blockedUntil != nil {
+		return lease{*blockedUntil, names, uuid.UUID{}}, false, nil
+	}
+	return lease{expires, names, nonce}, true, nil
+}
+
+func (s *sqlStore) release(ctx context.Context, rel lease) (bool, error) {
+	tag, err := s.pool.Exec(ctx, s.sql.release, rel.names, rel.nonce)
+	if err != nil {
+		return false, errors.Wrap(err, s.sql.release)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+func (s *sqlStore) renew(ctx context.Context, tgt lease, expires time.Time) (bool, error) {
+	tag, err := s.pool.Exec(ctx, s.sql.renew, expires, tgt.names, tgt.nonce)
+	if err != nil {
+		return false, errors.Wrap(err, s.sql.renew)
+	}
+	return tag.RowsAffected() > 0, nil
+}
@@ -0,0 +1,59 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package memo
+
+import (
+	"context"
+
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/kvstore"
+)
+
+// localPrefix is prepended to all keys stored by Local.
+const localPrefix = "memo/"
+
+// Local is an implementation of types.Memo backed by an embedded
+// store. Transactions are ignored.
+type Local struct {
+	store *kvstore.Store
+}
+
+var _ types.Memo = (*Local)(nil)
+
+// NewLocal constructs a Local memo.
+func NewLocal(store *kvstore.Store) *Local {
+	return &Local{store: store}
+}
+
+// Get implements types.Memo.
+func (m *Local) Get(_ context.Context, _ types.StagingQuerier, key string) ([]byte, error) {
+	var ret []byte
+	err := m.store.View(func(tx *kvstore.Tx) error {
+		if found := tx.Get([]byte(localPrefix + key)); found != nil {
+			ret = append([]byte{}, found...)
+		}
+		return nil
+	})
+	return ret, err
+}
+
+// Put implements types.Memo.
+func (m *Local) Put(_ context.Context, _ types.StagingQuerier, key string, value []byte) error {
+	return m.store.Update(func(tx *kvstore.Tx) error {
+		return tx.Put([]byte(localPrefix+key), value)
+	})
+}
@@ -0,0 +1,60 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package memo_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cockroachdb/replicator/internal/staging/memo"
+	"github.com/cockroachdb/replicator/internal/util/kvstore"
+	"github.com/stretchr/testify/require"
+)
+
+// TestLocal validates the embedded version of types.Memo.
+func TestLocal(t *testing.T) {
+	ctx := context.Background()
+	r := require.New(t)
+	dir := t.TempDir()
+
+	store, err := kvstore.Open(dir)
+	r.NoError(err)
+	m := memo.NewLocal(store)
+
+	r.NoError(m.Put(ctx, nil, "one", []byte("value")))
+	r.NoError(m.Put(ctx, nil, "two", []byte{}))
+
+	// Values should survive re-opening the store.
+	r.NoError(store.Close())
+	store, err = kvstore.Open(dir)
+	r.NoError(err)
+	defer func() { r.NoError(store.Close()) }()
+	m = memo.NewLocal(store)
+
+	got, err := m.Get(ctx, nil, "one")
+	r.NoError(err)
+	r.Equal([]byte("value"), got)
+
+	got, err = m.Get(ctx, nil, "two")
+	r.NoError(err)
+	r.NotNil(got)
+	r.Empty(got)
+
+	got, err = m.Get(ctx, nil, "three")
+	r.NoError(err)
+	r.Nil(got)
+}
@@ -22,6 +22,7 @@ import (
 
 	"github.com/cockroachdb/replicator/internal/types"
 	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/cockroachdb/replicator/internal/util/kvstore"
 	"github.com/cockroachdb/replicator/internal/util/retry"
 	"github.com/google/wire"
 )
@@ -29,13 +30,17 @@ import (
 // Set is used by Wire.
 var Set = wire.NewSet(
 	ProvideMemo,
-	wire.Bind(new(types.Memo), new(*Memo)),
 )
 
-// ProvideMemo is called by Wire to construct the KV wrapper.
+// ProvideMemo is called by Wire to construct the KV wrapper. If an
+// embedded store is provided, it will be used instead of the staging
+// database.
 func ProvideMemo(
-	ctx context.Context, db *types.StagingPool, staging ident.StagingSchema,
-) (*Memo, error) {
+	ctx context.Context, db *types.StagingPool, local *kvstore.Store, staging ident.StagingSchema,
+) (types.Memo, error) {
+	if local != nil {
+		return NewLocal(local), nil
+	}
 	target := ident.NewTable(staging.Schema(), ident.New("memo"))
 	if err := retry.Execute(ctx, db, fmt.Sprintf(schema, target)); err != nil {
 		return nil, err
@@ -18,37 +18,60 @@ package stage
 
 import (
 	"context"
+	"encoding/json"
 	"sync"
 	"time"
 
 	"github.com/cockroachdb/field-eng-powertools/stopper"
 	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/hlc"
 	"github.com/cockroachdb/replicator/internal/util/ident"
 	"github.com/pkg/errors"
 )
 
 type factory struct {
-	db        *types.StagingPool
-	stagingDB ident.Schema
-	stop      *stopper.Context
+	db       *types.StagingPool
+	newStage func(target ident.Table) (stager, error)
 
 	mu struct {
 		sync.RWMutex
-		instances *ident.TableMap[*stage]
+		instances *ident.TableMap[stager]
 	}
 }
 
+// stager is implemented by the SQL and embedded-store stages.
+type stager interface {
+	types.Stager
+
+	// readFragment returns, in order, up to limit mutations whose
+	// timestamps are within the bounds. If afterKey is non-nil, only
+	// mutations at the minimum time whose keys sort after afterKey
+	// will be returned.
+	readFragment(
+		ctx context.Context,
+		bounds hlc.Range,
+		afterKey json.RawMessage,
+		limit int,
+		includeApplied bool,
+	) ([]types.Mutation, error)
+}
+
 var _ types.Stagers = (*factory)(nil)
 
 // Get returns a memoized instance of a stage for the given table.
 func (f *factory) Get(_ context.Context, target ident.Table) (types.Stager, error) {
+	return f.get(target)
+}
+
+// get returns the concrete stager for the table.
+func (f *factory) get(target ident.Table) (stager, error) {
 	if ret := f.getUnlocked(target); ret != nil {
 		return ret, nil
 	}
 	return f.createUnlocked(target)
 }
 
-func (f *factory) createUnlocked(table ident.Table) (*stage, error) {
+func (f *factory) createUnlocked(table ident.Table) (stager, error) {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
@@ -56,14 +79,15 @@ func (f *factory) createUnlocked(table ident.Table) (*stage, error) {
 		return ret, nil
 	}
 
-	ret, err := newStage(f.stop, f.db, f.stagingDB, table)
-	if err == nil {
-		f.mu.instances.Put(table, ret)
+	ret, err := f.newStage(table)
+	if err != nil {
+		return nil, err
 	}
-	return ret, err
+	f.mu.instances.Put(table, ret)
+	return ret, nil
 }
 
-func (f *factory) getUnlocked(table ident.Table) *stage {
+func (f *factory) getUnlocked(table ident.Table) stager {
 	f.mu.RLock()
 	defer f.mu.RUnlock()
 	return f.mu.instances.GetZero(table)
@@ -92,8 +116,11 @@ var _ types.BatchReader = (*stagingReader)(nil)
 
 func (r *stagingReader) Read(ctx *stopper.Context) (<-chan *types.BatchCursor, error) {
 	// Ensure all staging tables exist.
-	for _, table := range r.Group.Tables {
-		if _, err := r.Get(ctx, table); err != nil {
+	stages := make([]stager, len(r.Group.Tables))
+	for idx, table := range r.Group.Tables {
+		var err error
+		stages[idx], err = r.get(table)
+		if err != nil {
 			return nil, err
 		}
 	}
@@ -104,7 +131,7 @@ func (r *stagingReader) Read(ctx *stopper.Context) (<-chan *types.BatchCursor, e
 		ch := make(chan *tableCursor, 2)
 		tableChans[idx] = ch
 		tableReader := newTableReader(
-			r.Bounds, r.db, r.FragmentSize, ch, r.stagingDB, target)
+			r.Bounds, r.db, r.FragmentSize, r.IncludeApplied, ch, stages[idx], target)
 		ctx.Go(func(ctx *stopper.Context) error {
 			tableReader.run(ctx)
 			return nil
@@ -0,0 +1,560 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package stage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/cockroachdb/field-eng-powertools/notify"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/hlc"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/cockroachdb/replicator/internal/util/kvstore"
+	"github.com/cockroachdb/replicator/internal/util/metrics"
+	"github.com/cockroachdb/replicator/internal/util/msort"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+// The layout of staged mutations in an embedded store is:
+//
+//	stage/<table>\x00<nanos><logical><key> -> localMutation
+//	stagekey/<table>\x00<key>\x00<nanos><logical> -> empty
+//
+// The time is encoded as fixed-width decimal values so that the
+// mutations for a table sort in (nanos, logical, key) order, which is
+// the same as the primary key of a staging table. The secondary
+// entries allow all mutations for a key to be found.
+const (
+	localIndexPrefix = "stagekey/"
+	localPrefix      = "stage/"
+	localTimeWidth   = 30
+)
+
+// localMutation is the persistent form of a staged mutation.
+type localMutation struct {
+	Applied   bool       `json:"applied,omitempty"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+	Before    []byte     `json:"before,omitempty"`
+	Data      []byte     `json:"mut"`
+	Deletion  bool       `json:"deletion,omitempty"`
+}
+
+// localTablePrefix returns the prefix for all mutations staged for the
+// target table.
+func localTablePrefix(stagingDB ident.Schema, target ident.Table) []byte {
+	return []byte(localPrefix + stagingTable(stagingDB, target).Raw() + "\x00")
+}
+
+// localTime encodes the time in a sortable fashion.
+func localTime(ts hlc.Time) string {
+	return fmt.Sprintf("%020d%010d", ts.Nanos(), ts.Logical())
+}
+
+// parseLocalTime decodes the value produced by localTime.
+func parseLocalTime(buf []byte) (hlc.Time, error) {
+	var nanos int64
+	var logical int
+	if len(buf) < localTimeWidth {
+		return hlc.Zero(), errors.Errorf("malformed staging key %q", buf)
+	}
+	if _, err := fmt.Sscanf(string(buf[:localTimeWidth]), "%020d%010d", &nanos, &logical); err != nil {
+		return hlc.Zero(), errors.Wrapf(err, "malformed staging key %q", buf)
+	}
+	return hlc.New(nanos, logical), nil
+}
+
+// decodeLocal reconstructs a mutation from the portion of a key after
+// the table prefix and its associated value.
+func decodeLocal(suffix, value []byte) (types.Mutation, *localMutation, error) {
+	ts, err := parseLocalTime(suffix)
+	if err != nil {
+		return types.Mutation{}, nil, err
+	}
+	var stored localMutation
+	if err := json.Unmarshal(value, &stored); err != nil {
+		return types.Mutation{}, nil, errors.WithStack(err)
+	}
+	return types.Mutation{
+		Before:   stored.Before,
+		Data:     stored.Data,
+		Deletion: stored.Deletion,
+		Key:      append(json.RawMessage(nil), suffix[localTimeWidth:]...),
+		Time:     ts,
+	}, &stored, nil
+}
+
+// localStage is an implementation of [types.Stager] which uses an
+// embedded store. Any transaction passed to its methods is ignored.
+type localStage struct {
+	index      []byte               // Prefix for per-key entries.
+	prefix     []byte               // Prefix for mutations.
+	retireFrom notify.Var[hlc.Time] // Makes subsequent calls to Retire() a bit faster.
+	store      *kvstore.Store
+	table      ident.Table
+
+	filterApplied  prometheus.Observer
+	filterCount    prometheus.Counter
+	markDuration   prometheus.Observer
+	retireDuration prometheus.Observer
+	retireError    prometheus.Counter
+	stageCount     prometheus.Counter
+	stageDuration  prometheus.Observer
+	stageError     prometheus.Counter
+}
+
+var _ stager = (*localStage)(nil)
+
+func newLocalStage(store *kvstore.Store, stagingDB ident.Schema, target ident.Table) *localStage {
+	labels := metrics.TableValues(target)
+	return &localStage{
+		index:  []byte(localIndexPrefix + stagingTable(stagingDB, target).Raw() + "\x00"),
+		prefix: localTablePrefix(stagingDB, target),
+		store:  store,
+		table:  stagingTable(stagingDB, target),
+
+		filterApplied:  stageFilterAppliedDuration.WithLabelValues(labels...),
+		filterCount:    stageFilterCount.WithLabelValues(labels...),
+		markDuration:   stageMarkDuration.WithLabelValues(labels...),
+		retireDuration: stageRetireDurations.WithLabelValues(labels...),
+		retireError:    stageRetireErrors.WithLabelValues(labels...),
+		stageCount:     stageCount.WithLabelValues(labels...),
+		stageDuration:  stageDuration.WithLabelValues(labels...),
+		stageError:     stageErrors.WithLabelValues(labels...),
+	}
+}
+
+func (s *localStage) key(ts hlc.Time, key []byte) []byte {
+	ret := append([]byte(nil), s.prefix...)
+	ret = append(ret, localTime(ts)...)
+	return append(ret, key...)
+}
+
+func (s *localStage) indexKey(ts hlc.Time, key []byte) []byte {
+	ret := append([]byte(nil), s.index...)
+	ret = append(ret, key...)
+	ret = append(ret, 0)
+	return append(ret, localTime(ts)...)
+}
+
+// get returns the stored mutation, or nil if it does not exist.
+func (s *localStage) get(tx *kvstore.Tx, mut types.Mutation) (*localMutation, error) {
+	buf := tx.Get(s.key(mut.Time, mut.Key))
+	if buf == nil {
+		return nil, nil
+	}
+	var ret localMutation
+	return &ret, errors.WithStack(json.Unmarshal(buf, &ret))
+}
+
+// put stores the mutation and its per-key entry.
+func (s *localStage) put(tx *kvstore.Tx, ts hlc.Time, key []byte, mut *localMutation) error {
+	buf, err := json.Marshal(mut)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if err := tx.Put(s.key(ts, key), buf); err != nil {
+		return err
+	}
+	return tx.Put(s.indexKey(ts, key), nil)
+}
+
+// forKey returns the times of all mutations staged for the key, in
+// time order.
+func (s *localStage) forKey(tx *kvstore.Tx, key []byte) ([]hlc.Time, error) {
+	prefix := append(append([]byte(nil), s.index...), key...)
+	prefix = append(prefix, 0)
+	var ret []hlc.Time
+	for k := range tx.ScanPrefix(prefix) {
+		ts, err := parseLocalTime(k[len(prefix):])
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, ts)
+	}
+	return ret, nil
+}
+
+// CheckConsistency implements [types.Stager]. It verifies that the
+// mutations for each key were applied in the order in which they were
+// staged.
+func (s *localStage) CheckConsistency(
+	_ context.Context, _ types.StagingQuerier, muts []types.Mutation, _ bool,
+) (int, error) {
+	var count int
+	err := s.store.View(func(tx *kvstore.Tx) error {
+		// Find the keys to check.
+		var keys [][]byte
+		if len(muts) > 0 {
+			seen := make(map[string]bool, len(muts))
+			for _, mut := range muts {
+				if !seen[string(mut.Key)] {
+					seen[string(mut.Key)] = true
+					keys = append(keys, mut.Key)
+				}
+			}
+		} else {
+			var last []byte
+			for k := range tx.ScanPrefix(s.index) {
+				key := k[len(s.index) : len(k)-localTimeWidth-1]
+				if !bytes.Equal(key, last) {
+					last = append([]byte(nil), key...)
+					keys = append(keys, last)
+				}
+			}
+		}
+
+		type entry struct {
+			appliedAt *time.Time
+			ts        hlc.Time
+		}
+		for _, key := range keys {
+			times, err := s.forKey(tx, key)
+			if err != nil {
+				return err
+			}
+			entries := make([]entry, len(times))
+			for idx, ts := range times {
+				stored, err := s.get(tx, types.Mutation{Key: key, Time: ts})
+				if err != nil {
+					return err
+				}
+				if stored == nil {
+					return errors.Errorf("missing staged mutation for %s@%s", key, ts)
+				}
+				entries[idx] = entry{stored.AppliedAt, ts}
+			}
+			applyOrder := make([]entry, len(entries))
+			copy(applyOrder, entries)
+			sort.SliceStable(applyOrder, func(i, j int) bool {
+				a, b := applyOrder[i].appliedAt, applyOrder[j].appliedAt
+				switch {
+				case a == nil && b == nil:
+					return false
+				case a == nil:
+					return false
+				case b == nil:
+					return true
+				default:
+					return a.Before(*b)
+				}
+			})
+			keyCount := 0
+			for idx := range entries {
+				if entries[idx].ts != applyOrder[idx].ts {
+					keyCount++
+				}
+			}
+			if keyCount > 0 {
+				count += keyCount
+				log.WithFields(log.Fields{
+					"count": keyCount,
+					"table": s.table,
+					"key":   string(key),
+				}).Trace("consistency check failed")
+			}
+		}
+		return nil
+	})
+	return count, err
+}
+
+// FilterApplied implements [types.Stager].
+func (s *localStage) FilterApplied(
+	_ context.Context, _ types.StagingQuerier, muts []types.Mutation,
+) ([]types.Mutation, error) {
+	start := time.Now()
+	ret := make([]types.Mutation, 0, len(muts))
+	err := s.store.View(func(tx *kvstore.Tx) error {
+		for _, mut := range muts {
+			stored, err := s.get(tx, mut)
+			if err != nil {
+				return err
+			}
+			if stored == nil || !stored.Applied {
+				ret = append(ret, mut)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.filterCount.Add(float64(len(muts) - len(ret)))
+	s.filterApplied.Observe(time.Since(start).Seconds())
+	return ret, nil
+}
+
+// MarkApplied implements [types.Stager]. If a mutation does not already
+// exist, a stub entry will be created.
+func (s *localStage) MarkApplied(
+	_ context.Context, _ types.StagingQuerier, muts []types.Mutation,
+) error {
+	start := time.Now()
+	now := time.Now().UTC()
+	err := s.store.Update(func(tx *kvstore.Tx) error {
+		for _, mut := range muts {
+			stored, err := s.get(tx, mut)
+			if err != nil {
+				return err
+			}
+			if stored == nil {
+				stored = &localMutation{Data: stubSentinel}
+			}
+			stored.Applied = true
+			stored.AppliedAt = &now
+			if err := s.put(tx, mut.Time, mut.Key, stored); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if extraSanityChecks {
+		count, err := s.CheckConsistency(context.Background(), nil, muts, false)
+		if err != nil {
+			return err
+		}
+		if count != 0 {
+			return errors.Errorf("consistency check failed with %d mutations", count)
+		}
+	}
+	s.markDuration.Observe(time.Since(start).Seconds())
+	return nil
+}
+
+// Retire implements [types.Stager].
+func (s *localStage) Retire(_ context.Context, _ types.StagingQuerier, end hlc.Time) error {
+	start := time.Now()
+	from, _ := s.retireFrom.Get()
+	if hlc.Compare(from, end) >= 0 {
+		return nil
+	}
+	err := s.store.Update(func(tx *kvstore.Tx) error {
+		// Collect keys first, since the store can't be modified while
+		// scanning.
+		var toDelete []types.Mutation
+		for k, v := range tx.Scan(s.key(from, nil), s.key(end.Next(), nil)) {
+			mut, stored, err := decodeLocal(k[len(s.prefix):], v)
+			if err != nil {
+				return err
+			}
+			if stored.Applied {
+				toDelete = append(toDelete, mut)
+			}
+		}
+		for _, mut := range toDelete {
+			if err := tx.Delete(s.key(mut.Time, mut.Key)); err != nil {
+				return err
+			}
+			if err := tx.Delete(s.indexKey(mut.Time, mut.Key)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		s.retireError.Inc()
+		return err
+	}
+	s.retireFrom.Set(end)
+	s.retireDuration.Observe(time.Since(start).Seconds())
+	return nil
+}
+
+// Rewind implements [types.Stager].
+func (s *localStage) Rewind(_ context.Context, _ types.StagingQuerier, after hlc.Time) error {
+	err := s.store.Update(func(tx *kvstore.Tx) error {
+		// Collect the changes first, since the store can't be modified
+		// while scanning.
+		var stubs, unapplied []types.Mutation
+		var stored []*localMutation
+		for k, v := range tx.Scan(s.key(after.Next(), nil), kvstore.PrefixEnd(s.prefix)) {
+			mut, entry, err := decodeLocal(k[len(s.prefix):], v)
+			if err != nil {
+				return err
+			}
+			if !entry.Applied {
+				continue
+			}
+			if bytes.Equal(entry.Data, stubSentinel) {
+				stubs = append(stubs, mut)
+				continue
+			}
+			entry.Applied = false
+			entry.AppliedAt = nil
+			unapplied = append(unapplied, mut)
+			stored = append(stored, entry)
+		}
+		for _, mut := range stubs {
+			if err := tx.Delete(s.key(mut.Time, mut.Key)); err != nil {
+				return err
+			}
+			if err := tx.Delete(s.indexKey(mut.Time, mut.Key)); err != nil {
+				return err
+			}
+		}
+		for idx, mut := range unapplied {
+			if err := s.put(tx, mut.Time, mut.Key, stored[idx]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	// Allow the re-applied mutations to be retired again.
+	_, _, _ = s.retireFrom.Update(func(from hlc.Time) (hlc.Time, error) {
+		if hlc.Compare(from, after) <= 0 {
+			return from, notify.ErrNoUpdate
+		}
+		return after, nil
+	})
+	return nil
+}
+
+// Stage implements [types.Stager].
+func (s *localStage) Stage(
+	_ context.Context, _ types.StagingQuerier, mutations []types.Mutation,
+) error {
+	start := time.Now()
+	mutations = msort.UniqueByTimeKey(mutations)
+	err := s.store.Update(func(tx *kvstore.Tx) error {
+		for _, mut := range mutations {
+			// Equivalent to ON CONFLICT DO NOTHING.
+			if tx.Get(s.key(mut.Time, mut.Key)) != nil {
+				continue
+			}
+			if err := s.put(tx, mut.Time, mut.Key, &localMutation{
+				Before:   mut.Before,
+				Data:     mut.Data,
+				Deletion: mut.Deletion,
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		s.stageError.Inc()
+		return err
+	}
+	s.stageCount.Add(float64(len(mutations)))
+	s.stageDuration.Observe(time.Since(start).Seconds())
+	return nil
+}
+
+// StageIfExists implements [types.Stager].
+func (s *localStage) StageIfExists(
+	_ context.Context, _ types.StagingQuerier, mutations []types.Mutation,
+) ([]types.Mutation, error) {
+	var ret []types.Mutation
+	err := s.store.Update(func(tx *kvstore.Tx) error {
+		// Determine which keys have unapplied mutations before making
+		// any changes.
+		existing := make(map[string]bool, len(mutations))
+		for _, mut := range mutations {
+			if _, found := existing[string(mut.Key)]; found {
+				continue
+			}
+			times, err := s.forKey(tx, mut.Key)
+			if err != nil {
+				return err
+			}
+			exists := false
+			for _, ts := range times {
+				stored, err := s.get(tx, types.Mutation{Key: mut.Key, Time: ts})
+				if err != nil {
+					return err
+				}
+				if stored != nil && !stored.Applied {
+					exists = true
+					break
+				}
+			}
+			existing[string(mut.Key)] = exists
+		}
+
+		ret = make([]types.Mutation, 0, len(mutations))
+		for _, mut := range mutations {
+			if !existing[string(mut.Key)] {
+				ret = append(ret, mut)
+				continue
+			}
+			if err := s.put(tx, mut.Time, mut.Key, &localMutation{
+				Before:   mut.Before,
+				Data:     mut.Data,
+				Deletion: mut.Deletion,
+			}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return ret, err
+}
+
+// readFragment implements stager.
+func (s *localStage) readFragment(
+	_ context.Context,
+	bounds hlc.Range,
+	afterKey json.RawMessage,
+	limit int,
+	includeApplied bool,
+) ([]types.Mutation, error) {
+	ret := make([]types.Mutation, 0, limit)
+
+	from := append([]byte(nil), s.prefix...)
+	from = append(from, localTime(bounds.Min())...)
+	if afterKey != nil {
+		// Resume after the last key that was read.
+		from = append(from, afterKey...)
+		from = append(from, 0)
+	}
+	to := append([]byte(nil), s.prefix...)
+	to = append(to, localTime(bounds.Max())...)
+
+	err := s.store.View(func(tx *kvstore.Tx) error {
+		for k, v := range tx.Scan(from, to) {
+			mut, stored, err := decodeLocal(k[len(s.prefix):], v)
+			if err != nil {
+				return err
+			}
+			if stored.Applied && !includeApplied {
+				continue
+			}
+			if bytes.Equal(stored.Data, stubSentinel) {
+				continue
+			}
+			ret = append(ret, mut)
+			if len(ret) >= limit {
+				break
+			}
+		}
+		return nil
+	})
+	return ret, err
+}
@@ -0,0 +1,244 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package stage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/field-eng-powertools/notify"
+	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/hlc"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/cockroachdb/replicator/internal/util/kvstore"
+	"github.com/cockroachdb/replicator/internal/util/stdpool"
+	"github.com/stretchr/testify/require"
+)
+
+// TestLocal exercises the embedded-store implementation of the
+// Stagers.
+func TestLocal(t *testing.T) {
+	r := require.New(t)
+	base, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	ctx := stopper.WithContext(base)
+	defer ctx.Stop(time.Second)
+
+	store, err := kvstore.Open(t.TempDir())
+	r.NoError(err)
+	defer func() { r.NoError(store.Close()) }()
+
+	stagingDB := ident.StagingSchema(ident.MustSchema(ident.New("_replicator"), ident.Public))
+	stagers := ProvideFactory(stdpool.NewLocalStaging(), store, stagingDB, ctx)
+
+	tbl := ident.NewTable(ident.MustSchema(ident.New("db"), ident.Public), ident.New("tbl"))
+	s, err := stagers.Get(ctx, tbl)
+	r.NoError(err)
+	r.IsType(&localStage{}, s)
+
+	mut := func(key, nanos int) types.Mutation {
+		return types.Mutation{
+			Data: json.RawMessage(fmt.Sprintf(`{"pk":%d,"v":%d}`, key, nanos)),
+			Key:  json.RawMessage(fmt.Sprintf(`[%d]`, key)),
+			Time: hlc.New(int64(nanos), 0),
+		}
+	}
+
+	// Stage is idempotent.
+	muts := []types.Mutation{mut(1, 1), mut(2, 1), mut(1, 2), mut(3, 3)}
+	r.NoError(s.Stage(ctx, nil, muts))
+	r.NoError(s.Stage(ctx, nil, muts))
+
+	// Only keys with pending mutations are staged.
+	remaining, err := s.StageIfExists(ctx, nil, []types.Mutation{mut(2, 4), mut(4, 4)})
+	r.NoError(err)
+	r.Equal([]types.Mutation{mut(4, 4)}, remaining)
+
+	// Read everything through the query interface.
+	bounds := &notify.Var[hlc.Range]{}
+	bounds.Set(hlc.RangeIncluding(hlc.Zero(), hlc.New(10, 0)))
+	reader, err := stagers.Query(ctx, &types.StagingQuery{
+		Bounds:       bounds,
+		FragmentSize: 2,
+		Group:        &types.TableGroup{Name: ident.New("group"), Tables: []ident.Table{tbl}},
+	})
+	r.NoError(err)
+	readCtx := stopper.WithContext(ctx)
+	ch, err := reader.Read(readCtx)
+	r.NoError(err)
+	var read []types.Mutation
+	for len(read) < 5 {
+		select {
+		case cursor := <-ch:
+			r.NoError(cursor.Error)
+			if cursor.Batch != nil {
+				read = append(read, types.Flatten(cursor.Batch)...)
+			}
+		case <-ctx.Done():
+			r.Fail("timed out", "read %d mutations", len(read))
+		}
+	}
+	readCtx.Stop(time.Second)
+	r.Len(read, 5)
+	for idx := 1; idx < len(read); idx++ {
+		r.True(hlc.Compare(read[idx-1].Time, read[idx].Time) <= 0)
+	}
+
+	// Applied mutations are filtered, and missing mutations become
+	// stubs.
+	r.NoError(s.MarkApplied(ctx, nil, []types.Mutation{mut(1, 1), mut(9, 9)}))
+	filtered, err := s.FilterApplied(ctx, nil, []types.Mutation{mut(1, 1), mut(1, 2), mut(9, 9)})
+	r.NoError(err)
+	r.Equal([]types.Mutation{mut(1, 2)}, filtered)
+
+	count, err := s.CheckConsistency(ctx, nil, nil, false)
+	r.NoError(err)
+	r.Zero(count)
+
+	// Applying out of order is reported.
+	r.NoError(s.MarkApplied(ctx, nil, []types.Mutation{mut(2, 4)}))
+	r.NoError(s.MarkApplied(ctx, nil, []types.Mutation{mut(2, 1)}))
+	count, err = s.CheckConsistency(ctx, nil, []types.Mutation{mut(2, 1)}, false)
+	r.NoError(err)
+	r.Equal(2, count)
+
+	// Retire removes applied mutations, including the stub.
+	before := store.Len()
+	r.NoError(s.Retire(ctx, nil, hlc.New(9, 0)))
+	r.Equal(before-2*4, store.Len())
+	filtered, err = s.FilterApplied(ctx, nil, []types.Mutation{mut(1, 2), mut(3, 3)})
+	r.NoError(err)
+	r.Len(filtered, 2)
+}
+
+// TestLocalRewind verifies that applied mutations can be made eligible
+// for re-application.
+func TestLocalRewind(t *testing.T) {
+	r := require.New(t)
+	base, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	ctx := stopper.WithContext(base)
+	defer ctx.Stop(time.Second)
+
+	store, err := kvstore.Open(t.TempDir())
+	r.NoError(err)
+	defer func() { r.NoError(store.Close()) }()
+
+	stagingDB := ident.StagingSchema(ident.MustSchema(ident.New("_replicator"), ident.Public))
+	stagers := ProvideFactory(stdpool.NewLocalStaging(), store, stagingDB, ctx)
+
+	tbl := ident.NewTable(ident.MustSchema(ident.New("db"), ident.Public), ident.New("tbl"))
+	s, err := stagers.Get(ctx, tbl)
+	r.NoError(err)
+
+	mut := func(key, nanos int) types.Mutation {
+		return types.Mutation{
+			Data: json.RawMessage(fmt.Sprintf(`{"pk":%d,"v":%d}`, key, nanos)),
+			Key:  json.RawMessage(fmt.Sprintf(`[%d]`, key)),
+			Time: hlc.New(int64(nanos), 0),
+		}
+	}
+	muts := []types.Mutation{mut(1, 1), mut(1, 2), mut(2, 3)}
+	r.NoError(s.Stage(ctx, nil, muts))
+	r.NoError(s.MarkApplied(ctx, nil, append(muts, mut(3, 4))))
+	filtered, err := s.FilterApplied(ctx, nil, muts)
+	r.NoError(err)
+	r.Empty(filtered)
+
+	// The stub is removed and later mutations are unapplied.
+	before := store.Len()
+	r.NoError(s.Rewind(ctx, nil, hlc.New(1, 0)))
+	r.Equal(before-2, store.Len())
+	filtered, err = s.FilterApplied(ctx, nil, muts)
+	r.NoError(err)
+	r.Equal([]types.Mutation{mut(1, 2), mut(2, 3)}, filtered)
+
+	// Rewound mutations can be applied and retired again.
+	r.NoError(s.MarkApplied(ctx, nil, filtered))
+	r.NoError(s.Retire(ctx, nil, hlc.New(9, 0)))
+	r.Zero(store.Len())
+}
+
+// TestLocalIncludeApplied verifies that applied mutations, but not
+// stub entries, can be read back for replay.
+func TestLocalIncludeApplied(t *testing.T) {
+	r := require.New(t)
+	base, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	ctx := stopper.WithContext(base)
+	defer ctx.Stop(time.Second)
+
+	store, err := kvstore.Open(t.TempDir())
+	r.NoError(err)
+	defer func() { r.NoError(store.Close()) }()
+
+	stagingDB := ident.StagingSchema(ident.MustSchema(ident.New("_replicator"), ident.Public))
+	stagers := ProvideFactory(stdpool.NewLocalStaging(), store, stagingDB, ctx)
+
+	tbl := ident.NewTable(ident.MustSchema(ident.New("db"), ident.Public), ident.New("tbl"))
+	s, err := stagers.Get(ctx, tbl)
+	r.NoError(err)
+
+	mut := func(key, nanos int) types.Mutation {
+		return types.Mutation{
+			Data: json.RawMessage(fmt.Sprintf(`{"pk":%d,"v":%d}`, key, nanos)),
+			Key:  json.RawMessage(fmt.Sprintf(`[%d]`, key)),
+			Time: hlc.New(int64(nanos), 0),
+		}
+	}
+	muts := []types.Mutation{mut(1, 1), mut(2, 2), mut(3, 3)}
+	r.NoError(s.Stage(ctx, nil, muts))
+	// The last mutation creates a stub.
+	r.NoError(s.MarkApplied(ctx, nil, []types.Mutation{mut(1, 1), mut(2, 2), mut(4, 4)}))
+
+	read := func(includeApplied bool) []types.Mutation {
+		bounds := notify.VarOf(hlc.RangeIncluding(hlc.Zero(), hlc.New(10, 0)))
+		reader, err := stagers.Query(ctx, &types.StagingQuery{
+			Bounds:         bounds,
+			FragmentSize:   10,
+			Group:          &types.TableGroup{Name: ident.New("group"), Tables: []ident.Table{tbl}},
+			IncludeApplied: includeApplied,
+		})
+		r.NoError(err)
+		readCtx := stopper.WithContext(ctx)
+		defer readCtx.Stop(time.Second)
+		ch, err := reader.Read(readCtx)
+		r.NoError(err)
+		var ret []types.Mutation
+		for {
+			select {
+			case cursor := <-ch:
+				r.NoError(cursor.Error)
+				if cursor.Batch != nil {
+					ret = append(ret, types.Flatten(cursor.Batch)...)
+				}
+				if hlc.Compare(cursor.Progress.Max(), hlc.New(10, 0)) >= 0 {
+					return ret
+				}
+			case <-ctx.Done():
+				r.FailNow("timed out")
+			}
+		}
+	}
+
+	r.Len(read(false), 1)
+	r.Len(read(true), 3)
+}
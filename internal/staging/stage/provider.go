@@ -20,6 +20,7 @@ import (
 	"github.com/cockroachdb/field-eng-powertools/stopper"
 	"github.com/cockroachdb/replicator/internal/types"
 	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/cockroachdb/replicator/internal/util/kvstore"
 	"github.com/google/wire"
 )
 
@@ -28,15 +29,29 @@ var Set = wire.NewSet(
 	ProvideFactory,
 )
 
-// ProvideFactory is called by Wire to construct the Stagers factory.
+// ProvideFactory is called by Wire to construct the Stagers factory. If
+// an embedded store is provided, it will be used instead of the staging
+// database.
 func ProvideFactory(
-	db *types.StagingPool, stagingDB ident.StagingSchema, stop *stopper.Context,
+	db *types.StagingPool,
+	local *kvstore.Store,
+	stagingDB ident.StagingSchema,
+	stop *stopper.Context,
 ) types.Stagers {
-	f := &factory{
-		db:        db,
-		stagingDB: stagingDB.Schema(),
-		stop:      stop,
+	f := &factory{db: db}
+	if local != nil {
+		f.newStage = func(target ident.Table) (stager, error) {
+			return newLocalStage(local, stagingDB.Schema(), target), nil
+		}
+	} else {
+		f.newStage = func(target ident.Table) (stager, error) {
+			ret, err := newStage(stop, db, stagingDB.Schema(), target)
+			if err != nil {
+				return nil, err
+			}
+			return ret, nil
+		}
 	}
-	f.mu.instances = &ident.TableMap[*stage]{}
+	f.mu.instances = &ident.TableMap[stager]{}
 	return f
 }
@@ -22,6 +22,7 @@ package stage
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"runtime"
@@ -87,7 +88,10 @@ type stage struct {
 	sql struct {
 		filterApplied string // Select mutation keys that have been applied.
 		markApplied   string // Mark mutations as having been applied.
+		read          string // Read a fragment of mutations.
 		retire        string // Delete a batch of staged mutations.
+		rewind        string // Mark mutations as unapplied.
+		rewindStubs   string // Delete placeholder entries.
 		stage         string // General-purpose upsert into staging table.
 		stageExists   string // Stage a mutation if one already exists.
 		unapplied     string // Count stale, unapplied mutations.
@@ -95,7 +99,7 @@ type stage struct {
 	}
 }
 
-var _ types.Stager = (*stage)(nil)
+var _ stager = (*stage)(nil)
 
 const tableSchema = `
 CREATE TABLE IF NOT EXISTS %[1]s (
@@ -190,7 +194,10 @@ ALTER TABLE %s ADD COLUMN IF NOT EXISTS deletion BOOL NULL
 	tableHinted := db.HintNoFTS(table)
 	s.sql.filterApplied = fmt.Sprintf(filterAppliedTemplate, tableHinted)
 	s.sql.markApplied = fmt.Sprintf(markAppliedTemplate, tableHinted, stubSentinel)
+	s.sql.read = fmt.Sprintf(readTableTemplate, table)
 	s.sql.retire = fmt.Sprintf(retireTemplate, tableHinted)
+	s.sql.rewind = fmt.Sprintf(rewindTemplate, tableHinted)
+	s.sql.rewindStubs = fmt.Sprintf(rewindStubsTemplate, tableHinted, stubSentinel)
 	s.sql.stage = fmt.Sprintf(stageTemplate, tableHinted)
 	s.sql.stageExists = fmt.Sprintf(stageIfExistsTemplate, tableHinted)
 	s.sql.unapplied = fmt.Sprintf(countTemplate, tableHinted, "")
@@ -650,6 +657,41 @@ func (s *stage) MarkApplied(
 	})
 }
 
+const rewindStubsTemplate = `
+DELETE FROM %s
+ WHERE (nanos, logical) > ($1, $2) AND applied AND mut = '%s'`
+
+const rewindTemplate = `
+UPDATE %s
+   SET applied = false, applied_at = NULL
+ WHERE (nanos, logical) > ($1, $2) AND applied`
+
+// Rewind implements [types.Stager]. The stub entries are deleted so
+// that they will not be mistaken for staged data.
+func (s *stage) Rewind(ctx context.Context, db types.StagingQuerier, after hlc.Time) error {
+	return retry.Retry(ctx, s.stagingDB, func(ctx context.Context) error {
+		tag, err := db.Exec(ctx, s.sql.rewindStubs, after.Nanos(), after.Logical())
+		if err != nil {
+			return errors.Wrap(err, s.sql.rewindStubs)
+		}
+		stubs := tag.RowsAffected()
+		tag, err = db.Exec(ctx, s.sql.rewind, after.Nanos(), after.Logical())
+		if err != nil {
+			return errors.Wrap(err, s.sql.rewind)
+		}
+		// Allow the re-applied mutations to be retired again.
+		_, _, _ = s.retireFrom.Update(func(from hlc.Time) (hlc.Time, error) {
+			if hlc.Compare(from, after) <= 0 {
+				return from, notify.ErrNoUpdate
+			}
+			return after, nil
+		})
+		log.Debugf("Rewind: %s unapplied %d mutations and removed %d stubs after %s",
+			s.stage, tag.RowsAffected(), stubs, after)
+		return nil
+	})
+}
+
 const retireTemplate = `
 WITH d AS (
      DELETE FROM %s
@@ -699,3 +741,59 @@ func (s *stage) Retire(ctx context.Context, db types.StagingQuerier, end hlc.Tim
 	}
 	return err
 }
+
+// Basic pagination-style query.
+//   - ($1, $2, $3): Start position, nanos, logical key
+//   - ($4, $5): End position nanos, logical
+//   - $6: Row limit
+//   - $7: Include applied mutations
+const readTableTemplate = `
+SELECT nanos, logical, key, mut, before, deletion
+FROM %s
+WHERE (nanos, logical, key) > ($1::INT8, $2::INT8, COALESCE($3::STRING, ''))
+AND (nanos, logical) < ($4::INT8, $5::INT8)
+AND (NOT applied OR $7::BOOL)
+ORDER BY nanos, logical, key
+LIMIT $6
+`
+
+// readFragment implements stager.
+func (s *stage) readFragment(
+	ctx context.Context,
+	bounds hlc.Range,
+	afterKey json.RawMessage,
+	limit int,
+	includeApplied bool,
+) ([]types.Mutation, error) {
+	ret := make([]types.Mutation, 0, limit)
+
+	rows, err := s.stagingDB.Query(ctx,
+		s.sql.read,
+		bounds.Min().Nanos(),
+		bounds.Min().Logical(),
+		afterKey,
+		bounds.Max().Nanos(),
+		bounds.Max().Logical(),
+		limit,
+		includeApplied)
+	if err != nil {
+		return nil, errors.Wrap(err, s.sql.read)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var mut types.Mutation
+		var nanos int64
+		var logical int
+		var deletion sql.NullBool // Could be migrated.
+		if err := rows.Scan(&nanos, &logical, &mut.Key,
+			&mut.Data, &mut.Before, &deletion); err != nil {
+			return nil, errors.WithStack(err)
+		}
+		mut.Deletion = deletion.Valid && deletion.Bool
+		mut.Time = hlc.New(nanos, logical)
+
+		ret = append(ret, mut)
+	}
+	return ret, errors.WithStack(rows.Err())
+}
@@ -108,8 +108,9 @@ func TestTableMerger(t *testing.T) {
 			bounds,
 			fixture.StagingPool,
 			fragmentSize,
+			false, // includeApplied
 			out,
-			fixture.StagingDB.Schema(),
+			stages[idx],
 			table)
 		ctx.Go(func(ctx *stopper.Context) error {
 			reader.run(ctx)
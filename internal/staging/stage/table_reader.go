@@ -19,9 +19,7 @@ package stage
 import (
 	"bytes"
 	"context"
-	"database/sql"
 	"encoding/json"
-	"fmt"
 	"time"
 
 	"github.com/cockroachdb/field-eng-powertools/notify"
@@ -31,7 +29,6 @@ import (
 	"github.com/cockroachdb/replicator/internal/util/ident"
 	"github.com/cockroachdb/replicator/internal/util/metrics"
 	"github.com/cockroachdb/replicator/internal/util/retry"
-	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 	log "github.com/sirupsen/logrus"
 )
@@ -46,14 +43,15 @@ type tableCursor struct {
 
 // tableReader returns batches of rows from an individual table.
 type tableReader struct {
-	bounds       *notify.Var[hlc.Range] // Timestamps to read within.
-	db           *types.StagingPool     // Access to the staging database.
-	fragmentSize int                    // Upper bound on the size of data we'll send.
-	out          chan<- *tableCursor    // Communicate to the caller.
-	scanBounds   hlc.Range              // The remaining range of data to scan.
-	scanKey      json.RawMessage        // Position within the table.
-	sqlQ         string                 // The SQL query that drives the tableReader.
-	table        ident.Table            // Names of target table.
+	bounds         *notify.Var[hlc.Range] // Timestamps to read within.
+	db             *types.StagingPool     // Used for retries.
+	fragmentSize   int                    // Upper bound on the size of data we'll send.
+	includeApplied bool                   // Also emit applied mutations.
+	out            chan<- *tableCursor    // Communicate to the caller.
+	scanBounds     hlc.Range              // The remaining range of data to scan.
+	scanKey        json.RawMessage        // Position within the table.
+	source         stager                 // The stage to read from.
+	table          ident.Table            // Names of target table.
 
 	readCount     prometheus.Counter
 	readDurations prometheus.Observer
@@ -61,37 +59,25 @@ type tableReader struct {
 	readQueue     prometheus.Gauge
 }
 
-// Basic pagination-style query.
-//   - ($1, $2, $3): Start position, nanos, logical key
-//   - ($4, $5): End position nanos, logical
-//   - $6: Row limit
-const readTableTemplate = `
-SELECT nanos, logical, key, mut, before, deletion
-FROM %s
-WHERE (nanos, logical, key) > ($1::INT8, $2::INT8, COALESCE($3::STRING, ''))
-AND (nanos, logical) < ($4::INT8, $5::INT8)
-AND NOT applied
-ORDER BY nanos, logical, key
-LIMIT $6
-`
-
 func newTableReader(
 	bounds *notify.Var[hlc.Range],
 	db *types.StagingPool,
 	fragmentSize int,
+	includeApplied bool,
 	out chan<- *tableCursor,
-	stagingDB ident.Schema,
+	source stager,
 	target ident.Table,
 ) *tableReader {
 	labels := metrics.TableValues(target)
 
 	return &tableReader{
-		bounds:       bounds,
-		db:           db,
-		fragmentSize: fragmentSize,
-		sqlQ:         fmt.Sprintf(readTableTemplate, stagingTable(stagingDB, target)),
-		out:          out,
-		table:        target,
+		bounds:         bounds,
+		db:             db,
+		fragmentSize:   fragmentSize,
+		includeApplied: includeApplied,
+		out:            out,
+		source:         source,
+		table:          target,
 
 		readCount:     stageReadRows.WithLabelValues(labels...),
 		readDurations: stageReadDurations.WithLabelValues(labels...),
@@ -208,9 +194,9 @@ func (r *tableReader) nextCursor(ctx *stopper.Context) *tableCursor {
 		if err != nil {
 			return &tableCursor{Error: err}
 		}
-		// This is a purely defensive check. We don't expect this to
-		// ever happen, since a stub entry would already be marked as
-		// having been applied and should be filtered by the query.
+		// A stub entry is marked as having been applied, so it is
+		// filtered by the query unless applied mutations have been
+		// requested.
 		if bytes.Equal(stubSentinel, mut.Data) {
 			continue
 		}
@@ -225,37 +211,10 @@ func (r *tableReader) nextCursor(ctx *stopper.Context) *tableCursor {
 // queryOnce retrieves a limited number of rows.
 func (r *tableReader) queryOnce(ctx context.Context) ([]types.Mutation, error) {
 	start := time.Now()
-	ret := make([]types.Mutation, 0, r.fragmentSize)
-
-	rows, err := r.db.Query(ctx,
-		r.sqlQ,
-		r.scanBounds.Min().Nanos(),
-		r.scanBounds.Min().Logical(),
-		r.scanKey,
-		r.scanBounds.Max().Nanos(),
-		r.scanBounds.Max().Logical(),
-		r.fragmentSize)
+	ret, err := r.source.readFragment(ctx,
+		r.scanBounds, r.scanKey, r.fragmentSize, r.includeApplied)
 	if err != nil {
-		return nil, errors.Wrap(err, r.sqlQ)
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var mut types.Mutation
-		var nanos int64
-		var logical int
-		var deletion sql.NullBool // Could be migrated.
-		if err := rows.Scan(&nanos, &logical, &mut.Key,
-			&mut.Data, &mut.Before, &deletion); err != nil {
-			return nil, errors.WithStack(err)
-		}
-		mut.Deletion = deletion.Valid && deletion.Bool
-		mut.Time = hlc.New(nanos, logical)
-
-		ret = append(ret, mut)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, errors.WithStack(err)
+		return nil, err
 	}
 	r.readCount.Add(float64(len(ret)))
 	r.readDurations.Observe(time.Since(start).Seconds())
@@ -88,8 +88,9 @@ func TestTableReader(t *testing.T) {
 		bounds,
 		fixture.StagingPool,
 		fragmentSize,
+		false, // includeApplied
 		out,
-		fixture.StagingDB.Schema(),
+		stage,
 		info.Name())
 	ctx.Go(func(ctx *stopper.Context) error {
 		reader.run(ctx)
@@ -88,7 +88,7 @@ func (c *Checker) Check(ctx context.Context) ([]string, error) {
 	err := retry.Retry(ctx, c.StagingPool, func(ctx context.Context) error {
 		warnings = nil
 
-		tx, err := c.StagingPool.Begin(ctx)
+		tx, err := c.StagingPool.BeginStagingTx(ctx)
 		if err != nil {
 			return err
 		}
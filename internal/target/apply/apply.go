@@ -37,6 +37,7 @@ import (
 	"github.com/cockroachdb/replicator/internal/util/metrics"
 	"github.com/cockroachdb/replicator/internal/util/msort"
 	"github.com/cockroachdb/replicator/internal/util/pjson"
+	"github.com/cockroachdb/replicator/internal/util/transform"
 	"github.com/pkg/errors"
 	"github.com/prometheus/client_golang/prometheus"
 	log "github.com/sirupsen/logrus"
@@ -51,17 +52,20 @@ type apply struct {
 	target  *ident.Hinted[ident.Table]
 
 	ages      prometheus.Observer
+	bulkRows  prometheus.Counter
 	conflicts prometheus.Counter
 	deletes   prometheus.Counter
 	durations prometheus.Observer
 	errors    prometheus.Counter
+	filtered  prometheus.Counter
 	resolves  prometheus.Counter
 	upserts   prometheus.Counter
 
 	mu struct {
 		sync.RWMutex
 		bagSpec   *merge.BagSpec
-		gen       int // Use for prepared-statement cache invalidation.
+		gen       int            // Use for prepared-statement cache invalidation.
+		scdSpec   *merge.BagSpec // Used to load the current version in SCD mode.
 		templates *templates
 	}
 }
@@ -98,10 +102,12 @@ func (f *factory) newApply(
 		target:  poolInfo.HintNoFTS(target),
 
 		ages:      applyMutationAge.WithLabelValues(labelValues...),
+		bulkRows:  applyBulkRows.WithLabelValues(labelValues...),
 		conflicts: applyConflicts.WithLabelValues(labelValues...),
 		deletes:   applyDeletes.WithLabelValues(labelValues...),
 		durations: applyDurations.WithLabelValues(labelValues...),
 		errors:    applyErrors.WithLabelValues(labelValues...),
+		filtered:  applyFiltered.WithLabelValues(labelValues...),
 		resolves:  applyResolves.WithLabelValues(labelValues...),
 		upserts:   applyUpserts.WithLabelValues(labelValues...),
 	}
@@ -151,18 +157,6 @@ func (f *factory) newApply(
 func (a *apply) Apply(ctx context.Context, tx types.TargetQuerier, muts []types.Mutation) error {
 	start := time.Now()
 
-	// A frontend may or may not coalesce multiple updates to the same
-	// row together. Thus, it's possible that there are multiple
-	// mutations for the same key present in the input. This is
-	// furthermore compounded by some frontends only providing sparse
-	// updates to rows, rather than complete rows. Instead of pushing
-	// this complexity out to the frontend, we'll solve it here by
-	// folding mutations for the same key together.
-	muts, err := msort.FoldByKey(muts)
-	if err != nil {
-		return err
-	}
-
 	countError := func(err error) error {
 		if err != nil {
 			a.errors.Inc()
@@ -177,6 +171,45 @@ func (a *apply) Apply(ctx context.Context, tx types.TargetQuerier, muts []types.
 		return errors.Errorf("no ColumnData available for %s", a.target)
 	}
 
+	// Discard rows which do not match the filter predicate before
+	// they are folded or versioned.
+	muts, err := a.filterLocked(muts)
+	if err != nil {
+		return countError(err)
+	}
+	if len(muts) == 0 {
+		return nil
+	}
+
+	// In history and SCD modes, every mutation is recorded as its own
+	// version of a row, so we don't want to fold them together.
+	if !a.mu.templates.HistoryOp.Empty() {
+		if err := a.historyLocked(ctx, tx, muts); err != nil {
+			return countError(err)
+		}
+		a.observe(start, muts)
+		return nil
+	}
+	if !a.mu.templates.SCDCurrent.Empty() {
+		if err := a.scdLocked(ctx, tx, muts); err != nil {
+			return countError(err)
+		}
+		a.observe(start, muts)
+		return nil
+	}
+
+	// A frontend may or may not coalesce multiple updates to the same
+	// row together. Thus, it's possible that there are multiple
+	// mutations for the same key present in the input. This is
+	// furthermore compounded by some frontends only providing sparse
+	// updates to rows, rather than complete rows. Instead of pushing
+	// this complexity out to the frontend, we'll solve it here by
+	// folding mutations for the same key together.
+	muts, err = msort.FoldByKey(muts)
+	if err != nil {
+		return err
+	}
+
 	// If the generated SQL doesn't depend on the number of rows being
 	// inserted, we don't need to do any incremental batching.
 	var deletes []types.Mutation
@@ -186,8 +219,9 @@ func (a *apply) Apply(ctx context.Context, tx types.TargetQuerier, muts []types.
 		deletes = make([]types.Mutation, 0, a.mu.templates.RowLimit)
 	}
 
+	// The bulk-load path is also insensitive to the number of rows.
 	var upserts []types.Mutation
-	if a.mu.templates.BulkUpsert {
+	if a.mu.templates.BulkUpsert || a.bulkEnabledLocked(tx, len(muts)) {
 		upserts = make([]types.Mutation, 0, len(muts))
 	} else {
 		upserts = make([]types.Mutation, 0, a.mu.templates.RowLimit)
@@ -236,12 +270,39 @@ func (a *apply) Apply(ctx context.Context, tx types.TargetQuerier, muts []types.
 		return countError(err)
 	}
 
+	a.observe(start, muts)
+	return nil
+}
+
+// filterLocked returns the mutations which satisfy the configured
+// filter predicate. The input slice is not modified.
+func (a *apply) filterLocked(muts []types.Mutation) ([]types.Mutation, error) {
+	f := a.mu.templates.Filter
+	if f == nil {
+		return muts, nil
+	}
+	ret := make([]types.Mutation, 0, len(muts))
+	for _, mut := range muts {
+		next, keep, err := f.Apply(mut)
+		if err != nil {
+			return nil, err
+		}
+		if keep {
+			ret = append(ret, next)
+		}
+	}
+	a.filtered.Add(float64(len(muts) - len(ret)))
+	return ret, nil
+}
+
+// observe records the duration of an Apply call and the age of the
+// mutations that were applied.
+func (a *apply) observe(start time.Time, muts []types.Mutation) {
 	endNanos := time.Now().UnixNano()
 	a.durations.Observe(time.Duration(endNanos - start.UnixNano()).Seconds())
 	for _, mut := range muts {
 		a.ages.Observe(time.Duration(endNanos - mut.Time.Nanos()).Seconds())
 	}
-	return nil
 }
 
 func (a *apply) deleteLocked(
@@ -295,7 +356,21 @@ func (a *apply) deleteLocked(
 					len(keyGroup), len(pkCols),
 					string(muts[i].Key), muts[i].Time)
 			}
+			// The key is in the target's column order, so we can only
+			// apply value transforms to it.
+			for pkIdx, col := range pkCols {
+				if pipeline, ok := a.mu.templates.Transforms.Get(col.Name); ok {
+					var err error
+					keyGroup[pkIdx], err = pipeline.Value(keyGroup[pkIdx])
+					if err != nil {
+						return errors.Wrapf(err, "could not transform key for %s", a.target)
+					}
+				}
+			}
 		} else {
+			if err := transform.ApplyAll(a.mu.templates.Transforms, body); err != nil {
+				return errors.Wrapf(err, "could not transform data for %s", a.target)
+			}
 			keyGroup = make([]any, len(pkCols))
 			for pkIdx, col := range pkCols {
 				colValue, found := body.Get(col.Name)
@@ -379,6 +454,26 @@ func (a *apply) upsertLocked(
 		return err
 	}
 
+	// Apply any declarative transforms before the bags are loaded.
+	for _, bag := range allPayloadData {
+		if err := transform.ApplyAll(a.mu.templates.Transforms, bag); err != nil {
+			return errors.Wrapf(err, "could not transform data for %s", a.target)
+		}
+	}
+
+	// In soft-delete mode, an upsert revives any previously-deleted
+	// row. Setting the values here also ensures that a sparse payload
+	// won't need to load them.
+	if tomb := a.mu.templates.SoftDelete; !tomb.Empty() {
+		tombTime := a.mu.templates.SoftDeleteTime
+		for _, bag := range allPayloadData {
+			bag.Put(tomb, false)
+			if !tombTime.Empty() {
+				bag.Put(tombTime, nil)
+			}
+		}
+	}
+
 	// Load data from sparse payloads. In the ideal case, this call to
 	// Load is a no-op, since all known properties will have valid
 	// values.
@@ -503,6 +598,9 @@ func (a *apply) upsertBagsLocked(
 	if len(bags) == 0 {
 		return nil
 	}
+	if mode == applyConditional && template == "" && a.bulkEnabledLocked(db, len(bags)) {
+		return a.bulkUpsertLocked(ctx, db, bags)
+	}
 	start := time.Now()
 
 	// Converts the property bags into the expected argument layout.
@@ -716,6 +814,10 @@ func (a *apply) refreshUnlocked(configData *applycfg.Config, schemaData []types.
 		Rename:  tmpl.Renames,
 	}
 	a.mu.gen++
+	a.mu.scdSpec = nil
+	if !tmpl.SCDCurrent.Empty() {
+		a.mu.scdSpec = newSCDSpec(tmpl)
+	}
 	a.mu.templates = tmpl
 	return nil
 }
@@ -757,6 +859,51 @@ func (a *apply) validate(configData *applycfg.Config, schemaData []types.ColData
 				"columns are defined to trigger it", a.target)
 		}
 	}
+	if !configData.SoftDeleteTime.Empty() && configData.SoftDelete.Empty() {
+		return errors.Errorf("a soft-delete time column is defined for %s, but "+
+			"no soft-delete column is defined", a.target)
+	}
+	for _, col := range []ident.Ident{configData.SoftDelete, configData.SoftDeleteTime} {
+		if col.Empty() {
+			continue
+		}
+		if _, found := allColNames.Get(col); !found {
+			return errors.Errorf("soft-delete column name %s not found in table %s", col, a.target)
+		}
+	}
+	if err := a.validateSCD(configData, schemaData); err != nil {
+		return err
+	}
+	if configData.HistoryOp.Empty() != configData.HistoryTime.Empty() {
+		return errors.Errorf("history mode for %s requires both an operation "+
+			"and a time column", a.target)
+	}
+	if !configData.HistoryOp.Empty() {
+		if !configData.SoftDelete.Empty() {
+			return errors.Errorf("history mode and soft deletes for %s are "+
+				"mutually exclusive", a.target)
+		}
+		if configData.Merger != nil {
+			return errors.Errorf("history mode for %s does not support "+
+				"merge functions", a.target)
+		}
+		if _, found := allColNames.Get(configData.HistoryOp); !found {
+			return errors.Errorf("history operation column name %s not found in table %s",
+				configData.HistoryOp, a.target)
+		}
+		// Each mutation must produce a distinct row.
+		timeIsPK := false
+		for _, col := range schemaData {
+			if col.Primary && ident.Equal(col.Name, configData.HistoryTime) {
+				timeIsPK = true
+				break
+			}
+		}
+		if !timeIsPK {
+			return errors.Errorf("history time column name %s must be part of "+
+				"the primary key of table %s", configData.HistoryTime, a.target)
+		}
+	}
 
 	// The Ignores field doesn't need validation, since you might want
 	// to mark a column as ignored in order to (eventually) drop it from
@@ -855,7 +1002,7 @@ func toDBTypes(colData []types.ColData, values []any) error {
 // Work items in https://github.com/cockroachdb/replicator/issues/487
 func IsMergeSupported(product types.Product) bool {
 	switch product {
-	case types.ProductCockroachDB, types.ProductPostgreSQL:
+	case types.ProductCockroachDB, types.ProductPostgreSQL, types.ProductSQLServer:
 		return true
 	default:
 		return false
@@ -35,11 +35,14 @@ import (
 	"github.com/cockroachdb/replicator/internal/types"
 	"github.com/cockroachdb/replicator/internal/util/applycfg"
 	"github.com/cockroachdb/replicator/internal/util/batches"
+	"github.com/cockroachdb/replicator/internal/util/filter"
 	"github.com/cockroachdb/replicator/internal/util/hlc"
 	"github.com/cockroachdb/replicator/internal/util/ident"
 	"github.com/cockroachdb/replicator/internal/util/merge"
 	"github.com/cockroachdb/replicator/internal/util/retry"
+	"github.com/cockroachdb/replicator/internal/util/transform"
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -80,6 +83,10 @@ func TestApply(t *testing.T) {
 		// upper-case names that exist within the database.
 		tableSchema = "CREATE TABLE %s (PK0 INT, PK1 VARCHAR2(4000), EXTRAS VARCHAR2(4000), " +
 			"HAS_DEFAULT VARCHAR2(4000) DEFAULT 'Hello' NOT NULL, PRIMARY KEY (PK0,PK1))"
+	case types.ProductSQLServer:
+		// SQL Server limits the size of an index key to 1700 bytes.
+		tableSchema = "CREATE TABLE %s (pk0 INT, pk1 VARCHAR(512), extras VARCHAR(2048), " +
+			"has_default VARCHAR(2048) NOT NULL DEFAULT 'Hello', PRIMARY KEY (pk0,pk1))"
 	default:
 		a.FailNow("untested product")
 	}
@@ -159,6 +166,8 @@ func TestApply(t *testing.T) {
 				a.ErrorContains(err, "1048 (23000)")
 			case types.ProductOracle:
 				a.ErrorContains(err, "ORA-01400: cannot insert NULL into")
+			case types.ProductSQLServer:
+				a.ErrorContains(err, "Cannot insert the value NULL into column")
 			default:
 				a.Fail("unimplemented", err)
 			}
@@ -250,6 +259,8 @@ func TestApply(t *testing.T) {
 			extrasQ = fmt.Sprintf("SELECT extras FROM %s WHERE pk0=? AND pk1=?", tbl.Name())
 		case types.ProductOracle:
 			extrasQ = fmt.Sprintf(`SELECT extras FROM %s WHERE pk0=:p1 AND pk1=:p2`, tbl.Name())
+		case types.ProductSQLServer:
+			extrasQ = fmt.Sprintf(`SELECT extras FROM %s WHERE pk0=@p1 AND pk1=@p2`, tbl.Name())
 		default:
 			a.Fail("unimplemented", fixture.TargetPool.Product)
 		}
@@ -869,6 +880,51 @@ var (
 			expectJSON: `"01C2A76EDD87492EB129F6E9ACD89556"`,
 		},
 	}
+	mssqlDataTypeTests = []dataTypeTestCase{
+		{name: `bigint`, columnType: `BIGINT`, sqlValue: `9223372036854775807`, indexable: true},
+		{name: `bigint_null`, columnType: `BIGINT`},
+		{name: `bit`, sourceType: `BOOL`, columnType: `BIT`, sqlValue: `true`, indexable: true},
+		{name: `bit_null`, sourceType: `BOOL`, columnType: `BIT`},
+		{name: `date`, columnType: `DATE`, sqlValue: `2016-01-25`, indexable: true},
+		{name: `date_null`, columnType: `DATE`},
+		{
+			name:       `datetime2`,
+			sourceType: `TIMESTAMP`,
+			columnType: `DATETIME2(3)`,
+			sqlValue:   `2016-01-25 10:10:10.123`,
+			expectJSON: `"2016-01-25T10:10:10.123"`,
+			indexable:  true,
+		},
+		{name: `datetime2_null`, sourceType: `TIMESTAMP`, columnType: `DATETIME2(3)`},
+		{name: `decimal`, columnType: `DECIMAL(6,3)`, sqlValue: `123.456`, indexable: true},
+		{name: `decimal_null`, columnType: `DECIMAL(6,3)`},
+		{name: `float`, sourceType: `FLOAT8`, columnType: `FLOAT`, sqlValue: `1.5`, indexable: true},
+		{name: `float_null`, sourceType: `FLOAT8`, columnType: `FLOAT`},
+		{name: `int`, columnType: `INT`, sqlValue: `12345`, indexable: true},
+		{name: `int_null`, columnType: `INT`},
+		{name: `nvarchar`, sourceType: `STRING`, columnType: `NVARCHAR(256)`, sqlValue: `a1b2c3`, indexable: true},
+		{name: `nvarchar_null`, sourceType: `STRING`, columnType: `NVARCHAR(256)`},
+		{name: `nvarchar_escape`, sourceType: `STRING`, columnType: `NVARCHAR(256)`, sqlValue: `a1\b/2?c"3`, indexable: true},
+		{
+			name:       `uniqueidentifier`,
+			sourceType: `UUID`,
+			columnType: `UNIQUEIDENTIFIER`,
+			sqlValue:   `e7b9f5ca-1c9e-4b3d-a4c2-1f1c8e9f0a55`,
+			expectJSON: `"E7B9F5CA-1C9E-4B3D-A4C2-1F1C8E9F0A55"`,
+			indexable:  true,
+		},
+		{name: `uniqueidentifier_null`, sourceType: `UUID`, columnType: `UNIQUEIDENTIFIER`},
+		{
+			// FOR JSON emits binary data as base64.
+			name:       `varbinary`,
+			sourceType: `BYTES`,
+			columnType: `VARBINARY(256)`,
+			sqlValue:   `a1b2c3`,
+			expectJSON: `"YTFiMmMz"`,
+			indexable:  true,
+		},
+		{name: `varbinary_null`, sourceType: `BYTES`, columnType: `VARBINARY(256)`},
+	}
 
 	pgDataTypeTests = []dataTypeTestCase{
 		{name: `string_array`, columnType: `STRING[]`, sqlValue: `{"sky","road","car"}`},
@@ -1026,6 +1082,7 @@ var (
 func TestAllDataTypes(t *testing.T) {
 
 	var expectArrayWrapper bool
+	var expectObjectWrapper bool
 	var readBackQ string
 	var testcases []dataTypeTestCase
 	{
@@ -1053,6 +1110,11 @@ func TestAllDataTypes(t *testing.T) {
 			// JSON_QUERY in older versions refuses to return raw scalars.
 			readBackQ = "SELECT JSON_QUERY(JSON_ARRAY(val NULL ON NULL), '$[0]' WITH ARRAY WRAPPER) FROM %s"
 			expectArrayWrapper = true
+		case types.ProductSQLServer:
+			testcases = mssqlDataTypeTests
+			// FOR JSON only emits objects, so we'll unwrap the value.
+			readBackQ = "SELECT (SELECT val FOR JSON PATH, WITHOUT_ARRAY_WRAPPER, INCLUDE_NULL_VALUES) FROM %s"
+			expectObjectWrapper = true
 		case types.ProductPostgreSQL:
 			// timestamptz PG specific
 			testcases = append(pgDataTypeTests,
@@ -1169,6 +1231,13 @@ func TestAllDataTypes(t *testing.T) {
 				if expectArrayWrapper {
 					readBack = readBack[1 : len(readBack)-1]
 				}
+				if expectObjectWrapper {
+					var wrapper struct {
+						Val json.RawMessage `json:"val"`
+					}
+					r.NoError(json.Unmarshal([]byte(readBack), &wrapper))
+					readBack = string(wrapper.Val)
+				}
 			}
 			readBack, err = normalizeJSON(readBack)
 			r.NoError(err)
@@ -1233,6 +1302,8 @@ func testConditions(t *testing.T, cas, deadline bool) {
 		createStatement = "CREATE TABLE %s (pk INT PRIMARY KEY, ver INT, ts TIMESTAMP WITH TIME ZONE)"
 	case types.ProductMariaDB, types.ProductMySQL:
 		createStatement = "CREATE TABLE %s (pk INT PRIMARY KEY, ver INT, ts TIMESTAMP)"
+	case types.ProductSQLServer:
+		createStatement = "CREATE TABLE %s (pk INT PRIMARY KEY, ver INT, ts DATETIMEOFFSET)"
 	default:
 		a.FailNow("product not supported")
 	}
@@ -1302,6 +1373,8 @@ func testConditions(t *testing.T, cas, deadline bool) {
 			return
 		case types.ProductOracle:
 			q = "SELECT ver, ts FROM %s WHERE pk = :p1"
+		case types.ProductSQLServer:
+			q = "SELECT ver, ts FROM %s WHERE pk = @p1"
 		default:
 			return 0, time.Time{}, errors.New("unimplemented product")
 		}
@@ -1540,6 +1613,8 @@ func TestMergeWiring(t *testing.T) {
 		createStatement = "CREATE TABLE %s (pk INT PRIMARY KEY, val INT, updated_at TIMESTAMP WITH TIME ZONE)"
 	case types.ProductMariaDB, types.ProductMySQL:
 		createStatement = "CREATE TABLE %s (pk INT PRIMARY KEY, val INT, updated_at TIMESTAMP)"
+	case types.ProductSQLServer:
+		createStatement = "CREATE TABLE %s (pk INT PRIMARY KEY, val INT, updated_at DATETIMEOFFSET)"
 	default:
 		r.FailNow("product not supported")
 	}
@@ -1880,6 +1955,10 @@ func TestRepeatedKeysWithIgnoredColumns(t *testing.T) {
 		tblSchema = "CREATE TABLE %s (pk0 INT PRIMARY KEY, " +
 			"ignored INT GENERATED ALWAYS AS (1) VIRTUAL, " +
 			"val VARCHAR(2048))"
+	case types.ProductSQLServer:
+		tblSchema = "CREATE TABLE %s (pk0 INT PRIMARY KEY, " +
+			"ignored AS (1), " +
+			"val VARCHAR(2048))"
 	default:
 		t.Fatalf("unimplemented: %s", fixture.TargetPool.Product)
 	}
@@ -1931,6 +2010,8 @@ func TestRepeatedKeysWithIgnoredColumns(t *testing.T) {
 			q = "SELECT val FROM %s WHERE pk0 = ?"
 		case types.ProductOracle:
 			q = "SELECT val FROM %s WHERE pk0 = :pk"
+		case types.ProductSQLServer:
+			q = "SELECT val FROM %s WHERE pk0 = @p1"
 		default:
 			a.Fail("unimplemented product")
 		}
@@ -2143,6 +2224,8 @@ func TestVirtualColumns(t *testing.T) {
 		// is not supported. Handle them separately.
 		testVirtualColumnsMySQL(t, fixture)
 		return
+	case types.ProductSQLServer:
+		t.Skip("SQL Server requires computed primary key columns to be persisted")
 	default:
 		// Keep going for the rest of the products.
 	}
@@ -2406,3 +2489,394 @@ func coerceToInt(r *require.Assertions, x any) int {
 		return 0
 	}
 }
+
+// This tests the soft-delete configuration, where a deletion sets a
+// tombstone column instead of removing the row.
+func TestSoftDelete(t *testing.T) {
+	r := require.New(t)
+
+	fixture, err := all.NewFixture(t)
+	r.NoError(err)
+
+	// Not all products have a boolean type.
+	tombType, timeType := "BOOLEAN NOT NULL DEFAULT false", "TIMESTAMP"
+	isDeleted, notDeleted := "deleted", "NOT deleted"
+	switch fixture.TargetPool.Product {
+	case types.ProductOracle:
+		tombType = "NUMBER(1) DEFAULT 0 NOT NULL"
+		isDeleted, notDeleted = "deleted = 1", "deleted = 0"
+	case types.ProductSQLServer:
+		tombType, timeType = "BIT NOT NULL DEFAULT 0", "DATETIME2"
+		isDeleted, notDeleted = "deleted = 1", "deleted = 0"
+	default:
+	}
+
+	ctx := fixture.Context
+	tbl, err := fixture.CreateTargetTable(ctx, fmt.Sprintf(
+		"CREATE TABLE %%s (pk INT PRIMARY KEY, val VARCHAR(2048), "+
+			"deleted %s, deleted_at %s)", tombType, timeType))
+	r.NoError(err)
+
+	configData := applycfg.NewConfig()
+	configData.SoftDelete = ident.New("deleted")
+	configData.SoftDeleteTime = ident.New("deleted_at")
+	r.NoError(fixture.Configs.Set(tbl.Name(), configData))
+
+	apply := fixture.Applier(ctx, tbl.Name())
+	count := func(predicate string) int {
+		ct, err := base.GetRowCountWithPredicate(ctx, fixture.TargetPool, tbl.Name(), predicate)
+		r.NoError(err)
+		return ct
+	}
+
+	upsert := types.Mutation{
+		Data: json.RawMessage(`{"pk":1,"val":"hello"}`),
+		Key:  json.RawMessage(`[1]`),
+	}
+	r.NoError(apply([]types.Mutation{upsert}))
+	r.Equal(1, count(notDeleted+" AND deleted_at IS NULL"))
+
+	// The row should still exist, but be marked as deleted.
+	r.NoError(apply([]types.Mutation{{
+		Deletion: true,
+		Key:      json.RawMessage(`[1]`),
+	}}))
+	r.Equal(1, count(isDeleted+" AND deleted_at IS NOT NULL"))
+
+	// Upserting the row should revive it.
+	r.NoError(apply([]types.Mutation{upsert}))
+	r.Equal(1, count(notDeleted+" AND deleted_at IS NULL"))
+}
+
+// This tests the append-only history mode, where every mutation is
+// inserted as a new row.
+func TestHistory(t *testing.T) {
+	r := require.New(t)
+
+	fixture, err := all.NewFixture(t)
+	r.NoError(err)
+
+	// The precision of the timestamp column is specified, since MySQL
+	// defaults to a scale of zero.
+	ctx := fixture.Context
+	tbl, err := fixture.CreateTargetTable(ctx,
+		"CREATE TABLE %s (pk INT, ts DECIMAL(30,10), op VARCHAR(16), val VARCHAR(2048), "+
+			"PRIMARY KEY (pk, ts))")
+	r.NoError(err)
+
+	configData := applycfg.NewConfig()
+	configData.HistoryOp = ident.New("op")
+	configData.HistoryTime = ident.New("ts")
+	r.NoError(fixture.Configs.Set(tbl.Name(), configData))
+
+	apply := fixture.Applier(ctx, tbl.Name())
+	count := func(predicate string) int {
+		ct, err := base.GetRowCountWithPredicate(ctx, fixture.TargetPool, tbl.Name(), predicate)
+		r.NoError(err)
+		return ct
+	}
+
+	// Multiple mutations to the same key must not be folded together.
+	muts := []types.Mutation{
+		{
+			Data: json.RawMessage(`{"pk":1,"val":"one"}`),
+			Key:  json.RawMessage(`[1]`),
+			Time: hlc.New(1, 0),
+		},
+		{
+			Data: json.RawMessage(`{"pk":1,"val":"two"}`),
+			Key:  json.RawMessage(`[1]`),
+			Time: hlc.New(2, 0),
+		},
+		{
+			Deletion: true,
+			Key:      json.RawMessage(`[1]`),
+			Time:     hlc.New(3, 0),
+		},
+	}
+	r.NoError(apply(muts))
+	r.Equal(2, count("pk = 1 AND op = 'upsert'"))
+	r.Equal(1, count("pk = 1 AND op = 'delete' AND val IS NULL"))
+
+	// Redelivery should be idempotent.
+	r.NoError(apply(muts))
+	ct, err := tbl.RowCount(ctx)
+	r.NoError(err)
+	r.Equal(len(muts), ct)
+}
+
+// This tests the SCD Type 2 mode, where updates close the current
+// version of a row and insert a new one.
+func TestSCD(t *testing.T) {
+	r := require.New(t)
+
+	fixture, err := all.NewFixture(t)
+	r.NoError(err)
+
+	switch fixture.TargetPool.Product {
+	case types.ProductCockroachDB, types.ProductPostgreSQL:
+	default:
+		t.Skip("test uses a BOOLEAN column")
+	}
+
+	ctx := fixture.Context
+	tbl, err := fixture.CreateTargetTable(ctx,
+		"CREATE TABLE %s (pk INT, valid_from DECIMAL, valid_to DECIMAL, "+
+			"is_current BOOLEAN, val VARCHAR(2048), PRIMARY KEY (pk, valid_from))")
+	r.NoError(err)
+
+	configData := applycfg.NewConfig()
+	configData.SCDCurrent = ident.New("is_current")
+	configData.SCDValidFrom = ident.New("valid_from")
+	configData.SCDValidTo = ident.New("valid_to")
+	r.NoError(fixture.Configs.Set(tbl.Name(), configData))
+
+	apply := fixture.Applier(ctx, tbl.Name())
+	count := func(predicate string) int {
+		ct, err := base.GetRowCountWithPredicate(ctx, fixture.TargetPool, tbl.Name(), predicate)
+		r.NoError(err)
+		return ct
+	}
+
+	// Multiple versions of a row within a batch are all retained.
+	muts := []types.Mutation{
+		{
+			Data: json.RawMessage(`{"pk":1,"val":"two"}`),
+			Key:  json.RawMessage(`[1]`),
+			Time: hlc.New(2, 0),
+		},
+		{
+			Data: json.RawMessage(`{"pk":1,"val":"one"}`),
+			Key:  json.RawMessage(`[1]`),
+			Time: hlc.New(1, 0),
+		},
+	}
+	r.NoError(apply(muts))
+	r.Equal(1, count("is_current AND val = 'two' AND valid_to IS NULL"))
+	r.Equal(1, count("NOT is_current AND val = 'one' AND valid_to IS NOT NULL"))
+
+	// Redelivery should be idempotent.
+	r.NoError(apply(muts))
+	ct, err := tbl.RowCount(ctx)
+	r.NoError(err)
+	r.Equal(2, ct)
+
+	// A sparse update inherits values from the current version.
+	r.NoError(apply([]types.Mutation{{
+		Data: json.RawMessage(`{"pk":1}`),
+		Key:  json.RawMessage(`[1]`),
+		Time: hlc.New(3, 0),
+	}}))
+	r.Equal(1, count("is_current AND val = 'two'"))
+	r.Equal(3, count("pk = 1"))
+
+	// A deletion closes the current version.
+	r.NoError(apply([]types.Mutation{{
+		Deletion: true,
+		Key:      json.RawMessage(`[1]`),
+		Time:     hlc.New(4, 0),
+	}}))
+	r.Zero(count("is_current"))
+	r.Equal(3, count("valid_to IS NOT NULL"))
+}
+
+// This tests declarative column transforms.
+func TestTransforms(t *testing.T) {
+	r := require.New(t)
+
+	fixture, err := all.NewFixture(t)
+	r.NoError(err)
+
+	ctx := fixture.Context
+	tbl, err := fixture.CreateTargetTable(ctx,
+		"CREATE TABLE %s (pk INT PRIMARY KEY, email VARCHAR(2048), phone VARCHAR(2048))")
+	r.NoError(err)
+
+	configData := applycfg.NewConfig()
+	configData.Transforms.Put(ident.New("email"), transform.Pipeline{
+		{Kind: transform.KindReplace, Pattern: `@.*$`, Replacement: "@example.com"},
+	})
+	configData.Transforms.Put(ident.New("mobile"), transform.Pipeline{
+		{Kind: transform.KindMask, Keep: 4},
+		{Kind: transform.KindRename, To: "phone"},
+	})
+	r.NoError(fixture.Configs.Set(tbl.Name(), configData))
+
+	apply := fixture.Applier(ctx, tbl.Name())
+	r.NoError(apply([]types.Mutation{{
+		Data: json.RawMessage(`{"pk":1,"email":"bob@cockroachlabs.com","mobile":"555-1234"}`),
+		Key:  json.RawMessage(`[1]`),
+	}}))
+
+	ct, err := base.GetRowCountWithPredicate(ctx, fixture.TargetPool, tbl.Name(),
+		"email = 'bob@example.com' AND phone = '****1234'")
+	r.NoError(err)
+	r.Equal(1, ct)
+}
+
+// This tests row-level filter predicates.
+func TestFilter(t *testing.T) {
+	r := require.New(t)
+
+	fixture, err := all.NewFixture(t)
+	r.NoError(err)
+
+	ctx := fixture.Context
+	tbl, err := fixture.CreateTargetTable(ctx,
+		"CREATE TABLE %s (pk INT PRIMARY KEY, region VARCHAR(2048))")
+	r.NoError(err)
+
+	pred, err := filter.Parse("region = 'us'")
+	r.NoError(err)
+	configData := applycfg.NewConfig()
+	configData.Filter = pred
+	r.NoError(fixture.Configs.Set(tbl.Name(), configData))
+
+	apply := fixture.Applier(ctx, tbl.Name())
+	count := func() int {
+		ct, err := base.GetRowCount(ctx, fixture.TargetPool, tbl.Name())
+		r.NoError(err)
+		return ct
+	}
+
+	r.NoError(apply([]types.Mutation{
+		{
+			Data: json.RawMessage(`{"pk":1,"region":"us"}`),
+			Key:  json.RawMessage(`[1]`),
+		},
+		{
+			Data: json.RawMessage(`{"pk":2,"region":"eu"}`),
+			Key:  json.RawMessage(`[2]`),
+		},
+	}))
+	r.Equal(1, count())
+
+	// Moving the row out of the filtered set should delete it.
+	r.NoError(apply([]types.Mutation{{
+		Before: json.RawMessage(`{"pk":1,"region":"us"}`),
+		Data:   json.RawMessage(`{"pk":1,"region":"eu"}`),
+		Key:    json.RawMessage(`[1]`),
+	}}))
+	r.Equal(0, count())
+}
+
+// This tests the bulk-load path, which loads large batches using COPY
+// or LOAD DATA. The bulk path requires a transaction that is bound to
+// a single connection.
+func TestBulk(t *testing.T) {
+	r := require.New(t)
+
+	fixture, err := all.NewFixture(t)
+	r.NoError(err)
+
+	ctx := fixture.Context
+	switch fixture.TargetPool.Product {
+	case types.ProductMariaDB, types.ProductMySQL:
+		// LOAD DATA LOCAL is disabled by default.
+		_, err := fixture.TargetPool.ExecContext(ctx, "SET GLOBAL local_infile = 1")
+		r.NoError(err)
+	default:
+	}
+
+	tbl, err := fixture.CreateTargetTable(ctx,
+		"CREATE TABLE %s (pk INT PRIMARY KEY, val VARCHAR(2048))")
+	r.NoError(err)
+
+	const threshold = 10
+	configData := applycfg.NewConfig()
+	configData.BulkThreshold = threshold
+	r.NoError(fixture.Configs.Set(tbl.Name(), configData))
+
+	// Oracle templates already use array binding, so they do not have
+	// a bulk-load path.
+	wantBulk := fixture.TargetPool.Product != types.ProductOracle
+
+	apply := func(muts []types.Mutation) {
+		tx, err := fixture.TargetPool.BeginConnTx(ctx, nil)
+		r.NoError(err)
+		defer func() { _ = tx.Rollback() }()
+		r.NoError(fixture.ApplyAcceptor.AcceptTableBatch(ctx,
+			sinktest.TableBatchOf(tbl.Name(), hlc.Zero(), muts),
+			&types.AcceptOptions{TargetQuerier: tx}))
+		r.NoError(tx.Commit())
+	}
+	count := func(predicate string) int {
+		ct, err := base.GetRowCountWithPredicate(ctx, fixture.TargetPool, tbl.Name(), predicate)
+		r.NoError(err)
+		return ct
+	}
+
+	// The values include characters which must be escaped by LOAD DATA.
+	const rowCount = 100
+	muts := make([]types.Mutation, rowCount)
+	for i := range muts {
+		data := fmt.Sprintf(`{"pk":%d,"val":"row\t%d\n\\"}`, i, i)
+		if i == 0 {
+			data = `{"pk":0,"val":null}`
+		}
+		muts[i] = types.Mutation{
+			Data: json.RawMessage(data),
+			Key:  json.RawMessage(fmt.Sprintf(`[%d]`, i)),
+		}
+	}
+
+	before := bulkRows(t, tbl.Name())
+	apply(muts)
+	r.Equal(rowCount, count("1 = 1"))
+	r.Equal(1, count("val IS NULL"))
+	var val string
+	r.NoError(fixture.TargetPool.QueryRowContext(ctx,
+		fmt.Sprintf("SELECT val FROM %s WHERE pk = 42", tbl.Name())).Scan(&val))
+	r.Equal("row\t42\n\\", val)
+	if wantBulk {
+		r.Equal(float64(rowCount), bulkRows(t, tbl.Name())-before)
+	} else {
+		r.Equal(before, bulkRows(t, tbl.Name()))
+	}
+
+	// Re-applying the batch updates the existing rows.
+	for i := range muts {
+		muts[i].Data = json.RawMessage(fmt.Sprintf(`{"pk":%d,"val":"updated"}`, i))
+	}
+	before = bulkRows(t, tbl.Name())
+	apply(muts)
+	r.Equal(rowCount, count("val = 'updated'"))
+	if wantBulk {
+		r.Equal(float64(rowCount), bulkRows(t, tbl.Name())-before)
+	}
+
+	// Batches below the threshold use the templated statements.
+	before = bulkRows(t, tbl.Name())
+	apply(muts[:threshold-1])
+	r.Equal(before, bulkRows(t, tbl.Name()))
+}
+
+// bulkRows returns the number of rows that have been bulk-loaded into
+// the table.
+func bulkRows(t *testing.T, table ident.Table) float64 {
+	t.Helper()
+	families, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() != "apply_bulk_rows_total" {
+			continue
+		}
+	nextMetric:
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				switch label.GetName() {
+				case "schema":
+					if label.GetValue() != table.Schema().Raw() {
+						continue nextMetric
+					}
+				case "table":
+					if label.GetValue() != table.Table().Raw() {
+						continue nextMetric
+					}
+				}
+			}
+			return metric.GetCounter().GetValue()
+		}
+	}
+	return 0
+}
@@ -0,0 +1,388 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package apply
+
+// This file contains the bulk-load path for large batches of upserts.
+// The proposed rows are streamed into a temporary staging table, using
+// the product's bulk-transfer protocol, and are then merged into the
+// target table with a single statement. The merge statement honors
+// the compare-and-set and deadline configuration, so it produces the
+// same result as the templated statements.
+//
+// Oracle targets do not use this path, since their templates already
+// transfer all rows in a single statement by using array binding.
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/cockroachdb/replicator/internal/util/merge"
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// bulkCounter is used to assign unique names to staging tables.
+var bulkCounter atomic.Uint64
+
+// bulkEnabledLocked returns true if the given number of upserts should
+// be applied using the bulk-load path.
+func (a *apply) bulkEnabledLocked(db types.TargetQuerier, count int) bool {
+	t := a.mu.templates
+	if t.bulk == nil || t.BulkThreshold <= 0 || count < t.BulkThreshold {
+		return false
+	}
+	// A merge function requires the conflicting rows to be returned
+	// and user-provided expressions may refer to the substitution
+	// parameters in arbitrary ways.
+	if t.Merger != nil || t.Exprs.Len() > 0 {
+		return false
+	}
+	// The staging table is session-scoped, so all statements must be
+	// executed on the same connection.
+	switch db.(type) {
+	case types.TargetConn:
+		return true
+	case *sql.Tx:
+		// LOAD DATA is an ordinary statement, but COPY requires access
+		// to the driver connection.
+		return a.product == types.ProductMariaDB || a.product == types.ProductMySQL
+	default:
+		return false
+	}
+}
+
+// bulkUpsertLocked loads the bags into a staging table and then merges
+// the staged rows into the target table.
+func (a *apply) bulkUpsertLocked(
+	ctx context.Context, db types.TargetQuerier, bags []*merge.Bag,
+) (ret error) {
+	start := time.Now()
+	allArgs, err := a.upsertArgsLocked(bags)
+	if err != nil {
+		return err
+	}
+	rows := make([][]any, len(bags))
+	width := a.mu.templates.UpsertParameterCount
+	for i := range rows {
+		rows[i] = allArgs[i*width : (i+1)*width]
+	}
+
+	stage := a.bulkStageTable()
+	create, err := a.mu.templates.bulkStageExpr(stage.String())
+	if err != nil {
+		return err
+	}
+	if a.product == types.ProductCockroachDB {
+		if _, err := db.ExecContext(ctx, "SET experimental_enable_temp_tables = 'on'"); err != nil {
+			return errors.WithStack(err)
+		}
+	}
+	if _, err := db.ExecContext(ctx, create); err != nil {
+		return errors.Wrap(err, create)
+	}
+	// If an error occurs, the PostgreSQL transaction will have been
+	// aborted and the rollback will discard the staging table. MySQL
+	// temporary tables are not transactional, so we always try to
+	// clean up.
+	defer func() {
+		var drop string
+		switch a.product {
+		case types.ProductMariaDB, types.ProductMySQL:
+			drop = fmt.Sprintf("DROP TEMPORARY TABLE IF EXISTS %s", stage)
+		default:
+			drop = fmt.Sprintf("DROP TABLE IF EXISTS %s", stage)
+		}
+		if _, err := db.ExecContext(ctx, drop); err != nil && ret == nil {
+			ret = errors.WithStack(err)
+		}
+	}()
+
+	switch a.product {
+	case types.ProductCockroachDB, types.ProductPostgreSQL:
+		err = bulkCopy(ctx, db, stage, width, rows)
+	case types.ProductMariaDB, types.ProductMySQL:
+		err = bulkLoadData(ctx, db, stage, width, rows)
+	default:
+		err = errors.Errorf("bulk loading not supported for %s", a.product)
+	}
+	if err != nil {
+		return err
+	}
+
+	q, err := a.mu.templates.bulkExpr(stage.String())
+	if err != nil {
+		return err
+	}
+	tag, err := db.ExecContext(ctx, q)
+	if err != nil {
+		return errors.Wrap(err, q)
+	}
+	upserted, err := tag.RowsAffected()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	// See discussion in upsertBagsLocked.
+	conflicts := max(int64(len(bags))-upserted, 0)
+	a.bulkRows.Add(float64(len(bags)))
+	a.conflicts.Add(float64(conflicts))
+	a.upserts.Add(float64(upserted))
+	log.WithFields(log.Fields{
+		"conflicts": conflicts,
+		"duration":  time.Since(start),
+		"proposed":  len(bags),
+		"target":    a.target,
+		"upserted":  upserted,
+	}).Debug("bulk upserted rows")
+	return nil
+}
+
+// bulkStageTable returns a unique name for a staging table.
+func (a *apply) bulkStageTable() ident.Table {
+	name := ident.New(fmt.Sprintf("replicator_bulk_%d", bulkCounter.Add(1)))
+	switch a.product {
+	case types.ProductCockroachDB:
+		// Temporary tables are created within the target database.
+		db := a.target.Base.Schema().Idents(nil)[0]
+		return ident.NewTable(ident.MustSchema(db, ident.New("pg_temp")), name)
+	case types.ProductPostgreSQL:
+		return ident.NewTable(ident.MustSchema(ident.New("pg_temp")), name)
+	default:
+		return ident.NewTable(a.target.Base.Schema(), name)
+	}
+}
+
+// bulkStageColumn returns the name of the staging column that holds
+// the value for the given 1-based substitution parameter.
+func bulkStageColumn(param int) string {
+	return fmt.Sprintf("p%d", param)
+}
+
+// bulkStageExpr returns a statement that creates a staging table. The
+// table has one column for each substitution parameter used by the
+// upsert templates.
+func (t *templates) bulkStageExpr(source string) (string, error) {
+	cpy := *t
+	cpy.RowCount = 1
+	vars, err := cpy.Vars()
+	if err != nil {
+		return "", err
+	}
+
+	colTypes := make([]string, t.UpsertParameterCount)
+	for _, pair := range vars[0] {
+		if pair.Param == 0 {
+			return "", errors.Errorf("column %s has no substitution parameter", pair.Column.Name)
+		}
+		colTypes[pair.Param-1] = bulkStageType(t.Product, pair.Column)
+		if pair.ValidityParam != 0 {
+			colTypes[pair.ValidityParam-1] = "INT"
+		}
+	}
+
+	var buf strings.Builder
+	switch t.Product {
+	case types.ProductMariaDB, types.ProductMySQL:
+		buf.WriteString("CREATE TEMPORARY TABLE ")
+	default:
+		buf.WriteString("CREATE TEMP TABLE ")
+	}
+	buf.WriteString(source)
+	buf.WriteString(" (")
+	for idx, colType := range colTypes {
+		if colType == "" {
+			return "", errors.Errorf("no column for substitution parameter %d", idx+1)
+		}
+		if idx > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(bulkStageColumn(idx + 1))
+		buf.WriteString(" ")
+		buf.WriteString(colType)
+	}
+	buf.WriteString(")")
+	if t.Product == types.ProductPostgreSQL {
+		buf.WriteString(" ON COMMIT DROP")
+	}
+	return buf.String(), nil
+}
+
+// bulkStageType returns the type of the staging column for the target
+// column. The conversions here must agree with the bulk.tmpl files.
+func bulkStageType(product types.Product, col types.ColData) string {
+	switch product {
+	case types.ProductCockroachDB, types.ProductPostgreSQL:
+		switch {
+		case strings.HasSuffix(col.Type, "[]") && strings.Contains(col.Type, "."):
+			// See isUDTArray.
+			return "TEXT[]"
+		case col.Type == "GEOGRAPHY", col.Type == "GEOMETRY":
+			return "JSONB"
+		default:
+			return col.Type
+		}
+	case types.ProductMariaDB, types.ProductMySQL:
+		// Values are loaded as text and are converted when they are
+		// inserted into the target table.
+		switch col.Type {
+		case "binary", "blob", "longblob", "mediumblob", "tinyblob", "varbinary":
+			return "LONGBLOB"
+		case "bit":
+			return "BIGINT UNSIGNED"
+		default:
+			return "LONGTEXT"
+		}
+	default:
+		return col.Type
+	}
+}
+
+// bulkCopy uses the COPY protocol to load the rows into the staging
+// table.
+func bulkCopy(
+	ctx context.Context, db types.TargetQuerier, stage ident.Table, width int, rows [][]any,
+) error {
+	conn, ok := db.(types.TargetConn)
+	if !ok {
+		return errors.Errorf("COPY requires a %T, got %T", conn, db)
+	}
+	var tableName pgx.Identifier
+	for _, part := range stage.Schema().Idents(nil) {
+		tableName = append(tableName, part.Raw())
+	}
+	tableName = append(tableName, stage.Table().Raw())
+
+	colNames := make([]string, width)
+	for i := range colNames {
+		colNames[i] = bulkStageColumn(i + 1)
+	}
+
+	return conn.Raw(func(driverConn any) error {
+		pgConn, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return errors.Errorf("COPY requires a pgx connection, got %T", driverConn)
+		}
+		count, err := pgConn.Conn().CopyFrom(ctx, tableName, colNames, pgx.CopyFromRows(rows))
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		if count != int64(len(rows)) {
+			return errors.Errorf("copied %d rows, expected %d", count, len(rows))
+		}
+		return nil
+	})
+}
+
+// bulkLoadData uses LOAD DATA LOCAL INFILE to load the rows into the
+// staging table. The server must have the local_infile variable set.
+func bulkLoadData(
+	ctx context.Context, db types.TargetQuerier, stage ident.Table, width int, rows [][]any,
+) error {
+	var buf bytes.Buffer
+	for _, row := range rows {
+		for idx, value := range row {
+			if idx > 0 {
+				buf.WriteByte('\t')
+			}
+			if err := writeLoadDataValue(&buf, value); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('\n')
+	}
+
+	// The reader handler is a process-wide registration, so it must
+	// have a unique name.
+	handler := stage.Table().Raw()
+	mysql.RegisterReaderHandler(handler, func() io.Reader { return &buf })
+	defer mysql.DeregisterReaderHandler(handler)
+
+	colNames := make([]string, width)
+	for i := range colNames {
+		colNames[i] = bulkStageColumn(i + 1)
+	}
+	q := fmt.Sprintf("LOAD DATA LOCAL INFILE 'Reader::%s' INTO TABLE %s "+
+		"CHARACTER SET binary "+
+		`FIELDS TERMINATED BY '\t' ESCAPED BY '\\' `+
+		`LINES TERMINATED BY '\n' (%s)`,
+		handler, stage, strings.Join(colNames, ","))
+	tag, err := db.ExecContext(ctx, q)
+	if err != nil {
+		return errors.Wrap(err, q)
+	}
+	count, err := tag.RowsAffected()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if count != int64(len(rows)) {
+		return errors.Errorf("loaded %d rows, expected %d", count, len(rows))
+	}
+	return nil
+}
+
+// loadDataEscaper escapes the characters that have special meaning in
+// the LOAD DATA format used by bulkLoadData.
+var loadDataEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"\x00", `\0`,
+	"\t", `\t`,
+	"\n", `\n`,
+	"\r", `\r`,
+)
+
+// writeLoadDataValue appends a single field value to the buffer.
+func writeLoadDataValue(buf *bytes.Buffer, value any) error {
+	switch t := value.(type) {
+	case nil:
+		buf.WriteString(`\N`)
+	case string:
+		_, _ = loadDataEscaper.WriteString(buf, t)
+	case []byte:
+		_, _ = loadDataEscaper.WriteString(buf, string(t))
+	case bool:
+		if t {
+			buf.WriteByte('1')
+		} else {
+			buf.WriteByte('0')
+		}
+	case int:
+		buf.WriteString(strconv.Itoa(t))
+	case int64:
+		buf.WriteString(strconv.FormatInt(t, 10))
+	case float64:
+		buf.WriteString(strconv.FormatFloat(t, 'g', -1, 64))
+	default:
+		data, err := json.Marshal(t)
+		if err != nil {
+			return errors.Wrapf(err, "could not encode %T for LOAD DATA", t)
+		}
+		_, _ = loadDataEscaper.WriteString(buf, string(data))
+	}
+	return nil
+}
@@ -22,11 +22,17 @@ import (
 
 	"github.com/cockroachdb/replicator/internal/types"
 	"github.com/cockroachdb/replicator/internal/util/applycfg"
+	"github.com/cockroachdb/replicator/internal/util/filter"
 	"github.com/cockroachdb/replicator/internal/util/ident"
 	"github.com/cockroachdb/replicator/internal/util/merge"
+	"github.com/cockroachdb/replicator/internal/util/transform"
 	"github.com/pkg/errors"
 )
 
+// sqlServerMaxParameters is the maximum number of parameters that may
+// be passed to a single SQL Server statement.
+const sqlServerMaxParameters = 2100
+
 // A columnMapping is used to resolve payload keys to database columns
 // that we intend to operate on. For instance, a CockroachDB changefeed
 // generally uses lower-case keys in the JSON payloads, while a target
@@ -35,23 +41,33 @@ import (
 // The columnMapping also contains data about the target schema that we
 // want to memoize.
 type columnMapping struct {
-	Conditions           []types.ColData              // The version-like fields for CAS ops.
-	Columns              []types.ColData              // All columns named in an upsert statement.
-	Data                 []types.ColData              // Non-PK, non-ignored columns.
-	Deadlines            types.Deadlines              // Allow too-old data to just be dropped.
-	DeleteParameterCount int                          // The number of SQL arguments.
-	Exprs                *ident.Map[string]           // Value-replacement expressions.
-	ExtrasColIdx         int                          // Position of the extras column, or -1 if unconfigured.
-	Ignore               ident.Idents                 // Named columns to ignore in the input.
-	Merger               merge.Merger                 // Conflict-resolution callback.
-	Positions            *ident.Map[positionalColumn] // Map of idents to column info and position.
-	Product              types.Product                // Target database product.
-	PK                   []types.ColData              // The names of the PK columns.
-	PKDelete             []types.ColData              // The names of the PK columns to delete.
-	Renames              *ident.Map[ident.Ident]      // External (source) names to target names.
-	RowLimit             int                          // Limits number of generated bind variables.
-	TableName            *ident.Hinted[ident.Table]   // The target table.
-	UpsertParameterCount int                          // The number of SQL arguments.
+	BulkThreshold        int                            // Minimum upsert count for bulk loading, or zero.
+	Conditions           []types.ColData                // The version-like fields for CAS ops.
+	Columns              []types.ColData                // All columns named in an upsert statement.
+	Data                 []types.ColData                // Non-PK, non-ignored columns.
+	Deadlines            types.Deadlines                // Allow too-old data to just be dropped.
+	DeleteParameterCount int                            // The number of SQL arguments.
+	Exprs                *ident.Map[string]             // Value-replacement expressions.
+	ExtrasColIdx         int                            // Position of the extras column, or -1 if unconfigured.
+	Filter               *filter.Filter                 // Discard rows which do not match.
+	HistoryOp            ident.Ident                    // Enables append-only history mode.
+	HistoryTime          ident.Ident                    // Receives the mutation time in history mode.
+	Ignore               ident.Idents                   // Named columns to ignore in the input.
+	Merger               merge.Merger                   // Conflict-resolution callback.
+	Positions            *ident.Map[positionalColumn]   // Map of idents to column info and position.
+	Product              types.Product                  // Target database product.
+	PK                   []types.ColData                // The names of the PK columns.
+	PKDelete             []types.ColData                // The names of the PK columns to delete.
+	Renames              *ident.Map[ident.Ident]        // External (source) names to target names.
+	RowLimit             int                            // Limits number of generated bind variables.
+	SCDCurrent           ident.Ident                    // Enables SCD Type 2 mode.
+	SCDValidFrom         ident.Ident                    // Start of a version's validity.
+	SCDValidTo           ident.Ident                    // End of a version's validity.
+	SoftDelete           ident.Ident                    // Enables soft deletes; a boolean tombstone column.
+	SoftDeleteTime       ident.Ident                    // Optional time of soft deletion.
+	TableName            *ident.Hinted[ident.Table]     // The target table.
+	Transforms           *ident.Map[transform.Pipeline] // Value transforms, by source column.
+	UpsertParameterCount int                            // The number of SQL arguments.
 }
 
 // positionalColumn augments ColData with the offset of the positional
@@ -70,16 +86,20 @@ func newColumnMapping(
 	table *ident.Hinted[ident.Table],
 ) (*columnMapping, error) {
 	ret := &columnMapping{
-		Conditions:   make([]types.ColData, len(cfg.CASColumns)),
-		Deadlines:    &ident.Map[time.Duration]{},
-		Exprs:        &ident.Map[string]{},
-		ExtrasColIdx: -1,
-		Positions:    &ident.Map[positionalColumn]{},
-		Product:      product,
-		Renames:      &ident.Map[ident.Ident]{},
-		RowLimit:     cfg.RowLimit,
-		TableName:    table,
+		BulkThreshold: cfg.BulkThreshold,
+		Conditions:    make([]types.ColData, len(cfg.CASColumns)),
+		Deadlines:     &ident.Map[time.Duration]{},
+		Exprs:         &ident.Map[string]{},
+		ExtrasColIdx:  -1,
+		Filter:        cfg.Filter,
+		Positions:     &ident.Map[positionalColumn]{},
+		Product:       product,
+		Renames:       &ident.Map[ident.Ident]{},
+		RowLimit:      cfg.RowLimit,
+		TableName:     table,
+		Transforms:    &ident.Map[transform.Pipeline]{},
 	}
+	cfg.Transforms.CopyInto(ret.Transforms)
 
 	if ret.RowLimit <= 0 {
 		ret.RowLimit = applycfg.DefaultRowLimit
@@ -183,10 +203,40 @@ func newColumnMapping(
 		if ident.Equal(col.Name, cfg.Extras) {
 			ret.ExtrasColIdx = upsertPosition
 		}
+		// Record the target's exact identifiers for the columns used
+		// by the history, SCD, and soft-delete modes.
+		if ident.Equal(col.Name, cfg.HistoryOp) {
+			ret.HistoryOp = col.Name
+		}
+		if ident.Equal(col.Name, cfg.HistoryTime) {
+			ret.HistoryTime = col.Name
+		}
+		if ident.Equal(col.Name, cfg.SCDCurrent) {
+			ret.SCDCurrent = col.Name
+		}
+		if ident.Equal(col.Name, cfg.SCDValidFrom) {
+			ret.SCDValidFrom = col.Name
+		}
+		if ident.Equal(col.Name, cfg.SCDValidTo) {
+			ret.SCDValidTo = col.Name
+		}
+		if ident.Equal(col.Name, cfg.SoftDelete) {
+			ret.SoftDelete = col.Name
+		}
+		if ident.Equal(col.Name, cfg.SoftDeleteTime) {
+			ret.SoftDeleteTime = col.Name
+		}
 	}
 	ret.DeleteParameterCount = len(ret.PKDelete)
 	ret.UpsertParameterCount = currentParameterIndex
 
+	// SQL Server rejects statements with more than 2100 parameters, so
+	// we'll reduce the number of rows in a batch to fit.
+	if product == types.ProductSQLServer {
+		limit := sqlServerMaxParameters / max(ret.UpsertParameterCount, ret.DeleteParameterCount, 1)
+		ret.RowLimit = max(min(ret.RowLimit, limit), 1)
+	}
+
 	// We also allow the user to force non-existent columns to be
 	// ignored (e.g. to drop a column).
 	for tgt := range cfg.Ignore.Keys() {
@@ -0,0 +1,160 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package apply
+
+// This file contains the append-only history mode. Rather than
+// maintaining the current state of a row, every mutation is inserted
+// into the target table as a new row. The target table's primary key
+// must include a column that receives the mutation's timestamp, so
+// that redelivered mutations are idempotent.
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/cockroachdb/replicator/internal/util/merge"
+	"github.com/cockroachdb/replicator/internal/util/pjson"
+	"github.com/cockroachdb/replicator/internal/util/transform"
+	"github.com/pkg/errors"
+)
+
+// Values for the history operation column.
+const (
+	historyDelete = "delete"
+	historyUpsert = "upsert"
+)
+
+// historyTemplate is the name of the per-product template which
+// inserts rows that have not yet been recorded.
+const historyTemplate = "history"
+
+// historyLocked records each mutation as a new row in the target table.
+func (a *apply) historyLocked(
+	ctx context.Context, db types.TargetQuerier, muts []types.Mutation,
+) error {
+	// The templated statement is insensitive to the number of rows if
+	// the target supports array binding.
+	chunkSize := a.mu.templates.RowLimit
+	if a.mu.templates.BulkUpsert {
+		chunkSize = len(muts)
+	}
+	for len(muts) > 0 {
+		chunk := muts[:min(chunkSize, len(muts))]
+		muts = muts[len(chunk):]
+		if err := a.historyChunkLocked(ctx, db, chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// historyChunkLocked decodes the mutations into property bags, adds the
+// operation and timestamp columns, and inserts them.
+func (a *apply) historyChunkLocked(
+	ctx context.Context, db types.TargetQuerier, muts []types.Mutation,
+) error {
+	tmpl := a.mu.templates
+	bags, err := a.decodeVersionsLocked(ctx, muts, versionKeyCols(tmpl.PKDelete, tmpl.HistoryTime))
+	if err != nil {
+		return err
+	}
+
+	for i, mut := range muts {
+		op := historyUpsert
+		if mut.IsDelete() {
+			op = historyDelete
+		}
+		bags[i].Put(tmpl.HistoryOp, op)
+		bags[i].Put(tmpl.HistoryTime, mut.Time.String())
+	}
+
+	return a.upsertBagsLocked(ctx, db, applyUnconditional, muts, bags, historyTemplate)
+}
+
+// decodeVersionsLocked decodes the mutations into property bags for
+// modes that record multiple versions of a row. A deletion may lack a
+// body, in which case the key columns are populated from the
+// replication key.
+func (a *apply) decodeVersionsLocked(
+	ctx context.Context, muts []types.Mutation, keyCols []types.ColData,
+) ([]*merge.Bag, error) {
+	noBody := func(mut *types.Mutation) bool {
+		return len(mut.Data) == 0 || bytes.Equal(mut.Data, []byte(`null`))
+	}
+
+	bags := make([]*merge.Bag, len(muts))
+	if err := pjson.Decode(ctx, bags, func(i int) []byte {
+		bags[i] = a.newBagLocked()
+		if noBody(&muts[i]) {
+			return []byte(`{}`)
+		}
+		return muts[i].Data
+	}); err != nil {
+		return nil, err
+	}
+
+	keys := make([][]any, len(muts))
+	if err := pjson.Decode(ctx, keys, func(i int) []byte {
+		if muts[i].IsDelete() && noBody(&muts[i]) && len(muts[i].Key) > 0 {
+			return muts[i].Key
+		}
+		return []byte(`null`)
+	}); err != nil {
+		return nil, err
+	}
+
+	for i, key := range keys {
+		if key == nil {
+			continue
+		}
+		if len(key) != len(keyCols) {
+			return nil, errors.Errorf(
+				"schema drift detected in %s: "+
+					"inconsistent number of key columns: "+
+					"received %d expect %d: "+
+					"key %s@%s",
+				a.target,
+				len(key), len(keyCols),
+				string(muts[i].Key), muts[i].Time)
+		}
+		for idx, col := range keyCols {
+			bags[i].Put(col.Name, key[idx])
+		}
+	}
+
+	for _, bag := range bags {
+		if err := transform.ApplyAll(a.mu.templates.Transforms, bag); err != nil {
+			return nil, errors.Wrapf(err, "could not transform data for %s", a.target)
+		}
+	}
+	return bags, nil
+}
+
+// versionKeyCols returns the primary-key columns, less the column that
+// distinguishes versions of a row. The replication key won't contain
+// the version column.
+func versionKeyCols(pk []types.ColData, version ident.Ident) []types.ColData {
+	ret := make([]types.ColData, 0, len(pk))
+	for _, col := range pk {
+		if !ident.Equal(col.Name, version) {
+			ret = append(ret, col)
+		}
+	}
+	return ret
+}
@@ -25,6 +25,10 @@ import (
 )
 
 var (
+	applyBulkRows = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "apply_bulk_rows_total",
+		Help: "the number of rows upserted using a bulk-load path",
+	}, metrics.TableLabels)
 	applyConflicts = promauto.NewCounterVec(prometheus.CounterOpts{
 		Name: "apply_conflicts_total",
 		Help: "the number of rows that experienced a CAS conflict",
@@ -42,6 +46,10 @@ var (
 		Name: "apply_errors_total",
 		Help: "the number of times an error was encountered while applying mutations",
 	}, metrics.TableLabels)
+	applyFiltered = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "apply_filtered_total",
+		Help: "the number of mutations discarded by a filter predicate",
+	}, metrics.TableLabels)
 	applyMutationAge = promauto.NewHistogramVec(prometheus.HistogramOpts{
 		Name: "apply_mutation_age_seconds",
 		Help: "the age of the mutation when it was applied; " +
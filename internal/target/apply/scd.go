@@ -0,0 +1,227 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package apply
+
+// This file contains the SCD Type 2 (slowly-changing dimension) mode.
+// The target table retains every version of a row, and its primary key
+// consists of the source's key columns plus a valid-from column. When
+// a row is updated, the current version is closed by setting its
+// valid-to column and clearing its current-version flag, and a new
+// current version is inserted. A deletion only closes the current
+// version. All of these statements are executed within the caller's
+// transaction.
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/applycfg"
+	"github.com/cockroachdb/replicator/internal/util/hlc"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/cockroachdb/replicator/internal/util/merge"
+	"github.com/pkg/errors"
+)
+
+// newSCDSpec returns a BagSpec used to load the current version of a
+// row. The valid-from column is demoted from the primary key, so that
+// the loader will query by the source's key columns. The loader also
+// expects the primary key columns to be listed first.
+func newSCDSpec(tmpl *templates) *merge.BagSpec {
+	keyCols := versionKeyCols(tmpl.PK, tmpl.SCDValidFrom)
+	cols := make([]types.ColData, 0, len(tmpl.Columns))
+	cols = append(cols, keyCols...)
+	for _, col := range tmpl.Columns {
+		if col.Primary && !ident.Equal(col.Name, tmpl.SCDValidFrom) {
+			continue
+		}
+		col.Primary = false
+		cols = append(cols, col)
+	}
+	return &merge.BagSpec{Columns: cols}
+}
+
+// scdLocked applies the mutations as versions of rows.
+func (a *apply) scdLocked(
+	ctx context.Context, db types.TargetQuerier, muts []types.Mutation,
+) error {
+	// The versions of any given row must be applied in order, so we'll
+	// divide the mutations into rounds that contain at most one
+	// mutation for any key.
+	sorted := slices.Clone(muts)
+	slices.SortStableFunc(sorted, func(a, b types.Mutation) int {
+		return hlc.Compare(a.Time, b.Time)
+	})
+	var rounds [][]types.Mutation
+	seen := make(map[string]int, len(sorted))
+	for _, mut := range sorted {
+		round := seen[string(mut.Key)]
+		seen[string(mut.Key)] = round + 1
+		if round == len(rounds) {
+			rounds = append(rounds, nil)
+		}
+		rounds[round] = append(rounds[round], mut)
+	}
+
+	chunkSize := a.mu.templates.RowLimit
+	for _, round := range rounds {
+		for len(round) > 0 {
+			chunk := round[:min(chunkSize, len(round))]
+			round = round[len(chunk):]
+			if err := a.scdChunkLocked(ctx, db, chunk); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// scdChunkLocked applies mutations which have distinct keys.
+func (a *apply) scdChunkLocked(
+	ctx context.Context, db types.TargetQuerier, muts []types.Mutation,
+) error {
+	tmpl := a.mu.templates
+	bags, err := a.decodeVersionsLocked(ctx, muts, versionKeyCols(tmpl.PKDelete, tmpl.SCDValidFrom))
+	if err != nil {
+		return err
+	}
+	keyCols := versionKeyCols(tmpl.PK, tmpl.SCDValidFrom)
+
+	// Find the current version of each row.
+	current := make([]*merge.Bag, len(bags))
+	for i, bag := range bags {
+		current[i] = merge.NewBag(a.mu.scdSpec)
+		for _, col := range keyCols {
+			value, ok := bag.Get(col.Name)
+			if !ok {
+				return errors.Errorf(
+					"schema drift detected in %s: "+
+						"could not find target PK column name %s in mutation data: "+
+						"key %s@%s",
+					a.target, col.Name, string(muts[i].Key), muts[i].Time)
+			}
+			current[i].Put(col.Name, value)
+		}
+	}
+	res, err := a.loader.LoadCurrent(ctx, db, a.target.Base, current, tmpl.SCDCurrent)
+	if err != nil {
+		return err
+	}
+	notFound := make(map[*merge.Bag]struct{}, len(res.NotFound))
+	for _, bag := range res.NotFound {
+		notFound[bag] = struct{}{}
+	}
+
+	closes := make([]*merge.Bag, 0, len(bags))
+	inserts := make([]*merge.Bag, 0, len(bags))
+	for i, mut := range muts {
+		ts := mut.Time.String()
+		if _, missing := notFound[current[i]]; !missing {
+			from, err := scdTime(current[i].GetZero(tmpl.SCDValidFrom))
+			if err != nil {
+				return errors.Wrapf(err, "could not parse %s in %s",
+					tmpl.SCDValidFrom, a.target)
+			}
+			// The mutation has already been applied or is older than
+			// the current version.
+			if hlc.Compare(from, mut.Time) >= 0 {
+				continue
+			}
+
+			// A sparse payload inherits values from the current
+			// version.
+			for name, value := range current[i].All() {
+				if entry, ok := bags[i].Entry(name); ok && !entry.Valid {
+					bags[i].Put(name, value)
+				}
+			}
+
+			current[i].Put(tmpl.SCDCurrent, false)
+			current[i].Put(tmpl.SCDValidTo, ts)
+			closes = append(closes, current[i])
+		}
+
+		if !mut.IsDelete() {
+			bags[i].Put(tmpl.SCDCurrent, true)
+			bags[i].Put(tmpl.SCDValidFrom, ts)
+			bags[i].Put(tmpl.SCDValidTo, nil)
+			inserts = append(inserts, bags[i])
+		}
+	}
+
+	// Close the current versions before inserting new ones, in case
+	// the target enforces a single current version per key.
+	if err := a.upsertBagsLocked(ctx, db, applyUnconditional, nil, closes, ""); err != nil {
+		return err
+	}
+	// A redelivered version may have already been closed, so we only
+	// insert versions that are absent.
+	return a.upsertBagsLocked(ctx, db, applyUnconditional, nil, inserts, historyTemplate)
+}
+
+// scdTime converts a value loaded from the valid-from column.
+func scdTime(value any) (hlc.Time, error) {
+	var ret hlc.Time
+	err := ret.Scan(fmt.Sprint(value))
+	return ret, err
+}
+
+// validateSCD checks the SCD Type 2 configuration.
+func (a *apply) validateSCD(configData *applycfg.Config, schemaData []types.ColData) error {
+	cols := []ident.Ident{configData.SCDCurrent, configData.SCDValidFrom, configData.SCDValidTo}
+	set := 0
+	for _, col := range cols {
+		if !col.Empty() {
+			set++
+		}
+	}
+	switch set {
+	case 0:
+		return nil
+	case len(cols):
+	default:
+		return errors.Errorf("SCD mode for %s requires current, valid-from, "+
+			"and valid-to columns", a.target)
+	}
+
+	if !configData.HistoryOp.Empty() || !configData.SoftDelete.Empty() {
+		return errors.Errorf("SCD mode for %s may not be combined with "+
+			"history mode or soft deletes", a.target)
+	}
+	// Values are copied between versions of a row, so they must not be
+	// transformed again.
+	if configData.Merger != nil || configData.Exprs.Len() > 0 || !configData.Extras.Empty() {
+		return errors.Errorf("SCD mode for %s does not support merge functions, "+
+			"expressions, or an extras column", a.target)
+	}
+
+	var allCols ident.Map[types.ColData]
+	for _, col := range schemaData {
+		allCols.Put(col.Name, col)
+	}
+	for _, name := range cols {
+		if _, found := allCols.Get(name); !found {
+			return errors.Errorf("SCD column name %s not found in table %s", name, a.target)
+		}
+	}
+	if from := allCols.GetZero(configData.SCDValidFrom); !from.Primary {
+		return errors.Errorf("SCD valid-from column name %s must be part of "+
+			"the primary key of table %s", configData.SCDValidFrom, a.target)
+	}
+	return nil
+}
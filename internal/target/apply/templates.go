@@ -111,10 +111,11 @@ var (
 		},
 	}
 
-	tmplCRDB *template.Template
-	tmplOra  *template.Template
-	tmplMy   *template.Template
-	tmplPG   *template.Template
+	tmplCRDB  *template.Template
+	tmplMSSQL *template.Template
+	tmplOra   *template.Template
+	tmplMy    *template.Template
+	tmplPG    *template.Template
 )
 
 // The error handling in this init function is panicky, since this
@@ -161,6 +162,10 @@ func loadTemplates(from fs.FS, override string) error {
 	if err != nil {
 		return err
 	}
+	tmplMSSQL, err = load("mssql")
+	if err != nil {
+		return err
+	}
 	tmplMy, err = load("my")
 	if err != nil {
 		return err
@@ -183,14 +188,17 @@ type templates struct {
 	BulkDelete bool
 	BulkUpsert bool
 
+	bulk        *template.Template // Nil if the product has no bulk-load path.
 	conditional *template.Template
 	delete      *template.Template
+	softDelete  *template.Template
 	upsert      *template.Template
 
 	tmpl *template.Template
 	// The variables below here are updated during evaluation.
-	ForDelete bool // True if we only iterate over PKs to delete
-	RowCount  int  // The number of rows to be applied.
+	BulkSource string // The name of a staging table to merge from.
+	ForDelete  bool   // True if we only iterate over PKs to delete
+	RowCount   int    // The number of rows to be applied.
 }
 
 // newTemplates constructs a new templates instance, performing some
@@ -201,14 +209,18 @@ func newTemplates(mapping *columnMapping) (*templates, error) {
 
 	switch mapping.Product {
 	case types.ProductCockroachDB:
+		ret.bulk = tmplCRDB.Lookup("bulk.tmpl")
 		ret.conditional = tmplCRDB.Lookup("conditional.tmpl")
 		ret.delete = tmplCRDB.Lookup("delete.tmpl")
+		ret.softDelete = tmplCRDB.Lookup("softdelete.tmpl")
 		ret.upsert = tmplCRDB.Lookup("upsert.tmpl")
 		ret.tmpl = tmplCRDB
 
 	case types.ProductMariaDB, types.ProductMySQL:
+		ret.bulk = tmplMy.Lookup("bulk.tmpl")
 		ret.conditional = tmplMy.Lookup("conditional.tmpl")
 		ret.delete = tmplMy.Lookup("delete.tmpl")
+		ret.softDelete = tmplMy.Lookup("softdelete.tmpl")
 		ret.upsert = tmplMy.Lookup("upsert.tmpl")
 		ret.tmpl = tmplMy
 
@@ -216,14 +228,23 @@ func newTemplates(mapping *columnMapping) (*templates, error) {
 		ret.BulkDelete = true
 		ret.BulkUpsert = true
 		ret.delete = tmplOra.Lookup("delete.tmpl")
+		ret.softDelete = tmplOra.Lookup("softdelete.tmpl")
 		ret.upsert = tmplOra.Lookup("upsert.tmpl")
 		ret.conditional = ret.upsert
 		ret.tmpl = tmplOra
 	case types.ProductPostgreSQL:
+		ret.bulk = tmplPG.Lookup("bulk.tmpl")
 		ret.conditional = tmplPG.Lookup("conditional.tmpl")
 		ret.delete = tmplPG.Lookup("delete.tmpl")
+		ret.softDelete = tmplPG.Lookup("softdelete.tmpl")
 		ret.upsert = tmplPG.Lookup("upsert.tmpl")
 		ret.tmpl = tmplPG
+	case types.ProductSQLServer:
+		ret.conditional = tmplMSSQL.Lookup("conditional.tmpl")
+		ret.delete = tmplMSSQL.Lookup("delete.tmpl")
+		ret.softDelete = tmplMSSQL.Lookup("softdelete.tmpl")
+		ret.upsert = tmplMSSQL.Lookup("upsert.tmpl")
+		ret.tmpl = tmplMSSQL
 
 	default:
 		return nil, errors.Errorf("unsupported product %s", mapping.Product)
@@ -293,6 +314,8 @@ func (t *templates) Vars() ([][]varPair, error) {
 					reference = "?"
 				case types.ProductOracle:
 					reference = fmt.Sprintf(":ref%d", vp.Param)
+				case types.ProductSQLServer:
+					reference = fmt.Sprintf("@p%d", vp.Param)
 				default:
 					return nil, errors.Errorf("unimplemented product %s", t.Product)
 				}
@@ -316,8 +339,36 @@ func (t *templates) deleteExpr(rowCount int) (string, error) {
 	cpy.ForDelete = true
 	cpy.RowCount = rowCount
 
+	// In soft-delete mode, the rows are updated with a tombstone.
+	tmpl := t.delete
+	if !t.SoftDelete.Empty() {
+		tmpl = t.softDelete
+	}
+
+	var buf strings.Builder
+	err := tmpl.Execute(&buf, &cpy)
+	return buf.String(), errors.WithStack(err)
+}
+
+// bulkExpr returns a statement that merges the contents of the named
+// staging table into the target table. The staging table must have
+// been created by bulkStageExpr.
+func (t *templates) bulkExpr(source string) (string, error) {
+	if t.bulk == nil {
+		return "", errors.Errorf("bulk loading not supported for %s", t.Product)
+	}
+	if t.Exprs.Len() > 0 {
+		return "", errors.New("bulk loading not supported with column expressions")
+	}
+
+	// Make a copy that we can tweak. The staging table has a single
+	// window of parameters, which map to its column names.
+	cpy := *t
+	cpy.BulkSource = source
+	cpy.RowCount = 1
+
 	var buf strings.Builder
-	err := t.delete.Execute(&buf, &cpy)
+	err := t.bulk.Execute(&buf, &cpy)
 	return buf.String(), errors.WithStack(err)
 }
 
@@ -27,6 +27,7 @@ import (
 	"github.com/cockroachdb/replicator/internal/types"
 	"github.com/cockroachdb/replicator/internal/util/applycfg"
 	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/cockroachdb/replicator/internal/util/merge"
 	"github.com/stretchr/testify/require"
 )
 
@@ -172,6 +173,154 @@ func TestQueryTemplatesMy(t *testing.T) {
 				),
 			},
 		},
+		{
+			name: "softDelete",
+			cfg: &applycfg.Config{
+				SoftDelete:     ident.New("val0"),
+				SoftDeleteTime: ident.New("val1"),
+			},
+		},
+		{
+			name: "history",
+			cfg: &applycfg.Config{
+				HistoryOp:   ident.New("val0"),
+				HistoryTime: ident.New("pk1"),
+			},
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			checkTemplate(t, global, tc)
+		})
+	}
+}
+
+func TestQueryTemplatesMSSQL(t *testing.T) {
+	global := &templateGlobal{
+		cols: []types.ColData{
+			{
+				Name:    ident.New("pk0"),
+				Primary: true,
+				Type:    "nvarchar(256)",
+			},
+			{
+				Name:    ident.New("pk1"),
+				Primary: true,
+				Type:    "int",
+			},
+			{
+				Name: ident.New("val0"),
+				Type: "nvarchar(256)",
+			},
+			{
+				Name: ident.New("val1"),
+				Type: "nvarchar(256)",
+			},
+			{
+				Ignored: true,
+				Name:    ident.New("ignored_val"),
+				Primary: false,
+				Type:    "int",
+			},
+			{
+				Name:        ident.New("has_default"),
+				Type:        "bigint",
+				DefaultExpr: "((0))",
+			},
+		},
+		dir:     "mssql",
+		product: types.ProductSQLServer,
+		tableID: ident.NewTable(
+			ident.MustSchema(ident.New("database"), ident.New("dbo")),
+			ident.New("table")),
+	}
+
+	tcs := []*templateTestCase{
+		{
+			name: "base",
+		},
+		{
+			name: "cas",
+			cfg: &applycfg.Config{
+				CASColumns: []ident.Ident{ident.New("val1"), ident.New("val0")},
+			},
+		},
+		{
+			// A merge function requires conflicting rows to be
+			// returned to the caller.
+			name: "casMerge",
+			cfg: &applycfg.Config{
+				CASColumns: []ident.Ident{ident.New("val1"), ident.New("val0")},
+				Merger:     merge.DLQ("dead"),
+			},
+		},
+		{
+			name: "deadline",
+			cfg: &applycfg.Config{
+				Deadlines: ident.MapOf[time.Duration](
+					ident.New("val1"), time.Second,
+					ident.New("val0"), time.Hour,
+				),
+			},
+		},
+		{
+			name: "casDeadline",
+			cfg: &applycfg.Config{
+				CASColumns: []ident.Ident{ident.New("val1"), ident.New("val0")},
+				Deadlines: ident.MapOf[time.Duration](
+					ident.New("val0"), time.Hour,
+					ident.New("val1"), time.Second,
+				),
+			},
+		},
+		{
+			// This ignore setup results in a PK-only table.
+			name: "ignore",
+			cfg: &applycfg.Config{
+				Ignore: ident.MapOf[bool](
+					"val0", true,
+					"val1", true,
+				)},
+		},
+		{
+			// Changing the source names should have no effect on the
+			// SQL that gets generated; we only care about the different
+			// value when looking up values in the incoming mutation.
+			name: "source names",
+			cfg: &applycfg.Config{
+				SourceNames: ident.MapOf[applycfg.SourceColumn](
+					ident.New("val1"), ident.New("val1Renamed"),
+					ident.New("unknown"), ident.New("is ok"),
+				),
+			},
+		},
+		{
+			// Verify user-configured expressions, with zero, one, and
+			// multiple uses of the substitution position.
+			name: "expr",
+			cfg: &applycfg.Config{
+				Exprs: ident.MapOf[string](
+					ident.New("val0"), `'fixed'`, // Doesn't consume a parameter slot.
+					ident.New("val1"), `$0+'foobar'`,
+					ident.New("pk1"), `$0+$0`,
+				),
+			},
+		},
+		{
+			name: "softDelete",
+			cfg: &applycfg.Config{
+				SoftDelete:     ident.New("val0"),
+				SoftDeleteTime: ident.New("val1"),
+			},
+		},
+		{
+			name: "history",
+			cfg: &applycfg.Config{
+				HistoryOp:   ident.New("val0"),
+				HistoryTime: ident.New("pk1"),
+			},
+		},
 	}
 
 	for _, tc := range tcs {
@@ -181,6 +330,25 @@ func TestQueryTemplatesMy(t *testing.T) {
 	}
 }
 
+// SQL Server limits the number of parameters in a statement, so wide
+// tables must be applied in smaller batches.
+func TestSQLServerRowLimit(t *testing.T) {
+	r := require.New(t)
+	cols := []types.ColData{{Name: ident.New("pk"), Primary: true, Type: "int"}}
+	for i := range 49 {
+		cols = append(cols, types.ColData{Name: ident.New(fmt.Sprintf("val%d", i)), Type: "int"})
+	}
+	tbl := ident.NewTable(ident.MustSchema(ident.New("database"), ident.New("dbo")), ident.New("table"))
+
+	mapping, err := newColumnMapping(applycfg.NewConfig(), cols, types.ProductSQLServer, ident.WithHint(tbl, ""))
+	r.NoError(err)
+	r.Equal(sqlServerMaxParameters/50, mapping.RowLimit)
+
+	mapping, err = newColumnMapping(applycfg.NewConfig(), cols, types.ProductPostgreSQL, ident.WithHint(tbl, ""))
+	r.NoError(err)
+	r.Equal(applycfg.DefaultRowLimit, mapping.RowLimit)
+}
+
 func TestQueryTemplatesOra(t *testing.T) {
 	global := &templateGlobal{
 		cols: []types.ColData{
@@ -293,6 +461,20 @@ func TestQueryTemplatesOra(t *testing.T) {
 				),
 			},
 		},
+		{
+			name: "softDelete",
+			cfg: &applycfg.Config{
+				SoftDelete:     ident.New("val0"),
+				SoftDeleteTime: ident.New("val1"),
+			},
+		},
+		{
+			name: "history",
+			cfg: &applycfg.Config{
+				HistoryOp:   ident.New("val0"),
+				HistoryTime: ident.New("pk1"),
+			},
+		},
 	}
 
 	for _, tc := range tcs {
@@ -444,6 +626,20 @@ func testQueryTemplates(t *testing.T, product types.Product) {
 				),
 			},
 		},
+		{
+			name: "softDelete",
+			cfg: &applycfg.Config{
+				SoftDelete:     ident.New("val0"),
+				SoftDeleteTime: ident.New("val1"),
+			},
+		},
+		{
+			name: "history",
+			cfg: &applycfg.Config{
+				HistoryOp:   ident.New("val0"),
+				HistoryTime: ident.New("pk1"),
+			},
+		},
 	}
 
 	for _, tc := range tcs {
@@ -500,6 +696,30 @@ func checkTemplate(t *testing.T, global *templateGlobal, tc *templateTestCase) {
 			fmt.Sprintf("testdata/%s/%s.delete.sql", global.dir, tc.name),
 			s)
 	})
+	if !tmpls.HistoryOp.Empty() {
+		t.Run("history", func(t *testing.T) {
+			r := require.New(t)
+			s, err := tmpls.customExpr(2, historyTemplate, applyUnconditional)
+			r.NoError(err)
+			checkFile(t,
+				fmt.Sprintf("testdata/%s/%s.history.sql", global.dir, tc.name),
+				s)
+		})
+	}
+	// The bulk-load path isn't available for all products and doesn't
+	// support user-defined expressions.
+	if tmpls.bulk != nil && tmpls.Exprs.Len() == 0 {
+		t.Run("bulk", func(t *testing.T) {
+			r := require.New(t)
+			stage, err := tmpls.bulkStageExpr("staging")
+			r.NoError(err)
+			s, err := tmpls.bulkExpr("staging")
+			r.NoError(err)
+			checkFile(t,
+				fmt.Sprintf("testdata/%s/%s.bulk.sql", global.dir, tc.name),
+				stage+";\n"+s)
+		})
+	}
 }
 
 func checkFile(t *testing.T, path string, contents string) {
@@ -0,0 +1,2 @@
+DELETE FROM "database"."schema"."table"@{NO_FULL_SCAN} WHERE ("pk0","pk1","ignored_pk")IN(($1::STRING,$2::INT8,$3::STRING),
+($4::STRING,$5::INT8,$6::STRING))
\ No newline at end of file
@@ -0,0 +1,6 @@
+CREATE TEMP TABLE staging (p1 STRING, p2 INT8, p3 STRING, p4 STRING, p5 "database"."schema"."MyEnum", p6 INT, p7 INT8);
+WITH data ("pk0","pk1","val0","val1","enum","has_default") AS (
+SELECT s.p1,s.p2,s.p3,s.p4,s.p5,CASE WHEN s.p6 = 1 THEN s.p7 ELSE expr() END
+FROM staging s)
+UPSERT INTO "database"."schema"."table"@{NO_FULL_SCAN} ("pk0","pk1","val0","val1","enum","has_default")
+SELECT "pk0","pk1","val0","val1","enum","has_default" FROM data
\ No newline at end of file
@@ -0,0 +1,6 @@
+CREATE TEMP TABLE staging (p1 STRING, p2 INT8, p3 STRING, p4 STRING, p5 JSONB, p6 JSONB, p7 "database"."schema"."MyEnum", p8 INT, p9 INT8);
+WITH data ("pk0","pk1","val0","val1","geom","geog","enum","has_default") AS (
+SELECT s.p1,s.p2,s.p3,s.p4,st_geomfromgeojson(s.p5),st_geogfromgeojson(s.p6),s.p7,CASE WHEN s.p8 = 1 THEN s.p9 ELSE expr() END
+FROM staging s)
+UPSERT INTO "database"."schema"."table"@{NO_FULL_SCAN} ("pk0","pk1","val0","val1","geom","geog","enum","has_default")
+SELECT "pk0","pk1","val0","val1","geom","geog","enum","has_default" FROM data
\ No newline at end of file
@@ -0,0 +1,6 @@
+DELETE t FROM "database"."dbo"."table" AS t
+JOIN (VALUES
+(CAST(@p1 AS nvarchar(256)),CAST(@p2 AS int)),
+(CAST(@p3 AS nvarchar(256)),CAST(@p4 AS int))
+) AS x ("pk0","pk1")
+ON t."pk0" = x."pk0" AND t."pk1" = x."pk1"
\ No newline at end of file
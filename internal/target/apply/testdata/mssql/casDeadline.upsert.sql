@@ -0,0 +1,15 @@
+WITH data AS (
+SELECT * FROM (VALUES
+(0,CAST(@p1 AS nvarchar(256)),CAST(@p2 AS int),CAST(@p3 AS nvarchar(256)),CAST(@p4 AS nvarchar(256)),CASE WHEN @p5 = 1 THEN CAST(@p6 AS bigint) ELSE ((0)) END),
+(1,CAST(@p7 AS nvarchar(256)),CAST(@p8 AS int),CAST(@p9 AS nvarchar(256)),CAST(@p10 AS nvarchar(256)),CASE WHEN @p11 = 1 THEN CAST(@p12 AS bigint) ELSE ((0)) END)) AS v ("__idx__", "pk0","pk1","val0","val1","has_default")),
+deadlined AS (SELECT * FROM data WHERE("val0">DATEADD(SECOND, -3600, SYSUTCDATETIME()))AND("val1">DATEADD(SECOND, -1, SYSUTCDATETIME()))),
+action AS (
+SELECT x.* FROM deadlined x
+LEFT JOIN "database"."dbo"."table" t
+ON t."pk0" = x."pk0" AND t."pk1" = x."pk1"
+WHERE t."pk0" IS NULL OR ((x."val1" > t."val1") OR (x."val1" = t."val1" AND x."val0" > t."val0")))
+MERGE INTO "database"."dbo"."table" WITH (HOLDLOCK) AS t
+USING action AS x
+ON t."pk0" = x."pk0" AND t."pk1" = x."pk1"
+WHEN MATCHED THEN UPDATE SET t."val0" = x."val0",t."val1" = x."val1",t."has_default" = x."has_default"
+WHEN NOT MATCHED THEN INSERT ("pk0","pk1","val0","val1","has_default") VALUES (x."pk0",x."pk1",x."val0",x."val1",x."has_default");
\ No newline at end of file
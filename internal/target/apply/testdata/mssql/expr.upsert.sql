@@ -0,0 +1,8 @@
+MERGE INTO "database"."dbo"."table" WITH (HOLDLOCK) AS t
+USING (VALUES
+(CAST(@p1 AS nvarchar(256)),CAST((@p2+@p2) AS int),CAST(('fixed') AS nvarchar(256)),CAST((@p3+'foobar') AS nvarchar(256)),CASE WHEN @p4 = 1 THEN CAST(@p5 AS bigint) ELSE ((0)) END),
+(CAST(@p6 AS nvarchar(256)),CAST((@p7+@p7) AS int),CAST(('fixed') AS nvarchar(256)),CAST((@p8+'foobar') AS nvarchar(256)),CASE WHEN @p9 = 1 THEN CAST(@p10 AS bigint) ELSE ((0)) END)
+) AS x ("pk0","pk1","val0","val1","has_default")
+ON t."pk0" = x."pk0" AND t."pk1" = x."pk1"
+WHEN MATCHED THEN UPDATE SET t."val0" = x."val0",t."val1" = x."val1",t."has_default" = x."has_default"
+WHEN NOT MATCHED THEN INSERT ("pk0","pk1","val0","val1","has_default") VALUES (x."pk0",x."pk1",x."val0",x."val1",x."has_default");
\ No newline at end of file
@@ -0,0 +1,7 @@
+MERGE INTO "database"."dbo"."table" WITH (HOLDLOCK) AS t
+USING (VALUES
+(CAST(@p1 AS nvarchar(256)),CAST(@p2 AS int),CAST(@p3 AS nvarchar(256)),CAST(@p4 AS nvarchar(256)),CASE WHEN @p5 = 1 THEN CAST(@p6 AS bigint) ELSE ((0)) END),
+(CAST(@p7 AS nvarchar(256)),CAST(@p8 AS int),CAST(@p9 AS nvarchar(256)),CAST(@p10 AS nvarchar(256)),CASE WHEN @p11 = 1 THEN CAST(@p12 AS bigint) ELSE ((0)) END)
+) AS x ("pk0","pk1","val0","val1","has_default")
+ON t."pk0" = x."pk0" AND t."pk1" = x."pk1"
+WHEN NOT MATCHED THEN INSERT ("pk0","pk1","val0","val1","has_default") VALUES (x."pk0",x."pk1",x."val0",x."val1",x."has_default");
\ No newline at end of file
@@ -0,0 +1,8 @@
+MERGE INTO "database"."dbo"."table" WITH (HOLDLOCK) AS t
+USING (VALUES
+(CAST(@p1 AS nvarchar(256)),CAST(@p2 AS int),CASE WHEN @p3 = 1 THEN CAST(@p4 AS bigint) ELSE ((0)) END),
+(CAST(@p5 AS nvarchar(256)),CAST(@p6 AS int),CASE WHEN @p7 = 1 THEN CAST(@p8 AS bigint) ELSE ((0)) END)
+) AS x ("pk0","pk1","has_default")
+ON t."pk0" = x."pk0" AND t."pk1" = x."pk1"
+WHEN MATCHED THEN UPDATE SET t."has_default" = x."has_default"
+WHEN NOT MATCHED THEN INSERT ("pk0","pk1","has_default") VALUES (x."pk0",x."pk1",x."has_default");
\ No newline at end of file
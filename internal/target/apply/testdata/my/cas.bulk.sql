@@ -0,0 +1,20 @@
+CREATE TEMPORARY TABLE staging (p1 LONGTEXT, p2 LONGTEXT, p3 LONGTEXT, p4 LONGTEXT, p5 INT, p6 LONGTEXT);
+INSERT
+INTO "schema"."table"("pk0","pk1","val0","val1","has_default")
+WITH data  ("pk0","pk1","val0","val1","has_default") AS (
+  SELECT s.p1,s.p2,s.p3,s.p4,CASE WHEN s.p5 = 1 THEN s.p6 ELSE expr() END FROM staging s
+),
+current AS (
+SELECT "pk0","pk1", "table"."val1","table"."val0"
+FROM "schema"."table"
+JOIN data
+USING ("pk0","pk1")),
+action AS (
+SELECT data.* FROM data
+LEFT JOIN current
+USING ("pk0","pk1")
+WHERE current."pk0" IS NULL OR
+(data."val1",data."val0") > (current."val1",current."val0"))
+SELECT * FROM action
+ON DUPLICATE KEY UPDATE
+"val0"=VALUES("val0"),"val1"=VALUES("val1"),"has_default"=VALUES("has_default")
\ No newline at end of file
@@ -0,0 +1,10 @@
+CREATE TEMPORARY TABLE staging (p1 LONGTEXT, p2 LONGTEXT, p3 LONGTEXT, p4 LONGTEXT, p5 INT, p6 LONGTEXT);
+INSERT
+INTO "schema"."table"("pk0","pk1","val0","val1","has_default")
+WITH data  ("pk0","pk1","val0","val1","has_default") AS (
+  SELECT s.p1,s.p2,s.p3,s.p4,CASE WHEN s.p5 = 1 THEN s.p6 ELSE expr() END FROM staging s
+),
+deadlined AS (SELECT * FROM data WHERE("val0"> now()- INTERVAL '3600' SECOND)AND("val1"> now()- INTERVAL '1' SECOND))
+SELECT * FROM deadlined
+ON DUPLICATE KEY UPDATE
+"val0"=VALUES("val0"),"val1"=VALUES("val1"),"has_default"=VALUES("has_default")
\ No newline at end of file
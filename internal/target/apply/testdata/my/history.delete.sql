@@ -0,0 +1,2 @@
+DELETE FROM "schema"."table"  WHERE ("pk0","pk1")IN((?,?),
+(?,?))
\ No newline at end of file
@@ -0,0 +1,5 @@
+INSERT IGNORE INTO "schema"."table"
+("pk0","pk1","val0","val1","has_default")
+VALUES
+(?,?,?,?,CASE WHEN ? THEN ? ELSE expr() END),
+(?,?,?,?,CASE WHEN ? THEN ? ELSE expr() END)
\ No newline at end of file
@@ -0,0 +1,9 @@
+CREATE TEMPORARY TABLE staging (p1 LONGTEXT, p2 LONGTEXT, p3 INT, p4 LONGTEXT);
+INSERT
+INTO "schema"."table"("pk0","pk1","has_default")
+WITH data  ("pk0","pk1","has_default") AS (
+  SELECT s.p1,s.p2,CASE WHEN s.p3 = 1 THEN s.p4 ELSE expr() END FROM staging s
+)
+SELECT * FROM data
+ON DUPLICATE KEY UPDATE
+"has_default"=VALUES("has_default")
\ No newline at end of file
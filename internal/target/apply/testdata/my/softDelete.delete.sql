@@ -0,0 +1,2 @@
+UPDATE "schema"."table" SET "val0" = TRUE, "val1" = CURRENT_TIMESTAMP(6) WHERE ("pk0","pk1")IN((?,?),
+(?,?))
\ No newline at end of file
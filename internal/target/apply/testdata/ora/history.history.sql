@@ -0,0 +1,6 @@
+MERGE INTO "schema"."table" USING (
+WITH data ("pk0","pk1","val0","val1","has_default") AS (
+SELECT CAST(:1 AS VARCHAR(256)), CAST(:2 AS INT), CAST(:3 AS VARCHAR(256)), CAST(:4 AS VARCHAR(256)), CASE WHEN :5 = 1 THEN CAST(:6 AS INT8) ELSE expr() END FROM DUAL
+)
+SELECT * FROM data) x ON ("schema"."table"."pk0" = x."pk0" AND "schema"."table"."pk1" = x."pk1")
+WHEN NOT MATCHED THEN INSERT ("pk0","pk1","val0","val1","has_default") VALUES (x."pk0", x."pk1", x."val0", x."val1", x."has_default")
\ No newline at end of file
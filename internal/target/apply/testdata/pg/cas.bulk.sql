@@ -0,0 +1,19 @@
+CREATE TEMP TABLE staging (p1 STRING, p2 INT8, p3 STRING, p4 STRING, p5 JSONB, p6 JSONB, p7 "database"."schema"."MyEnum", p8 INT, p9 INT8) ON COMMIT DROP;
+WITH data ("pk0","pk1","val0","val1","geom","geog","enum","has_default") AS (
+SELECT s.p1,s.p2,s.p3,s.p4,st_geomfromgeojson(s.p5),st_geogfromgeojson(s.p6),s.p7,CASE WHEN s.p8 = 1 THEN s.p9 ELSE expr() END
+FROM staging s),
+current AS (
+SELECT "pk0","pk1", "table"."val1","table"."val0"
+FROM "database"."schema"."table"
+JOIN data
+USING ("pk0","pk1")),
+action AS (
+SELECT data.* FROM data
+LEFT JOIN current
+USING ("pk0","pk1")
+WHERE current."pk0" IS NULL OR
+(data."val1",data."val0") > (current."val1",current."val0"))
+INSERT INTO "database"."schema"."table" ("pk0","pk1","val0","val1","geom","geog","enum","has_default")
+SELECT "pk0","pk1","val0","val1","geom","geog","enum","has_default" FROM action
+ON CONFLICT ( "pk0","pk1" )
+DO UPDATE SET ("val0","val1","geom","geog","enum","has_default") = ROW(excluded."val0",excluded."val1",excluded."geom",excluded."geog",excluded."enum",excluded."has_default")
\ No newline at end of file
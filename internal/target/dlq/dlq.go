@@ -135,6 +135,8 @@ func (d *dlqs) Get(ctx context.Context, target ident.Schema, name string) (types
 		q = qBase + argsOra
 	case types.ProductMariaDB, types.ProductMySQL:
 		q = qBase + argsMySQL
+	case types.ProductSQLServer:
+		q = qBase + argsMSSQL
 	default:
 		return nil, errors.Errorf("dlq unimplemented for product %s", d.targetPool.Product)
 	}
@@ -37,6 +37,7 @@ var expectedColumns = []ident.Ident{
 const (
 	qBase     = `INSERT INTO %s (dlq_name, source_nanos, source_logical, data_after, data_before) VALUES `
 	argsPG    = `($1, $2, $3, $4, $5)`
+	argsMSSQL = `(@p1, @p2, @p3, @p4, @p5)`
 	argsMySQL = `(?, ?, ?, ?, ?)`
 	argsOra   = `(:1, :2, :3, :4, :5)`
 )
@@ -52,6 +53,14 @@ source_nanos INT8 NOT NULL,
 source_logical INT8 NOT NULL,
 data_after JSONB NOT NULL,
 data_before JSONB NOT NULL
+)`
+	basicMSSQLSchema = `CREATE TABLE %[1]s (
+event UNIQUEIDENTIFIER DEFAULT NEWID() PRIMARY KEY,
+dlq_name NVARCHAR(256) NOT NULL,
+source_nanos BIGINT NOT NULL,
+source_logical BIGINT NOT NULL,
+data_after NVARCHAR(MAX) NOT NULL,
+data_before NVARCHAR(MAX) NOT NULL
 )`
 	basicMySQLSchema = `CREATE TABLE %[1]s (
 event binary(16) DEFAULT (uuid()) PRIMARY KEY,
@@ -88,4 +97,5 @@ var BasicSchemas = map[types.Product]string{
 	types.ProductMySQL:       basicMySQLSchema,
 	types.ProductOracle:      basicOraSchema,
 	types.ProductPostgreSQL:  basicPGSchema,
+	types.ProductSQLServer:   basicMSSQLSchema,
 }
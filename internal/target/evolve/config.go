@@ -0,0 +1,48 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package evolve
+
+import (
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+)
+
+// Config controls the automatic creation and evolution of target
+// tables.
+type Config struct {
+	// Only log the DDL statements that would be executed.
+	DryRun bool
+	// Create missing target tables and add newly seen columns.
+	Enabled bool
+}
+
+// Bind adds flags to the set.
+func (c *Config) Bind(f *pflag.FlagSet) {
+	f.BoolVar(&c.Enabled, "schemaEvolution", false,
+		"create missing target tables and add new columns to existing target tables, "+
+			"based on the table metadata sent by the source")
+	f.BoolVar(&c.DryRun, "schemaEvolutionDryRun", false,
+		"log the DDL statements that schema evolution would execute, without executing them")
+}
+
+// Preflight validates the configuration.
+func (c *Config) Preflight() error {
+	if c.DryRun && !c.Enabled {
+		return errors.New("schemaEvolutionDryRun requires schemaEvolution")
+	}
+	return nil
+}
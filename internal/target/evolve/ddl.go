@@ -0,0 +1,243 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package evolve
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/pkg/errors"
+)
+
+// typeAliases maps PostgreSQL type names onto the names used as keys in
+// the translation tables below.
+var typeAliases = map[string]string{
+	"bigint":                      "int8",
+	"binary":                      "bytea",
+	"boolean":                     "bool",
+	"bpchar":                      "text",
+	"bytes":                       "bytea",
+	"char":                        "text",
+	"character":                   "text",
+	"character varying":           "text",
+	"decimal":                     "numeric",
+	"double precision":            "float8",
+	"float":                       "float8",
+	"int":                         "int4",
+	"integer":                     "int4",
+	"json":                        "jsonb",
+	"name":                        "text",
+	"real":                        "float4",
+	"smallint":                    "int2",
+	"string":                      "text",
+	"time without time zone":      "time",
+	"timestamp with time zone":    "timestamptz",
+	"timestamp without time zone": "timestamp",
+	"varchar":                     "text",
+}
+
+// typeTranslations maps normalized PostgreSQL type names to the types
+// used for other target products. PostgreSQL and CockroachDB targets
+// use the source type names as-is.
+var typeTranslations = map[types.Product]map[string]string{
+	types.ProductMariaDB: mySQLTypes,
+	types.ProductMySQL:   mySQLTypes,
+	types.ProductOracle: {
+		"bool":        "NUMBER(1)",
+		"bytea":       "BLOB",
+		"date":        "DATE",
+		"float4":      "BINARY_FLOAT",
+		"float8":      "BINARY_DOUBLE",
+		"int2":        "NUMBER(5)",
+		"int4":        "NUMBER(10)",
+		"int8":        "NUMBER(19)",
+		"jsonb":       "CLOB",
+		"numeric":     "NUMBER",
+		"text":        "VARCHAR2(4000 CHAR)",
+		"time":        "VARCHAR2(32)",
+		"timestamp":   "TIMESTAMP",
+		"timestamptz": "TIMESTAMP WITH TIME ZONE",
+		"uuid":        "VARCHAR2(36)",
+	},
+	types.ProductSQLServer: {
+		"bool":        "BIT",
+		"bytea":       "VARBINARY(MAX)",
+		"date":        "DATE",
+		"float4":      "REAL",
+		"float8":      "FLOAT",
+		"int2":        "SMALLINT",
+		"int4":        "INT",
+		"int8":        "BIGINT",
+		"jsonb":       "NVARCHAR(MAX)",
+		"numeric":     "DECIMAL(38,10)",
+		"text":        "NVARCHAR(MAX)",
+		"time":        "TIME",
+		"timestamp":   "DATETIME2",
+		"timestamptz": "DATETIMEOFFSET",
+		"uuid":        "UNIQUEIDENTIFIER",
+	},
+}
+
+var mySQLTypes = map[string]string{
+	"bool":        "BOOLEAN",
+	"bytea":       "LONGBLOB",
+	"date":        "DATE",
+	"float4":      "FLOAT",
+	"float8":      "DOUBLE",
+	"geometry":    "GEOMETRY",
+	"int2":        "SMALLINT",
+	"int4":        "INT",
+	"int8":        "BIGINT",
+	"jsonb":       "JSON",
+	"numeric":     "DECIMAL(65,30)",
+	"text":        "LONGTEXT",
+	"time":        "TIME(6)",
+	"timestamp":   "DATETIME(6)",
+	"timestamptz": "TIMESTAMP(6)",
+	"uuid":        "CHAR(36)",
+}
+
+// keyTypes override the translated type of primary-key columns, for
+// products that cannot index unbounded types.
+var keyTypes = map[types.Product]map[string]string{
+	types.ProductMariaDB: mySQLKeyTypes,
+	types.ProductMySQL:   mySQLKeyTypes,
+	types.ProductOracle: {
+		"bytea": "RAW(2000)",
+	},
+	types.ProductSQLServer: {
+		"bytea": "VARBINARY(900)",
+		"text":  "NVARCHAR(450)",
+	},
+}
+
+var mySQLKeyTypes = map[string]string{
+	"bytea": "VARBINARY(255)",
+	"text":  "VARCHAR(255)",
+}
+
+// normalizeType strips any qualification, quoting, and type modifiers
+// from a PostgreSQL type name and resolves aliases.
+func normalizeType(typ string) (base string, array bool) {
+	base = strings.ToLower(strings.TrimSpace(typ))
+	if strings.HasSuffix(base, "[]") {
+		base = strings.TrimSuffix(base, "[]")
+		array = true
+	}
+	if idx := strings.IndexByte(base, '('); idx >= 0 {
+		base = strings.TrimSpace(base[:idx])
+	}
+	if idx := strings.LastIndexByte(base, '.'); idx >= 0 {
+		base = base[idx+1:]
+	}
+	base = strings.Trim(base, `"`)
+	if alias, ok := typeAliases[base]; ok {
+		base = alias
+	}
+	return base, array
+}
+
+// targetType returns the type to use for the column in the target
+// product.
+func targetType(product types.Product, col types.ColData) (string, error) {
+	if col.Type == "" {
+		return "", errors.Errorf("column %s has no known type", col.Name)
+	}
+	switch product {
+	case types.ProductCockroachDB, types.ProductPostgreSQL:
+		return col.Type, nil
+	}
+	base, array := normalizeType(col.Type)
+	if array {
+		return "", errors.Errorf("column %s: array types are not supported by %s", col.Name, product)
+	}
+	if col.Primary {
+		if ret, ok := keyTypes[product][base]; ok {
+			return ret, nil
+		}
+	}
+	if ret, ok := typeTranslations[product][base]; ok {
+		return ret, nil
+	}
+	return "", errors.Errorf("column %s: cannot translate type %s for %s", col.Name, col.Type, product)
+}
+
+// plan returns the DDL statements required to make the target table
+// contain the source columns. The existing argument should be nil if
+// the target table does not exist. Columns which are present in the
+// target but not in the source are left alone.
+func plan(
+	product types.Product, table ident.Table, cols []types.ColData, existing []types.ColData,
+) ([]string, error) {
+	if existing == nil {
+		return createTable(product, table, cols)
+	}
+
+	var known ident.Map[bool]
+	for _, col := range existing {
+		known.Put(col.Name, true)
+	}
+
+	var ret []string
+	for _, col := range cols {
+		if known.GetZero(col.Name) {
+			continue
+		}
+		typ, err := targetType(product, col)
+		if err != nil {
+			return nil, err
+		}
+		// New primary-key columns are added as ordinary, nullable
+		// columns, since the key of an existing table can't be
+		// altered in a portable manner.
+		switch product {
+		case types.ProductOracle:
+			ret = append(ret, fmt.Sprintf("ALTER TABLE %s ADD (%s %s)", table, col.Name, typ))
+		case types.ProductSQLServer:
+			ret = append(ret, fmt.Sprintf("ALTER TABLE %s ADD %s %s", table, col.Name, typ))
+		default:
+			ret = append(ret, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, col.Name, typ))
+		}
+	}
+	return ret, nil
+}
+
+// createTable returns a CREATE TABLE statement for the source columns.
+func createTable(product types.Product, table ident.Table, cols []types.ColData) ([]string, error) {
+	var defs, pks []string
+	for _, col := range cols {
+		typ, err := targetType(product, col)
+		if err != nil {
+			return nil, err
+		}
+		def := fmt.Sprintf("%s %s", col.Name, typ)
+		if col.Primary {
+			def += " NOT NULL"
+			pks = append(pks, col.Name.String())
+		}
+		defs = append(defs, def)
+	}
+	if len(pks) == 0 {
+		return nil, errors.Errorf("cannot create %s without a primary key", table)
+	}
+	defs = append(defs, fmt.Sprintf("PRIMARY KEY (%s)", strings.Join(pks, ", ")))
+	return []string{
+		fmt.Sprintf("CREATE TABLE %s (%s)", table, strings.Join(defs, ", ")),
+	}, nil
+}
@@ -0,0 +1,159 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package evolve
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlan(t *testing.T) {
+	table := ident.NewTable(
+		ident.MustSchema(ident.New("db"), ident.Public), ident.New("tbl"))
+	cols := []types.ColData{
+		{Name: ident.New("pk"), Primary: true, Type: "text"},
+		{Name: ident.New("val"), Type: "int8"},
+		{Name: ident.New("doc"), Type: "jsonb"},
+	}
+
+	tcs := []struct {
+		name     string
+		product  types.Product
+		cols     []types.ColData
+		existing []types.ColData
+		expect   []string
+		err      string
+	}{
+		{
+			name:    "create crdb",
+			product: types.ProductCockroachDB,
+			cols:    cols,
+			expect: []string{
+				`CREATE TABLE "db"."public"."tbl" ("pk" text NOT NULL, "val" int8, "doc" jsonb, PRIMARY KEY ("pk"))`,
+			},
+		},
+		{
+			name:    "create mysql",
+			product: types.ProductMySQL,
+			cols:    cols,
+			expect: []string{
+				`CREATE TABLE "db"."public"."tbl" ("pk" VARCHAR(255) NOT NULL, "val" BIGINT, "doc" JSON, PRIMARY KEY ("pk"))`,
+			},
+		},
+		{
+			name:    "create sql server",
+			product: types.ProductSQLServer,
+			cols:    cols,
+			expect: []string{
+				`CREATE TABLE "db"."public"."tbl" ("pk" NVARCHAR(450) NOT NULL, "val" BIGINT, "doc" NVARCHAR(MAX), PRIMARY KEY ("pk"))`,
+			},
+		},
+		{
+			name:    "create without pk",
+			product: types.ProductPostgreSQL,
+			cols:    cols[1:],
+			err:     "without a primary key",
+		},
+		{
+			name:     "add columns pg",
+			product:  types.ProductPostgreSQL,
+			cols:     cols,
+			existing: []types.ColData{{Name: ident.New("PK"), Primary: true, Type: "text"}},
+			expect: []string{
+				`ALTER TABLE "db"."public"."tbl" ADD COLUMN "val" int8`,
+				`ALTER TABLE "db"."public"."tbl" ADD COLUMN "doc" jsonb`,
+			},
+		},
+		{
+			name:     "add columns oracle",
+			product:  types.ProductOracle,
+			cols:     cols,
+			existing: cols[:2],
+			expect: []string{
+				`ALTER TABLE "db"."public"."tbl" ADD ("doc" CLOB)`,
+			},
+		},
+		{
+			name:     "up to date",
+			product:  types.ProductCockroachDB,
+			cols:     cols,
+			existing: cols,
+		},
+		{
+			name:    "unknown type",
+			product: types.ProductMySQL,
+			cols: []types.ColData{
+				{Name: ident.New("pk"), Primary: true, Type: "my_enum"},
+			},
+			err: "cannot translate type my_enum",
+		},
+		{
+			name:    "array",
+			product: types.ProductSQLServer,
+			cols: []types.ColData{
+				{Name: ident.New("pk"), Primary: true, Type: "int8[]"},
+			},
+			err: "array types are not supported",
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			r := require.New(t)
+			stmts, err := plan(tc.product, table, tc.cols, tc.existing)
+			if tc.err != "" {
+				r.ErrorContains(err, tc.err)
+				return
+			}
+			r.NoError(err)
+			r.Equal(tc.expect, stmts)
+		})
+	}
+}
+
+func TestNormalizeType(t *testing.T) {
+	tcs := []struct {
+		typ   string
+		base  string
+		array bool
+	}{
+		{"int8", "int8", false},
+		{"BIGINT", "int8", false},
+		{"character varying(255)", "text", false},
+		{`"pg_catalog"."numeric"`, "numeric", false},
+		{"timestamp with time zone", "timestamptz", false},
+		{"text[]", "text", true},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.typ, func(t *testing.T) {
+			r := require.New(t)
+			base, array := normalizeType(tc.typ)
+			r.Equal(tc.base, base)
+			r.Equal(tc.array, array)
+		})
+	}
+}
+
+func TestConfigPreflight(t *testing.T) {
+	r := require.New(t)
+	r.NoError((&Config{}).Preflight())
+	r.NoError((&Config{Enabled: true, DryRun: true}).Preflight())
+	r.ErrorContains((&Config{DryRun: true}).Preflight(), "requires schemaEvolution")
+}
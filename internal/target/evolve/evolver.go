@@ -0,0 +1,126 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package evolve creates and alters target tables to match the table
+// metadata reported by a source.
+package evolve
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/cockroachdb/replicator/internal/util/metrics"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// memoPrefix is prepended to the memo keys that record the DDL
+// statements that have been executed.
+const memoPrefix = "schema-evolution"
+
+// An Evolver creates missing target tables and adds newly seen columns
+// to existing target tables. All methods are no-ops if schema evolution
+// has not been enabled.
+type Evolver struct {
+	config      *Config
+	memo        types.Memo
+	stagingPool *types.StagingPool
+	targetPool  *types.TargetPool
+	watchers    types.Watchers
+
+	mu struct {
+		sync.Mutex
+		// The source columns most recently reconciled for each table.
+		// This avoids repeated work, since sources may report table
+		// metadata before every row event.
+		seen *ident.TableMap[string]
+	}
+}
+
+// Ensure updates the target table so that it has at least the given
+// source columns. The column types must be PostgreSQL type names; they
+// are translated as necessary for the target product. Each statement
+// that is executed is recorded in the memo table.
+func (e *Evolver) Ensure(ctx context.Context, table ident.Table, cols []types.ColData) error {
+	if e == nil || !e.config.Enabled {
+		return nil
+	}
+	sig := signature(cols)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if prev, ok := e.mu.seen.Get(table); ok && prev == sig {
+		return nil
+	}
+
+	w, err := e.watchers.Get(table.Schema())
+	if err != nil {
+		return err
+	}
+	existing, _ := w.Get().Columns.Get(table)
+	stmts, err := plan(e.targetPool.Product, table, cols, existing)
+	if err != nil {
+		return err
+	}
+
+	labels := metrics.TableValues(table)
+	for _, stmt := range stmts {
+		if e.config.DryRun {
+			log.WithFields(log.Fields{
+				"statement": stmt,
+				"table":     table,
+			}).Info("schema evolution dry run; statement not executed")
+			continue
+		}
+		if _, err := e.targetPool.ExecContext(ctx, stmt); err != nil {
+			evolveErrors.WithLabelValues(labels...).Inc()
+			return errors.Wrap(err, stmt)
+		}
+		evolveStatements.WithLabelValues(labels...).Inc()
+		log.WithFields(log.Fields{
+			"statement": stmt,
+			"table":     table,
+		}).Info("schema evolution executed statement")
+
+		key := fmt.Sprintf("%s-%s-%d", memoPrefix, table.Raw(), time.Now().UnixNano())
+		if err := e.memo.Put(ctx, e.stagingPool, key, []byte(stmt)); err != nil {
+			return err
+		}
+	}
+
+	if len(stmts) > 0 && !e.config.DryRun {
+		if err := w.Refresh(ctx, e.targetPool); err != nil {
+			return err
+		}
+	}
+	e.mu.seen.Put(table, sig)
+	return nil
+}
+
+// signature returns a string that identifies the column names and
+// types.
+func signature(cols []types.ColData) string {
+	var sb strings.Builder
+	for _, col := range cols {
+		fmt.Fprintf(&sb, "%s %s %t;", col.Name.Raw(), col.Type, col.Primary)
+	}
+	return sb.String()
+}
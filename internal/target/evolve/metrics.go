@@ -0,0 +1,34 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package evolve
+
+import (
+	"github.com/cockroachdb/replicator/internal/util/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	evolveErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "schema_evolution_errors_total",
+		Help: "the number of times a schema evolution statement could not be executed",
+	}, metrics.TableLabels)
+	evolveStatements = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "schema_evolution_statements_total",
+		Help: "the number of schema evolution statements executed against the target",
+	}, metrics.TableLabels)
+)
@@ -0,0 +1,324 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package kafka contains a non-SQL target which produces mutations to
+// Kafka topics. The target database is still used to describe the
+// target tables, so that userscripts and sequencers operate as they
+// would for a SQL target.
+package kafka
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/hlc"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/cockroachdb/replicator/internal/util/metrics"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// Acceptor produces one keyed message per mutation to a topic that is
+// derived from the target table's name. Resolved markers are produced
+// to every partition of the topics within a table group, no more often
+// than the configured interval.
+//
+// The messages are produced synchronously, so any mutations which
+// have been accepted have been acknowledged by the brokers. Any
+// [types.TargetQuerier] passed in the [types.AcceptOptions] is
+// ignored.
+type Acceptor struct {
+	cfg        *Config
+	partitions func(topic string) ([]int32, error)
+	producer   sarama.SyncProducer
+
+	mu struct {
+		sync.Mutex
+		pending ident.Map[*pendingResolved]
+		seen    ident.TableMap[struct{}]
+	}
+}
+
+// pendingResolved holds a resolved timestamp that has yet to be
+// produced.
+type pendingResolved struct {
+	group *types.TableGroup
+	ts    hlc.Time
+}
+
+var (
+	_ types.MultiAcceptor    = (*Acceptor)(nil)
+	_ types.ResolvedAcceptor = (*Acceptor)(nil)
+)
+
+// NewAcceptor connects to the configured brokers. The connection will
+// be closed when the context is stopped.
+func NewAcceptor(ctx *stopper.Context, cfg *Config) (*Acceptor, error) {
+	if err := cfg.Preflight(); err != nil {
+		return nil, err
+	}
+	client, err := sarama.NewClient(cfg.Brokers, cfg.saramaConfig())
+	if err != nil {
+		return nil, errors.Wrap(err, "could not connect to Kafka brokers")
+	}
+	producer, err := sarama.NewSyncProducerFromClient(client)
+	if err != nil {
+		_ = client.Close()
+		return nil, errors.WithStack(err)
+	}
+	ret := newAcceptor(cfg, producer, client.Partitions)
+	ret.flushResolved(ctx)
+	ctx.Defer(func() {
+		if err := producer.Close(); err != nil {
+			log.WithError(err).Warn("could not close Kafka producer")
+		}
+		if err := client.Close(); err != nil {
+			log.WithError(err).Warn("could not close Kafka client")
+		}
+	})
+	return ret, nil
+}
+
+func newAcceptor(
+	cfg *Config, producer sarama.SyncProducer, partitions func(string) ([]int32, error),
+) *Acceptor {
+	return &Acceptor{
+		cfg:        cfg,
+		partitions: partitions,
+		producer:   producer,
+	}
+}
+
+// AcceptMultiBatch implements [types.MultiAcceptor]. The temporal
+// batches are produced in time order.
+func (a *Acceptor) AcceptMultiBatch(
+	ctx context.Context, batch *types.MultiBatch, opts *types.AcceptOptions,
+) error {
+	for _, temporal := range batch.Data {
+		if err := a.AcceptTemporalBatch(ctx, temporal, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AcceptTemporalBatch implements [types.TemporalAcceptor]. Since there
+// are no referential constraints between topics, the tables are
+// produced in an arbitrary order.
+func (a *Acceptor) AcceptTemporalBatch(
+	ctx context.Context, batch *types.TemporalBatch, opts *types.AcceptOptions,
+) error {
+	for _, table := range batch.Data.All() {
+		if err := a.AcceptTableBatch(ctx, table, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AcceptTableBatch implements [types.TableAcceptor]. The key of each
+// message is the mutation's key, a JSON array.
+func (a *Acceptor) AcceptTableBatch(
+	_ context.Context, batch *types.TableBatch, _ *types.AcceptOptions,
+) error {
+	if len(batch.Data) == 0 {
+		return nil
+	}
+	start := time.Now()
+	labels := metrics.TableValues(batch.Table)
+	topic := a.topic(batch.Table)
+
+	msgs := make([]*sarama.ProducerMessage, 0, len(batch.Data))
+	for _, mut := range batch.Data {
+		values, err := a.cfg.Envelope.encodeMutation(batch.Table, mut)
+		if err != nil {
+			return err
+		}
+		for _, value := range values {
+			msg := &sarama.ProducerMessage{
+				Topic:     topic,
+				Key:       sarama.ByteEncoder(mut.Key),
+				Timestamp: time.Unix(0, mut.Time.Nanos()),
+			}
+			// A nil Value represents a tombstone.
+			if value != nil {
+				msg.Value = sarama.ByteEncoder(value)
+			}
+			msgs = append(msgs, msg)
+		}
+	}
+	if err := a.producer.SendMessages(msgs); err != nil {
+		produceErrors.WithLabelValues(labels...).Inc()
+		return errors.Wrapf(err, "could not produce messages to topic %s", topic)
+	}
+	produceDurations.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+	produceMessages.WithLabelValues(labels...).Add(float64(len(msgs)))
+
+	a.mu.Lock()
+	a.mu.seen.Put(batch.Table, struct{}{})
+	a.mu.Unlock()
+	return nil
+}
+
+// AcceptResolved implements [types.ResolvedAcceptor]. The marker will
+// be produced asynchronously, to limit the rate at which markers are
+// produced when the group's checkpoint advances rapidly.
+func (a *Acceptor) AcceptResolved(
+	_ context.Context, group *types.TableGroup, ts hlc.Time,
+) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if found, ok := a.mu.pending.Get(group.Name); ok && hlc.Compare(found.ts, ts) >= 0 {
+		return nil
+	}
+	a.mu.pending.Put(group.Name, &pendingResolved{group, ts})
+	return nil
+}
+
+// flushResolved starts a goroutine to periodically produce resolved
+// markers.
+func (a *Acceptor) flushResolved(ctx *stopper.Context) {
+	ctx.Go(func(ctx *stopper.Context) error {
+		ticker := time.NewTicker(a.cfg.ResolvedInterval)
+		defer ticker.Stop()
+		for {
+			if err := a.produceResolved(); err != nil {
+				log.WithError(err).Warn("could not produce resolved markers; will retry")
+			}
+			select {
+			case <-ctx.Stopping():
+				return nil
+			case <-ticker.C:
+			}
+		}
+	})
+}
+
+// produceResolved produces any pending resolved markers to every
+// partition of the topics in each group. If the markers cannot be
+// produced, they will be retried unless they have been superseded.
+func (a *Acceptor) produceResolved() error {
+	a.mu.Lock()
+	var work []*pendingResolved
+	for _, pending := range a.mu.pending.All() {
+		work = append(work, pending)
+	}
+	a.mu.pending = ident.Map[*pendingResolved]{}
+	a.mu.Unlock()
+
+	for idx, pending := range work {
+		if err := a.produceMarker(pending); err != nil {
+			// Requeue the markers that we have not produced.
+			for _, retry := range work[idx:] {
+				_ = a.AcceptResolved(context.Background(), retry.group, retry.ts)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// produceMarker produces a single resolved marker.
+func (a *Acceptor) produceMarker(pending *pendingResolved) error {
+	value, err := a.cfg.Envelope.encodeResolved(pending.ts)
+	if err != nil || value == nil {
+		return err
+	}
+	var msgs []*sarama.ProducerMessage
+	for _, table := range a.groupTables(pending.group) {
+		topic := a.topic(table)
+		partitions, err := a.partitions(topic)
+		if err != nil {
+			return errors.Wrapf(err, "could not determine partitions of topic %s", topic)
+		}
+		for _, partition := range partitions {
+			msgs = append(msgs, &sarama.ProducerMessage{
+				Topic:     topic,
+				Partition: partition,
+				Value:     sarama.ByteEncoder(value),
+				Metadata:  resolvedMarker{},
+			})
+		}
+	}
+	if len(msgs) == 0 {
+		return nil
+	}
+	if err := a.producer.SendMessages(msgs); err != nil {
+		return errors.Wrapf(err, "could not produce resolved markers for %s", pending.group)
+	}
+	resolvedMarkers.Add(float64(len(msgs)))
+	log.Tracef("produced resolved markers for %s at %s", pending.group, pending.ts)
+	return nil
+}
+
+// groupTables returns the tables in the group, plus any tables within
+// the group's enclosing schema to which messages have been produced.
+func (a *Acceptor) groupTables(group *types.TableGroup) []ident.Table {
+	var tables ident.TableMap[struct{}]
+	for _, table := range group.Tables {
+		tables.Put(table, struct{}{})
+	}
+	if !group.Enclosing.Empty() {
+		a.mu.Lock()
+		for table := range a.mu.seen.Keys() {
+			if group.Enclosing.Contains(table) {
+				tables.Put(table, struct{}{})
+			}
+		}
+		a.mu.Unlock()
+	}
+	ret := make([]ident.Table, 0, tables.Len())
+	for table := range tables.Keys() {
+		ret = append(ret, table)
+	}
+	return ret
+}
+
+// topic returns the name of the topic for the table.
+func (a *Acceptor) topic(table ident.Table) string {
+	return a.cfg.TopicPrefix + table.Table().Raw()
+}
+
+// resolvedMarker is attached to the Metadata of a resolved marker so
+// that the partitioner will send it to the requested partition.
+type resolvedMarker struct{}
+
+// partitioner hashes message keys, except for resolved markers which
+// are sent to every partition.
+type partitioner struct {
+	hash sarama.Partitioner
+}
+
+func newPartitioner(topic string) sarama.Partitioner {
+	return &partitioner{sarama.NewHashPartitioner(topic)}
+}
+
+// Partition implements sarama.Partitioner.
+func (p *partitioner) Partition(msg *sarama.ProducerMessage, numPartitions int32) (int32, error) {
+	if _, ok := msg.Metadata.(resolvedMarker); ok {
+		return msg.Partition, nil
+	}
+	return p.hash.Partition(msg, numPartitions)
+}
+
+// RequiresConsistency implements sarama.Partitioner.
+func (p *partitioner) RequiresConsistency() bool {
+	return true
+}
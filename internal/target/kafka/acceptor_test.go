@@ -0,0 +1,295 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"github.com/IBM/sarama"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/hlc"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// recorder is a SyncProducer which retains the messages sent to it.
+type recorder struct {
+	sarama.SyncProducer // Not implemented.
+
+	fail bool
+	mu   sync.Mutex
+	msgs []*sarama.ProducerMessage
+}
+
+func (r *recorder) SendMessages(msgs []*sarama.ProducerMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.fail {
+		return errors.New("broker unavailable")
+	}
+	r.msgs = append(r.msgs, msgs...)
+	return nil
+}
+
+func (r *recorder) take() []*sarama.ProducerMessage {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ret := r.msgs
+	r.msgs = nil
+	return ret
+}
+
+func encoded(t *testing.T, enc sarama.Encoder) string {
+	t.Helper()
+	if enc == nil {
+		return ""
+	}
+	buf, err := enc.Encode()
+	require.NoError(t, err)
+	return string(buf)
+}
+
+func TestAcceptor(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+
+	schema := ident.MustSchema(ident.New("db"), ident.New("public"))
+	parent := ident.NewTable(schema, ident.New("parent"))
+	child := ident.NewTable(schema, ident.New("child"))
+	other := ident.NewTable(ident.MustSchema(ident.New("other")), ident.New("tbl"))
+
+	prod := &recorder{}
+	acc := newAcceptor(&Config{TopicPrefix: "cdc."}, prod,
+		func(topic string) ([]int32, error) {
+			if topic == "cdc.child" {
+				return []int32{0, 1, 2}, nil
+			}
+			return []int32{0}, nil
+		})
+
+	t1 := hlc.New(100, 1)
+	t2 := hlc.New(200, 0)
+	batch := &types.MultiBatch{}
+	r.NoError(batch.Accumulate(parent, types.Mutation{
+		Data: json.RawMessage(`{"pk":1}`),
+		Key:  json.RawMessage(`[1]`),
+		Time: t1,
+	}))
+	r.NoError(batch.Accumulate(child, types.Mutation{
+		Data: json.RawMessage(`{"pk":2,"parent":1}`),
+		Key:  json.RawMessage(`[2]`),
+		Time: t1,
+	}))
+	r.NoError(batch.Accumulate(child, types.Mutation{
+		Key:  json.RawMessage(`[2]`),
+		Time: t2,
+	}))
+	r.NoError(batch.Accumulate(other, types.Mutation{
+		Data: json.RawMessage(`{"pk":3}`),
+		Key:  json.RawMessage(`[3]`),
+		Time: t2,
+	}))
+	r.NoError(acc.AcceptMultiBatch(ctx, batch, &types.AcceptOptions{}))
+
+	// Messages are produced in time order, keyed by the mutation key.
+	type message struct{ topic, key, value string }
+	var got []message
+	for _, msg := range prod.take() {
+		got = append(got, message{msg.Topic, encoded(t, msg.Key), encoded(t, msg.Value)})
+	}
+	r.ElementsMatch([]message{
+		{"cdc.parent", `[1]`, `{"after":{"pk":1},"updated":"100.0000000001"}`},
+		{"cdc.child", `[2]`, `{"after":{"pk":2,"parent":1},"updated":"100.0000000001"}`},
+	}, got[:2])
+	r.ElementsMatch([]message{
+		{"cdc.child", `[2]`, `{"after":null,"updated":"200.0000000000"}`},
+		{"cdc.tbl", `[3]`, `{"after":{"pk":3},"updated":"200.0000000000"}`},
+	}, got[2:])
+
+	// Resolved markers are sent to every partition of the topics to
+	// which the group's tables have been written.
+	group := &types.TableGroup{Name: ident.New("group"), Enclosing: schema}
+	r.NoError(acc.AcceptResolved(ctx, group, t2))
+	r.NoError(acc.AcceptResolved(ctx, group, t1)) // Ignored.
+	r.NoError(acc.produceResolved())
+
+	type marker struct {
+		topic     string
+		partition int32
+		value     string
+	}
+	var markers []marker
+	for _, msg := range prod.take() {
+		r.Nil(msg.Key)
+		r.Equal(resolvedMarker{}, msg.Metadata)
+		markers = append(markers, marker{msg.Topic, msg.Partition, encoded(t, msg.Value)})
+	}
+	const resolved = `{"resolved":"200.0000000000"}`
+	r.ElementsMatch([]marker{
+		{"cdc.child", 0, resolved},
+		{"cdc.child", 1, resolved},
+		{"cdc.child", 2, resolved},
+		{"cdc.parent", 0, resolved},
+	}, markers)
+
+	// No work to do.
+	r.NoError(acc.produceResolved())
+	r.Empty(prod.take())
+
+	// Markers are retried if they cannot be produced.
+	t3 := hlc.New(300, 0)
+	r.NoError(acc.AcceptResolved(ctx, group, t3))
+	prod.fail = true
+	r.ErrorContains(acc.produceResolved(), "broker unavailable")
+	prod.fail = false
+	r.NoError(acc.produceResolved())
+	r.Len(prod.take(), 4)
+
+	// Errors are reported.
+	prod.fail = true
+	r.ErrorContains(acc.AcceptTableBatch(ctx, &types.TableBatch{
+		Data:  []types.Mutation{{Data: json.RawMessage(`{"pk":1}`), Key: json.RawMessage(`[1]`)}},
+		Table: parent,
+	}, nil), "could not produce messages to topic cdc.parent")
+}
+
+func TestEnvelopes(t *testing.T) {
+	table := ident.NewTable(ident.MustSchema(ident.New("db"), ident.New("public")), ident.New("tbl"))
+	ts := hlc.New(1_700_000_000_000_000_000, 2)
+	upsert := types.Mutation{
+		Data: json.RawMessage(`{"pk":1,"val":"one"}`),
+		Key:  json.RawMessage(`[1]`),
+		Time: ts,
+	}
+	update := types.Mutation{
+		Before: json.RawMessage(`{"pk":1,"val":"zero"}`),
+		Data:   json.RawMessage(`{"pk":1,"val":"one"}`),
+		Key:    json.RawMessage(`[1]`),
+		Time:   ts,
+	}
+	deletion := types.Mutation{
+		Key:  json.RawMessage(`[1]`),
+		Time: ts,
+	}
+
+	tcs := []struct {
+		envelope Envelope
+		mut      types.Mutation
+		want     []string // Empty strings are tombstones.
+	}{
+		{EnvelopeWrapped, upsert, []string{
+			`{"after":{"pk":1,"val":"one"},"updated":"1700000000000000000.0000000002"}`,
+		}},
+		{EnvelopeWrapped, update, []string{
+			`{"after":{"pk":1,"val":"one"},"before":{"pk":1,"val":"zero"},"updated":"1700000000000000000.0000000002"}`,
+		}},
+		{EnvelopeWrapped, deletion, []string{
+			`{"after":null,"updated":"1700000000000000000.0000000002"}`,
+		}},
+		{EnvelopeBare, upsert, []string{
+			`{"__crdb__":{"updated":"1700000000000000000.0000000002"},"pk":1,"val":"one"}`,
+		}},
+		{EnvelopeBare, deletion, []string{""}},
+		{EnvelopeDebezium, upsert, []string{
+			`{"after":{"pk":1,"val":"one"},"before":null,"op":"c","source":{"connector":"replicator","schema":"db.public","table":"tbl","ts_ns":1700000000000000000},"ts_ms":0}`,
+		}},
+		{EnvelopeDebezium, update, []string{
+			`{"after":{"pk":1,"val":"one"},"before":{"pk":1,"val":"zero"},"op":"u","source":{"connector":"replicator","schema":"db.public","table":"tbl","ts_ns":1700000000000000000},"ts_ms":0}`,
+		}},
+		{EnvelopeDebezium, deletion, []string{
+			`{"after":null,"before":null,"op":"d","source":{"connector":"replicator","schema":"db.public","table":"tbl","ts_ns":1700000000000000000},"ts_ms":0}`,
+			"",
+		}},
+	}
+
+	for idx, tc := range tcs {
+		t.Run(tc.envelope.String(), func(t *testing.T) {
+			r := require.New(t)
+			values, err := tc.envelope.encodeMutation(table, tc.mut)
+			r.NoError(err, idx)
+			var got []string
+			for _, value := range values {
+				if value == nil {
+					got = append(got, "")
+					continue
+				}
+				// Zero out the processing time.
+				if tc.envelope == EnvelopeDebezium {
+					var evt debezium
+					r.NoError(json.Unmarshal(value, &evt))
+					r.NotZero(evt.TsMs)
+					evt.TsMs = 0
+					value, err = json.Marshal(&evt)
+					r.NoError(err)
+				}
+				got = append(got, string(value))
+			}
+			r.Equal(tc.want, got, idx)
+		})
+	}
+
+	t.Run("resolved", func(t *testing.T) {
+		r := require.New(t)
+		ts := hlc.New(10, 1)
+		buf, err := EnvelopeWrapped.encodeResolved(ts)
+		r.NoError(err)
+		r.Equal(`{"resolved":"10.0000000001"}`, string(buf))
+		buf, err = EnvelopeBare.encodeResolved(ts)
+		r.NoError(err)
+		r.Equal(`{"__crdb__":{"resolved":"10.0000000001"}}`, string(buf))
+		buf, err = EnvelopeDebezium.encodeResolved(ts)
+		r.NoError(err)
+		r.Nil(buf)
+	})
+}
+
+func TestEnvelopeFlag(t *testing.T) {
+	r := require.New(t)
+	var e Envelope
+	r.NoError(e.Set("BARE"))
+	r.Equal(EnvelopeBare, e)
+	r.NoError(e.Set(""))
+	r.Equal(EnvelopeWrapped, e)
+	r.ErrorContains(e.Set("nope"), `invalid envelope "nope"`)
+	r.Equal([]string{"wrapped", "bare", "debezium"}, Envelopes())
+}
+
+func TestPartitioner(t *testing.T) {
+	r := require.New(t)
+	p := newPartitioner("topic")
+
+	part, err := p.Partition(&sarama.ProducerMessage{
+		Partition: 3,
+		Metadata:  resolvedMarker{},
+	}, 4)
+	r.NoError(err)
+	r.Equal(int32(3), part)
+
+	// Keyed messages are consistently hashed.
+	msg := &sarama.ProducerMessage{Key: sarama.StringEncoder(`[1]`)}
+	first, err := p.Partition(msg, 4)
+	r.NoError(err)
+	for range 10 {
+		next, err := p.Partition(msg, 4)
+		r.NoError(err)
+		r.Equal(first, next)
+	}
+}
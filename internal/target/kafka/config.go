@@ -0,0 +1,131 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package kafka
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/IBM/sarama"
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+)
+
+// Envelope determines how mutations are encoded into Kafka messages.
+type Envelope int
+
+//go:generate go run golang.org/x/tools/cmd/stringer -type=Envelope -trimprefix Envelope
+
+const (
+	// EnvelopeWrapped is the default, CockroachDB changefeed-style
+	// envelope which places the row data in an "after" field. It can
+	// be consumed by Replicator's kafka source using the crdb format.
+	EnvelopeWrapped Envelope = iota
+	// EnvelopeBare places the row data at the top level of the
+	// message, with the timestamp in a "__crdb__" field.
+	EnvelopeBare
+	// EnvelopeDebezium emits Debezium-style change events. Resolved
+	// markers are not emitted, since Debezium has no equivalent.
+	EnvelopeDebezium
+)
+
+// Envelopes returns the available envelope formats.
+func Envelopes() []string {
+	var res []string
+	for i := EnvelopeWrapped; i <= EnvelopeDebezium; i++ {
+		res = append(res, strings.ToLower(i.String()))
+	}
+	return res
+}
+
+var _ pflag.Value = new(Envelope)
+
+// Set implements pflag.Value.
+func (e *Envelope) Set(value string) error {
+	switch strings.ToLower(value) {
+	case "", "wrapped":
+		*e = EnvelopeWrapped
+	case "bare":
+		*e = EnvelopeBare
+	case "debezium":
+		*e = EnvelopeDebezium
+	default:
+		return errors.Errorf("invalid envelope %q", value)
+	}
+	return nil
+}
+
+// Type implements pflag.Value.
+func (e Envelope) Type() string {
+	return fmt.Sprintf("%T", e)
+}
+
+const defaultResolvedInterval = 5 * time.Second
+
+// Config controls the production of mutations to Kafka. The Kafka
+// target is enabled by providing at least one broker address.
+type Config struct {
+	Brokers          []string      // The addresses of the Kafka brokers.
+	Envelope         Envelope      // The message encoding.
+	ResolvedInterval time.Duration // Minimum duration between resolved markers.
+	TopicPrefix      string        // Prepended to table names.
+}
+
+// Bind adds flags to the set.
+func (c *Config) Bind(f *pflag.FlagSet) {
+	f.StringArrayVar(&c.Brokers, "targetKafkaBroker", nil,
+		"address of Kafka broker(s); if set, mutations will be produced to Kafka "+
+			"instead of being applied to the target database, which is used only "+
+			"to describe the target tables")
+	f.Var(&c.Envelope, "targetKafkaEnvelope",
+		fmt.Sprintf("the envelope format of Kafka messages: %s", strings.Join(Envelopes(), ", ")))
+	f.DurationVar(&c.ResolvedInterval, "targetKafkaResolvedInterval", defaultResolvedInterval,
+		"the minimum duration between resolved-timestamp messages")
+	f.StringVar(&c.TopicPrefix, "targetKafkaTopicPrefix", "",
+		"a prefix to add to the table name to form the Kafka topic")
+}
+
+// Enabled returns true if Kafka brokers have been configured.
+func (c *Config) Enabled() bool {
+	return len(c.Brokers) > 0
+}
+
+// Preflight ensures that unset configuration options have sane
+// defaults and returns an error if the Config is invalid.
+func (c *Config) Preflight() error {
+	if !c.Enabled() {
+		return nil
+	}
+	if c.ResolvedInterval == 0 {
+		c.ResolvedInterval = defaultResolvedInterval
+	}
+	if c.ResolvedInterval < 0 {
+		return errors.New("targetKafkaResolvedInterval must not be negative")
+	}
+	return nil
+}
+
+// saramaConfig returns the producer configuration.
+func (c *Config) saramaConfig() *sarama.Config {
+	sc := sarama.NewConfig()
+	sc.Producer.Partitioner = newPartitioner
+	sc.Producer.RequiredAcks = sarama.WaitForAll
+	sc.Producer.Return.Errors = true
+	sc.Producer.Return.Successes = true
+	return sc
+}
@@ -0,0 +1,157 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package kafka
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/hlc"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/pkg/errors"
+)
+
+// crdbMeta is the metadata field added by the bare envelope.
+const crdbMeta = "__crdb__"
+
+// wrapped is the encoding of the wrapped envelope.
+type wrapped struct {
+	After   json.RawMessage `json:"after"`
+	Before  json.RawMessage `json:"before,omitempty"`
+	Updated string          `json:"updated"`
+}
+
+// debezium is the encoding of a Debezium change event.
+type debezium struct {
+	After  json.RawMessage `json:"after"`
+	Before json.RawMessage `json:"before"`
+	Op     string          `json:"op"`
+	Source debeziumSource  `json:"source"`
+	TsMs   int64           `json:"ts_ms"`
+}
+
+// debeziumSource is the source block of a Debezium change event.
+type debeziumSource struct {
+	Connector string `json:"connector"`
+	Schema    string `json:"schema"`
+	Table     string `json:"table"`
+	TsNs      int64  `json:"ts_ns"`
+}
+
+// encodeMutation returns the message values to produce for the
+// mutation. A nil element represents a tombstone.
+func (e Envelope) encodeMutation(table ident.Table, mut types.Mutation) ([][]byte, error) {
+	var after json.RawMessage
+	if !mut.IsDelete() {
+		after = mut.Data
+	}
+	var before json.RawMessage
+	if len(mut.Before) > 0 && string(mut.Before) != "null" {
+		before = mut.Before
+	}
+
+	switch e {
+	case EnvelopeWrapped:
+		if after == nil {
+			after = json.RawMessage("null")
+		}
+		buf, err := json.Marshal(&wrapped{
+			After:   after,
+			Before:  before,
+			Updated: mut.Time.String(),
+		})
+		return [][]byte{buf}, errors.WithStack(err)
+
+	case EnvelopeBare:
+		// A deletion is represented by a tombstone.
+		if after == nil {
+			return [][]byte{nil}, nil
+		}
+		var row map[string]json.RawMessage
+		if err := json.Unmarshal(after, &row); err != nil {
+			return nil, errors.Wrapf(err, "could not decode row for %s", table)
+		}
+		meta, err := json.Marshal(map[string]string{"updated": mut.Time.String()})
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		row[crdbMeta] = meta
+		buf, err := json.Marshal(row)
+		return [][]byte{buf}, errors.WithStack(err)
+
+	case EnvelopeDebezium:
+		// We can only distinguish inserts from updates if the source
+		// provided a before image.
+		op := "c"
+		if after == nil {
+			op = "d"
+		} else if before != nil {
+			op = "u"
+		}
+		evt := &debezium{
+			After:  after,
+			Before: before,
+			Op:     op,
+			Source: debeziumSource{
+				Connector: "replicator",
+				Schema:    table.Schema().Raw(),
+				Table:     table.Table().Raw(),
+				TsNs:      mut.Time.Nanos(),
+			},
+			TsMs: time.Now().UnixMilli(),
+		}
+		if evt.After == nil {
+			evt.After = json.RawMessage("null")
+		}
+		if evt.Before == nil {
+			evt.Before = json.RawMessage("null")
+		}
+		buf, err := json.Marshal(evt)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		// A delete event is followed by a tombstone, to allow log
+		// compaction.
+		if op == "d" {
+			return [][]byte{buf, nil}, nil
+		}
+		return [][]byte{buf}, nil
+
+	default:
+		return nil, errors.Errorf("unimplemented envelope %s", e)
+	}
+}
+
+// encodeResolved returns the message value for a resolved marker. A
+// nil value indicates that the envelope does not support markers.
+func (e Envelope) encodeResolved(ts hlc.Time) ([]byte, error) {
+	switch e {
+	case EnvelopeWrapped:
+		buf, err := json.Marshal(map[string]string{"resolved": ts.String()})
+		return buf, errors.WithStack(err)
+	case EnvelopeBare:
+		buf, err := json.Marshal(map[string]map[string]string{
+			crdbMeta: {"resolved": ts.String()},
+		})
+		return buf, errors.WithStack(err)
+	case EnvelopeDebezium:
+		return nil, nil
+	default:
+		return nil, errors.Errorf("unimplemented envelope %s", e)
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package kafka
+
+import (
+	"github.com/cockroachdb/replicator/internal/util/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	produceDurations = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "target_kafka_produce_duration_seconds",
+		Help:    "the length of time it took to produce mutations to Kafka",
+		Buckets: metrics.LatencyBuckets,
+	}, metrics.TableLabels)
+	produceErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "target_kafka_produce_errors_total",
+		Help: "the number of times an error was encountered while producing mutations to Kafka",
+	}, metrics.TableLabels)
+	produceMessages = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "target_kafka_messages_total",
+		Help: "the number of messages produced to Kafka",
+	}, metrics.TableLabels)
+	resolvedMarkers = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "target_kafka_resolved_markers_total",
+		Help: "the number of resolved markers produced to Kafka partitions",
+	})
+)
@@ -42,6 +42,7 @@ var templateFS embed.FS
 // This type is also used by the templates.
 type demand struct {
 	Bags          []*merge.Bag        // The containers to populate.
+	Current       ident.Ident         // Optional boolean column to filter rows.
 	PKs           []*types.ColData    // The primary key columns to query.
 	PKData        [][]any             // ( Row x Col ) PK values.
 	Product       types.Product       // Target database info.
@@ -57,6 +58,10 @@ func (d *demand) Key() string {
 	sb.WriteString(strconv.Itoa(len(d.Bags)))
 	sb.WriteRune(':')
 	sb.WriteString(d.Table.Raw())
+	if !d.Current.Empty() {
+		sb.WriteString(":current=")
+		sb.WriteString(d.Current.Raw())
+	}
 	for _, col := range d.SelectCols {
 		sb.WriteRune(':')
 		sb.WriteString(col.Name.Raw())
@@ -90,6 +95,34 @@ type Loader struct {
 // by [crep.Canonical] to ensure reasonably consistent behavior.
 func (l *Loader) Load(
 	ctx context.Context, tx types.TargetQuerier, table ident.Table, bags []*merge.Bag,
+) (*Result, error) {
+	return l.loadBags(ctx, tx, table, bags, ident.Ident{})
+}
+
+// LoadCurrent is like Load, but only considers rows in the target
+// table where the given boolean column is true. This allows a
+// versioned table, whose primary key is a superset of the key columns
+// in the property bags, to be queried for the current version of a
+// row.
+func (l *Loader) LoadCurrent(
+	ctx context.Context,
+	tx types.TargetQuerier,
+	table ident.Table,
+	bags []*merge.Bag,
+	current ident.Ident,
+) (*Result, error) {
+	if current.Empty() {
+		return nil, errors.New("a current-version column must be specified")
+	}
+	return l.loadBags(ctx, tx, table, bags, current)
+}
+
+func (l *Loader) loadBags(
+	ctx context.Context,
+	tx types.TargetQuerier,
+	table ident.Table,
+	bags []*merge.Bag,
+	current ident.Ident,
 ) (*Result, error) {
 	start := time.Now()
 	demands := make(map[string]*demand)
@@ -151,6 +184,7 @@ func (l *Loader) Load(
 		// Otherwise, populate a new demand request.
 		work := &demand{
 			Bags:          []*merge.Bag{bag},
+			Current:       current,
 			PKData:        [][]any{pkData},
 			Product:       l.pool.Product,
 			SelectTargets: [][]*merge.Entry{selectTargets},
@@ -50,6 +50,8 @@ func ProvideLoader(statements *types.TargetStatements, target *types.TargetPool)
 		l.selectTemplate = templates.Lookup("my.tmpl")
 	case types.ProductOracle:
 		l.selectTemplate = templates.Lookup("ora.tmpl")
+	case types.ProductSQLServer:
+		l.selectTemplate = templates.Lookup("mssql.tmpl")
 	default:
 		return nil, errors.Errorf("unimplemented product: %s", target.Product)
 	}
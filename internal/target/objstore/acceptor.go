@@ -0,0 +1,416 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package objstore contains a non-SQL target which writes mutations to
+// an object store, using the same layout as a CockroachDB changefeed
+// with a cloud-storage sink. The files may be read by Replicator's
+// objstore source. The target database is still used to describe the
+// target tables, so that userscripts and sequencers operate as they
+// would for a SQL target.
+package objstore
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"net/url"
+	"path"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/replicator/internal/source/objstore/bucket"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/hlc"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/cockroachdb/replicator/internal/util/metrics"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// Acceptor buffers mutations in memory, in one file per target table.
+// A file is written to the bucket once it reaches the configured size
+// or age. When a table group's checkpoint is committed, the buffered
+// mutations within the group, at or before the resolved timestamp, are
+// written, followed by a resolved-timestamp marker. Readers which
+// process files in lexical order will therefore see every mutation at
+// or before the resolved timestamp before they see the marker.
+//
+// The files for each target schema are written into a separate folder
+// under the configured prefix, so that the resolved-timestamp markers
+// for one schema do not describe the files of another.
+//
+// Each object is written in its entirety, so readers will never
+// observe a partial file. Mutations which are buffered when the
+// process exits will not be written; it is expected that a checkpoint
+// will be committed soon after mutations are accepted. Any
+// [types.TargetQuerier] passed in the [types.AcceptOptions] is
+// ignored.
+type Acceptor struct {
+	bucket  bucket.Writer
+	cfg     *Config
+	fileID  atomic.Uint32 // Distinguishes files with the same timestamp.
+	session string        // Distinguishes files written by this process.
+
+	// Data files are written while holding a read lock. Resolved
+	// markers are written while holding the write lock, so that any
+	// data files which are being concurrently written will precede
+	// the marker.
+	writing sync.RWMutex
+
+	mu struct {
+		sync.Mutex
+		files    ident.TableMap[*file]
+		resolved ident.Map[hlc.Time]
+	}
+}
+
+// file accumulates encoded mutations for a single table.
+type file struct {
+	lines  []line
+	opened time.Time
+	size   int
+}
+
+// line is a single, encoded mutation.
+type line struct {
+	data []byte
+	time hlc.Time
+}
+
+var (
+	_ types.MultiAcceptor    = (*Acceptor)(nil)
+	_ types.ResolvedAcceptor = (*Acceptor)(nil)
+)
+
+// NewAcceptor opens the configured bucket. Buffered files will be
+// rotated in the background until the context is stopped.
+func NewAcceptor(ctx *stopper.Context, cfg *Config) (*Acceptor, error) {
+	if err := cfg.Preflight(); err != nil {
+		return nil, err
+	}
+	b, err := cfg.newBucket()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not open bucket")
+	}
+	ret := newAcceptor(cfg, b)
+	ret.rotateLoop(ctx)
+	return ret, nil
+}
+
+func newAcceptor(cfg *Config, b bucket.Writer) *Acceptor {
+	return &Acceptor{
+		bucket:  b,
+		cfg:     cfg,
+		session: fmt.Sprintf("%016x", rand.Uint64()),
+	}
+}
+
+// AcceptMultiBatch implements [types.MultiAcceptor].
+func (a *Acceptor) AcceptMultiBatch(
+	ctx context.Context, batch *types.MultiBatch, opts *types.AcceptOptions,
+) error {
+	for _, temporal := range batch.Data {
+		if err := a.AcceptTemporalBatch(ctx, temporal, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AcceptTemporalBatch implements [types.TemporalAcceptor].
+func (a *Acceptor) AcceptTemporalBatch(
+	ctx context.Context, batch *types.TemporalBatch, opts *types.AcceptOptions,
+) error {
+	for _, table := range batch.Data.All() {
+		if err := a.AcceptTableBatch(ctx, table, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AcceptTableBatch implements [types.TableAcceptor]. The mutations are
+// buffered, and the table's file is written if it should be rotated.
+func (a *Acceptor) AcceptTableBatch(
+	ctx context.Context, batch *types.TableBatch, _ *types.AcceptOptions,
+) error {
+	if len(batch.Data) == 0 {
+		return nil
+	}
+	lines := make([]line, len(batch.Data))
+	for idx, mut := range batch.Data {
+		data, err := encodeMutation(mut)
+		if err != nil {
+			return errors.Wrapf(err, "could not encode mutation for %s", batch.Table)
+		}
+		lines[idx] = line{data, mut.Time}
+	}
+
+	now := time.Now()
+	a.mu.Lock()
+	f, ok := a.mu.files.Get(batch.Table)
+	if !ok {
+		f = &file{opened: now}
+		a.mu.files.Put(batch.Table, f)
+	}
+	for _, l := range lines {
+		f.lines = append(f.lines, l)
+		f.size += len(l.data) + 1
+	}
+	rotate := a.shouldRotate(f, now)
+	if rotate {
+		a.mu.files.Delete(batch.Table)
+	}
+	a.mu.Unlock()
+
+	if !rotate {
+		return nil
+	}
+	a.writing.RLock()
+	defer a.writing.RUnlock()
+	if err := a.writeFile(ctx, batch.Table, f.lines); err != nil {
+		// The mutations have been buffered, so we don't want the
+		// batch to be retried. The file will be written by the next
+		// rotation or checkpoint.
+		log.WithError(err).Warnf("could not rotate file for %s; will retry", batch.Table)
+		a.requeue(batch.Table, f.lines)
+	}
+	return nil
+}
+
+// AcceptResolved implements [types.ResolvedAcceptor]. All buffered
+// mutations within the group's schema, at or before the timestamp, are
+// written to the bucket, followed by a resolved-timestamp marker in the
+// schema's folder.
+func (a *Acceptor) AcceptResolved(ctx context.Context, group *types.TableGroup, ts hlc.Time) error {
+	schema, err := group.Schema()
+	if err != nil {
+		return err
+	}
+	a.mu.Lock()
+	if last, ok := a.mu.resolved.Get(group.Name); ok && hlc.Compare(last, ts) >= 0 {
+		a.mu.Unlock()
+		return nil
+	}
+	var work ident.TableMap[[]line]
+	for table, f := range a.mu.files.All() {
+		if !ident.Equal(table.Schema(), schema) {
+			continue
+		}
+		var keep []line
+		var ready []line
+		keepSize := 0
+		for _, l := range f.lines {
+			if hlc.Compare(l.time, ts) <= 0 {
+				ready = append(ready, l)
+			} else {
+				keep = append(keep, l)
+				keepSize += len(l.data) + 1
+			}
+		}
+		if len(ready) == 0 {
+			continue
+		}
+		work.Put(table, ready)
+		if len(keep) == 0 {
+			a.mu.files.Delete(table)
+		} else {
+			f.lines = keep
+			f.size = keepSize
+		}
+	}
+	a.mu.Unlock()
+
+	if err := a.writeFiles(ctx, &work); err != nil {
+		return err
+	}
+
+	// Wait for any concurrent writes of data files to complete.
+	a.writing.Lock()
+	defer a.writing.Unlock()
+	if err := a.writeResolved(ctx, schema, ts); err != nil {
+		return errors.Wrapf(err, "could not write resolved marker for %s", group)
+	}
+	a.mu.Lock()
+	a.mu.resolved.Put(group.Name, ts)
+	a.mu.Unlock()
+	log.Tracef("wrote resolved marker for %s at %s", group, ts)
+	return nil
+}
+
+// rotateLoop starts a goroutine to periodically write files which have
+// exceeded the configured age.
+func (a *Acceptor) rotateLoop(ctx *stopper.Context) {
+	ctx.Go(func(ctx *stopper.Context) error {
+		// Check more often than the interval, so that files are not
+		// held for up to twice the interval.
+		ticker := time.NewTicker(max(a.cfg.FileInterval/4, time.Second))
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Stopping():
+				return nil
+			case <-ticker.C:
+			}
+			if err := a.rotateAged(ctx, time.Now()); err != nil {
+				log.WithError(err).Warn("could not write files to bucket; will retry")
+			}
+		}
+	})
+}
+
+// rotateAged writes any files which should be rotated at the given
+// time.
+func (a *Acceptor) rotateAged(ctx context.Context, now time.Time) error {
+	var work ident.TableMap[[]line]
+	a.mu.Lock()
+	for table, f := range a.mu.files.All() {
+		if a.shouldRotate(f, now) {
+			work.Put(table, f.lines)
+			a.mu.files.Delete(table)
+		}
+	}
+	a.mu.Unlock()
+	return a.writeFiles(ctx, &work)
+}
+
+// shouldRotate returns true if the file has reached the configured size
+// or age.
+func (a *Acceptor) shouldRotate(f *file, now time.Time) bool {
+	if a.cfg.FileSize > 0 && f.size >= a.cfg.FileSize {
+		return true
+	}
+	return a.cfg.FileInterval > 0 && now.Sub(f.opened) >= a.cfg.FileInterval
+}
+
+// writeFiles writes a file for each table. Any lines which could not be
+// written will be requeued.
+func (a *Acceptor) writeFiles(ctx context.Context, work *ident.TableMap[[]line]) error {
+	a.writing.RLock()
+	defer a.writing.RUnlock()
+	var firstErr error
+	for table, lines := range work.All() {
+		if firstErr == nil {
+			firstErr = a.writeFile(ctx, table, lines)
+			if firstErr == nil {
+				continue
+			}
+		}
+		a.requeue(table, lines)
+	}
+	return firstErr
+}
+
+// writeFile writes the lines into a single object.
+func (a *Acceptor) writeFile(ctx context.Context, table ident.Table, lines []line) error {
+	start := time.Now()
+	labels := metrics.TableValues(table)
+	minTime := lines[0].time
+	var buf bytes.Buffer
+	for _, l := range lines {
+		if hlc.Compare(l.time, minTime) < 0 {
+			minTime = l.time
+		}
+		buf.Write(l.data)
+		buf.WriteByte('\n')
+	}
+	name, err := a.fileName(table, minTime, a.fileID.Add(1))
+	if err != nil {
+		return err
+	}
+	if err := a.bucket.Put(ctx, name, buf.Bytes()); err != nil {
+		writeErrors.WithLabelValues(labels...).Inc()
+		return errors.Wrapf(err, "could not write %s", name)
+	}
+	writeDurations.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+	writeBytes.WithLabelValues(labels...).Add(float64(buf.Len()))
+	writeFiles.WithLabelValues(labels...).Inc()
+	writeMutations.WithLabelValues(labels...).Add(float64(len(lines)))
+	log.Tracef("wrote %d mutations to %s", len(lines), name)
+	return nil
+}
+
+// writeResolved writes a resolved-timestamp marker for the schema.
+func (a *Acceptor) writeResolved(ctx context.Context, schema ident.Schema, ts hlc.Time) error {
+	name, err := a.resolvedName(schema, ts)
+	if err != nil {
+		return err
+	}
+	content, err := encodeResolved(ts)
+	if err != nil {
+		return err
+	}
+	if err := a.bucket.Put(ctx, name, content); err != nil {
+		return err
+	}
+	resolvedMarkers.Inc()
+	return nil
+}
+
+// requeue returns lines which could not be written to the table's
+// buffer.
+func (a *Acceptor) requeue(table ident.Table, lines []line) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	f, ok := a.mu.files.Get(table)
+	if !ok {
+		f = &file{opened: time.Now()}
+		a.mu.files.Put(table, f)
+	}
+	f.lines = append(lines, f.lines...)
+	f.size = 0
+	for _, l := range f.lines {
+		f.size += len(l.data) + 1
+	}
+}
+
+// fileName returns the path of a data file, using the same format as
+// a changefeed: [timestamp]-[uniquer]-[topic]-[schema-id].ndjson
+func (a *Acceptor) fileName(table ident.Table, ts hlc.Time, id uint32) (string, error) {
+	dir, stamp, err := a.timestampPath(table.Schema(), ts)
+	if err != nil {
+		return "", err
+	}
+	// The uniquer has four components, as expected by the source.
+	name := fmt.Sprintf("%s-%s-1-0-%08x-%s-1.ndjson", stamp, a.session, id, table.Table().Raw())
+	return path.Join(dir, name), nil
+}
+
+// resolvedName returns the path of a resolved-timestamp marker.
+func (a *Acceptor) resolvedName(schema ident.Schema, ts hlc.Time) (string, error) {
+	dir, stamp, err := a.timestampPath(schema, ts)
+	if err != nil {
+		return "", err
+	}
+	return path.Join(dir, stamp+".RESOLVED"), nil
+}
+
+// timestampPath returns the folder for a timestamp within the schema's
+// folder and its encoding as YYYYMMDDHHMMSSNNNNNNNNNLLLLLLLLLL, where N
+// are the nanoseconds and L the logical clock.
+func (a *Acceptor) timestampPath(schema ident.Schema, ts hlc.Time) (dir, stamp string, _ error) {
+	wall := time.Unix(0, ts.Nanos()).UTC()
+	partition, err := a.cfg.PartitionFormat.Dir(wall)
+	if err != nil {
+		return "", "", err
+	}
+	stamp = fmt.Sprintf("%s%09d%010d",
+		wall.Format("20060102150405"), wall.Nanosecond(), ts.Logical())
+	return path.Join(a.cfg.prefix, url.PathEscape(schema.Raw()), partition), stamp, nil
+}
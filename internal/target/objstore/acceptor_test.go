@@ -0,0 +1,300 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package objstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/replicator/internal/source/objstore/bucket"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/cdcjson"
+	"github.com/cockroachdb/replicator/internal/util/hlc"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+// The pattern used by the objstore source to parse file names.
+var fileRegex = regexp.MustCompile(`^(?P<prelude>([^-]+-){5})(?P<topic>.+)-(?P<schema_id>[^-.]+)\.(?P<ext>[^-]+)$`)
+
+// memBucket is an in-memory bucket.Writer.
+type memBucket struct {
+	fail  bool
+	mu    sync.Mutex
+	files map[string][]byte
+}
+
+var _ bucket.Writer = (*memBucket)(nil)
+
+func (b *memBucket) Put(_ context.Context, path string, content []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.fail {
+		return errors.New("bucket unavailable")
+	}
+	if b.files == nil {
+		b.files = make(map[string][]byte)
+	}
+	b.files[path] = bytes.Clone(content)
+	return nil
+}
+
+// take returns the names of the files, in lexical order, and clears the
+// bucket.
+func (b *memBucket) take() ([]string, map[string][]byte) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	files := b.files
+	b.files = nil
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, files
+}
+
+// decode parses the content of a data file.
+func decode(t *testing.T, content []byte) []types.Mutation {
+	t.Helper()
+	var ret []types.Mutation
+	for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+		mut, err := cdcjson.BulkMutationReader()(strings.NewReader(line))
+		require.NoError(t, err)
+		ret = append(ret, mut)
+	}
+	return ret
+}
+
+func TestAcceptor(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+
+	schema := ident.MustSchema(ident.New("db"), ident.New("public"))
+	parent := ident.NewTable(schema, ident.New("parent"))
+	child := ident.NewTable(schema, ident.New("child"))
+	other := ident.NewTable(ident.MustSchema(ident.New("other")), ident.New("tbl"))
+
+	b := &memBucket{}
+	cfg := &Config{
+		FileInterval: time.Hour,
+		FileSize:     1 << 20,
+		prefix:       "prefix",
+	}
+	acc := newAcceptor(cfg, b)
+
+	// 2024-01-02T03:04:05Z
+	base := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC).UnixNano()
+	t1 := hlc.New(base+1, 1)
+	t2 := hlc.New(base+2, 0)
+	batch := &types.MultiBatch{}
+	r.NoError(batch.Accumulate(parent, types.Mutation{
+		Data: json.RawMessage(`{"pk":1}`),
+		Key:  json.RawMessage(`[1]`),
+		Time: t1,
+	}))
+	r.NoError(batch.Accumulate(child, types.Mutation{
+		Before: json.RawMessage(`{"pk":2,"parent":0}`),
+		Data:   json.RawMessage(`{"pk":2,"parent":1}`),
+		Key:    json.RawMessage(`[2]`),
+		Time:   t1,
+	}))
+	r.NoError(batch.Accumulate(child, types.Mutation{
+		Key:  json.RawMessage(`[2]`),
+		Time: t2,
+	}))
+	r.NoError(batch.Accumulate(other, types.Mutation{
+		Data: json.RawMessage(`{"pk":3}`),
+		Key:  json.RawMessage(`[3]`),
+		Time: t1,
+	}))
+	r.NoError(acc.AcceptMultiBatch(ctx, batch, &types.AcceptOptions{}))
+
+	// Nothing is written until the files are rotated.
+	names, _ := b.take()
+	r.Empty(names)
+
+	// Only the mutations in the group, at or before the resolved
+	// timestamp, are written before the marker.
+	group := &types.TableGroup{Name: ident.New("group"), Enclosing: schema}
+	r.NoError(acc.AcceptResolved(ctx, group, t1))
+	names, files := b.take()
+	r.Len(names, 3)
+	// The data files precede the marker, in an arbitrary order.
+	r.Equal("prefix/db.public/2024-01-02/202401020304050000000010000000001.RESOLVED", names[2])
+	byTopic := make(map[string]string)
+	for _, name := range names[:2] {
+		r.Regexp(`^prefix/db.public/2024-01-02/202401020304050000000010000000001-[0-9a-f]{16}-1-0-[0-9a-f]{8}-`, name)
+		match := fileRegex.FindStringSubmatch(strings.TrimPrefix(name, "prefix/db.public/2024-01-02/"))
+		r.NotNil(match, name)
+		byTopic[match[fileRegex.SubexpIndex("topic")]] = name
+	}
+	r.Equal([]types.Mutation{{
+		Before: json.RawMessage(`{"pk":2,"parent":0}`),
+		Data:   json.RawMessage(`{"pk":2,"parent":1}`),
+		Key:    json.RawMessage(`[2]`),
+		Time:   t1,
+	}}, decode(t, files[byTopic["child"]]))
+	r.Equal([]types.Mutation{{
+		Data: json.RawMessage(`{"pk":1}`),
+		Key:  json.RawMessage(`[1]`),
+		Time: t1,
+	}}, decode(t, files[byTopic["parent"]]))
+	ts, err := (&cdcjson.NDJsonParser{}).Resolved(bytes.NewReader(files[names[2]]))
+	r.NoError(err)
+	r.Equal(t1, ts)
+
+	// Repeated or older markers are ignored.
+	r.NoError(acc.AcceptResolved(ctx, group, t1))
+	names, _ = b.take()
+	r.Empty(names)
+
+	// The deletion is written as a null after block.
+	r.NoError(acc.AcceptResolved(ctx, group, t2))
+	names, files = b.take()
+	r.Len(names, 2)
+	r.Contains(names[0], "-child-1.ndjson")
+	r.Equal(`{"after":null,"key":[2],"updated":"`+t2.String()+`"}`+"\n", string(files[names[0]]))
+	r.True(strings.HasSuffix(names[1], ".RESOLVED"))
+
+	// Mutations outside the group have been retained.
+	acc.mu.Lock()
+	_, found := acc.mu.files.Get(other)
+	r.Equal(1, acc.mu.files.Len())
+	acc.mu.Unlock()
+	r.True(found)
+
+	// Errors are reported and the mutations are retained.
+	otherGroup := &types.TableGroup{Name: ident.New("other"), Tables: []ident.Table{other}}
+	b.fail = true
+	r.ErrorContains(acc.AcceptResolved(ctx, otherGroup, t2), "bucket unavailable")
+	b.fail = false
+	r.NoError(acc.AcceptResolved(ctx, otherGroup, t2))
+	names, _ = b.take()
+	r.Len(names, 2)
+	r.Contains(names[0], "-tbl-1.ndjson")
+
+	// Each schema has its own folder, so that its marker does not
+	// describe the files of another schema.
+	r.Regexp(`^prefix/other/2024-01-02/`, names[0])
+	r.Equal("prefix/other/2024-01-02/202401020304050000000020000000000.RESOLVED", names[1])
+}
+
+func TestRotation(t *testing.T) {
+	r := require.New(t)
+	ctx := context.Background()
+
+	table := ident.NewTable(ident.MustSchema(ident.New("db")), ident.New("tbl"))
+	b := &memBucket{}
+	cfg := &Config{
+		FileInterval:    time.Minute,
+		FileSize:        100,
+		PartitionFormat: bucket.Hourly,
+	}
+	acc := newAcceptor(cfg, b)
+
+	base := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC).UnixNano()
+	accept := func(pk int) {
+		r.NoError(acc.AcceptTableBatch(ctx, &types.TableBatch{
+			Data: []types.Mutation{{
+				Data: json.RawMessage(`{"pk":1}`),
+				Key:  json.RawMessage(`[1]`),
+				Time: hlc.New(base+int64(pk), 0),
+			}},
+			Table: table,
+		}, nil))
+	}
+
+	// Each line is about 60 bytes, so the second will cause the file
+	// to be rotated.
+	accept(1)
+	names, _ := b.take()
+	r.Empty(names)
+	accept(2)
+	names, files := b.take()
+	r.Len(names, 1)
+	r.Regexp(`^db/2024-01-02/03/20240102030405000000001`, names[0])
+	r.Len(decode(t, files[names[0]]), 2)
+
+	// Files are rotated once they are old enough.
+	accept(3)
+	r.NoError(acc.rotateAged(ctx, time.Now()))
+	names, _ = b.take()
+	r.Empty(names)
+	r.NoError(acc.rotateAged(ctx, time.Now().Add(time.Minute)))
+	names, _ = b.take()
+	r.Len(names, 1)
+
+	// A failed rotation retains the mutations.
+	b.fail = true
+	accept(4)
+	accept(5)
+	b.fail = false
+	r.NoError(acc.rotateAged(ctx, time.Now()))
+	names, files = b.take()
+	r.Len(names, 1)
+	r.Len(decode(t, files[names[0]]), 2)
+}
+
+func TestConfig(t *testing.T) {
+	tcs := []struct {
+		url     string
+		local   string
+		prefix  string
+		bucket  string
+		wantErr string
+	}{
+		{url: "file:///tmp/out", local: "/tmp/out"},
+		{url: "s3://bucket/a/b?AWS_ENDPOINT=http://localhost:9000", prefix: "a/b", bucket: "bucket"},
+		{url: "s3:///a/b", wantErr: "missing bucket name"},
+		{url: "file://", wantErr: "missing directory"},
+		{url: "ftp://host/x", wantErr: `unsupported targetStorageURL scheme "ftp"`},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.url, func(t *testing.T) {
+			r := require.New(t)
+			cfg := &Config{StorageURL: tc.url}
+			err := cfg.Preflight()
+			if tc.wantErr != "" {
+				r.ErrorContains(err, tc.wantErr)
+				return
+			}
+			r.NoError(err)
+			r.Equal(defaultFileInterval, cfg.FileInterval)
+			r.Equal(defaultFileSize, cfg.FileSize)
+			r.Equal(tc.local, cfg.local)
+			r.Equal(tc.prefix, cfg.prefix)
+			if tc.bucket != "" {
+				r.Equal(tc.bucket, cfg.s3.Bucket)
+				r.True(cfg.s3.Insecure)
+			}
+		})
+	}
+
+	// The target is disabled by default.
+	cfg := &Config{}
+	require.False(t, cfg.Enabled())
+	require.NoError(t, cfg.Preflight())
+}
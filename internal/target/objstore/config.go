@@ -0,0 +1,154 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package objstore
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/replicator/internal/source/objstore/bucket"
+	"github.com/cockroachdb/replicator/internal/source/objstore/providers/local"
+	"github.com/cockroachdb/replicator/internal/source/objstore/providers/s3"
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+)
+
+const (
+	defaultEndpoint     = "https://s3.amazonaws.com"
+	defaultFileInterval = time.Minute
+	defaultFileSize     = 16 << 20 // 16 MiB
+)
+
+// Config controls the writing of changefeed-style files to an object
+// store. The target is enabled by providing a storage URL.
+type Config struct {
+	FileInterval    time.Duration          // Maximum age of a file before it is rotated.
+	FileSize        int                    // Size of a file, in bytes, at which it is rotated.
+	PartitionFormat bucket.PartitionFormat // How files are organized into folders.
+	StorageURL      string                 // The bucket, and an optional prefix, to write to.
+
+	// The following are computed.
+	local  string     // The root directory, if writing to local storage.
+	prefix string     // Prepended to the names of the objects.
+	s3     *s3.Config // The S3 configuration, if writing to S3.
+}
+
+// Bind adds flags to the set.
+func (c *Config) Bind(f *pflag.FlagSet) {
+	f.DurationVar(&c.FileInterval, "targetStorageFileInterval", defaultFileInterval,
+		"the maximum length of time that mutations are buffered before a file is written")
+	f.IntVar(&c.FileSize, "targetStorageFileSize", defaultFileSize,
+		"the size, in bytes, at which a buffered file is written")
+	f.Var(&c.PartitionFormat, "targetStoragePartitionFormat",
+		fmt.Sprintf("how changefeed file paths are partitioned: %s",
+			strings.Join(bucket.PartitionFormats(), ", ")))
+	f.StringVar(&c.StorageURL, "targetStorageURL", "",
+		"the URL of an object store; if set, changefeed-style files will be written "+
+			"instead of applying mutations to the target database, which is used only "+
+			"to describe the target tables; one of file:// or s3://")
+}
+
+// Enabled returns true if a storage URL has been configured.
+func (c *Config) Enabled() bool {
+	return c.StorageURL != ""
+}
+
+// Preflight ensures that unset configuration options have sane
+// defaults and returns an error if the Config is invalid.
+func (c *Config) Preflight() error {
+	if !c.Enabled() {
+		return nil
+	}
+	if c.FileInterval == 0 {
+		c.FileInterval = defaultFileInterval
+	}
+	if c.FileInterval < 0 {
+		return errors.New("targetStorageFileInterval must not be negative")
+	}
+	if c.FileSize == 0 {
+		c.FileSize = defaultFileSize
+	}
+	if c.FileSize < 0 {
+		return errors.New("targetStorageFileSize must not be negative")
+	}
+	u, err := url.Parse(c.StorageURL)
+	if err != nil {
+		return errors.Wrap(err, "could not parse targetStorageURL")
+	}
+	c.local = ""
+	c.prefix = ""
+	c.s3 = nil
+	switch u.Scheme {
+	case "file":
+		if u.Path == "" {
+			return errors.New("missing directory in URL. Must be file:///path")
+		}
+		c.local = u.Path
+	case "s3":
+		if u.Host == "" {
+			return errors.New("missing bucket name in URL. Must be s3://bucket/folder")
+		}
+		c.prefix = strings.TrimPrefix(u.Path, "/")
+		params := u.Query()
+		endpointURL := paramValue(params, "AWS_ENDPOINT")
+		// The minio API require a endpoint to be set.
+		// We will be using AWS S3 as the default.
+		if endpointURL == "" {
+			endpointURL = defaultEndpoint
+		}
+		endpoint, err := url.Parse(endpointURL)
+		if err != nil {
+			return errors.Wrap(err, "could not parse AWS_ENDPOINT")
+		}
+		c.s3 = &s3.Config{
+			AccessKey:    paramValue(params, "AWS_ACCESS_KEY_ID"),
+			Bucket:       u.Host,
+			Endpoint:     endpoint.Host,
+			Insecure:     endpoint.Scheme == "http",
+			SecretKey:    paramValue(params, "AWS_SECRET_ACCESS_KEY"),
+			SessionToken: paramValue(params, "AWS_SESSION_TOKEN"),
+		}
+	default:
+		return errors.Errorf("unsupported targetStorageURL scheme %q", u.Scheme)
+	}
+	return nil
+}
+
+// newBucket returns the bucket to which files will be written.
+func (c *Config) newBucket() (bucket.Writer, error) {
+	switch {
+	case c.local != "":
+		return local.NewDir(c.local)
+	case c.s3 != nil:
+		return s3.NewReadWriter(c.s3)
+	default:
+		return nil, errors.New("invalid configuration. Missing bucket specification")
+	}
+}
+
+// paramValue gets the value for the specified parameter from the URL.
+// If not present in the URL, it retrieves a value from the environment.
+func paramValue(params url.Values, key string) string {
+	value := params.Get(key)
+	if value != "" {
+		return value
+	}
+	return os.Getenv(key)
+}
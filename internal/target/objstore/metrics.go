@@ -0,0 +1,51 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package objstore
+
+import (
+	"github.com/cockroachdb/replicator/internal/util/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	resolvedMarkers = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "target_objstore_resolved_markers_total",
+		Help: "the number of resolved markers written to the object store",
+	})
+	writeBytes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "target_objstore_bytes_total",
+		Help: "the number of bytes of mutation files written to the object store",
+	}, metrics.TableLabels)
+	writeDurations = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "target_objstore_write_duration_seconds",
+		Help:    "the length of time it took to write a mutation file to the object store",
+		Buckets: metrics.LatencyBuckets,
+	}, metrics.TableLabels)
+	writeErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "target_objstore_write_errors_total",
+		Help: "the number of times an error was encountered while writing a mutation file",
+	}, metrics.TableLabels)
+	writeFiles = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "target_objstore_files_total",
+		Help: "the number of mutation files written to the object store",
+	}, metrics.TableLabels)
+	writeMutations = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "target_objstore_mutations_total",
+		Help: "the number of mutations written to the object store",
+	}, metrics.TableLabels)
+)
@@ -0,0 +1,62 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package objstore
+
+import (
+	"encoding/json"
+
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/hlc"
+	"github.com/pkg/errors"
+)
+
+// payload is the encoding of a mutation in a changefeed created WITH
+// updated, key_in_value, diff.
+type payload struct {
+	After   json.RawMessage `json:"after"`
+	Before  json.RawMessage `json:"before,omitempty"`
+	Key     json.RawMessage `json:"key"`
+	Updated string          `json:"updated"`
+}
+
+// encodeMutation returns a single line of a changefeed ndjson file.
+func encodeMutation(mut types.Mutation) ([]byte, error) {
+	after := json.RawMessage("null")
+	if !mut.IsDelete() {
+		after = mut.Data
+	}
+	var before json.RawMessage
+	if len(mut.Before) > 0 && string(mut.Before) != "null" {
+		before = mut.Before
+	}
+	buf, err := json.Marshal(&payload{
+		After:   after,
+		Before:  before,
+		Key:     mut.Key,
+		Updated: mut.Time.String(),
+	})
+	return buf, errors.WithStack(err)
+}
+
+// encodeResolved returns the content of a resolved-timestamp marker.
+func encodeResolved(ts hlc.Time) ([]byte, error) {
+	buf, err := json.Marshal(map[string]string{"resolved": ts.String()})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return append(buf, '\n'), nil
+}
@@ -328,6 +328,55 @@ const sqlColumnsQueryPg = `
      AND table_name = $3
 ORDER BY ordered.ordinal_position, column_name`
 
+// Retrieve the primary key columns in their index-order, then append
+// any remaining non-computed columns. The catalog views are always
+// referenced through the database name, since the connection's current
+// database may differ from the table's database.
+//
+// Parts of the query:
+// * pk: the key columns of the table's primary-key index, if any.
+// * data_type: reconstructs the type name, including the length,
+// precision, or scale for types where they are a mandatory part of
+// the grammar. Lengths for the N-types are reported in bytes.
+// * ignored: computed columns, as well as rowversion columns, cannot
+// be written to.
+const sqlColumnsQueryMSSQL = `
+WITH pk AS (
+  SELECT ic.object_id, ic.column_id, ic.key_ordinal
+    FROM %[1]s.sys.indexes i
+    JOIN %[1]s.sys.index_columns ic
+      ON i.object_id = ic.object_id AND i.index_id = ic.index_id
+   WHERE i.is_primary_key = 1
+)
+  SELECT c.name,
+         CAST(CASE WHEN pk.key_ordinal IS NULL THEN 0 ELSE 1 END AS BIT),
+         CASE
+           WHEN t.name IN ('varchar', 'char', 'varbinary', 'binary') THEN
+             t.name + '(' + CASE WHEN c.max_length = -1 THEN 'MAX'
+                            ELSE CAST(c.max_length AS VARCHAR(10)) END + ')'
+           WHEN t.name IN ('nvarchar', 'nchar') THEN
+             t.name + '(' + CASE WHEN c.max_length = -1 THEN 'MAX'
+                            ELSE CAST(c.max_length / 2 AS VARCHAR(10)) END + ')'
+           WHEN t.name IN ('decimal', 'numeric') THEN
+             t.name + '(' + CAST(c.precision AS VARCHAR(10)) + ','
+                          + CAST(c.scale AS VARCHAR(10)) + ')'
+           WHEN t.name IN ('datetime2', 'datetimeoffset', 'time') THEN
+             t.name + '(' + CAST(c.scale AS VARCHAR(10)) + ')'
+           ELSE t.name
+         END,
+         dc.definition,
+         CAST(CASE WHEN c.is_computed = 1 OR t.name IN ('timestamp', 'rowversion')
+                   THEN 1 ELSE 0 END AS BIT)
+    FROM %[1]s.sys.columns c
+    JOIN %[1]s.sys.tables tb ON c.object_id = tb.object_id
+    JOIN %[1]s.sys.schemas s ON tb.schema_id = s.schema_id
+    JOIN %[1]s.sys.types t ON c.user_type_id = t.user_type_id
+    LEFT JOIN pk ON c.object_id = pk.object_id AND c.column_id = pk.column_id
+    LEFT JOIN %[1]s.sys.default_constraints dc ON c.default_object_id = dc.object_id
+   WHERE s.name = @p1
+     AND tb.name = @p2
+ORDER BY COALESCE(pk.key_ordinal, 2048), c.name`
+
 // getColumns returns the column names for the primary key columns in
 // their index-order, followed by all other columns that should be
 // mutated.
@@ -378,6 +427,16 @@ func getColumns(
 			sql.Named("owner", parts[0].Raw()),
 			sql.Named("tbl_name", parts[1].Raw()),
 		}
+	case types.ProductSQLServer:
+		parts := table.Idents(make([]ident.Ident, 0, 3))
+		if len(parts) != 3 {
+			return nil, errors.Errorf("expecting three table name parts, had %d", len(parts))
+		}
+		stmt = fmt.Sprintf(sqlColumnsQueryMSSQL, parts[0])
+		args = []any{
+			parts[1].Raw(),
+			parts[2].Raw(),
+		}
 	default:
 		return nil, errors.Errorf("unimplemented: %s", tx.Product)
 	}
@@ -463,6 +522,12 @@ func getColumns(
 					// Oracle also likes to include some dangling whitespace.
 					column.DefaultExpr = strings.TrimSpace(defaultExpr.String)
 				}
+			case types.ProductSQLServer:
+				if defaultExpr.Valid {
+					// SQL Server reports default constraints wrapped
+					// in parentheses, e.g. ((0)) or (getdate()).
+					column.DefaultExpr = defaultExpr.String
+				}
 			default:
 				return errors.Errorf("unimplemented: %s", tx.Product)
 			}
@@ -318,6 +318,22 @@ func TestGetColumns(t *testing.T) {
 				"c", "NUMBER",
 			),
 		},
+		// Check type extraction.
+		{
+			products: []types.Product{types.ProductSQLServer},
+			tableSchema: `a INT, b NVARCHAR(42), c VARBINARY(MAX), d DECIMAL(4,2),
+			              e DATETIME2(3), f INT DEFAULT 0, g AS (a + 1), PRIMARY KEY (b, a)`,
+			primaryKeys: []string{"b", "a"},
+			dataCols:    []string{"c", "d", "e", "f", "ignored_g"},
+			types: ident.MapOf[string](
+				"a", "int",
+				"b", "nvarchar(42)",
+				"c", "varbinary(MAX)",
+				"d", "decimal(4,2)",
+				"e", "datetime2(3)",
+				"f", "int",
+			),
+		},
 		// Check default value extraction
 		{
 			products: []types.Product{types.ProductCockroachDB, types.ProductMariaDB,
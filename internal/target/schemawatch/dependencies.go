@@ -217,6 +217,50 @@ WITH RECURSIVE
 SELECT * FROM refs UNION ALL SELECT * FROM seeds
 `
 
+// depOrderTemplateMSSQL creates a mapping of child to parent tables.
+//
+// - %[1]s: Target database ("my_database")
+// - @p1: Target table schema ("dbo")
+// - @p2: Target database name, as a string
+//
+// Parts of the query:
+//   - refs: Creates child to parent mappings from the foreign keys
+//     defined on any table that refers to a table in the schema. Table
+//     self-references are filtered out.
+//   - seeds: Emits a dummy row for all tables in the schema. This
+//     ensures that tables with no children are still in the result set.
+//   - top level: Union of refs and seeds.
+const depOrderTemplateMSSQL = `
+WITH
+  refs
+    AS (
+      SELECT
+        CAST(@p2 AS SYSNAME) AS child_catalog,
+        cs.name AS child_schema,
+        ct.name AS child_table_name,
+        CAST(@p2 AS SYSNAME) AS parent_catalog,
+        ps.name AS parent_schema,
+        pt.name AS parent_table_name
+      FROM %[1]s.sys.foreign_keys fk
+      JOIN %[1]s.sys.tables ct ON fk.parent_object_id = ct.object_id
+      JOIN %[1]s.sys.schemas cs ON ct.schema_id = cs.schema_id
+      JOIN %[1]s.sys.tables pt ON fk.referenced_object_id = pt.object_id
+      JOIN %[1]s.sys.schemas ps ON pt.schema_id = ps.schema_id
+      WHERE ps.name = @p1
+        AND fk.parent_object_id != fk.referenced_object_id
+    ),
+  seeds
+    AS (
+      SELECT
+        NULL AS child_catalog, NULL AS child_schema, NULL AS child_table_name,
+        TABLE_CATALOG, TABLE_SCHEMA, TABLE_NAME
+      FROM %[1]s.INFORMATION_SCHEMA.TABLES
+      WHERE TABLE_SCHEMA = @p1
+        AND TABLE_TYPE = 'BASE TABLE'
+    )
+SELECT * FROM refs UNION ALL SELECT * FROM seeds
+`
+
 // getDependencyRefs returns a map describing the parent-to-children
 // relationships of tables. That is, the map values are the tables that
 // have some immediate dependency on the key. Tables with no
@@ -259,6 +303,13 @@ func getDependencyRefs(
 	case types.ProductOracle:
 		stmt = depOrderTemplateOra
 		args = []any{sql.Named("owner", db.Raw())}
+	case types.ProductSQLServer:
+		parts := db.Idents(make([]ident.Ident, 0, 2))
+		if len(parts) != 2 {
+			return nil, errors.Errorf("expecting two schema parts, had %d", len(parts))
+		}
+		stmt = fmt.Sprintf(depOrderTemplateMSSQL, parts[0])
+		args = []any{parts[1].Raw(), parts[0].Raw()}
 	default:
 		return nil, errors.Errorf("getDependencyOrder unimplemented product: %s", tx.Product)
 	}
@@ -287,7 +338,7 @@ func getDependencyRefs(
 			var childSchema, parentSchema ident.Schema
 			var childTableName, parentTableName ident.Ident
 			switch tx.Product {
-			case types.ProductCockroachDB, types.ProductPostgreSQL:
+			case types.ProductCockroachDB, types.ProductPostgreSQL, types.ProductSQLServer:
 				parentSchema, err = ident.NewSchema(ident.New(parentDBRaw), ident.New(parentSchemaRaw))
 				if err != nil {
 					return err
@@ -193,6 +193,14 @@ ALTER TABLE %[1]s.cycle_a ADD COLUMN ref int references %[1]s.cycle_b;
 			fixture.TargetSchema.Schema()))
 		r.NoError(err)
 
+	case types.ProductSQLServer:
+		_, err = pool.ExecContext(ctx, fmt.Sprintf(`
+CREATE TABLE %[1]s."cycle_a" (pk int primary key);
+CREATE TABLE %[1]s."cycle_b" (pk int primary key, ref int references %[1]s."cycle_a");
+ALTER TABLE %[1]s."cycle_a" ADD ref int references %[1]s."cycle_b";
+`, fixture.TargetSchema.Schema()))
+		r.NoError(err)
+
 	default:
 		r.FailNow("unsupported product")
 	}
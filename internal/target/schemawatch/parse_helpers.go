@@ -39,6 +39,9 @@ import (
 )
 
 var (
+	sqlServerBinaryPattern = regexp.MustCompile(`^(binary|varbinary)(\(.+\))?$`)
+	sqlServerTextPattern   = regexp.MustCompile(`^(nvarchar|varchar|ntext|text)(\(.+\))?$`)
+
 	// These are evaluated in order.
 	oraParseHelpers = []struct {
 		pattern *regexp.Regexp
@@ -125,6 +128,15 @@ func coerceJSON(a any) (any, error) {
 	return json.Marshal(a)
 }
 
+// coerce non-string values to their json text representation
+func coerceJSONText(a any) (any, error) {
+	if s, ok := a.(string); ok {
+		return s, nil
+	}
+	buf, err := json.Marshal(a)
+	return string(buf), err
+}
+
 func parseHelper(product types.Product, typeName string) func(any) (any, error) {
 	switch product {
 	case types.ProductCockroachDB, types.ProductPostgreSQL:
@@ -147,6 +159,15 @@ func parseHelper(product types.Product, typeName string) func(any) (any, error)
 				return helper.parser
 			}
 		}
+	case types.ProductSQLServer:
+		// Binary types are reported with a length, e.g. varbinary(16).
+		if sqlServerBinaryPattern.MatchString(typeName) {
+			return coerceHexString
+		}
+		// SQL Server has no JSON type, so documents are stored as text.
+		if sqlServerTextPattern.MatchString(typeName) {
+			return coerceJSONText
+		}
 	}
 	return nil
 }
@@ -110,3 +110,25 @@ func TestMyParseHelpers(t *testing.T) {
 		})
 	}
 }
+
+func TestSQLServerParseHelpers(t *testing.T) {
+	r := require.New(t)
+	r.Nil(parseHelper(types.ProductSQLServer, "int"))
+
+	helper := parseHelper(types.ProductSQLServer, "nvarchar(MAX)")
+	r.NotNil(helper)
+	ret, err := helper("hello")
+	r.NoError(err)
+	r.Equal("hello", ret)
+	ret, err = helper(map[string]any{"a": 1})
+	r.NoError(err)
+	r.Equal(`{"a":1}`, ret)
+
+	for _, typ := range []string{"binary(16)", "varbinary(MAX)", "varbinary"} {
+		helper := parseHelper(types.ProductSQLServer, typ)
+		r.NotNil(helper, typ)
+		ret, err := helper(`\xdeadbeef`)
+		r.NoError(err)
+		r.Equal([]byte{0xde, 0xad, 0xbe, 0xef}, ret)
+	}
+}
@@ -196,6 +196,12 @@ WHERE upper(table_schema) = upper(?)
 AND table_type = 'BASE TABLE'`
 	tableTemplateOracle = `
 SELECT OWNER, NULL, TABLE_NAME FROM ALL_TABLES WHERE UPPER(OWNER) = UPPER(:owner)`
+	databaseTemplateSQLServer = `SELECT name FROM sys.databases WHERE name = @p1`
+	tableTemplateSQLServer    = `
+SELECT TABLE_CATALOG, TABLE_SCHEMA, TABLE_NAME
+  FROM %s.INFORMATION_SCHEMA.TABLES
+ WHERE TABLE_SCHEMA = @p1
+   AND TABLE_TYPE = 'BASE TABLE'`
 )
 
 func (w *watcher) getTables(ctx context.Context, tx *types.TargetPool) (*types.SchemaData, error) {
@@ -231,6 +237,24 @@ func (w *watcher) getTables(ctx context.Context, tx *types.TargetPool) (*types.S
 			rows, err = tx.QueryContext(ctx, tableTemplateMySQL, w.schema.Raw())
 		case types.ProductOracle:
 			rows, err = tx.QueryContext(ctx, tableTemplateOracle, w.schema.Raw())
+		case types.ProductSQLServer:
+			parts := w.schema.Idents(make([]ident.Ident, 0, 2))
+			if len(parts) != 2 {
+				return errors.Errorf("expecting a schema with 2 parts, got %v", parts)
+			}
+
+			// Normalize the database name, since the default collation
+			// is case-insensitive.
+			var dbNameRaw string
+			if err := tx.QueryRowContext(ctx, databaseTemplateSQLServer, parts[0].Raw()).Scan(&dbNameRaw); err != nil {
+				if errors.Is(err, sql.ErrNoRows) {
+					return errors.Errorf("unknown schema: %s", w.schema)
+				}
+				return errors.Wrap(err, w.schema.String())
+			}
+
+			rows, err = tx.QueryContext(ctx,
+				fmt.Sprintf(tableTemplateSQLServer, ident.New(dbNameRaw)), parts[1].Raw())
 
 		default:
 			return errors.Errorf("unimplemented product: %s", tx.Product)
@@ -88,6 +88,9 @@ func TestWatch(t *testing.T) {
 		case types.ProductOracle:
 			r.NoError(retry.Execute(ctx, fixture.TargetPool,
 				fmt.Sprintf("ALTER TABLE %s ADD (v INT)", tblInfo.Name())))
+		case types.ProductSQLServer:
+			r.NoError(retry.Execute(ctx, fixture.TargetPool,
+				fmt.Sprintf("ALTER TABLE %s ADD v VARCHAR(10)", tblInfo.Name())))
 
 		default:
 			r.FailNow("unsupported product")
@@ -63,6 +63,19 @@ func OrderedAcceptorFrom(acc TableAcceptor, watchers Watchers) MultiAcceptor {
 	}
 }
 
+// A ResolvedAcceptor is implemented by acceptors which must be told
+// when all mutations within a group, at or before some timestamp, have
+// been durably accepted (e.g. to emit resolved-timestamp markers).
+// Implementations must make mutations durable before the accept
+// methods return, since the resolved timestamp is only delivered on a
+// best-effort basis.
+type ResolvedAcceptor interface {
+	// AcceptResolved is called once the group has been committed
+	// through the timestamp. It may be called more than once for the
+	// same timestamp and must be idempotent.
+	AcceptResolved(ctx context.Context, group *TableGroup, ts hlc.Time) error
+}
+
 // A TemporalAcceptor operates on a batch of data that has a single
 // timestamp (i.e. a source database transaction).
 type TemporalAcceptor interface {
@@ -81,6 +81,16 @@ func TestProductExpand(t *testing.T) {
 			product: ProductMariaDB,
 			err:     "expecting exactly one schema part",
 		},
+		{
+			input:    ident.MustSchema(ident.New("foo")),
+			product:  ProductSQLServer,
+			expected: ident.MustSchema(ident.New("foo"), ident.New("dbo")),
+		},
+		{
+			input:    ident.MustSchema(ident.New("foo"), ident.New("bar")),
+			product:  ProductSQLServer,
+			expected: ident.MustSchema(ident.New("foo"), ident.New("bar")),
+		},
 	}
 
 	for idx, tc := range tcs {
@@ -0,0 +1,91 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package types
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pkg/errors"
+)
+
+// ErrNoStagingDB is returned by the [StagingTx] of a [StagingPool]
+// that has no connection to a staging database.
+var ErrNoStagingDB = errors.New("no staging database; staging data is kept in an embedded store")
+
+// StagingTx is a transaction in the staging database. Instances are
+// created by [StagingPool.BeginStagingTx].
+type StagingTx interface {
+	StagingQuerier
+	Commit(ctx context.Context) error
+	Rollback(ctx context.Context) error
+}
+
+var _ StagingTx = pgx.Tx(nil)
+
+// BeginStagingTx starts a transaction in the staging database. If the
+// pool has no connection, because staging data is kept in an embedded
+// store, the returned transaction will reject any queries and its
+// Commit and Rollback methods do nothing. This allows callers to pass
+// the transaction to [Stager] and [Memo] implementations which do not
+// use SQL.
+func (p *StagingPool) BeginStagingTx(ctx context.Context) (StagingTx, error) {
+	if p.Pool == nil {
+		return noStagingTx{}, nil
+	}
+	tx, err := p.BeginTx(ctx, pgx.TxOptions{})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return tx, nil
+}
+
+// Ping verifies the connection to the staging database. It returns nil
+// if the pool has no connection.
+func (p *StagingPool) Ping(ctx context.Context) error {
+	if p.Pool == nil {
+		return nil
+	}
+	return p.Pool.Ping(ctx)
+}
+
+// noStagingTx is returned by [StagingPool.BeginStagingTx] when there is
+// no staging database.
+type noStagingTx struct{}
+
+var _ StagingTx = noStagingTx{}
+
+func (noStagingTx) Commit(context.Context) error   { return nil }
+func (noStagingTx) Rollback(context.Context) error { return nil }
+
+func (noStagingTx) Exec(context.Context, string, ...any) (pgconn.CommandTag, error) {
+	return pgconn.CommandTag{}, ErrNoStagingDB
+}
+
+func (noStagingTx) Query(context.Context, string, ...any) (pgx.Rows, error) {
+	return nil, ErrNoStagingDB
+}
+
+func (noStagingTx) QueryRow(context.Context, string, ...any) pgx.Row {
+	return noStagingRow{}
+}
+
+// noStagingRow is returned by noStagingTx.QueryRow.
+type noStagingRow struct{}
+
+func (noStagingRow) Scan(...any) error { return ErrNoStagingDB }
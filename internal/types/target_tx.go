@@ -0,0 +1,69 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package types
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+// ConnTx is a [TargetTx] that also exposes its underlying driver
+// connection. Instances are created by [TargetPool.BeginConnTx].
+type ConnTx struct {
+	*sql.Tx
+	conn *sql.Conn
+}
+
+var _ TargetTx = (*ConnTx)(nil)
+
+// BeginConnTx starts a transaction on a dedicated connection. The
+// connection is returned to the pool once the transaction has been
+// committed or rolled back.
+func (p *TargetPool) BeginConnTx(ctx context.Context, opts *sql.TxOptions) (*ConnTx, error) {
+	conn, err := p.Conn(ctx)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	tx, err := conn.BeginTx(ctx, opts)
+	if err != nil {
+		_ = conn.Close()
+		return nil, errors.WithStack(err)
+	}
+	return &ConnTx{Tx: tx, conn: conn}, nil
+}
+
+// Commit commits the transaction and releases the connection.
+func (t *ConnTx) Commit() error {
+	err := t.Tx.Commit()
+	_ = t.conn.Close()
+	return err
+}
+
+// Raw implements [TargetConn].
+func (t *ConnTx) Raw(fn func(driverConn any) error) error {
+	return t.conn.Raw(fn)
+}
+
+// Rollback aborts the transaction and releases the connection. It is
+// safe to call Rollback after Commit.
+func (t *ConnTx) Rollback() error {
+	err := t.Tx.Rollback()
+	_ = t.conn.Close()
+	return err
+}
@@ -183,6 +183,13 @@ type Stager interface {
 	// not occur within a single database transaction.
 	Retire(ctx context.Context, db StagingQuerier, end hlc.Time) error
 
+	// Rewind marks all staged mutations whose timestamp is strictly
+	// greater than the given time as being unapplied, so that they
+	// will be delivered again. Stub entries created by MarkApplied are
+	// deleted. Mutations which have already been retired cannot be
+	// recovered.
+	Rewind(ctx context.Context, db StagingQuerier, after hlc.Time) error
+
 	// Stage writes the mutations into the staging table. This method is
 	// idempotent.
 	Stage(ctx context.Context, db StagingQuerier, muts []Mutation) error
@@ -211,6 +218,11 @@ type StagingQuery struct {
 
 	// The tables to query.
 	Group *TableGroup
+
+	// If true, mutations which have already been marked as applied
+	// will also be returned. This is used to replay staged data that
+	// has not yet been retired.
+	IncludeApplied bool
 }
 
 // Stagers is a factory for Stager instances.
@@ -236,6 +248,7 @@ const (
 	ProductMySQL
 	ProductOracle
 	ProductPostgreSQL
+	ProductSQLServer
 )
 
 // ExpandSchema validates a Schema against the expected form used by the
@@ -262,6 +275,18 @@ func (p Product) ExpandSchema(s ident.Schema) (ident.Schema, error) {
 			return ident.Schema{}, errors.Errorf("unexpected number of schema parts: %d", numParts)
 		}
 
+	case ProductSQLServer:
+		switch numParts {
+		case 1:
+			// Add missing "dbo" identifier.
+			return ident.NewSchema(parts[0], ident.New("dbo"))
+		case 2:
+			// Fully-specified, return as-is.
+			return s, nil
+		default:
+			return ident.Schema{}, errors.Errorf("unexpected number of schema parts: %d", numParts)
+		}
+
 	case ProductMySQL, ProductMariaDB, ProductOracle:
 		if numParts != 1 {
 			return ident.Schema{}, errors.Errorf("expecting exactly one schema part, had %d", numParts)
@@ -329,7 +354,7 @@ func (i *PoolInfo) Info() *PoolInfo { return i }
 
 // StagingPool is an injection point for a connection to the staging database.
 type StagingPool struct {
-	*pgxpool.Pool
+	*pgxpool.Pool // Nil if staging data is kept in an embedded store.
 	PoolInfo
 	_ noCopy
 }
@@ -377,6 +402,18 @@ type TargetTx interface {
 
 var _ TargetTx = (*sql.Tx)(nil)
 
+// TargetConn is implemented by TargetQuerier instances that are bound
+// to a single database connection. It allows driver-specific APIs, such
+// as bulk-copy protocols, to be used alongside ordinary statements.
+type TargetConn interface {
+	TargetQuerier
+	// Raw executes the callback with the underlying driver connection.
+	// See [sql.Conn.Raw].
+	Raw(fn func(driverConn any) error) error
+}
+
+var _ TargetConn = (*ConnTx)(nil)
+
 // Watcher allows table metadata to be observed.
 //
 // The methods in this type return column data such that primary key
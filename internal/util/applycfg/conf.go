@@ -20,8 +20,10 @@ import (
 	"time"
 
 	"github.com/cockroachdb/replicator/internal/util/cmap"
+	"github.com/cockroachdb/replicator/internal/util/filter"
 	"github.com/cockroachdb/replicator/internal/util/ident"
 	"github.com/cockroachdb/replicator/internal/util/merge"
+	"github.com/cockroachdb/replicator/internal/util/transform"
 )
 
 // DefaultRowLimit limits the number of rows to be sent in a single
@@ -52,14 +54,25 @@ type (
 // A Config contains per-target-table configuration.
 type Config struct {
 	// NB: Update TestCopyEquals if adding new fields.
-	CASColumns  TargetColumns             // The columns for compare-and-set operations.
-	Deadlines   *ident.Map[time.Duration] // Deadline-based operation.
-	Exprs       *ident.Map[string]        // Synthetic or replacement SQL expressions.
-	Extras      TargetColumn              // JSONB column to store unmapped values in.
-	Ignore      *ident.Map[bool]          // Source column names to ignore.
-	Merger      merge.Merger              // Conflict resolution.
-	RowLimit    int                       // Adjust if hitting limits on bind variables.
-	SourceNames *ident.Map[SourceColumn]  // Look for alternate name in the incoming data.
+	BulkThreshold  int                            // Minimum batch size to use a bulk-load path; zero disables.
+	CASColumns     TargetColumns                  // The columns for compare-and-set operations.
+	Deadlines      *ident.Map[time.Duration]      // Deadline-based operation.
+	Exprs          *ident.Map[string]             // Synthetic or replacement SQL expressions.
+	Extras         TargetColumn                   // JSONB column to store unmapped values in.
+	Filter         *filter.Filter                 // Discard rows that do not match a predicate.
+	HistoryOp      TargetColumn                   // Enables append-only history; records the operation.
+	HistoryTime    TargetColumn                   // Records the mutation's HLC time; part of the PK.
+	Ignore         *ident.Map[bool]               // Source column names to ignore.
+	Merger         merge.Merger                   // Conflict resolution.
+	Mode           Mode                           // Override the sequencing mode; see ParseMode.
+	RowLimit       int                            // Adjust if hitting limits on bind variables.
+	SCDCurrent     TargetColumn                   // Enables SCD Type 2; a boolean current-version column.
+	SCDValidFrom   TargetColumn                   // Start of a version's validity; part of the PK.
+	SCDValidTo     TargetColumn                   // End of a version's validity.
+	SoftDelete     TargetColumn                   // Enables soft deletes; a boolean tombstone column.
+	SoftDeleteTime TargetColumn                   // Optionally records the time of a soft delete.
+	SourceNames    *ident.Map[SourceColumn]       // Look for alternate name in the incoming data.
+	Transforms     *ident.Map[transform.Pipeline] // Declarative value transforms, by source column.
 }
 
 // NewConfig constructs a Config with all map fields populated.
@@ -69,20 +82,32 @@ func NewConfig() *Config {
 		Exprs:       &ident.Map[string]{},
 		Ignore:      &ident.Map[bool]{},
 		SourceNames: &ident.Map[SourceColumn]{},
+		Transforms:  &ident.Map[transform.Pipeline]{},
 	}
 }
 
 // Copy returns a copy of the Config.
 func (c *Config) Copy() *Config {
 	ret := NewConfig()
+	ret.BulkThreshold = c.BulkThreshold
 	ret.CASColumns = append(ret.CASColumns, c.CASColumns...)
 	c.Deadlines.CopyInto(ret.Deadlines)
 	c.Exprs.CopyInto(ret.Exprs)
 	ret.Extras = c.Extras
+	ret.Filter = c.Filter
+	ret.HistoryOp = c.HistoryOp
+	ret.HistoryTime = c.HistoryTime
 	c.Ignore.CopyInto(ret.Ignore)
 	ret.Merger = c.Merger
+	ret.Mode = c.Mode
 	ret.RowLimit = c.RowLimit
+	ret.SCDCurrent = c.SCDCurrent
+	ret.SCDValidFrom = c.SCDValidFrom
+	ret.SCDValidTo = c.SCDValidTo
+	ret.SoftDelete = c.SoftDelete
+	ret.SoftDeleteTime = c.SoftDeleteTime
 	c.SourceNames.CopyInto(ret.SourceNames)
+	c.Transforms.CopyInto(ret.Transforms)
 
 	return ret
 }
@@ -97,32 +122,57 @@ func (c *Config) Equal(o *Config) bool {
 	return c == o || // Identity or nil-nil.
 		(c != nil) && (o != nil) &&
 			// Not all implementations of Acceptor are comparable.
+			c.BulkThreshold == o.BulkThreshold &&
 			c.CASColumns.Equal(o.CASColumns) &&
 			c.Deadlines.Equal(o.Deadlines, cmap.Comparator[time.Duration]()) &&
 			c.Exprs.Equal(o.Exprs, cmap.Comparator[string]()) &&
 			ident.Equal(c.Extras, o.Extras) &&
+			c.Filter.Equal(o.Filter) &&
+			ident.Equal(c.HistoryOp, o.HistoryOp) &&
+			ident.Equal(c.HistoryTime, o.HistoryTime) &&
 			c.Ignore.Equal(o.Ignore, cmap.Comparator[bool]()) &&
 			// Not all implementations of Merger are comparable: merge.Func or similar.
+			c.Mode == o.Mode &&
 			c.RowLimit == o.RowLimit &&
-			c.SourceNames.Equal(o.SourceNames, ident.Comparator[ident.Ident]())
+			ident.Equal(c.SCDCurrent, o.SCDCurrent) &&
+			ident.Equal(c.SCDValidFrom, o.SCDValidFrom) &&
+			ident.Equal(c.SCDValidTo, o.SCDValidTo) &&
+			ident.Equal(c.SoftDelete, o.SoftDelete) &&
+			ident.Equal(c.SoftDeleteTime, o.SoftDeleteTime) &&
+			c.SourceNames.Equal(o.SourceNames, ident.Comparator[ident.Ident]()) &&
+			c.Transforms.Equal(o.Transforms, transform.Pipeline.Equal)
 }
 
 // IsZero returns true if the Config represents the absence of a
 // configuration.
 func (c *Config) IsZero() bool {
-	return len(c.CASColumns) == 0 &&
+	return c.BulkThreshold == 0 &&
+		len(c.CASColumns) == 0 &&
 		c.Deadlines.Len() == 0 &&
 		c.Exprs.Len() == 0 &&
 		c.Extras.Empty() &&
+		c.Filter == nil &&
+		c.HistoryOp.Empty() &&
+		c.HistoryTime.Empty() &&
 		c.Ignore.Len() == 0 &&
 		c.Merger == nil &&
+		c.Mode == ModeUnknown &&
 		c.RowLimit == 0 &&
-		c.SourceNames.Len() == 0
+		c.SCDCurrent.Empty() &&
+		c.SCDValidFrom.Empty() &&
+		c.SCDValidTo.Empty() &&
+		c.SoftDelete.Empty() &&
+		c.SoftDeleteTime.Empty() &&
+		c.SourceNames.Len() == 0 &&
+		c.Transforms.Len() == 0
 }
 
 // Patch applies any non-empty fields from another Config to the
 // receiver and returns the receiver.
 func (c *Config) Patch(other *Config) *Config {
+	if other.BulkThreshold != 0 {
+		c.BulkThreshold = other.BulkThreshold
+	}
 	c.CASColumns = append(c.CASColumns, other.CASColumns...)
 	if other.Deadlines != nil {
 		other.Deadlines.CopyInto(c.Deadlines)
@@ -133,17 +183,47 @@ func (c *Config) Patch(other *Config) *Config {
 	if !other.Extras.Empty() {
 		c.Extras = other.Extras
 	}
+	if other.Filter != nil {
+		c.Filter = other.Filter
+	}
+	if !other.HistoryOp.Empty() {
+		c.HistoryOp = other.HistoryOp
+	}
+	if !other.HistoryTime.Empty() {
+		c.HistoryTime = other.HistoryTime
+	}
 	if other.Ignore != nil {
 		other.Ignore.CopyInto(c.Ignore)
 	}
 	if other.Merger != nil {
 		c.Merger = other.Merger
 	}
+	if other.Mode != ModeUnknown {
+		c.Mode = other.Mode
+	}
 	if other.RowLimit != 0 {
 		c.RowLimit = other.RowLimit
 	}
+	if !other.SCDCurrent.Empty() {
+		c.SCDCurrent = other.SCDCurrent
+	}
+	if !other.SCDValidFrom.Empty() {
+		c.SCDValidFrom = other.SCDValidFrom
+	}
+	if !other.SCDValidTo.Empty() {
+		c.SCDValidTo = other.SCDValidTo
+	}
+	if !other.SoftDelete.Empty() {
+		c.SoftDelete = other.SoftDelete
+	}
+	if !other.SoftDeleteTime.Empty() {
+		c.SoftDeleteTime = other.SoftDeleteTime
+	}
 	if other.SourceNames != nil {
 		other.SourceNames.CopyInto(c.SourceNames)
 	}
+	if other.Transforms != nil {
+		other.Transforms.CopyInto(c.Transforms)
+	}
 	return c
 }
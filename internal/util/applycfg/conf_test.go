@@ -21,25 +21,43 @@ import (
 	"testing"
 	"time"
 
+	"github.com/cockroachdb/replicator/internal/util/filter"
 	"github.com/cockroachdb/replicator/internal/util/ident"
 	"github.com/cockroachdb/replicator/internal/util/merge"
+	"github.com/cockroachdb/replicator/internal/util/transform"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestCopyEquals(t *testing.T) {
 	a := assert.New(t)
 
+	pred, err := filter.Parse("region = 'us'")
+	a.NoError(err)
+
 	cfg := &Config{
-		CASColumns: TargetColumns{ident.New("cas")},
-		Deadlines:  ident.MapOf[time.Duration](ident.New("dl"), time.Hour),
-		Exprs:      ident.MapOf[string]("expr", "foo"),
-		Extras:     ident.New("extras"),
-		RowLimit:   42,
-		Ignore:     ident.MapOf[bool]("ign", true),
+		BulkThreshold:  1000,
+		CASColumns:     TargetColumns{ident.New("cas")},
+		Deadlines:      ident.MapOf[time.Duration](ident.New("dl"), time.Hour),
+		Exprs:          ident.MapOf[string]("expr", "foo"),
+		Extras:         ident.New("extras"),
+		Filter:         pred,
+		HistoryOp:      ident.New("hist_op"),
+		HistoryTime:    ident.New("hist_time"),
+		Mode:           ModeImmediate,
+		RowLimit:       42,
+		SCDCurrent:     ident.New("is_current"),
+		SCDValidFrom:   ident.New("valid_from"),
+		SCDValidTo:     ident.New("valid_to"),
+		SoftDelete:     ident.New("deleted"),
+		SoftDeleteTime: ident.New("deleted_at"),
+		Ignore:         ident.MapOf[bool]("ign", true),
 		Merger: merge.Func(func(context.Context, *merge.Conflict) (*merge.Resolution, error) {
 			panic("unused")
 		}),
 		SourceNames: ident.MapOf[SourceColumn](ident.New("new"), ident.New("old")),
+		Transforms: ident.MapOf[transform.Pipeline](ident.New("secret"), transform.Pipeline{
+			{Kind: transform.KindHash, Algorithm: transform.AlgorithmSHA256},
+		}),
 	}
 
 	a.True(cfg.Equal(cfg))
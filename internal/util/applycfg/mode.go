@@ -0,0 +1,58 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package applycfg
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Mode describes the strategy used to sequence mutations.
+//
+//go:generate go run golang.org/x/tools/cmd/stringer -type=Mode
+type Mode int
+
+// The modes of operation that can be dynamically selected from.
+const (
+	ModeUnknown Mode = iota
+	ModeBestEffort
+	ModeConsistent
+	ModeImmediate
+
+	MaxMode = iota - 1 // Used for testing all modes.
+	MinMode = Mode(1)  // Used for testing all modes.
+)
+
+// ParseMode converts a user-provided mode name to a Mode. The value
+// "auto" returns [ModeUnknown], which indicates that the mode should
+// be selected automatically.
+func ParseMode(s string) (Mode, error) {
+	switch strings.TrimPrefix(strings.ToLower(strings.TrimSpace(s)), "mode") {
+	case "auto":
+		return ModeUnknown, nil
+	case "besteffort":
+		return ModeBestEffort, nil
+	case "consistent":
+		return ModeConsistent, nil
+	case "immediate":
+		return ModeImmediate, nil
+	default:
+		return ModeUnknown, errors.Errorf(
+			"unknown mode %q; expecting one of auto, besteffort, consistent, immediate", s)
+	}
+}
@@ -1,6 +1,6 @@
 // Code generated by "stringer -type=Mode"; DO NOT EDIT.
 
-package switcher
+package applycfg
 
 import "strconv"
 
@@ -0,0 +1,41 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package applycfg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMode(t *testing.T) {
+	r := require.New(t)
+	for input, expected := range map[string]Mode{
+		"auto":           ModeUnknown,
+		"BestEffort":     ModeBestEffort,
+		"consistent":     ModeConsistent,
+		"ModeImmediate":  ModeImmediate,
+		"modeconsistent": ModeConsistent,
+		" immediate ":    ModeImmediate,
+	} {
+		found, err := ParseMode(input)
+		r.NoError(err, input)
+		r.Equal(expected, found, input)
+	}
+	_, err := ParseMode("fast")
+	r.ErrorContains(err, "unknown mode")
+}
@@ -0,0 +1,222 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package filter
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/cockroachdb/replicator/internal/util/ident"
+)
+
+// A node in the parsed expression. The eval method returns a value
+// which is nil for SQL NULL, a bool, a float64, or a string.
+type node interface {
+	eval(lookup func(ident.Ident) any) any
+}
+
+type andNode struct{ left, right node }
+
+func (n *andNode) eval(lookup func(ident.Ident) any) any {
+	left, right := n.left.eval(lookup), n.right.eval(lookup)
+	if left == false || right == false {
+		return false
+	}
+	if left == true && right == true {
+		return true
+	}
+	return nil
+}
+
+type columnNode struct{ name ident.Ident }
+
+func (n *columnNode) eval(lookup func(ident.Ident) any) any {
+	return normalize(lookup(n.name))
+}
+
+type compareNode struct {
+	left  node
+	op    string
+	right node
+}
+
+func (n *compareNode) eval(lookup func(ident.Ident) any) any {
+	cmp, ok := compare(n.left.eval(lookup), n.right.eval(lookup))
+	if !ok {
+		return nil
+	}
+	switch n.op {
+	case "=":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	default:
+		return nil
+	}
+}
+
+type inNode struct {
+	value node
+	list  []*literalNode
+}
+
+func (n *inNode) eval(lookup func(ident.Ident) any) any {
+	value := n.value.eval(lookup)
+	if value == nil {
+		return nil
+	}
+	sawNull := false
+	for _, lit := range n.list {
+		if lit.value == nil {
+			sawNull = true
+			continue
+		}
+		if cmp, ok := compare(value, lit.value); ok && cmp == 0 {
+			return true
+		}
+	}
+	if sawNull {
+		return nil
+	}
+	return false
+}
+
+type isNullNode struct{ value node }
+
+func (n *isNullNode) eval(lookup func(ident.Ident) any) any {
+	return n.value.eval(lookup) == nil
+}
+
+type literalNode struct{ value any }
+
+func (n *literalNode) eval(func(ident.Ident) any) any {
+	return n.value
+}
+
+type notNode struct{ inner node }
+
+func (n *notNode) eval(lookup func(ident.Ident) any) any {
+	if b, ok := n.inner.eval(lookup).(bool); ok {
+		return !b
+	}
+	return nil
+}
+
+type orNode struct{ left, right node }
+
+func (n *orNode) eval(lookup func(ident.Ident) any) any {
+	left, right := n.left.eval(lookup), n.right.eval(lookup)
+	if left == true || right == true {
+		return true
+	}
+	if left == false && right == false {
+		return false
+	}
+	return nil
+}
+
+// compare returns the ordering of the two values. Numbers and strings
+// that contain numbers are compared numerically, since some sources
+// encode decimal values as strings. The returned boolean will be
+// false if either value is null or if the values are not comparable.
+func compare(a, b any) (int, bool) {
+	if a == nil || b == nil {
+		return 0, false
+	}
+	_, aNum := a.(float64)
+	_, bNum := b.(float64)
+	if aNum || bNum {
+		af, aOK := asFloat(a)
+		bf, bOK := asFloat(b)
+		if !aOK || !bOK {
+			return 0, false
+		}
+		switch {
+		case af < bf:
+			return -1, true
+		case af > bf:
+			return 1, true
+		default:
+			return 0, true
+		}
+	}
+	switch at := a.(type) {
+	case bool:
+		if bt, ok := b.(bool); ok {
+			switch {
+			case at == bt:
+				return 0, true
+			case !at:
+				return -1, true
+			default:
+				return 1, true
+			}
+		}
+	case string:
+		if bt, ok := b.(string); ok {
+			return strings.Compare(at, bt), true
+		}
+	}
+	return 0, false
+}
+
+// asFloat returns a numeric interpretation of the value.
+func asFloat(v any) (float64, bool) {
+	switch t := v.(type) {
+	case float64:
+		return t, true
+	case string:
+		f, err := strconv.ParseFloat(t, 64)
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+// normalize converts values decoded from JSON into the types returned
+// by [node.eval]. Nested objects and arrays are compared using their
+// JSON representation.
+func normalize(v any) any {
+	switch t := v.(type) {
+	case nil, bool, float64, string:
+		return t
+	case json.Number:
+		if f, err := t.Float64(); err == nil {
+			return f
+		}
+		return t.String()
+	case int:
+		return float64(t)
+	case int64:
+		return float64(t)
+	default:
+		buf, err := json.Marshal(t)
+		if err != nil {
+			return nil
+		}
+		return string(buf)
+	}
+}
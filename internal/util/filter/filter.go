@@ -0,0 +1,152 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package filter contains a row-filtering predicate which is evaluated
+// natively against mutation documents.
+//
+// The predicate syntax is a subset of SQL:
+//
+//	region = 'us-east' AND (tenant_id IN (1, 2, 3) OR NOT archived)
+//
+// Column references are case-insensitive and may be double-quoted.
+// Comparisons use SQL's three-valued logic, so that a comparison with a
+// null or missing value is neither true nor false. A row is retained
+// only if the predicate evaluates to true.
+package filter
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/pkg/errors"
+)
+
+// A Filter is a compiled row-filtering predicate. A Filter should not
+// be modified once it has been configured.
+type Filter struct {
+	// Deletions, or updates that no longer match the predicate, cannot
+	// be evaluated if the mutation does not carry the prior state of
+	// the row. By default, these mutations are passed through as
+	// deletions, since the row may have been replicated previously.
+	// If this field is set, they will instead be discarded.
+	DropUnknownDeletes bool
+
+	expr string
+	root node
+}
+
+// Parse compiles a predicate expression.
+func Parse(expr string) (*Filter, error) {
+	tokens, err := lex(expr)
+	if err != nil {
+		return nil, errors.Wrapf(err, "filter %q", expr)
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, errors.Wrapf(err, "filter %q", expr)
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, errors.Errorf("filter %q: unexpected %q at position %d", expr, tok.text, tok.pos)
+	}
+	return &Filter{expr: expr, root: root}, nil
+}
+
+// Apply evaluates the Filter against a mutation. It returns false if
+// the mutation should be discarded. An update whose prior state
+// matched the predicate, but whose new state does not, will be
+// returned as a deletion so that the row leaves the target. See also
+// [Filter.DropUnknownDeletes].
+func (f *Filter) Apply(mut types.Mutation) (types.Mutation, bool, error) {
+	hasBefore := len(mut.Before) > 0 && !bytes.Equal(mut.Before, nullBytes)
+
+	if mut.IsDelete() {
+		if !hasBefore {
+			return mut, !f.DropUnknownDeletes, nil
+		}
+		ok, err := f.MatchDocument(mut.Before)
+		return mut, ok, err
+	}
+
+	if ok, err := f.MatchDocument(mut.Data); err != nil || ok {
+		return mut, ok, err
+	}
+
+	// The new state of the row does not match. Determine if the row
+	// may have previously been replicated.
+	if hasBefore {
+		if ok, err := f.MatchDocument(mut.Before); err != nil || !ok {
+			return mut, false, err
+		}
+	} else if f.DropUnknownDeletes {
+		return mut, false, nil
+	}
+	mut.Data = nil
+	mut.Deletion = true
+	return mut, true, nil
+}
+
+// Equal returns true if the Filters have the same configuration.
+func (f *Filter) Equal(o *Filter) bool {
+	return f == o ||
+		(f != nil) && (o != nil) &&
+			f.expr == o.expr &&
+			f.DropUnknownDeletes == o.DropUnknownDeletes
+}
+
+// MarshalJSON reports the source text of the predicate.
+func (f *Filter) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		DropUnknownDeletes bool   `json:"dropUnknownDeletes,omitempty"`
+		Expr               string `json:"expr"`
+	}{f.DropUnknownDeletes, f.expr})
+}
+
+// Match evaluates the predicate, using the lookup function to resolve
+// column references. The lookup function should return nil for
+// unknown columns.
+func (f *Filter) Match(lookup func(ident.Ident) any) bool {
+	ret, _ := f.root.eval(lookup).(bool)
+	return ret
+}
+
+// MatchDocument evaluates the predicate against a JSON object. A null
+// or empty document does not match.
+func (f *Filter) MatchDocument(data json.RawMessage) (bool, error) {
+	if len(data) == 0 || bytes.Equal(data, nullBytes) {
+		return false, nil
+	}
+	var doc map[string]any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return false, errors.WithStack(err)
+	}
+	props := &ident.Map[any]{}
+	for k, v := range doc {
+		props.Put(ident.New(k), v)
+	}
+	return f.Match(props.GetZero), nil
+}
+
+// String returns the source text of the predicate.
+func (f *Filter) String() string {
+	return f.expr
+}
+
+var nullBytes = []byte("null")
@@ -0,0 +1,150 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package filter
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatch(t *testing.T) {
+	doc := json.RawMessage(`{
+  "Region": "us-east",
+  "tenant": 42,
+  "price": "19.99",
+  "archived": false,
+  "name": "O'Brien",
+  "nothing": null
+}`)
+
+	tcs := []struct {
+		expr     string
+		expected bool
+	}{
+		{`region = 'us-east'`, true},
+		{`"REGION" = 'us-east'`, true},
+		{`region <> 'us-east'`, false},
+		{`region != 'us-west'`, true},
+		{`tenant = 42`, true},
+		{`tenant >= 42.0 AND tenant < 43`, true},
+		{`tenant > 42`, false},
+		{`price > 10`, true},           // Numeric string.
+		{`price > '10'`, true},         // Lexical comparison.
+		{`price < '2'`, true},          // Lexical comparison.
+		{`name = 'O''Brien'`, true},    // Escaped quote.
+		{`tenant IN (1, 42, 3)`, true}, // List membership.
+		{`tenant NOT IN (1, 2)`, true},
+		{`tenant IN (1, NULL)`, false}, // Three-valued.
+		{`tenant NOT IN (1, NULL)`, false},
+		{`region IN ('us-east', 'us-west')`, true},
+		{`NOT archived`, true},
+		{`archived = false`, true},
+		{`archived`, false},
+		{`nothing IS NULL`, true},
+		{`missing IS NULL`, true},
+		{`region IS NOT NULL`, true},
+		{`nothing = 1`, false},
+		{`NOT (nothing = 1)`, false}, // Three-valued.
+		{`nothing = 1 OR tenant = 42`, true},
+		{`nothing = 1 AND tenant = 42`, false},
+		{`NOT (nothing = 1 AND tenant = 0)`, true},
+		{`region = 'us-west' OR (tenant = 42 AND NOT archived)`, true},
+		{`region = 42`, false}, // Not comparable.
+		{`1 = 1`, true},
+		{`tenant = -1`, false},
+		{`TRUE`, true},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.expr, func(t *testing.T) {
+			r := require.New(t)
+			f, err := Parse(tc.expr)
+			r.NoError(err)
+			r.Equal(tc.expr, f.String())
+			ok, err := f.MatchDocument(doc)
+			r.NoError(err)
+			r.Equal(tc.expected, ok)
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tcs := []struct {
+		expr     string
+		expected string
+	}{
+		{``, "unexpected end of expression"},
+		{`a =`, "unexpected end of expression"},
+		{`a = 'b`, "unterminated quote at position 4"},
+		{`(a = 1`, `expected ")" at end of expression`},
+		{`a = 1 b`, `unexpected "b" at position 6`},
+		{`a IS 1`, `expected "NULL" at position 5, found "1"`},
+		{`a IN (b)`, `unexpected "b" at position 6`},
+		{`a ! b`, `unexpected "!" at position 2`},
+		{`a = 1.2.3`, `invalid number "1.2.3" at position 4`},
+		{`a = $1`, `unexpected '$' at position 4`},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.expr, func(t *testing.T) {
+			_, err := Parse(tc.expr)
+			require.ErrorContains(t, err, tc.expected)
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	us := json.RawMessage(`{"pk":1,"region":"us"}`)
+	eu := json.RawMessage(`{"pk":1,"region":"eu"}`)
+	key := json.RawMessage(`[1]`)
+
+	tcs := []struct {
+		name       string
+		drop       bool
+		mut        types.Mutation
+		keep       bool
+		isDeletion bool
+	}{
+		{name: "upsert match", mut: types.Mutation{Data: us, Key: key}, keep: true},
+		{name: "upsert skip, before skip", mut: types.Mutation{Before: eu, Data: eu, Key: key}},
+		{name: "upsert leaves set", mut: types.Mutation{Before: us, Data: eu, Key: key}, keep: true, isDeletion: true},
+		{name: "upsert unknown", mut: types.Mutation{Data: eu, Key: key}, keep: true, isDeletion: true},
+		{name: "upsert unknown drop", drop: true, mut: types.Mutation{Data: eu, Key: key}},
+		{name: "delete match", mut: types.Mutation{Before: us, Key: key}, keep: true, isDeletion: true},
+		{name: "delete skip", mut: types.Mutation{Before: eu, Key: key}, isDeletion: true},
+		{name: "delete unknown", mut: types.Mutation{Key: key}, keep: true, isDeletion: true},
+		{name: "delete unknown drop", drop: true, mut: types.Mutation{Key: key}, isDeletion: true},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			r := require.New(t)
+			f, err := Parse(`region = 'us'`)
+			r.NoError(err)
+			f.DropUnknownDeletes = tc.drop
+
+			mut, keep, err := f.Apply(tc.mut)
+			r.NoError(err)
+			r.Equal(tc.keep, keep)
+			r.Equal(tc.isDeletion, mut.IsDelete())
+			r.Equal(tc.mut.Key, mut.Key)
+		})
+	}
+}
@@ -0,0 +1,338 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package filter
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/pkg/errors"
+)
+
+// tokenKind identifies the lexical class of a token.
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenKeyword
+	tokenNumber
+	tokenOperator
+	tokenString
+)
+
+type token struct {
+	kind tokenKind
+	pos  int
+	text string // Keywords are upper-cased.
+}
+
+var keywords = map[string]bool{
+	"AND": true, "FALSE": true, "IN": true, "IS": true,
+	"NOT": true, "NULL": true, "OR": true, "TRUE": true,
+}
+
+// lex splits the expression into tokens.
+func lex(expr string) ([]token, error) {
+	var ret []token
+	runes := []rune(expr)
+	for idx := 0; idx < len(runes); {
+		r := runes[idx]
+		start := idx
+		switch {
+		case unicode.IsSpace(r):
+			idx++
+			continue
+
+		case r == '\'' || r == '"':
+			// SQL-style quoting, where a doubled quote is an escape.
+			var sb strings.Builder
+			idx++
+			for {
+				if idx >= len(runes) {
+					return nil, errors.Errorf("unterminated quote at position %d", start)
+				}
+				if runes[idx] == r {
+					if idx+1 < len(runes) && runes[idx+1] == r {
+						sb.WriteRune(r)
+						idx += 2
+						continue
+					}
+					idx++
+					break
+				}
+				sb.WriteRune(runes[idx])
+				idx++
+			}
+			kind := tokenString
+			if r == '"' {
+				kind = tokenIdent
+			}
+			ret = append(ret, token{kind, start, sb.String()})
+
+		case unicode.IsDigit(r) || r == '-' || r == '.':
+			idx++
+			for idx < len(runes) && (unicode.IsDigit(runes[idx]) ||
+				strings.ContainsRune(".eE", runes[idx]) ||
+				((runes[idx] == '-' || runes[idx] == '+') && (runes[idx-1] == 'e' || runes[idx-1] == 'E'))) {
+				idx++
+			}
+			text := string(runes[start:idx])
+			if _, err := strconv.ParseFloat(text, 64); err != nil {
+				return nil, errors.Errorf("invalid number %q at position %d", text, start)
+			}
+			ret = append(ret, token{tokenNumber, start, text})
+
+		case unicode.IsLetter(r) || r == '_':
+			for idx < len(runes) && (unicode.IsLetter(runes[idx]) ||
+				unicode.IsDigit(runes[idx]) || runes[idx] == '_') {
+				idx++
+			}
+			text := string(runes[start:idx])
+			if upper := strings.ToUpper(text); keywords[upper] {
+				ret = append(ret, token{tokenKeyword, start, upper})
+			} else {
+				ret = append(ret, token{tokenIdent, start, text})
+			}
+
+		case strings.ContainsRune("(),", r):
+			idx++
+			ret = append(ret, token{tokenOperator, start, string(r)})
+
+		case strings.ContainsRune("=!<>", r):
+			idx++
+			if idx < len(runes) && (runes[idx] == '=' || (r == '<' && runes[idx] == '>')) {
+				idx++
+			}
+			text := string(runes[start:idx])
+			if text == "!" {
+				return nil, errors.Errorf("unexpected %q at position %d", text, start)
+			}
+			if text == "<>" {
+				text = "!="
+			}
+			ret = append(ret, token{tokenOperator, start, text})
+
+		default:
+			return nil, errors.Errorf("unexpected %q at position %d", r, start)
+		}
+	}
+	return append(ret, token{tokenEOF, len(runes), ""}), nil
+}
+
+// parser is a recursive-descent parser for the grammar:
+//
+//	or      := and ( OR and )*
+//	and     := not ( AND not )*
+//	not     := NOT not | compare
+//	compare := operand [ op operand | IS [NOT] NULL | [NOT] IN ( literal, ... ) ]
+//	operand := ( or ) | identifier | literal
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	ret := p.tokens[p.pos]
+	if ret.kind != tokenEOF {
+		p.pos++
+	}
+	return ret
+}
+
+// accept consumes the next token if it matches.
+func (p *parser) accept(kind tokenKind, text string) bool {
+	if tok := p.peek(); tok.kind == kind && tok.text == text {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(kind tokenKind, text string) error {
+	if p.accept(kind, text) {
+		return nil
+	}
+	tok := p.peek()
+	if tok.kind == tokenEOF {
+		return errors.Errorf("expected %q at end of expression", text)
+	}
+	return errors.Errorf("expected %q at position %d, found %q", text, tok.pos, tok.text)
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept(tokenKeyword, "OR") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept(tokenKeyword, "AND") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (node, error) {
+	if p.accept(tokenKeyword, "NOT") {
+		inner, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{inner}, nil
+	}
+	return p.parseCompare()
+}
+
+func (p *parser) parseCompare() (node, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+
+	tok := p.peek()
+	switch {
+	case tok.kind == tokenOperator && isComparison(tok.text):
+		p.next()
+		right, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		return &compareNode{left: left, op: tok.text, right: right}, nil
+
+	case p.accept(tokenKeyword, "IS"):
+		negate := p.accept(tokenKeyword, "NOT")
+		if err := p.expect(tokenKeyword, "NULL"); err != nil {
+			return nil, err
+		}
+		var ret node = &isNullNode{left}
+		if negate {
+			ret = &notNode{ret}
+		}
+		return ret, nil
+
+	case tok.kind == tokenKeyword && (tok.text == "IN" || tok.text == "NOT"):
+		negate := p.accept(tokenKeyword, "NOT")
+		if err := p.expect(tokenKeyword, "IN"); err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokenOperator, "("); err != nil {
+			return nil, err
+		}
+		in := &inNode{value: left}
+		for {
+			lit, err := p.parseLiteral()
+			if err != nil {
+				return nil, err
+			}
+			in.list = append(in.list, lit)
+			if !p.accept(tokenOperator, ",") {
+				break
+			}
+		}
+		if err := p.expect(tokenOperator, ")"); err != nil {
+			return nil, err
+		}
+		var ret node = in
+		if negate {
+			ret = &notNode{ret}
+		}
+		return ret, nil
+
+	default:
+		return left, nil
+	}
+}
+
+func (p *parser) parseOperand() (node, error) {
+	tok := p.peek()
+	switch {
+	case p.accept(tokenOperator, "("):
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(tokenOperator, ")"); err != nil {
+			return nil, err
+		}
+		return inner, nil
+	case tok.kind == tokenIdent:
+		p.next()
+		return &columnNode{ident.New(tok.text)}, nil
+	default:
+		lit, err := p.parseLiteral()
+		if err != nil {
+			return nil, err
+		}
+		return lit, nil
+	}
+}
+
+func (p *parser) parseLiteral() (*literalNode, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokenNumber:
+		f, _ := strconv.ParseFloat(tok.text, 64)
+		return &literalNode{f}, nil
+	case tokenString:
+		return &literalNode{tok.text}, nil
+	case tokenKeyword:
+		switch tok.text {
+		case "FALSE":
+			return &literalNode{false}, nil
+		case "NULL":
+			return &literalNode{nil}, nil
+		case "TRUE":
+			return &literalNode{true}, nil
+		}
+	case tokenEOF:
+		return nil, errors.New("unexpected end of expression")
+	}
+	return nil, errors.Errorf("unexpected %q at position %d", tok.text, tok.pos)
+}
+
+func isComparison(op string) bool {
+	switch op {
+	case "=", "!=", "<", "<=", ">", ">=":
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,218 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package kvstore contains an embedded, on-disk, ordered key-value
+// store. It is used to provide a local staging backend for deployments
+// that do not have access to a staging database.
+//
+// The store is a thin wrapper around a bbolt B+tree file, so the
+// amount of staged data is limited by disk space rather than memory.
+// Read-only transactions see a consistent snapshot and are not blocked
+// by a concurrent writer. The data file is locked when the store is
+// opened, so that it cannot be used by more than one process.
+package kvstore
+
+import (
+	"bytes"
+	"iter"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	dbName = "store.db"
+
+	// How long to wait for another process to release the data file.
+	lockTimeout = time.Second
+)
+
+// ErrClosed is returned when operating on a closed Store.
+var ErrClosed = errors.New("store is closed")
+
+// All entries are kept in a single bucket.
+var bucketName = []byte("data")
+
+// Store is an embedded, ordered key-value store. All methods are safe
+// to call concurrently.
+type Store struct {
+	db  *bolt.DB
+	dir string
+
+	mu struct {
+		sync.RWMutex
+		closed bool
+	}
+}
+
+// Open the Store contained in the directory, creating it if necessary.
+// An error will be returned if the Store is already open, either in
+// this process or another.
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	path := filepath.Join(dir, dbName)
+	db, err := bolt.Open(path, 0o644, &bolt.Options{Timeout: lockTimeout})
+	if errors.Is(err, bolt.ErrTimeout) {
+		return nil, errors.Errorf("local store %s is in use by another process", dir)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, path)
+	}
+	if err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bucketName)
+		return err
+	}); err != nil {
+		_ = db.Close()
+		return nil, errors.Wrap(err, path)
+	}
+	s := &Store{db: db, dir: dir}
+	log.WithFields(log.Fields{
+		"dir":     dir,
+		"entries": s.Len(),
+	}).Debug("opened local store")
+	return s, nil
+}
+
+// Close releases the underlying data file. It is safe to call Close
+// more than once.
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.mu.closed {
+		return nil
+	}
+	s.mu.closed = true
+	return errors.WithStack(s.db.Close())
+}
+
+// Len returns the number of entries in the Store.
+func (s *Store) Len() int {
+	var ret int
+	_ = s.View(func(tx *Tx) error {
+		ret = tx.b.Stats().KeyN
+		return nil
+	})
+	return ret
+}
+
+// Update executes the callback within a read-write transaction. The
+// changes made by the callback will be durably written if the callback
+// returns a nil error, otherwise they are discarded.
+func (s *Store) Update(fn func(tx *Tx) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.mu.closed {
+		return ErrClosed
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return fn(&Tx{b: tx.Bucket(bucketName)})
+	})
+}
+
+// View executes the callback within a read-only transaction.
+func (s *Store) View(fn func(tx *Tx) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.mu.closed {
+		return ErrClosed
+	}
+	return s.db.View(func(tx *bolt.Tx) error {
+		return fn(&Tx{b: tx.Bucket(bucketName)})
+	})
+}
+
+// A Tx provides access to the contents of a Store. Byte slices returned
+// from a Tx are copies, which remain valid after the transaction ends.
+type Tx struct {
+	b *bolt.Bucket
+}
+
+// Delete removes the key from the Store.
+func (tx *Tx) Delete(key []byte) error {
+	return errors.WithStack(tx.b.Delete(key))
+}
+
+// Get returns the value associated with the key, or nil if no such
+// key exists. Empty values are returned as a non-nil, empty slice.
+func (tx *Tx) Get(key []byte) []byte {
+	k, v := tx.b.Cursor().Seek(key)
+	if k == nil || !bytes.Equal(k, key) {
+		return nil
+	}
+	return clone(v)
+}
+
+// Put associates the value with the key. The key and value are copied.
+func (tx *Tx) Put(key, value []byte) error {
+	return errors.WithStack(tx.b.Put(clone(key), clone(value)))
+}
+
+// Scan returns the entries whose keys are within the half-open range
+// [start, end), in key order. A nil end value scans to the end of the
+// Store. The Tx must not be modified while the sequence is being
+// consumed.
+func (tx *Tx) Scan(start, end []byte) iter.Seq2[[]byte, []byte] {
+	return func(yield func([]byte, []byte) bool) {
+		c := tx.b.Cursor()
+		var k, v []byte
+		if len(start) == 0 {
+			k, v = c.First()
+		} else {
+			k, v = c.Seek(start)
+		}
+		for ; k != nil; k, v = c.Next() {
+			if end != nil && bytes.Compare(k, end) >= 0 {
+				return
+			}
+			if !yield(clone(k), clone(v)) {
+				return
+			}
+		}
+	}
+}
+
+// ScanPrefix returns all entries whose keys have the given prefix.
+func (tx *Tx) ScanPrefix(prefix []byte) iter.Seq2[[]byte, []byte] {
+	return tx.Scan(prefix, PrefixEnd(prefix))
+}
+
+// PrefixEnd returns the smallest key which is greater than all keys
+// having the given prefix. It returns nil if no such key exists.
+func PrefixEnd(prefix []byte) []byte {
+	end := append([]byte(nil), prefix...)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	return nil
+}
+
+// clone returns a non-nil copy of the data, so that empty values can
+// be distinguished from missing keys.
+func clone(data []byte) []byte {
+	ret := make([]byte, len(data))
+	copy(ret, data)
+	return ret
+}
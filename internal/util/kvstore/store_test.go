@@ -0,0 +1,171 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package kvstore
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func collect(tx *Tx, start, end []byte) []string {
+	var ret []string
+	for k, v := range tx.Scan(start, end) {
+		ret = append(ret, fmt.Sprintf("%s=%s", k, v))
+	}
+	return ret
+}
+
+func TestStore(t *testing.T) {
+	r := require.New(t)
+	dir := t.TempDir()
+
+	s, err := Open(dir)
+	r.NoError(err)
+
+	r.NoError(s.Update(func(tx *Tx) error {
+		for _, k := range []string{"b", "a", "c", "ab", "d"} {
+			if err := tx.Put([]byte(k), []byte("v"+k)); err != nil {
+				return err
+			}
+		}
+		return tx.Delete([]byte("d"))
+	}))
+
+	// A failed update should be rolled back.
+	r.EqualError(s.Update(func(tx *Tx) error {
+		r.NoError(tx.Put([]byte("a"), []byte("changed")))
+		r.NoError(tx.Put([]byte("z"), []byte("new")))
+		r.NoError(tx.Delete([]byte("b")))
+		return errors.New("boom")
+	}), "boom")
+
+	check := func(s *Store) {
+		r.NoError(s.View(func(tx *Tx) error {
+			r.Equal([]string{"a=va", "ab=vab", "b=vb", "c=vc"}, collect(tx, nil, nil))
+			r.Equal([]string{"ab=vab", "b=vb"}, collect(tx, []byte("aa"), []byte("c")))
+			var prefixed []string
+			for k := range tx.ScanPrefix([]byte("a")) {
+				prefixed = append(prefixed, string(k))
+			}
+			r.Equal([]string{"a", "ab"}, prefixed)
+			r.Equal([]byte("vb"), tx.Get([]byte("b")))
+			r.Nil(tx.Get([]byte("d")))
+			r.Error(tx.Put([]byte("x"), nil))
+			return nil
+		}))
+	}
+	check(s)
+	r.Equal(4, s.Len())
+
+	// Reopen from the log.
+	r.NoError(s.Close())
+	r.ErrorIs(s.View(func(*Tx) error { return nil }), ErrClosed)
+	s, err = Open(dir)
+	r.NoError(err)
+	check(s)
+
+	// Make further changes and reopen.
+	r.NoError(s.Update(func(tx *Tx) error {
+		return tx.Put([]byte("e"), []byte("ve"))
+	}))
+	r.NoError(s.Close())
+	s, err = Open(dir)
+	r.NoError(err)
+	r.Equal(5, s.Len())
+	r.NoError(s.Update(func(tx *Tx) error { return tx.Delete([]byte("e")) }))
+	check(s)
+	r.NoError(s.Close())
+}
+
+// Verify that a Store cannot be opened twice.
+func TestLocked(t *testing.T) {
+	r := require.New(t)
+	dir := t.TempDir()
+
+	s, err := Open(dir)
+	r.NoError(err)
+
+	_, err = Open(dir)
+	r.ErrorContains(err, "in use by another process")
+
+	r.NoError(s.Close())
+	s, err = Open(dir)
+	r.NoError(err)
+	r.NoError(s.Close())
+}
+
+// Verify that readers see a consistent snapshot and are not blocked
+// by a writer.
+func TestSnapshotRead(t *testing.T) {
+	r := require.New(t)
+	s, err := Open(t.TempDir())
+	r.NoError(err)
+	defer func() { r.NoError(s.Close()) }()
+
+	r.NoError(s.Update(func(tx *Tx) error {
+		return tx.Put([]byte("a"), []byte("before"))
+	}))
+
+	inWrite := make(chan struct{})
+	readDone := make(chan struct{})
+	writeErr := make(chan error, 1)
+	go func() {
+		writeErr <- s.Update(func(tx *Tx) error {
+			if err := tx.Put([]byte("a"), []byte("after")); err != nil {
+				return err
+			}
+			close(inWrite)
+			<-readDone
+			return nil
+		})
+	}()
+
+	<-inWrite
+	r.NoError(s.View(func(tx *Tx) error {
+		r.Equal([]byte("before"), tx.Get([]byte("a")))
+		return nil
+	}))
+	close(readDone)
+	r.NoError(<-writeErr)
+
+	r.NoError(s.View(func(tx *Tx) error {
+		r.Equal([]byte("after"), tx.Get([]byte("a")))
+		return nil
+	}))
+
+	// Empty values are distinguished from missing keys.
+	r.NoError(s.Update(func(tx *Tx) error {
+		return tx.Put([]byte("empty"), nil)
+	}))
+	r.NoError(s.View(func(tx *Tx) error {
+		r.NotNil(tx.Get([]byte("empty")))
+		r.Empty(tx.Get([]byte("empty")))
+		r.Nil(tx.Get([]byte("missing")))
+		return nil
+	}))
+}
+
+func TestPrefixEnd(t *testing.T) {
+	r := require.New(t)
+	r.Equal([]byte("b"), PrefixEnd([]byte("a")))
+	r.Equal([]byte("b"), PrefixEnd([]byte{'a', 0xff}))
+	r.Nil(PrefixEnd([]byte{0xff, 0xff}))
+	r.Nil(PrefixEnd(nil))
+}
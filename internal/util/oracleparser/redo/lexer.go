@@ -0,0 +1,139 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package redo
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+type tokenKind int
+
+const (
+	tokEOF    tokenKind = iota
+	tokIdent            // A quoted identifier.
+	tokNumber           // A numeric literal.
+	tokPunct            // One of ( ) , . = ;
+	tokString           // A string literal.
+	tokWord             // A keyword, function name, or unquoted identifier.
+)
+
+// A token has had any quotes removed from its text.
+type token struct {
+	kind tokenKind
+	text string
+}
+
+// source returns the token as it would appear in a statement.
+func (t token) source() string {
+	switch t.kind {
+	case tokIdent:
+		return `"` + strings.ReplaceAll(t.text, `"`, `""`) + `"`
+	case tokString:
+		return `'` + strings.ReplaceAll(t.text, `'`, `''`) + `'`
+	default:
+		return t.text
+	}
+}
+
+// String is for debugging use.
+func (t token) String() string {
+	if t.kind == tokEOF {
+		return "end of statement"
+	}
+	return fmt.Sprintf("%q", t.source())
+}
+
+// lex splits the statement into tokens, discarding whitespace.
+func lex(sql string) ([]token, error) {
+	var ret []token
+	runes := []rune(sql)
+	for idx := 0; idx < len(runes); {
+		r := runes[idx]
+		switch {
+		case unicode.IsSpace(r):
+			idx++
+
+		case r == '"' || r == '\'':
+			text, next, err := quoted(runes, idx)
+			if err != nil {
+				return nil, err
+			}
+			kind := tokString
+			if r == '"' {
+				kind = tokIdent
+			}
+			ret = append(ret, token{kind: kind, text: text})
+			idx = next
+
+		case strings.ContainsRune("(),.=;", r):
+			ret = append(ret, token{kind: tokPunct, text: string(r)})
+			idx++
+
+		case unicode.IsDigit(r) || r == '-' && idx+1 < len(runes) && unicode.IsDigit(runes[idx+1]):
+			start := idx
+			idx++
+			for idx < len(runes) && (unicode.IsDigit(runes[idx]) ||
+				strings.ContainsRune(".eE", runes[idx]) ||
+				strings.ContainsRune("+-", runes[idx]) && strings.ContainsRune("eE", runes[idx-1])) {
+				idx++
+			}
+			ret = append(ret, token{kind: tokNumber, text: string(runes[start:idx])})
+
+		case unicode.IsLetter(r):
+			// National character literals, e.g. N'text'.
+			if (r == 'N' || r == 'n') && idx+1 < len(runes) && runes[idx+1] == '\'' {
+				idx++
+				continue
+			}
+			start := idx
+			for idx < len(runes) && (unicode.IsLetter(runes[idx]) ||
+				unicode.IsDigit(runes[idx]) || strings.ContainsRune("_$#", runes[idx])) {
+				idx++
+			}
+			ret = append(ret, token{kind: tokWord, text: string(runes[start:idx])})
+
+		default:
+			return nil, errors.Errorf("unexpected character %q at offset %d", r, idx)
+		}
+	}
+	return ret, nil
+}
+
+// quoted reads the quoted text that begins at the index, returning the
+// unquoted text and the index following the closing quote. A quote
+// character is escaped by doubling it.
+func quoted(runes []rune, idx int) (string, int, error) {
+	q := runes[idx]
+	var sb strings.Builder
+	for idx++; idx < len(runes); idx++ {
+		if runes[idx] != q {
+			sb.WriteRune(runes[idx])
+			continue
+		}
+		if idx+1 < len(runes) && runes[idx+1] == q {
+			sb.WriteRune(q)
+			idx++
+			continue
+		}
+		return sb.String(), idx + 1, nil
+	}
+	return "", 0, errors.Errorf("unterminated quote %c", q)
+}
@@ -0,0 +1,431 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package redo parses the SQL_REDO statements that are produced by
+// Oracle LogMiner.
+//
+// The statements in V$LOGMNR_CONTENTS are machine-generated and use a
+// small, regular subset of PL/SQL: single-row INSERT, UPDATE, and
+// DELETE statements whose identifiers are always quoted and whose
+// values are literals or calls to conversion functions. Rather than
+// running the complete PL/SQL grammar for every redo record, this
+// package implements a scanner for that subset.
+//
+// The ANTLR grammar in the parent package is not used, since its
+// generated parser (plsql_parser.go) and the PlSqlLexerBase support
+// code are not checked in, so it cannot be built. Regenerating them
+// would add a large amount of generated code to parse a handful of
+// statement shapes. If that parser is restored, this package could be
+// reimplemented as a listener without changing its API.
+package redo
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Operation is the kind of DML statement.
+type Operation int
+
+// The kinds of statements that may be parsed.
+const (
+	Unknown Operation = iota
+	Insert
+	Update
+	Delete
+)
+
+//go:generate go run golang.org/x/tools/cmd/stringer -type=Operation
+
+// A Column associates a column name with a value. The value will be
+// nil for a NULL, a string for literals, or an Expr.
+type Column struct {
+	Name  string
+	Value any
+}
+
+// An Expr is a value that LogMiner rendered as a function call, such
+// as TO_DATE('2024-01-01 00:00:00','YYYY-MM-DD HH24:MI:SS'). The text
+// is normalized to remove whitespace outside of literals.
+type Expr string
+
+// Literal returns the value that an Expr represents, if it can be
+// determined without evaluating the expression in the source database.
+// Conversion functions, such as TO_DATE, return their first argument,
+// which is formatted according to the session's NLS parameters.
+// HEXTORAW returns its argument with a \x prefix, and empty LOB
+// locators return an empty string.
+func (e Expr) Literal() (any, bool) {
+	name, args, ok := strings.Cut(string(e), "(")
+	if !ok || !strings.HasSuffix(args, ")") {
+		return nil, false
+	}
+	args = strings.TrimSuffix(args, ")")
+	switch strings.ToUpper(name) {
+	case "EMPTY_BLOB", "EMPTY_CLOB":
+		return "", args == ""
+	case "HEXTORAW":
+		toks, err := lex(args)
+		if err != nil || len(toks) != 1 || toks[0].kind != tokString {
+			return nil, false
+		}
+		return `\x` + toks[0].text, true
+	case "TO_BINARY_DOUBLE", "TO_BINARY_FLOAT", "TO_DATE", "TO_DSINTERVAL",
+		"TO_NUMBER", "TO_TIMESTAMP", "TO_TIMESTAMP_TZ", "TO_YMINTERVAL":
+		toks, err := lex(args)
+		if err != nil || len(toks) == 0 {
+			return nil, false
+		}
+		switch toks[0].kind {
+		case tokString, tokNumber:
+			return toks[0].text, true
+		}
+	}
+	return nil, false
+}
+
+// A Statement is a parsed SQL_REDO statement.
+type Statement struct {
+	Operation Operation
+	Owner     string   // The schema containing the table; may be empty.
+	Table     string   // The name of the table.
+	Values    []Column // The inserted values or the SET clause of an update.
+	Where     []Column // The values in the WHERE clause, excluding the ROWID.
+}
+
+// Image returns the values of the row after an insert or an update, or
+// before a delete. Since the WHERE clause of an update contains the
+// previous values of the row, the complete row is only available if
+// supplemental logging of all columns is enabled.
+func (s *Statement) Image() []Column {
+	if s.Operation == Insert {
+		return s.Values
+	}
+	ret := make([]Column, len(s.Where), len(s.Where)+len(s.Values))
+	copy(ret, s.Where)
+	if s.Operation == Delete {
+		return ret
+	}
+outer:
+	for _, col := range s.Values {
+		for idx := range ret {
+			if ret[idx].Name == col.Name {
+				ret[idx].Value = col.Value
+				continue outer
+			}
+		}
+		ret = append(ret, col)
+	}
+	return ret
+}
+
+// Parse parses a single INSERT, UPDATE, or DELETE statement.
+func Parse(sql string) (*Statement, error) {
+	toks, err := lex(sql)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not parse %q", sql)
+	}
+	p := &parser{toks: toks}
+	ret, err := p.statement()
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not parse %q", sql)
+	}
+	return ret, nil
+}
+
+type parser struct {
+	toks []token
+	pos  int
+}
+
+// peek returns the next token without consuming it.
+func (p *parser) peek() token {
+	if p.pos >= len(p.toks) {
+		return token{kind: tokEOF}
+	}
+	return p.toks[p.pos]
+}
+
+// next consumes a token.
+func (p *parser) next() token {
+	ret := p.peek()
+	if ret.kind != tokEOF {
+		p.pos++
+	}
+	return ret
+}
+
+// keyword consumes the expected, case-insensitive keyword.
+func (p *parser) keyword(kw string) error {
+	if tok := p.next(); tok.kind != tokWord || !strings.EqualFold(tok.text, kw) {
+		return errors.Errorf("expecting %s, found %s", kw, tok)
+	}
+	return nil
+}
+
+// punct consumes the expected punctuation.
+func (p *parser) punct(text string) error {
+	if tok := p.next(); tok.kind != tokPunct || tok.text != text {
+		return errors.Errorf("expecting %s, found %s", text, tok)
+	}
+	return nil
+}
+
+// isPunct returns true if the next token is the punctuation.
+func (p *parser) isPunct(text string) bool {
+	tok := p.peek()
+	return tok.kind == tokPunct && tok.text == text
+}
+
+// isKeyword returns true if the next token is the keyword.
+func (p *parser) isKeyword(kw string) bool {
+	tok := p.peek()
+	return tok.kind == tokWord && strings.EqualFold(tok.text, kw)
+}
+
+func (p *parser) statement() (*Statement, error) {
+	ret := &Statement{}
+	tok := p.next()
+	if tok.kind != tokWord {
+		return nil, errors.Errorf("unexpected %s", tok)
+	}
+	var err error
+	switch strings.ToUpper(tok.text) {
+	case "INSERT":
+		ret.Operation = Insert
+		err = p.insert(ret)
+	case "UPDATE":
+		ret.Operation = Update
+		err = p.update(ret)
+	case "DELETE":
+		ret.Operation = Delete
+		err = p.delete(ret)
+	default:
+		return nil, errors.Errorf("unsupported statement %s", tok.text)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if p.isPunct(";") {
+		p.next()
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, errors.Errorf("unexpected %s", tok)
+	}
+	return ret, nil
+}
+
+// insert parses
+//
+//	INSERT INTO table (col, ...) VALUES (value, ...)
+func (p *parser) insert(stmt *Statement) error {
+	if err := p.keyword("INTO"); err != nil {
+		return err
+	}
+	if err := p.table(stmt); err != nil {
+		return err
+	}
+	if !p.isPunct("(") {
+		return errors.New("column list is not specified")
+	}
+	p.next()
+	var names []string
+	for {
+		name, err := p.ident()
+		if err != nil {
+			return err
+		}
+		names = append(names, name)
+		if !p.isPunct(",") {
+			break
+		}
+		p.next()
+	}
+	if err := p.punct(")"); err != nil {
+		return err
+	}
+	if err := p.keyword("VALUES"); err != nil {
+		return err
+	}
+	if err := p.punct("("); err != nil {
+		return err
+	}
+	for idx, name := range names {
+		if idx > 0 {
+			if err := p.punct(","); err != nil {
+				return err
+			}
+		}
+		value, err := p.value()
+		if err != nil {
+			return err
+		}
+		stmt.Values = append(stmt.Values, Column{Name: name, Value: value})
+	}
+	return p.punct(")")
+}
+
+// update parses
+//
+//	UPDATE table SET col = value, ... [WHERE conditions]
+func (p *parser) update(stmt *Statement) error {
+	if err := p.table(stmt); err != nil {
+		return err
+	}
+	if err := p.keyword("SET"); err != nil {
+		return err
+	}
+	for {
+		name, err := p.ident()
+		if err != nil {
+			return err
+		}
+		if err := p.punct("="); err != nil {
+			return err
+		}
+		value, err := p.value()
+		if err != nil {
+			return err
+		}
+		stmt.Values = append(stmt.Values, Column{Name: name, Value: value})
+		if !p.isPunct(",") {
+			break
+		}
+		p.next()
+	}
+	return p.where(stmt)
+}
+
+// delete parses
+//
+//	DELETE FROM table [WHERE conditions]
+func (p *parser) delete(stmt *Statement) error {
+	if err := p.keyword("FROM"); err != nil {
+		return err
+	}
+	if err := p.table(stmt); err != nil {
+		return err
+	}
+	return p.where(stmt)
+}
+
+// where parses an optional clause of the form
+//
+//	WHERE col = value AND col IS NULL AND ROWID = 'rowid'
+func (p *parser) where(stmt *Statement) error {
+	if !p.isKeyword("WHERE") {
+		return nil
+	}
+	p.next()
+	for {
+		isRowID := p.isKeyword("ROWID")
+		name, err := p.ident()
+		if err != nil {
+			return err
+		}
+		var value any
+		if p.isKeyword("IS") {
+			p.next()
+			if err := p.keyword("NULL"); err != nil {
+				return err
+			}
+		} else {
+			if err := p.punct("="); err != nil {
+				return err
+			}
+			value, err = p.value()
+			if err != nil {
+				return err
+			}
+		}
+		if !isRowID {
+			stmt.Where = append(stmt.Where, Column{Name: name, Value: value})
+		}
+		if !p.isKeyword("AND") {
+			return nil
+		}
+		p.next()
+	}
+}
+
+// table parses a possibly-qualified table name.
+func (p *parser) table(stmt *Statement) error {
+	name, err := p.ident()
+	if err != nil {
+		return err
+	}
+	if p.isPunct(".") {
+		p.next()
+		stmt.Owner = name
+		name, err = p.ident()
+		if err != nil {
+			return err
+		}
+	}
+	stmt.Table = name
+	return nil
+}
+
+// ident parses an identifier. Unquoted identifiers are converted to
+// upper case, as Oracle does.
+func (p *parser) ident() (string, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokIdent:
+		return tok.text, nil
+	case tokWord:
+		return strings.ToUpper(tok.text), nil
+	default:
+		return "", errors.Errorf("expecting identifier, found %s", tok)
+	}
+}
+
+// value parses a literal, NULL, or a function call.
+func (p *parser) value() (any, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokString, tokNumber:
+		return tok.text, nil
+	case tokWord:
+		if !p.isPunct("(") {
+			if strings.EqualFold(tok.text, "NULL") {
+				return nil, nil
+			}
+			return nil, errors.Errorf("unexpected %s", tok)
+		}
+		var sb strings.Builder
+		sb.WriteString(tok.text)
+		depth := 0
+		for {
+			tok := p.next()
+			switch {
+			case tok.kind == tokEOF:
+				return nil, errors.New("unterminated function call")
+			case tok.kind == tokPunct && tok.text == "(":
+				depth++
+			case tok.kind == tokPunct && tok.text == ")":
+				depth--
+			}
+			sb.WriteString(tok.source())
+			if depth == 0 {
+				return Expr(sb.String()), nil
+			}
+		}
+	default:
+		return nil, errors.Errorf("expecting value, found %s", tok)
+	}
+}
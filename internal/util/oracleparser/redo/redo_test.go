@@ -0,0 +1,77 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package redo
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/datadriven"
+)
+
+// format renders a value for the test output.
+func format(value any) string {
+	switch t := value.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return fmt.Sprintf("%q", t)
+	case Expr:
+		return fmt.Sprintf("Expr(%s)", t)
+	default:
+		return fmt.Sprintf("%T(%v)", t, t)
+	}
+}
+
+func TestDataDriven(t *testing.T) {
+	datadriven.Walk(t, "./testdata", func(t *testing.T, path string) {
+		datadriven.RunTest(t, path, func(t *testing.T, td *datadriven.TestData) string {
+			var sb strings.Builder
+			switch td.Cmd {
+			case "parse":
+				stmt, err := Parse(td.Input)
+				if err != nil {
+					return "ERROR: " + err.Error()
+				}
+				fmt.Fprintf(&sb, "%s %q.%q\n", stmt.Operation, stmt.Owner, stmt.Table)
+				for _, col := range stmt.Values {
+					fmt.Fprintf(&sb, "value %s = %s\n", col.Name, format(col.Value))
+				}
+				for _, col := range stmt.Where {
+					fmt.Fprintf(&sb, "where %s = %s\n", col.Name, format(col.Value))
+				}
+				for _, col := range stmt.Image() {
+					fmt.Fprintf(&sb, "image %s = %s\n", col.Name, format(col.Value))
+				}
+
+			case "literal":
+				for _, line := range strings.Split(td.Input, "\n") {
+					if value, ok := Expr(line).Literal(); ok {
+						fmt.Fprintf(&sb, "%s => %s\n", line, format(value))
+					} else {
+						fmt.Fprintf(&sb, "%s => not a literal\n", line)
+					}
+				}
+
+			default:
+				t.Fatalf("unknown command %s", td.Cmd)
+			}
+			return sb.String()
+		})
+	})
+}
@@ -0,0 +1,67 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package snapshot
+
+import (
+	"github.com/pkg/errors"
+	"github.com/spf13/pflag"
+)
+
+const (
+	defaultBatchSize   = 10_000
+	defaultParallelism = 4
+)
+
+// Config controls the initial snapshot of a logical replication source.
+type Config struct {
+	// The maximum number of rows to read from a table at once.
+	BatchSize int
+	// Copy the contents of the source tables before streaming changes.
+	Enabled bool
+	// The number of tables to copy concurrently.
+	Parallelism int
+}
+
+// Bind adds flags to the set.
+func (c *Config) Bind(f *pflag.FlagSet) {
+	f.BoolVar(&c.Enabled, "snapshot", false,
+		"copy the contents of the source tables into the target before streaming changes; "+
+			"the snapshot is only taken if replication has not already started")
+	f.IntVar(&c.BatchSize, "snapshotBatchSize", defaultBatchSize,
+		"the maximum number of rows to read from a source table at once during the snapshot")
+	f.IntVar(&c.Parallelism, "snapshotParallelism", defaultParallelism,
+		"the number of source tables to copy concurrently during the snapshot")
+}
+
+// Preflight updates the configuration with sane defaults or returns an
+// error if there are missing options for which a default cannot be
+// provided.
+func (c *Config) Preflight() error {
+	if c.BatchSize == 0 {
+		c.BatchSize = defaultBatchSize
+	}
+	if c.BatchSize < 0 {
+		return errors.New("snapshotBatchSize must be positive")
+	}
+	if c.Parallelism == 0 {
+		c.Parallelism = defaultParallelism
+	}
+	if c.Parallelism < 0 {
+		return errors.New("snapshotParallelism must be positive")
+	}
+	return nil
+}
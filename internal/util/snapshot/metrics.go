@@ -0,0 +1,41 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package snapshot
+
+import (
+	"time"
+
+	"github.com/cockroachdb/replicator/internal/util/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var (
+	snapshotRowCount = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "snapshot_row_total",
+		Help: "the number of rows copied by the initial snapshot",
+	}, metrics.TableLabels)
+	snapshotTableDurations = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "snapshot_table_duration_seconds",
+		Help:    "the length of time it took to copy a table during the initial snapshot",
+		Buckets: metrics.Buckets(1, (24 * time.Hour).Seconds()),
+	}, metrics.TableLabels)
+	snapshotTablesRemaining = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "snapshot_tables_remaining",
+		Help: "the number of tables that have not yet been copied by the initial snapshot",
+	})
+)
@@ -0,0 +1,306 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package snapshot copies the contents of source tables into the
+// target, so that a logical replication source can be started without
+// a separate dump and restore of the data.
+//
+// Each table is read in primary-key order, in pages, from a consistent
+// view of the source database. The last key that was copied from each
+// table is recorded, so that a partially-completed snapshot can be
+// resumed after a restart. A resumed snapshot will read the remaining
+// rows from a newer view of the source. This is safe, because the
+// change stream is replayed from the position of the original view,
+// and the replayed changes will converge the target.
+//
+// Tables are copied concurrently, so foreign-key constraints between
+// target tables may be violated while the snapshot is in progress.
+package snapshot
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/replicator/internal/script"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/cockroachdb/replicator/internal/util/metrics"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/sync/errgroup"
+)
+
+// A Table describes a source table to be copied.
+type Table struct {
+	Columns []ident.Ident // The columns to copy, in source order.
+	Key     []ident.Ident // The primary key columns.
+	Source  ident.Table   // The table within the source database.
+	Target  ident.Table   // The table within the target schema.
+}
+
+// A Reader provides access to a consistent view of the source
+// database. A Reader is used by a single goroutine.
+type Reader interface {
+	// Read returns up to limit rows of the table, ordered by the
+	// primary key, whose keys are greater than after. The first rows
+	// in the table are returned if after is nil. Each row contains one
+	// value for each of the table's columns, which must be encodable
+	// as JSON.
+	Read(ctx context.Context, table *Table, after []any, limit int) ([][]any, error)
+	// Close releases the view of the source database.
+	Close() error
+}
+
+// A Copier copies source tables into the target.
+type Copier struct {
+	// The destination for the copied rows.
+	Acceptor types.TableAcceptor
+	// Controls the size and concurrency of the snapshot.
+	Config *Config
+	// The memo key used to record the State.
+	Key string
+	// Persistent storage for the State.
+	Memo types.Memo
+	// Identifies the source in userscript metadata.
+	Source string
+	// Access to the staging cluster.
+	StagingPool *types.StagingPool
+	// If present, each page of rows is applied within a transaction on
+	// a dedicated connection, so that large pages may be bulk-loaded.
+	TargetPool *types.TargetPool
+}
+
+// Run copies the tables that have not yet been copied, using one
+// goroutine per reader, and marks the snapshot as done. The state is
+// updated and stored as the copy progresses. The readers will be
+// closed.
+func (c *Copier) Run(
+	ctx context.Context, state *State, tables []*Table, readers []Reader,
+) error {
+	defer func() {
+		for _, r := range readers {
+			if err := r.Close(); err != nil {
+				log.WithError(err).Warn("could not close snapshot reader")
+			}
+		}
+	}()
+	if len(readers) == 0 {
+		return errors.New("no snapshot readers were provided")
+	}
+	if state.Tables == nil {
+		state.Tables = make(map[string]*TableState)
+	}
+
+	var pending []*Table
+	for _, table := range tables {
+		if len(table.Key) == 0 {
+			return errors.Errorf("table %s has no primary key and cannot be copied", table.Source)
+		}
+		ts, ok := state.Tables[table.Source.Raw()]
+		if !ok {
+			ts = &TableState{}
+			state.Tables[table.Source.Raw()] = ts
+		}
+		if !ts.Done {
+			pending = append(pending, table)
+		}
+	}
+	log.Infof("copying %d of %d tables", len(pending), len(tables))
+	snapshotTablesRemaining.Set(float64(len(pending)))
+
+	// The state is shared between the workers.
+	var mu sync.Mutex
+	progress := func(ctx context.Context, table *Table, key json.RawMessage, done bool) error {
+		mu.Lock()
+		defer mu.Unlock()
+		ts := state.Tables[table.Source.Raw()]
+		ts.LastKey = key
+		ts.Done = done
+		if done {
+			snapshotTablesRemaining.Dec()
+		}
+		return Store(ctx, c.Memo, c.StagingPool, c.Key, state)
+	}
+	lastKey := func(table *Table) json.RawMessage {
+		mu.Lock()
+		defer mu.Unlock()
+		return state.Tables[table.Source.Raw()].LastKey
+	}
+
+	work := make(chan *Table, len(pending))
+	for _, table := range pending {
+		work <- table
+	}
+	close(work)
+
+	eg, egCtx := errgroup.WithContext(ctx)
+	for _, reader := range readers {
+		eg.Go(func() error {
+			for table := range work {
+				if err := c.copyTable(egCtx, reader, state, table, lastKey(table), progress); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+	if err := eg.Wait(); err != nil {
+		return err
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	state.Done = true
+	if err := Store(ctx, c.Memo, c.StagingPool, c.Key, state); err != nil {
+		return err
+	}
+	log.Info("initial snapshot complete")
+	return nil
+}
+
+// copyTable copies the rows of the table that follow the last key.
+func (c *Copier) copyTable(
+	ctx context.Context,
+	reader Reader,
+	state *State,
+	table *Table,
+	lastKey json.RawMessage,
+	progress func(context.Context, *Table, json.RawMessage, bool) error,
+) error {
+	after, err := decodeKey(lastKey)
+	if err != nil {
+		return errors.Wrap(err, table.Source.Raw())
+	}
+	keyIdx := make([]int, len(table.Key))
+	for idx, key := range table.Key {
+		keyIdx[idx] = -1
+		for colIdx, col := range table.Columns {
+			if ident.Equal(key, col) {
+				keyIdx[idx] = colIdx
+				break
+			}
+		}
+		if keyIdx[idx] < 0 {
+			return errors.Errorf("key column %s is not copied from %s", key, table.Source)
+		}
+	}
+
+	if after == nil {
+		log.Infof("copying %s", table.Source)
+	} else {
+		log.Infof("resuming copy of %s", table.Source)
+	}
+	labels := metrics.TableValues(table.Target)
+	rowCount := snapshotRowCount.WithLabelValues(labels...)
+	start := time.Now()
+
+	for {
+		rows, err := reader.Read(ctx, table, after, c.Config.BatchSize)
+		if err != nil {
+			return errors.Wrapf(err, "could not read from %s", table.Source)
+		}
+
+		if len(rows) > 0 {
+			batch := &types.TableBatch{
+				Table: table.Target,
+				Time:  state.Time,
+			}
+			for _, row := range rows {
+				mut, key, err := toMutation(table, keyIdx, row)
+				if err != nil {
+					return err
+				}
+				mut.Time = state.Time
+				script.AddMeta(c.Source, table.Target, &mut)
+				if err := batch.Accumulate(table.Target, mut); err != nil {
+					return err
+				}
+				after = key
+			}
+			if err := c.accept(ctx, batch); err != nil {
+				return err
+			}
+			rowCount.Add(float64(len(rows)))
+		}
+
+		done := len(rows) < c.Config.BatchSize
+		var encoded json.RawMessage
+		if after != nil {
+			encoded, err = json.Marshal(after)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+		}
+		if err := progress(ctx, table, encoded, done); err != nil {
+			return err
+		}
+		if done {
+			break
+		}
+	}
+	snapshotTableDurations.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+	log.Infof("copied %s", table.Source)
+	return nil
+}
+
+// accept applies the batch, within a target transaction if a
+// TargetPool has been provided.
+func (c *Copier) accept(ctx context.Context, batch *types.TableBatch) error {
+	if c.TargetPool == nil {
+		return c.Acceptor.AcceptTableBatch(ctx, batch, &types.AcceptOptions{})
+	}
+	tx, err := c.TargetPool.BeginConnTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+	if err := c.Acceptor.AcceptTableBatch(ctx, batch, &types.AcceptOptions{
+		TargetQuerier: tx,
+	}); err != nil {
+		return err
+	}
+	return errors.WithStack(tx.Commit())
+}
+
+// toMutation converts a row into an upsert, returning the key values.
+func toMutation(table *Table, keyIdx []int, row []any) (types.Mutation, []any, error) {
+	var mut types.Mutation
+	if len(row) != len(table.Columns) {
+		return mut, nil, errors.Errorf("expecting %d columns from %s, got %d",
+			len(table.Columns), table.Source, len(row))
+	}
+	data := make(map[string]any, len(row))
+	for idx, col := range table.Columns {
+		data[col.Raw()] = row[idx]
+	}
+	key := make([]any, len(keyIdx))
+	for idx, colIdx := range keyIdx {
+		key[idx] = row[colIdx]
+	}
+
+	var err error
+	mut.Data, err = json.Marshal(data)
+	if err != nil {
+		return mut, nil, errors.WithStack(err)
+	}
+	mut.Key, err = json.Marshal(key)
+	if err != nil {
+		return mut, nil, errors.WithStack(err)
+	}
+	return mut, key, nil
+}
@@ -0,0 +1,221 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package snapshot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/cockroachdb/replicator/internal/staging/memo"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/hlc"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeReader serves rows from memory. The first column of each row is
+// an integer key.
+type fakeReader struct {
+	closed bool
+	data   map[string][][]any
+}
+
+var _ Reader = (*fakeReader)(nil)
+
+func (r *fakeReader) Read(
+	_ context.Context, table *Table, after []any, limit int,
+) ([][]any, error) {
+	var ret [][]any
+	for _, row := range r.data[table.Source.Raw()] {
+		if after != nil {
+			// Keys may be restored from JSON as strings.
+			last, err := strconv.Atoi(fmt.Sprint(after[0]))
+			if err != nil {
+				return nil, err
+			}
+			if row[0].(int) <= last {
+				continue
+			}
+		}
+		ret = append(ret, row)
+		if len(ret) == limit {
+			break
+		}
+	}
+	return ret, nil
+}
+
+func (r *fakeReader) Close() error {
+	r.closed = true
+	return nil
+}
+
+// recorder accumulates the keys of the mutations it receives.
+type recorder struct {
+	mu   sync.Mutex
+	keys map[string][]string
+}
+
+var _ types.TableAcceptor = (*recorder)(nil)
+
+func (r *recorder) AcceptTableBatch(
+	_ context.Context, batch *types.TableBatch, _ *types.AcceptOptions,
+) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.keys == nil {
+		r.keys = make(map[string][]string)
+	}
+	for _, mut := range batch.Data {
+		r.keys[batch.Table.Raw()] = append(r.keys[batch.Table.Raw()], string(mut.Key))
+	}
+	return nil
+}
+
+func testTables(t *testing.T, rowCount int) ([]*Table, map[string][][]any) {
+	t.Helper()
+	source := ident.MustSchema(ident.New("source"), ident.Public)
+	target := ident.MustSchema(ident.New("target"), ident.Public)
+	var tables []*Table
+	data := make(map[string][][]any)
+	for _, name := range []string{"t1", "t2"} {
+		table := &Table{
+			Columns: []ident.Ident{ident.New("pk"), ident.New("val")},
+			Key:     []ident.Ident{ident.New("pk")},
+			Source:  ident.NewTable(source, ident.New(name)),
+			Target:  ident.NewTable(target, ident.New(name)),
+		}
+		tables = append(tables, table)
+		for i := 0; i < rowCount; i++ {
+			data[table.Source.Raw()] = append(data[table.Source.Raw()],
+				[]any{i, fmt.Sprintf("%s-%d", name, i)})
+		}
+	}
+	return tables, data
+}
+
+func TestCopier(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+	ctx := context.Background()
+
+	const rowCount = 10
+	tables, data := testTables(t, rowCount)
+	rec := &recorder{}
+	m := &memo.Memory{}
+	copier := &Copier{
+		Acceptor: rec,
+		Config:   &Config{BatchSize: 3, Parallelism: 2},
+		Key:      "snapshot",
+		Memo:     m,
+		Source:   "test",
+	}
+	readers := []Reader{&fakeReader{data: data}, &fakeReader{data: data}}
+
+	state := &State{Position: "pos", Time: hlc.New(1, 0)}
+	r.NoError(copier.Run(ctx, state, tables, readers))
+	for _, reader := range readers {
+		a.True(reader.(*fakeReader).closed)
+	}
+
+	for _, table := range tables {
+		keys := rec.keys[table.Target.Raw()]
+		r.Len(keys, rowCount)
+		for i, key := range keys {
+			a.Equal(fmt.Sprintf("[%d]", i), key)
+		}
+	}
+
+	loaded, err := Load(ctx, m, nil, "snapshot")
+	r.NoError(err)
+	r.NotNil(loaded)
+	a.True(loaded.Done)
+	a.Equal("pos", loaded.Position)
+	a.Equal(hlc.New(1, 0), loaded.Time)
+	for _, table := range tables {
+		ts := loaded.Tables[table.Source.Raw()]
+		r.NotNil(ts)
+		a.True(ts.Done)
+		a.JSONEq(fmt.Sprintf("[%d]", rowCount-1), string(ts.LastKey))
+	}
+}
+
+func TestCopierResume(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+	ctx := context.Background()
+
+	const rowCount = 10
+	tables, data := testTables(t, rowCount)
+	rec := &recorder{}
+	copier := &Copier{
+		Acceptor: rec,
+		Config:   &Config{BatchSize: 4, Parallelism: 1},
+		Key:      "snapshot",
+		Memo:     &memo.Memory{},
+		Source:   "test",
+	}
+
+	// The first table was completed and the second was interrupted.
+	state := &State{
+		Tables: map[string]*TableState{
+			tables[0].Source.Raw(): {Done: true, LastKey: json.RawMessage(`[9]`)},
+			tables[1].Source.Raw(): {LastKey: json.RawMessage(`[5]`)},
+		},
+		Time: hlc.New(1, 0),
+	}
+	r.NoError(copier.Run(ctx, state, tables, []Reader{&fakeReader{data: data}}))
+	a.True(state.Done)
+	a.Empty(rec.keys[tables[0].Target.Raw()])
+	a.Equal([]string{"[6]", "[7]", "[8]", "[9]"}, rec.keys[tables[1].Target.Raw()])
+}
+
+func TestCopierNoKey(t *testing.T) {
+	r := require.New(t)
+	tables, data := testTables(t, 1)
+	tables[0].Key = nil
+	copier := &Copier{
+		Acceptor: &recorder{},
+		Config:   &Config{BatchSize: 1, Parallelism: 1},
+		Key:      "snapshot",
+		Memo:     &memo.Memory{},
+	}
+	err := copier.Run(context.Background(), &State{Time: hlc.New(1, 0)},
+		tables, []Reader{&fakeReader{data: data}})
+	r.ErrorContains(err, "has no primary key")
+}
+
+func TestDecodeKey(t *testing.T) {
+	a := assert.New(t)
+	r := require.New(t)
+
+	key, err := decodeKey(nil)
+	r.NoError(err)
+	a.Nil(key)
+
+	key, err = decodeKey(json.RawMessage(`[12345678901234567890, "abc", null]`))
+	r.NoError(err)
+	a.Equal([]any{"12345678901234567890", "abc", nil}, key)
+
+	_, err = decodeKey(json.RawMessage(`{`))
+	a.ErrorContains(err, "could not decode")
+}
@@ -0,0 +1,102 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/hlc"
+	"github.com/pkg/errors"
+)
+
+// State records the progress of a snapshot.
+type State struct {
+	// Set once all tables have been copied.
+	Done bool `json:"done,omitempty"`
+	// The position in the source's change stream that corresponds to
+	// the snapshot. Streaming must resume from this position, which is
+	// encoded by the source. This may be empty if the source tracks
+	// the position itself.
+	Position string `json:"position,omitempty"`
+	// The progress of each table, keyed by the raw source table name.
+	Tables map[string]*TableState `json:"tables,omitempty"`
+	// The time assigned to the copied mutations.
+	Time hlc.Time `json:"time"`
+}
+
+// TableState records the progress of copying a table.
+type TableState struct {
+	// Set once all rows have been copied.
+	Done bool `json:"done,omitempty"`
+	// The primary key of the last row that was copied.
+	LastKey json.RawMessage `json:"lastKey,omitempty"`
+}
+
+// Load returns the state of the snapshot that is stored under the
+// key. A nil value is returned if no snapshot has been started.
+func Load(
+	ctx context.Context, memo types.Memo, tx types.StagingQuerier, key string,
+) (*State, error) {
+	data, err := memo.Get(ctx, tx, key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+	ret := &State{}
+	if err := json.Unmarshal(data, ret); err != nil {
+		return nil, errors.Wrapf(err, "could not decode snapshot state %s", key)
+	}
+	return ret, nil
+}
+
+// Store records the state of the snapshot under the key.
+func Store(
+	ctx context.Context, memo types.Memo, tx types.StagingQuerier, key string, state *State,
+) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	return memo.Put(ctx, tx, key, data)
+}
+
+// decodeKey restores a key that was recorded by a [TableState]. Numeric
+// values are returned as strings, to avoid any loss of precision. The
+// source database is expected to coerce the value to the type of the
+// key column.
+func decodeKey(data json.RawMessage) ([]any, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var ret []any
+	if err := dec.Decode(&ret); err != nil {
+		return nil, errors.Wrap(err, "could not decode snapshot key")
+	}
+	for idx, value := range ret {
+		if num, ok := value.(json.Number); ok {
+			ret[idx] = num.String()
+		}
+	}
+	return ret, nil
+}
@@ -25,6 +25,7 @@ import (
 	_ "net/http/pprof" // Register pprof handlers.
 	"runtime"
 	"runtime/debug"
+	"strings"
 	"time"
 
 	"github.com/cockroachdb/field-eng-powertools/stopper"
@@ -49,6 +50,10 @@ func init() {
 	runtime.SetMutexProfileFraction(1000)
 }
 
+// AdminPath is the prefix under which the handler provided by
+// [HasAdmin] will be mounted.
+const AdminPath = "/_/admin/"
+
 // MetricsAddrFlag is a global flag that will start an HTTP server.
 const MetricsAddrFlag = "metricsAddr"
 
@@ -57,7 +62,27 @@ type Config interface {
 	Bind(set *pflag.FlagSet)
 }
 
+// HasAdmin allows the object to supply administrative endpoints, which
+// will be mounted under [AdminPath]. The handler is responsible for
+// authenticating requests with the given [types.Authenticator]. The
+// endpoints are only mounted if the object also implements
+// [HasAdminAuthenticator] or [HasAuthenticator] and returns a non-nil
+// value.
+type HasAdmin interface {
+	GetAdminHandler(auth types.Authenticator) http.Handler
+}
+
+// HasAdminAuthenticator allows the object to supply a
+// [types.Authenticator] which only protects the endpoints under
+// [AdminPath]. It takes precedence over [HasAuthenticator] for those
+// endpoints and does not affect the diagnostic endpoints.
+type HasAdminAuthenticator interface {
+	GetAdminAuthenticator() types.Authenticator
+}
+
 // HasAuthenticator allows the object to supply a [types.Authenticator].
+// If the object does not implement this interface, or returns nil, the
+// diagnostic endpoints will not require authentication.
 type HasAuthenticator interface {
 	GetAuthenticator() types.Authenticator
 }
@@ -117,7 +142,14 @@ func New(t *Template) *cobra.Command {
 			var auth types.Authenticator
 			if x, ok := started.(HasAuthenticator); ok {
 				auth = x.GetAuthenticator()
-			} else {
+			}
+			// The administrative endpoints can modify the replication
+			// process, so they are never served without authentication.
+			adminAuth := auth
+			if x, ok := started.(HasAdminAuthenticator); ok {
+				adminAuth = x.GetAdminAuthenticator()
+			}
+			if auth == nil {
 				auth = trust.New()
 			}
 
@@ -130,15 +162,24 @@ func New(t *Template) *cobra.Command {
 				diags = diag.New(stopper.From(cmd.Context()))
 			}
 
+			var admin http.Handler
+			if x, ok := started.(HasAdmin); ok {
+				if adminAuth != nil {
+					admin = x.GetAdminHandler(adminAuth)
+				} else {
+					log.Warnf("no authenticator available; not serving %s", AdminPath)
+				}
+			}
+
 			// Start metrics on a separate port or bind to an existing mux.
 			if metricsAddr != "" {
-				cancelServer, err := MetricsServer(auth, metricsAddr, diags)
+				cancelServer, err := MetricsServer(auth, metricsAddr, diags, admin)
 				if err != nil {
 					return err
 				}
 				defer cancelServer()
 			} else if x, ok := started.(HasServeMux); ok {
-				AddHandlers(auth, x.GetServeMux(), diags)
+				AddHandlers(auth, x.GetServeMux(), diags, admin)
 			}
 
 			if t.testCallback != nil {
@@ -158,8 +199,11 @@ func New(t *Template) *cobra.Command {
 	return cmd
 }
 
-// AddHandlers populates the ServeMux with diagnostic endpoints.
-func AddHandlers(auth types.Authenticator, mux *http.ServeMux, diags *diag.Diagnostics) {
+// AddHandlers populates the ServeMux with diagnostic endpoints. The
+// admin handler is optional and will be mounted under [AdminPath].
+func AddHandlers(
+	auth types.Authenticator, mux *http.ServeMux, diags *diag.Diagnostics, admin http.Handler,
+) {
 	// The pprof handlers attach themselves to the system-default mux.
 	// The index page also assumes that the handlers are reachable from
 	// this specific prefix. It seems unlikely that this would collide
@@ -174,15 +218,18 @@ func AddHandlers(auth types.Authenticator, mux *http.ServeMux, diags *diag.Diagn
 				EnableOpenMetrics: true,
 				ErrorLog:          log.StandardLogger().WithField("promhttp", "true"),
 			})))
+	if admin != nil {
+		mux.Handle(AdminPath, http.StripPrefix(strings.TrimSuffix(AdminPath, "/"), admin))
+	}
 	mux.Handle("/_/", http.NotFoundHandler()) // Reserve all under /_/
 }
 
 // MetricsServer starts a trivial HTTP server which runs until canceled.
 func MetricsServer(
-	auth types.Authenticator, bindAddr string, diags *diag.Diagnostics,
+	auth types.Authenticator, bindAddr string, diags *diag.Diagnostics, admin http.Handler,
 ) (func(), error) {
 	mux := &http.ServeMux{}
-	AddHandlers(auth, mux, diags)
+	AddHandlers(auth, mux, diags, admin)
 	mux.HandleFunc("/_/healthz", func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		_, _ = w.Write([]byte("OK"))
@@ -18,12 +18,16 @@ package stdlogical
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"net/http"
 	"testing"
 	"time"
 
 	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/replicator/internal/types"
+	"github.com/cockroachdb/replicator/internal/util/auth/trust"
+	"github.com/cockroachdb/replicator/internal/util/ident"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/require"
@@ -73,3 +77,97 @@ func TestSmoke(t *testing.T) {
 
 	cancel()
 }
+
+type adminOnly struct{}
+
+func (adminOnly) GetAdminHandler(types.Authenticator) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+type adminWithAuth struct {
+	adminOnly
+}
+
+func (adminWithAuth) GetAuthenticator() types.Authenticator {
+	return trust.New()
+}
+
+type adminWithAdminAuth struct {
+	adminOnly
+}
+
+func (adminWithAdminAuth) GetAdminAuthenticator() types.Authenticator {
+	return denyAll{}
+}
+
+// denyAll rejects every request.
+type denyAll struct{}
+
+func (denyAll) Check(context.Context, ident.Schema, string) (bool, error) {
+	return false, nil
+}
+
+// Verify that the admin handler is mounted only if there is an
+// authenticator to protect it, and that an admin-only authenticator
+// does not protect the diagnostic endpoints.
+func TestAdminRequiresAuthenticator(t *testing.T) {
+	tcs := []struct {
+		started any
+		code    int
+		diag    int
+	}{
+		{adminOnly{}, http.StatusNotFound, http.StatusOK},
+		{adminWithAuth{}, http.StatusOK, http.StatusOK},
+		{adminWithAdminAuth{}, http.StatusOK, http.StatusOK},
+	}
+
+	for idx, tc := range tcs {
+		t.Run(fmt.Sprintf("%d", idx), func(t *testing.T) {
+			r := require.New(t)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			ready := make(chan struct{})
+			addr := fmt.Sprintf("127.0.0.1:%d", 13014+idx)
+
+			cmd := New(&Template{
+				Metrics: addr,
+				Start: func(ctx *stopper.Context, cmd *cobra.Command) (started any, err error) {
+					return tc.started, nil
+				},
+				Use: "test",
+				testCallback: func() {
+					close(ready)
+				},
+			})
+			cmd.SetArgs([]string{})
+
+			errs := make(chan error, 1)
+			go func() {
+				errs <- cmd.ExecuteContext(ctx)
+			}()
+
+			select {
+			case <-ctx.Done():
+				r.Fail("timed out waiting for server")
+			case <-ready:
+			}
+
+			resp, err := http.Get("http://" + addr + AdminPath + "conveyors")
+			r.NoError(err)
+			r.NoError(resp.Body.Close())
+			r.Equal(tc.code, resp.StatusCode)
+
+			resp, err = http.Get("http://" + addr + "/_/diag")
+			r.NoError(err)
+			r.NoError(resp.Body.Close())
+			r.Equal(tc.diag, resp.StatusCode)
+
+			cancel()
+			r.NoError(<-errs)
+		})
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package stdpool
+
+import (
+	"database/sql"
+	"strconv"
+	"time"
+
+	"github.com/cockroachdb/field-eng-powertools/stopper"
+	"github.com/cockroachdb/replicator/internal/types"
+	mssql "github.com/microsoft/go-mssqldb"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// sqlServerSessionInit ensures that double-quoted identifiers are
+// interpreted consistently, regardless of the server's defaults.
+const sqlServerSessionInit = `SET QUOTED_IDENTIFIER ON; SET ANSI_NULLS ON; SET XACT_ABORT ON;`
+
+// See also:
+// https://learn.microsoft.com/en-us/sql/relational-databases/errors-events/database-engine-events-and-errors
+func sqlServerErrCode(err error) (string, bool) {
+	if msErr := (mssql.Error{}); errors.As(err, &msErr) {
+		return strconv.Itoa(int(msErr.Number)), true
+	}
+	return "", false
+}
+
+func sqlServerErrDeferrable(err error) bool {
+	code, ok := sqlServerErrCode(err)
+	if !ok {
+		return false
+	}
+	switch code {
+	case "547": // Statement conflicted with a FOREIGN KEY constraint.
+		return true
+	case "1205": // Deadlock victim.
+		// Concurrent MERGE statements may acquire locks in differing
+		// orders.
+		return true
+	case "2601", "2627": // Unique index or PK constraint violation.
+		// A MERGE checks for matching rows before inserting, so it's
+		// possible for concurrent statements to attempt to insert the
+		// same row.
+		return true
+	default:
+		return false
+	}
+}
+
+func sqlServerErrRetryable(err error) bool {
+	code, ok := sqlServerErrCode(err)
+	if !ok {
+		return false
+	}
+	switch code {
+	case "1205", // Deadlock victim
+		"2601", "2627": // Concurrent insert; see above.
+		return true
+	default:
+		return false
+	}
+}
+
+// OpenSQLServerAsTarget opens a connection to a Microsoft SQL Server
+// database endpoint and returns it as a [types.TargetPool].
+func OpenSQLServerAsTarget(
+	ctx *stopper.Context, connectString string, options ...Option,
+) (*types.TargetPool, error) {
+	var tc TestControls
+	if err := attachOptions(ctx, &tc, options); err != nil {
+		return nil, err
+	}
+
+	connector, err := mssql.NewConnector(connectString)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not parse connection string")
+	}
+	connector.SessionInitSQL = sqlServerSessionInit
+
+	ret := &types.TargetPool{
+		DB: sql.OpenDB(connector),
+		PoolInfo: types.PoolInfo{
+			ConnectionString: connectString,
+			Product:          types.ProductSQLServer,
+
+			ErrCode:      sqlServerErrCode,
+			IsDeferrable: sqlServerErrDeferrable,
+			ShouldRetry:  sqlServerErrRetryable,
+		},
+	}
+	ctx.Defer(func() { _ = ret.Close() })
+
+ping:
+	if err := ret.Ping(); err != nil {
+		if tc.WaitForStartup && isSQLServerStartupError(err) {
+			log.WithError(err).Info("waiting for database to become ready")
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(10 * time.Second):
+				goto ping
+			}
+		}
+		return nil, errors.Wrap(err, "could not ping the database")
+	}
+
+	if err := ret.QueryRow("SELECT @@VERSION").Scan(&ret.Version); err != nil {
+		return nil, errors.Wrap(err, "could not query version")
+	}
+	if err := setTableHint(ret.Info()); err != nil {
+		return nil, err
+	}
+	if err := attachOptions(ctx, ret.DB, options); err != nil {
+		return nil, err
+	}
+
+	if err := attachOptions(ctx, &ret.PoolInfo, options); err != nil {
+		return nil, err
+	}
+	return ret, nil
+}
+
+// These have been enumerated through trial and error.
+var sqlServerStartupErrors = map[string]bool{
+	"4060":  true, // Cannot open database requested by the login
+	"18456": true, // Login failed; reported while the server recovers
+}
+
+func isSQLServerStartupError(err error) bool {
+	code, ok := sqlServerErrCode(err)
+	if !ok {
+		return false
+	}
+	return sqlServerStartupErrors[code]
+}
@@ -129,6 +129,22 @@ func OpenPgxAsStaging(
 	return ret, err
 }
 
+// NewLocalStaging returns a [types.StagingPool] that has no connection
+// to a staging database. It is used when staged mutations, checkpoints,
+// leases, and memos are kept in an embedded store. The error-handling
+// functions of the pool behave as for a CockroachDB pool, so that it may
+// be used with the retry package.
+func NewLocalStaging() *types.StagingPool {
+	return &types.StagingPool{
+		PoolInfo: types.PoolInfo{
+			ErrCode:      pgErrCode,
+			HintNoFTS:    emptyHint,
+			IsDeferrable: pgErrDeferrable,
+			ShouldRetry:  pgErrRetryable,
+		},
+	}
+}
+
 // OpenPgxAsTarget uses pgx to open a database connection, returning it as a
 // stdlib pool.
 func OpenPgxAsTarget(
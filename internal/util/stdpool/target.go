@@ -42,6 +42,8 @@ func OpenTarget(
 		return OpenPgxAsTarget(ctx, connectString, options...)
 	case "ora", "oracle":
 		return OpenOracleAsTarget(ctx, connectString, options...)
+	case "sqlserver":
+		return OpenSQLServerAsTarget(ctx, connectString, options...)
 	default:
 		return nil, errors.Errorf("unknown URL scheme: %s", u.Scheme)
 	}
@@ -50,3 +50,29 @@ func Authenticator(
 	}
 	return auth, err
 }
+
+// StagingAuthenticator constructs a JWT-based authenticator for
+// processes which do not accept mutations over HTTP, but which may
+// still expose diagnostic or administrative endpoints. It returns nil
+// if there is no staging database in which to look up keys.
+func StagingAuthenticator(
+	ctx *stopper.Context,
+	diags *diag.Diagnostics,
+	pool *types.StagingPool,
+	stagingDB ident.StagingSchema,
+) (types.Authenticator, error) {
+	if pool.Pool == nil {
+		log.Warn("no staging database; administrative endpoints will be unavailable")
+		return nil, nil
+	}
+	auth, err := jwt.ProvideAuth(ctx, pool, stagingDB)
+	if err != nil {
+		return nil, err
+	}
+	if d, ok := auth.(diag.Diagnostic); ok {
+		if err := diags.Register("auth", d); err != nil {
+			return nil, err
+		}
+	}
+	return auth, nil
+}
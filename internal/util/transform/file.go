@@ -0,0 +1,72 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package transform
+
+import (
+	"os"
+
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// A File contains the transforms for a number of tables. It is keyed
+// by table name, then by column name.
+//
+//	public.users:
+//	  email:
+//	    - op: hash
+//	      algorithm: hmac-sha256
+//	      secret: correct-horse-battery-staple
+//	  name:
+//	    - op: truncate
+//	      length: 32
+type File map[string]map[string]Pipeline
+
+// ReadFile loads and compiles the transforms in a YAML file.
+func ReadFile(path string) (File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return Parse(data)
+}
+
+// Parse loads and compiles transforms from YAML data.
+func Parse(data []byte) (File, error) {
+	var ret File
+	if err := yaml.Unmarshal(data, &ret); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	for table, cols := range ret {
+		for col, pipeline := range cols {
+			if err := pipeline.Compile(); err != nil {
+				return nil, errors.Wrapf(err, "%s.%s", table, col)
+			}
+		}
+	}
+	return ret, nil
+}
+
+// Table returns the pipelines for the named table as a map.
+func (f File) Table(table string) *ident.Map[Pipeline] {
+	ret := &ident.Map[Pipeline]{}
+	for col, pipeline := range f[table] {
+		ret.Put(ident.New(col), pipeline)
+	}
+	return ret
+}
@@ -0,0 +1,350 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+// Package transform contains declarative, per-column value transforms
+// which are applied natively, before mutations are written to the
+// target.
+package transform
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/cockroachdb/replicator/internal/util/cmap"
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/pkg/errors"
+)
+
+// Kind identifies a transform operation.
+type Kind string
+
+// The supported transform operations.
+const (
+	KindCast     Kind = "cast"     // Convert the value to Type.
+	KindConstant Kind = "constant" // Replace the value with Value.
+	KindHash     Kind = "hash"     // Replace the value with a hex-encoded digest.
+	KindMask     Kind = "mask"     // Replace all but the last Keep characters.
+	KindRename   Kind = "rename"   // Move the value to the To property.
+	KindReplace  Kind = "replace"  // Regular-expression replacement.
+	KindTruncate Kind = "truncate" // Limit the value to Length characters.
+)
+
+// Hash algorithms.
+const (
+	AlgorithmSHA256     = "sha256"
+	AlgorithmHMACSHA256 = "hmac-sha256"
+)
+
+// Cast types.
+const (
+	TypeBool   = "bool"
+	TypeFloat  = "float"
+	TypeInt    = "int"
+	TypeString = "string"
+)
+
+// An Op is a single step in a Pipeline. Only the fields relevant to
+// the Kind of operation are used. The hash algorithm defaults to
+// sha256 and the mask character defaults to an asterisk. An Op must be
+// compiled before use.
+type Op struct {
+	Kind        Kind   `json:"op"                    yaml:"op"`
+	Algorithm   string `json:"algorithm,omitempty"   yaml:"algorithm,omitempty"`   // hash
+	Char        string `json:"char,omitempty"        yaml:"char,omitempty"`        // mask
+	Keep        int    `json:"keep,omitempty"        yaml:"keep,omitempty"`        // mask
+	Length      int    `json:"length,omitempty"      yaml:"length,omitempty"`      // truncate
+	Pattern     string `json:"pattern,omitempty"     yaml:"pattern,omitempty"`     // replace
+	Replacement string `json:"replacement,omitempty" yaml:"replacement,omitempty"` // replace
+	Secret      string `json:"secret,omitempty"      yaml:"secret,omitempty"`      // hash
+	To          string `json:"to,omitempty"          yaml:"to,omitempty"`          // rename
+	Type        string `json:"type,omitempty"        yaml:"type,omitempty"`        // cast
+	Value       any    `json:"value,omitempty"       yaml:"value,omitempty"`       // constant
+
+	compiled bool
+	pattern  *regexp.Regexp
+}
+
+// Compile validates the Op and prepares it for use.
+func (o *Op) Compile() error {
+	switch o.Kind {
+	case KindCast:
+		switch o.Type {
+		case TypeBool, TypeFloat, TypeInt, TypeString:
+		default:
+			return errors.Errorf("cast: unsupported type %q", o.Type)
+		}
+	case KindConstant:
+	case KindHash:
+		switch o.Algorithm {
+		case "", AlgorithmSHA256:
+		case AlgorithmHMACSHA256:
+			if o.Secret == "" {
+				return errors.New("hash: a secret is required for hmac")
+			}
+		default:
+			return errors.Errorf("hash: unsupported algorithm %q", o.Algorithm)
+		}
+	case KindMask:
+		if o.Keep < 0 {
+			return errors.New("mask: keep must not be negative")
+		}
+	case KindRename:
+		if o.To == "" {
+			return errors.New("rename: a destination is required")
+		}
+	case KindReplace:
+		var err error
+		o.pattern, err = regexp.Compile(o.Pattern)
+		if err != nil {
+			return errors.Wrap(err, "replace")
+		}
+	case KindTruncate:
+		if o.Length < 0 {
+			return errors.New("truncate: length must not be negative")
+		}
+	default:
+		return errors.Errorf("unknown transform %q", o.Kind)
+	}
+	o.compiled = true
+	return nil
+}
+
+// Equal returns true if the ops have the same configuration.
+func (o *Op) Equal(other *Op) bool {
+	return o == other ||
+		(o != nil) && (other != nil) &&
+			o.Kind == other.Kind &&
+			o.Algorithm == other.Algorithm &&
+			o.Char == other.Char &&
+			o.Keep == other.Keep &&
+			o.Length == other.Length &&
+			o.Pattern == other.Pattern &&
+			o.Replacement == other.Replacement &&
+			o.Secret == other.Secret &&
+			o.To == other.To &&
+			o.Type == other.Type &&
+			reflect.DeepEqual(o.Value, other.Value)
+}
+
+// value applies a value-only transform. Renames are handled by the
+// Pipeline.
+func (o *Op) value(value any) (any, error) {
+	if !o.compiled {
+		return nil, errors.Errorf("transform %q was not compiled", o.Kind)
+	}
+	if o.Kind == KindConstant {
+		return o.Value, nil
+	}
+	// Preserve null values.
+	if value == nil {
+		return nil, nil
+	}
+	if o.Kind == KindCast {
+		return cast(o.Type, value)
+	}
+
+	s, err := asString(value)
+	if err != nil {
+		return nil, err
+	}
+	switch o.Kind {
+	case KindHash:
+		var h hash.Hash
+		if o.Algorithm == AlgorithmHMACSHA256 {
+			h = hmac.New(sha256.New, []byte(o.Secret))
+		} else {
+			h = sha256.New()
+		}
+		h.Write([]byte(s))
+		return hex.EncodeToString(h.Sum(nil)), nil
+
+	case KindMask:
+		count := utf8.RuneCountInString(s)
+		if count <= o.Keep {
+			return s, nil
+		}
+		char := o.Char
+		if char == "" {
+			char = "*"
+		}
+		var sb strings.Builder
+		idx := 0
+		for _, r := range s {
+			if idx < count-o.Keep {
+				sb.WriteString(char)
+			} else {
+				sb.WriteRune(r)
+			}
+			idx++
+		}
+		return sb.String(), nil
+
+	case KindReplace:
+		return o.pattern.ReplaceAllString(s, o.Replacement), nil
+
+	case KindTruncate:
+		if utf8.RuneCountInString(s) <= o.Length {
+			return s, nil
+		}
+		return string([]rune(s)[:o.Length]), nil
+
+	default:
+		return nil, errors.Errorf("unexpected transform %q", o.Kind)
+	}
+}
+
+// A Pipeline is a sequence of operations applied to a column.
+type Pipeline []*Op
+
+// Compile validates all operations in the pipeline.
+func (p Pipeline) Compile() error {
+	for idx, op := range p {
+		if err := op.Compile(); err != nil {
+			return errors.Wrapf(err, "transform %d", idx)
+		}
+	}
+	return nil
+}
+
+// Equal returns true if the pipelines have the same configuration.
+func (p Pipeline) Equal(o Pipeline) bool {
+	if len(p) != len(o) {
+		return false
+	}
+	for idx := range p {
+		if !p[idx].Equal(o[idx]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Apply transforms the named property within the map. A rename
+// operation moves the property, and any subsequent operations will
+// apply to the renamed property. Missing properties are ignored,
+// unless set by a constant operation.
+func (p Pipeline) Apply(m cmap.Map[ident.Ident, any], col ident.Ident) error {
+	for _, op := range p {
+		value, present := m.Get(col)
+		if op.Kind == KindRename {
+			if present {
+				m.Delete(col)
+				m.Put(ident.New(op.To), value)
+			}
+			col = ident.New(op.To)
+			continue
+		}
+		if !present && op.Kind != KindConstant {
+			continue
+		}
+		next, err := op.value(value)
+		if err != nil {
+			return errors.Wrapf(err, "column %s", col)
+		}
+		m.Put(col, next)
+	}
+	return nil
+}
+
+// Value applies the non-rename operations in the pipeline to a single
+// value.
+func (p Pipeline) Value(value any) (any, error) {
+	var err error
+	for _, op := range p {
+		if op.Kind == KindRename {
+			continue
+		}
+		value, err = op.value(value)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return value, nil
+}
+
+// ApplyAll applies each of the pipelines to the map. The pipelines are
+// independent of one another and are not applied in any particular
+// order.
+func ApplyAll(pipelines *ident.Map[Pipeline], m cmap.Map[ident.Ident, any]) error {
+	for col, pipeline := range pipelines.All() {
+		if err := pipeline.Apply(m, col); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// asString returns a string representation of a reified JSON value.
+func asString(value any) (string, error) {
+	switch t := value.(type) {
+	case string:
+		return t, nil
+	case json.Number:
+		return t.String(), nil
+	case bool, float64, int, int64:
+		return fmt.Sprint(t), nil
+	case []byte:
+		return string(t), nil
+	default:
+		buf, err := json.Marshal(t)
+		return string(buf), errors.WithStack(err)
+	}
+}
+
+// cast converts the value to the named type.
+func cast(typ string, value any) (any, error) {
+	s, err := asString(value)
+	if err != nil {
+		return nil, err
+	}
+	switch typ {
+	case TypeBool:
+		ret, err := strconv.ParseBool(s)
+		return ret, errors.Wrapf(err, "cast %q to bool", s)
+	case TypeFloat:
+		ret, err := strconv.ParseFloat(s, 64)
+		return ret, errors.Wrapf(err, "cast %q to float", s)
+	case TypeInt:
+		if b, ok := value.(bool); ok {
+			if b {
+				return int64(1), nil
+			}
+			return int64(0), nil
+		}
+		if ret, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return ret, nil
+		}
+		// Allow fractional values to be truncated.
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "cast %q to int", s)
+		}
+		return int64(f), nil
+	case TypeString:
+		return s, nil
+	default:
+		return nil, errors.Errorf("unsupported type %q", typ)
+	}
+}
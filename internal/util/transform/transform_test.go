@@ -0,0 +1,161 @@
+// Copyright 2024 The Cockroach Authors
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+// SPDX-License-Identifier: Apache-2.0
+
+package transform
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/cockroachdb/replicator/internal/util/ident"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOps(t *testing.T) {
+	tcs := []struct {
+		name     string
+		op       *Op
+		input    any
+		expected any
+		err      string
+	}{
+		{name: "cast bool", op: &Op{Kind: KindCast, Type: TypeBool}, input: "true", expected: true},
+		{name: "cast float", op: &Op{Kind: KindCast, Type: TypeFloat}, input: json.Number("1.5"), expected: 1.5},
+		{name: "cast int", op: &Op{Kind: KindCast, Type: TypeInt}, input: "42", expected: int64(42)},
+		{name: "cast int fraction", op: &Op{Kind: KindCast, Type: TypeInt}, input: json.Number("4.9"), expected: int64(4)},
+		{name: "cast int bad", op: &Op{Kind: KindCast, Type: TypeInt}, input: "foo", err: "cast"},
+		{name: "cast string", op: &Op{Kind: KindCast, Type: TypeString}, input: json.Number("42"), expected: "42"},
+		{name: "cast null", op: &Op{Kind: KindCast, Type: TypeString}, input: nil, expected: nil},
+		{name: "constant", op: &Op{Kind: KindConstant, Value: "fixed"}, input: "foo", expected: "fixed"},
+		{
+			name:     "hash",
+			op:       &Op{Kind: KindHash},
+			input:    "hello",
+			expected: "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+		},
+		{
+			name:     "hmac",
+			op:       &Op{Kind: KindHash, Algorithm: AlgorithmHMACSHA256, Secret: "key"},
+			input:    "The quick brown fox jumps over the lazy dog",
+			expected: "f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8",
+		},
+		{name: "mask", op: &Op{Kind: KindMask, Keep: 4}, input: "4111111111111111", expected: "************1111"},
+		{name: "mask char", op: &Op{Kind: KindMask, Char: "#"}, input: "héllo", expected: "#####"},
+		{name: "mask short", op: &Op{Kind: KindMask, Keep: 4}, input: "abc", expected: "abc"},
+		{name: "replace", op: &Op{Kind: KindReplace, Pattern: `\d`, Replacement: "X"}, input: "a1b2", expected: "aXbX"},
+		{name: "truncate", op: &Op{Kind: KindTruncate, Length: 3}, input: "héllo", expected: "hél"},
+		{name: "truncate number", op: &Op{Kind: KindTruncate, Length: 2}, input: json.Number("12345"), expected: "12"},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			r := require.New(t)
+			r.NoError(tc.op.Compile())
+			actual, err := Pipeline{tc.op}.Value(tc.input)
+			if tc.err != "" {
+				r.ErrorContains(err, tc.err)
+				return
+			}
+			r.NoError(err)
+			r.Equal(tc.expected, actual)
+		})
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tcs := []struct {
+		op  *Op
+		err string
+	}{
+		{&Op{Kind: "nope"}, "unknown transform"},
+		{&Op{Kind: KindCast, Type: "blob"}, "unsupported type"},
+		{&Op{Kind: KindHash, Algorithm: "md5"}, "unsupported algorithm"},
+		{&Op{Kind: KindHash, Algorithm: AlgorithmHMACSHA256}, "secret"},
+		{&Op{Kind: KindMask, Keep: -1}, "negative"},
+		{&Op{Kind: KindRename}, "destination"},
+		{&Op{Kind: KindReplace, Pattern: "("}, "replace"},
+		{&Op{Kind: KindTruncate, Length: -1}, "negative"},
+	}
+	for _, tc := range tcs {
+		t.Run(tc.err, func(t *testing.T) {
+			require.ErrorContains(t, tc.op.Compile(), tc.err)
+		})
+	}
+
+	// Using an uncompiled op is an error.
+	_, err := Pipeline{{Kind: KindConstant}}.Value(nil)
+	require.ErrorContains(t, err, "not compiled")
+}
+
+func TestPipelineApply(t *testing.T) {
+	r := require.New(t)
+
+	p := Pipeline{
+		{Kind: KindRename, To: "renamed"},
+		{Kind: KindTruncate, Length: 3},
+	}
+	r.NoError(p.Compile())
+
+	same := func(a, b any) bool { return a == b }
+
+	m := ident.MapOf[any]("col", "abcdef", "other", 1)
+	r.NoError(p.Apply(m, ident.New("col")))
+	r.True(ident.MapOf[any]("renamed", "abc", "other", 1).Equal(m, same))
+
+	// Missing properties are ignored.
+	m = ident.MapOf[any]("other", 1)
+	r.NoError(p.Apply(m, ident.New("col")))
+	r.True(ident.MapOf[any]("other", 1).Equal(m, same))
+
+	// Constants create the property.
+	c := Pipeline{{Kind: KindConstant, Value: "synthetic"}}
+	r.NoError(c.Compile())
+	r.NoError(ApplyAll(ident.MapOf[Pipeline]("col", c), m))
+	r.True(ident.MapOf[any]("col", "synthetic", "other", 1).Equal(m, same))
+}
+
+func TestParse(t *testing.T) {
+	r := require.New(t)
+
+	f, err := Parse([]byte(`
+public.users:
+  email:
+    - op: hash
+      algorithm: hmac-sha256
+      secret: shh
+  name:
+    - op: truncate
+      length: 2
+    - op: rename
+      to: short_name
+`))
+	r.NoError(err)
+
+	users := f.Table("public.users")
+	r.Equal(2, users.Len())
+	r.True(users.GetZero(ident.New("name")).Equal(Pipeline{
+		{Kind: KindTruncate, Length: 2},
+		{Kind: KindRename, To: "short_name"},
+	}))
+	r.Zero(f.Table("missing").Len())
+
+	_, err = Parse([]byte(`
+public.users:
+  email:
+    - op: bogus
+`))
+	r.ErrorContains(err, "public.users.email")
+}
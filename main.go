@@ -34,10 +34,13 @@ import (
 	"github.com/cockroachdb/replicator/internal/cmd/kafka"
 	"github.com/cockroachdb/replicator/internal/cmd/licenses"
 	"github.com/cockroachdb/replicator/internal/cmd/mkjwt"
+	"github.com/cockroachdb/replicator/internal/cmd/mssqlcdc"
 	"github.com/cockroachdb/replicator/internal/cmd/mylogical"
 	"github.com/cockroachdb/replicator/internal/cmd/objstore"
+	"github.com/cockroachdb/replicator/internal/cmd/oraclelogminer"
 	"github.com/cockroachdb/replicator/internal/cmd/pglogical"
 	"github.com/cockroachdb/replicator/internal/cmd/preflight"
+	"github.com/cockroachdb/replicator/internal/cmd/replay"
 	"github.com/cockroachdb/replicator/internal/cmd/start"
 	"github.com/cockroachdb/replicator/internal/cmd/version"
 	"github.com/cockroachdb/replicator/internal/cmd/workload"
@@ -113,10 +116,13 @@ func main() {
 		kafka.Command(),
 		licenses.Command(),
 		mkjwt.Command(),
+		mssqlcdc.Command(),
 		mylogical.Command(),
 		objstore.Command(),
+		oraclelogminer.Command(),
 		pglogical.Command(),
 		preflight.Command(),
+		replay.Command(),
 		script.HelpCommand(),
 		start.Command(),
 		workload.Command(),